@@ -3,13 +3,34 @@ package main
 import (
 	"context"
 	_ "embed"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 
 	"vrcvideocacher/internal/api"
 	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/cookies"
 	"vrcvideocacher/internal/config"
+	"vrcvideocacher/internal/downloader"
+	"vrcvideocacher/internal/events"
+	"vrcvideocacher/internal/github"
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/internal/network"
 	"vrcvideocacher/internal/patcher"
+	"vrcvideocacher/internal/paths"
+	"vrcvideocacher/internal/platform"
+	"vrcvideocacher/internal/startup"
+	"vrcvideocacher/internal/stats"
+	"vrcvideocacher/internal/updater"
 	"vrcvideocacher/internal/ytdl"
 	"vrcvideocacher/pkg/models"
 )
@@ -20,11 +41,19 @@ var stubData []byte
 // App struct
 type App struct {
 	ctx           context.Context
+	lifetime      context.Context
+	cancel        context.CancelFunc
 	configManager *config.Manager
 	cacheManager  *cache.Manager
 	server        *api.Server
 	patcher       *patcher.Patcher
 	ytdlManager   *ytdl.Manager
+	updates       *updater.Notifier
+	steps         *startup.Tracker
+	logFile       io.Closer
+
+	// quitting lets the window close although minimizeToTray hides it
+	quitting atomic.Bool
 }
 
 // NewApp creates a new App application struct
@@ -32,54 +61,142 @@ func NewApp() *App {
 	return &App{}
 }
 
+// log returns the logger of the app
+func log() *slog.Logger {
+	return logger.For("app")
+}
+
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
+// Independent steps run in parallel and steps the GUI does not need (yt-dlp
+// updates, aria2c, patching) are deferred to the background; each step is
+// timed and reported with the "startup:step" event
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.lifetime, a.cancel = context.WithCancel(ctx)
+	a.steps = startup.NewTracker(func(step startup.Step) {
+		runtime.EventsEmit(a.ctx, "startup:step", step)
+	})
+
+	// Log to the console and the logs directory; the configured level is
+	// applied by the server
+	dirs := paths.Resolve()
+	logFile, err := logger.Setup(logger.Options{Dir: dirs.Logs, Level: slog.LevelInfo, Console: os.Stdout})
+	if err != nil {
+		log().Warn("File logging unavailable", logger.Err(err))
+	} else {
+		a.logFile = logFile
+	}
 
 	// Initialize configuration
-	configPath := config.GetDefaultConfigPath()
-	cfgManager, err := config.NewManager(configPath)
+	var cfgManager *config.Manager
+	err = a.steps.Run("config", func() error {
+		var err error
+		cfgManager, err = config.NewManager(dirs.Config)
+		return err
+	})
 	if err != nil {
-		fmt.Printf("Failed to load config: %v\n", err)
+		log().Error("Failed to load config", logger.Err(err))
+		a.initWindow(nil)
 		return
 	}
 	a.configManager = cfgManager
 
 	cfg := cfgManager.Get()
+	a.initWindow(cfg)
 
 	// Set cache path if not configured
 	if cfg.CachePath == "" {
-		cfg.CachePath = filepath.Join(config.GetDataDir(), "cache")
+		cfg.CachePath = dirs.Cache
 		cfgManager.Update(func(c *models.Config) {
 			c.CachePath = cfg.CachePath
 		})
 	}
 
-	// Initialize cache manager
-	a.cacheManager = cache.NewManager(cfg.CachePath, cfg.CacheMaxSizeGB)
+	// Initialize yt-dlp manager
+	ghClient := github.NewClient(cfg.GitHubToken, filepath.Join(dirs.Data, github.CacheFileName))
+	a.ytdlManager = ytdl.NewManagerWithClient(dirs.Utils, ghClient)
+	a.ytdlManager.SetMirrors(cfg.DownloadMirrors)
+	a.updates = a.newUpdateNotifier(ghClient)
+	a.ytdlManager.SetProgress(func(name string, done, total int64) {
+		runtime.EventsEmit(a.ctx, "ytdlp:download-progress", map[string]any{
+			"name":  name,
+			"done":  done,
+			"total": total,
+		})
+	})
+
+	// Scanning a big cache and installing yt-dlp are the slow steps of a
+	// first run and do not depend on each other
+	var online bool
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		a.steps.Run("cache", func() error {
+			a.cacheManager = cache.NewManager(cfg.CachePath, cfg.CacheMaxSizeGB)
+			return nil
+		})
+	}()
+	go func() {
+		defer wg.Done()
+
+		// Without internet, skip installs and update checks; cached videos
+		// are still served and downloads start once the connection returns
+		a.steps.Run("network", func() error {
+			online = network.NewMonitor(network.DefaultInterval).Check(a.ctx)
+			return nil
+		})
+		if !online {
+			log().Warn("No internet connection, skipping update checks")
+			a.steps.Skip("yt-dlp", "offline")
+			return
+		}
+
+		// Ensure yt-dlp is installed
+		err := a.steps.Run("yt-dlp", func() error {
+			return a.ytdlManager.EnsureInstalled(a.lifetime)
+		})
+		if err != nil {
+			log().Warn("Failed to install yt-dlp", logger.Err(err))
+		}
+	}()
+	wg.Wait()
 
 	// Initialize HTTP server
 	a.server = api.NewServer(cfg, a.cacheManager)
+	a.server.SetConfigManager(cfgManager)
+	a.server.SetStats(stats.NewCollector(filepath.Join(dirs.Data, stats.FileName), time.Now()), filepath.Join(dirs.Data, stats.ReportsDir))
+	a.server.SetRPCTokenFile(filepath.Join(dirs.Data, api.RPCTokenFileName))
+	a.server.Downloader().SetLogDir(filepath.Join(dirs.Logs, downloader.LogsDir))
+	if err := a.server.Downloader().SetCookieDir(dirs.Data); err != nil {
+		log().Warn("Failed to move cookies out of the cache directory", logger.Err(err))
+	}
+	if !online {
+		a.server.Network().Check(a.ctx) // Start in offline mode
+	}
 
-	// Initialize patcher
-	a.patcher = patcher.NewPatcher(stubData)
+	// Ask the user to log in again when cookies are about to expire
+	a.server.SetCookieNotifier(func(status cookies.AccountStatus) {
+		runtime.EventsEmit(a.ctx, "cookies:expiring", status)
+	})
 
-	// Initialize yt-dlp manager
-	utilsDir := filepath.Join(config.GetDataDir(), "Utils")
-	a.ytdlManager = ytdl.NewManager(utilsDir)
+	// Ask the user before caching downloads over the size limit
+	a.server.SetConfirmNotifier(func(req downloader.DownloadRequest) {
+		runtime.EventsEmit(a.ctx, "download:confirm", api.NewDownloadInfo(&req))
+	})
 
-	// Ensure yt-dlp is installed
-	if err := a.ytdlManager.EnsureInstalled(); err != nil {
-		fmt.Printf("Warning: Failed to install yt-dlp: %v\n", err)
-	}
+	// The frontend gets the events streamed to /ws clients as well
+	go a.forwardEvents(a.server.Events())
 
-	// Auto-update yt-dlp if configured
-	if cfg.YtdlAutoUpdate {
-		if err := a.ytdlManager.AutoUpdate(); err != nil {
-			fmt.Printf("Warning: Failed to update yt-dlp: %v\n", err)
-		}
-	}
+	// Initialize patcher, also exposed over the JSON-RPC interface. The
+	// server runs in this process, so new stubs have to reach it
+	// Earlier release hashes are trusted before any patch request arrives;
+	// the latest release is fetched by autoPatch
+	a.patcher = patcher.NewPatcher(stubData)
+	a.patcher.SetRequireServer(true)
+	a.patcher.LoadHashHistory(filepath.Join(dirs.Data, patcher.HashHistoryFileName))
+	a.server.SetPatcher(a.patcher)
 
 	// Update config with yt-dlp path
 	if cfg.YtdlPath == "" || cfg.YtdlPath == "Utils/yt-dlp.exe" {
@@ -87,18 +204,180 @@ func (a *App) startup(ctx context.Context) {
 			c.YtdlPath = a.ytdlManager.GetYtdlpPath()
 		})
 	}
+	a.server.SetYtdlManager(a.ytdlManager)
 
 	// Auto-start server if configured
-	if err := a.server.Start(); err != nil {
-		fmt.Printf("Failed to start server: %v\n", err)
+	if err := a.steps.Run("server", a.server.Start); err != nil {
+		log().Error("Failed to start server", logger.Err(err))
+	}
+
+	if elapsed := a.steps.Elapsed(); elapsed > startup.Budget {
+		log().Warn("Startup over budget", "elapsed", elapsed.Round(time.Millisecond), "budget", startup.Budget, "steps", a.steps.Summary())
+	}
+
+	go a.deferredStartup(cfg, online)
+}
+
+// shutdown is called when the app is closing. It cancels yt-dlp and aria2c
+// installs and updates still in progress and stops app update checks
+func (a *App) shutdown(ctx context.Context) {
+	if a.cancel != nil {
+		a.cancel()
+	}
+	if a.updates != nil {
+		a.updates.Stop()
+	}
+	if a.logFile != nil {
+		a.logFile.Close()
 	}
+}
+
+// forwardEvents emits the events of bus to the frontend under their type
+// until the app shuts down
+func (a *App) forwardEvents(bus *events.Bus) {
+	ch, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-a.lifetime.Done():
+			return
+		case event := <-ch:
+			runtime.EventsEmit(a.ctx, event.Type, event.Data)
+		}
+	}
+}
+
+// deferredStartup runs the startup steps the GUI does not wait for
+func (a *App) deferredStartup(cfg *models.Config, online bool) {
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	// Update yt-dlp, then probe it, reinstalling if it is broken
+	go func() {
+		defer wg.Done()
+
+		if cfg.YtdlAutoUpdate && online {
+			err := a.steps.RunDeferred("yt-dlp update", func() error {
+				return a.ytdlManager.AutoUpdate(a.lifetime)
+			})
+			if err != nil {
+				log().Warn("Failed to update yt-dlp", logger.Err(err))
+			}
+		}
+
+		var health ytdl.Health
+		a.steps.RunDeferred("yt-dlp health", func() error {
+			if !online {
+				health = a.ytdlManager.SelfTest(a.lifetime)
+				return nil
+			}
+
+			var err error
+			health, err = a.ytdlManager.EnsureHealthy(a.lifetime)
+			if err != nil {
+				log().Warn("yt-dlp is unhealthy", logger.Err(err))
+			}
+			return err
+		})
+		runtime.EventsEmit(a.ctx, "ytdlp:health", health)
+	}()
+
+	// Install aria2c for faster downloads if enabled
+	go func() {
+		defer wg.Done()
 
-	// Auto-patch VRChat if configured
+		if !cfg.Aria2cEnabled || !online {
+			return
+		}
+		err := a.steps.RunDeferred("aria2c", func() error {
+			_, err := a.ytdlManager.EnsureAria2c(a.lifetime)
+			return err
+		})
+		if err != nil {
+			log().Warn("aria2c unavailable, using built-in downloader", logger.Err(err))
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		a.autoPatch(cfg, online)
+	}()
+
+	wg.Wait()
+	a.updates.Start()
+	log().Info("Startup finished", "elapsed", a.steps.Elapsed().Round(time.Millisecond), "steps", a.steps.Summary())
+	runtime.EventsEmit(a.ctx, "startup:done", a.steps.Steps())
+}
+
+// autoPatch patches the configured targets, skipping binaries we don't
+// recognize
+func (a *App) autoPatch(cfg *models.Config, online bool) {
+	var autoPatch []string
 	if cfg.PatchVRC {
-		if err := a.PatchVRChat(); err != nil {
-			fmt.Printf("Failed to patch VRChat: %v\n", err)
+		autoPatch = append(autoPatch, patcher.TargetVRChat)
+	}
+	if cfg.PatchVRCBeta {
+		autoPatch = append(autoPatch, patcher.TargetVRChatBeta)
+	}
+	if cfg.PatchResonite {
+		autoPatch = append(autoPatch, patcher.TargetResonite)
+	}
+	if cfg.PatchChilloutVR {
+		autoPatch = append(autoPatch, patcher.TargetChilloutVR)
+	}
+	if len(autoPatch) == 0 {
+		return
+	}
+
+	a.steps.RunDeferred("patch", func() error {
+		history := filepath.Join(paths.Resolve().Data, patcher.HashHistoryFileName)
+		if err := a.patcher.LoadKnownHashes(nil, history, !online); err != nil {
+			log().Warn("Failed to fetch known yt-dlp hashes", logger.Err(err))
+		}
+
+		return a.reportPatchResults(a.patcher.PatchAll(autoPatch, true))
+	})
+}
+
+// PatchAll patches every detected platform at once, refusing binaries we
+// don't recognize, and returns the result of each
+func (a *App) PatchAll() []patcher.PatchResult {
+	results := a.patcher.PatchAll(nil, true)
+	a.reportPatchResults(results)
+	return results
+}
+
+// reportPatchResults logs failed targets and emits patch:results, plus
+// patch:unknown-binary for binaries that were refused and
+// patch:unverified-binary for binaries there were no hashes to check against
+func (a *App) reportPatchResults(results []patcher.PatchResult) error {
+	var errs []error
+	for _, result := range results {
+		err := result.Err()
+		if err == nil {
+			continue
+		}
+		log().Error("Failed to patch", "target", result.Target, logger.Err(err))
+		errs = append(errs, err)
+		switch {
+		case errors.Is(err, patcher.ErrUnknownBinary):
+			if v, err := a.patcher.VerifyTarget(result.Target); err == nil {
+				runtime.EventsEmit(a.ctx, "patch:unknown-binary", v)
+			}
+		case errors.Is(err, patcher.ErrUnverifiedBinary):
+			if v, err := a.patcher.VerifyTarget(result.Target); err == nil {
+				runtime.EventsEmit(a.ctx, "patch:unverified-binary", v)
+			}
 		}
 	}
+	runtime.EventsEmit(a.ctx, "patch:results", results)
+	return errors.Join(errs...)
+}
+
+// GetStartupSteps returns the startup steps run so far and their timing
+func (a *App) GetStartupSteps() []startup.Step {
+	return a.steps.Steps()
 }
 
 // GetConfig returns the current configuration
@@ -108,9 +387,35 @@ func (a *App) GetConfig() *models.Config {
 
 // UpdateConfig updates the configuration
 func (a *App) UpdateConfig(cfg *models.Config) error {
-	return a.configManager.Update(func(c *models.Config) {
+	if err := a.configManager.Update(func(c *models.Config) {
 		*c = *cfg
-	})
+	}); err != nil {
+		return err
+	}
+
+	// Apply to the running server
+	return a.server.SetConfig(a.configManager.Get())
+}
+
+// ValidateConfig checks a config before it is saved and returns every
+// problem found, per field, so the settings UI can highlight them. Besides
+// the checks UpdateConfig applies, paths must exist and ports be free
+func (a *App) ValidateConfig(cfg *models.Config) []config.FieldError {
+	return append(config.ValidateFields(cfg), config.CheckSystem(cfg, a.server.Config())...)
+}
+
+// ListDeviceProfiles returns the built-in device profiles
+func (a *App) ListDeviceProfiles() []config.DeviceProfile {
+	return config.Profiles()
+}
+
+// ApplyDeviceProfile applies a device profile's download limits to the config
+// The new limits apply to downloads queued afterwards
+func (a *App) ApplyDeviceProfile(name string) error {
+	if err := a.configManager.ApplyProfile(name); err != nil {
+		return err
+	}
+	return a.server.SetConfig(a.configManager.Get())
 }
 
 // StartServer starts the HTTP server
@@ -135,7 +440,47 @@ func (a *App) GetServerStatus() map[string]interface{} {
 		"addr":          a.server.GetActualAddr(),
 		"cacheSize":     a.cacheManager.GetSize(),
 		"cacheEntries":  len(a.cacheManager.ListEntries()),
+		"stubs":         a.server.StubActivity(),
+	}
+}
+
+// SetCachingEnabled switches the server between caching and pass-through mode
+func (a *App) SetCachingEnabled(enabled bool) {
+	a.server.SetCachingEnabled(enabled)
+}
+
+// IsCachingEnabled returns whether caching is enabled
+func (a *App) IsCachingEnabled() bool {
+	return a.server.IsCachingEnabled()
+}
+
+// GetAutostart returns whether the app starts at login
+func (a *App) GetAutostart() (platform.Autostart, error) {
+	return platform.GetAutostart()
+}
+
+// SetAutostart registers or removes the app, started minimized, to start
+// at login
+func (a *App) SetAutostart(enabled bool) error {
+	if !enabled {
+		return platform.DisableAutostart()
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return platform.EnableAutostart(exe, []string{"--minimized"}, false)
+}
+
+// RollbackUpdate restores the version of the app the last update replaced,
+// used after a restart
+func (a *App) RollbackUpdate() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
 	}
+	return updater.Rollback(exe)
 }
 
 // PatchVRChat patches VRChat's yt-dlp.exe
@@ -168,11 +513,68 @@ func (a *App) IsVRChatPatched() (bool, error) {
 	return a.patcher.IsPatched(toolsPath)
 }
 
+// ListPatchTargets returns the supported platforms with their patch state
+func (a *App) ListPatchTargets() []patcher.Target {
+	return a.patcher.ListTargets()
+}
+
+// PatchTarget patches the yt-dlp.exe of the named platform
+func (a *App) PatchTarget(name string) error {
+	return a.patcher.PatchTarget(name)
+}
+
+// UnpatchTarget restores the original yt-dlp.exe of the named platform
+func (a *App) UnpatchTarget(name string) error {
+	return a.patcher.UnpatchTarget(name)
+}
+
+// VerifyPatchTarget checks whether the named platform's yt-dlp.exe is
+// patched, a known original or an unknown binary
+func (a *App) VerifyPatchTarget(name string) (*patcher.Verification, error) {
+	return a.patcher.VerifyTarget(name)
+}
+
+// MoveCache relocates the cache directory, emitting cache:move-progress
+// events while files are moved
+func (a *App) MoveCache(newPath string) error {
+	return a.server.MoveCache(newPath, func(p cache.MoveProgress) {
+		runtime.EventsEmit(a.ctx, "cache:move-progress", p)
+	})
+}
+
+// GetYtdlHealth returns the result of the last yt-dlp self-test
+func (a *App) GetYtdlHealth() ytdl.Health {
+	return a.ytdlManager.Health()
+}
+
 // GetCacheEntries returns all cache entries
 func (a *App) GetCacheEntries() []*models.CacheEntry {
 	return a.cacheManager.ListEntries()
 }
 
+// UploadCacheFile lets the user pick a local video file and adds a copy to
+// the cache as the video at videoURL (a YouTube URL or video ID)
+// Returns nil if the dialog was cancelled
+func (a *App) UploadCacheFile(videoURL string) (*models.CacheEntry, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Select a video file",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Videos (*.mp4, *.webm)", Pattern: "*.mp4;*.webm"},
+		},
+	})
+	if err != nil || path == "" {
+		return nil, err
+	}
+
+	entry, err := a.server.AddLocalFile(videoURL, path)
+	if err != nil {
+		return nil, err
+	}
+
+	runtime.EventsEmit(a.ctx, "cache:updated")
+	return entry, nil
+}
+
 // ClearCache clears all cache entries
 func (a *App) ClearCache() error {
 	return a.cacheManager.Clear()
@@ -183,6 +585,74 @@ func (a *App) DeleteCacheEntry(id string) error {
 	return a.cacheManager.DeleteEntry(id)
 }
 
+// GetCachePreview returns the preview sprite of a cache entry as a data
+// URL, or an empty string if none was generated yet
+func (a *App) GetCachePreview(id string) (string, error) {
+	if _, err := a.cacheManager.GetEntry(id); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(a.cacheManager.PreviewPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// SetCacheEntryPrivate marks a cache entry private, served only to this
+// PC, or shared with LAN clients
+func (a *App) SetCacheEntryPrivate(id string, private bool) error {
+	return a.cacheManager.SetPrivate(id, private)
+}
+
+// GetFailedDownloads returns failed downloads kept for review
+func (a *App) GetFailedDownloads() []api.DownloadInfo {
+	failed := a.server.Downloader().ListFailed()
+
+	items := make([]api.DownloadInfo, 0, len(failed))
+	for _, req := range failed {
+		items = append(items, api.NewDownloadInfo(req))
+	}
+
+	return items
+}
+
+// RetryDownload re-queues a failed download
+func (a *App) RetryDownload(id string) error {
+	return a.server.Downloader().Retry(id)
+}
+
+// ConfirmDownload queues a download held because it exceeds the size limit
+func (a *App) ConfirmDownload(id string) error {
+	return a.server.Downloader().Confirm(id)
+}
+
+// DeclineDownload discards a download held because it exceeds the size limit
+func (a *App) DeclineDownload(id string) error {
+	return a.server.Downloader().Decline(id)
+}
+
+// CancelDownload removes a queued download or stops an active one
+func (a *App) CancelDownload(id string) error {
+	return a.server.Downloader().Cancel(id)
+}
+
+// GetDownloadLog returns the output of the last yt-dlp run for a video
+func (a *App) GetDownloadLog(id string) (string, error) {
+	path, err := a.server.Downloader().DownloadLog(id)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 // Greet returns a greeting for the given name
 func (a *App) Greet(name string) string {
 	return fmt.Sprintf("Hello %s, It's show time!", name)
@@ -1,17 +1,42 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"vrcvideocacher/internal/api"
 	"vrcvideocacher/internal/cache"
 	"vrcvideocacher/internal/cli"
 	"vrcvideocacher/internal/config"
+	"vrcvideocacher/internal/downloader"
+	"vrcvideocacher/internal/github"
+	"vrcvideocacher/internal/loadtest"
+	"vrcvideocacher/internal/logger"
 	"vrcvideocacher/internal/patcher"
+	"vrcvideocacher/internal/paths"
+	"vrcvideocacher/internal/platform"
+	"vrcvideocacher/internal/stats"
+	"vrcvideocacher/internal/support"
+	"vrcvideocacher/internal/tui"
+	"vrcvideocacher/internal/uninstall"
 	"vrcvideocacher/internal/updater"
 	"vrcvideocacher/internal/ytdl"
+	"vrcvideocacher/pkg/models"
 )
 
 const (
@@ -27,25 +52,25 @@ func main() {
 	// Parse command-line arguments
 	if len(os.Args) < 2 {
 		cliApp.PrintHelp(os.Stderr)
-		os.Exit(1)
+		os.Exit(cli.ExitFailure)
 	}
 
 	cmd, err := cliApp.ParseCommand(os.Args[1:])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
 		cliApp.PrintHelp(os.Stderr)
-		os.Exit(1)
+		os.Exit(cli.ExitFailure)
 	}
 
 	// Handle help and version commands
 	if cmd.Type == cli.CommandHelp {
 		cliApp.PrintHelp(os.Stdout)
-		os.Exit(0)
+		os.Exit(cli.ExitOK)
 	}
 
 	if cmd.Type == cli.CommandVersion {
 		cliApp.PrintVersion(os.Stdout)
-		os.Exit(0)
+		os.Exit(cli.ExitOK)
 	}
 
 	// Execute command
@@ -54,30 +79,68 @@ func main() {
 }
 
 func executeCommand(cmd *cli.Command) int {
+	// Games run under Wine are detected in the configured prefix, and
+	// Resonite in its configured install directory
+	if cmd.Type != cli.CommandConfigInit {
+		cfg := savedConfig()
+		patcher.SetWinePrefix(cfg.WinePrefix)
+		patcher.SetResonitePath(cfg.ResonitePath)
+	}
+
 	switch cmd.Type {
 	case cli.CommandServer:
-		return runServer(cmd.Port)
+		return runServer(cmd.Port, cmd.SafeMode)
 	case cli.CommandPatch:
-		return runPatch(cmd.Path)
+		if cmd.All {
+			return runPatchAll(cmd.NoElevate)
+		}
+		return runPatch(cmd.Path, cmd.Target, cmd.NoElevate)
 	case cli.CommandUnpatch:
-		return runUnpatch(cmd.Path)
+		return runUnpatch(cmd.Path, cmd.Target)
 	case cli.CommandUpdate:
+		if cmd.Rollback {
+			return runRollback()
+		}
 		return runUpdate(cmd.CheckOnly)
+	case cli.CommandCacheMode:
+		return runCacheMode(cmd.Port, cmd.Enabled)
+	case cli.CommandDoctor:
+		return runDoctor(cmd.Offline)
+	case cli.CommandMoveCache:
+		return runMoveCache(cmd.Path, cmd.Port)
+	case cli.CommandSupportBundle:
+		return runSupportBundle(cmd.Path, cmd.Port, cmd.Offline)
+	case cli.CommandLoadTest:
+		return runLoadTest(cmd.Port, cmd.Concurrency, cmd.Duration, cmd.URLs)
+	case cli.CommandUninstall:
+		return runUninstall(cmd.Port, cmd.RemoveCache, cmd.RemoveConfig, cmd.RemoveTools)
+	case cli.CommandConfigInit:
+		return runConfigInit(cmd.Force)
+	case cli.CommandAVExclusion:
+		return runAVExclusion(cmd.Path, cmd.Target, cmd.AddExclusion)
+	case cli.CommandTUI:
+		return runTUI(cmd.Port)
+	case cli.CommandAutostart:
+		return runAutostart(cmd.Action, cmd.App)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", cmd.String())
-		return 1
+		return cli.ExitFailure
 	}
 }
 
-func runServer(port int) int {
+func runServer(port int, safeMode bool) int {
 	fmt.Printf("Starting VRCYouTubePatcher server on port %d...\n", port)
 
+	// Ctrl+C cancels installs in progress and stops the server
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	// Initialize configuration
-	configPath := config.GetDefaultConfigPath()
-	cfgMgr, err := config.NewManager(configPath)
+	dirs := paths.Resolve()
+	cfgMgr, err := config.NewManager(dirs.Config)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		return 1
+		return cli.ExitConfig
 	}
 
 	// Get config and override port if specified
@@ -86,57 +149,168 @@ func runServer(port int) int {
 		cfg.WebServerPort = port
 	}
 
-	// Initialize yt-dlp manager
-	utilsDir := filepath.Join(config.GetDataDir(), "Utils")
-	ytdlManager := ytdl.NewManager(utilsDir)
-
-	// Ensure yt-dlp is installed
-	fmt.Println("Checking yt-dlp installation...")
-	if err := ytdlManager.EnsureInstalled(); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to install yt-dlp: %v\n", err)
+	// Log to the console and the logs directory
+	level, _ := logger.ParseLevel(cfg.LogLevel)
+	logFile, err := logger.Setup(logger.Options{Dir: dirs.Logs, Level: level, Console: os.Stdout})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: File logging unavailable: %v\n", err)
+	} else {
+		defer logFile.Close()
 	}
 
 	// Initialize cache manager
-	cacheDir := filepath.Join(config.GetDataDir(), "Cache")
+	cacheDir := cfg.CachePath
+	if cacheDir == "" {
+		cacheDir = dirs.Cache
+	}
 	maxSize := float64(cfg.CacheMaxSizeGB) * 1024 * 1024 * 1024
 	cacheMgr := cache.NewManager(cacheDir, maxSize)
 
 	// Initialize API server (downloader is created inside)
 	server := api.NewServer(cfg, cacheMgr)
+	server.SetConfigManager(cfgMgr)
+	server.SetStats(stats.NewCollector(filepath.Join(dirs.Data, stats.FileName), time.Now()), filepath.Join(dirs.Data, stats.ReportsDir))
+	server.SetRPCTokenFile(filepath.Join(dirs.Data, api.RPCTokenFileName))
+	server.Downloader().SetLogDir(filepath.Join(dirs.Logs, downloader.LogsDir))
+	if err := server.Downloader().SetCookieDir(dirs.Data); err != nil {
+		slog.Warn("Failed to move cookies out of the cache directory", logger.Err(err))
+	}
+
+	// Patching from the dashboard and `vrcvideocacher tui`. The server runs
+	// in this process, so new stubs have to reach it
+	if stubData, err := loadStubData(); err == nil {
+		// Earlier release hashes are trusted before any patch request
+		// arrives; the latest release is fetched in the background
+		history := filepath.Join(dirs.Data, patcher.HashHistoryFileName)
+		p := patcher.NewPatcher(stubData)
+		p.SetRequireServer(true)
+		p.LoadHashHistory(history)
+		server.SetPatcher(p)
+		go func() {
+			if err := p.LoadKnownHashes(nil, history, false); err != nil {
+				slog.Warn("Failed to fetch known yt-dlp hashes", logger.Err(err))
+			}
+		}()
+	}
+
+	// Without internet, skip installs and update checks; cached videos are
+	// still served and downloads start once the connection returns
+	online := server.Network().Check(ctx)
+	if !online {
+		slog.Warn("No internet connection, skipping update checks")
+	}
+
+	// Initialize yt-dlp manager
+	ytdlManager := ytdl.NewManagerWithClient(dirs.Utils, newGitHubClient(cfg))
+	ytdlManager.SetMirrors(cfg.DownloadMirrors)
+
+	if online {
+		// Ensure yt-dlp is installed
+		fmt.Println("Checking yt-dlp installation...")
+		if err := ytdlManager.EnsureInstalled(ctx); err != nil {
+			slog.Warn("Failed to install yt-dlp", logger.Err(err))
+		}
+
+		// Install aria2c for faster downloads if enabled
+		if cfg.Aria2cEnabled {
+			if _, err := ytdlManager.EnsureAria2c(ctx); err != nil {
+				slog.Warn("aria2c unavailable, using built-in downloader", logger.Err(err))
+			}
+		}
+
+		// Probe yt-dlp in the background, reinstalling if it is broken
+		go func() {
+			if _, err := ytdlManager.EnsureHealthy(ctx); err != nil {
+				slog.Warn("yt-dlp is unhealthy", logger.Err(err))
+			}
+		}()
+	}
+	server.SetYtdlManager(ytdlManager)
+
+	// Log app updates once a day, unless skipUpdateVersion or
+	// updateRemindAfter hold them back
+	notifier := updater.NewNotifier(updater.NewUpdaterWithClient(GitHubRepo, Version, newGitHubClient(cfg)), updater.DefaultInterval, func() updater.Preferences {
+		return updater.PreferencesFrom(cfgMgr.Get())
+	}, nil)
+	notifier.Start()
+	defer notifier.Stop()
+
+	if ctx.Err() != nil {
+		fmt.Println("Interrupted")
+		return cli.ExitFailure
+	}
+
+	// Safe mode isolates VRChat/YouTube problems from the cacher
+	if safeMode {
+		if err := server.EnableSafeMode(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		fmt.Println("Safe mode: every video is passed through; caching, cookies, additional yt-dlp args and blocklists are off")
+	}
 
 	// Start server (downloader is started automatically)
 	fmt.Printf("Server listening on :%d\n", cfg.WebServerPort)
+	fmt.Printf("Dashboard: http://127.0.0.1:%d/dashboard/\n", cfg.WebServerPort)
 	fmt.Println("Press Ctrl+C to stop")
 
 	if err := server.Start(); err != nil {
 		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
-		return 1
+		if serverRunning(cfg.WebServerPort) {
+			fmt.Fprintf(os.Stderr, "VRCYouTubePatcher is already running on port %d\n", cfg.WebServerPort)
+			return cli.ExitAlreadyRunning
+		}
+		return cli.ExitFailure
 	}
 
-	// Keep server running (Start returns immediately)
-	select {}
+	// Keep server running until interrupted (Start returns immediately)
+	<-ctx.Done()
+	stop()
+	fmt.Println("Stopping server...")
+	if err := server.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error stopping server: %v\n", err)
+		return cli.ExitFailure
+	}
+	return cli.ExitOK
 }
 
-func runPatch(toolsPath string) int {
-	fmt.Println("Patching VRChat's yt-dlp.exe...")
+// detectToolsPath returns the directory of target's yt-dlp.exe, VRChat's if
+// no target is given
+func detectToolsPath(target string) (string, error) {
+	if target == "" {
+		target = patcher.TargetVRChat
+	}
+
+	toolsPath, err := patcher.DetectTargetPath(target)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Detected %s Tools directory: %s\n", target, toolsPath)
+	return toolsPath, nil
+}
 
-	// Detect VRChat path if not provided
+func runPatch(toolsPath, target string, noElevate bool) int {
+	name := patchedName(toolsPath, target)
+	fmt.Printf("Patching %s yt-dlp.exe...\n", name)
+
+	// Detect the target path if not provided
 	if toolsPath == "" {
-		detectedPath, err := patcher.DetectVRChatPath()
+		if target == "" {
+			target = patcher.TargetVRChat
+		}
+		detectedPath, err := detectToolsPath(target)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			fmt.Fprintln(os.Stderr, "Please specify the VRChat Tools directory with -path flag")
-			return 1
+			fmt.Fprintln(os.Stderr, "Please specify the Tools directory with -path flag")
+			return patchExitCode(err)
 		}
 		toolsPath = detectedPath
-		fmt.Printf("Detected VRChat Tools directory: %s\n", toolsPath)
 	}
 
 	// Load stub data
 	stubData, err := loadStubData()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading stub: %v\n", err)
-		return 1
+		return cli.ExitFailure
 	}
 
 	// Create patcher
@@ -145,39 +319,191 @@ func runPatch(toolsPath string) int {
 	// Check if already patched
 	if patched, err := p.IsPatched(toolsPath); err == nil && patched {
 		fmt.Println("Already patched!")
-		return 0
+		return cli.ExitOK
 	}
 
-	// Patch
-	if err := p.PatchVRChat(toolsPath); err != nil {
+	// Patch, recording the target in the manifest if it was detected
+	if target != "" {
+		err = p.PatchTarget(target)
+	} else {
+		err = p.PatchVRChat(toolsPath)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error patching: %v\n", err)
-		return 1
+		if errors.Is(err, patcher.ErrAccessDenied) {
+			// Relaunch with the same target so it is recorded and bound
+			// like it would have been here
+			args := []string{"patch", "-path", toolsPath}
+			if target != "" {
+				args = []string{"patch", "-target", target}
+			}
+			return patchElevated(args, noElevate)
+		}
+		return patchExitCode(err)
+	}
+
+	fmt.Printf("Successfully patched %s yt-dlp.exe\n", name)
+	return cli.ExitOK
+}
+
+func runPatchAll(noElevate bool) int {
+	fmt.Println("Patching every detected target...")
+
+	stubData, err := loadStubData()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading stub: %v\n", err)
+		return cli.ExitFailure
+	}
+
+	results := patcher.NewPatcher(stubData).PatchAll(nil, false)
+	exitCode := printPatchResults(os.Stdout, results)
+	for _, result := range results {
+		if errors.Is(result.Err(), patcher.ErrAccessDenied) {
+			return patchElevated([]string{"patch", "-all"}, noElevate)
+		}
+	}
+	return exitCode
+}
+
+// patchElevated handles a patch the OS refused for lack of rights. On
+// Windows the patch alone runs again as administrator after a UAC prompt,
+// elsewhere the command to run with sudo is printed
+func patchElevated(args []string, noElevate bool) int {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "vrcvideocacher"
+	}
+
+	if runtime.GOOS != "windows" {
+		fmt.Fprintln(os.Stderr, "The Tools directory is not writable by this user, patch it as root:")
+		fmt.Fprintf(os.Stderr, "\n  sudo %s\n\n", shellJoin(append([]string{exe}, args...)))
+		return cli.ExitPatch
+	}
+	if noElevate {
+		fmt.Fprintln(os.Stderr, "Patch from a terminal opened with \"Run as administrator\"")
+		return cli.ExitPatch
+	}
+
+	fmt.Println("Asking Windows for administrator rights to patch...")
+	code, err := platform.RunElevated(exe, append(args, "-no-elevate"))
+	switch {
+	case errors.Is(err, platform.ErrElevationCancelled):
+		fmt.Fprintln(os.Stderr, "Error: administrator rights were not granted")
+		return cli.ExitPatch
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return cli.ExitPatch
+	case code != cli.ExitOK:
+		fmt.Fprintf(os.Stderr, "Error: patching as administrator failed (exit code %d)\n", code)
+		return code
+	}
+
+	fmt.Println("Successfully patched as administrator")
+	return cli.ExitOK
+}
+
+// shellJoin joins args into a command line for a POSIX shell
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`*?&;|<>()[]{}~#!") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// patchExitCode returns the exit code of a failed patch, unpatch or Tools
+// directory detection
+func patchExitCode(err error) int {
+	switch {
+	case errors.Is(err, patcher.ErrTargetNotFound),
+		errors.Is(err, patcher.ErrVRChatNotFound),
+		errors.Is(err, patcher.ErrFileNotFound):
+		return cli.ExitNotFound
+	case errors.Is(err, patcher.ErrUnknownTarget):
+		return cli.ExitFailure
+	default:
+		return cli.ExitPatch
 	}
+}
+
+// networkExitCode returns cli.ExitNetwork if err is a failure to reach a
+// host, otherwise fallback
+func networkExitCode(err error, fallback int) int {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return cli.ExitNetwork
+	}
+	return fallback
+}
+
+// serverRunning reports whether a server answers health checks on port
+func serverRunning(port int) bool {
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/api/health", port))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+// printPatchResults writes a table of patch results to w and returns
+// cli.ExitPatch if any target failed
+func printPatchResults(w io.Writer, results []patcher.PatchResult) int {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tSTATUS\tDETAIL")
 
-	fmt.Println("Successfully patched VRChat's yt-dlp.exe")
-	return 0
+	exitCode := cli.ExitOK
+	for _, result := range results {
+		detail := result.Path
+		if result.Error != "" {
+			detail = result.Error
+		}
+		if result.Status == patcher.PatchFailed {
+			exitCode = cli.ExitPatch
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", result.DisplayName, result.Status, detail)
+	}
+	tw.Flush()
+
+	return exitCode
 }
 
-func runUnpatch(toolsPath string) int {
-	fmt.Println("Unpatching VRChat's yt-dlp.exe...")
+// patchedName names whose yt-dlp.exe is patched: the target's, the given
+// directory's or VRChat's by default
+func patchedName(toolsPath, target string) string {
+	switch {
+	case target != "":
+		return patcher.TargetDisplayName(target) + "'s"
+	case toolsPath != "":
+		return "the"
+	default:
+		return "VRChat's"
+	}
+}
 
-	// Detect VRChat path if not provided
+func runUnpatch(toolsPath, target string) int {
+	fmt.Printf("Unpatching %s yt-dlp.exe...\n", patchedName(toolsPath, target))
+
+	// Detect the target path if not provided
 	if toolsPath == "" {
-		detectedPath, err := patcher.DetectVRChatPath()
+		detectedPath, err := detectToolsPath(target)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			fmt.Fprintln(os.Stderr, "Please specify the VRChat Tools directory with -path flag")
-			return 1
+			fmt.Fprintln(os.Stderr, "Please specify the Tools directory with -path flag")
+			return patchExitCode(err)
 		}
 		toolsPath = detectedPath
-		fmt.Printf("Detected VRChat Tools directory: %s\n", toolsPath)
 	}
 
 	// Load stub data (for patcher instance)
 	stubData, err := loadStubData()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading stub: %v\n", err)
-		return 1
+		return cli.ExitFailure
 	}
 
 	// Create patcher
@@ -186,11 +512,11 @@ func runUnpatch(toolsPath string) int {
 	// Unpatch
 	if err := p.UnpatchVRChat(toolsPath); err != nil {
 		fmt.Fprintf(os.Stderr, "Error unpatching: %v\n", err)
-		return 1
+		return patchExitCode(err)
 	}
 
 	fmt.Println("Successfully restored original yt-dlp.exe")
-	return 0
+	return cli.ExitOK
 }
 
 func runUpdate(checkOnly bool) int {
@@ -201,43 +527,591 @@ func runUpdate(checkOnly bool) int {
 	}
 
 	// Create updater
-	u := updater.NewUpdater(GitHubRepo, Version)
+	cfg := savedConfig()
+	u := updater.NewUpdaterWithClient(GitHubRepo, Version, newGitHubClient(cfg))
+	u.SetMirrors(cfg.DownloadMirrors)
 
 	// Check for updates
 	latestVersion, hasUpdate, err := u.CheckForUpdate()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error checking for updates: %v\n", err)
-		return 1
+		return networkExitCode(err, cli.ExitFailure)
 	}
 
 	if !hasUpdate {
 		fmt.Printf("Already up to date (version %s)\n", Version)
-		return 0
+		return cli.ExitOK
 	}
 
 	fmt.Printf("Update available: %s -> %s\n", Version, latestVersion)
 
 	if checkOnly {
 		fmt.Println("Run 'vrcvideocacher update' to install the update")
-		return 0
+		return cli.ExitOK
 	}
 
 	// Get current executable path
 	exePath, err := os.Executable()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error getting executable path: %v\n", err)
-		return 1
+		return cli.ExitFailure
 	}
 
 	// Download and install update
 	if err := u.Download(exePath); err != nil {
 		fmt.Fprintf(os.Stderr, "Error updating: %v\n", err)
-		return 1
+		return networkExitCode(err, cli.ExitFailure)
 	}
 
 	fmt.Printf("Successfully updated to version %s\n", latestVersion)
+	fmt.Println("Run 'vrcvideocacher update --rollback' if it breaks something")
+	fmt.Println("Please restart the application")
+	return cli.ExitOK
+}
+
+func runRollback() int {
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting executable path: %v\n", err)
+		return cli.ExitFailure
+	}
+
+	if err := updater.Rollback(exePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error rolling back: %v\n", err)
+		if errors.Is(err, updater.ErrNoRollback) {
+			return cli.ExitNotFound
+		}
+		return cli.ExitFailure
+	}
+
+	fmt.Println("Restored the version the last update replaced")
 	fmt.Println("Please restart the application")
-	return 0
+	return cli.ExitOK
+}
+
+func runCacheMode(port int, enabled bool) int {
+	// Use configured port if not specified
+	if port == 0 {
+		cfgMgr, err := config.NewManager(paths.Resolve().Config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			return cli.ExitConfig
+		}
+		port = cfgMgr.Get().WebServerPort
+	}
+
+	body, err := json.Marshal(map[string]bool{"enabled": enabled})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return cli.ExitFailure
+	}
+
+	reqURL := fmt.Sprintf("http://127.0.0.1:%d/api/cache-mode", port)
+	resp, err := http.Post(reqURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to reach server - is it running? %v\n", err)
+		return cli.ExitNetwork
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Error: server returned status %d\n", resp.StatusCode)
+		return cli.ExitFailure
+	}
+
+	if enabled {
+		fmt.Println("Caching enabled")
+	} else {
+		fmt.Println("Caching disabled (pass-through mode)")
+	}
+	return cli.ExitOK
+}
+
+func runDoctor(offline bool) int {
+	return doctor(os.Stdout, offline)
+}
+
+// doctor writes the yt-dlp self-test and patch target checks to w
+func doctor(w io.Writer, offline bool) int {
+	stubData, err := loadStubData()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading stub: %v\n", err)
+		return cli.ExitFailure
+	}
+
+	p := patcher.NewPatcher(stubData)
+
+	history := filepath.Join(paths.Resolve().Data, patcher.HashHistoryFileName)
+	if err := p.LoadKnownHashes(nil, history, offline); err != nil {
+		fmt.Fprintf(w, "Warning: %v (using bundled list and earlier releases only)\n", err)
+	}
+
+	exitCode := cli.ExitOK
+
+	// yt-dlp self-test
+	ytdlManager := ytdl.NewManagerWithClient(paths.Resolve().Utils, newGitHubClient(nil))
+	if !ytdlManager.IsInstalled() {
+		fmt.Fprintf(w, "[!!] yt-dlp: not installed (%s)\n", ytdlManager.GetYtdlpPath())
+		exitCode = cli.ExitFailure
+	} else if health := ytdlManager.SelfTest(context.Background()); health.Healthy {
+		fmt.Fprintf(w, "[ok] yt-dlp: %s\n", health.Version)
+	} else {
+		fmt.Fprintf(w, "[!!] yt-dlp: %s\n", health.Error)
+		exitCode = cli.ExitFailure
+	}
+
+	for _, target := range p.ListTargets() {
+		if !target.Detected {
+			fmt.Fprintf(w, "[--] %s: not installed\n", target.DisplayName)
+			continue
+		}
+
+		v, err := p.Verify(target.Path)
+		if err != nil {
+			fmt.Fprintf(w, "[!!] %s: %v\n", target.DisplayName, err)
+			exitCode = cli.ExitFailure
+			continue
+		}
+
+		switch v.Status {
+		case patcher.BinaryPatched:
+			fmt.Fprintf(w, "[ok] %s: patched (%s)\n", target.DisplayName, v.Path)
+		case patcher.BinaryKnown:
+			fmt.Fprintf(w, "[ok] %s: original yt-dlp %s (%s)\n", target.DisplayName, v.Label, v.Path)
+		case patcher.BinaryMissing:
+			fmt.Fprintf(w, "[--] %s: yt-dlp.exe not found (%s)\n", target.DisplayName, v.Path)
+		case patcher.BinaryRemoved:
+			fmt.Fprintf(w, "[!!] %s: the stub was removed, likely by antivirus (%s)\n", target.DisplayName, v.Path)
+			fmt.Fprintf(w, "     Run `vrcvideocacher av-exclusion -target %s` and patch again\n", target.Name)
+			exitCode = cli.ExitFailure
+			continue
+		default:
+			fmt.Fprintf(w, "[!!] %s: unknown yt-dlp.exe (sha256 %s)\n", target.DisplayName, v.Hash)
+			fmt.Fprintln(w, "     It may have been modified by another tool; patching will skip it")
+			exitCode = cli.ExitFailure
+		}
+
+		if v.BlockedAt != nil {
+			fmt.Fprintf(w, "[!!] %s: antivirus blocked the stub at %s\n", target.DisplayName, v.BlockedAt.Format(time.DateTime))
+			fmt.Fprintf(w, "     Run `vrcvideocacher av-exclusion -target %s` and patch again\n", target.Name)
+			exitCode = cli.ExitFailure
+		}
+	}
+
+	return exitCode
+}
+
+func runMoveCache(newPath string, port int) int {
+	dirs := paths.Resolve()
+	cfgMgr, err := config.NewManager(dirs.Config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return cli.ExitConfig
+	}
+	cfg := cfgMgr.Get()
+
+	if port == 0 {
+		port = cfg.WebServerPort
+	}
+
+	// Let a running server move its own cache so downloads are paused
+	body, err := json.Marshal(map[string]string{"path": newPath})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return cli.ExitFailure
+	}
+
+	reqURL := fmt.Sprintf("http://127.0.0.1:%d/api/cache/move", port)
+	resp, err := http.Post(reqURL, "application/json", bytes.NewReader(body))
+	if err == nil {
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			msg, _ := io.ReadAll(resp.Body)
+			fmt.Fprintf(os.Stderr, "Error: %s\n", strings.TrimSpace(string(msg)))
+			return cli.ExitFailure
+		}
+		fmt.Printf("Cache moved to %s\n", newPath)
+		return cli.ExitOK
+	}
+
+	// No server running, move the files directly
+	cacheDir := cfg.CachePath
+	if cacheDir == "" {
+		cacheDir = dirs.Cache
+	}
+	cacheMgr := cache.NewManager(cacheDir, 0)
+
+	fmt.Printf("Moving cache from %s to %s...\n", cacheDir, newPath)
+	err = cacheMgr.MoveCache(newPath, func(p cache.MoveProgress) {
+		fmt.Printf("  [%d/%d] %s\n", p.FilesDone, p.FilesTotal, p.File)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error moving cache: %v\n", err)
+		return cli.ExitFailure
+	}
+
+	if err := cfgMgr.Update(func(c *models.Config) {
+		c.CachePath = cacheMgr.GetCachePath()
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+		if rbErr := cacheMgr.MoveCache(cacheDir, nil); rbErr != nil {
+			fmt.Fprintf(os.Stderr, "Error rolling back: %v\n", rbErr)
+		}
+		return cli.ExitConfig
+	}
+
+	fmt.Printf("Cache moved to %s\n", cacheMgr.GetCachePath())
+	return cli.ExitOK
+}
+
+func runSupportBundle(output string, port int, offline bool) int {
+	dirs := paths.Resolve()
+	cfgMgr, err := config.NewManager(dirs.Config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return cli.ExitConfig
+	}
+	cfg := cfgMgr.Get()
+
+	if port == 0 {
+		port = cfg.WebServerPort
+	}
+	if output == "" {
+		output = fmt.Sprintf("vrcvideocacher-support-%s.zip", time.Now().Format("20060102-150405"))
+	}
+
+	home, _ := os.UserHomeDir()
+	bundle := support.NewBundle(home)
+
+	bundle.Add("system.txt", []byte(fmt.Sprintf("VRCYouTubePatcher %s\n%s/%s (%s)\nCreated %s\n",
+		Version, runtime.GOOS, runtime.GOARCH, runtime.Version(), time.Now().Format(time.RFC3339))))
+
+	if err := bundle.AddJSON("config.json", support.RedactConfig(cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	fmt.Println("Running diagnostics...")
+	var diagnostics bytes.Buffer
+	doctor(&diagnostics, offline)
+	bundle.Add("diagnostics.txt", diagnostics.Bytes())
+
+	// Patch manifests record which stubs were written to each target
+	stubData, _ := loadStubData()
+	for _, target := range patcher.NewPatcher(stubData).ListTargets() {
+		if !target.Detected {
+			continue
+		}
+		if data, err := os.ReadFile(patcher.ManifestPath(target.Path)); err == nil {
+			bundle.Add("patch/"+target.Name+".json", data)
+		}
+	}
+
+	// The log file covers past runs, /api/logs only the running server
+	if data, err := os.ReadFile(filepath.Join(dirs.Logs, logger.FileName)); err == nil {
+		bundle.Add("logs/"+logger.FileName, data)
+	}
+
+	// Logs and history only exist in a running server
+	client := &http.Client{Timeout: 5 * time.Second}
+	endpoints := []struct{ name, path string }{
+		{"server/status.json", "/api/status"},
+		{"server/health.json", "/api/health"},
+		{"server/logs.json", "/api/logs"},
+		{"server/history.json", "/api/now-playing"},
+		{"server/downloads.json", "/api/downloads"},
+		{"server/blocklist.json", "/api/blocklist"},
+	}
+	for _, e := range endpoints {
+		resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, e.path))
+		if err != nil {
+			bundle.Add("server/unreachable.txt", []byte(fmt.Sprintf("Server not reachable on port %d: %v\n", port, err)))
+			break
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			continue
+		}
+		bundle.Add(e.name, data)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", output, err)
+		return cli.ExitFailure
+	}
+	if err := bundle.WriteZip(f); err != nil {
+		f.Close()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return cli.ExitFailure
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", output, err)
+		return cli.ExitFailure
+	}
+
+	for _, name := range bundle.Names() {
+		fmt.Printf("  %s\n", name)
+	}
+	fmt.Printf("Support bundle written to %s\n", output)
+	fmt.Println("Review it before attaching it to a GitHub issue")
+	return cli.ExitOK
+}
+
+func runLoadTest(port, concurrency int, duration time.Duration, videoURLs []string) int {
+	if port == 0 {
+		port = savedConfig().WebServerPort
+	}
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	// Stop early on Ctrl+C, still reporting what was measured
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if len(videoURLs) == 0 {
+		var err error
+		videoURLs, err = loadtest.CachedVideos(ctx, nil, baseURL, 100)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v - is the server running with videos cached? Pass -url to request others\n", err)
+			return networkExitCode(err, cli.ExitFailure)
+		}
+	}
+
+	fmt.Printf("Requesting %d videos from %s with %d clients for %s...\n", len(videoURLs), baseURL, concurrency, duration)
+	report, err := loadtest.Run(ctx, loadtest.Options{
+		BaseURL:     baseURL,
+		VideoURLs:   videoURLs,
+		Concurrency: concurrency,
+		Duration:    duration,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return cli.ExitFailure
+	}
+
+	fmt.Println()
+	report.Write(os.Stdout)
+	if report.GetVideo.Errors > 0 || report.Files.Errors > 0 {
+		return cli.ExitFailure
+	}
+	return cli.ExitOK
+}
+
+// newGitHubClient returns the GitHub client for update checks, using the
+// token of cfg, or of the saved config if cfg is nil
+func newGitHubClient(cfg *models.Config) *github.Client {
+	if cfg == nil {
+		cfg = savedConfig()
+	}
+	return github.NewClient(cfg.GitHubToken, filepath.Join(paths.Resolve().Data, github.CacheFileName))
+}
+
+// savedConfig returns the saved config, or the defaults if it cannot be read
+func runUninstall(port int, removeCache, removeConfig, removeTools bool) int {
+	cfg := savedConfig()
+	if port == 0 {
+		port = cfg.WebServerPort
+	}
+
+	// A running app would keep using the cache and patch again at its next
+	// start
+	if serverRunning(port) {
+		fmt.Fprintf(os.Stderr, "Error: a server is running on port %d, close VRCYouTubePatcher first\n", port)
+		return cli.ExitAlreadyRunning
+	}
+
+	dirs := paths.Resolve()
+	cachePath := cfg.CachePath
+	if cachePath == "" {
+		cachePath = dirs.Cache
+	}
+
+	stubData, err := loadStubData()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading stub: %v\n", err)
+		return cli.ExitFailure
+	}
+
+	report := uninstall.Run(patcher.NewPatcher(stubData), uninstall.Options{
+		Paths:        dirs,
+		CachePath:    cachePath,
+		RemoveCache:  removeCache,
+		RemoveConfig: removeConfig,
+		RemoveTools:  removeTools,
+	})
+
+	if len(report.Unpatched) == 0 {
+		fmt.Println("No patched targets found")
+	}
+	for _, target := range report.Unpatched {
+		fmt.Printf("Restored original yt-dlp.exe of %s (%s)\n", target.DisplayName, target.Path)
+	}
+	for _, path := range report.Removed {
+		fmt.Printf("Removed %s\n", path)
+	}
+	for _, path := range report.Kept {
+		fmt.Printf("Kept %s, it holds files not created by VRCYouTubePatcher\n", path)
+	}
+
+	if len(report.Errors) > 0 {
+		for _, err := range report.Errors {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		if errors.Is(errors.Join(report.Errors...), uninstall.ErrStillPatched) {
+			fmt.Fprintln(os.Stderr, "Verify the game files (e.g. in Steam) to restore the original yt-dlp.exe")
+			return cli.ExitPatch
+		}
+		return cli.ExitFailure
+	}
+	return cli.ExitOK
+}
+
+func runConfigInit(force bool) int {
+	dirs := paths.Resolve()
+	docsPath, err := config.Init(dirs.Config, force)
+	if errors.Is(err, config.ErrConfigExists) {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists, pass -force to replace it with the defaults\n", dirs.Config)
+		return cli.ExitConfig
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
+		return cli.ExitConfig
+	}
+
+	fmt.Printf("Wrote %s\n", dirs.Config)
+	fmt.Printf("Wrote %s\n\n", docsPath)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Data directory:\t%s\n", dirs.Data)
+	fmt.Fprintf(tw, "Config:\t%s\n", dirs.Config)
+	fmt.Fprintf(tw, "Cache:\t%s\n", dirs.Cache)
+	fmt.Fprintf(tw, "Tools:\t%s\n", dirs.Utils)
+	fmt.Fprintf(tw, "Logs:\t%s\n", dirs.Logs)
+	tw.Flush()
+	return cli.ExitOK
+}
+
+func runAVExclusion(toolsPath, target string, add bool) int {
+	if toolsPath == "" {
+		detectedPath, err := detectToolsPath(target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintln(os.Stderr, "Please specify the Tools directory with -path flag")
+			return patchExitCode(err)
+		}
+		toolsPath = detectedPath
+	}
+
+	command := platform.DefenderExclusionCommand(toolsPath)
+	fmt.Println("Antivirus software may remove the stub yt-dlp.exe. To exclude the Tools")
+	fmt.Println("directory from Microsoft Defender, run in an elevated PowerShell:")
+	fmt.Printf("\n  %s\n\n", command)
+	fmt.Println("Other antivirus software has its own exclusion settings for this directory.")
+	if !add {
+		return cli.ExitOK
+	}
+
+	// An exclusion weakens protection, so only add it when the user agrees
+	fmt.Printf("Add this exclusion to Microsoft Defender now? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		fmt.Println("No exclusion added")
+		return cli.ExitOK
+	}
+
+	if err := platform.AddDefenderExclusion(toolsPath); err != nil {
+		if errors.Is(err, platform.ErrUnsupported) {
+			fmt.Fprintln(os.Stderr, "Error: Microsoft Defender is only available on Windows")
+			return cli.ExitFailure
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Run this command from a terminal opened with \"Run as administrator\"")
+		return cli.ExitFailure
+	}
+
+	fmt.Printf("Excluded %s from Microsoft Defender scans\n", toolsPath)
+	return cli.ExitOK
+}
+
+func runTUI(port int) int {
+	if port == 0 {
+		port = savedConfig().WebServerPort
+	}
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := tui.Run(ctx, baseURL, os.Stdin, os.Stdout); err != nil {
+		if errors.Is(err, tui.ErrNotTerminal) {
+			fmt.Fprintln(os.Stderr, "Error: tui needs an interactive terminal; use the dashboard or the API instead")
+			return cli.ExitFailure
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return cli.ExitFailure
+	}
+	return cli.ExitOK
+}
+
+// runAutostart enables, disables or shows starting at login: the server of
+// this executable in a minimized window, or the desktop app at app
+func runAutostart(action, app string) int {
+	switch action {
+	case "enable":
+		path, args, minimized := app, []string{"--minimized"}, false
+		if app == "" {
+			exe, err := os.Executable()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return cli.ExitFailure
+			}
+			path, args, minimized = exe, []string{"server"}, true
+		} else {
+			abs, err := filepath.Abs(app)
+			if err == nil {
+				_, err = os.Stat(abs)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: desktop app not found: %v\n", err)
+				return cli.ExitNotFound
+			}
+			path = abs
+		}
+
+		if err := platform.EnableAutostart(path, args, minimized); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return cli.ExitFailure
+		}
+		fmt.Println("Enabled starting at login")
+	case "disable":
+		if err := platform.DisableAutostart(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return cli.ExitFailure
+		}
+		fmt.Println("Disabled starting at login")
+		return cli.ExitOK
+	}
+
+	state, err := platform.GetAutostart()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return cli.ExitFailure
+	}
+	if !state.Enabled {
+		fmt.Println("Start at login: disabled")
+		return cli.ExitOK
+	}
+	fmt.Println("Start at login: enabled")
+	fmt.Printf("  Command:  %s\n", state.Command)
+	fmt.Printf("  Location: %s\n", state.Location)
+	return cli.ExitOK
+}
+
+func savedConfig() *models.Config {
+	if cfgMgr, err := config.NewManager(paths.Resolve().Config); err == nil {
+		return cfgMgr.Get()
+	}
+	return models.DefaultConfig()
 }
 
 func loadStubData() ([]byte, error) {
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// checkFlag makes the stub report whether it runs and reaches the server
+// instead of fetching a video. The patcher runs every stub it writes with
+// it, so it must match patcher.StubCheckFlag
+const checkFlag = "--vrcvideocacher-check"
+
+// checkTimeout bounds the health request of a check
+const checkTimeout = 3 * time.Second
+
+// check prints the stub version to stdout and returns exitOK if the server
+// answers its health endpoint
+func check(stdout, stderr io.Writer) int {
+	fmt.Fprintf(stdout, "vrcvideocacher-stub %s\n", version)
+
+	client := &http.Client{Timeout: checkTimeout}
+	resp, err := client.Get(serverURL + "/api/health")
+	if err != nil {
+		return report(stderr, exitNotRunning, msgNotRunning, fmt.Errorf("%w %w", ErrConnection, err), "")
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return report(stderr, exitServerError, msgServerError, fmt.Errorf("%w: health check returned status %d", ErrServerError, resp.StatusCode), "")
+	}
+	return exitOK
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	t.Setenv(langEnv, "en")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/health", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	oldServerURL := serverURL
+	defer func() { serverURL = oldServerURL }()
+
+	serverURL = server.URL
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitOK, check(&stdout, &stderr))
+	assert.Equal(t, "vrcvideocacher-stub "+version+"\n", stdout.String())
+	assert.Empty(t, stderr.String())
+
+	serverURL = "http://localhost:1"
+	stdout.Reset()
+	assert.Equal(t, exitNotRunning, check(&stdout, &stderr))
+	assert.Contains(t, stderr.String(), message("en", msgNotRunning))
+}
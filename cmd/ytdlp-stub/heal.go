@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	// healAfterFailures is how many connection failures in a row it takes
+	// before the stub restores the original yt-dlp.exe
+	healAfterFailures = 3
+
+	// healAfterIdle restores the original when the server has been
+	// unreachable this long even though the app is still installed
+	healAfterIdle = 7 * 24 * time.Hour
+)
+
+// Files next to the stub, named after it like the patcher names them
+const (
+	backupSuffix   = ".bkp"
+	manifestSuffix = ".patch.json"
+	failuresSuffix = ".failures"
+	oldStubSuffix  = ".old"
+)
+
+// patchManifest is the part of the patcher's manifest the stub reads
+type patchManifest struct {
+	// App is the program that installed the stub
+	App string `json:"app"`
+}
+
+// failures are the connection failures since the server was last reached
+type failures struct {
+	Count int       `json:"count"`
+	First time.Time `json:"first"`
+}
+
+// recordFailure counts a connection failure and reports whether the stub
+// should restore the original yt-dlp.exe: after healAfterFailures failures
+// once the app that installed it is gone, or the server stayed unreachable
+// for healAfterIdle. Without a backup there is nothing to restore
+func recordFailure(exePath string, now time.Time) bool {
+	if _, err := os.Stat(exePath + backupSuffix); err != nil {
+		return false
+	}
+
+	var f failures
+	if data, err := os.ReadFile(exePath + failuresSuffix); err == nil {
+		json.Unmarshal(data, &f) // A corrupt file restarts the count
+	}
+	if f.Count == 0 {
+		f.First = now
+	}
+	f.Count++
+	if data, err := json.Marshal(f); err == nil {
+		os.WriteFile(exePath+failuresSuffix, data, 0644)
+	}
+
+	if f.Count < healAfterFailures {
+		return false
+	}
+	return appRemoved(exePath) || now.Sub(f.First) >= healAfterIdle
+}
+
+// clearFailures resets the failure count once the server answers
+func clearFailures(exePath string) {
+	os.Remove(exePath + failuresSuffix)
+}
+
+// appRemoved reports whether the app recorded in the patch manifest no
+// longer exists
+func appRemoved(exePath string) bool {
+	data, err := os.ReadFile(exePath + manifestSuffix)
+	if err != nil {
+		return false
+	}
+
+	var m patchManifest
+	if err := json.Unmarshal(data, &m); err != nil || m.App == "" {
+		return false
+	}
+
+	_, err = os.Stat(m.App)
+	return errors.Is(err, os.ErrNotExist)
+}
+
+// restoreOriginal puts the backed up yt-dlp.exe back in place of the stub
+// The running stub can be renamed but not deleted on Windows, so it is moved
+// aside and removed by the patcher the next time it patches
+func restoreOriginal(exePath string) error {
+	oldPath := exePath + oldStubSuffix
+	os.Remove(oldPath) // Left by an earlier restore
+
+	if err := os.Rename(exePath, oldPath); err != nil {
+		return err
+	}
+	if err := os.Rename(exePath+backupSuffix, exePath); err != nil {
+		os.Rename(oldPath, exePath)
+		return err
+	}
+
+	os.Remove(exePath + manifestSuffix)
+	clearFailures(exePath)
+	os.Remove(oldPath) // Fails while running on Windows
+	return nil
+}
+
+// runOriginal runs the restored yt-dlp.exe with the stub's arguments, so
+// the playback that found the server gone still works
+func runOriginal(exePath string, args []string) int {
+	cmd := exec.Command(exePath, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return 1
+	}
+	return 0
+}
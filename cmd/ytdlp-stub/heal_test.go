@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupPatched writes a stub with a backup and a manifest naming app
+func setupPatched(t *testing.T, app string) string {
+	dir := t.TempDir()
+	exePath := filepath.Join(dir, "yt-dlp.exe")
+	require.NoError(t, os.WriteFile(exePath, []byte("stub"), 0644))
+	require.NoError(t, os.WriteFile(exePath+backupSuffix, []byte("original"), 0644))
+
+	data, err := json.Marshal(patchManifest{App: app})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(exePath+manifestSuffix, data, 0644))
+	return exePath
+}
+
+func TestRecordFailure_AppRemoved(t *testing.T) {
+	exePath := setupPatched(t, filepath.Join(t.TempDir(), "vrcvideocacher.exe"))
+	now := time.Now()
+
+	for i := 1; i < healAfterFailures; i++ {
+		assert.False(t, recordFailure(exePath, now))
+	}
+	assert.True(t, recordFailure(exePath, now))
+
+	// Reaching the server starts over
+	clearFailures(exePath)
+	assert.False(t, recordFailure(exePath, now))
+}
+
+func TestRecordFailure_AppInstalled(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "vrcvideocacher.exe")
+	require.NoError(t, os.WriteFile(app, []byte("app"), 0644))
+	exePath := setupPatched(t, app)
+	now := time.Now()
+
+	// The app is only closed
+	for i := 0; i < healAfterFailures+2; i++ {
+		assert.False(t, recordFailure(exePath, now))
+	}
+
+	// ...but has not been started for a long time
+	assert.True(t, recordFailure(exePath, now.Add(healAfterIdle)))
+}
+
+func TestRecordFailure_NoBackup(t *testing.T) {
+	exePath := filepath.Join(t.TempDir(), "yt-dlp.exe")
+	require.NoError(t, os.WriteFile(exePath, []byte("stub"), 0644))
+
+	for i := 0; i < healAfterFailures; i++ {
+		assert.False(t, recordFailure(exePath, time.Now()))
+	}
+	assert.NoFileExists(t, exePath+failuresSuffix)
+}
+
+func TestRestoreOriginal(t *testing.T) {
+	exePath := setupPatched(t, "")
+	recordFailure(exePath, time.Now())
+	require.NoError(t, os.Chmod(exePath, 0444))
+
+	require.NoError(t, restoreOriginal(exePath))
+
+	data, err := os.ReadFile(exePath)
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(data))
+	assert.NoFileExists(t, exePath+backupSuffix)
+	assert.NoFileExists(t, exePath+manifestSuffix)
+	assert.NoFileExists(t, exePath+failuresSuffix)
+	assert.NoFileExists(t, exePath+oldStubSuffix)
+
+	// Without a backup the stub stays
+	assert.Error(t, restoreOriginal(exePath))
+	data, _ = os.ReadFile(exePath)
+	assert.Equal(t, "original", string(data))
+}
+
+func TestRunOriginal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as yt-dlp")
+	}
+
+	exePath := filepath.Join(t.TempDir(), "yt-dlp.exe")
+	require.NoError(t, os.WriteFile(exePath, []byte("#!/bin/sh\necho \"original $1\"\nexit 3\n"), 0755))
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	exitCode := runOriginal(exePath, []string{"https://example.com/video.mp4"})
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	assert.Equal(t, 3, exitCode)
+	var buf [1024]byte
+	n, _ := r.Read(buf[:])
+	assert.Equal(t, "original https://example.com/video.mp4\n", string(buf[:n]))
+}
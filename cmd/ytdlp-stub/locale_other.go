@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// userLocale returns the user's locale from the environment, e.g. "ja_JP.UTF-8"
+func userLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetUserDefaultLocaleName = syscall.NewLazyDLL("kernel32.dll").NewProc("GetUserDefaultLocaleName")
+
+// localeNameMaxLength is LOCALE_NAME_MAX_LENGTH
+const localeNameMaxLength = 85
+
+// userLocale returns the user's Windows locale, e.g. "ja-JP"
+func userLocale() string {
+	buf := make([]uint16, localeNameMaxLength)
+	ret, _, _ := procGetUserDefaultLocaleName.Call(uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	if ret == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf)
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -8,37 +11,78 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 )
 
 var (
 	serverURL       = "http://127.0.0.1:9696"
 	ErrNoURL        = errors.New("no URL found in arguments")
 	ErrServerError  = errors.New("server returned error")
+	ErrBlocked      = errors.New("server blocked the video")
+	ErrConnection   = errors.New("connection refused - is VRCVideoCacher running?")
 )
 
+// version is sent to the server so it can report which stub is in use
+// Set at build time with -ldflags "-X main.version=..."
+var version = "0.1.0"
+
 func main() {
 	os.Exit(run(os.Args[1:]))
 }
 
 // run executes the stub logic and returns exit code
 func run(args []string) int {
+	if len(args) == 1 && args[0] == checkFlag {
+		return check(os.Stdout, os.Stderr)
+	}
+
 	// Parse arguments
 	videoURL, avPro, source, err := parseArgs(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		return 1
+		return report(os.Stderr, exitNoURL, msgNoURL, err, "")
+	}
+
+	// ChilloutVR runs yt-dlp like VRChat; tell it apart by install location
+	exe, exeErr := os.Executable()
+	if exeErr == nil {
+		if pathSource, ok := sourceFromPath(exe); ok {
+			source = pathSource
+		}
 	}
 
-	// Make request to local server
-	response, err := makeRequest(videoURL, avPro, source)
+	// Make request to local server, tagged so this playback can be found
+	// in the server logs
+	requestID := newRequestID()
+	response, err := makeRequest(videoURL, avPro, source, requestID, argsHash(args))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		return 1
+		switch {
+		case errors.Is(err, ErrConnection):
+			// A stub whose app was removed would break playback for good, so
+			// put the original yt-dlp.exe back and let it handle this video
+			if exeErr == nil && recordFailure(exe, time.Now()) {
+				if err := restoreOriginal(exe); err != nil {
+					return report(os.Stderr, exitFailed, msgRestoreFailed, err, requestID)
+				}
+				fmt.Fprintln(os.Stderr, message(language(), msgRestored))
+				return runOriginal(exe, args)
+			}
+			return report(os.Stderr, exitNotRunning, msgNotRunning, err, requestID)
+		case errors.Is(err, ErrBlocked):
+			return report(os.Stderr, exitBlocked, msgBlocked, nil, requestID)
+		case errors.Is(err, ErrServerError):
+			return report(os.Stderr, exitServerError, msgServerError, err, requestID)
+		default:
+			fmt.Fprintf(os.Stderr, "ERROR: %v (request ID %s)\n", err, requestID)
+			return exitFailed
+		}
+	}
+	if exeErr == nil {
+		clearFailures(exe)
 	}
 
 	// Output response
 	fmt.Println(response)
-	return 0
+	return exitOK
 }
 
 // parseArgs parses command line arguments
@@ -75,8 +119,33 @@ func parseArgs(args []string) (string, bool, string, error) {
 	return videoURL, avPro, source, nil
 }
 
+// sourceFromPath returns the source of a stub installed in a game directory
+// that cannot be told apart by its arguments
+func sourceFromPath(exePath string) (string, bool) {
+	for _, dir := range strings.FieldsFunc(exePath, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if strings.EqualFold(dir, "ChilloutVR") {
+			return "chilloutvr", true
+		}
+	}
+	return "", false
+}
+
+// newRequestID returns a random ID for the X-Request-ID header
+func newRequestID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// argsHash returns a short hash of the yt-dlp arguments, so the server can
+// tell invocations apart without logging them
+func argsHash(args []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(args, "\x00")))
+	return hex.EncodeToString(sum[:6])
+}
+
 // makeRequest sends request to local server
-func makeRequest(videoURL string, avPro bool, source string) (string, error) {
+func makeRequest(videoURL string, avPro bool, source, requestID, argsHash string) (string, error) {
 	// Build request URL
 	reqURL := fmt.Sprintf("%s/api/getvideo?url=%s&avpro=%t&source=%s",
 		serverURL,
@@ -86,9 +155,17 @@ func makeRequest(videoURL string, avPro bool, source string) (string, error) {
 	)
 
 	// Make HTTP request
-	resp, err := http.Get(reqURL)
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("connection refused - is VRCVideoCacher running? %w", err)
+		return "", err
+	}
+	req.Header.Set("X-Request-ID", requestID)
+	req.Header.Set("X-Stub-Version", version)
+	req.Header.Set("X-Stub-Args", argsHash)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w %w", ErrConnection, err)
 	}
 	defer resp.Body.Close()
 
@@ -99,6 +176,9 @@ func makeRequest(videoURL string, avPro bool, source string) (string, error) {
 	}
 
 	// Check status code
+	if resp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("%w: %s", ErrBlocked, strings.TrimSpace(string(body)))
+	}
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("%w: %s", ErrServerError, string(body))
 	}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -78,6 +80,15 @@ func TestParseArgs(t *testing.T) {
 	}
 }
 
+func TestSourceFromPath(t *testing.T) {
+	source, ok := sourceFromPath(`C:\Program Files (x86)\Steam\steamapps\common\ChilloutVR\ChilloutVR_Data\StreamingAssets\yt-dlp.exe`)
+	assert.True(t, ok)
+	assert.Equal(t, "chilloutvr", source)
+
+	_, ok = sourceFromPath(`C:\Users\user\AppData\LocalLow\VRChat\VRChat\Tools\yt-dlp.exe`)
+	assert.False(t, ok)
+}
+
 func TestMakeRequest(t *testing.T) {
 	// Create mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -85,6 +96,9 @@ func TestMakeRequest(t *testing.T) {
 		assert.Equal(t, "https://example.com/video.mp4", r.URL.Query().Get("url"))
 		assert.Equal(t, "true", r.URL.Query().Get("avpro"))
 		assert.Equal(t, "vrchat", r.URL.Query().Get("source"))
+		assert.Equal(t, "abc123", r.Header.Get("X-Request-ID"))
+		assert.Equal(t, version, r.Header.Get("X-Stub-Version"))
+		assert.Equal(t, "def456", r.Header.Get("X-Stub-Args"))
 
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("http://localhost:9696/cached_video.mp4"))
@@ -96,18 +110,25 @@ func TestMakeRequest(t *testing.T) {
 	serverURL = server.URL
 	defer func() { serverURL = oldServerURL }()
 
-	response, err := makeRequest("https://example.com/video.mp4", true, "vrchat")
+	response, err := makeRequest("https://example.com/video.mp4", true, "vrchat", "abc123", "def456")
 	require.NoError(t, err)
 	assert.Equal(t, "http://localhost:9696/cached_video.mp4", response)
 }
 
+func TestArgsHash(t *testing.T) {
+	a := argsHash([]string{"-f", "best", "https://www.youtube.com/watch?v=TEST"})
+	assert.Len(t, a, 12)
+	assert.Equal(t, a, argsHash([]string{"-f", "best", "https://www.youtube.com/watch?v=TEST"}))
+	assert.NotEqual(t, a, argsHash([]string{"-J", "https://www.youtube.com/watch?v=TEST"}))
+}
+
 func TestMakeRequestError(t *testing.T) {
 	// Use invalid server URL
 	oldServerURL := serverURL
 	serverURL = "http://localhost:1" // Invalid port
 	defer func() { serverURL = oldServerURL }()
 
-	_, err := makeRequest("https://example.com/video.mp4", true, "vrchat")
+	_, err := makeRequest("https://example.com/video.mp4", true, "vrchat", "abc123", "def456")
 	require.Error(t, err)
 }
 
@@ -123,7 +144,7 @@ func TestMakeRequest500Error(t *testing.T) {
 	serverURL = server.URL
 	defer func() { serverURL = oldServerURL }()
 
-	_, err := makeRequest("https://example.com/video.mp4", true, "vrchat")
+	_, err := makeRequest("https://example.com/video.mp4", true, "vrchat", "abc123", "def456")
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "Server error")
 }
@@ -161,7 +182,7 @@ func TestRunWithValidArgs(t *testing.T) {
 
 func TestRunWithNoArgs(t *testing.T) {
 	exitCode := run([]string{})
-	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, exitNoURL, exitCode)
 }
 
 func TestRunWithConnectionError(t *testing.T) {
@@ -170,5 +191,26 @@ func TestRunWithConnectionError(t *testing.T) {
 	defer func() { serverURL = oldServerURL }()
 
 	exitCode := run([]string{"https://example.com/video.mp4"})
-	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, exitNotRunning, exitCode)
+}
+
+func FuzzParseArgs(f *testing.F) {
+	f.Add("--no-check-certificate\x00-f\x00(mp4/best)[protocol^=http]\x00https://www.youtube.com/watch?v=VIDEO")
+	f.Add("-J\x00HTTPS://youtu.be/VIDEO")
+	f.Add("\x00http")
+
+	f.Fuzz(func(t *testing.T, joined string) {
+		args := strings.Split(joined, "\x00")
+		videoURL, _, source, err := parseArgs(args)
+		if err != nil {
+			return
+		}
+
+		if videoURL == "" || !slices.Contains(args, videoURL) {
+			t.Errorf("parseArgs(%q) returned URL %q not among the arguments", args, videoURL)
+		}
+		if source != "vrchat" && source != "resonite" {
+			t.Errorf("parseArgs(%q) returned unknown source %q", args, source)
+		}
+	})
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Exit codes of the stub, referenced in docs/API.md
+const (
+	exitOK          = 0
+	exitFailed      = 1 // Unexpected error, including a failed self-restore
+	exitNoURL       = 2 // No video URL in the arguments
+	exitNotRunning  = 3 // VRCVideoCacher is not running
+	exitBlocked     = 4 // The video is blocked or rejected by crasher protection
+	exitServerError = 5 // VRCVideoCacher failed to process the video
+)
+
+// Messages shown to players, who see the stub's stderr in VRChat
+const (
+	msgNoURL         = "no-url"
+	msgNotRunning    = "not-running"
+	msgBlocked       = "blocked"
+	msgServerError   = "server-error"
+	msgRestored      = "restored"
+	msgRestoreFailed = "restore-failed"
+)
+
+// langEnv overrides the language of the messages, e.g. "ja"
+const langEnv = "VRCVIDEOCACHER_LANG"
+
+// messages holds the messages per language; English is the fallback
+var messages = map[string]map[string]string{
+	"en": {
+		msgNoURL:         "No video URL given to yt-dlp",
+		msgNotRunning:    "VRCVideoCacher not running - start the app or run 'vrcvideocacher server'",
+		msgBlocked:       "This video is blocked by VRCVideoCacher",
+		msgServerError:   "VRCVideoCacher could not load this video - check its logs",
+		msgRestored:      "VRCVideoCacher is gone, restored the original yt-dlp.exe",
+		msgRestoreFailed: "VRCVideoCacher is gone and the original yt-dlp.exe could not be restored - verify the game files",
+	},
+	"ja": {
+		msgNoURL:         "yt-dlp に動画の URL が渡されていません",
+		msgNotRunning:    "VRCVideoCacher が起動していません - アプリを起動するか 'vrcvideocacher server' を実行してください",
+		msgBlocked:       "この動画は VRCVideoCacher でブロックされています",
+		msgServerError:   "VRCVideoCacher がこの動画を読み込めませんでした - ログを確認してください",
+		msgRestored:      "VRCVideoCacher が見つからないため、元の yt-dlp.exe を復元しました",
+		msgRestoreFailed: "VRCVideoCacher が見つからず、元の yt-dlp.exe を復元できませんでした - ゲームファイルの整合性を確認してください",
+	},
+}
+
+// language returns the language of the messages: the override, else the
+// user's locale if there are messages for it, else English
+func language() string {
+	for _, locale := range []string{os.Getenv(langEnv), userLocale()} {
+		lang := strings.ToLower(locale)
+		if i := strings.IndexAny(lang, "-_."); i >= 0 {
+			lang = lang[:i]
+		}
+		if _, ok := messages[lang]; ok {
+			return lang
+		}
+	}
+	return "en"
+}
+
+// message returns the text of key in lang
+func message(lang, key string) string {
+	if text, ok := messages[lang][key]; ok {
+		return text
+	}
+	return messages["en"][key]
+}
+
+// report writes the message for key to w, followed by the error that
+// caused it for the logs, and returns code
+func report(w io.Writer, code int, key string, err error, requestID string) int {
+	text := message(language(), key)
+	if requestID != "" {
+		text += fmt.Sprintf(" (request ID %s)", requestID)
+	}
+	fmt.Fprintf(w, "ERROR: %s\n", text)
+	if err != nil {
+		fmt.Fprintf(w, "  %v\n", err)
+	}
+	return code
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguage(t *testing.T) {
+	t.Setenv(langEnv, "ja")
+	assert.Equal(t, "ja", language())
+
+	t.Setenv(langEnv, "JA-jp")
+	assert.Equal(t, "ja", language())
+
+	if runtime.GOOS != "windows" {
+		// Unknown override falls back to the locale
+		t.Setenv(langEnv, "xx")
+		t.Setenv("LC_ALL", "")
+		t.Setenv("LC_MESSAGES", "")
+		t.Setenv("LANG", "ja_JP.UTF-8")
+		assert.Equal(t, "ja", language())
+
+		t.Setenv("LANG", "de_DE.UTF-8")
+		assert.Equal(t, "en", language())
+	}
+}
+
+func TestMessages_Complete(t *testing.T) {
+	for lang, texts := range messages {
+		assert.Len(t, texts, len(messages["en"]), lang)
+		for key := range messages["en"] {
+			assert.NotEmpty(t, texts[key], "%s %s", lang, key)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	t.Setenv(langEnv, "en")
+
+	var buf bytes.Buffer
+	code := report(&buf, exitNotRunning, msgNotRunning, errors.New("dial tcp: refused"), "abc123")
+	assert.Equal(t, exitNotRunning, code)
+	assert.Equal(t, "ERROR: VRCVideoCacher not running - start the app or run 'vrcvideocacher server' (request ID abc123)\n  dial tcp: refused\n", buf.String())
+}
+
+func TestRunExitCodes(t *testing.T) {
+	tests := []struct {
+		status int
+		want   int
+	}{
+		{http.StatusForbidden, exitBlocked},
+		{http.StatusInternalServerError, exitServerError},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", tt.status)
+		}))
+
+		oldServerURL := serverURL
+		serverURL = server.URL
+		assert.Equal(t, tt.want, run([]string{"https://www.youtube.com/watch?v=TEST"}))
+		serverURL = oldServerURL
+		server.Close()
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"vrcvideocacher/internal/patcher"
+)
+
+// actionResult is the response of the one-shot action endpoints
+// Changed is false when the action was a no-op, so repeating an action is
+// safe
+type actionResult struct {
+	Action  string `json:"action"`
+	Changed bool   `json:"changed"`
+	Message string `json:"message"`
+}
+
+// handleActionToggleCache handles POST /api/actions/toggle-cache
+// With ?enabled=true|false caching is set to that state (idempotent);
+// without it caching is toggled
+func (s *Server) handleActionToggleCache(w http.ResponseWriter, r *http.Request) {
+	var want *bool
+	if v := r.URL.Query().Get("enabled"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid enabled value", http.StatusBadRequest)
+			return
+		}
+		want = &enabled
+	}
+
+	s.mu.Lock()
+	enabled := !s.caching
+	if want != nil {
+		enabled = *want
+	}
+	changed := s.caching != enabled
+	s.caching = enabled
+	s.mu.Unlock()
+
+	message := "Caching disabled"
+	if enabled {
+		message = "Caching enabled"
+	}
+	s.writeAction(w, r, actionResult{Action: "toggle-cache", Changed: changed, Message: message})
+}
+
+// handleActionPatch handles POST /api/actions/patch
+// Patches ?target= (default vrchat), doing nothing if it is already patched
+// and refusing unknown binaries
+func (s *Server) handleActionPatch(w http.ResponseWriter, r *http.Request) {
+	p, err := s.getPatcher()
+	if err != nil {
+		http.Error(w, "Patching not available", http.StatusNotImplemented)
+		return
+	}
+
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		target = patcher.TargetVRChat
+	}
+
+	if v, err := p.VerifyTarget(target); err == nil && v.Status == patcher.BinaryPatched {
+		s.writeAction(w, r, actionResult{Action: "patch", Message: target + " already patched"})
+		return
+	}
+
+	if err := p.SafePatchTarget(target); err != nil {
+		s.auditAction(r, "patch", "failed: "+err.Error())
+		switch {
+		case errors.Is(err, patcher.ErrUnknownTarget):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, patcher.ErrTargetNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, patcher.ErrUnknownBinary), errors.Is(err, patcher.ErrUnverifiedBinary):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.writeAction(w, r, actionResult{Action: "patch", Changed: true, Message: target + " patched"})
+}
+
+// handleActionUnpatch handles POST /api/actions/unpatch
+// Restores the original yt-dlp of ?target= (default vrchat); targets that
+// are not patched are left alone
+func (s *Server) handleActionUnpatch(w http.ResponseWriter, r *http.Request) {
+	p, err := s.getPatcher()
+	if err != nil {
+		http.Error(w, "Patching not available", http.StatusNotImplemented)
+		return
+	}
+
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		target = patcher.TargetVRChat
+	}
+
+	if v, err := p.VerifyTarget(target); err == nil && v.Status != patcher.BinaryPatched {
+		s.writeAction(w, r, actionResult{Action: "unpatch", Message: target + " not patched"})
+		return
+	}
+
+	if err := p.UnpatchTarget(target); err != nil {
+		s.auditAction(r, "unpatch", "failed: "+err.Error())
+		switch {
+		case errors.Is(err, patcher.ErrUnknownTarget):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, patcher.ErrTargetNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.writeAction(w, r, actionResult{Action: "unpatch", Changed: true, Message: target + " restored"})
+}
+
+// handleActionPauseDownloads handles POST /api/actions/pause-downloads
+// With ?paused=true|false queued downloads are paused or resumed
+// (idempotent); without it pausing is toggled. Active downloads finish
+func (s *Server) handleActionPauseDownloads(w http.ResponseWriter, r *http.Request) {
+	paused := !s.downloader.IsPaused()
+	if v := r.URL.Query().Get("paused"); v != "" {
+		p, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid paused value", http.StatusBadRequest)
+			return
+		}
+		paused = p
+	}
+
+	changed := s.downloader.IsPaused() != paused
+	s.downloader.SetPaused(paused)
+
+	message := "Downloads resumed"
+	if paused {
+		message = "Downloads paused"
+	}
+	s.writeAction(w, r, actionResult{Action: "pause-downloads", Changed: changed, Message: message})
+}
+
+// handleActionClearQueue handles POST /api/actions/clear-queue
+// Queued downloads are removed; downloads already running finish
+func (s *Server) handleActionClearQueue(w http.ResponseWriter, r *http.Request) {
+	n := s.downloader.ClearQueue()
+
+	s.writeAction(w, r, actionResult{
+		Action:  "clear-queue",
+		Changed: n > 0,
+		Message: fmt.Sprintf("Removed %d queued downloads", n),
+	})
+}
+
+// writeAction records an action in the log and writes its result
+func (s *Server) writeAction(w http.ResponseWriter, r *http.Request, result actionResult) {
+	s.auditAction(r, result.Action, result.Message)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// auditAction logs who triggered an action and its outcome
+func (s *Server) auditAction(r *http.Request, action, outcome string) {
+	s.reqLog(r).Info("Action", "action", action, "remote", r.RemoteAddr, "userAgent", r.UserAgent(), "outcome", outcome)
+}
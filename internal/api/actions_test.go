@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/patcher"
+	"vrcvideocacher/pkg/models"
+)
+
+// postAction sends a POST to an action endpoint and decodes the result
+func postAction(t *testing.T, server *Server, target string) (int, actionResult) {
+	req := httptest.NewRequest("POST", target, nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var result actionResult
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	}
+	return w.Code, result
+}
+
+func TestActionToggleCache(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+
+	// Toggle without a state
+	code, result := postAction(t, server, "/api/actions/toggle-cache")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, result.Changed)
+	assert.False(t, server.IsCachingEnabled())
+
+	// Setting an explicit state is idempotent
+	for _, wantChanged := range []bool{true, false} {
+		code, result = postAction(t, server, "/api/actions/toggle-cache?enabled=true")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, wantChanged, result.Changed)
+		assert.True(t, server.IsCachingEnabled())
+	}
+
+	code, _ = postAction(t, server, "/api/actions/toggle-cache?enabled=maybe")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// Actions are audit logged
+	logs := strings.Join(server.logs.Lines(), "\n")
+	assert.Contains(t, logs, "action=toggle-cache")
+}
+
+func TestActionClearQueue(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+	code, result := postAction(t, server, "/api/actions/clear-queue")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, result.Changed)
+	assert.Equal(t, "clear-queue", result.Action)
+}
+
+func TestActionPatch(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+
+	code, _ := postAction(t, server, "/api/actions/patch")
+	assert.Equal(t, http.StatusNotImplemented, code)
+
+	server.SetPatcher(patcher.NewPatcher([]byte("stub")))
+	code, _ = postAction(t, server, "/api/actions/patch?target=nope")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestActionUnpatch(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+
+	code, _ := postAction(t, server, "/api/actions/unpatch")
+	assert.Equal(t, http.StatusNotImplemented, code)
+
+	server.SetPatcher(patcher.NewPatcher([]byte("stub")))
+	code, _ = postAction(t, server, "/api/actions/unpatch?target=nope")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestActionPauseDownloads(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+
+	// Toggle without a state
+	code, result := postAction(t, server, "/api/actions/pause-downloads")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, result.Changed)
+	assert.True(t, server.downloader.IsPaused())
+
+	// Setting an explicit state is idempotent
+	for _, wantChanged := range []bool{true, false} {
+		code, result = postAction(t, server, "/api/actions/pause-downloads?paused=false")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, wantChanged, result.Changed)
+		assert.False(t, server.downloader.IsPaused())
+	}
+
+	code, _ = postAction(t, server, "/api/actions/pause-downloads?paused=maybe")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestActionsCrossOrigin(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+
+	// A form on another site posting to the actions is refused, so nothing
+	// changes even though the browser would hide the response anyway
+	for _, target := range []string{
+		"/api/actions/toggle-cache?enabled=false",
+		"/api/actions/patch",
+		"/api/actions/unpatch",
+		"/api/actions/pause-downloads?paused=true",
+		"/api/actions/clear-queue",
+	} {
+		req := httptest.NewRequest("POST", target, strings.NewReader("x=1"))
+		req.Header.Set("Origin", "https://evil.example")
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code, target)
+	}
+	assert.True(t, server.IsCachingEnabled())
+	assert.False(t, server.downloader.IsPaused())
+
+	// Stream Deck plugins and hotkey tools send no Origin
+	code, _ := postAction(t, server, "/api/actions/toggle-cache?enabled=false")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, server.IsCachingEnabled())
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+
+	"vrcvideocacher/internal/logger"
+)
+
+// ignoredURLParams are query parameters that do not change which video a
+// link points to, such as start times and share tracking
+var ignoredURLParams = []string{"t", "start", "si", "feature", "pp", "ab_channel"}
+
+// normalizeURL returns the alias key of a video URL: scheme and host are
+// lowercased, any "www." and "m." host prefixes, the fragment, trailing slashes
+// and ignoredURLParams are dropped, and the remaining query is sorted, so
+// timestamped and shared variants of a link map to the same key
+func normalizeURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	host := strings.ToLower(u.Host)
+	for {
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(host, "www."), "m.")
+		if trimmed == host {
+			break
+		}
+		host = trimmed
+	}
+
+	query := u.Query()
+	for name := range query {
+		if isIgnoredURLParam(name) {
+			query.Del(name)
+		}
+	}
+
+	normalized := url.URL{
+		Scheme:   strings.ToLower(u.Scheme),
+		Host:     host,
+		Path:     strings.TrimRight(u.Path, "/"),
+		RawQuery: query.Encode(),
+	}
+	return normalized.String()
+}
+
+// isIgnoredURLParam reports whether a query parameter is dropped by
+// normalizeURL
+func isIgnoredURLParam(name string) bool {
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, "utm_") {
+		return true
+	}
+	for _, ignored := range ignoredURLParams {
+		if name == ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// recordAlias remembers videoURL as an alias of the cached video videoID
+// Canonical watch URLs are not recorded, they always resolve
+func (s *Server) recordAlias(videoURL, videoID string) {
+	alias := normalizeURL(videoURL)
+	if alias == normalizeURL("https://www.youtube.com/watch?v="+videoID) {
+		return
+	}
+
+	if err := s.cache.AddAlias(alias, videoID); err != nil {
+		s.log.Error("Failed to record alias", "alias", alias, "video", videoID, logger.Err(err))
+	}
+}
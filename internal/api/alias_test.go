@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://youtu.be/VIDEO?t=42", "https://youtu.be/VIDEO"},
+		{"https://youtu.be/VIDEO?si=share&feature=shared", "https://youtu.be/VIDEO"},
+		{"HTTPS://WWW.YouTube.com/watch?v=VIDEO&t=1m2s#comments", "https://youtube.com/watch?v=VIDEO"},
+		{"https://m.youtube.com/watch?v=VIDEO&list=PL1", "https://youtube.com/watch?list=PL1&v=VIDEO"},
+		{"https://example.com/event/?utm_source=discord", "https://example.com/event"},
+		{"not a url", "not a url"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, normalizeURL(tt.url), tt.url)
+	}
+}
+
+func TestAliasResolution(t *testing.T) {
+	dir := t.TempDir()
+	cacheManager := cache.NewManager(dir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "VIDEO.mp4"), []byte("video"), 0644))
+	require.NoError(t, cacheManager.AddEntry("VIDEO", "VIDEO.mp4"))
+
+	server := NewServer(models.DefaultConfig(), cacheManager)
+
+	getVideo := func(videoURL string) string {
+		req := httptest.NewRequest("GET", "/api/getvideo?url="+videoURL, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// Links seen for a cached video are recorded by their normalized form
+	assert.Contains(t, getVideo("https://youtu.be/VIDEO?t=30"), "/VIDEO.mp4?v=")
+	id, ok := cacheManager.ResolveAlias("https://youtu.be/VIDEO")
+	require.True(t, ok)
+	assert.Equal(t, "VIDEO", id)
+
+	// Canonical links are not recorded
+	getVideo("https://www.youtube.com/watch?v=VIDEO")
+	assert.Len(t, cacheManager.ListAliases(), 1)
+
+	// Manual aliases let any link resolve to the entry
+	body, _ := json.Marshal(aliasRequest{Alias: "https://example.com/event?utm_source=x", Target: "https://youtu.be/VIDEO"})
+	req := httptest.NewRequest("POST", "/api/cache/aliases", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	assert.Contains(t, getVideo("https://example.com/event"), "/VIDEO.mp4?v=")
+
+	req = httptest.NewRequest("GET", "/api/cache/aliases", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	var list struct {
+		Aliases []cache.Alias `json:"aliases"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.Aliases, 2)
+
+	req = httptest.NewRequest("DELETE", "/api/cache/aliases?alias=https://example.com/event", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, getVideo("https://example.com/event"))
+
+	// Unknown targets and aliases
+	body, _ = json.Marshal(aliasRequest{Alias: "https://example.com/other", Target: "MISSING"})
+	req = httptest.NewRequest("POST", "/api/cache/aliases", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = httptest.NewRequest("DELETE", "/api/cache/aliases?alias=https://example.com/event", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func FuzzNormalizeURL(f *testing.F) {
+	f.Add("https://www.youtube.com/watch?v=VIDEO&t=42&si=abc")
+	f.Add("HTTPS://M.YouTube.com/watch/?utm_source=x&v=VIDEO#t=1")
+	f.Add("https://m.www.example.com/a/")
+	f.Add("not a url")
+
+	f.Fuzz(func(t *testing.T, rawURL string) {
+		// Normalizing twice must give the same key, or aliases recorded
+		// from one form would not resolve from the other
+		once := normalizeURL(rawURL)
+		if twice := normalizeURL(once); twice != once {
+			t.Errorf("normalizeURL(%q) = %q, normalizing again gives %q", rawURL, once, twice)
+		}
+	})
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"vrcvideocacher/internal/logger"
+)
+
+const (
+	// blocklistFetchTimeout bounds the download of one subscribed blocklist
+	blocklistFetchTimeout = 30 * time.Second
+
+	// maxBlocklistSize bounds the size of one subscribed blocklist
+	maxBlocklistSize = 4 << 20
+
+	// blockSourceLocal is the match source of the configured BlockedURLs
+	blockSourceLocal = "local"
+)
+
+// blockList matches URLs that must not be played
+// An entry blocks every URL starting with it; YouTube entries also block the
+// other URL forms of the same video (youtu.be, embed, ...)
+type blockList struct {
+	entries  []string
+	videoIDs map[string]string
+}
+
+// parseBlockList reads a blocklist in the shared text format: one entry per
+// line, blank lines and lines starting with # or ! ignored
+func parseBlockList(r io.Reader) ([]string, error) {
+	var entries []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+
+	return entries, scanner.Err()
+}
+
+// newBlockList builds a block list from entries
+func newBlockList(entries []string) *blockList {
+	b := &blockList{
+		videoIDs: make(map[string]string),
+	}
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		b.entries = append(b.entries, entry)
+
+		if isYouTubeURL(entry) {
+			if id, err := extractYouTubeVideoID(entry); err == nil {
+				b.videoIDs[id] = entry
+			}
+		}
+	}
+
+	return b
+}
+
+// Match returns the entry blocking the URL, if any
+func (b *blockList) Match(urlStr string) (string, bool) {
+	for _, entry := range b.entries {
+		if strings.HasPrefix(urlStr, entry) {
+			return entry, true
+		}
+	}
+
+	if len(b.videoIDs) > 0 && isYouTubeURL(urlStr) {
+		if id, err := extractYouTubeVideoID(urlStr); err == nil {
+			if entry, ok := b.videoIDs[id]; ok {
+				return entry, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// blockMatch describes why a URL is blocked
+type blockMatch struct {
+	Entry  string `json:"entry"`
+	Source string `json:"source"`
+}
+
+// blocklistStatus is the state of a subscribed blocklist
+type blocklistStatus struct {
+	URL       string     `json:"url"`
+	Entries   int        `json:"entries"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// subscription is a remote blocklist and the entries last downloaded from it
+type subscription struct {
+	url       string
+	list      *blockList
+	updatedAt time.Time
+	err       error
+}
+
+// blocker combines the local BlockedURLs with subscribed community
+// blocklists, which are downloaded on Start and refreshed periodically
+// A subscription that fails to refresh keeps its previous entries
+type blocker struct {
+	mu       sync.RWMutex
+	local    *blockList
+	subs     []*subscription
+	interval time.Duration
+	client   *http.Client
+	log      *slog.Logger
+	kick     chan struct{}
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// newBlocker creates a blocker with no entries
+func newBlocker(log *slog.Logger) *blocker {
+	return &blocker{
+		local:    newBlockList(nil),
+		interval: 24 * time.Hour,
+		client:   &http.Client{Timeout: blocklistFetchTimeout},
+		log:      log,
+		kick:     make(chan struct{}, 1),
+	}
+}
+
+// SetLocal replaces the local blocked entries
+func (b *blocker) SetLocal(entries []string) {
+	list := newBlockList(entries)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.local = list
+}
+
+// SetSubscriptions replaces the subscribed blocklists and their refresh
+// interval. Lists already downloaded are kept; new ones are fetched right away
+// when the blocker is running
+func (b *blocker) SetSubscriptions(urls []string, interval time.Duration) {
+	b.mu.Lock()
+
+	existing := make(map[string]*subscription, len(b.subs))
+	for _, sub := range b.subs {
+		existing[sub.url] = sub
+	}
+
+	added := false
+	subs := make([]*subscription, 0, len(urls))
+	for _, u := range urls {
+		if sub, ok := existing[u]; ok {
+			subs = append(subs, sub)
+			continue
+		}
+		subs = append(subs, &subscription{url: u, list: newBlockList(nil)})
+		added = true
+	}
+
+	b.subs = subs
+	if interval > 0 {
+		b.interval = interval
+	}
+	b.mu.Unlock()
+
+	if added {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start downloads the subscribed blocklists and refreshes them periodically
+func (b *blocker) Start() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		for {
+			// Subscriptions added before this refresh are covered by it
+			select {
+			case <-b.kick:
+			default:
+			}
+			b.Refresh(ctx)
+
+			b.mu.RLock()
+			timer := time.NewTimer(b.interval)
+			b.mu.RUnlock()
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			case <-b.kick:
+				timer.Stop()
+			}
+		}
+	}()
+}
+
+// Stop ends periodic refreshes
+func (b *blocker) Stop() {
+	b.mu.Lock()
+	cancel := b.cancel
+	b.cancel = nil
+	b.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		b.wg.Wait()
+	}
+}
+
+// Refresh downloads every subscribed blocklist
+func (b *blocker) Refresh(ctx context.Context) {
+	b.mu.RLock()
+	subs := slices.Clone(b.subs)
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		entries, err := b.fetch(ctx, sub.url)
+
+		b.mu.Lock()
+		sub.err = err
+		if err == nil {
+			sub.list = newBlockList(entries)
+			sub.updatedAt = time.Now()
+		}
+		b.mu.Unlock()
+
+		if err != nil {
+			b.log.Error("Failed to refresh blocklist", "url", sub.url, logger.Err(err))
+		} else {
+			b.log.Info("Loaded blocklist", "url", sub.url, "entries", len(entries))
+		}
+	}
+}
+
+// fetch downloads and parses a subscribed blocklist
+func (b *blocker) fetch(ctx context.Context, url string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return parseBlockList(io.LimitReader(resp.Body, maxBlocklistSize))
+}
+
+// Match reports whether a URL is blocked and by which list
+// Local entries are checked first
+func (b *blocker) Match(urlStr string) (blockMatch, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if entry, ok := b.local.Match(urlStr); ok {
+		return blockMatch{Entry: entry, Source: blockSourceLocal}, true
+	}
+
+	for _, sub := range b.subs {
+		if entry, ok := sub.list.Match(urlStr); ok {
+			return blockMatch{Entry: entry, Source: sub.url}, true
+		}
+	}
+
+	return blockMatch{}, false
+}
+
+// Subscriptions returns the state of the subscribed blocklists
+func (b *blocker) Subscriptions() []blocklistStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	statuses := make([]blocklistStatus, 0, len(b.subs))
+	for _, sub := range b.subs {
+		status := blocklistStatus{
+			URL:     sub.url,
+			Entries: len(sub.list.entries),
+		}
+		if !sub.updatedAt.IsZero() {
+			updatedAt := sub.updatedAt
+			status.UpdatedAt = &updatedAt
+		}
+		if sub.err != nil {
+			status.Error = sub.err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
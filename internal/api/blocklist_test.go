@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/config"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestBlockListMatch(t *testing.T) {
+	list := newBlockList([]string{
+		"https://www.youtube.com/watch?v=CRASHER",
+		"https://bad.example.com/",
+		"  ",
+	})
+
+	tests := []struct {
+		url     string
+		blocked bool
+	}{
+		{"https://www.youtube.com/watch?v=CRASHER", true},
+		{"https://youtu.be/CRASHER", true},
+		{"https://www.youtube.com/embed/CRASHER", true},
+		{"https://www.youtube.com/watch?v=CRASHER&t=10", true},
+		{"https://www.youtube.com/watch?v=FINE", false},
+		{"https://bad.example.com/video.mp4", true},
+		{"https://example.com/video.mp4", false},
+	}
+
+	for _, tt := range tests {
+		_, blocked := list.Match(tt.url)
+		assert.Equal(t, tt.blocked, blocked, tt.url)
+	}
+}
+
+func TestParseBlockList(t *testing.T) {
+	entries, err := parseBlockList(strings.NewReader("# Crashers\n\nhttps://youtu.be/A\n! comment\n  https://youtu.be/B  \n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://youtu.be/A", "https://youtu.be/B"}, entries)
+}
+
+func TestBlockerSubscriptions(t *testing.T) {
+	var fail atomic.Bool
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "gone", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("# Community list\nhttps://youtu.be/REMOTE\n"))
+	}))
+	defer remote.Close()
+
+	b := newBlocker(slog.New(slog.DiscardHandler))
+	b.SetLocal([]string{"https://youtu.be/LOCAL"})
+	b.SetSubscriptions([]string{remote.URL}, time.Hour)
+
+	_, blocked := b.Match("https://www.youtube.com/watch?v=REMOTE")
+	assert.False(t, blocked, "not downloaded yet")
+
+	b.Refresh(context.Background())
+
+	match, blocked := b.Match("https://www.youtube.com/watch?v=REMOTE")
+	assert.True(t, blocked)
+	assert.Equal(t, remote.URL, match.Source)
+
+	match, blocked = b.Match("https://www.youtube.com/watch?v=LOCAL")
+	assert.True(t, blocked)
+	assert.Equal(t, blockSourceLocal, match.Source)
+
+	// A failed refresh keeps the previous entries
+	fail.Store(true)
+	b.Refresh(context.Background())
+
+	statuses := b.Subscriptions()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, 1, statuses[0].Entries)
+	assert.NotNil(t, statuses[0].UpdatedAt)
+	assert.Contains(t, statuses[0].Error, "500")
+
+	_, blocked = b.Match("https://youtu.be/REMOTE")
+	assert.True(t, blocked)
+
+	// Unsubscribing drops the entries
+	b.SetSubscriptions(nil, time.Hour)
+	_, blocked = b.Match("https://youtu.be/REMOTE")
+	assert.False(t, blocked)
+}
+
+func TestBlockerStartFetchesNewSubscriptions(t *testing.T) {
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("https://youtu.be/REMOTE\n"))
+	}))
+	defer remote.Close()
+
+	b := newBlocker(slog.New(slog.DiscardHandler))
+	b.Start()
+	defer b.Stop()
+
+	b.SetSubscriptions([]string{remote.URL}, time.Hour)
+
+	assert.Eventually(t, func() bool {
+		_, blocked := b.Match("https://youtu.be/REMOTE")
+		return blocked
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestHandleGetVideoBlocked(t *testing.T) {
+	cfg := models.DefaultConfig()
+	cfg.BlockedURLs = []string{"https://www.youtube.com/watch?v=CRASHER"}
+	server := NewServer(cfg, cache.NewManager(t.TempDir(), 0))
+
+	req := httptest.NewRequest("GET", "/api/getvideo?url="+url.QueryEscape("https://youtu.be/CRASHER"), nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, resultBlocked, server.history.List()[0].Result)
+
+	// With a redirect configured the replacement video is served instead
+	cfg.BlockRedirect = "https://www.youtube.com/watch?v=SAFE"
+	require.NoError(t, server.SetConfig(cfg))
+
+	req = httptest.NewRequest("GET", "/api/getvideo?url="+url.QueryEscape("https://youtu.be/CRASHER"), nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, cfg.BlockRedirect, w.Body.String())
+}
+
+func TestHandleTestBlocklist(t *testing.T) {
+	cfg := models.DefaultConfig()
+	cfg.BlockedURLs = []string{"https://www.youtube.com/watch?v=CRASHER"}
+	server := NewServer(cfg, cache.NewManager(t.TempDir(), 0))
+
+	req := httptest.NewRequest("GET", "/api/blocklist/test?url="+url.QueryEscape("https://youtu.be/CRASHER"), nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, true, result["blocked"])
+	assert.Equal(t, blockSourceLocal, result["source"])
+	assert.Equal(t, "https://www.youtube.com/watch?v=CRASHER", result["entry"])
+
+	req = httptest.NewRequest("GET", "/api/blocklist/test?url="+url.QueryEscape("https://youtu.be/FINE"), nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"url":"https://youtu.be/FINE","blocked":false}`, w.Body.String())
+
+	req = httptest.NewRequest("GET", "/api/blocklist/test", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestBlocklistImportExport(t *testing.T) {
+	cfgMgr, err := config.NewManager(t.TempDir() + "/config.json")
+	require.NoError(t, err)
+
+	server := NewServer(cfgMgr.Get(), cache.NewManager(t.TempDir(), 0))
+	server.SetConfigManager(cfgMgr)
+
+	body := "# Shared list\nhttps://youtu.be/A\nhttps://youtu.be/B\nhttps://youtu.be/A\n"
+	req := httptest.NewRequest("POST", "/api/blocklist/import", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var result struct {
+		Added   int      `json:"added"`
+		Entries []string `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 2, result.Added)
+	assert.Equal(t, []string{"https://youtu.be/A", "https://youtu.be/B"}, cfgMgr.Get().BlockedURLs)
+
+	// Imported entries apply immediately
+	_, blocked := server.blocker.Match("https://www.youtube.com/watch?v=B")
+	assert.True(t, blocked)
+
+	req = httptest.NewRequest("GET", "/api/blocklist/export", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	entries, err := parseBlockList(w.Body)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://youtu.be/A", "https://youtu.be/B"}, entries)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"vrcvideocacher/pkg/models"
+)
+
+// bypassList matches URLs that must be passed through without any processing
+// Plain entries are domains matching the host and its subdomains; entries
+// prefixed with models.BypassRegexPrefix are regexes matched against the full URL
+type bypassList struct {
+	entries  []string
+	domains  []string
+	patterns []*regexp.Regexp
+}
+
+// newBypassList compiles a bypass list from config entries
+func newBypassList(entries []string) (*bypassList, error) {
+	b := &bypassList{
+		entries: append([]string{}, entries...),
+	}
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if pattern, ok := models.BypassRegex(entry); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bypass pattern %q: %w", entry, err)
+			}
+			b.patterns = append(b.patterns, re)
+			continue
+		}
+
+		b.domains = append(b.domains, strings.ToLower(strings.TrimPrefix(entry, ".")))
+	}
+
+	return b, nil
+}
+
+// Match reports whether the URL is on the bypass list
+func (b *bypassList) Match(urlStr string) bool {
+	for _, re := range b.patterns {
+		if re.MatchString(urlStr) {
+			return true
+		}
+	}
+
+	if len(b.domains) == 0 {
+		return false
+	}
+
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(parsedURL.Hostname())
+	for _, domain := range b.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
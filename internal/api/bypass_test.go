@@ -0,0 +1,45 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBypassListMatch(t *testing.T) {
+	b, err := newBypassList([]string{
+		"videos.example.com",
+		".world.net",
+		`regex:^https://cdn\d+\.custom\.org/`,
+		` regex:^https://padded\.org/ `,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		url  string
+		want bool
+	}{
+		{"exact domain", "https://videos.example.com/a.mp4", true},
+		{"subdomain", "https://eu.videos.example.com/a.mp4", true},
+		{"leading dot domain", "https://stream.world.net/live", true},
+		{"different domain", "https://example.com/a.mp4", false},
+		{"suffix but not subdomain", "https://badworld.net/a.mp4", false},
+		{"regex match", "https://cdn12.custom.org/v.mp4", true},
+		{"regex no match", "https://cdn.custom.org/v.mp4", false},
+		{"regex with surrounding spaces", "https://padded.org/v.mp4", true},
+		{"youtube", "https://www.youtube.com/watch?v=TEST", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, b.Match(tt.url))
+		})
+	}
+}
+
+func TestBypassListInvalidRegex(t *testing.T) {
+	_, err := newBypassList([]string{"regex:("})
+	assert.Error(t, err)
+}
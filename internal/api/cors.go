@@ -0,0 +1,125 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"vrcvideocacher/pkg/models"
+)
+
+const (
+	corsAllowMethods = "GET, POST, PUT, DELETE, OPTIONS"
+	corsAllowHeaders = "Content-Type"
+
+	// corsMaxAge is how long, in seconds, browsers may cache a preflight
+	corsMaxAge = 600
+)
+
+// extensionSchemes start the origins of browser extensions, such as cookie
+// exporters; web pages cannot send these
+var extensionSchemes = []string{"chrome-extension://", "moz-extension://", "safari-web-extension://"}
+
+// corsPolicy decides which browser origins may call the API
+type corsPolicy struct {
+	origins  []string
+	allowAll bool
+}
+
+// newCORSPolicy builds a policy from config entries
+// models.CORSAllowAll allows any origin
+func newCORSPolicy(origins []string) *corsPolicy {
+	return &corsPolicy{
+		origins:  slices.Clone(origins),
+		allowAll: slices.Contains(origins, models.CORSAllowAll),
+	}
+}
+
+// Allowed reports whether a request from origin may read API responses
+func (c *corsPolicy) Allowed(origin string) bool {
+	return origin != "" && (c.allowAll || slices.Contains(c.origins, origin))
+}
+
+// originAllowed admits requests without an Origin header, such as the CLI,
+// pages served by this server and the CORS allowed origins
+func (s *Server) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cors.Allowed(origin)
+}
+
+// isExtensionOrigin reports whether origin belongs to a browser extension
+func isExtensionOrigin(origin string) bool {
+	for _, scheme := range extensionSchemes {
+		if strings.HasPrefix(origin, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSafeMethod reports whether method only reads
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// requireJSON refuses requests whose body is not declared as JSON; browsers
+// only send that type cross-origin after a preflight
+func requireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsMiddleware adds CORS headers for allowed origins and answers their
+// preflight requests. Browsers still send simple requests (form posts,
+// text/plain bodies) from other origins and only hide the response, so
+// state-changing requests from other web pages are refused with 403;
+// GET requests from them pass through and the browser blocks reading them
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+
+		s.mu.RLock()
+		allowed := s.cors.Allowed(origin)
+		s.mu.RUnlock()
+
+		w.Header().Add("Vary", "Origin")
+		if !allowed {
+			if !isSafeMethod(r.Method) && !isExtensionOrigin(origin) && !s.originAllowed(r) {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxSafeURLLength is the longest URL passed on to yt-dlp or the player
+	maxSafeURLLength = 2048
+
+	// maxSafeQueryParams is the most query parameters a safe URL carries
+	maxSafeQueryParams = 32
+
+	// maxSafeDimension is the largest width or height a URL may request
+	maxSafeDimension = 8192
+
+	// crasherSource is the blocklist test source of heuristic matches
+	crasherSource = "crasher-protection"
+)
+
+// ErrSuspiciousURL is returned for URLs matching a known crasher pattern
+var ErrSuspiciousURL = errors.New("suspicious URL")
+
+// crasherHosts are IP grabbers and hosts known to serve crasher videos
+var crasherHosts = []string{
+	"grabify.link",
+	"iplogger.org",
+	"iplogger.com",
+	"iplogger.ru",
+	"iplogger.co",
+	"2no.co",
+	"yip.su",
+	"blasze.tk",
+}
+
+// dimensionParams are query parameters carrying a resolution
+var dimensionParams = []string{"w", "h", "width", "height", "res", "resolution", "maxres"}
+
+// dimensionPattern matches WIDTHxHEIGHT values such as 99999x99999
+var dimensionPattern = regexp.MustCompile(`^(\d+)[xX](\d+)$`)
+
+// checkCrasherURL applies the crasher protection heuristics, returning an
+// ErrSuspiciousURL describing the first one that matches
+func checkCrasherURL(urlStr string) error {
+	if len(urlStr) > maxSafeURLLength {
+		return fmt.Errorf("%w: URL is %d characters long", ErrSuspiciousURL, len(urlStr))
+	}
+
+	if strings.IndexFunc(urlStr, unicode.IsControl) >= 0 {
+		return fmt.Errorf("%w: control characters", ErrSuspiciousURL)
+	}
+
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSuspiciousURL, err)
+	}
+
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", ErrSuspiciousURL, parsedURL.Scheme)
+	}
+
+	host := strings.ToLower(parsedURL.Hostname())
+	for _, blocked := range crasherHosts {
+		if host == blocked || strings.HasSuffix(host, "."+blocked) {
+			return fmt.Errorf("%w: host %s", ErrSuspiciousURL, host)
+		}
+	}
+
+	query := parsedURL.Query()
+	params := 0
+	for _, values := range query {
+		params += len(values)
+	}
+	if params > maxSafeQueryParams {
+		return fmt.Errorf("%w: %d query parameters", ErrSuspiciousURL, params)
+	}
+
+	for name, values := range query {
+		for _, value := range values {
+			if dimension, ok := requestedDimension(name, value); ok && dimension > maxSafeDimension {
+				return fmt.Errorf("%w: resolution %s=%s", ErrSuspiciousURL, name, value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// requestedDimension returns the largest width or height in a query value
+// Values of resolution parameters count, as do WIDTHxHEIGHT values anywhere
+func requestedDimension(name, value string) (int, bool) {
+	if m := dimensionPattern.FindStringSubmatch(value); m != nil {
+		width, _ := parseDimension(m[1])
+		height, _ := parseDimension(m[2])
+		return max(width, height), true
+	}
+
+	for _, param := range dimensionParams {
+		if strings.EqualFold(name, param) {
+			return parseDimension(strings.TrimSuffix(strings.ToLower(value), "p"))
+		}
+	}
+
+	return 0, false
+}
+
+// parseDimension parses a width or height; numbers too large for an int
+// count as math.MaxInt
+func parseDimension(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if errors.Is(err, strconv.ErrRange) && !strings.HasPrefix(s, "-") {
+		return math.MaxInt, true
+	}
+	return n, err == nil
+}
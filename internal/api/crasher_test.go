@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestCheckCrasherURL(t *testing.T) {
+	flood := "https://example.com/video.mp4?" + strings.Repeat("a=1&", 40)
+
+	tests := []struct {
+		name       string
+		url        string
+		suspicious bool
+	}{
+		{"YouTube", "https://www.youtube.com/watch?v=VIDEO_ID&t=30", false},
+		{"Direct file", "https://cdn.example.com/video.mp4?w=1920&h=1080", false},
+		{"Resolution with suffix", "https://example.com/v?res=1080p", false},
+		{"Too long", "https://example.com/" + strings.Repeat("a", maxSafeURLLength), true},
+		{"Control characters", "https://example.com/v\x00.mp4", true},
+		{"File scheme", "file:///C:/Windows/win.ini", true},
+		{"IP grabber", "https://grabify.link/ABC123", true},
+		{"IP grabber subdomain", "https://www.iplogger.org/abc", true},
+		{"Query flood", flood, true},
+		{"Absurd height", "https://example.com/v.mp4?height=65535", true},
+		{"Absurd size", "https://example.com/v.mp4?size=99999x99999", true},
+		{"Overflowing resolution", "https://example.com/v.mp4?res=999999999999999999999999", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCrasherURL(tt.url)
+			if tt.suspicious {
+				assert.ErrorIs(t, err, ErrSuspiciousURL)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestHandleGetVideoCrasherProtection(t *testing.T) {
+	cfg := models.DefaultConfig()
+	cfg.CrasherProtection = true
+	server := NewServer(cfg, cache.NewManager(t.TempDir(), 0))
+
+	crasher := url.QueryEscape("https://example.com/v.mp4?height=65535")
+
+	req := httptest.NewRequest("GET", "/api/getvideo?url="+crasher, nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, resultRejected, server.history.List()[0].Result)
+
+	// The override lets the URL through as a normal non-YouTube bypass
+	req = httptest.NewRequest("GET", "/api/getvideo?allowUnsafe=true&url="+crasher, nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, resultBypass, server.history.List()[0].Result)
+
+	req = httptest.NewRequest("GET", "/api/blocklist/test?url="+crasher, nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"source":"crasher-protection"`)
+
+	// Disabled protection lets everything through
+	cfg.CrasherProtection = false
+	server.SetConfig(cfg)
+
+	req = httptest.NewRequest("GET", "/api/getvideo?url="+crasher, nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
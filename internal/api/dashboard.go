@@ -0,0 +1,20 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// dashboardHandler serves the embedded web dashboard under /dashboard/
+func dashboardHandler() http.Handler {
+	sub, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+
+	return http.StripPrefix("/dashboard", http.FileServer(http.FS(sub)))
+}
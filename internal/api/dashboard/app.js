@@ -0,0 +1,210 @@
+'use strict';
+
+const REFRESH_MS = 2000;
+
+// Frames side by side in a cache preview sprite
+const PREVIEW_FRAMES = 10;
+
+async function getJSON(path) {
+  const resp = await fetch(path);
+  if (!resp.ok) {
+    throw new Error(await resp.text());
+  }
+  return resp.json();
+}
+
+function el(tag, text) {
+  const node = document.createElement(tag);
+  if (text !== undefined) {
+    node.textContent = text;
+  }
+  return node;
+}
+
+function formatSize(bytes) {
+  const units = ['B', 'KB', 'MB', 'GB'];
+  let i = 0;
+  while (bytes >= 1024 && i < units.length - 1) {
+    bytes /= 1024;
+    i++;
+  }
+  return `${bytes.toFixed(i === 0 ? 0 : 1)} ${units[i]}`;
+}
+
+function actionButton(label, onClick) {
+  const button = el('button', label);
+  button.addEventListener('click', onClick);
+  const cell = el('td');
+  cell.appendChild(button);
+  return cell;
+}
+
+async function refreshStatus() {
+  const status = await getJSON('/api/status');
+  const list = document.getElementById('status');
+  list.replaceChildren();
+  for (const [key, value] of [
+    ['Version', status.version],
+    ['Caching', status.caching ? 'enabled' : 'disabled (pass-through)'],
+    ['Cache size', formatSize(status.cacheSize)],
+    ['Cached videos', status.cacheCount],
+  ]) {
+    list.appendChild(el('dt', key));
+    list.appendChild(el('dd', String(value)));
+  }
+
+  const badge = document.getElementById('status-badge');
+  badge.textContent = status.caching ? 'caching' : 'pass-through';
+  badge.className = 'badge ' + (status.caching ? 'ok' : 'off');
+
+  const toggle = document.getElementById('cache-mode-toggle');
+  toggle.textContent = status.caching ? 'Disable caching' : 'Enable caching';
+  toggle.onclick = async () => {
+    await fetch('/api/cache-mode', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ enabled: !status.caching }),
+    });
+    refreshStatus();
+  };
+}
+
+async function refreshDownloads() {
+  const data = await getJSON('/api/downloads');
+  const body = document.getElementById('downloads');
+  body.replaceChildren();
+  for (const item of data.items) {
+    const row = el('tr');
+    row.appendChild(el('td', item.videoId));
+    row.appendChild(el('td', item.format));
+    row.appendChild(el('td', item.error ? `${item.status}: ${item.error}` : item.status));
+    row.appendChild(el('td', `${item.progress.toFixed(1)}%`));
+    if (item.status === 'failed') {
+      row.appendChild(actionButton('Retry', async () => {
+        await fetch(`/api/downloads/${encodeURIComponent(item.videoId)}/retry`, { method: 'POST' });
+        refreshDownloads();
+      }));
+    } else if (['queued', 'downloading', 'awaiting-confirmation', 'upcoming'].includes(item.status)) {
+      row.appendChild(actionButton('Cancel', async () => {
+        await fetch(`/api/downloads/${encodeURIComponent(item.videoId)}`, { method: 'DELETE' });
+        refreshDownloads();
+      }));
+    } else {
+      row.appendChild(el('td'));
+    }
+    body.appendChild(row);
+  }
+}
+
+function previewCell(id) {
+  const preview = el('div');
+  preview.className = 'preview';
+  preview.style.backgroundImage = `url("/api/cache/${encodeURIComponent(id)}/preview")`;
+  preview.style.backgroundSize = `${PREVIEW_FRAMES * 100}% 100%`;
+  const cell = el('td');
+  cell.appendChild(preview);
+  return cell;
+}
+
+async function refreshCache() {
+  const data = await getJSON('/api/cache/list?limit=500');
+  const body = document.getElementById('cache');
+  body.replaceChildren();
+  for (const entry of data.items) {
+    const row = el('tr');
+    row.appendChild(previewCell(entry.id));
+    row.appendChild(el('td', entry.id));
+    row.appendChild(el('td', entry.filename));
+    row.appendChild(el('td', formatSize(entry.size)));
+    row.appendChild(el('td', new Date(entry.lastAccess).toLocaleString()));
+    row.appendChild(actionButton('Delete', async () => {
+      await fetch(`/api/cache/${encodeURIComponent(entry.id)}`, { method: 'DELETE' });
+      refreshCache();
+    }));
+    body.appendChild(row);
+  }
+}
+
+async function refreshLogs() {
+  const data = await getJSON('/api/logs');
+  const logs = document.getElementById('logs');
+  const atBottom = logs.scrollTop + logs.clientHeight >= logs.scrollHeight - 4;
+  logs.textContent = data.lines.join('\n');
+  if (atBottom) {
+    logs.scrollTop = logs.scrollHeight;
+  }
+}
+
+async function loadConfig() {
+  const config = await getJSON('/api/config');
+  document.getElementById('config').value = JSON.stringify(config, null, 2);
+}
+
+async function loadProfiles() {
+  const data = await getJSON('/api/profiles');
+  const select = document.getElementById('profile');
+  select.replaceChildren(new Option('Custom', ''));
+  for (const p of data.profiles) {
+    select.appendChild(new Option(`${p.displayName} (${p.maxRes}p)`, p.name));
+  }
+  select.value = data.current;
+}
+
+async function applyProfile() {
+  const result = document.getElementById('config-result');
+  result.className = '';
+  const name = document.getElementById('profile').value;
+  if (!name) {
+    return;
+  }
+  try {
+    const resp = await fetch('/api/profile', {
+      method: 'PUT',
+      body: JSON.stringify({ name }),
+    });
+    if (!resp.ok) {
+      throw new Error(await resp.text());
+    }
+    result.textContent = 'Profile applied, restart to take effect';
+    loadConfig();
+  } catch (err) {
+    result.textContent = err.message;
+    result.className = 'error';
+  }
+}
+
+async function saveConfig() {
+  const result = document.getElementById('config-result');
+  result.className = '';
+  try {
+    const config = JSON.parse(document.getElementById('config').value);
+    const resp = await fetch('/api/config', {
+      method: 'PUT',
+      body: JSON.stringify(config),
+    });
+    if (!resp.ok) {
+      throw new Error(await resp.text());
+    }
+    result.textContent = 'Saved';
+    loadConfig();
+  } catch (err) {
+    result.textContent = err.message;
+    result.className = 'error';
+  }
+}
+
+async function refresh() {
+  await Promise.allSettled([
+    refreshStatus(),
+    refreshDownloads(),
+    refreshCache(),
+    refreshLogs(),
+  ]);
+}
+
+document.getElementById('config-save').addEventListener('click', saveConfig);
+document.getElementById('profile-apply').addEventListener('click', applyProfile);
+loadConfig();
+loadProfiles();
+refresh();
+setInterval(refresh, REFRESH_MS);
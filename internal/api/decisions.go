@@ -0,0 +1,105 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// maxDecisions is the number of getvideo decisions kept for /api/decisions
+const maxDecisions = 500
+
+// Rules that decide how a getvideo request is answered
+const (
+	ruleBlocklist       = "blocklist"
+	ruleCrasher         = "crasher-protection"
+	ruleAllowlist       = "allowlist"
+	ruleCachingDisabled = "caching-disabled"
+	ruleNonYouTube      = "non-youtube"
+	ruleNoVideoID       = "no-video-id"
+	ruleCacheHit        = "cache-hit"
+	rulePrivate         = "private"
+	ruleDownloaded      = "downloaded-while-waiting"
+	ruleLive            = "live"
+	ruleUncacheable     = "uncacheable"
+	ruleQueued          = "queued"
+)
+
+// decision records which rule answered a getvideo request
+type decision struct {
+	Timestamp time.Time `json:"timestamp"`
+	URL       string    `json:"url"`
+	VideoID   string    `json:"videoId,omitempty"`
+	Source    string    `json:"source"`
+	Rule      string    `json:"rule"`
+	Detail    string    `json:"detail,omitempty"`
+	Result    string    `json:"result"`
+	RequestID string    `json:"requestId,omitempty"`
+}
+
+// decisionLog keeps the most recent decisions, oldest first
+type decisionLog struct {
+	mu      sync.Mutex
+	entries []decision
+	limit   int
+}
+
+// newDecisionLog creates a log holding at most limit decisions
+func newDecisionLog(limit int) *decisionLog {
+	return &decisionLog{
+		entries: make([]decision, 0, limit),
+		limit:   limit,
+	}
+}
+
+// Add records a decision
+func (l *decisionLog) Add(d decision) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, d)
+	if len(l.entries) > l.limit {
+		l.entries = append([]decision{}, l.entries[len(l.entries)-l.limit:]...)
+	}
+}
+
+// Since returns a copy of the decisions made after t, oldest first
+func (l *decisionLog) Since(t time.Time) []decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := []decision{}
+	for _, d := range l.entries {
+		if d.Timestamp.After(t) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// recordDecision adds the rule that produced a served video to the log and
+// the metrics
+func (s *Server) recordDecision(v servedVideo) {
+	s.metrics.getvideo.With(v.rule).Inc()
+	s.decisions.Add(decision{
+		Timestamp: v.Timestamp,
+		URL:       v.URL,
+		VideoID:   v.VideoID,
+		Source:    v.Source,
+		Rule:      v.rule,
+		Detail:    v.detail,
+		Result:    v.Result,
+		RequestID: v.RequestID,
+	})
+}
+
+// parseSince parses the since parameter of /api/decisions as an RFC 3339
+// time or a duration before now. An empty value returns every decision
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestDecisionLog(t *testing.T) {
+	l := newDecisionLog(3)
+	start := time.Now()
+	assert.Empty(t, l.Since(time.Time{}))
+
+	for i := 0; i < 5; i++ {
+		l.Add(decision{URL: fmt.Sprintf("https://example.com/%d", i), Timestamp: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	all := l.Since(time.Time{})
+	require.Len(t, all, 3)
+	assert.Equal(t, "https://example.com/2", all[0].URL)
+	assert.Equal(t, "https://example.com/4", all[2].URL)
+
+	recent := l.Since(start.Add(3 * time.Second))
+	require.Len(t, recent, 1)
+	assert.Equal(t, "https://example.com/4", recent[0].URL)
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	since, err := parseSince("", now)
+	require.NoError(t, err)
+	assert.True(t, since.IsZero())
+
+	since, err = parseSince("10m", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-10*time.Minute), since)
+
+	since, err = parseSince("2024-05-01T11:00:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-time.Hour), since)
+
+	_, err = parseSince("yesterday", now)
+	assert.Error(t, err)
+	_, err = parseSince("-5m", now)
+	assert.Error(t, err)
+}
+
+func TestHandleDecisions(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "TEST123.mp4"), []byte("cached video"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("TEST123", "TEST123.mp4"))
+
+	cfg := models.DefaultConfig()
+	cfg.BlockedURLs = []string{"https://www.youtube.com/watch?v=CRASHER"}
+	cfg.BypassURLs = []string{"allowed.example.com"}
+	server := NewServer(cfg, cacheMgr)
+
+	for _, u := range []string{
+		"https://www.youtube.com/watch?v=CRASHER",
+		"https://allowed.example.com/video.mp4",
+		"https://example.com/video.mp4",
+		"https://www.youtube.com/watch?v=TEST123",
+	} {
+		req := httptest.NewRequest("GET", "/api/getvideo?url="+url.QueryEscape(u), nil)
+		server.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest("GET", "/api/decisions", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Decisions []decision `json:"decisions"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Decisions, 4)
+
+	assert.Equal(t, ruleBlocklist, resp.Decisions[0].Rule)
+	assert.Equal(t, resultBlocked, resp.Decisions[0].Result)
+	assert.Contains(t, resp.Decisions[0].Detail, "https://www.youtube.com/watch?v=CRASHER")
+	assert.Equal(t, ruleAllowlist, resp.Decisions[1].Rule)
+	assert.Equal(t, ruleNonYouTube, resp.Decisions[2].Rule)
+	assert.Equal(t, ruleCacheHit, resp.Decisions[3].Rule)
+	assert.Equal(t, "TEST123", resp.Decisions[3].VideoID)
+
+	// Only decisions after since are returned
+	req = httptest.NewRequest("GET", "/api/decisions?since="+url.QueryEscape(time.Now().Add(time.Minute).Format(time.RFC3339)), nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"total":0,"decisions":[]}`, w.Body.String())
+
+	// Decisions can be filtered, searched and paged
+	var page struct {
+		Total     int        `json:"total"`
+		Decisions []decision `json:"decisions"`
+	}
+	req = httptest.NewRequest("GET", "/api/decisions?rule=cache-hit,non-youtube&q=test123", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+	assert.Equal(t, 1, page.Total)
+	require.Len(t, page.Decisions, 1)
+	assert.Equal(t, ruleCacheHit, page.Decisions[0].Rule)
+
+	req = httptest.NewRequest("GET", "/api/decisions?offset=3&limit=2", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+	assert.Equal(t, 4, page.Total)
+	require.Len(t, page.Decisions, 1)
+	assert.Equal(t, ruleCacheHit, page.Decisions[0].Rule)
+
+	req = httptest.NewRequest("GET", "/api/decisions?since=bogus", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
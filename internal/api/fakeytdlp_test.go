@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/fakeytdlp"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestGetVideoFakeYtdlp(t *testing.T) {
+	cfg := models.DefaultConfig()
+	cfg.YtdlPath = fakeytdlp.Build(t)
+	server := NewServer(cfg, cache.NewManager(t.TempDir(), 0))
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	// A miss waited on is downloaded, cached and served
+	req := httptest.NewRequest("GET", "/api/getvideo?wait=10000&avpro=false&url=https://www.youtube.com/watch?v=e2eVideo", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "/e2eVideo.mp4?v=")
+	assert.Equal(t, resultCached, server.history.List()[0].Result)
+
+	served, err := url.Parse(w.Body.String())
+	require.NoError(t, err)
+	req = httptest.NewRequest("GET", served.RequestURI(), nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "fake video e2eVideo\n", w.Body.String())
+
+	// A members-only video is passed through with its reason
+	req = httptest.NewRequest("GET", "/api/getvideo?wait=10000&url=https://www.youtube.com/watch?v=membersVideo", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	req = httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=membersVideo", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, resultMembersOnly, server.history.List()[0].Result)
+}
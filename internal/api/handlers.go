@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,123 +9,1377 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/config"
+	"vrcvideocacher/internal/cookies"
+	"vrcvideocacher/internal/downloader"
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/internal/reqid"
 	"vrcvideocacher/pkg/models"
 )
 
-var (
-	ErrNoURL           = errors.New("no URL provided")
-	ErrInvalidCookies  = errors.New("invalid cookies")
-	ErrVideoIDNotFound = errors.New("video ID not found")
-)
+var (
+	ErrNoURL           = errors.New("no URL provided")
+	ErrInvalidCookies  = errors.New("invalid cookies")
+	ErrVideoIDNotFound = errors.New("video ID not found")
+)
+
+// maxFormValue bounds the non-file fields of a cache upload
+const maxFormValue = 4096
+
+// handleGetVideo handles the /api/getvideo endpoint
+func (s *Server) handleGetVideo(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	videoURL := r.URL.Query().Get("url")
+	avproStr := r.URL.Query().Get("avpro")
+	source := r.URL.Query().Get("source")
+
+	if videoURL == "" {
+		http.Error(w, "No URL provided", http.StatusBadRequest)
+		return
+	}
+
+	// Determine avpro (default true)
+	avpro := true
+	if avproStr == "false" {
+		avpro = false
+	}
+
+	// Default source
+	if source == "" {
+		source = models.SourceVRChat
+	}
+	s.stubs.Record(r, source, time.Now())
+
+	wait, err := parseWait(r.URL.Query().Get("wait"))
+	if err != nil {
+		http.Error(w, "Invalid wait", http.StatusBadRequest)
+		return
+	}
+
+	// Blocked URLs are never played, even when allowlisted or not cached
+	if match, ok := s.blocker.Match(videoURL); ok {
+		s.reqLog(r).Info("Blocked", "url", videoURL, "source", match.Source, "entry", match.Entry)
+		s.writeBlocked(w, r, servedVideo{URL: videoURL, Source: source, Result: resultBlocked,
+			rule: ruleBlocklist, detail: fmt.Sprintf("%s entry %s", match.Source, match.Entry)})
+		return
+	}
+
+	// Crasher protection rejects suspicious URLs unless explicitly overridden
+	if s.cfg().CrasherProtection {
+		if err := checkCrasherURL(videoURL); err != nil {
+			if r.URL.Query().Get("allowUnsafe") != "true" {
+				s.reqLog(r).Warn("Rejected", "url", videoURL, logger.Err(err))
+				s.writeBlocked(w, r, servedVideo{URL: videoURL, Source: source, Result: resultRejected,
+					rule: ruleCrasher, detail: err.Error()})
+				return
+			}
+			s.reqLog(r).Warn("Allowing despite crasher protection", "url", videoURL, logger.Err(err))
+		}
+	}
+
+	// Allowlisted URLs are passed through before any other processing
+	if s.isBypassed(videoURL) {
+		s.writeVideoResponse(w, r, servedVideo{URL: videoURL, Source: source, Result: resultAllowlisted, rule: ruleAllowlist})
+		return
+	}
+
+	// Pass-through mode: bypass everything
+	if !s.IsCachingEnabled() {
+		s.writeVideoResponse(w, r, servedVideo{URL: videoURL, Source: source, Result: resultPassthrough, rule: ruleCachingDisabled})
+		return
+	}
+
+	// Known aliases resolve to their cache entry, whatever the URL
+	videoID, aliased := s.cache.ResolveAlias(normalizeURL(videoURL))
+	if !aliased {
+		// Check if it's a YouTube URL
+		if !isYouTubeURL(videoURL) {
+			// Non-YouTube URLs are bypassed (return empty)
+			s.writeVideoResponse(w, r, servedVideo{URL: videoURL, Source: source, Result: resultBypass, rule: ruleNonYouTube})
+			return
+		}
+
+		// Extract video ID
+		id, err := extractYouTubeVideoID(videoURL)
+		if err != nil {
+			// If can't extract ID, bypass
+			s.writeVideoResponse(w, r, servedVideo{URL: videoURL, Source: source, Result: resultBypass, rule: ruleNoVideoID, detail: err.Error()})
+			return
+		}
+		videoID = id
+	}
+
+	cfg := s.cfg()
+	ctx := r.Context()
+	policy := cfg.SourcePolicies[source]
+
+	// Timestamped links share the video's cache entry; the start time is
+	// passed on with the cached URL
+	startTime := videoStartTime(videoURL)
+
+	aliasDetail := ""
+	if aliased {
+		aliasDetail = "alias"
+	}
+
+	// serveCached returns the cached URL, versioned so players drop a stale
+	// copy when the file is replaced
+	serveCached := func(entry *models.CacheEntry, rule string) {
+		// Private entries are played from the original URL on other devices
+		if hiddenFromLAN(r, entry) {
+			s.writeVideoResponse(w, r, servedVideo{URL: videoURL, VideoID: videoID, Source: source, Result: resultBypass, rule: rulePrivate})
+			return
+		}
+
+		cachedURL := fmt.Sprintf("%s/%s?%s=%s", baseURL(r, cfg), url.PathEscape(entry.FileName), versionParam, entry.Version())
+		if startTime > 0 && policy.Seek {
+			cachedURL += fmt.Sprintf("#t=%d", startTime)
+		}
+
+		// Update last access time
+		s.cache.UpdateLastAccess(videoID)
+		if c := s.statsCollector(); c != nil {
+			c.Hit(videoID, entry.Size)
+			s.upgradeIfPopular(r, entry, c.Hits(videoID))
+		}
+		if !aliased {
+			s.recordAlias(videoURL, videoID)
+		}
+
+		s.writeVideoResponse(w, r, servedVideo{
+			URL:       videoURL,
+			VideoID:   videoID,
+			ServedURL: cachedURL,
+			Source:    source,
+			Result:    resultCached,
+			StartTime: startTime,
+			rule:      rule,
+			detail:    aliasDetail,
+		})
+	}
+
+	// Try to find cached file
+	entry, err := s.cache.Lookup(ctx, videoID)
+	if ctx.Err() != nil {
+		// Client disconnected, nothing left to do
+		return
+	}
+	if err == nil {
+		serveCached(entry, ruleCacheHit)
+		return
+	}
+
+	// Cache miss - queue download, letting the source policy override the
+	// format and resolution
+	format := models.DownloadFormatMP4
+	if avpro {
+		format = models.DownloadFormatWebm
+	}
+	if f, ok := models.ParseDownloadFormat(policy.Format); ok {
+		format = f
+	}
+
+	// Live streams are played directly while they are recorded
+	if cfg.LiveRecord {
+		streamURL, live, err := s.downloader.ResolveLive(ctx, videoURL)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.reqLog(r).Warn("Live stream check failed", "video", videoID, logger.Err(err))
+		}
+		if live {
+			if err := s.downloader.QueueLive(ctx, videoID, videoURL, format); err != nil && !errors.Is(err, downloader.ErrAlreadyQueued) {
+				s.reqLog(r).Error("Failed to queue live recording", "video", videoID, logger.Err(err))
+			}
+			s.writeVideoResponse(w, r, servedVideo{URL: videoURL, VideoID: videoID, ServedURL: streamURL, Source: source, Result: resultLive, rule: ruleLive})
+			return
+		}
+	}
+
+	var queueErr error
+	if err := s.downloader.QueueWithMaxRes(ctx, videoID, videoURL, format, policy.MaxRes); err != nil {
+		if ctx.Err() != nil {
+			// Client disconnected before the download was queued
+			return
+		}
+		// Premieres and scheduled streams are passed through until they
+		// start, uncacheable videos always, so the player fails on its own
+		if result, ok := uncachedResult(err); ok {
+			s.reqLog(r).Info("Not caching", "video", videoID, "reason", err)
+			s.writeVideoResponse(w, r, servedVideo{URL: videoURL, VideoID: videoID, Source: source, Result: result, Reason: err.Error(), rule: ruleUncacheable})
+			return
+		}
+		// Log error but don't fail the request
+		s.reqLog(r).Error("Failed to queue download", "video", videoID, logger.Err(err))
+		queueErr = err
+	}
+
+	// Callers may block briefly for a fast download to finish
+	if wait > 0 && s.waitForDownload(ctx, videoID, wait) {
+		if entry, err := s.cache.Lookup(ctx, videoID); err == nil {
+			serveCached(entry, ruleDownloaded)
+			return
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	queued := servedVideo{URL: videoURL, VideoID: videoID, Source: source, Result: resultQueued, rule: ruleQueued}
+	switch {
+	case queueErr != nil:
+		queued.detail = queueErr.Error()
+	case wait > 0:
+		queued.detail = fmt.Sprintf("not finished within %s", wait)
+	}
+
+	// JSON sources can start playing while the download progresses, with
+	// the original URL as fallback
+	if policy.Response == models.ResponseJSON {
+		if _, pending := s.downloader.PartialFile(videoID); pending {
+			queued.ServedURL = baseURL(r, cfg) + streamPath(videoID, format)
+			queued.Result = resultStreaming
+		}
+	}
+
+	// Return empty (download will happen in background)
+	s.writeVideoResponse(w, r, queued)
+}
+
+// videoInfo is the yt-dlp style info object returned to sources that
+// expect JSON output (yt-dlp -J)
+type videoInfo struct {
+	ID         string `json:"id"`
+	URL        string `json:"url"`
+	Ext        string `json:"ext"`
+	WebpageURL string `json:"webpage_url"`
+	Protocol   string `json:"protocol"`
+	StartTime  int    `json:"start_time,omitempty"`
+
+	Formats []videoFormat `json:"formats,omitempty"`
+}
+
+// videoFormat is an entry of the yt-dlp style formats list, ordered from
+// least to most preferred
+type videoFormat struct {
+	FormatID string `json:"format_id"`
+	URL      string `json:"url"`
+	Ext      string `json:"ext,omitempty"`
+	Protocol string `json:"protocol"`
+}
+
+// writeVideoResponse records the served video and writes its URL as plain
+// text, or in the shape the source policy asks for if there is a URL
+func (s *Server) writeVideoResponse(w http.ResponseWriter, r *http.Request, v servedVideo) {
+	v.Timestamp = time.Now()
+	v.RequestID = reqid.From(r.Context())
+	s.history.Add(v)
+	s.recordDecision(v)
+
+	if v.ServedURL == "" {
+		w.Header().Set("Content-Type", "text/plain")
+		return
+	}
+
+	policy := s.cfg().SourcePolicies[v.Source]
+	info := videoInfo{
+		ID:         v.VideoID,
+		URL:        v.ServedURL,
+		Ext:        servedExt(v.ServedURL),
+		WebpageURL: v.URL,
+		Protocol:   "http",
+		StartTime:  v.StartTime,
+	}
+	if v.Result == resultLive {
+		// The HLS manifest of the stream, as yt-dlp describes it
+		info.Ext = "mp4"
+		info.Protocol = "m3u8_native"
+	}
+	if v.Result == resultStreaming {
+		info.Formats = []videoFormat{
+			{FormatID: "upstream", URL: v.URL, Protocol: urlScheme(v.URL)},
+			{FormatID: "local", URL: v.ServedURL, Ext: info.Ext, Protocol: "http"},
+		}
+	}
+
+	switch policy.Response {
+	case models.ResponseJSON:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+		return
+	case models.ResponseTemplate:
+		if body, ok := s.renderTemplate(v.Source, templateData{videoInfo: info, Source: v.Source}); ok {
+			contentType := policy.ContentType
+			if contentType == "" {
+				contentType = "text/plain"
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.Write(body)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(v.ServedURL))
+}
+
+// renderTemplate executes the response template of a source
+// ok is false if the source has no valid template or it failed, in which
+// case the plain URL is served
+func (s *Server) renderTemplate(source string, data templateData) ([]byte, bool) {
+	s.mu.RLock()
+	tmpl := s.templates[source]
+	s.mu.RUnlock()
+
+	if tmpl == nil {
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		s.log.Error("Response template failed", "source", source, logger.Err(err))
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// writeBlocked answers a getvideo request that must not be played with the
+// configured BlockRedirect video, or 403 Forbidden if there is none
+func (s *Server) writeBlocked(w http.ResponseWriter, r *http.Request, v servedVideo) {
+	if redirect := s.cfg().BlockRedirect; redirect != "" {
+		v.ServedURL = redirect
+		s.writeVideoResponse(w, r, v)
+		return
+	}
+
+	v.Timestamp = time.Now()
+	v.RequestID = reqid.From(r.Context())
+	s.history.Add(v)
+	s.recordDecision(v)
+	http.Error(w, "URL is blocked", http.StatusForbidden)
+}
+
+// servedExt returns the file extension of a served URL, ignoring the query
+func servedExt(servedURL string) string {
+	u, err := url.Parse(servedURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(path.Ext(u.Path), ".")
+}
+
+// uncachedResult returns the history result of a video the downloader
+// refuses to queue, e.g. a DRM protected one
+func uncachedResult(err error) (string, bool) {
+	switch {
+	case errors.Is(err, downloader.ErrUpcoming):
+		return resultUpcoming, true
+	case errors.Is(err, downloader.ErrDRMProtected):
+		return resultDRM, true
+	case errors.Is(err, downloader.ErrMembersOnly):
+		return resultMembersOnly, true
+	}
+	return "", false
+}
+
+// urlScheme returns the scheme of a URL, "https" if it has none
+func urlScheme(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" {
+		return strings.ToLower(u.Scheme)
+	}
+	return "https"
+}
+
+// handleNowPlaying handles the /api/now-playing endpoint
+// The list parameters apply to history; current is always the last video
+func (s *Server) handleNowPlaying(w http.ResponseWriter, r *http.Request) {
+	list, err := parseListQuery(r.URL.Query(), maxServedHistory, "result", "source")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	served := s.history.List()
+
+	var current *servedVideo
+	if len(served) > 0 {
+		current = &served[0]
+	}
+
+	history := []servedVideo{}
+	for _, v := range served {
+		if list.allows("result", v.Result) && list.allows("source", v.Source) &&
+			list.matches(v.URL, v.VideoID, v.ServedURL) {
+			history = append(history, v)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"current": current,
+		"total":   len(history),
+		"history": paginate(history, list),
+	})
+}
+
+// handleDecisions handles the /api/decisions endpoint
+// ?since= takes an RFC 3339 time or a duration like 10m, and returns the
+// decisions made after it, oldest first
+func (s *Server) handleDecisions(w http.ResponseWriter, r *http.Request) {
+	since, err := parseSince(r.URL.Query().Get("since"), time.Now())
+	if err != nil {
+		http.Error(w, "Invalid since", http.StatusBadRequest)
+		return
+	}
+	list, err := parseListQuery(r.URL.Query(), maxDecisions, "rule", "result", "source")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	decisions := []decision{}
+	for _, d := range s.decisions.Since(since) {
+		if list.allows("rule", d.Rule) && list.allows("result", d.Result) && list.allows("source", d.Source) &&
+			list.matches(d.URL, d.VideoID) {
+			decisions = append(decisions, d)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":     len(decisions),
+		"decisions": paginate(decisions, list),
+	})
+}
+
+// handleLogs handles the /api/logs endpoint
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{
+		"lines": s.logs.Lines(),
+	})
+}
+
+// handleGetConfig handles GET /api/config
+func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	cfgMgr := s.cfgMgr
+	s.mu.RUnlock()
+
+	cfg := s.Config()
+	if cfgMgr != nil {
+		cfg = cfgMgr.Get()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfg)
+}
+
+// handleSetConfig handles PUT /api/config
+// The config is saved to disk and applied to the running server; the port
+// and startup options take effect after a restart
+func (s *Server) handleSetConfig(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	cfgMgr := s.cfgMgr
+	s.mu.RUnlock()
+
+	if cfgMgr == nil {
+		http.Error(w, "Config editing not available", http.StatusNotImplemented)
+		return
+	}
+
+	var cfg models.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := cfgMgr.Update(func(c *models.Config) {
+		*c = cfg
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.SetConfig(cfgMgr.Get()); err != nil {
+		s.log.Error("Failed to apply config", logger.Err(err))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfgMgr.Get())
+}
+
+// handleListProfiles handles GET /api/profiles
+func (s *Server) handleListProfiles(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	cfgMgr := s.cfgMgr
+	s.mu.RUnlock()
+
+	current := s.cfg().DeviceProfile
+	if cfgMgr != nil {
+		current = cfgMgr.Get().DeviceProfile
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"current":  current,
+		"profiles": config.Profiles(),
+	})
+}
+
+// handleApplyProfile handles PUT /api/profile
+// The new limits apply to downloads queued afterwards
+func (s *Server) handleApplyProfile(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	cfgMgr := s.cfgMgr
+	s.mu.RUnlock()
+
+	if cfgMgr == nil {
+		http.Error(w, "Config editing not available", http.StatusNotImplemented)
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := cfgMgr.ApplyProfile(body.Name); err != nil {
+		if errors.Is(err, config.ErrUnknownProfile) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := s.SetConfig(cfgMgr.Get()); err != nil {
+		s.log.Error("Failed to apply config", logger.Err(err))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfgMgr.Get())
+}
+
+// handleListCache handles the /api/cache/list endpoint
+func (s *Server) handleListCache(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	list, err := parseListQuery(query, defaultListLimit, "origin", "private", "variant")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// ListEntries is already sorted by last access
+	entries := []*models.CacheEntry{}
+	for _, entry := range s.cache.ListEntries() {
+		if list.allows("origin", entry.Origin) &&
+			list.allows("private", strconv.FormatBool(entry.Private)) &&
+			list.allows("variant", entry.Variant) &&
+			list.matches(append([]string{entry.ID, entry.FileName, entry.Title}, entry.Aliases...)...) {
+			entries = append(entries, entry)
+		}
+	}
+	switch query.Get("sort") {
+	case "", "date":
+	case "size":
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Size > entries[j].Size
+		})
+	case "name":
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].FileName < entries[j].FileName
+		})
+	default:
+		http.Error(w, "Invalid sort", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":   len(entries),
+		"items":   paginate(entries, list),
+		"foreign": s.cache.ForeignFiles(),
+	})
+}
+
+// handleDeleteCache handles DELETE /api/cache/{id}
+func (s *Server) handleDeleteCache(w http.ResponseWriter, r *http.Request) {
+	if err := s.cache.DeleteEntry(chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, cache.ErrEntryNotFound) {
+			http.Error(w, "Video not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete video", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Deleted",
+	})
+}
+
+// handleCachePreview handles GET /api/cache/{id}/preview
+func (s *Server) handleCachePreview(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := s.cache.GetEntry(id); err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	path := s.cache.PreviewPath(id)
+	if _, err := os.Stat(path); err != nil {
+		http.Error(w, "No preview", http.StatusNotFound)
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+// cacheAccessRequest is the body of PUT /api/cache/{id}/access
+type cacheAccessRequest struct {
+	Private *bool `json:"private"`
+}
+
+// handleSetCacheAccess handles PUT /api/cache/{id}/access
+func (s *Server) handleSetCacheAccess(w http.ResponseWriter, r *http.Request) {
+	var req cacheAccessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Private == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if err := s.cache.SetPrivate(id, *req.Private); err != nil {
+		if errors.Is(err, cache.ErrEntryNotFound) {
+			http.Error(w, "Video not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	entry, err := s.cache.GetEntry(id)
+	if err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// moveCacheRequest is the body of POST /api/cache/move
+type moveCacheRequest struct {
+	Path string `json:"path"`
+}
+
+// handleMoveCache handles POST /api/cache/move
+func (s *Server) handleMoveCache(w http.ResponseWriter, r *http.Request) {
+	var req moveCacheRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Path == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.MoveCache(req.Path, nil); err != nil {
+		switch {
+		case errors.Is(err, cache.ErrSameCachePath),
+			errors.Is(err, cache.ErrNestedCachePath),
+			errors.Is(err, cache.ErrCacheFileExists):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrDownloadsActive):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":    "success",
+		"cachePath": s.cache.GetCachePath(),
+	})
+}
+
+// handleUploadCache handles POST /api/cache/upload
+// The multipart body holds a "file" part; the video is named by a "url" or
+// "id" query parameter or form field sent before the file. The upload is
+// streamed to the cache, so read and write deadlines are lifted
+func (s *Server) handleUploadCache(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Expected multipart/form-data body", http.StatusBadRequest)
+		return
+	}
+
+	target := r.URL.Query().Get("url")
+	if id := r.URL.Query().Get("id"); id != "" {
+		target = id
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			http.Error(w, "No file provided", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Invalid multipart body", http.StatusBadRequest)
+			return
+		}
+
+		switch part.FormName() {
+		case "url", "id":
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValue))
+			if err != nil {
+				http.Error(w, "Invalid multipart body", http.StatusBadRequest)
+				return
+			}
+			target = strings.TrimSpace(string(value))
+			continue
+		case "file":
+		default:
+			continue
+		}
+
+		id, err := videoIDFor(target)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		entry, err := s.cache.AddUpload(id, path.Ext(part.FileName()), part)
+		if err != nil {
+			switch {
+			case errors.Is(err, cache.ErrInvalidEntry):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, cache.ErrCacheMoved):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		s.log.Info("Uploaded", "file", part.FileName(), "bytes", entry.Size, "id", entry.ID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(entry)
+		return
+	}
+}
+
+// videoIDFor returns the cache ID of a video named by a YouTube URL or a
+// plain video ID
+func videoIDFor(target string) (string, error) {
+	if target == "" {
+		return "", ErrNoURL
+	}
+	if !strings.Contains(target, "/") {
+		return target, nil
+	}
+	if !isYouTubeURL(target) {
+		return "", fmt.Errorf("not a YouTube URL: %s", target)
+	}
+	return extractYouTubeVideoID(target)
+}
+
+// aliasRequest is the body of POST /api/cache/aliases
+type aliasRequest struct {
+	Alias  string `json:"alias"`
+	Target string `json:"target"`
+}
+
+// handleListAliases handles GET /api/cache/aliases
+func (s *Server) handleListAliases(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"aliases": s.cache.ListAliases(),
+	})
+}
+
+// handleAddAlias handles POST /api/cache/aliases
+// The alias URL is normalized; the target is a cached video's ID or URL
+func (s *Server) handleAddAlias(w http.ResponseWriter, r *http.Request) {
+	var req aliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Alias == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	id, err := videoIDFor(req.Target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	alias := normalizeURL(req.Alias)
+	if err := s.cache.AddAlias(alias, id); err != nil {
+		if errors.Is(err, cache.ErrEntryNotFound) {
+			http.Error(w, "Video not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cache.Alias{Alias: alias, ID: id})
+}
+
+// handleDeleteAlias handles DELETE /api/cache/aliases?alias=
+func (s *Server) handleDeleteAlias(w http.ResponseWriter, r *http.Request) {
+	alias := r.URL.Query().Get("alias")
+	if alias == "" {
+		http.Error(w, "alias parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.cache.RemoveAlias(normalizeURL(alias)); err != nil {
+		if errors.Is(err, cache.ErrAliasNotFound) {
+			http.Error(w, "Alias not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
 
-// handleGetVideo handles the /api/getvideo endpoint
-func (s *Server) handleGetVideo(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	videoURL := r.URL.Query().Get("url")
-	avproStr := r.URL.Query().Get("avpro")
-	source := r.URL.Query().Get("source")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Deleted",
+	})
+}
 
-	if videoURL == "" {
-		http.Error(w, "No URL provided", http.StatusBadRequest)
+// handleYouTubeCookies handles the /api/youtube-cookies endpoint
+func (s *Server) handleYouTubeCookies(w http.ResponseWriter, r *http.Request) {
+	// Read cookies from body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
 		return
 	}
 
-	// Determine avpro (default true)
-	avpro := true
-	if avproStr == "false" {
-		avpro = false
+	// Normalize Netscape, JSON export or header string cookies to Netscape format
+	cookieData, err := cookies.Normalize(string(body))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid cookies: %v", err), http.StatusBadRequest)
+		return
 	}
-	_ = avpro // Will be used for download queue
 
-	// Default source
-	if source == "" {
-		source = "vrchat"
+	// Save cookies to the account (default if not specified)
+	account := r.URL.Query().Get("account")
+	if err := s.downloader.CookieStore().Save(account, cookieData); err != nil {
+		if errors.Is(err, cookies.ErrInvalidAccount) {
+			http.Error(w, "Invalid account name", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to save cookies", http.StatusInternalServerError)
+		return
 	}
-	_ = source // Will be used for download queue
 
-	// Check if it's a YouTube URL
-	if !isYouTubeURL(videoURL) {
-		// Non-YouTube URLs are bypassed (return empty)
-		w.Header().Set("Content-Type", "text/plain")
-		w.Write([]byte(""))
+	// Return success
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Cookies received",
+	})
+}
+
+// cacheModeRequest is the body of POST /api/cache-mode
+type cacheModeRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// handleGetCacheMode handles GET /api/cache-mode
+func (s *Server) handleGetCacheMode(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{
+		"enabled": s.IsCachingEnabled(),
+	})
+}
+
+// handleSetCacheMode handles POST /api/cache-mode
+func (s *Server) handleSetCacheMode(w http.ResponseWriter, r *http.Request) {
+	var req cacheModeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	s.SetCachingEnabled(*req.Enabled)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{
+		"enabled": *req.Enabled,
+	})
+}
+
+// cookieOrderRequest is the body of PUT /api/cookie-accounts
+type cookieOrderRequest struct {
+	Order []string `json:"order"`
+}
+
+// handleListCookieAccounts handles GET /api/cookie-accounts
+func (s *Server) handleListCookieAccounts(w http.ResponseWriter, r *http.Request) {
+	store := s.downloader.CookieStore()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{
+		"accounts": store.List(),
+		"order":    store.Order(),
+	})
+}
+
+// handleCookieStatus handles GET /api/cookie-accounts/status
+// External login helpers poll this to decide when to push refreshed cookies
+func (s *Server) handleCookieStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"accounts": s.downloader.CookieStore().Statuses(),
+	})
+}
+
+// handleSetCookieOrder handles PUT /api/cookie-accounts
+// The order is saved to YtdlCookieAccounts when the config can be edited,
+// so later config changes and restarts keep it
+func (s *Server) handleSetCookieOrder(w http.ResponseWriter, r *http.Request) {
+	var req cookieOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	store := s.downloader.CookieStore()
+	if err := store.SetOrder(req.Order); err != nil {
+		http.Error(w, "Invalid account name", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	cfgMgr := s.cfgMgr
+	s.mu.RUnlock()
+
+	if cfgMgr != nil {
+		if err := cfgMgr.Update(func(c *models.Config) {
+			c.YtdlCookieAccounts = append([]string{}, req.Order...)
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.SetConfig(cfgMgr.Get()); err != nil {
+			s.log.Error("Failed to apply config", logger.Err(err))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{
+		"accounts": store.List(),
+		"order":    store.Order(),
+	})
+}
+
+// handleDeleteCookieAccount handles DELETE /api/cookie-accounts/{name}
+func (s *Server) handleDeleteCookieAccount(w http.ResponseWriter, r *http.Request) {
+	if err := s.downloader.CookieStore().Delete(chi.URLParam(r, "name")); err != nil {
+		switch {
+		case errors.Is(err, cookies.ErrInvalidAccount):
+			http.Error(w, "Invalid account name", http.StatusBadRequest)
+		case errors.Is(err, cookies.ErrAccountNotFound):
+			http.Error(w, "Account not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// bypassRequest is the body of PUT /api/bypass
+type bypassRequest struct {
+	Entries []string `json:"entries"`
+}
+
+// handleGetBypass handles GET /api/bypass
+func (s *Server) handleGetBypass(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{
+		"entries": s.GetBypassURLs(),
+	})
+}
+
+// handleSetBypass handles PUT /api/bypass
+// The entries are saved to BypassURLs when the config can be edited, so
+// later config changes and restarts keep them
+func (s *Server) handleSetBypass(w http.ResponseWriter, r *http.Request) {
+	var req bypassRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	cfgMgr := s.cfgMgr
+	s.mu.RUnlock()
+
+	if cfgMgr == nil {
+		if err := s.SetBypassURLs(req.Entries); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := cfgMgr.Update(func(c *models.Config) {
+			c.BypassURLs = append([]string{}, req.Entries...)
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.SetConfig(cfgMgr.Get()); err != nil {
+			s.log.Error("Failed to apply config", logger.Err(err))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{
+		"entries": s.GetBypassURLs(),
+	})
+}
+
+// handleGetBlocklist handles GET /api/blocklist
+func (s *Server) handleGetBlocklist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries":       s.cfg().BlockedURLs,
+		"subscriptions": s.blocker.Subscriptions(),
+	})
+}
+
+// handleExportBlocklist handles GET /api/blocklist/export
+// The local entries are written in the text format subscriptions and
+// imports read, so the file can be shared as is
+func (s *Server) handleExportBlocklist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="blocklist.txt"`)
+
+	fmt.Fprintf(w, "# VRCVideoCacher blocklist, exported %s\n", time.Now().UTC().Format(time.RFC3339))
+	for _, entry := range s.cfg().BlockedURLs {
+		fmt.Fprintln(w, entry)
+	}
+}
+
+// handleImportBlocklist handles POST /api/blocklist/import
+// Entries from a text blocklist are added to BlockedURLs and saved;
+// entries already present are skipped
+func (s *Server) handleImportBlocklist(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	cfgMgr := s.cfgMgr
+	s.mu.RUnlock()
+
+	if cfgMgr == nil {
+		http.Error(w, "Config editing not available", http.StatusNotImplemented)
 		return
 	}
 
-	// Extract video ID
-	videoID, err := extractYouTubeVideoID(videoURL)
+	entries, err := parseBlockList(io.LimitReader(r.Body, maxBlocklistSize))
 	if err != nil {
-		// If can't extract ID, bypass
-		w.Header().Set("Content-Type", "text/plain")
-		w.Write([]byte(""))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// Try to find cached file
-	cachedPath, err := s.cache.GetFilePath(videoID)
-	if err == nil {
-		// Cache hit - return cached URL
-		filename := filepath.Base(cachedPath)
-		cachedURL := fmt.Sprintf("%s/%s", s.config.WebServerURL, filename)
+	added := 0
+	if err := cfgMgr.Update(func(c *models.Config) {
+		for _, entry := range entries {
+			if !slices.Contains(c.BlockedURLs, entry) {
+				c.BlockedURLs = append(c.BlockedURLs, entry)
+				added++
+			}
+		}
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		// Update last access time
-		s.cache.UpdateLastAccess(videoID)
+	if err := s.SetConfig(cfgMgr.Get()); err != nil {
+		s.log.Error("Failed to apply config", logger.Err(err))
+	}
 
-		w.Header().Set("Content-Type", "text/plain")
-		w.Write([]byte(cachedURL))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"added":   added,
+		"entries": s.cfg().BlockedURLs,
+	})
+}
+
+// handleRefreshBlocklist handles POST /api/blocklist/refresh
+func (s *Server) handleRefreshBlocklist(w http.ResponseWriter, r *http.Request) {
+	s.blocker.Refresh(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"subscriptions": s.blocker.Subscriptions(),
+	})
+}
+
+// handleTestBlocklist handles GET /api/blocklist/test
+// Reports whether getvideo would block ?url= and which entry or crasher
+// heuristic matched
+func (s *Server) handleTestBlocklist(w http.ResponseWriter, r *http.Request) {
+	videoURL := r.URL.Query().Get("url")
+	if videoURL == "" {
+		http.Error(w, "No URL provided", http.StatusBadRequest)
 		return
 	}
 
-	// Cache miss - queue download
-	format := models.DownloadFormatMP4
-	if avpro {
-		format = models.DownloadFormatWebm
+	response := map[string]interface{}{
+		"url":     videoURL,
+		"blocked": false,
+	}
+	if match, ok := s.blocker.Match(videoURL); ok {
+		response["blocked"] = true
+		response["entry"] = match.Entry
+		response["source"] = match.Source
+	} else if s.cfg().CrasherProtection {
+		if err := checkCrasherURL(videoURL); err != nil {
+			response["blocked"] = true
+			response["source"] = crasherSource
+			response["reason"] = err.Error()
+		}
 	}
 
-	if err := s.downloader.Queue(videoID, videoURL, format); err != nil {
-		// Log error but don't fail the request
-		fmt.Printf("Failed to queue download for %s: %v\n", videoID, err)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DownloadInfo is the JSON representation of a download request
+type DownloadInfo struct {
+	VideoID       string     `json:"videoId"`
+	VideoURL      string     `json:"videoUrl"`
+	Format        string     `json:"format"`
+	Status        string     `json:"status"`
+	Progress      float64    `json:"progress"`
+	QueuedAt      time.Time  `json:"queuedAt"`
+	StartedAt     time.Time  `json:"startedAt"`
+	FinishedAt    time.Time  `json:"finishedAt"`
+	Error         string     `json:"error,omitempty"`
+	MaxRes        int        `json:"maxRes,omitempty"`
+	EstimatedSize int64      `json:"estimatedSize,omitempty"`
+	RequestID     string     `json:"requestId,omitempty"`
+	Live          bool       `json:"live,omitempty"`
+	Upgrade       bool       `json:"upgrade,omitempty"`
+	ScheduledAt   *time.Time `json:"scheduledAt,omitempty"`
+	LogFile       string     `json:"logFile,omitempty"`
+}
+
+// NewDownloadInfo converts a download request into its JSON representation
+func NewDownloadInfo(req *downloader.DownloadRequest) DownloadInfo {
+	info := DownloadInfo{
+		VideoID:       req.VideoID,
+		VideoURL:      req.VideoURL,
+		Format:        req.Format.String(),
+		Status:        req.Status.String(),
+		Progress:      req.Progress,
+		QueuedAt:      req.QueuedAt,
+		StartedAt:     req.StartedAt,
+		FinishedAt:    req.FinishedAt,
+		MaxRes:        req.MaxRes,
+		EstimatedSize: req.EstimatedSize,
+		RequestID:     req.RequestID,
+		Live:          req.Live,
+		Upgrade:       req.Upgrade,
+		LogFile:       req.LogFile,
+	}
+	if req.Error != nil {
+		info.Error = req.Error.Error()
+	}
+	if !req.ScheduledAt.IsZero() {
+		info.ScheduledAt = &req.ScheduledAt
+	}
+	return info
+}
+
+// handleListDownloads handles the /api/downloads endpoint
+func (s *Server) handleListDownloads(w http.ResponseWriter, r *http.Request) {
+	list, err := parseListQuery(r.URL.Query(), defaultListLimit, "status", "format")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Return empty (download will happen in background)
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte(""))
+	items := filterDownloads(s.downloader.ListDownloads(), list)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":  len(items),
+		"queued": s.downloader.GetQueueLength(),
+		"active": s.downloader.GetActiveDownloads(),
+		"items":  paginate(items, list),
+	})
 }
 
-// handleYouTubeCookies handles the /api/youtube-cookies endpoint
-func (s *Server) handleYouTubeCookies(w http.ResponseWriter, r *http.Request) {
-	// Read cookies from body
-	body, err := io.ReadAll(r.Body)
+// handleListFailedDownloads handles the /api/downloads/failed endpoint
+func (s *Server) handleListFailedDownloads(w http.ResponseWriter, r *http.Request) {
+	list, err := parseListQuery(r.URL.Query(), defaultListLimit, "format")
 	if err != nil {
-		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	cookies := string(body)
+	items := filterDownloads(s.downloader.ListFailed(), list)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total": len(items),
+		"items": paginate(items, list),
+	})
+}
+
+// filterDownloads converts the download requests matching list into their
+// JSON representation
+func filterDownloads(requests []*downloader.DownloadRequest, list listQuery) []DownloadInfo {
+	items := make([]DownloadInfo, 0, len(requests))
+	for _, req := range requests {
+		info := NewDownloadInfo(req)
+		if list.allows("status", info.Status) && list.allows("format", info.Format) &&
+			list.matches(info.VideoID, info.VideoURL) {
+			items = append(items, info)
+		}
+	}
+	return items
+}
+
+// handleRetryDownload handles the /api/downloads/{id}/retry endpoint
+func (s *Server) handleRetryDownload(w http.ResponseWriter, r *http.Request) {
+	videoID := chi.URLParam(r, "id")
 
-	// Validate cookies
-	if !validateCookies(cookies) {
-		http.Error(w, "Invalid cookies", http.StatusBadRequest)
+	if err := s.downloader.Retry(videoID); err != nil {
+		switch {
+		case errors.Is(err, downloader.ErrNotFound):
+			http.Error(w, "Failed download not found", http.StatusNotFound)
+		case errors.Is(err, downloader.ErrAlreadyQueued):
+			http.Error(w, "Download already queued", http.StatusConflict)
+		default:
+			http.Error(w, "Downloader unavailable", http.StatusServiceUnavailable)
+		}
 		return
 	}
 
-	// Save cookies to file
-	cookiesPath := filepath.Join(s.config.CachePath, "youtube_cookies.txt")
-	if err := s.saveCookies(cookiesPath, cookies); err != nil {
-		http.Error(w, "Failed to save cookies", http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Download queued",
+	})
+}
+
+// handleConfirmDownload handles the /api/downloads/{id}/confirm endpoint
+func (s *Server) handleConfirmDownload(w http.ResponseWriter, r *http.Request) {
+	videoID := chi.URLParam(r, "id")
+
+	if err := s.downloader.Confirm(videoID); err != nil {
+		if errors.Is(err, downloader.ErrNotFound) {
+			http.Error(w, "No download awaiting confirmation", http.StatusNotFound)
+		} else {
+			http.Error(w, "Downloader unavailable", http.StatusServiceUnavailable)
+		}
 		return
 	}
 
-	// Return success
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"status":  "success",
-		"message": "Cookies received",
+		"message": "Download queued",
+	})
+}
+
+// handleDeclineDownload handles the /api/downloads/{id}/decline endpoint
+func (s *Server) handleDeclineDownload(w http.ResponseWriter, r *http.Request) {
+	videoID := chi.URLParam(r, "id")
+
+	if err := s.downloader.Decline(videoID); err != nil {
+		http.Error(w, "No download awaiting confirmation", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Download discarded",
+	})
+}
+
+// handleCancelDownload handles DELETE /api/downloads/{id}
+func (s *Server) handleCancelDownload(w http.ResponseWriter, r *http.Request) {
+	videoID := chi.URLParam(r, "id")
+
+	if err := s.downloader.Cancel(videoID); err != nil {
+		http.Error(w, "No queued or active download", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Download cancelled",
 	})
 }
 
+// handleGetDownload handles the /api/downloads/{id} endpoint
+func (s *Server) handleGetDownload(w http.ResponseWriter, r *http.Request) {
+	videoID := chi.URLParam(r, "id")
+
+	req, err := s.downloader.GetStatus(videoID)
+	if err != nil {
+		http.Error(w, "Download not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(NewDownloadInfo(req))
+}
+
+// handleDownloadLog handles GET /api/downloads/{id}/log, the output of the
+// last yt-dlp run for a video
+func (s *Server) handleDownloadLog(w http.ResponseWriter, r *http.Request) {
+	path, err := s.downloader.DownloadLog(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "No download log", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	http.ServeFile(w, r, path)
+}
+
+// validVideoID matches IDs safe to use as cache file names and yt-dlp
+// arguments
+var validVideoID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
 // extractYouTubeVideoID extracts video ID from YouTube URL
 func extractYouTubeVideoID(urlStr string) (string, error) {
 	parsedURL, err := url.Parse(urlStr)
@@ -140,7 +1395,7 @@ func extractYouTubeVideoID(urlStr string) (string, error) {
 		// Path is /VIDEO_ID
 		videoID := strings.TrimPrefix(parsedURL.Path, "/")
 		if videoID != "" {
-			return videoID, nil
+			return checkVideoID(videoID)
 		}
 		return "", ErrVideoIDNotFound
 	}
@@ -151,7 +1406,7 @@ func extractYouTubeVideoID(urlStr string) (string, error) {
 		if parsedURL.Path == "/watch" {
 			videoID := parsedURL.Query().Get("v")
 			if videoID != "" {
-				return videoID, nil
+				return checkVideoID(videoID)
 			}
 		}
 
@@ -159,7 +1414,7 @@ func extractYouTubeVideoID(urlStr string) (string, error) {
 		if strings.HasPrefix(parsedURL.Path, "/embed/") {
 			videoID := strings.TrimPrefix(parsedURL.Path, "/embed/")
 			if videoID != "" {
-				return videoID, nil
+				return checkVideoID(videoID)
 			}
 		}
 
@@ -167,7 +1422,7 @@ func extractYouTubeVideoID(urlStr string) (string, error) {
 		if strings.HasPrefix(parsedURL.Path, "/v/") {
 			videoID := strings.TrimPrefix(parsedURL.Path, "/v/")
 			if videoID != "" {
-				return videoID, nil
+				return checkVideoID(videoID)
 			}
 		}
 	}
@@ -175,46 +1430,65 @@ func extractYouTubeVideoID(urlStr string) (string, error) {
 	return "", ErrVideoIDNotFound
 }
 
-// isYouTubeURL checks if URL is a YouTube URL
-func isYouTubeURL(urlStr string) bool {
-	if urlStr == "" {
-		return false
+// checkVideoID returns id if it is a valid video ID, so crafted links cannot
+// reach outside the cache directory
+func checkVideoID(id string) (string, error) {
+	if !validVideoID.MatchString(id) {
+		return "", ErrVideoIDNotFound
 	}
+	return id, nil
+}
 
+// startTimePattern matches YouTube start times: seconds ("90", "90s") or
+// "1h2m3s" style durations
+var startTimePattern = regexp.MustCompile(`^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s?)?$`)
+
+// videoStartTime returns the start time in seconds of a timestamped link
+// (the t or start query parameter, or a #t= fragment), or 0 if there is none
+func videoStartTime(urlStr string) int {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
-		return false
+		return 0
 	}
 
-	host := parsedURL.Hostname()
-	return strings.Contains(host, "youtube.com") || host == "youtu.be"
-}
+	value := parsedURL.Query().Get("t")
+	if value == "" {
+		value = parsedURL.Query().Get("start")
+	}
+	if value == "" {
+		value, _ = strings.CutPrefix(parsedURL.Fragment, "t=")
+	}
 
-// validateCookies validates YouTube cookies
-func validateCookies(cookies string) bool {
-	if cookies == "" {
-		return false
+	m := startTimePattern.FindStringSubmatch(strings.ToLower(value))
+	if m == nil {
+		return 0
 	}
 
-	// Check for youtube.com domain
-	if !strings.Contains(cookies, "youtube.com") {
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		n, _ := strconv.Atoi(m[i+1]) // Empty groups are 0
+		seconds += n * unit
+	}
+	return seconds
+}
+
+// isYouTubeURL checks if URL is a YouTube URL
+func isYouTubeURL(urlStr string) bool {
+	if urlStr == "" {
 		return false
 	}
 
-	// Check for LOGIN_INFO cookie (indicates logged in)
-	if !strings.Contains(cookies, "LOGIN_INFO") {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
 		return false
 	}
 
-	return true
+	host := parsedURL.Hostname()
+	return strings.Contains(host, "youtube.com") || host == "youtu.be"
 }
 
-// saveCookies saves cookies to file
-func (s *Server) saveCookies(path string, cookies string) error {
-	// Write cookies to file
-	if err := os.WriteFile(path, []byte(cookies), 0644); err != nil {
-		return fmt.Errorf("failed to write cookies file: %w", err)
-	}
-
-	return nil
+// validateCookies validates YouTube cookies in any supported format
+func validateCookies(data string) bool {
+	_, err := cookies.Normalize(data)
+	return err == nil
 }
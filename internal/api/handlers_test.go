@@ -1,18 +1,27 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/config"
 	"vrcvideocacher/pkg/models"
 )
 
@@ -105,6 +114,8 @@ func TestHandleYouTubeCookies(t *testing.T) {
 	cfg := models.DefaultConfig()
 
 	server := NewServer(cfg, cacheMgr)
+	cookieDir := t.TempDir()
+	require.NoError(t, server.Downloader().SetCookieDir(cookieDir))
 
 	tests := []struct {
 		name           string
@@ -146,6 +157,774 @@ func TestHandleYouTubeCookies(t *testing.T) {
 			assert.Contains(t, strings.ToLower(string(body)), tt.wantContains)
 		})
 	}
+
+	// Cookies are saved to the cookie directory, not the cache
+	assert.FileExists(t, filepath.Join(cookieDir, "youtube_cookies.txt"))
+	assert.NoFileExists(t, filepath.Join(tempDir, "youtube_cookies.txt"))
+}
+
+func TestHandleCacheMode(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	// Create cached file
+	testFile := filepath.Join(tempDir, "TEST123.mp4")
+	os.WriteFile(testFile, []byte("cached video"), 0644)
+	cacheMgr.AddEntry("TEST123", "TEST123.mp4")
+
+	server := NewServer(cfg, cacheMgr)
+	assert.True(t, server.IsCachingEnabled())
+
+	// Invalid body is rejected
+	req := httptest.NewRequest("POST", "/api/cache-mode", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Disable caching
+	req = httptest.NewRequest("POST", "/api/cache-mode", strings.NewReader(`{"enabled": false}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, server.IsCachingEnabled())
+
+	// Cached video is bypassed in pass-through mode
+	req = httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=TEST123", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// Current mode is reported
+	req = httptest.NewRequest("GET", "/api/cache-mode", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"enabled":false`)
+}
+
+func TestHandleBypass(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+	cfg.BypassURLs = []string{"youtu.be"}
+
+	// Create cached file
+	testFile := filepath.Join(tempDir, "TEST123.mp4")
+	os.WriteFile(testFile, []byte("cached video"), 0644)
+	cacheMgr.AddEntry("TEST123", "TEST123.mp4")
+
+	server := NewServer(cfg, cacheMgr)
+
+	// Allowlisted domain is passed through even when cached
+	req := httptest.NewRequest("GET", "/api/getvideo?url=https://youtu.be/TEST123", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// Invalid regex is rejected
+	req = httptest.NewRequest("PUT", "/api/bypass", strings.NewReader(`{"entries": ["regex:("]}`))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []string{"youtu.be"}, server.GetBypassURLs())
+
+	// Replace list at runtime
+	req = httptest.NewRequest("PUT", "/api/bypass", strings.NewReader(`{"entries": ["regex:watch\\?v=OTHER"]}`))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest("GET", "/api/getvideo?url=https://youtu.be/TEST123", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), "TEST123.mp4")
+}
+
+func TestHandleBypassSaved(t *testing.T) {
+	cfgMgr, err := config.NewManager(filepath.Join(t.TempDir(), "config.json"))
+	require.NoError(t, err)
+
+	server := NewServer(cfgMgr.Get(), cache.NewManager(t.TempDir(), 0))
+	server.SetConfigManager(cfgMgr)
+
+	req := httptest.NewRequest("PUT", "/api/bypass", strings.NewReader(`{"entries": ["example.com"]}`))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"example.com"}, cfgMgr.Get().BypassURLs)
+	assert.Equal(t, []string{"example.com"}, server.Config().BypassURLs)
+
+	// Later config changes keep the list
+	req = httptest.NewRequest("POST", "/api/blocklist/import", strings.NewReader("https://youtu.be/BLOCKED"))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"example.com"}, server.GetBypassURLs())
+
+	// Invalid entries are neither saved nor applied
+	req = httptest.NewRequest("PUT", "/api/bypass", strings.NewReader(`{"entries": ["regex:("]}`))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []string{"example.com"}, cfgMgr.Get().BypassURLs)
+	assert.Equal(t, []string{"example.com"}, server.GetBypassURLs())
+}
+
+func TestHandleGetVideoSourcePolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+	cfg.SourcePolicies[models.SourceVRChat] = models.SourcePolicy{MaxRes: 720}
+
+	// Create cached file
+	testFile := filepath.Join(tempDir, "TEST123.mp4")
+	os.WriteFile(testFile, []byte("cached video"), 0644)
+	cacheMgr.AddEntry("TEST123", "TEST123.mp4")
+
+	server := NewServer(cfg, cacheMgr)
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	// Resonite gets a yt-dlp style JSON object
+	req := httptest.NewRequest("GET", "/api/getvideo?source=resonite&url=https://www.youtube.com/watch?v=TEST123", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var info videoInfo
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&info))
+	assert.Equal(t, "TEST123", info.ID)
+	assert.Equal(t, "mp4", info.Ext)
+	assert.Contains(t, info.URL, "/TEST123.mp4")
+
+	// VRChat still gets a plain URL
+	req = httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=TEST123", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+
+	// Resonite downloads are mp4 even with avpro, VRChat uses its own resolution
+	req = httptest.NewRequest("GET", "/api/getvideo?source=resonite&avpro=true&url=https://www.youtube.com/watch?v=RESONITE", nil)
+	server.router.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest("GET", "/api/getvideo?avpro=true&url=https://www.youtube.com/watch?v=VRCHAT", nil)
+	server.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	status, err := server.downloader.GetStatus("RESONITE")
+	require.NoError(t, err)
+	assert.Equal(t, models.DownloadFormatMP4, status.Format)
+	assert.Equal(t, cfg.CacheYouTubeMaxRes, status.MaxRes)
+
+	status, err = server.downloader.GetStatus("VRCHAT")
+	require.NoError(t, err)
+	assert.Equal(t, models.DownloadFormatWebm, status.Format)
+	assert.Equal(t, 720, status.MaxRes)
+}
+
+func TestHandleGetVideoTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+	cfg.SourcePolicies["custom"] = models.SourcePolicy{
+		Response:    models.ResponseTemplate,
+		Template:    `{"src": {{json .URL}}, "id": {{json .ID}}, "from": {{json .Source}}}`,
+		ContentType: "application/json",
+	}
+	cfg.SourcePolicies["broken"] = models.SourcePolicy{
+		Response: models.ResponseTemplate,
+		Template: "{{.Missing}}",
+	}
+
+	os.WriteFile(filepath.Join(tempDir, "TEST123.mp4"), []byte("cached video"), 0644)
+	cacheMgr.AddEntry("TEST123", "TEST123.mp4")
+
+	server := NewServer(cfg, cacheMgr)
+
+	req := httptest.NewRequest("GET", "/api/getvideo?source=custom&url=https://www.youtube.com/watch?v=TEST123", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Contains(t, body["src"], "/TEST123.mp4?v=")
+	assert.Equal(t, "TEST123", body["id"])
+	assert.Equal(t, "custom", body["from"])
+
+	// A failing template falls back to the plain URL
+	req = httptest.NewRequest("GET", "/api/getvideo?source=broken&url=https://www.youtube.com/watch?v=TEST123", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "/TEST123.mp4?v=")
+}
+
+func TestHandleGetVideoStartTime(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+	cfg.SourcePolicies[models.SourceVRChat] = models.SourcePolicy{Seek: true}
+
+	os.WriteFile(filepath.Join(tempDir, "TEST123.mp4"), []byte("cached video"), 0644)
+	cacheMgr.AddEntry("TEST123", "TEST123.mp4")
+
+	server := NewServer(cfg, cacheMgr)
+
+	// Timestamped links hit the cache, with the offset as media fragment
+	req := httptest.NewRequest("GET", "/api/getvideo?url="+url.QueryEscape("https://www.youtube.com/watch?v=TEST123&t=1m30s"), nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), "/TEST123.mp4?v=")
+	assert.True(t, strings.HasSuffix(w.Body.String(), "#t=90"), w.Body.String())
+
+	// JSON responses carry it as start_time
+	req = httptest.NewRequest("GET", "/api/getvideo?source=resonite&url="+url.QueryEscape("https://youtu.be/TEST123?t=90"), nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var info videoInfo
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&info))
+	assert.Equal(t, 90, info.StartTime)
+	assert.NotContains(t, info.URL, "#t=")
+}
+
+func TestHandleGetVideoClientGone(t *testing.T) {
+	cacheMgr := cache.NewManager(t.TempDir(), 0)
+	server := NewServer(models.DefaultConfig(), cacheMgr)
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=GONE", nil).WithContext(ctx)
+	server.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Nothing is queued for a client that has already disconnected
+	assert.Equal(t, 0, server.downloader.GetQueueLength())
+	assert.Empty(t, server.history.List())
+}
+
+func TestHandleNowPlaying(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	// Create cached file
+	testFile := filepath.Join(tempDir, "TEST123.mp4")
+	os.WriteFile(testFile, []byte("cached video"), 0644)
+	cacheMgr.AddEntry("TEST123", "TEST123.mp4")
+
+	server := NewServer(cfg, cacheMgr)
+
+	// Nothing served yet
+	req := httptest.NewRequest("GET", "/api/now-playing", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"current":null`)
+
+	// Serve a bypassed and then a cached video
+	for _, u := range []string{"https://example.com/video.mp4", "https://www.youtube.com/watch?v=TEST123"} {
+		req = httptest.NewRequest("GET", "/api/getvideo?source=resonite&url="+u, nil)
+		server.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req = httptest.NewRequest("GET", "/api/now-playing", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var resp struct {
+		Current *servedVideo  `json:"current"`
+		History []servedVideo `json:"history"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.NotNil(t, resp.Current)
+	assert.Equal(t, "TEST123", resp.Current.VideoID)
+	assert.Equal(t, resultCached, resp.Current.Result)
+	assert.Equal(t, "resonite", resp.Current.Source)
+	assert.Contains(t, resp.Current.ServedURL, "TEST123.mp4")
+	require.Len(t, resp.History, 2)
+	assert.Equal(t, resultBypass, resp.History[1].Result)
+
+	// Filters apply to the history, not the current video
+	req = httptest.NewRequest("GET", "/api/now-playing?result=bypass&q=example.com", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	resp.History = nil
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "TEST123", resp.Current.VideoID)
+	require.Len(t, resp.History, 1)
+	assert.Equal(t, resultBypass, resp.History[0].Result)
+}
+
+func TestHandleListDownloads(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	server := NewServer(cfg, cacheMgr)
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	err := server.downloader.Queue("TEST123", "https://www.youtube.com/watch?v=TEST123", models.DownloadFormatMP4)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/api/downloads", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Total int            `json:"total"`
+		Items []DownloadInfo `json:"items"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, 1, resp.Total)
+	assert.Equal(t, "TEST123", resp.Items[0].VideoID)
+	assert.Equal(t, "mp4", resp.Items[0].Format)
+}
+
+func TestHandleGetDownload(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	server := NewServer(cfg, cacheMgr)
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	err := server.downloader.Queue("TEST123", "https://www.youtube.com/watch?v=TEST123", models.DownloadFormatWebm)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		id             string
+		wantStatusCode int
+		wantContains   string
+	}{
+		{
+			name:           "known download",
+			id:             "TEST123",
+			wantStatusCode: http.StatusOK,
+			wantContains:   "TEST123",
+		},
+		{
+			name:           "unknown download",
+			id:             "MISSING",
+			wantStatusCode: http.StatusNotFound,
+			wantContains:   "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/downloads/"+tt.id, nil)
+			w := httptest.NewRecorder()
+
+			server.router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatusCode, w.Code)
+
+			body, _ := io.ReadAll(w.Body)
+			assert.Contains(t, string(body), tt.wantContains)
+		})
+	}
+}
+
+func TestHandleRetryDownload(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	server := NewServer(cfg, cacheMgr)
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	// Unknown videos cannot be retried
+	req := httptest.NewRequest("POST", "/api/downloads/MISSING/retry", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Failed list is empty
+	req = httptest.NewRequest("GET", "/api/downloads/failed", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":0`)
+}
+
+func TestHandleConfirmDownload(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	server := NewServer(cfg, cacheMgr)
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	// Nothing is awaiting confirmation
+	for _, action := range []string{"confirm", "decline"} {
+		req := httptest.NewRequest("POST", "/api/downloads/MISSING/"+action, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandleCancelDownload(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	server := NewServer(cfg, cacheMgr)
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	// Offline, the download stays queued
+	server.downloader.SetOffline(true)
+	require.NoError(t, server.downloader.Queue("QUEUED", "https://www.youtube.com/watch?v=QUEUED", models.DownloadFormatMP4))
+
+	req := httptest.NewRequest("DELETE", "/api/downloads/QUEUED", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, server.downloader.GetQueueLength())
+
+	req = httptest.NewRequest("GET", "/api/downloads/QUEUED", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"status":"cancelled"`)
+
+	// Nothing to cancel
+	req = httptest.NewRequest("DELETE", "/api/downloads/MISSING", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleCookieAccounts(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	server := NewServer(cfg, cacheMgr)
+	cookieDir := t.TempDir()
+	require.NoError(t, server.Downloader().SetCookieDir(cookieDir))
+
+	cookies := `.youtube.com	TRUE	/	TRUE	0	LOGIN_INFO	test_cookie`
+	for _, account := range []string{"", "burner"} {
+		req := httptest.NewRequest("POST", "/api/youtube-cookies?account="+account, strings.NewReader(cookies))
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.FileExists(t, filepath.Join(cookieDir, "youtube_cookies.txt"))
+	assert.NoFileExists(t, filepath.Join(tempDir, "youtube_cookies.txt"))
+
+	// Invalid account name
+	req := httptest.NewRequest("POST", "/api/youtube-cookies?account=../x", strings.NewReader(cookies))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Set fallback order
+	req = httptest.NewRequest("PUT", "/api/cookie-accounts", strings.NewReader(`{"order": ["burner"]}`))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"order":["burner","default"]`)
+
+	// Delete account
+	req = httptest.NewRequest("DELETE", "/api/cookie-accounts/burner", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	req = httptest.NewRequest("GET", "/api/cookie-accounts", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"accounts":["default"]`)
+}
+
+func TestHandleCookieOrderSaved(t *testing.T) {
+	cfgMgr, err := config.NewManager(filepath.Join(t.TempDir(), "config.json"))
+	require.NoError(t, err)
+
+	server := NewServer(cfgMgr.Get(), cache.NewManager(t.TempDir(), 0))
+	server.SetConfigManager(cfgMgr)
+	require.NoError(t, server.Downloader().SetCookieDir(t.TempDir()))
+
+	store := server.Downloader().CookieStore()
+	for _, account := range []string{"", "burner"} {
+		require.NoError(t, store.Save(account, `.youtube.com	TRUE	/	TRUE	0	LOGIN_INFO	test_cookie`))
+	}
+
+	req := httptest.NewRequest("PUT", "/api/cookie-accounts", strings.NewReader(`{"order": ["burner"]}`))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"burner"}, cfgMgr.Get().YtdlCookieAccounts)
+
+	// Later config changes keep the order
+	req = httptest.NewRequest("POST", "/api/blocklist/import", strings.NewReader("https://youtu.be/BLOCKED"))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"burner", "default"}, store.Order())
+}
+
+func TestHandleCacheList(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "abc.mp4"), make([]byte, 100), 0644))
+	require.NoError(t, cacheMgr.AddEntry("abc", "abc.mp4"))
+
+	server := NewServer(cfg, cacheMgr)
+
+	req := httptest.NewRequest("GET", "/api/cache/list", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Total int                  `json:"total"`
+		Items []*models.CacheEntry `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 1, body.Total)
+	assert.Equal(t, "abc", body.Items[0].ID)
+
+	req = httptest.NewRequest("GET", "/api/cache/list?offset=5&sort=size", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 1, body.Total)
+	assert.Empty(t, body.Items)
+
+	req = httptest.NewRequest("GET", "/api/cache/list?sort=bogus", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Entries can be searched and filtered
+	req = httptest.NewRequest("GET", "/api/cache/list?q=ABC.MP4&origin=downloaded&private=false", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 1, body.Total)
+
+	req = httptest.NewRequest("GET", "/api/cache/list?origin=uploaded", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 0, body.Total)
+	assert.Empty(t, body.Items)
+
+	req = httptest.NewRequest("GET", "/api/cache/list?limit=-1", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest("DELETE", "/api/cache/abc", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoFileExists(t, filepath.Join(tempDir, "abc.mp4"))
+
+	req = httptest.NewRequest("DELETE", "/api/cache/abc", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleCachePreview(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	server := NewServer(models.DefaultConfig(), cacheMgr)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "abc.mp4"), make([]byte, 100), 0644))
+	require.NoError(t, cacheMgr.AddEntry("abc", "abc.mp4"))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	// Not generated yet
+	assert.Equal(t, http.StatusNotFound, get("/api/cache/abc/preview").Code)
+
+	entry, err := cacheMgr.GetEntry("abc")
+	require.NoError(t, err)
+	sprite := filepath.Join(t.TempDir(), "abc.jpg")
+	require.NoError(t, os.WriteFile(sprite, []byte("\xff\xd8\xff\xe0sprite"), 0644))
+	require.NoError(t, cacheMgr.SavePreview("abc", entry.Version(), sprite))
+
+	w := get("/api/cache/abc/preview")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+
+	assert.Equal(t, http.StatusNotFound, get("/api/cache/missing/preview").Code)
+}
+
+func TestHandleMoveCache(t *testing.T) {
+	tempDir := t.TempDir()
+	newDir := filepath.Join(t.TempDir(), "cache")
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "abc.mp4"), []byte("video"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("abc", "abc.mp4"))
+
+	cfgMgr, err := config.NewManager(filepath.Join(t.TempDir(), "config.json"))
+	require.NoError(t, err)
+
+	server := NewServer(cfg, cacheMgr)
+	server.SetConfigManager(cfgMgr)
+
+	req := httptest.NewRequest("POST", "/api/cache/move", strings.NewReader(`{"path": "`+filepath.ToSlash(newDir)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, newDir, cacheMgr.GetCachePath())
+	assert.Equal(t, newDir, cfgMgr.Get().CachePath)
+
+	// Files are served from the new location
+	req = httptest.NewRequest("GET", "/abc.mp4", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "video", w.Body.String())
+
+	// Moving onto itself is rejected
+	req = httptest.NewRequest("POST", "/api/cache/move", strings.NewReader(`{"path": "`+filepath.ToSlash(newDir)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleLogs(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	server := NewServer(cfg, cacheMgr)
+
+	// Request logging feeds the buffer
+	req := httptest.NewRequest("GET", "/api/health", nil)
+	server.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("GET", "/api/logs", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Lines []string `json:"lines"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.NotEmpty(t, body.Lines)
+	assert.Contains(t, body.Lines[0], "/api/health")
+}
+
+func TestHandleConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	server := NewServer(cfg, cacheMgr)
+
+	// Editing is unavailable without a config manager
+	req := httptest.NewRequest("PUT", "/api/config", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	cfgMgr, err := config.NewManager(filepath.Join(tempDir, "config.json"))
+	require.NoError(t, err)
+	server.SetConfigManager(cfgMgr)
+
+	req = httptest.NewRequest("GET", "/api/config", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"webServerPort"`)
+
+	updated := *cfgMgr.Get()
+	updated.BypassURLs = []string{"example.com"}
+	data, err := json.Marshal(updated)
+	require.NoError(t, err)
+
+	req = httptest.NewRequest("PUT", "/api/config", strings.NewReader(string(data)))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"example.com"}, cfgMgr.Get().BypassURLs)
+	assert.Equal(t, []string{"example.com"}, server.GetBypassURLs())
+
+	// Invalid config is rejected and not applied
+	updated.WebServerPort = -1
+	data, err = json.Marshal(updated)
+	require.NoError(t, err)
+
+	req = httptest.NewRequest("PUT", "/api/config", strings.NewReader(string(data)))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotEqual(t, -1, cfgMgr.Get().WebServerPort)
+}
+
+func TestHandleProfiles(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	server := NewServer(cfg, cacheMgr)
+
+	req := httptest.NewRequest("GET", "/api/profiles", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"quest2"`)
+
+	// Applying is unavailable without a config manager
+	req = httptest.NewRequest("PUT", "/api/profile", strings.NewReader(`{"name":"quest2"}`))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	cfgMgr, err := config.NewManager(filepath.Join(tempDir, "config.json"))
+	require.NoError(t, err)
+	server.SetConfigManager(cfgMgr)
+
+	req = httptest.NewRequest("PUT", "/api/profile", strings.NewReader(`{"name":"vive"}`))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest("PUT", "/api/profile", strings.NewReader(`{"name":"pcvr-4k"}`))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2160, cfgMgr.Get().CacheYouTubeMaxRes)
+
+	req = httptest.NewRequest("GET", "/api/profiles", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"current":"pcvr-4k"`)
 }
 
 func TestExtractYouTubeVideoID(t *testing.T) {
@@ -225,6 +1004,26 @@ func TestIsYouTubeURL(t *testing.T) {
 	}
 }
 
+func TestVideoStartTime(t *testing.T) {
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"https://youtu.be/TEST?t=90", 90},
+		{"https://www.youtube.com/watch?v=TEST&t=90s", 90},
+		{"https://www.youtube.com/watch?v=TEST&t=1h2m3s", 3723},
+		{"https://www.youtube.com/watch?v=TEST&t=2m", 120},
+		{"https://www.youtube.com/embed/TEST?start=45", 45},
+		{"https://www.youtube.com/watch?v=TEST#t=1m5s", 65},
+		{"https://www.youtube.com/watch?v=TEST", 0},
+		{"https://www.youtube.com/watch?v=TEST&t=abc", 0},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, videoStartTime(tt.url), tt.url)
+	}
+}
+
 func TestValidateCookies(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -261,3 +1060,271 @@ func TestValidateCookies(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleUploadCache(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+
+	upload := func(query string, fields map[string]string, filename, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for name, value := range fields {
+			mw.WriteField(name, value)
+		}
+		if filename != "" {
+			fw, _ := mw.CreateFormFile("file", filename)
+			fw.Write([]byte(content))
+		}
+		mw.Close()
+
+		req := httptest.NewRequest("POST", "/api/cache/upload"+query, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload("", map[string]string{"url": "https://youtu.be/EVENT"}, "event.mp4", "local copy")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var entry models.CacheEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+	assert.Equal(t, "EVENT", entry.ID)
+	assert.Equal(t, models.OriginUploaded, entry.Origin)
+
+	// The upload is served for the video
+	req := httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=EVENT", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), "/EVENT.mp4?v=")
+
+	// A plain ID works as query parameter too
+	w = upload("?id=OTHER", nil, "other.webm", "x")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	assert.Equal(t, http.StatusBadRequest, upload("", nil, "event.mp4", "x").Code, "no video named")
+	assert.Equal(t, http.StatusBadRequest, upload("?id=EVENT", nil, "event.mkv", "x").Code, "unsupported format")
+	assert.Equal(t, http.StatusBadRequest, upload("?id=EVENT", nil, "", "").Code, "no file")
+	assert.Equal(t, http.StatusBadRequest, upload("?url=https://example.com/v.mp4", nil, "v.mp4", "x").Code, "not YouTube")
+}
+
+func TestHandleGetVideoLive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	// Fake yt-dlp: LIVE is a live stream, anything else a video
+	script := filepath.Join(t.TempDir(), "fake-ytdlp.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+for last; do :; done
+case "$last" in
+*LIVE) echo '{"is_live": true, "url": "https://manifest.googlevideo.com/live.m3u8"}' ;;
+*) echo '{"is_live": false}' ;;
+esac
+`), 0755))
+
+	cfg := models.DefaultConfig()
+	cfg.YtdlPath = script
+	cfg.LiveRecord = true
+	server := NewServer(cfg, cache.NewManager(t.TempDir(), 0))
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+	server.downloader.SetOffline(true) // Keep the recording queued
+
+	// Live streams play directly and are recorded
+	req := httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=LIVE", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, "https://manifest.googlevideo.com/live.m3u8", w.Body.String())
+	assert.Equal(t, resultLive, server.history.List()[0].Result)
+
+	status, err := server.downloader.GetStatus("LIVE")
+	require.NoError(t, err)
+	assert.True(t, status.Live)
+
+	// JSON sources are told it is HLS
+	req = httptest.NewRequest("GET", "/api/getvideo?source=resonite&url=https://www.youtube.com/watch?v=LIVE", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	var info videoInfo
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&info))
+	assert.Equal(t, "m3u8_native", info.Protocol)
+
+	// Other videos are queued as usual
+	req = httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=VOD", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Empty(t, w.Body.String())
+
+	status, err = server.downloader.GetStatus("VOD")
+	require.NoError(t, err)
+	assert.False(t, status.Live)
+}
+
+func TestHandleGetVideoUpcoming(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	// Fake yt-dlp: every video is a premiere without a known start
+	script := filepath.Join(t.TempDir(), "fake-ytdlp.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+if [ "$1" = "-j" ]; then
+	echo '{"live_status": "is_upcoming"}'
+	exit 0
+fi
+echo "ERROR: [youtube] PREMIERE: Premieres in 3 days"
+exit 1
+`), 0755))
+
+	cfg := models.DefaultConfig()
+	cfg.YtdlPath = script
+	server := NewServer(cfg, cache.NewManager(t.TempDir(), 0))
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	getVideo := func() {
+		req := httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=PREMIERE", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String())
+	}
+
+	getVideo()
+	require.Eventually(t, func() bool {
+		status, err := server.downloader.GetStatus("PREMIERE")
+		return err == nil && status.Status.String() == "upcoming"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Later requests pass through, recorded as upcoming
+	getVideo()
+	assert.Equal(t, resultUpcoming, server.history.List()[0].Result)
+	assert.Empty(t, server.downloader.ListFailed())
+}
+
+func TestHandleGetVideoMembersOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	script := filepath.Join(t.TempDir(), "fake-ytdlp.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "ERROR: [youtube] MEMBERS: Join this channel to get access to members-only content like this video."
+exit 1
+`), 0755))
+
+	cfg := models.DefaultConfig()
+	cfg.YtdlPath = script
+	server := NewServer(cfg, cache.NewManager(t.TempDir(), 0))
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	getVideo := func() {
+		req := httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=MEMBERS", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String())
+	}
+
+	getVideo()
+	require.Eventually(t, func() bool {
+		status, err := server.downloader.GetStatus("MEMBERS")
+		return err == nil && status.Status.String() == "restricted"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Later requests pass through with the reason, without another attempt
+	getVideo()
+	served := server.history.List()[0]
+	assert.Equal(t, resultMembersOnly, served.Result)
+	assert.Contains(t, served.Reason, "members-only")
+	assert.Equal(t, 0, server.downloader.GetQueueLength())
+}
+
+func FuzzExtractYouTubeVideoID(f *testing.F) {
+	f.Add("https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	f.Add("https://youtu.be/dQw4w9WgXcQ?t=10")
+	f.Add("https://www.youtube.com/embed/dQw4w9WgXcQ")
+	f.Add("https://www.youtube.com/v/dQw4w9WgXcQ")
+	f.Add("https://youtu.be/..%2F..%2Fconfig")
+	f.Add("https://www.youtube.com/watch?v=a%00b")
+
+	f.Fuzz(func(t *testing.T, urlStr string) {
+		id, err := extractYouTubeVideoID(urlStr)
+		if err != nil {
+			return
+		}
+
+		// IDs become cache file names and yt-dlp arguments
+		if !validVideoID.MatchString(id) {
+			t.Errorf("extractYouTubeVideoID(%q) = %q, not a valid video ID", urlStr, id)
+		}
+	})
+}
+
+// newBenchmarkServer returns a server with VIDEO0 to VIDEO99 cached
+func newBenchmarkServer(b *testing.B) *Server {
+	tempDir := b.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("VIDEO%d.mp4", i)
+		require.NoError(b, os.WriteFile(filepath.Join(tempDir, name), bytes.Repeat([]byte("v"), 64<<10), 0644))
+		require.NoError(b, cacheMgr.AddEntry(fmt.Sprintf("VIDEO%d", i), name))
+	}
+	return NewServer(models.DefaultConfig(), cacheMgr)
+}
+
+func BenchmarkGetVideoCachedParallel(b *testing.B) {
+	server := newBenchmarkServer(b)
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			req := httptest.NewRequest("GET", fmt.Sprintf("/api/getvideo?avpro=false&url=https://www.youtube.com/watch?v=VIDEO%d", i%100), nil)
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK || w.Body.Len() == 0 {
+				b.Fatalf("getvideo returned %d %q", w.Code, w.Body.String())
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkServeCachedFileParallel(b *testing.B) {
+	server := newBenchmarkServer(b)
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			req := httptest.NewRequest("GET", fmt.Sprintf("/VIDEO%d.mp4", i%100), nil)
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				b.Fatalf("file returned %d", w.Code)
+			}
+			i++
+		}
+	})
+}
+
+func TestHandleDownloadLog(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+
+	req := httptest.NewRequest("GET", "/api/downloads/TEST123/log", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	dir := t.TempDir()
+	server.downloader.SetLogDir(dir)
+	name := "TEST123-" + time.Now().Format("20060102-150405.000") + ".log"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("[youtube] TEST123: Downloading\n"), 0644))
+
+	req = httptest.NewRequest("GET", "/api/downloads/TEST123/log", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Equal(t, "[youtube] TEST123: Downloading\n", w.Body.String())
+}
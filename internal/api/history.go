@@ -0,0 +1,76 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// maxServedHistory is the number of served videos kept for /api/now-playing
+const maxServedHistory = 20
+
+// Results recorded for each getvideo response
+const (
+	resultBlocked     = "blocked"
+	resultRejected    = "rejected"
+	resultAllowlisted = "allowlisted"
+	resultPassthrough = "passthrough"
+	resultBypass      = "bypass"
+	resultCached      = "cached"
+	resultQueued      = "queued"
+	resultStreaming   = "streaming"
+	resultLive        = "live"
+	resultUpcoming    = "upcoming"
+	resultDRM         = "drm"
+	resultMembersOnly = "members-only"
+)
+
+// servedVideo records a single getvideo response
+type servedVideo struct {
+	URL       string    `json:"url"`
+	VideoID   string    `json:"videoId,omitempty"`
+	ServedURL string    `json:"servedUrl"`
+	Source    string    `json:"source"`
+	Result    string    `json:"result"`
+	StartTime int       `json:"startTime,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// rule and detail explain the result in the decision log
+	rule   string
+	detail string
+}
+
+// servedHistory keeps the most recently served videos, newest first
+type servedHistory struct {
+	mu      sync.Mutex
+	entries []servedVideo
+	limit   int
+}
+
+// newServedHistory creates a history holding at most limit entries
+func newServedHistory(limit int) *servedHistory {
+	return &servedHistory{
+		entries: make([]servedVideo, 0, limit),
+		limit:   limit,
+	}
+}
+
+// Add records a served video
+func (h *servedHistory) Add(v servedVideo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append([]servedVideo{v}, h.entries...)
+	if len(h.entries) > h.limit {
+		h.entries = h.entries[:h.limit]
+	}
+}
+
+// List returns a copy of the history, newest first
+func (h *servedHistory) List() []servedVideo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]servedVideo{}, h.entries...)
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServedHistory(t *testing.T) {
+	h := newServedHistory(3)
+	assert.Empty(t, h.List())
+
+	for i := 0; i < 5; i++ {
+		h.Add(servedVideo{URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+
+	list := h.List()
+	require.Len(t, list, 3)
+	assert.Equal(t, "https://example.com/4", list[0].URL)
+	assert.Equal(t, "https://example.com/2", list[2].URL)
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/pkg/models"
+)
+
+// lanCheckTimeout bounds the reachability check of the LAN base URL
+const lanCheckTimeout = 5 * time.Second
+
+// lanRoutes are the API routes open to LAN clients; everything else under
+// /api, the dashboard and /ws stays local-only. Of the cache directory LAN
+// clients only get indexed entries, see cacheVersion
+var lanRoutes = map[string]bool{
+	"/api/health":   true,
+	"/api/getvideo": true,
+}
+
+// listenHost returns the host the server listens on: all interfaces in LAN
+// mode, loopback otherwise
+func listenHost(cfg *models.Config) string {
+	if cfg.LANMode {
+		return "0.0.0.0"
+	}
+	return "127.0.0.1"
+}
+
+// isLoopback reports whether a host or host:port is this machine
+func isLoopback(addr string) bool {
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// lanGuard rejects LAN clients outside lanRoutes and cached files while the
+// server listens on the LAN, or reaches them through a trusted proxy, so
+// only this machine can manage it
+func (s *Server) lanGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.fromLAN(r) && !lanRoutes[r.URL.Path] &&
+			(strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/dashboard") || r.URL.Path == "/ws") {
+			http.Error(w, "Only available from this machine", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// fromLAN reports whether r comes from another device, reaching the server
+// in LAN mode or through a trusted proxy
+func (s *Server) fromLAN(r *http.Request) bool {
+	s.mu.RLock()
+	lan := s.lan
+	s.mu.RUnlock()
+
+	return (lan || forwarded(r)) && !isLoopback(r.RemoteAddr)
+}
+
+// hiddenFromLAN reports whether entry is private and r comes from another
+// device, which must not be served it
+func hiddenFromLAN(r *http.Request, entry *models.CacheEntry) bool {
+	return entry.Private && !isLoopback(r.RemoteAddr)
+}
+
+// baseURL returns the base of cached file URLs for a request. Local clients
+// get webServerUrl; in LAN mode or through a trusted proxy, LAN clients get
+// lanBaseUrl or, if unset, the address of the interface they connected to
+func baseURL(r *http.Request, cfg *models.Config) string {
+	if (!cfg.LANMode && !forwarded(r)) || isLoopback(r.RemoteAddr) {
+		return cfg.WebServerURL
+	}
+	if cfg.LANBaseURL != "" {
+		return strings.TrimSuffix(cfg.LANBaseURL, "/")
+	}
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		return "http://" + addr.String()
+	}
+	return cfg.WebServerURL
+}
+
+// checkLANURL verifies LAN clients can reach the server at base
+func checkLANURL(client *http.Client, base string) error {
+	u, err := url.Parse(base)
+	if err != nil {
+		return err
+	}
+	if isLoopback(u.Hostname()) {
+		return fmt.Errorf("%s points to this machine's loopback address", u.Host)
+	}
+
+	resp, err := client.Get(strings.TrimSuffix(base, "/") + "/api/health")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// lanURLs returns the server URL on each LAN interface address
+func lanURLs(port int) []string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+
+	var urls []string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		urls = append(urls, "http://"+net.JoinHostPort(ipNet.IP.String(), fmt.Sprint(port)))
+	}
+	return urls
+}
+
+// announceLAN logs where LAN clients can reach the server, warning if the
+// configured LAN base URL is unreachable
+func (s *Server) announceLAN(port int) {
+	cfg := s.cfg()
+	if cfg.LANBaseURL != "" {
+		client := &http.Client{Timeout: lanCheckTimeout}
+		if err := checkLANURL(client, cfg.LANBaseURL); err != nil {
+			s.log.Warn("LAN base URL is unreachable", "url", cfg.LANBaseURL, logger.Err(err))
+		}
+		return
+	}
+
+	urls := lanURLs(port)
+	if len(urls) == 0 {
+		s.log.Warn("LAN mode is enabled but no network interface has a LAN address")
+		return
+	}
+	s.log.Info("LAN mode: serving LAN clients", "urls", strings.Join(urls, ", "))
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestBaseURL(t *testing.T) {
+	cfg := models.DefaultConfig()
+
+	lanReq := httptest.NewRequest("GET", "/api/getvideo", nil)
+	lanReq.RemoteAddr = "192.168.1.20:50000"
+	lanReq = lanReq.WithContext(context.WithValue(lanReq.Context(), http.LocalAddrContextKey,
+		&net.TCPAddr{IP: net.ParseIP("192.168.1.5"), Port: 9696}))
+
+	localReq := httptest.NewRequest("GET", "/api/getvideo", nil)
+	localReq.RemoteAddr = "127.0.0.1:50000"
+
+	// Without LAN mode every client gets webServerUrl
+	assert.Equal(t, "http://localhost:9696", baseURL(lanReq, cfg))
+
+	// LAN clients get the interface they connected to
+	cfg.LANMode = true
+	assert.Equal(t, "http://192.168.1.5:9696", baseURL(lanReq, cfg))
+	assert.Equal(t, "http://localhost:9696", baseURL(localReq, cfg))
+
+	// unless a LAN base URL is configured
+	cfg.LANBaseURL = "http://cacher.lan:9696/"
+	assert.Equal(t, "http://cacher.lan:9696", baseURL(lanReq, cfg))
+	assert.Equal(t, "http://localhost:9696", baseURL(localReq, cfg))
+}
+
+func TestLANGuard(t *testing.T) {
+	dir := t.TempDir()
+	cacheManager := cache.NewManager(dir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "VIDEO.mp4"), []byte("video"), 0644))
+	require.NoError(t, cacheManager.AddEntry("VIDEO", "VIDEO.mp4"))
+
+	cfg := models.DefaultConfig()
+	cfg.LANMode = true
+	server := NewServer(cfg, cacheManager)
+	server.lan = true
+
+	serve := func(path, remoteAddr string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/api/getvideo?url=https://www.youtube.com/watch?v=VIDEO", "192.168.1.20:50000"))
+	assert.Equal(t, http.StatusOK, serve("/VIDEO.mp4", "192.168.1.20:50000"))
+	assert.Equal(t, http.StatusForbidden, serve("/api/config", "192.168.1.20:50000"))
+	assert.Equal(t, http.StatusForbidden, serve("/dashboard/", "192.168.1.20:50000"))
+	assert.Equal(t, http.StatusOK, serve("/api/config", "127.0.0.1:50000"))
+
+	// Only indexed entries are served to other devices, without listings
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "youtube_cookies.txt"), []byte("cookies"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "VIDEO.mp4"), []byte("other"), 0644))
+	assert.Equal(t, http.StatusNotFound, serve("/youtube_cookies.txt", "192.168.1.20:50000"))
+	assert.Equal(t, http.StatusNotFound, serve("/cache-index.json", "192.168.1.20:50000"))
+	assert.Equal(t, http.StatusNotFound, serve("/sub/VIDEO.mp4", "192.168.1.20:50000"))
+	assert.Equal(t, http.StatusNotFound, serve("/", "192.168.1.20:50000"))
+	assert.Equal(t, http.StatusNotFound, serve("/", "127.0.0.1:50000"))
+}
+
+func TestLANPrivateEntry(t *testing.T) {
+	dir := t.TempDir()
+	cacheManager := cache.NewManager(dir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "VIDEO.mp4"), []byte("video"), 0644))
+	require.NoError(t, cacheManager.AddEntry("VIDEO", "VIDEO.mp4"))
+
+	cfg := models.DefaultConfig()
+	cfg.LANMode = true
+	cfg.CacheYouTube = true
+	server := NewServer(cfg, cacheManager)
+	server.lan = true
+	server.SetCachingEnabled(true)
+
+	serve := func(method, path, body, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	getvideo := "/api/getvideo?url=https://www.youtube.com/watch?v=VIDEO"
+
+	// Shared entries are served to every device
+	assert.Contains(t, serve("GET", getvideo, "", "192.168.1.20:50000").Body.String(), "VIDEO.mp4")
+
+	// Only this machine may mark entries private
+	assert.Equal(t, http.StatusForbidden, serve("PUT", "/api/cache/VIDEO/access", `{"private": true}`, "192.168.1.20:50000").Code)
+	w := serve("PUT", "/api/cache/VIDEO/access", `{"private": true}`, "127.0.0.1:50000")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"private":true`)
+
+	// Other devices play private entries from the original URL
+	w = serve("GET", getvideo, "", "192.168.1.20:50000")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/VIDEO.mp4", "", "192.168.1.20:50000").Code)
+
+	// and never see their names
+	for _, path := range []string{"/", "/stream/VIDEO.mp4", "/VIDEO.mp4?v=stale", "/api/cache"} {
+		w = serve("GET", path, "", "192.168.1.20:50000")
+		assert.NotContains(t, w.Body.String(), "VIDEO", path)
+		assert.NotContains(t, w.Header().Get("Location"), "VIDEO", path)
+	}
+
+	assert.Contains(t, serve("GET", getvideo, "", "127.0.0.1:50000").Body.String(), "VIDEO.mp4")
+	assert.Equal(t, http.StatusOK, serve("GET", "/VIDEO.mp4", "", "127.0.0.1:50000").Code)
+
+	assert.Equal(t, http.StatusBadRequest, serve("PUT", "/api/cache/VIDEO/access", `{}`, "127.0.0.1:50000").Code)
+	assert.Equal(t, http.StatusNotFound, serve("PUT", "/api/cache/MISSING/access", `{"private": false}`, "127.0.0.1:50000").Code)
+}
+
+func TestCheckLANURL(t *testing.T) {
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+
+	err := checkLANURL(client, "http://localhost:9696")
+	assert.ErrorContains(t, err, "loopback")
+
+	// TEST-NET-1 is never routed
+	assert.Error(t, checkLANURL(client, "http://192.0.2.1:9696"))
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidListQuery is returned for list parameters that cannot be parsed
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+// defaultListLimit is the default page size of list endpoints that can grow
+// without bound
+const defaultListLimit = 100
+
+// listQuery holds the pagination, search and field filters shared by list
+// endpoints:
+//
+//	?limit=&offset=  page through the matching items
+//	?q=              case-insensitive substring of an item's IDs or URLs
+//	?<field>=a,b     only items whose field is one of the listed values
+type listQuery struct {
+	limit   int
+	offset  int
+	search  string
+	filters map[string][]string
+}
+
+// parseListQuery parses the list parameters of query. limit defaults to
+// defaultLimit, and fields names the filters the endpoint supports
+func parseListQuery(query url.Values, defaultLimit int, fields ...string) (listQuery, error) {
+	q := listQuery{
+		limit:   defaultLimit,
+		search:  strings.ToLower(strings.TrimSpace(query.Get("q"))),
+		filters: make(map[string][]string),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("%w: limit must be a non-negative integer", ErrInvalidListQuery)
+		}
+		q.limit = n
+	}
+
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("%w: offset must be a non-negative integer", ErrInvalidListQuery)
+		}
+		q.offset = n
+	}
+
+	for _, field := range fields {
+		if v := query.Get(field); v != "" {
+			q.filters[field] = strings.Split(v, ",")
+		}
+	}
+
+	return q, nil
+}
+
+// matches reports whether one of values contains the search term. Every
+// item matches without one
+func (q listQuery) matches(values ...string) bool {
+	if q.search == "" {
+		return true
+	}
+	for _, v := range values {
+		if strings.Contains(strings.ToLower(v), q.search) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether value passes the filter on field. Every value
+// passes when the field is not filtered
+func (q listQuery) allows(field, value string) bool {
+	values, ok := q.filters[field]
+	return !ok || slices.Contains(values, value)
+}
+
+// paginate returns the page of items selected by q
+func paginate[T any](items []T, q listQuery) []T {
+	offset := min(q.offset, len(items))
+	end := offset + min(q.limit, len(items)-offset)
+	return items[offset:end]
+}
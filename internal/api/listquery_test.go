@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListQuery(t *testing.T) {
+	query, _ := url.ParseQuery("limit=2&offset=1&q=+YouTube+&status=failed,cancelled&ignored=x")
+	list, err := parseListQuery(query, 10, "status", "format")
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{2, 3}, paginate([]int{1, 2, 3, 4}, list))
+	assert.Equal(t, []int{}, paginate([]int{1}, list))
+
+	assert.True(t, list.matches("ID", "https://www.YOUTUBE.com/watch?v=ID"))
+	assert.False(t, list.matches("ID", "https://example.com/video.mp4"))
+
+	assert.True(t, list.allows("status", "cancelled"))
+	assert.False(t, list.allows("status", "completed"))
+	assert.True(t, list.allows("format", "mp4"))
+	assert.True(t, list.allows("ignored", "y"))
+
+	// Defaults
+	list, err = parseListQuery(url.Values{}, 10)
+	require.NoError(t, err)
+	assert.Len(t, paginate(make([]int, 20), list), 10)
+	assert.True(t, list.matches())
+
+	for _, raw := range []string{"limit=-1", "limit=x", "offset=-1", "offset=1.5"} {
+		query, _ := url.ParseQuery(raw)
+		_, err := parseListQuery(query, 10)
+		assert.ErrorIs(t, err, ErrInvalidListQuery, raw)
+	}
+}
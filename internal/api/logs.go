@@ -0,0 +1,54 @@
+package api
+
+import (
+	"strings"
+	"sync"
+)
+
+// maxLogLines is the number of log lines kept for /api/logs
+const maxLogLines = 500
+
+// logBuffer is an io.Writer keeping the most recent complete log lines
+type logBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	partial string
+	limit   int
+}
+
+// newLogBuffer creates a buffer holding at most limit lines
+func newLogBuffer(limit int) *logBuffer {
+	return &logBuffer{
+		lines: make([]string, 0, limit),
+		limit: limit,
+	}
+}
+
+// Write implements io.Writer
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data := b.partial + string(p)
+	parts := strings.Split(data, "\n")
+
+	// The last part is an incomplete line (empty if data ended with a newline)
+	b.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		b.lines = append(b.lines, line)
+	}
+
+	if len(b.lines) > b.limit {
+		b.lines = append([]string{}, b.lines[len(b.lines)-b.limit:]...)
+	}
+
+	return len(p), nil
+}
+
+// Lines returns a copy of the buffered lines, oldest first
+func (b *logBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]string{}, b.lines...)
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogBuffer(t *testing.T) {
+	b := newLogBuffer(3)
+
+	// Partial lines are held until completed
+	fmt.Fprint(b, "first ")
+	assert.Empty(t, b.Lines())
+	fmt.Fprint(b, "line\nsecond\n")
+	assert.Equal(t, []string{"first line", "second"}, b.Lines())
+
+	// Oldest lines are dropped beyond the limit
+	fmt.Fprint(b, "third\nfourth\n")
+	assert.Equal(t, []string{"second", "third", "fourth"}, b.Lines())
+}
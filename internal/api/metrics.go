@@ -0,0 +1,115 @@
+package api
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/internal/metrics"
+)
+
+// Routes whose served bytes are counted
+const (
+	routeCache  = "cache"
+	routeStream = "stream"
+)
+
+// cacheMissRules are the getvideo rules answering YouTube videos that were
+// not in the cache
+var cacheMissRules = map[string]bool{
+	ruleLive:        true,
+	ruleUncacheable: true,
+	ruleDownloaded:  true,
+	ruleQueued:      true,
+}
+
+// serverMetrics counts what the server answered since it was created
+type serverMetrics struct {
+	// getvideo counts getvideo requests by the rule that answered them
+	getvideo metrics.CounterVec
+	// served counts bytes of videos sent by route
+	served metrics.CounterVec
+}
+
+// countServed counts the bytes sent by the handlers of route
+func (s *Server) countServed(route string) func(http.Handler) http.Handler {
+	counter := s.metrics.served.With(route)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				counter.Add(uint64(ww.BytesWritten()))
+			}()
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+// handleMetrics serves the metrics in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	mw := metrics.NewWriter(w)
+
+	mw.Gauge("vrcvideocacher_cache_size_bytes", "Size of the cached videos.",
+		metrics.Sample{Value: float64(s.cache.GetSize())})
+	mw.Gauge("vrcvideocacher_cache_max_size_bytes", "Cache size limit, 0 if unlimited.",
+		metrics.Sample{Value: float64(s.cache.GetMaxSize())})
+	mw.Gauge("vrcvideocacher_cache_entries", "Number of cached videos.",
+		metrics.Sample{Value: float64(len(s.cache.ListEntries()))})
+
+	rules := s.metrics.getvideo.Values()
+	var hits, misses uint64
+	var requests []metrics.Sample
+	for _, rule := range slices.Sorted(maps.Keys(rules)) {
+		if rule == ruleCacheHit {
+			hits += rules[rule]
+		} else if cacheMissRules[rule] {
+			misses += rules[rule]
+		}
+		requests = append(requests, metrics.Sample{Labels: metrics.Labels{"rule": rule}, Value: float64(rules[rule])})
+	}
+	mw.Counter("vrcvideocacher_cache_hits_total", "YouTube videos served from the cache.",
+		metrics.Sample{Value: float64(hits)})
+	mw.Counter("vrcvideocacher_cache_misses_total", "YouTube videos that were not in the cache.",
+		metrics.Sample{Value: float64(misses)})
+	mw.Counter("vrcvideocacher_getvideo_requests_total", "getvideo requests by the rule that answered them.", requests...)
+
+	var served []metrics.Sample
+	for _, route := range []string{routeCache, routeStream} {
+		served = append(served, metrics.Sample{Labels: metrics.Labels{"route": route}, Value: float64(s.metrics.served.With(route).Value())})
+	}
+	mw.Counter("vrcvideocacher_served_bytes_total", "Bytes of videos sent to players.", served...)
+
+	paused := 0.0
+	if s.downloader.IsPaused() {
+		paused = 1
+	}
+	dm := s.downloader.Metrics()
+	mw.Gauge("vrcvideocacher_downloads_queued", "Downloads waiting for a worker.",
+		metrics.Sample{Value: float64(s.downloader.GetQueueLength())})
+	mw.Gauge("vrcvideocacher_downloads_active", "Downloads running.",
+		metrics.Sample{Value: float64(s.downloader.GetActiveDownloads())})
+	mw.Gauge("vrcvideocacher_downloads_paused", "1 if downloads are paused.",
+		metrics.Sample{Value: paused})
+	mw.Counter("vrcvideocacher_downloads_completed_total", "Downloads that finished.",
+		metrics.Sample{Value: float64(dm.Completed)})
+	mw.Counter("vrcvideocacher_downloads_failed_total", "Downloads that failed.",
+		metrics.Sample{Value: float64(dm.Failed)})
+
+	var durations []metrics.HistogramSample
+	var ytdlpErrors []metrics.Sample
+	for _, kind := range slices.Sorted(maps.Keys(dm.Ytdlp)) {
+		labels := metrics.Labels{"kind": kind}
+		durations = append(durations, metrics.HistogramSample{Labels: labels, Histogram: dm.Ytdlp[kind]})
+		ytdlpErrors = append(ytdlpErrors, metrics.Sample{Labels: labels, Value: float64(dm.YtdlpErrors[kind])})
+	}
+	mw.Histogram("vrcvideocacher_ytdlp_duration_seconds", "Duration of yt-dlp runs, downloads or metadata probes.", durations...)
+	mw.Counter("vrcvideocacher_ytdlp_errors_total", "yt-dlp runs that failed.", ytdlpErrors...)
+
+	if err := mw.Err(); err != nil {
+		s.reqLog(r).Debug("Failed to write metrics", logger.Err(err))
+	}
+}
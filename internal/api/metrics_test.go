@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestHandleMetrics(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "TEST123.mp4"), []byte("cached video"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("TEST123", "TEST123.mp4"))
+
+	server := NewServer(models.DefaultConfig(), cacheMgr)
+
+	for _, u := range []string{
+		"https://www.youtube.com/watch?v=TEST123",
+		"https://www.youtube.com/watch?v=TEST123",
+		"https://example.com/video.mp4",
+	} {
+		req := httptest.NewRequest("GET", "/api/getvideo?url="+url.QueryEscape(u), nil)
+		server.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	server.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/TEST123.mp4", nil))
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE vrcvideocacher_cache_size_bytes gauge\nvrcvideocacher_cache_size_bytes 12\n",
+		"vrcvideocacher_cache_entries 1\n",
+		"vrcvideocacher_cache_hits_total 2\n",
+		"vrcvideocacher_cache_misses_total 0\n",
+		`vrcvideocacher_getvideo_requests_total{rule="cache-hit"} 2` + "\n",
+		`vrcvideocacher_getvideo_requests_total{rule="non-youtube"} 1` + "\n",
+		`vrcvideocacher_served_bytes_total{route="cache"} 12` + "\n",
+		`vrcvideocacher_served_bytes_total{route="stream"} 0` + "\n",
+		"vrcvideocacher_downloads_queued 0\n",
+		"vrcvideocacher_downloads_failed_total 0\n",
+		`vrcvideocacher_ytdlp_duration_seconds_count{kind="download"} 0` + "\n",
+		`vrcvideocacher_ytdlp_errors_total{kind="probe"} 0` + "\n",
+	} {
+		assert.Contains(t, body, line)
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"vrcvideocacher/internal/downloader"
+)
+
+const (
+	// maxMetadataEntries bounds the overlay's video metadata cache
+	maxMetadataEntries = 100
+
+	// metadataTimeout bounds a background metadata lookup
+	metadataTimeout = 30 * time.Second
+
+	// thumbnailURL is YouTube's thumbnail for a video ID
+	thumbnailURL = "https://i.ytimg.com/vi/%s/hqdefault.jpg"
+)
+
+// overlayInfo is the GET /api/overlay response
+type overlayInfo struct {
+	Playing   bool       `json:"playing"`
+	VideoID   string     `json:"videoId,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	Thumbnail string     `json:"thumbnail,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	Elapsed   float64    `json:"elapsed"`
+	Duration  float64    `json:"duration"`
+	Progress  float64    `json:"progress"`
+}
+
+// metadataFetcher looks up a video's title and duration
+type metadataFetcher func(ctx context.Context, videoURL string) (downloader.VideoMetadata, error)
+
+// metadataCache remembers video metadata for the overlay, fetching missing
+// entries in the background so requests never wait on yt-dlp
+type metadataCache struct {
+	mu      sync.Mutex
+	entries map[string]*downloader.VideoMetadata
+	fetch   metadataFetcher
+}
+
+// newMetadataCache creates a cache that looks up videos with fetch
+func newMetadataCache(fetch metadataFetcher) *metadataCache {
+	return &metadataCache{
+		entries: make(map[string]*downloader.VideoMetadata),
+		fetch:   fetch,
+	}
+}
+
+// Get returns the metadata of a video if known, starting a lookup otherwise
+// Failed lookups are remembered as empty metadata and not retried
+func (c *metadataCache) Get(videoID, videoURL string) (downloader.VideoMetadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if meta, ok := c.entries[videoID]; ok {
+		if meta == nil {
+			return downloader.VideoMetadata{}, false // Lookup in progress
+		}
+		return *meta, true
+	}
+
+	// Make room, dropping any finished entry
+	if len(c.entries) >= maxMetadataEntries {
+		for id, meta := range c.entries {
+			if meta != nil {
+				delete(c.entries, id)
+				break
+			}
+		}
+	}
+	c.entries[videoID] = nil
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+		defer cancel()
+
+		meta, err := c.fetch(ctx, videoURL)
+		if err != nil {
+			meta = downloader.VideoMetadata{}
+		}
+
+		c.mu.Lock()
+		c.entries[videoID] = &meta
+		c.mu.Unlock()
+	}()
+
+	return downloader.VideoMetadata{}, false
+}
+
+// handleOverlay handles GET /api/overlay
+// The current video is the latest getvideo response if it was served from
+// the cache; it stops playing once its duration has elapsed
+func (s *Server) handleOverlay(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.overlay(time.Now()))
+}
+
+// overlay builds the overlay state at now
+func (s *Server) overlay(now time.Time) overlayInfo {
+	history := s.history.List()
+	if len(history) == 0 || history[0].Result != resultCached {
+		return overlayInfo{}
+	}
+
+	current := history[0]
+	info := overlayInfo{
+		Playing:   true,
+		VideoID:   current.VideoID,
+		Thumbnail: fmt.Sprintf(thumbnailURL, current.VideoID),
+		URL:       current.URL,
+		StartedAt: &current.Timestamp,
+		Elapsed:   now.Sub(current.Timestamp).Seconds(),
+	}
+
+	if meta, ok := s.metadata.Get(current.VideoID, current.URL); ok {
+		info.Title = meta.Title
+		info.Duration = meta.Duration
+	}
+
+	if info.Duration > 0 {
+		info.Progress = info.Elapsed / info.Duration
+		if info.Progress >= 1 {
+			info.Playing = false
+			info.Progress = 1
+			info.Elapsed = info.Duration
+		}
+	}
+
+	return info
+}
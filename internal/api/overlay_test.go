@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/downloader"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestOverlay(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+	server.metadata = newMetadataCache(func(ctx context.Context, videoURL string) (downloader.VideoMetadata, error) {
+		return downloader.VideoMetadata{Title: "Test Video", Duration: 100}, nil
+	})
+
+	// Nothing served yet
+	assert.False(t, server.overlay(time.Now()).Playing)
+
+	start := time.Now()
+	server.history.Add(servedVideo{
+		URL:       "https://www.youtube.com/watch?v=PLAYING",
+		VideoID:   "PLAYING",
+		ServedURL: "http://localhost:9696/PLAYING.mp4",
+		Result:    resultCached,
+		Timestamp: start,
+	})
+
+	// Metadata is fetched in the background
+	info := server.overlay(start.Add(25 * time.Second))
+	assert.True(t, info.Playing)
+	assert.Equal(t, "PLAYING", info.VideoID)
+	assert.Contains(t, info.Thumbnail, "/PLAYING/")
+
+	require.Eventually(t, func() bool {
+		return server.overlay(start).Title != ""
+	}, time.Second, 10*time.Millisecond)
+
+	info = server.overlay(start.Add(25 * time.Second))
+	assert.Equal(t, "Test Video", info.Title)
+	assert.InDelta(t, 0.25, info.Progress, 0.001)
+
+	// Finished once the duration has elapsed
+	info = server.overlay(start.Add(150 * time.Second))
+	assert.False(t, info.Playing)
+	assert.Equal(t, 1.0, info.Progress)
+
+	// A newer uncached video replaces it
+	server.history.Add(servedVideo{URL: "https://example.com/live", Result: resultBypass, Timestamp: start})
+	assert.False(t, server.overlay(start).Playing)
+
+	req := httptest.NewRequest("GET", "/api/overlay", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"playing":false,"elapsed":0,"duration":0,"progress":0}`, w.Body.String())
+
+	// The overlay page is served with the dashboard
+	req = httptest.NewRequest("GET", "/dashboard/overlay.html", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/api/overlay")
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// forwardedKey marks requests whose client address was taken from the
+// headers of a trusted proxy
+type forwardedKey struct{}
+
+// trustedProxies are the peers whose X-Forwarded-For and X-Real-IP headers
+// are believed
+type trustedProxies []netip.Prefix
+
+// newTrustedProxies builds the proxy list from config entries, IP addresses
+// or CIDR ranges. Invalid entries are skipped; config validation rejects
+// them
+func newTrustedProxies(entries []string) trustedProxies {
+	var proxies trustedProxies
+	for _, entry := range entries {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			proxies = append(proxies, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			addr = addr.Unmap()
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return proxies
+}
+
+// Contains reports whether addr is a trusted proxy
+func (t trustedProxies) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range t {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddr returns the client a request from a trusted proxy was made
+// for. X-Forwarded-For is read from the right, skipping further trusted
+// proxies, so addresses a client put in the header itself are never
+// reached. ok is false if the header the client is taken from is malformed
+func (t trustedProxies) clientAddr(peer netip.Addr, header http.Header) (client netip.Addr, ok bool) {
+	hops := header.Values("X-Real-IP")
+	if xff := header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops = strings.Split(strings.Join(xff, ","), ",")
+	}
+
+	client = peer.Unmap()
+	for i := len(hops) - 1; i >= 0 && t.Contains(client); i-- {
+		addr, err := parseHop(hops[i])
+		if err != nil {
+			return client, false
+		}
+		client = addr
+	}
+	return client, true
+}
+
+// parseHop parses an address in a forwarding header, with or without a port
+func parseHop(hop string) (netip.Addr, error) {
+	hop = strings.TrimSpace(hop)
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), nil
+	}
+	addr, err := netip.ParseAddr(hop)
+	return addr.Unmap(), err
+}
+
+// forwardedFor replaces the remote address of requests from trusted proxies
+// with the client they forward, so logging and the LAN checks see the
+// client rather than the proxy
+func (s *Server) forwardedFor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		proxies := s.proxies
+		s.mu.RUnlock()
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		peer, err := netip.ParseAddr(host)
+		if err != nil || !proxies.Contains(peer) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client, ok := proxies.clientAddr(peer, r.Header)
+		if !ok {
+			http.Error(w, "Invalid forwarding header", http.StatusBadRequest)
+			return
+		}
+		if client == peer.Unmap() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), forwardedKey{}, true))
+		r.RemoteAddr = client.String()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwarded reports whether r was forwarded by a trusted proxy
+func forwarded(r *http.Request) bool {
+	v, _ := r.Context().Value(forwardedKey{}).(bool)
+	return v
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestTrustedProxiesClientAddr(t *testing.T) {
+	proxies := newTrustedProxies([]string{"127.0.0.1", "10.0.0.0/8", "bogus"})
+	require.Len(t, proxies, 2)
+
+	peer := netip.MustParseAddr("127.0.0.1")
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+		ok     bool
+	}{
+		{"no header", http.Header{}, "127.0.0.1", true},
+		{"single hop", http.Header{"X-Forwarded-For": {"192.168.1.20"}}, "192.168.1.20", true},
+		{"chained trusted proxies", http.Header{"X-Forwarded-For": {"192.168.1.20, 10.1.2.3"}}, "192.168.1.20", true},
+		{"spoofed hops are not reached", http.Header{"X-Forwarded-For": {"127.0.0.1, 192.168.1.20"}}, "192.168.1.20", true},
+		{"repeated headers", http.Header{"X-Forwarded-For": {"192.168.1.20", "10.1.2.3"}}, "192.168.1.20", true},
+		{"with port", http.Header{"X-Forwarded-For": {"[2001:db8::1]:4711"}}, "2001:db8::1", true},
+		{"real IP", http.Header{"X-Real-Ip": {"192.168.1.20"}}, "192.168.1.20", true},
+		{"malformed", http.Header{"X-Forwarded-For": {"unknown"}}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, ok := proxies.clientAddr(peer, tt.header)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.want, client.String())
+			}
+		})
+	}
+}
+
+func TestForwardedFor(t *testing.T) {
+	cfg := models.DefaultConfig()
+	cfg.TrustedProxies = []string{"127.0.0.1"}
+	server := NewServer(cfg, cache.NewManager(t.TempDir(), 0))
+
+	serve := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest("GET", "/api/config", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// A LAN client behind a local proxy is not mistaken for this machine
+	assert.Equal(t, http.StatusForbidden, serve("127.0.0.1:50000", "192.168.1.20"))
+	assert.Equal(t, http.StatusOK, serve("127.0.0.1:50000", "127.0.0.1"))
+	assert.Equal(t, http.StatusBadRequest, serve("127.0.0.1:50000", "unknown"))
+
+	// Other peers cannot forward
+	server.lan = true
+	assert.Equal(t, http.StatusForbidden, serve("192.168.1.20:50000", "127.0.0.1"))
+
+	// Without trusted proxies the header is ignored
+	cfg.TrustedProxies = nil
+	require.NoError(t, server.SetConfig(cfg))
+	assert.Equal(t, http.StatusOK, serve("127.0.0.1:50000", "192.168.1.20"))
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"vrcvideocacher/internal/downloader"
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/internal/stats"
+)
+
+const (
+	// reportInterval is how long a stats period lasts before it is reported
+	reportInterval = 7 * 24 * time.Hour
+
+	// reportCheck is how often the reporter saves the stats and checks
+	// whether a report is due
+	reportCheck = time.Hour
+
+	// webhookTimeout bounds posting a report to the webhook
+	webhookTimeout = 30 * time.Second
+)
+
+// SetStats sets the collector fed with cache hits and finished downloads,
+// and the directory weekly reports are written to. Call before Start
+func (s *Server) SetStats(c *stats.Collector, reportDir string) {
+	s.mu.Lock()
+	s.stats = c
+	s.reportDir = reportDir
+	s.mu.Unlock()
+
+	s.downloader.SetFinishNotifier(func(req downloader.DownloadRequest) {
+		if req.Status != downloader.StatusCompleted {
+			if req.Error != nil {
+				c.Failed(req.VideoID, req.Error, req.FinishedAt)
+			}
+			return
+		}
+
+		var size int64
+		if entry, err := s.cache.GetEntry(req.VideoID); err == nil {
+			size = entry.Size
+		}
+		c.Cached(req.VideoID, size)
+	})
+}
+
+// statsCollector returns the collector, nil if stats are not collected
+func (s *Server) statsCollector() *stats.Collector {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stats
+}
+
+// startReports saves the stats and sends a report once a week until
+// stopReports. Must be called with s.mu held
+func (s *Server) startReports() {
+	if s.stats == nil || s.reportStop != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.reportStop = cancel
+	s.reportDone = done
+	c, dir := s.stats, s.reportDir
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(reportCheck)
+		defer ticker.Stop()
+
+		for {
+			s.checkReport(ctx, c, dir, time.Now())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopReports ends the reporter and saves the stats
+func (s *Server) stopReports() {
+	s.mu.Lock()
+	stop, done, c := s.reportStop, s.reportDone, s.stats
+	s.reportStop = nil
+	s.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	stop()
+	<-done
+
+	if err := c.Save(); err != nil {
+		s.log.Error("Failed to save stats", logger.Err(err))
+	}
+}
+
+// checkReport sends the report of c if its period is over, otherwise saves
+// the stats collected so far
+func (s *Server) checkReport(ctx context.Context, c *stats.Collector, dir string, now time.Time) {
+	if s.cfg().WeeklyReport && now.Sub(c.PeriodStart()) >= reportInterval {
+		s.sendReport(ctx, c, dir, now)
+		return
+	}
+
+	if err := c.Save(); err != nil {
+		s.log.Error("Failed to save stats", logger.Err(err))
+	}
+}
+
+// sendReport ends the stats period of c, writes its report to dir and
+// posts it to the webhook if one is configured
+func (s *Server) sendReport(ctx context.Context, c *stats.Collector, dir string, now time.Time) *stats.Report {
+	report, err := c.Rotate(now)
+	if err != nil {
+		s.log.Error("Failed to save stats", logger.Err(err))
+	}
+
+	if path, err := report.Write(dir); err != nil {
+		s.log.Error("Failed to write report", logger.Err(err))
+	} else {
+		s.log.Info("Wrote weekly report", "path", path)
+	}
+
+	if webhook := s.cfg().ReportWebhookURL; webhook != "" {
+		ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+		defer cancel()
+		if err := report.PostDiscord(ctx, http.DefaultClient, webhook); err != nil {
+			s.log.Error("Failed to post report", logger.Err(err))
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/stats"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestWeeklyReport(t *testing.T) {
+	cacheDir := t.TempDir()
+	dataDir := t.TempDir()
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "TEST123.mp4"), []byte("cached video"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("TEST123", "TEST123.mp4"))
+
+	posted := make(chan struct{}, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted <- struct{}{}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhook.Close()
+
+	cfg := models.DefaultConfig()
+	cfg.ReportWebhookURL = webhook.URL
+	server := NewServer(cfg, cacheMgr)
+
+	start := time.Now()
+	statsPath := filepath.Join(dataDir, stats.FileName)
+	reportDir := filepath.Join(dataDir, stats.ReportsDir)
+	c := stats.NewCollector(statsPath, start)
+	server.SetStats(c, reportDir)
+
+	req := httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=TEST123", nil)
+	server.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Not due yet: the stats are only saved
+	server.checkReport(context.Background(), c, reportDir, start.Add(time.Hour))
+	assert.FileExists(t, statsPath)
+	assert.NoDirExists(t, reportDir)
+
+	server.checkReport(context.Background(), c, reportDir, start.Add(reportInterval))
+	select {
+	case <-posted:
+	default:
+		t.Fatal("report was not posted to the webhook")
+	}
+
+	entries, err := os.ReadDir(reportDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	data, err := os.ReadFile(filepath.Join(reportDir, entries[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Played from cache: 1 times")
+	assert.Contains(t, string(data), "TEST123")
+
+	// The next period starts when the report was sent
+	assert.Equal(t, start.Add(reportInterval), c.PeriodStart())
+}
+
+func TestWeeklyReport_Disabled(t *testing.T) {
+	dataDir := t.TempDir()
+	cfg := models.DefaultConfig()
+	cfg.WeeklyReport = false
+	server := NewServer(cfg, cache.NewManager(t.TempDir(), 0))
+
+	start := time.Now()
+	reportDir := filepath.Join(dataDir, stats.ReportsDir)
+	c := stats.NewCollector(filepath.Join(dataDir, stats.FileName), start)
+	server.SetStats(c, reportDir)
+
+	server.checkReport(context.Background(), c, reportDir, start.Add(2*reportInterval))
+	assert.NoDirExists(t, reportDir)
+	assert.Equal(t, start, c.PeriodStart())
+}
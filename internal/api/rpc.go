@@ -0,0 +1,586 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"vrcvideocacher/internal/config"
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/pkg/models"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcAppError       = -32000
+	rpcUnauthorized   = -32001
+)
+
+// RPCTokenFileName is the file in the data directory holding the token
+// clients send in their first request
+const RPCTokenFileName = "rpc-token"
+
+// ErrNoRPCToken is returned when the RPC interface is started without a
+// token file
+var ErrNoRPCToken = errors.New("no RPC token file set")
+
+// httpMethods start the request line of an HTTP request; browsers can send
+// these to any local port, so connections starting with one are dropped
+var httpMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "CONNECT", "TRACE"}
+
+// maxRPCMessage bounds a single request line
+const maxRPCMessage = 1 << 20
+
+// openRPCSchema describes the control interface for client generators
+//
+//go:embed openrpc.json
+var openRPCSchema []byte
+
+// rpcRequest is a JSON-RPC 2.0 request; requests without an ID are
+// notifications and get no response
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC 2.0 error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// rpcMethod handles one JSON-RPC method
+type rpcMethod func(s *Server, params json.RawMessage) (interface{}, error)
+
+// rpcMethods is the control surface exposed over JSON-RPC, mirroring the
+// REST API. Keep openrpc.json in sync when adding methods
+var rpcMethods = map[string]rpcMethod{
+	"rpc.discover":  (*Server).rpcDiscover,
+	"status":        (*Server).rpcStatus,
+	"queue.list":    (*Server).rpcQueueList,
+	"queue.add":     (*Server).rpcQueueAdd,
+	"queue.retry":   (*Server).rpcQueueRetry,
+	"queue.confirm": (*Server).rpcQueueConfirm,
+	"queue.decline": (*Server).rpcQueueDecline,
+	"queue.cancel":  (*Server).rpcQueueCancel,
+	"cache.list":    (*Server).rpcCacheList,
+	"cache.delete":  (*Server).rpcCacheDelete,
+	"config.get":    (*Server).rpcConfigGet,
+	"config.set":    (*Server).rpcConfigSet,
+	"patch.list":    (*Server).rpcPatchList,
+	"patch.apply":   (*Server).rpcPatchApply,
+	"patch.revert":  (*Server).rpcPatchRevert,
+	"patch.verify":  (*Server).rpcPatchVerify,
+}
+
+// rpcServer serves newline-delimited JSON-RPC 2.0 on a local TCP socket
+// Each connection must start with an auth request carrying the token
+type rpcServer struct {
+	server    *Server
+	mu        sync.Mutex
+	tokenFile string
+	token     string
+	listener  net.Listener
+	conns     map[net.Conn]struct{}
+}
+
+// SetRPCTokenFile sets the file holding the token RPC clients must send,
+// created with a random token if missing. Call before Start
+func (s *Server) SetRPCTokenFile(path string) {
+	s.rpc.mu.Lock()
+	s.rpc.tokenFile = path
+	s.rpc.mu.Unlock()
+}
+
+// start listens on addr and serves connections in the background
+func (r *rpcServer) start(addr string) error {
+	r.mu.Lock()
+	tokenFile := r.tokenFile
+	r.mu.Unlock()
+
+	if tokenFile == "" {
+		return ErrNoRPCToken
+	}
+	token, err := loadRPCToken(tokenFile)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to create RPC listener: %w", err)
+	}
+
+	r.mu.Lock()
+	r.token = token
+	r.listener = listener
+	r.conns = make(map[net.Conn]struct{})
+	r.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			r.mu.Lock()
+			r.conns[conn] = struct{}{}
+			r.mu.Unlock()
+
+			go func() {
+				r.serveConn(conn)
+
+				r.mu.Lock()
+				delete(r.conns, conn)
+				r.mu.Unlock()
+			}()
+		}
+	}()
+
+	return nil
+}
+
+// stop closes the listener and all open connections
+// Requests already running finish in the background
+func (r *rpcServer) stop() {
+	r.mu.Lock()
+	if r.listener == nil {
+		r.mu.Unlock()
+		return
+	}
+	r.listener.Close()
+	r.listener = nil
+	for conn := range r.conns {
+		conn.Close()
+	}
+	r.mu.Unlock()
+}
+
+// addr returns the listening address, or "" if not listening
+func (r *rpcServer) addr() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.listener == nil {
+		return ""
+	}
+	return r.listener.Addr().String()
+}
+
+// loadRPCToken reads the token from path, writing a new random one if the
+// file is missing or empty
+func loadRPCToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read RPC token: %w", err)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate RPC token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create RPC token directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write RPC token: %w", err)
+	}
+	return token, nil
+}
+
+// serveConn answers requests on conn, one JSON object per line
+// The connection is closed on the first line that is not a valid JSON-RPC
+// request, and unless the first request is an auth with the token
+func (r *rpcServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRPCMessage)
+	enc := json.NewEncoder(conn)
+
+	authorized := false
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if !authorized && isHTTPRequest(line) {
+			return
+		}
+
+		req, rpcErr := parseRPC(line)
+		if rpcErr != nil {
+			enc.Encode(rpcFailure(req.ID, rpcErr))
+			return
+		}
+
+		if !authorized {
+			if !r.authorize(req) {
+				enc.Encode(rpcFailure(req.ID, &rpcError{Code: rpcUnauthorized, Message: "unauthorized"}))
+				return
+			}
+			authorized = true
+			if req.ID != nil {
+				if err := enc.Encode(&rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage("true")}); err != nil {
+					return
+				}
+			}
+			continue
+		}
+
+		if resp := r.server.runRPC(req); resp != nil {
+			if err := enc.Encode(resp); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// authorize reports whether req is an auth request with the right token
+func (r *rpcServer) authorize(req rpcRequest) bool {
+	if req.Method != "auth" {
+		return false
+	}
+	var p struct {
+		Token string `json:"token"`
+	}
+	if decodeParams(req.Params, &p) != nil || p.Token == "" {
+		return false
+	}
+
+	r.mu.Lock()
+	token := r.token
+	r.mu.Unlock()
+	return token != "" && subtle.ConstantTimeCompare([]byte(p.Token), []byte(token)) == 1
+}
+
+// isHTTPRequest reports whether line looks like an HTTP request line
+func isHTTPRequest(line []byte) bool {
+	for _, method := range httpMethods {
+		if bytes.HasPrefix(line, []byte(method+" ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRPC decodes a JSON-RPC request, returning a parse or invalid
+// request error if line is not one
+func parseRPC(data []byte) (rpcRequest, *rpcError) {
+	var req rpcRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return rpcRequest{}, &rpcError{Code: rpcParseError, Message: "parse error"}
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return req, &rpcError{Code: rpcInvalidRequest, Message: "invalid request"}
+	}
+	return req, nil
+}
+
+// handleRPC runs a single JSON-RPC request, returning nil for notifications
+func (s *Server) handleRPC(data []byte) *rpcResponse {
+	req, rpcErr := parseRPC(data)
+	if rpcErr != nil {
+		return rpcFailure(req.ID, rpcErr)
+	}
+	return s.runRPC(req)
+}
+
+// runRPC runs a parsed request, returning nil for notifications
+func (s *Server) runRPC(req rpcRequest) *rpcResponse {
+	method, ok := rpcMethods[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil
+		}
+		return rpcFailure(req.ID, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + req.Method})
+	}
+
+	result, err := method(s, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: rpcAppError, Message: err.Error()}
+		}
+		return rpcFailure(req.ID, rpcErr)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return rpcFailure(req.ID, &rpcError{Code: rpcAppError, Message: err.Error()})
+	}
+	return &rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: data}
+}
+
+// rpcFailure builds an error response; a missing ID is sent as null
+func rpcFailure(id json.RawMessage, err *rpcError) *rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &rpcResponse{JSONRPC: "2.0", ID: id, Error: err}
+}
+
+// decodeParams unmarshals named params into v
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return &rpcError{Code: rpcInvalidParams, Message: "missing params"}
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+// idParams are the params of methods acting on a single item
+type idParams struct {
+	ID string `json:"id"`
+}
+
+// decodeID reads {"id": "..."} params
+func decodeID(params json.RawMessage) (string, error) {
+	var p idParams
+	if err := decodeParams(params, &p); err != nil {
+		return "", err
+	}
+	if p.ID == "" {
+		return "", &rpcError{Code: rpcInvalidParams, Message: "id is required"}
+	}
+	return p.ID, nil
+}
+
+// targetParams are the params of patch methods
+type targetParams struct {
+	Target string `json:"target"`
+}
+
+// rpcDiscover returns the OpenRPC schema (rpc.discover)
+func (s *Server) rpcDiscover(json.RawMessage) (interface{}, error) {
+	return json.RawMessage(openRPCSchema), nil
+}
+
+// rpcStatus implements status
+func (s *Server) rpcStatus(json.RawMessage) (interface{}, error) {
+	return s.status(), nil
+}
+
+// rpcQueueList implements queue.list
+func (s *Server) rpcQueueList(json.RawMessage) (interface{}, error) {
+	requests := s.downloader.ListDownloads()
+
+	items := make([]DownloadInfo, 0, len(requests))
+	for _, req := range requests {
+		items = append(items, NewDownloadInfo(req))
+	}
+	return items, nil
+}
+
+// rpcQueueAdd implements queue.add, queueing a YouTube video for caching
+func (s *Server) rpcQueueAdd(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		URL    string `json:"url"`
+		Format string `json:"format"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	videoID, err := extractYouTubeVideoID(p.URL)
+	if err != nil || !isYouTubeURL(p.URL) {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: ErrVideoIDNotFound.Error()}
+	}
+
+	format := models.DownloadFormatMP4
+	if p.Format != "" {
+		f, ok := models.ParseDownloadFormat(p.Format)
+		if !ok {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "format must be mp4 or webm"}
+		}
+		format = f
+	}
+
+	if err := s.downloader.Queue(videoID, p.URL, format); err != nil {
+		return nil, err
+	}
+	return map[string]string{"videoId": videoID}, nil
+}
+
+// rpcQueueRetry implements queue.retry
+func (s *Server) rpcQueueRetry(params json.RawMessage) (interface{}, error) {
+	id, err := decodeID(params)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.downloader.Retry(id)
+}
+
+// rpcQueueConfirm implements queue.confirm
+func (s *Server) rpcQueueConfirm(params json.RawMessage) (interface{}, error) {
+	id, err := decodeID(params)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.downloader.Confirm(id)
+}
+
+// rpcQueueDecline implements queue.decline
+func (s *Server) rpcQueueDecline(params json.RawMessage) (interface{}, error) {
+	id, err := decodeID(params)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.downloader.Decline(id)
+}
+
+// rpcQueueCancel implements queue.cancel
+func (s *Server) rpcQueueCancel(params json.RawMessage) (interface{}, error) {
+	id, err := decodeID(params)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.downloader.Cancel(id)
+}
+
+// rpcCacheList implements cache.list
+func (s *Server) rpcCacheList(json.RawMessage) (interface{}, error) {
+	return s.cache.ListEntries(), nil
+}
+
+// rpcCacheDelete implements cache.delete
+func (s *Server) rpcCacheDelete(params json.RawMessage) (interface{}, error) {
+	id, err := decodeID(params)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.cache.DeleteEntry(id)
+}
+
+// rpcConfigGet implements config.get
+func (s *Server) rpcConfigGet(json.RawMessage) (interface{}, error) {
+	s.mu.RLock()
+	cfgMgr := s.cfgMgr
+	s.mu.RUnlock()
+
+	if cfgMgr != nil {
+		return cfgMgr.Get(), nil
+	}
+	return s.Config(), nil
+}
+
+// rpcConfigSet implements config.set, saving and applying a full config
+func (s *Server) rpcConfigSet(params json.RawMessage) (interface{}, error) {
+	s.mu.RLock()
+	cfgMgr := s.cfgMgr
+	s.mu.RUnlock()
+
+	if cfgMgr == nil {
+		return nil, errors.New("config editing not available")
+	}
+
+	var p struct {
+		Config *models.Config `json:"config"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Config == nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "config is required"}
+	}
+	if err := config.Validate(p.Config); err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+
+	if err := cfgMgr.Update(func(c *models.Config) { *c = *p.Config }); err != nil {
+		return nil, err
+	}
+	if err := s.SetConfig(cfgMgr.Get()); err != nil {
+		s.log.Error("Failed to apply config", logger.Err(err))
+	}
+	return cfgMgr.Get(), nil
+}
+
+// rpcPatchList implements patch.list
+func (s *Server) rpcPatchList(json.RawMessage) (interface{}, error) {
+	p, err := s.getPatcher()
+	if err != nil {
+		return nil, err
+	}
+	return p.ListTargets(), nil
+}
+
+// rpcPatchApply implements patch.apply, refusing binaries that are not a
+// known yt-dlp release or cannot be verified
+func (s *Server) rpcPatchApply(params json.RawMessage) (interface{}, error) {
+	p, err := s.getPatcher()
+	if err != nil {
+		return nil, err
+	}
+	var tp targetParams
+	if err := decodeParams(params, &tp); err != nil {
+		return nil, err
+	}
+	return nil, p.SafePatchTarget(tp.Target)
+}
+
+// rpcPatchRevert implements patch.revert
+func (s *Server) rpcPatchRevert(params json.RawMessage) (interface{}, error) {
+	p, err := s.getPatcher()
+	if err != nil {
+		return nil, err
+	}
+	var tp targetParams
+	if err := decodeParams(params, &tp); err != nil {
+		return nil, err
+	}
+	return nil, p.UnpatchTarget(tp.Target)
+}
+
+// rpcPatchVerify implements patch.verify
+func (s *Server) rpcPatchVerify(params json.RawMessage) (interface{}, error) {
+	p, err := s.getPatcher()
+	if err != nil {
+		return nil, err
+	}
+	var tp targetParams
+	if err := decodeParams(params, &tp); err != nil {
+		return nil, err
+	}
+	return p.VerifyTarget(tp.Target)
+}
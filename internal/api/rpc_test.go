@@ -0,0 +1,224 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/config"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestHandleRPC(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	tests := []struct {
+		name     string
+		request  string
+		wantCode int
+	}{
+		{"status", `{"jsonrpc":"2.0","id":1,"method":"status"}`, 0},
+		{"discover", `{"jsonrpc":"2.0","id":2,"method":"rpc.discover"}`, 0},
+		{"parse error", `{"jsonrpc":`, rpcParseError},
+		{"wrong version", `{"jsonrpc":"1.0","id":3,"method":"status"}`, rpcInvalidRequest},
+		{"unknown method", `{"jsonrpc":"2.0","id":4,"method":"nope"}`, rpcMethodNotFound},
+		{"missing params", `{"jsonrpc":"2.0","id":5,"method":"cache.delete"}`, rpcInvalidParams},
+		{"bad url", `{"jsonrpc":"2.0","id":6,"method":"queue.add","params":{"url":"https://example.com/a.mp4"}}`, rpcInvalidParams},
+		{"app error", `{"jsonrpc":"2.0","id":7,"method":"cache.delete","params":{"id":"MISSING"}}`, rpcAppError},
+		{"no patcher", `{"jsonrpc":"2.0","id":8,"method":"patch.list"}`, rpcAppError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := server.handleRPC([]byte(tt.request))
+			require.NotNil(t, resp)
+			assert.Equal(t, "2.0", resp.JSONRPC)
+
+			if tt.wantCode == 0 {
+				assert.Nil(t, resp.Error)
+				assert.NotEmpty(t, resp.Result)
+			} else {
+				require.NotNil(t, resp.Error)
+				assert.Equal(t, tt.wantCode, resp.Error.Code)
+			}
+		})
+	}
+
+	// Notifications are run but not answered
+	assert.Nil(t, server.handleRPC([]byte(`{"jsonrpc":"2.0","method":"queue.add","params":{"url":"https://www.youtube.com/watch?v=NOTIFY"}}`)))
+	_, err := server.downloader.GetStatus("NOTIFY")
+	assert.NoError(t, err)
+
+	// The schema lists every method
+	var schema struct {
+		Methods []struct {
+			Name string `json:"name"`
+		} `json:"methods"`
+	}
+	require.NoError(t, json.Unmarshal(openRPCSchema, &schema))
+	names := make(map[string]bool)
+	for _, m := range schema.Methods {
+		names[m.Name] = true
+	}
+	for name := range rpcMethods {
+		if name != "rpc.discover" {
+			assert.True(t, names[name], "method %s missing from openrpc.json", name)
+		}
+	}
+}
+
+func TestRPCConfigSet(t *testing.T) {
+	cfgMgr, err := config.NewManager(filepath.Join(t.TempDir(), "config.json"))
+	require.NoError(t, err)
+
+	server := NewServer(cfgMgr.Get(), cache.NewManager(t.TempDir(), 0))
+	server.SetConfigManager(cfgMgr)
+
+	cfg := cfgMgr.Get()
+	cfg.CacheYouTubeMaxRes = 720
+	params, err := json.Marshal(map[string]interface{}{"config": cfg})
+	require.NoError(t, err)
+
+	resp := server.handleRPC([]byte(`{"jsonrpc":"2.0","id":1,"method":"config.set","params":` + string(params) + `}`))
+	require.Nil(t, resp.Error)
+	assert.Equal(t, 720, cfgMgr.Get().CacheYouTubeMaxRes)
+	assert.Equal(t, 720, server.Config().CacheYouTubeMaxRes)
+
+	// Invalid configs are rejected
+	cfg.CacheYouTubeMaxRes = 1
+	params, err = json.Marshal(map[string]interface{}{"config": cfg})
+	require.NoError(t, err)
+	resp = server.handleRPC([]byte(`{"jsonrpc":"2.0","id":2,"method":"config.set","params":` + string(params) + `}`))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, rpcInvalidParams, resp.Error.Code)
+	assert.Equal(t, 720, cfgMgr.Get().CacheYouTubeMaxRes)
+}
+
+// startRPC starts the RPC interface of server and returns its token
+func startRPC(t *testing.T, server *Server) string {
+	t.Helper()
+
+	tokenFile := filepath.Join(t.TempDir(), RPCTokenFileName)
+	server.SetRPCTokenFile(tokenFile)
+	require.NoError(t, server.rpc.start("127.0.0.1:0"))
+	t.Cleanup(server.rpc.stop)
+
+	data, err := os.ReadFile(tokenFile)
+	require.NoError(t, err)
+	return strings.TrimSpace(string(data))
+}
+
+func TestRPCSocket(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+	token := startRPC(t, server)
+	assert.Len(t, token, 64)
+
+	conn, err := net.Dial("tcp", server.RPCAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(`{"jsonrpc":"2.0","id":"auth","method":"auth","params":{"token":"` + token + `"}}` + "\n" +
+		`{"jsonrpc":"2.0","id":"a","method":"status"}` + "\n" + `{"jsonrpc":"2.0","id":"b","method":"cache.list"}` + "\n"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	for _, id := range []string{`"auth"`, `"a"`, `"b"`} {
+		line, err := reader.ReadBytes('\n')
+		require.NoError(t, err)
+
+		var resp rpcResponse
+		require.NoError(t, json.Unmarshal(line, &resp))
+		assert.Equal(t, id, string(resp.ID))
+		assert.Nil(t, resp.Error)
+	}
+
+	server.rpc.stop()
+	assert.Empty(t, server.RPCAddr())
+}
+
+func TestRPCAuth(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+	token := startRPC(t, server)
+
+	// The token survives restarts
+	server.rpc.stop()
+	require.NoError(t, server.rpc.start("127.0.0.1:0"))
+	loaded, err := loadRPCToken(server.rpc.tokenFile)
+	require.NoError(t, err)
+	assert.Equal(t, token, loaded)
+
+	tests := []struct {
+		name  string
+		first string
+	}{
+		{"no auth", `{"jsonrpc":"2.0","id":1,"method":"status"}`},
+		{"wrong token", `{"jsonrpc":"2.0","id":1,"method":"auth","params":{"token":"nope"}}`},
+		{"not json", `hello`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", server.RPCAddr())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write([]byte(tt.first + "\n" + `{"jsonrpc":"2.0","id":2,"method":"status"}` + "\n"))
+			require.NoError(t, err)
+
+			// One error, then the connection is closed
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+			reader := bufio.NewReader(conn)
+			line, err := reader.ReadBytes('\n')
+			require.NoError(t, err)
+
+			var resp rpcResponse
+			require.NoError(t, json.Unmarshal(line, &resp))
+			require.NotNil(t, resp.Error)
+
+			_, err = reader.ReadBytes('\n')
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestRPCRejectsHTTP(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+	token := startRPC(t, server)
+
+	// A web page posting to the RPC port gets its body sent after the
+	// request line and headers; even with the token nothing may run
+	body := `{"jsonrpc":"2.0","method":"auth","params":{"token":"` + token + `"}}` + "\n" +
+		`{"jsonrpc":"2.0","id":1,"method":"queue.add","params":{"url":"https://www.youtube.com/watch?v=FROMWEB"}}` + "\n"
+	request := "POST / HTTP/1.1\r\nHost: 127.0.0.1\r\nContent-Type: text/plain\r\n" +
+		"Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+
+	conn, err := net.Dial("tcp", server.RPCAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(request))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	_, err = server.downloader.GetStatus("FROMWEB")
+	assert.Error(t, err)
+}
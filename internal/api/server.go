@@ -5,8 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"net"
 	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,17 +19,53 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 
 	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/config"
+	"vrcvideocacher/internal/cookies"
 	"vrcvideocacher/internal/downloader"
+	"vrcvideocacher/internal/events"
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/internal/network"
+	"vrcvideocacher/internal/patcher"
+	"vrcvideocacher/internal/stats"
+	"vrcvideocacher/internal/ytdl"
 	"vrcvideocacher/pkg/models"
 )
 
+const (
+	// drainTimeout is how long Stop waits for active downloads to finish
+	drainTimeout = 3 * time.Second
+
+	// cookieCheckInterval is how often stored cookies are checked for expiry
+	cookieCheckInterval = time.Hour
+
+	// apiTimeout bounds API and dashboard requests
+	apiTimeout = 30 * time.Second
+
+	// writeTimeout is the server-wide response write deadline
+	// Streaming routes clear it so long transfers are not cut off
+	writeTimeout = 15 * time.Second
+
+	// versionParam is the query parameter carrying a cached file's version
+	versionParam = "v"
+
+	// compressLevel is the gzip/deflate level for API responses
+	compressLevel = 5
+)
+
+// compressTypes are the API content types worth compressing
+// Media never matches, so video responses are passed through untouched
+var compressTypes = []string{"application/json", "text/plain"}
+
 var (
 	ErrServerAlreadyRunning = errors.New("server is already running")
 	ErrServerNotRunning     = errors.New("server is not running")
+	ErrDownloadsActive      = errors.New("downloads are still in progress")
+	ErrPatcherUnavailable   = errors.New("patcher not available")
 )
 
 // Server represents the HTTP server
 type Server struct {
+	cfgMu      sync.RWMutex
 	config     *models.Config
 	cache      *cache.Manager
 	downloader *downloader.Downloader
@@ -32,11 +73,40 @@ type Server struct {
 	server     *http.Server
 	listener   net.Listener
 	running    bool
+	stopping   bool
+	lan        bool
+	caching    bool
+	safeMode   bool
+	bypass     *bypassList
+	blocker    *blocker
+	cors       *corsPolicy
+	proxies    trustedProxies
+	templates  responseTemplates
+	history    *servedHistory
+	decisions  *decisionLog
+	stubs      *stubActivity
+	stats      *stats.Collector
+	reportDir  string
+	reportStop context.CancelFunc
+	reportDone chan struct{}
+	metadata   *metadataCache
+	cookieMon  *cookies.Monitor
+	network    *network.Monitor
+	cfgMgr     *config.Manager
+	ytdlMgr    *ytdl.Manager
+	patcher    *patcher.Patcher
+	rpc        *rpcServer
+	logs       *logBuffer
+	log        *slog.Logger
+	events     *events.Bus
+	sockets    *sockets
+	metrics    *serverMetrics
 	mu         sync.RWMutex
 }
 
 // NewServer creates a new HTTP server
 func NewServer(config *models.Config, cache *cache.Manager) *Server {
+	config = config.Clone()
 	dl := downloader.NewDownloader(config, cache, 2)
 
 	s := &Server{
@@ -44,8 +114,63 @@ func NewServer(config *models.Config, cache *cache.Manager) *Server {
 		cache:      cache,
 		downloader: dl,
 		router:     chi.NewRouter(),
+		caching:    true,
+		history:    newServedHistory(maxServedHistory),
+		decisions:  newDecisionLog(maxDecisions),
+		stubs:      newStubActivity(),
+		metadata:   newMetadataCache(dl.FetchMetadata),
+		logs:       newLogBuffer(maxLogLines),
+		events:     events.NewBus(),
+		sockets:    newSockets(),
+		metrics:    &serverMetrics{},
+	}
+	s.rpc = &rpcServer{server: s}
+
+	// Downloads, evictions and patches are streamed to /ws clients
+	dl.SetEventBus(s.events)
+	cache.SetEventBus(s.events)
+
+	// Records go to the app log and the buffer served at /api/logs
+	base := slog.New(logger.Tee(slog.Default().Handler(), logger.NewTextHandler(s.logs)))
+	s.log = base.With("component", "api")
+	dl.SetLogger(base.With("component", "downloader"))
+	setLogLevel(config.LogLevel)
+
+	bypass, err := newBypassList(config.BypassURLs)
+	if err != nil {
+		s.log.Warn("Ignoring bypass list", logger.Err(err))
+		bypass, _ = newBypassList(nil)
+	}
+	s.bypass = bypass
+	s.blocker = newBlocker(base.With("component", "blocklist"))
+	s.blocker.SetLocal(config.BlockedURLs)
+	s.blocker.SetSubscriptions(config.BlocklistURLs, time.Duration(config.BlocklistRefreshHours)*time.Hour)
+	s.cors = newCORSPolicy(config.CORSAllowedOrigins)
+	s.proxies = newTrustedProxies(config.TrustedProxies)
+	patcher.SetWinePrefix(config.WinePrefix)
+	patcher.SetResonitePath(config.ResonitePath)
+
+	templates, err := newResponseTemplates(config.SourcePolicies)
+	if err != nil {
+		s.log.Warn("Ignoring response templates", logger.Err(err))
+	}
+	s.templates = templates
+
+	if err := cache.SetForeignPolicy(config.CacheForeignFiles); err != nil {
+		s.log.Error("Failed to rescan cache", logger.Err(err))
 	}
 
+	s.cookieMon = cookies.NewMonitor(dl.CookieStore(), cookieCheckInterval, func(status cookies.AccountStatus) {
+		s.log.Warn("YouTube cookies expire, please log in again", "account", status.Account, "expiresAt", status.ExpiresAt.Format(time.RFC3339))
+	})
+
+	// Hold downloads while offline and retry them when the connection returns
+	s.network = network.NewMonitor(network.DefaultInterval)
+	s.network.OnChange(s.setOnline)
+	dl.SetNetworkCheck(func() bool {
+		return s.network.Check(context.Background())
+	})
+
 	s.setupRoutes()
 
 	return s
@@ -54,21 +179,118 @@ func NewServer(config *models.Config, cache *cache.Manager) *Server {
 // setupRoutes configures all routes
 func (s *Server) setupRoutes() {
 	// Middleware
-	s.router.Use(middleware.Logger)
+	s.router.Use(s.forwardedFor)
+	s.router.Use(requestID)
+	s.router.Use(s.requestLogger)
 	s.router.Use(middleware.Recoverer)
-	s.router.Use(middleware.Timeout(30 * time.Second))
+	s.router.Use(s.lanGuard)
 
 	// API routes
 	s.router.Route("/api", func(r chi.Router) {
-		r.Get("/health", s.handleHealth)
-		r.Get("/status", s.handleStatus)
-		r.Get("/getvideo", s.handleGetVideo)
-		r.Post("/youtube-cookies", s.handleYouTubeCookies)
+		r.Use(s.corsMiddleware)
+
+		// Uploads stream whole videos and must not time out
+		r.Post("/cache/upload", s.handleUploadCache)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(apiTimeout))
+			r.Use(middleware.Compress(compressLevel, compressTypes...))
+
+			r.Get("/health", s.handleHealth)
+			r.Get("/status", s.handleStatus)
+			r.Get("/getvideo", s.handleGetVideo)
+			r.Get("/now-playing", s.handleNowPlaying)
+			r.Get("/decisions", s.handleDecisions)
+			r.Get("/overlay", s.handleOverlay)
+			r.Get("/logs", s.handleLogs)
+			r.Get("/config", s.handleGetConfig)
+			r.Put("/config", s.handleSetConfig)
+			r.Get("/profiles", s.handleListProfiles)
+			r.Put("/profile", s.handleApplyProfile)
+			r.Get("/cache/list", s.handleListCache)
+			r.Get("/cache/aliases", s.handleListAliases)
+			r.With(requireJSON).Post("/cache/aliases", s.handleAddAlias)
+			r.Delete("/cache/aliases", s.handleDeleteAlias)
+			r.Delete("/cache/{id}", s.handleDeleteCache)
+			r.Put("/cache/{id}/access", s.handleSetCacheAccess)
+			r.Get("/cache/{id}/preview", s.handleCachePreview)
+			r.With(requireJSON).Post("/cache/move", s.handleMoveCache)
+			r.Post("/youtube-cookies", s.handleYouTubeCookies)
+			r.Get("/cookie-accounts", s.handleListCookieAccounts)
+			r.Get("/cookie-accounts/status", s.handleCookieStatus)
+			r.Put("/cookie-accounts", s.handleSetCookieOrder)
+			r.Delete("/cookie-accounts/{name}", s.handleDeleteCookieAccount)
+			r.Get("/cache-mode", s.handleGetCacheMode)
+			r.With(requireJSON).Post("/cache-mode", s.handleSetCacheMode)
+			r.Get("/bypass", s.handleGetBypass)
+			r.Put("/bypass", s.handleSetBypass)
+			r.Get("/blocklist", s.handleGetBlocklist)
+			r.Get("/blocklist/export", s.handleExportBlocklist)
+			r.Post("/blocklist/import", s.handleImportBlocklist)
+			r.Post("/blocklist/refresh", s.handleRefreshBlocklist)
+			r.Get("/blocklist/test", s.handleTestBlocklist)
+			r.Get("/downloads", s.handleListDownloads)
+			r.Get("/downloads/failed", s.handleListFailedDownloads)
+			r.Get("/downloads/{id}", s.handleGetDownload)
+			r.Delete("/downloads/{id}", s.handleCancelDownload)
+			r.Get("/downloads/{id}/log", s.handleDownloadLog)
+			r.Post("/downloads/{id}/retry", s.handleRetryDownload)
+			r.Post("/downloads/{id}/confirm", s.handleConfirmDownload)
+			r.Post("/downloads/{id}/decline", s.handleDeclineDownload)
+			r.Post("/actions/toggle-cache", s.handleActionToggleCache)
+			r.Post("/actions/patch", s.handleActionPatch)
+			r.Post("/actions/unpatch", s.handleActionUnpatch)
+			r.Post("/actions/pause-downloads", s.handleActionPauseDownloads)
+			r.Post("/actions/clear-queue", s.handleActionClearQueue)
+		})
+	})
+
+	// Web dashboard
+	s.router.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(apiTimeout))
+
+		r.Get("/dashboard", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/dashboard/", http.StatusMovedPermanently)
+		})
+		r.Handle("/dashboard/*", dashboardHandler())
+	})
+
+	// Prometheus metrics
+	s.router.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(apiTimeout))
+
+		r.Get("/metrics", s.handleMetrics)
+	})
+
+	// Event stream; the connection sets its own deadlines once upgraded
+	s.router.Get("/ws", s.handleWebSocket)
+
+	// Progressive downloads, streamed without a timeout
+	s.router.Group(func(r chi.Router) {
+		r.Use(noWriteDeadline)
+		r.Use(s.countServed(routeStream))
+
+		r.Get("/stream/{file}", s.handleStream)
 	})
 
-	// Static file serving (cache directory)
-	fileServer := http.FileServer(http.Dir(s.cache.GetCachePath()))
-	s.router.Handle("/*", fileServer)
+	// Static file serving (cache directory), streamed without a timeout
+	s.router.Group(func(r chi.Router) {
+		r.Use(noWriteDeadline)
+		r.Use(s.countServed(routeCache))
+		r.Use(s.cacheVersion)
+
+		r.Handle("/*", http.FileServer(cacheFS{s.cache}))
+	})
+}
+
+// noWriteDeadline clears the server write deadline so cached videos can
+// stream for as long as the client keeps reading
+func noWriteDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Not every ResponseWriter supports deadlines; ignore if unsupported
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		next.ServeHTTP(w, r)
+	})
 }
 
 // Start starts the HTTP server
@@ -92,54 +314,108 @@ func (s *Server) Start() error {
 	httpServer := &http.Server{
 		Handler:      s.router,
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: writeTimeout,
 		IdleTimeout:  60 * time.Second,
 	}
 	s.server = httpServer
 
 	s.running = true
+	s.lan = s.cfg().LANMode
 
 	// Start downloader
 	if err := s.downloader.Start(); err != nil {
 		return fmt.Errorf("failed to start downloader: %w", err)
 	}
 
+	// Watch cookies for expiry
+	s.cookieMon.Start()
+
+	// Watch for the internet connection dropping or returning
+	s.network.Start()
+
+	// Download subscribed blocklists and keep them fresh
+	s.blocker.Start()
+
+	// Save usage stats and send the weekly report
+	s.startReports()
+
+	// Serve the JSON-RPC control interface if enabled
+	if port := s.cfg().RPCPort; port > 0 {
+		if err := s.rpc.start(fmt.Sprintf("127.0.0.1:%d", port)); err != nil {
+			s.log.Error("JSON-RPC interface unavailable", logger.Err(err))
+		}
+	}
+
 	// Start server in goroutine
 	go func() {
 		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			fmt.Printf("Server error: %v\n", err)
+			s.log.Error("Server error", logger.Err(err))
 		}
 	}()
 
+	// Tell where LAN clients reach the server, checked once it is serving
+	if s.lan {
+		go s.announceLAN(listener.Addr().(*net.TCPAddr).Port)
+	}
+
 	return nil
 }
 
 // Stop gracefully stops the HTTP server
+// s.mu is only held to change state: requests still being served take it
+// while downloads drain and connections close
 func (s *Server) Stop() error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if !s.running {
+	if !s.running || s.stopping {
+		s.mu.Unlock()
 		return ErrServerNotRunning
 	}
+	s.stopping = true
+	httpServer := s.server
+	s.mu.Unlock()
+
+	// Let active downloads finish, then stop downloader
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), drainTimeout)
+	unfinished, _ := s.downloader.Drain(drainCtx)
+	drainCancel()
+	if len(unfinished) > 0 {
+		s.log.Warn("Stopping with unfinished downloads", "count", len(unfinished))
+	}
 
-	// Stop downloader first
 	if err := s.downloader.Stop(); err != nil {
-		fmt.Printf("Downloader stop error: %v\n", err)
+		s.log.Error("Downloader stop error", logger.Err(err))
+	}
+
+	s.cookieMon.Stop()
+	s.network.Stop()
+	s.blocker.Stop()
+	s.stopReports()
+	s.rpc.stop()
+
+	if err := s.cache.Flush(); err != nil {
+		s.log.Error("Failed to save cache index", logger.Err(err))
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := s.server.Shutdown(ctx); err != nil {
-		return fmt.Errorf("server shutdown failed: %w", err)
+	s.sockets.closeAll()
+	var shutdownErr error
+	if err := httpServer.Shutdown(ctx); err != nil {
+		// Connections still open when the timeout hits are closed
+		httpServer.Close()
+		shutdownErr = fmt.Errorf("server shutdown failed: %w", err)
 	}
 
+	s.mu.Lock()
 	s.running = false
+	s.stopping = false
+	s.lan = false
 	s.server = nil
 	s.listener = nil
+	s.mu.Unlock()
 
-	return nil
+	return shutdownErr
 }
 
 // IsRunning returns whether the server is currently running
@@ -149,9 +425,406 @@ func (s *Server) IsRunning() bool {
 	return s.running
 }
 
-// GetAddr returns the server address
+// SetCachingEnabled switches caching on or off at runtime
+// When disabled, every getvideo request is bypassed and nothing is downloaded
+func (s *Server) SetCachingEnabled(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.caching = enabled
+}
+
+// IsCachingEnabled returns whether caching is enabled
+func (s *Server) IsCachingEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.caching
+}
+
+// Config returns a copy of the configuration the server is using
+func (s *Server) Config() *models.Config {
+	return s.cfg().Clone()
+}
+
+// SetConfig applies a new configuration to the running server
+// Requests and newly queued downloads use it immediately; the port and
+// startup options (patching, tool installs) take effect after a restart
+func (s *Server) SetConfig(config *models.Config) error {
+	snapshot := config.Clone()
+	if s.IsSafeMode() {
+		applySafeMode(snapshot)
+	}
+
+	s.cfgMu.Lock()
+	s.config = snapshot
+	s.cfgMu.Unlock()
+
+	templates, err := newResponseTemplates(snapshot.SourcePolicies)
+	if err != nil {
+		s.log.Warn("Ignoring response templates", logger.Err(err))
+	}
+
+	s.mu.Lock()
+	s.cors = newCORSPolicy(snapshot.CORSAllowedOrigins)
+	s.proxies = newTrustedProxies(snapshot.TrustedProxies)
+	s.templates = templates
+	s.mu.Unlock()
+
+	patcher.SetWinePrefix(snapshot.WinePrefix)
+	patcher.SetResonitePath(snapshot.ResonitePath)
+	setLogLevel(snapshot.LogLevel)
+
+	s.blocker.SetLocal(snapshot.BlockedURLs)
+	s.blocker.SetSubscriptions(snapshot.BlocklistURLs, time.Duration(snapshot.BlocklistRefreshHours)*time.Hour)
+
+	// A new cache path (e.g. edited in the settings) switches the cache to
+	// it; MoveCache has already switched when it saves the path
+	var pathErr error
+	if s.cachePathChanged(snapshot.CachePath) {
+		if pathErr = s.SetCachePath(snapshot.CachePath); pathErr != nil {
+			s.log.Error("Failed to switch cache", "path", snapshot.CachePath, logger.Err(pathErr))
+			pathErr = fmt.Errorf("failed to switch cache path: %w", pathErr)
+		}
+	}
+
+	if err := s.cache.SetForeignPolicy(snapshot.CacheForeignFiles); err != nil {
+		s.log.Error("Failed to rescan cache", logger.Err(err))
+	}
+
+	s.downloader.SetConfig(snapshot)
+	return errors.Join(pathErr, s.SetBypassURLs(snapshot.BypassURLs))
+}
+
+// EnableSafeMode switches the server to troubleshooting mode until restart
+// Caching is disabled so every video is passed through, and cookies,
+// additional yt-dlp arguments, blocklists and crasher protection are ignored.
+// The saved config is not changed
+func (s *Server) EnableSafeMode() error {
+	s.mu.Lock()
+	s.safeMode = true
+	s.caching = false
+	s.mu.Unlock()
+
+	s.log.Info("Safe mode enabled: caching, cookies, additional yt-dlp arguments and blocklists are off")
+	return s.SetConfig(s.Config())
+}
+
+// IsSafeMode returns whether the server runs in safe mode
+func (s *Server) IsSafeMode() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.safeMode
+}
+
+// applySafeMode turns off the options safe mode ignores
+func applySafeMode(cfg *models.Config) {
+	cfg.YtdlUseCookies = false
+	cfg.YtdlAdditionalArgs = ""
+	cfg.BlockedURLs = nil
+	cfg.BlocklistURLs = nil
+	cfg.CrasherProtection = false
+}
+
+// cfg returns the current configuration
+// The returned config is shared and must not be modified
+func (s *Server) cfg() *models.Config {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.config
+}
+
+// SetBypassURLs replaces the pass-through allowlist at runtime
+func (s *Server) SetBypassURLs(entries []string) error {
+	bypass, err := newBypassList(entries)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bypass = bypass
+
+	return nil
+}
+
+// GetBypassURLs returns the current pass-through allowlist
+func (s *Server) GetBypassURLs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string{}, s.bypass.entries...)
+}
+
+// isBypassed reports whether a URL is on the pass-through allowlist
+func (s *Server) isBypassed(urlStr string) bool {
+	s.mu.RLock()
+	bypass := s.bypass
+	s.mu.RUnlock()
+	return bypass.Match(urlStr)
+}
+
+// SetConfigManager enables config editing through the API
+func (s *Server) SetConfigManager(m *config.Manager) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfgMgr = m
+}
+
+// SetPatcher enables patching through the JSON-RPC interface and the
+// patch action
+func (s *Server) SetPatcher(p *patcher.Patcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.patcher = p
+	p.SetEventBus(s.events)
+}
+
+// Events returns the bus of the events streamed to /ws clients
+func (s *Server) Events() *events.Bus {
+	return s.events
+}
+
+// getPatcher returns the patcher or ErrPatcherUnavailable
+func (s *Server) getPatcher() (*patcher.Patcher, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.patcher == nil {
+		return nil, ErrPatcherUnavailable
+	}
+	return s.patcher, nil
+}
+
+// SetYtdlManager reports the yt-dlp self-test result via /api/health, defers
+// yt-dlp updates while downloads run and runs downloads through the Python
+// zipapp when no native binary is installed.
+// If aria2c is enabled and installed, it is used as external downloader
+func (s *Server) SetYtdlManager(m *ytdl.Manager) {
+	s.mu.Lock()
+	s.ytdlMgr = m
+	s.mu.Unlock()
+
+	// Updates are not installed while downloads run yt-dlp
+	m.SetInUse(func() bool { return s.downloader.GetActiveDownloads() > 0 })
+
+	if cmd := m.Command(); len(cmd.Args) > 0 {
+		s.downloader.SetCommand(cmd.Path, cmd.Args)
+	}
+
+	if s.cfg().Aria2cEnabled {
+		if path, err := m.Aria2cPath(); err == nil {
+			s.downloader.SetAria2cPath(path)
+		}
+	}
+}
+
+// Network returns the connectivity monitor, e.g. to skip update checks when
+// starting offline
+func (s *Server) Network() *network.Monitor {
+	return s.network
+}
+
+// setOnline pauses or resumes downloads as connectivity changes
+func (s *Server) setOnline(online bool) {
+	s.downloader.SetOffline(!online)
+	if !online {
+		s.log.Warn("Internet connection lost, serving cached videos only until it returns")
+		return
+	}
+
+	if n := s.downloader.GetQueueLength(); n > 0 {
+		s.log.Info("Internet connection restored, resuming queued downloads", "count", n)
+	} else {
+		s.log.Info("Internet connection restored")
+	}
+}
+
+// MoveCache relocates the cache directory, pausing downloads while files
+// are moved and saving the new path to the config. The move is rolled back
+// if the config cannot be saved.
+func (s *Server) MoveCache(newPath string, progress func(cache.MoveProgress)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	defer s.downloader.Resume()
+	if _, err := s.downloader.Drain(ctx); err != nil {
+		return ErrDownloadsActive
+	}
+
+	oldPath := s.cache.GetCachePath()
+	if err := s.cache.MoveCache(newPath, progress); err != nil {
+		return err
+	}
+	newPath = s.cache.GetCachePath()
+
+	s.mu.RLock()
+	cfgMgr := s.cfgMgr
+	s.mu.RUnlock()
+
+	if cfgMgr != nil {
+		if err := cfgMgr.Update(func(c *models.Config) {
+			c.CachePath = newPath
+		}); err != nil {
+			if rbErr := s.cache.MoveCache(oldPath, nil); rbErr != nil {
+				s.log.Error("Failed to roll back cache move", logger.Err(rbErr))
+			}
+			return fmt.Errorf("failed to save cache path: %w", err)
+		}
+		if err := s.SetConfig(cfgMgr.Get()); err != nil {
+			s.log.Error("Failed to apply config", logger.Err(err))
+		}
+	}
+
+	s.log.Info("Cache moved", "from", oldPath, "to", newPath)
+	return nil
+}
+
+// SetCachePath switches the cache to another directory without moving its
+// files, rescanning the new directory. Downloads are paused until the
+// switch is done. Serves already reading a file from the old directory
+// complete
+func (s *Server) SetCachePath(newPath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	defer s.downloader.Resume()
+	if _, err := s.downloader.Drain(ctx); err != nil {
+		return ErrDownloadsActive
+	}
+
+	newPath, err := filepath.Abs(newPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve new cache path: %w", err)
+	}
+
+	oldPath := s.cache.GetCachePath()
+	if err := s.cache.SetCachePath(newPath); err != nil {
+		return err
+	}
+
+	s.log.Info("Cache switched", "from", oldPath, "to", newPath)
+	return nil
+}
+
+// cachePathChanged reports whether the configured cache path differs from
+// the directory the cache is using
+func (s *Server) cachePathChanged(configured string) bool {
+	if configured == "" {
+		return false
+	}
+	configured, err := filepath.Abs(configured)
+	if err != nil {
+		return false
+	}
+	current, err := filepath.Abs(s.cache.GetCachePath())
+	return err == nil && configured != current
+}
+
+// AddLocalFile copies a local video into the cache as the video named by
+// target, a YouTube URL or video ID. The original file is left in place
+func (s *Server) AddLocalFile(target, srcPath string) (*models.CacheEntry, error) {
+	id, err := videoIDFor(target)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.cache.AddLocalFile(id, srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Added local file to the cache", "path", srcPath, "id", entry.ID)
+	return entry, nil
+}
+
+// cacheFS serves files from the current cache directory, without
+// directory listings
+type cacheFS struct {
+	cache *cache.Manager
+}
+
+// Open implements http.FileSystem
+func (c cacheFS) Open(name string) (http.File, error) {
+	f, err := http.Dir(c.cache.GetCachePath()).Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err != nil || info.IsDir() {
+		f.Close()
+		return nil, fs.ErrNotExist
+	}
+	return f, nil
+}
+
+// cacheVersion tags cached files with their content version as an ETag
+// A request for an outdated version is redirected to the current one, so a
+// player holding a stale URL fetches the replaced file. Other devices are
+// only served indexed entries, never the index or other files kept next to
+// them
+func (s *Server) cacheVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Base(r.URL.Path)
+		entry, err := s.cache.GetEntry(strings.TrimSuffix(name, path.Ext(name)))
+		if err != nil || entry.FileName != name || r.URL.Path != "/"+name {
+			if s.fromLAN(r) {
+				http.NotFound(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if hiddenFromLAN(r, entry) {
+			http.NotFound(w, r)
+			return
+		}
+
+		version := entry.Version()
+		query := r.URL.Query()
+		if v := query.Get(versionParam); v != "" && v != version {
+			query.Set(versionParam, version)
+			current := *r.URL
+			current.RawQuery = query.Encode()
+
+			w.Header().Set("Cache-Control", "no-store")
+			http.Redirect(w, r, current.String(), http.StatusFound)
+			return
+		}
+
+		w.Header().Set("ETag", `"`+version+`"`)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setLogLevel applies the configured log level, keeping the current one
+// if it is invalid, which Validate reports
+func setLogLevel(name string) {
+	if l, err := logger.ParseLevel(name); err == nil {
+		logger.SetLevel(l)
+	}
+}
+
+// SetCookieNotifier sets the callback invoked when stored cookies need re-authentication
+func (s *Server) SetCookieNotifier(notify func(cookies.AccountStatus)) {
+	s.cookieMon.SetNotify(notify)
+}
+
+// SetConfirmNotifier sets the callback invoked when a download exceeds the
+// size limit and waits for confirmation
+func (s *Server) SetConfirmNotifier(notify func(downloader.DownloadRequest)) {
+	s.downloader.SetConfirmNotifier(notify)
+}
+
+// Downloader returns the server's downloader
+func (s *Server) Downloader() *downloader.Downloader {
+	return s.downloader
+}
+
+// GetAddr returns the server address, on all interfaces in LAN mode
 func (s *Server) GetAddr() string {
-	return fmt.Sprintf("127.0.0.1:%d", s.config.WebServerPort)
+	cfg := s.cfg()
+	return net.JoinHostPort(listenHost(cfg), fmt.Sprint(cfg.WebServerPort))
+}
+
+// RPCAddr returns the JSON-RPC listening address, or "" if it is disabled
+func (s *Server) RPCAddr() string {
+	return s.rpc.addr()
 }
 
 // GetActualAddr returns the actual listening address (useful when port is 0)
@@ -167,29 +840,56 @@ func (s *Server) GetActualAddr() string {
 }
 
 // handleHealth handles health check endpoint
+// The yt-dlp self-test result is included once a ytdl manager is set
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+	s.mu.RLock()
+	ytdlMgr := s.ytdlMgr
+	s.mu.RUnlock()
+
+	response := map[string]interface{}{
 		"status": "ok",
-	})
+	}
+	if ytdlMgr != nil {
+		health := ytdlMgr.Health()
+		if !health.CheckedAt.IsZero() && !health.Healthy {
+			response["status"] = "degraded"
+		}
+		response["ytdlp"] = health
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
 // handleStatus handles status endpoint
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.status())
+}
+
+// status returns the server status shared by REST and JSON-RPC
+func (s *Server) status() map[string]interface{} {
 	s.mu.RLock()
 	running := s.running
+	caching := s.caching
+	safeMode := s.safeMode
 	s.mu.RUnlock()
 
 	cacheSize := s.cache.GetSize()
 	cacheEntries := s.cache.ListEntries()
 
-	response := map[string]interface{}{
-		"running":    running,
-		"cacheSize":  cacheSize,
-		"cacheCount": len(cacheEntries),
-		"version":    "0.1.0",
+	return map[string]interface{}{
+		"running":         running,
+		"caching":         caching,
+		"safeMode":        safeMode,
+		"offline":         !s.network.Online(),
+		"cacheSize":       cacheSize,
+		"cacheMaxSize":    s.cache.GetMaxSize(),
+		"cacheCount":      len(cacheEntries),
+		"downloadsActive": s.downloader.GetActiveDownloads(),
+		"downloadsQueued": s.downloader.GetQueueLength(),
+		"downloadsPaused": s.downloader.IsPaused(),
+		"version":         "0.1.0",
+		"stubs":           s.stubs.List(),
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
 }
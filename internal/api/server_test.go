@@ -1,11 +1,15 @@
 package api
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +17,10 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/config"
+	"vrcvideocacher/internal/downloader"
+	"vrcvideocacher/internal/fakeytdlp"
+	"vrcvideocacher/internal/ytdl"
 	"vrcvideocacher/pkg/models"
 )
 
@@ -23,10 +31,105 @@ func TestNewServer(t *testing.T) {
 
 	server := NewServer(cfg, cacheMgr)
 	require.NotNil(t, server)
-	assert.Equal(t, cfg, server.config)
+	assert.Equal(t, cfg, server.Config())
 	assert.Equal(t, cacheMgr, server.cache)
 }
 
+func TestServerSetConfig(t *testing.T) {
+	cacheMgr := cache.NewManager(t.TempDir(), 0)
+	cfg := models.DefaultConfig()
+	server := NewServer(cfg, cacheMgr)
+
+	// The server keeps its own copy
+	cfg.WebServerURL = "http://changed"
+	assert.NotEqual(t, "http://changed", server.Config().WebServerURL)
+
+	updated := models.DefaultConfig()
+	updated.WebServerURL = "http://localhost:9999"
+	updated.BypassURLs = []string{"example.com"}
+	require.NoError(t, server.SetConfig(updated))
+
+	assert.Equal(t, "http://localhost:9999", server.Config().WebServerURL)
+	assert.True(t, server.isBypassed("https://example.com/video.mp4"))
+
+	// Mutating a returned copy does not affect the server
+	server.Config().WebServerURL = "http://mutated"
+	assert.Equal(t, "http://localhost:9999", server.Config().WebServerURL)
+
+	updated.BypassURLs = []string{"regex:("}
+	assert.Error(t, server.SetConfig(updated))
+}
+
+func TestServerSetConfig_CachePath(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := filepath.Join(t.TempDir(), "cache")
+	cacheMgr := cache.NewManager(oldDir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(oldDir, "old.mp4"), []byte("old"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("old", "old.mp4"))
+
+	cfg := models.DefaultConfig()
+	cfg.CachePath = oldDir
+	server := NewServer(cfg, cacheMgr)
+	cookieDir := t.TempDir()
+	require.NoError(t, server.downloader.SetCookieDir(cookieDir))
+	require.NoError(t, server.downloader.CookieStore().Save("", "cookies"))
+
+	// Changing the path switches the cache to the new directory, rescanned
+	require.NoError(t, os.MkdirAll(newDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(newDir, "new.mp4"), []byte("new"), 0644))
+	updated := models.DefaultConfig()
+	updated.CachePath = newDir
+	require.NoError(t, server.SetConfig(updated))
+
+	assert.Equal(t, newDir, cacheMgr.GetCachePath())
+	_, err := cacheMgr.GetEntry("new")
+	assert.NoError(t, err)
+	_, err = cacheMgr.GetEntry("old")
+	assert.ErrorIs(t, err, cache.ErrEntryNotFound)
+	assert.FileExists(t, filepath.Join(oldDir, "old.mp4"))
+	// Cookies stay out of the cache directory
+	assert.FileExists(t, filepath.Join(cookieDir, "youtube_cookies.txt"))
+	assert.NoFileExists(t, filepath.Join(newDir, "youtube_cookies.txt"))
+
+	req := httptest.NewRequest("GET", "/new.mp4", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "new", w.Body.String())
+
+	// Applying the same path again does not rescan
+	require.NoError(t, server.SetConfig(updated))
+	assert.Equal(t, newDir, cacheMgr.GetCachePath())
+}
+
+func TestEnableSafeMode(t *testing.T) {
+	cfg := models.DefaultConfig()
+	cfg.YtdlUseCookies = true
+	cfg.YtdlAdditionalArgs = "--proxy socks5://127.0.0.1:1080"
+	cfg.BlockedURLs = []string{"https://www.youtube.com/watch?v=BLOCKED"}
+	cfg.CrasherProtection = true
+	server := NewServer(cfg, cache.NewManager(t.TempDir(), 0))
+
+	require.NoError(t, server.EnableSafeMode())
+	assert.True(t, server.IsSafeMode())
+	assert.False(t, server.IsCachingEnabled())
+
+	// Configs applied later stay stripped
+	require.NoError(t, server.SetConfig(cfg))
+	applied := server.Config()
+	assert.False(t, applied.YtdlUseCookies)
+	assert.Empty(t, applied.YtdlAdditionalArgs)
+	assert.False(t, applied.CrasherProtection)
+
+	// Formerly blocked videos are passed through untouched
+	req := httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=BLOCKED", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, resultPassthrough, server.history.List()[0].Result)
+	assert.Equal(t, true, server.status()["safeMode"])
+}
+
 func TestServerStart(t *testing.T) {
 	tempDir := t.TempDir()
 	cacheMgr := cache.NewManager(tempDir, 0)
@@ -63,6 +166,49 @@ func TestServerStartAlreadyRunning(t *testing.T) {
 	assert.ErrorIs(t, err, ErrServerAlreadyRunning)
 }
 
+func TestServerStopServesWhileDraining(t *testing.T) {
+	t.Setenv(fakeytdlp.EnvDelay, "500ms")
+	cfg := models.DefaultConfig()
+	cfg.WebServerPort = 0
+	cfg.YtdlPath = fakeytdlp.Build(t)
+
+	server := NewServer(cfg, cache.NewManager(t.TempDir(), 0))
+	require.NoError(t, server.Start())
+	addr := server.listener.Addr().String()
+
+	require.NoError(t, server.downloader.Queue("drainVideo", "https://www.youtube.com/watch?v=drainVideo", models.DownloadFormatMP4))
+	require.Eventually(t, func() bool {
+		req, err := server.downloader.GetStatus("drainVideo")
+		return err == nil && req.Status == downloader.StatusDownloading
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Stop() }()
+	require.Eventually(t, func() bool {
+		server.mu.RLock()
+		defer server.mu.RUnlock()
+		return server.stopping
+	}, time.Second, time.Millisecond)
+
+	// Requests take s.mu in the middleware; they are answered while the
+	// download drains instead of waiting for Stop
+	client := &http.Client{Timeout: time.Second}
+	resp, err := client.Get("http://" + addr + "/api/status")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned before the download drained: %v", err)
+	default:
+	}
+
+	require.NoError(t, <-stopped)
+	assert.False(t, server.IsRunning())
+	assert.ErrorIs(t, server.Stop(), ErrServerNotRunning)
+}
+
 func TestServerStopNotRunning(t *testing.T) {
 	tempDir := t.TempDir()
 	cacheMgr := cache.NewManager(tempDir, 0)
@@ -97,6 +243,141 @@ func TestStaticFileServing(t *testing.T) {
 	assert.Equal(t, testContent, w.Body.Bytes())
 }
 
+func TestCachedFileVersion(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	testFile := filepath.Join(tempDir, "VERSIONED.mp4")
+	require.NoError(t, os.WriteFile(testFile, []byte("low quality"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("VERSIONED", "VERSIONED.mp4"))
+
+	server := NewServer(models.DefaultConfig(), cacheMgr)
+
+	getVideo := func() string {
+		req := httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=VERSIONED", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	served := getVideo()
+	entry, err := cacheMgr.GetEntry("VERSIONED")
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(served, "/VERSIONED.mp4?v="+entry.Version()))
+	oldURL := "/VERSIONED.mp4?v=" + entry.Version()
+
+	req := httptest.NewRequest("GET", oldURL, nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"`+entry.Version()+`"`, w.Header().Get("ETag"))
+
+	// Replace the file with a different variant
+	require.NoError(t, os.WriteFile(testFile, []byte("high quality video"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("VERSIONED", "VERSIONED.mp4"))
+	assert.NotEqual(t, served, getVideo())
+
+	// The stale URL redirects to the current version
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", oldURL, nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	entry, err = cacheMgr.GetEntry("VERSIONED")
+	require.NoError(t, err)
+	assert.Equal(t, "/VERSIONED.mp4?v="+entry.Version(), w.Header().Get("Location"))
+
+	// Unversioned requests still work
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/VERSIONED.mp4", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "high quality video", w.Body.String())
+}
+
+func TestCompression(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "VIDEO.mp4"), []byte(strings.Repeat("video", 1000)), 0644))
+	require.NoError(t, cacheMgr.AddEntry("VIDEO", "VIDEO.mp4"))
+
+	server := NewServer(models.DefaultConfig(), cacheMgr)
+
+	// JSON API responses are compressed
+	req := httptest.NewRequest("GET", "/api/cache/list", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+	zr, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	var list map[string]interface{}
+	require.NoError(t, json.NewDecoder(zr).Decode(&list))
+	assert.Contains(t, list, "items")
+
+	// Video files are not
+	req = httptest.NewRequest("GET", "/VIDEO.mp4", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, 5000, w.Body.Len())
+}
+
+func TestNoWriteDeadline(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("video data"))
+	})
+
+	get := func(h http.Handler) (string, error) {
+		ts := httptest.NewUnstartedServer(h)
+		ts.Config.WriteTimeout = 50 * time.Millisecond
+		ts.Start()
+		defer ts.Close()
+
+		resp, err := http.Get(ts.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	// The server write deadline cuts off a slow response...
+	_, err := get(slow)
+	assert.Error(t, err)
+
+	// ...unless the streaming middleware clears it
+	body, err := get(noWriteDeadline(slow))
+	require.NoError(t, err)
+	assert.Equal(t, "video data", body)
+}
+
+func TestDashboard(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	server := NewServer(cfg, cacheMgr)
+
+	req := httptest.NewRequest("GET", "/dashboard", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+
+	req = httptest.NewRequest("GET", "/dashboard/", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "<html")
+
+	req = httptest.NewRequest("GET", "/dashboard/app.js", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
 func TestHealthEndpoint(t *testing.T) {
 	tempDir := t.TempDir()
 	cacheMgr := cache.NewManager(tempDir, 0)
@@ -115,6 +396,33 @@ func TestHealthEndpoint(t *testing.T) {
 	assert.Contains(t, string(body), "ok")
 }
 
+func TestHealthEndpointYtdl(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	cfg := models.DefaultConfig()
+
+	server := NewServer(cfg, cacheMgr)
+	ytdlMgr := ytdl.NewManager(t.TempDir())
+	server.SetYtdlManager(ytdlMgr)
+
+	// Not yet tested
+	req := httptest.NewRequest("GET", "/api/health", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"status":"ok"`)
+	assert.Contains(t, w.Body.String(), `"ytdlp"`)
+
+	// Missing binary fails the self-test
+	ytdlMgr.SelfTest(context.Background())
+
+	req = httptest.NewRequest("GET", "/api/health", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"degraded"`)
+	assert.Contains(t, w.Body.String(), `"healthy":false`)
+}
+
 func TestStatusEndpoint(t *testing.T) {
 	tempDir := t.TempDir()
 	cacheMgr := cache.NewManager(tempDir, 0)
@@ -150,15 +458,115 @@ func TestCORSHeaders(t *testing.T) {
 
 	server := NewServer(cfg, cacheMgr)
 
+	// Preflight from a default dev server origin is answered
 	req := httptest.NewRequest("OPTIONS", "/api/status", nil)
 	req.Header.Set("Origin", "http://localhost:5173")
+	req.Header.Set("Access-Control-Request-Method", "GET")
 	w := httptest.NewRecorder()
 
 	server.router.ServeHTTP(w, req)
 
-	// Local server doesn't need CORS support
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "http://localhost:5173", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "PUT")
+
+	// Simple requests get the allow header too
+	req = httptest.NewRequest("GET", "/api/status", nil)
+	req.Header.Set("Origin", "http://localhost:5173")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "http://localhost:5173", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// Other origins get no CORS headers
 	// OPTIONS on routes that don't explicitly support it return 405
+	req = httptest.NewRequest("OPTIONS", "/api/status", nil)
+	req.Header.Set("Origin", "http://evil.example")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	// Origins can be changed at runtime
+	updated := models.DefaultConfig()
+	updated.CORSAllowedOrigins = []string{models.CORSAllowAll}
+	require.NoError(t, server.SetConfig(updated))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "http://evil.example", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSRejectsCrossOriginWrites(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+
+	cfgMgr, err := config.NewManager(filepath.Join(t.TempDir(), "config.json"))
+	require.NoError(t, err)
+
+	server := NewServer(cfgMgr.Get(), cacheMgr)
+	server.SetConfigManager(cfgMgr)
+
+	newDir := filepath.ToSlash(filepath.Join(t.TempDir(), "moved"))
+
+	// Simple requests a web page can send without a preflight
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+	}{
+		{"move", "/api/cache/move", "text/plain", `{"path": "` + newDir + `"}`},
+		{"blocklist import", "/api/blocklist/import", "text/plain", "https://youtu.be/BLOCKED"},
+		{"upload", "/api/cache/upload", "multipart/form-data; boundary=x", "--x--"},
+		{"cookies", "/api/youtube-cookies", "text/plain", "LOGIN_INFO=x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Origin", "http://evil.example")
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	}
+
+	assert.Equal(t, tempDir, cacheMgr.GetCachePath())
+	assert.Empty(t, cfgMgr.Get().BlockedURLs)
+	assert.Empty(t, server.Downloader().CookieStore().List())
+
+	// JSON endpoints refuse other content types even without an Origin
+	req := httptest.NewRequest("POST", "/api/cache/move", strings.NewReader(`{"path": "`+newDir+`"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Equal(t, tempDir, cacheMgr.GetCachePath())
+
+	// The dashboard posts from the server's own origin
+	req = httptest.NewRequest("POST", "/api/blocklist/import", strings.NewReader("https://youtu.be/BLOCKED"))
+	req.Host = "127.0.0.1:9696"
+	req.Header.Set("Origin", "http://127.0.0.1:9696")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Browser extensions push cookies; these are rejected for their content
+	req = httptest.NewRequest("POST", "/api/youtube-cookies", strings.NewReader("nope"))
+	req.Header.Set("Origin", "chrome-extension://abcdefghijklmnop")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Allowed CORS origins may write
+	req = httptest.NewRequest("POST", "/api/blocklist/import", strings.NewReader("https://youtu.be/OTHER"))
+	req.Header.Set("Origin", "http://localhost:5173")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
 }
 
 func TestGetAddr(t *testing.T) {
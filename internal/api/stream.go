@@ -0,0 +1,105 @@
+package api
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"vrcvideocacher/pkg/models"
+)
+
+const (
+	// streamPoll is how often a progressive stream checks for new data
+	streamPoll = 250 * time.Millisecond
+
+	// streamChunk is the most copied from a partial file per read
+	streamChunk = 1 << 20
+)
+
+// streamPath returns the progressive download path of a video
+func streamPath(videoID string, format models.DownloadFormat) string {
+	return "/stream/" + url.PathEscape(videoID) + "." + format.String()
+}
+
+// handleStream handles GET /stream/{file}, the progressive download URL
+// offered to JSON sources on a cache miss. A cached video is redirected to
+// its file. A pending download is streamed as yt-dlp writes it, without a
+// length, and finished from the cache file once complete; the response ends
+// early if the download fails
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "file")
+	ext := path.Ext(name)
+	id := strings.TrimSuffix(name, ext)
+
+	if entry, err := s.cache.GetEntry(id); err == nil {
+		if hiddenFromLAN(r, entry) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "/"+url.PathEscape(entry.FileName)+"?"+versionParam+"="+entry.Version(), http.StatusFound)
+		return
+	}
+	if _, pending := s.downloader.PartialFile(id); !pending {
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType := "video/mp4"
+	if ext == ".webm" {
+		contentType = "video/webm"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	rc := http.NewResponseController(w)
+
+	var offset int64
+	for {
+		partial, pending := s.downloader.PartialFile(id)
+		if !pending {
+			// Finished: send the rest from the cache, if it succeeded
+			if filePath, err := s.cache.GetFilePath(id); err == nil {
+				copyFrom(w, filePath, offset, math.MaxInt64)
+			}
+			return
+		}
+
+		n, err := copyFrom(w, partial, offset, streamChunk)
+		offset += n
+		if err != nil {
+			return
+		}
+		if n > 0 {
+			rc.Flush()
+			continue
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(streamPoll):
+		}
+	}
+}
+
+// copyFrom copies up to limit bytes of a file from offset to w. The file is
+// only held open for the copy, so yt-dlp can rename it when done. A missing
+// file copies nothing
+func copyFrom(w io.Writer, filePath string, offset, limit int64) (int64, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, nil
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, nil
+	}
+	return io.Copy(w, io.LimitReader(f, limit))
+}
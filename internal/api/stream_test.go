@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestHandleStream(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	server := NewServer(models.DefaultConfig(), cacheMgr)
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+	ts := httptest.NewServer(server.router)
+	defer ts.Close()
+
+	// Unknown videos are not streamed
+	resp, err := http.Get(ts.URL + "/stream/UNKNOWN.mp4")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Offline, so the request stays pending
+	server.downloader.SetOffline(true)
+	require.NoError(t, server.downloader.QueueWithMaxRes(context.Background(), "STREAM", "https://www.youtube.com/watch?v=STREAM", models.DownloadFormatMP4, 0))
+	partial, pending := server.downloader.PartialFile("STREAM")
+	require.True(t, pending)
+	require.NoError(t, os.WriteFile(partial, []byte("hello "), 0644))
+
+	resp, err = http.Get(ts.URL + "/stream/STREAM.mp4")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "video/mp4", resp.Header.Get("Content-Type"))
+
+	head := make([]byte, 6)
+	_, err = io.ReadFull(resp.Body, head)
+	require.NoError(t, err)
+	assert.Equal(t, "hello ", string(head))
+
+	// Completing the download finishes the stream from the cache file
+	f, err := os.OpenFile(partial, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("world")
+	require.NoError(t, err)
+	f.Close()
+	require.NoError(t, os.Rename(partial, filepath.Join(tempDir, "STREAM.mp4")))
+	err = cacheMgr.AddEntry("STREAM", "STREAM.mp4")
+	require.NoError(t, err)
+	server.downloader.ClearQueue()
+
+	rest, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(rest))
+
+	// Cached videos are redirected to their file
+	req := httptest.NewRequest("GET", "/stream/STREAM.mp4", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Location"), "/STREAM.mp4?"+versionParam+"="), w.Header().Get("Location"))
+}
+
+func TestHandleGetVideoStreaming(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+	server.downloader.SetOffline(true)
+
+	// Resonite gets the progressive download, with the original URL as fallback
+	videoURL := "https://www.youtube.com/watch?v=PARTIAL"
+	req := httptest.NewRequest("GET", "/api/getvideo?source=resonite&url="+videoURL, nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var info videoInfo
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&info))
+	assert.True(t, strings.HasSuffix(info.URL, "/stream/PARTIAL.mp4"), info.URL)
+	require.Len(t, info.Formats, 2)
+	assert.Equal(t, "upstream", info.Formats[0].FormatID)
+	assert.Equal(t, videoURL, info.Formats[0].URL)
+	assert.Equal(t, "https", info.Formats[0].Protocol)
+	assert.Equal(t, "local", info.Formats[1].FormatID)
+	assert.Equal(t, info.URL, info.Formats[1].URL)
+	assert.Equal(t, resultStreaming, server.history.List()[0].Result)
+
+	// VRChat still gets an empty response on a miss
+	req = httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=PARTIAL", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Empty(t, w.Body.String())
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Headers the stub sends with each getvideo request
+const (
+	stubVersionHeader = "X-Stub-Version"
+	stubArgsHeader    = "X-Stub-Args"
+)
+
+// StubActivity is when the stub was last run by a source, so users can
+// confirm the patch is actually being used
+type StubActivity struct {
+	Source   string    `json:"source"`
+	LastSeen time.Time `json:"lastSeen"`
+	Count    int       `json:"count"`
+	Version  string    `json:"version"`
+	// ArgsHash identifies the yt-dlp arguments the source last used
+	ArgsHash string `json:"argsHash,omitempty"`
+}
+
+// stubActivity aggregates stub requests per source
+type stubActivity struct {
+	mu      sync.Mutex
+	sources map[string]*StubActivity
+}
+
+// newStubActivity creates an empty aggregate
+func newStubActivity() *stubActivity {
+	return &stubActivity{sources: make(map[string]*StubActivity)}
+}
+
+// Record counts a getvideo request from source if the stub sent it
+func (a *stubActivity) Record(r *http.Request, source string, now time.Time) {
+	version := r.Header.Get(stubVersionHeader)
+	if version == "" {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	activity, ok := a.sources[source]
+	if !ok {
+		activity = &StubActivity{Source: source}
+		a.sources[source] = activity
+	}
+	activity.LastSeen = now
+	activity.Count++
+	activity.Version = version
+	activity.ArgsHash = r.Header.Get(stubArgsHeader)
+}
+
+// List returns the activity per source, most recently seen first
+func (a *stubActivity) List() []StubActivity {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	list := make([]StubActivity, 0, len(a.sources))
+	for _, activity := range a.sources {
+		list = append(list, *activity)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastSeen.After(list[j].LastSeen)
+	})
+	return list
+}
+
+// StubActivity returns when the stub was last run by each source since
+// the server started
+func (s *Server) StubActivity() []StubActivity {
+	return s.stubs.List()
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestStubActivity(t *testing.T) {
+	a := newStubActivity()
+	now := time.Now()
+
+	// Requests not sent by the stub are not counted
+	a.Record(httptest.NewRequest("GET", "/api/getvideo", nil), models.SourceVRChat, now)
+	assert.Empty(t, a.List())
+
+	for i, source := range []string{models.SourceVRChat, "resonite", models.SourceVRChat} {
+		req := httptest.NewRequest("GET", "/api/getvideo", nil)
+		req.Header.Set(stubVersionHeader, "0.1.0")
+		req.Header.Set(stubArgsHeader, source)
+		a.Record(req, source, now.Add(time.Duration(i)*time.Second))
+	}
+
+	list := a.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, models.SourceVRChat, list[0].Source)
+	assert.Equal(t, 2, list[0].Count)
+	assert.Equal(t, now.Add(2*time.Second), list[0].LastSeen)
+	assert.Equal(t, "0.1.0", list[0].Version)
+	assert.Equal(t, "resonite", list[1].Source)
+	assert.Equal(t, 1, list[1].Count)
+}
+
+func TestHandleStatus_Stubs(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+
+	req := httptest.NewRequest("GET", "/api/getvideo?url=https://example.com/video.mp4&source=resonite", nil)
+	req.Header.Set(stubVersionHeader, "0.1.0")
+	req.Header.Set(stubArgsHeader, "abcdef012345")
+	server.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/status", nil))
+
+	var status struct {
+		Stubs []StubActivity `json:"stubs"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+	require.Len(t, status.Stubs, 1)
+	assert.Equal(t, "resonite", status.Stubs[0].Source)
+	assert.Equal(t, "abcdef012345", status.Stubs[0].ArgsHash)
+	assert.False(t, status.Stubs[0].LastSeen.IsZero())
+}
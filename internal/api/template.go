@@ -0,0 +1,42 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"text/template"
+
+	"vrcvideocacher/internal/config"
+	"vrcvideocacher/pkg/models"
+)
+
+// responseTemplates are the parsed response templates of the source
+// policies, keyed by source
+type responseTemplates map[string]*template.Template
+
+// templateData is the data response templates are executed with
+type templateData struct {
+	videoInfo
+	Source string
+}
+
+// newResponseTemplates parses the templates of the source policies
+// Invalid templates are skipped and reported in the returned error
+func newResponseTemplates(policies map[string]models.SourcePolicy) (responseTemplates, error) {
+	templates := make(responseTemplates)
+	var errs []error
+
+	for source, policy := range policies {
+		if policy.Response != models.ResponseTemplate {
+			continue
+		}
+
+		tmpl, err := config.ParseResponseTemplate(policy.Template)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid response template for %q: %w", source, err))
+			continue
+		}
+		templates[source] = tmpl
+	}
+
+	return templates, errors.Join(errs...)
+}
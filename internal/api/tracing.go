@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"vrcvideocacher/internal/reqid"
+)
+
+// requestID tags each request with the ID sent by the stub, or a new one,
+// and returns it in the X-Request-ID header. The request log line and the
+// getvideo, queue and download logs carry it, so a single playback can be
+// followed across them
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(reqid.Header)
+		if !reqid.Valid(id) {
+			id = reqid.New()
+		}
+
+		ctx := reqid.With(r.Context(), id)
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, id)
+		w.Header().Set(reqid.Header, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// reqLog returns the server logger tagged with the request ID, like the
+// request log line
+func (s *Server) reqLog(r *http.Request) *slog.Logger {
+	return s.log.With("request", reqid.From(r.Context()))
+}
+
+// requestLogger logs each request once it is served, with its status, size
+// and duration
+func (s *Server) requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		defer func() {
+			s.reqLog(r).Info(r.Method+" "+r.URL.RequestURI(),
+				"status", ww.Status(),
+				"bytes", ww.BytesWritten(),
+				"duration", time.Since(start),
+				"remote", r.RemoteAddr,
+			)
+		}()
+		next.ServeHTTP(ww, r)
+	})
+}
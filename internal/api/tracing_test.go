@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/reqid"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	server := NewServer(models.DefaultConfig(), cache.NewManager(t.TempDir(), 0))
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	// The stub's ID is echoed and follows the request into the queue
+	req := httptest.NewRequest("GET", "/api/getvideo?url=https://www.youtube.com/watch?v=TRACED", nil)
+	req.Header.Set(reqid.Header, "stub-1234")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "stub-1234", w.Header().Get(reqid.Header))
+
+	assert.Equal(t, "stub-1234", server.history.List()[0].RequestID)
+	download, err := server.downloader.GetStatus("TRACED")
+	require.NoError(t, err)
+	assert.Equal(t, "stub-1234", download.RequestID)
+	assert.Equal(t, "stub-1234", NewDownloadInfo(download).RequestID)
+
+	// Other clients get a new ID; unsafe ones are replaced
+	for _, header := range []string{"", "bad id\r\n"} {
+		req = httptest.NewRequest("GET", "/api/health", nil)
+		req.Header.Set(reqid.Header, header)
+		w = httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		id := w.Header().Get(reqid.Header)
+		assert.True(t, reqid.Valid(id), id)
+		assert.NotEqual(t, header, id)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"vrcvideocacher/internal/downloader"
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/pkg/models"
+)
+
+// upgradeIfPopular queues a download of entry at cacheYouTubeMaxRes once it
+// has been served upgradeAfterHits times this stats period, if it was cached
+// at a lower resolution limit. Upgrades only start while nothing else is
+// downloading, so they never hold up videos that are not cached yet
+func (s *Server) upgradeIfPopular(r *http.Request, entry *models.CacheEntry, hits int) {
+	cfg := s.cfg()
+	if cfg.UpgradeAfterHits <= 0 || hits < cfg.UpgradeAfterHits {
+		return
+	}
+	// Uploads and files cached before limits were recorded have no MaxRes
+	if entry.MaxRes <= 0 || entry.MaxRes >= cfg.CacheYouTubeMaxRes {
+		return
+	}
+	if s.downloader.GetQueueLength() > 0 || s.downloader.GetActiveDownloads() > 0 || s.downloader.IsOffline() {
+		return
+	}
+	// Already upgrading, or finished or failed recently
+	if _, err := s.downloader.GetStatus(entry.ID); err == nil {
+		return
+	}
+
+	format := models.DownloadFormatMP4
+	if strings.EqualFold(path.Ext(entry.FileName), ".webm") {
+		format = models.DownloadFormatWebm
+	}
+
+	videoURL := "https://www.youtube.com/watch?v=" + entry.ID
+	err := s.downloader.QueueUpgrade(r.Context(), entry.ID, videoURL, format, cfg.CacheYouTubeMaxRes)
+	if err != nil {
+		if !errors.Is(err, downloader.ErrAlreadyQueued) {
+			s.reqLog(r).Error("Failed to queue upgrade", "video", entry.ID, logger.Err(err))
+		}
+		return
+	}
+	s.reqLog(r).Info("Upgrading", "video", entry.ID, "fromRes", entry.MaxRes, "toRes", cfg.CacheYouTubeMaxRes, "plays", hits)
+}
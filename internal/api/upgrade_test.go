@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/fakeytdlp"
+	"vrcvideocacher/internal/stats"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestUpgradeIfPopular(t *testing.T) {
+	cacheDir := t.TempDir()
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "popular.mp4"), []byte("480p"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("popular", "popular.mp4"))
+	require.NoError(t, cacheMgr.SetMaxRes("popular", 480))
+
+	cfg := models.DefaultConfig()
+	cfg.YtdlPath = fakeytdlp.Build(t)
+	cfg.CacheYouTubeMaxRes = 1080
+	cfg.UpgradeAfterHits = 2
+	server := NewServer(cfg, cacheMgr)
+	server.SetStats(stats.NewCollector(filepath.Join(t.TempDir(), stats.FileName), time.Now()), t.TempDir())
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	play := func() {
+		req := httptest.NewRequest("GET", "/api/getvideo?avpro=false&url=https://www.youtube.com/watch?v=popular", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "/popular.mp4?v=")
+	}
+
+	// Below the threshold the low resolution copy is kept
+	play()
+	assert.Zero(t, server.downloader.GetQueueLength()+server.downloader.GetActiveDownloads())
+
+	// At the threshold it is downloaded again at the configured limit and
+	// swapped in
+	play()
+	require.Eventually(t, func() bool {
+		entry, err := cacheMgr.GetEntry("popular")
+		return err == nil && entry.MaxRes == 1080
+	}, 10*time.Second, 10*time.Millisecond)
+
+	data, err := os.ReadFile(filepath.Join(cacheDir, "popular.mp4"))
+	require.NoError(t, err)
+	assert.Equal(t, "fake video popular\n", string(data))
+
+	// Entries at the limit are left alone
+	play()
+	assert.Zero(t, server.downloader.GetQueueLength()+server.downloader.GetActiveDownloads())
+}
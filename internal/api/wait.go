@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxGetVideoWait caps the wait parameter of getvideo, well inside the
+	// response write deadline
+	maxGetVideoWait = 10 * time.Second
+
+	// waitPoll is how often a waiting getvideo checks its download
+	waitPoll = 100 * time.Millisecond
+)
+
+// parseWait parses the wait parameter of getvideo in milliseconds, capped
+// at maxGetVideoWait. An empty value does not wait
+func parseWait(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	ms, err := strconv.Atoi(v)
+	if err != nil || ms < 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return time.Duration(min(ms, int(maxGetVideoWait/time.Millisecond))) * time.Millisecond, nil
+}
+
+// waitForDownload waits up to budget for the download of videoID to leave
+// the queue, reporting whether it did. A finished download may still have
+// failed, so callers check the cache
+func (s *Server) waitForDownload(ctx context.Context, videoID string, budget time.Duration) bool {
+	timer := time.NewTimer(budget)
+	defer timer.Stop()
+	ticker := time.NewTicker(waitPoll)
+	defer ticker.Stop()
+
+	for {
+		if _, pending := s.downloader.PartialFile(videoID); !pending {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
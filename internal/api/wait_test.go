@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestParseWait(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"1500", 1500 * time.Millisecond, false},
+		{"999999999999", maxGetVideoWait, false},
+		{"-1", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseWait(tt.value)
+		if tt.wantErr {
+			assert.Error(t, err, tt.value)
+			continue
+		}
+		assert.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got, tt.value)
+	}
+}
+
+func TestHandleGetVideoWait(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheMgr := cache.NewManager(tempDir, 0)
+	server := NewServer(models.DefaultConfig(), cacheMgr)
+	require.NoError(t, server.downloader.Start())
+	defer server.downloader.Stop()
+
+	// Offline, so downloads stay queued until the test completes them
+	server.downloader.SetOffline(true)
+
+	// Not ready within the budget: the usual empty response
+	req := httptest.NewRequest("GET", "/api/getvideo?wait=50&url=https://www.youtube.com/watch?v=SLOW", nil)
+	w := httptest.NewRecorder()
+	start := time.Now()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// Ready within the budget: the cached URL
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		os.WriteFile(filepath.Join(tempDir, "FAST.mp4"), []byte("video"), 0644)
+		cacheMgr.AddEntry("FAST", "FAST.mp4")
+		server.downloader.ClearQueue()
+	}()
+	req = httptest.NewRequest("GET", "/api/getvideo?wait=5000&avpro=false&url=https://www.youtube.com/watch?v=FAST", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/FAST.mp4?v=")
+	assert.Equal(t, resultCached, server.history.List()[0].Result)
+
+	req = httptest.NewRequest("GET", "/api/getvideo?wait=later&url=https://www.youtube.com/watch?v=FAST", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteTimeout bounds writing one message to a WebSocket client
+	wsWriteTimeout = 10 * time.Second
+
+	// wsPingInterval is how often clients are pinged; a client that has not
+	// answered for two intervals is dropped
+	wsPingInterval = 30 * time.Second
+
+	// wsReadLimit bounds messages from clients, which only send control
+	// frames
+	wsReadLimit = 512
+)
+
+// sockets tracks open WebSocket connections so Stop can close them, as
+// the HTTP server lets go of hijacked connections
+type sockets struct {
+	mu    sync.Mutex
+	conns map[*websocket.Conn]struct{}
+}
+
+func newSockets() *sockets {
+	return &sockets{conns: make(map[*websocket.Conn]struct{})}
+}
+
+func (s *sockets) add(conn *websocket.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns[conn] = struct{}{}
+}
+
+func (s *sockets) remove(conn *websocket.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// closeAll tells every client the server is going away and closes its
+// connection
+func (s *sockets) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server stopping")
+	for conn := range s.conns {
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)) // Ignore errors
+		conn.Close()
+	}
+}
+
+// handleWebSocket streams the events of the bus to a WebSocket client as
+// JSON messages until it disconnects or the server stops
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Subscribed before the handshake completes, so clients get every event
+	// published once they are connected
+	events, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	// Other web pages cannot read the stream
+	upgrader := websocket.Upgrader{CheckOrigin: s.originAllowed}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade has answered the request
+	}
+	defer conn.Close()
+
+	s.sockets.add(conn)
+	defer s.sockets.remove(conn)
+
+	// Reading answers pings and notices the client going away
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		conn.SetReadLimit(wsReadLimit)
+		conn.SetReadDeadline(time.Now().Add(2 * wsPingInterval))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * wsPingInterval))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case event := <-events:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// maxAliases is the most aliases kept per entry; the oldest are dropped first
+const maxAliases = 32
+
+// ErrAliasNotFound is returned when removing an unknown alias
+var ErrAliasNotFound = errors.New("cache alias not found")
+
+// Alias is an alternative name resolving to a cache entry
+type Alias struct {
+	Alias string `json:"alias"`
+	ID    string `json:"id"`
+}
+
+// AddAlias makes alias resolve to the entry id, moving it from any other
+// entry. Aliases are opaque to the manager; callers normalize them
+func (m *Manager) AddAlias(alias, id string) error {
+	if alias == "" {
+		return fmt.Errorf("%w: empty alias", ErrInvalidEntry)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[id]
+	if !ok {
+		return ErrEntryNotFound
+	}
+	if m.aliases[alias] == id {
+		return nil
+	}
+
+	if oldID, ok := m.aliases[alias]; ok {
+		m.unlinkAlias(alias, oldID)
+	}
+
+	entry.Aliases = append(entry.Aliases, alias)
+	if len(entry.Aliases) > maxAliases {
+		delete(m.aliases, entry.Aliases[0])
+		entry.Aliases = entry.Aliases[1:]
+	}
+	m.aliases[alias] = id
+
+	return m.saveIndex()
+}
+
+// RemoveAlias removes an alias
+func (m *Manager) RemoveAlias(alias string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, ok := m.aliases[alias]
+	if !ok {
+		return ErrAliasNotFound
+	}
+	m.unlinkAlias(alias, id)
+
+	return m.saveIndex()
+}
+
+// ResolveAlias returns the ID of the entry an alias resolves to
+func (m *Manager) ResolveAlias(alias string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, ok := m.aliases[alias]
+	return id, ok
+}
+
+// ListAliases returns all aliases sorted by alias
+func (m *Manager) ListAliases() []Alias {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	aliases := make([]Alias, 0, len(m.aliases))
+	for alias, id := range m.aliases {
+		aliases = append(aliases, Alias{Alias: alias, ID: id})
+	}
+
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Alias < aliases[j].Alias
+	})
+
+	return aliases
+}
+
+// unlinkAlias removes alias from the entry id
+// Must be called with lock held
+func (m *Manager) unlinkAlias(alias, id string) {
+	delete(m.aliases, alias)
+
+	entry, ok := m.entries[id]
+	if !ok {
+		return
+	}
+	kept := make([]string, 0, len(entry.Aliases))
+	for _, a := range entry.Aliases {
+		if a != alias {
+			kept = append(kept, a)
+		}
+	}
+	entry.Aliases = kept
+}
+
+// removeEntry removes an entry and its aliases from the maps
+// Must be called with lock held
+func (m *Manager) removeEntry(id string) {
+	if entry, ok := m.entries[id]; ok {
+		for _, alias := range entry.Aliases {
+			delete(m.aliases, alias)
+		}
+	}
+	delete(m.entries, id)
+	m.removePreview(id)
+}
+
+// rebuildAliases rebuilds the alias map from the entries
+// Must be called with lock held
+func (m *Manager) rebuildAliases() {
+	m.aliases = make(map[string]string)
+	for id, entry := range m.entries {
+		for _, alias := range entry.Aliases {
+			m.aliases[alias] = id
+		}
+	}
+}
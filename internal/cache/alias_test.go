@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliases(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewManager(dir, 0)
+
+	for _, id := range []string{"ONE", "TWO"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, id+".mp4"), []byte(id), 0644))
+		require.NoError(t, manager.AddEntry(id, id+".mp4"))
+	}
+
+	require.NoError(t, manager.AddAlias("https://youtu.be/ONE", "ONE"))
+	require.NoError(t, manager.AddAlias("https://example.com/event", "ONE"))
+	assert.ErrorIs(t, manager.AddAlias("https://youtu.be/MISSING", "MISSING"), ErrEntryNotFound)
+	assert.ErrorIs(t, manager.AddAlias("", "ONE"), ErrInvalidEntry)
+
+	id, ok := manager.ResolveAlias("https://example.com/event")
+	require.True(t, ok)
+	assert.Equal(t, "ONE", id)
+
+	// An alias moves to the entry it is added to last
+	require.NoError(t, manager.AddAlias("https://example.com/event", "TWO"))
+	id, _ = manager.ResolveAlias("https://example.com/event")
+	assert.Equal(t, "TWO", id)
+
+	entry, err := manager.GetEntry("ONE")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://youtu.be/ONE"}, entry.Aliases)
+
+	// Aliases survive a restart and a re-download
+	restarted := NewManager(dir, 0)
+	assert.Equal(t, []Alias{
+		{Alias: "https://example.com/event", ID: "TWO"},
+		{Alias: "https://youtu.be/ONE", ID: "ONE"},
+	}, restarted.ListAliases())
+
+	require.NoError(t, restarted.AddEntry("ONE", "ONE.mp4"))
+	_, ok = restarted.ResolveAlias("https://youtu.be/ONE")
+	assert.True(t, ok)
+
+	// Aliases go with their entry
+	require.NoError(t, restarted.DeleteEntry("TWO"))
+	_, ok = restarted.ResolveAlias("https://example.com/event")
+	assert.False(t, ok)
+
+	require.NoError(t, restarted.RemoveAlias("https://youtu.be/ONE"))
+	assert.Empty(t, restarted.ListAliases())
+	assert.ErrorIs(t, restarted.RemoveAlias("https://youtu.be/ONE"), ErrAliasNotFound)
+}
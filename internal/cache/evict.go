@@ -0,0 +1,35 @@
+package cache
+
+// scheduleEviction evicts entries over the size limit in the background, so
+// callers adding files do not wait for it. Requests made before a scheduled
+// eviction runs are served by it
+// Must be called with lock held
+func (m *Manager) scheduleEviction() {
+	if m.maxSizeBytes <= 0 || m.evicting {
+		return
+	}
+	m.evicting = true
+	go m.runEviction()
+}
+
+// runEviction evicts entries over the size limit and saves the index
+func (m *Manager) runEviction() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.evictIfNeeded() {
+		m.saveIndex() // Ignore errors, the next change saves it again
+	}
+
+	m.evicting = false
+	m.evictDone.Broadcast()
+}
+
+// WaitEviction blocks until a scheduled eviction has finished
+func (m *Manager) WaitEviction() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for m.evicting {
+		m.evictDone.Wait()
+	}
+}
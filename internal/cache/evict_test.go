@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/events"
+)
+
+func TestEvictionInBackground(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := NewManager(tempDir, 0)
+	manager.maxSizeBytes = 2000
+	bus := events.NewBus()
+	manager.SetEventBus(bus)
+	evicted, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	for i := 1; i <= 3; i++ {
+		name := fmt.Sprintf("video%d.mp4", i)
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), make([]byte, 1000), 0644))
+		require.NoError(t, manager.AddEntry(fmt.Sprintf("video%d", i), name))
+		time.Sleep(10 * time.Millisecond) // Ensure different timestamps
+	}
+
+	manager.WaitEviction()
+	_, err := manager.GetEntry("video1")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	assert.Equal(t, int64(2000), manager.GetSize())
+	require.Len(t, evicted, 1)
+	event := <-evicted
+	assert.Equal(t, events.CacheEvicted, event.Type)
+	assert.Equal(t, events.Eviction{ID: "video1", FileName: "video1.mp4", Size: 1000}, event.Data)
+
+	// The index is saved without the evicted entry
+	index, ok := manager.loadIndex()
+	require.True(t, ok)
+	assert.NotContains(t, index, "video1.mp4")
+	assert.Len(t, index, 2)
+}
+
+func TestDeleterBacklog(t *testing.T) {
+	d := newDeleter()
+
+	d.mu.Lock()
+	d.backlog = 100 // As if a large file were being deleted
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.waitBacklog(50)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("waitBacklog returned while over the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	d.mu.Lock()
+	d.backlog = 0
+	d.idle.Broadcast()
+	d.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitBacklog did not return")
+	}
+
+	// Deleted files leave the backlog
+	path := filepath.Join(t.TempDir(), "big")
+	require.NoError(t, os.WriteFile(path, []byte("x"), 0644))
+	d.add(path, 100)
+	d.wait()
+	assert.NoFileExists(t, path)
+	assert.Zero(t, d.backlog)
+}
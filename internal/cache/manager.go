@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -8,8 +9,10 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"vrcvideocacher/internal/events"
 	"vrcvideocacher/pkg/models"
 )
 
@@ -24,6 +27,33 @@ type Manager struct {
 	cachePath    string
 	entries      map[string]*models.CacheEntry
 	maxSizeBytes int64
+
+	// foreignPolicy decides what Scan does with files missing from the
+	// provenance index; foreign lists the files it ignored
+	foreignPolicy string
+	foreign       []string
+
+	// aliases maps alternative names (e.g. other links to the same video)
+	// to entry IDs; entries carry their own aliases
+	aliases map[string]string
+
+	// trash deletes removed files outside the lock; trashSeq keeps their
+	// names in the trash unique
+	trash    *deleter
+	trashSeq uint64
+
+	// evicting is set while a background eviction is scheduled; evictDone
+	// is signaled when it finishes
+	evicting  bool
+	evictDone *sync.Cond
+
+	// indexDirty is set while recorded accesses are not saved to the index;
+	// indexSaving while a save is scheduled
+	indexDirty  bool
+	indexSaving bool
+
+	// bus receives an event for every evicted entry
+	bus atomic.Pointer[events.Bus]
 }
 
 // NewManager creates a new cache manager
@@ -34,10 +64,16 @@ func NewManager(cachePath string, maxSizeGB float64) *Manager {
 	os.MkdirAll(cachePath, 0755)
 
 	manager := &Manager{
-		cachePath:    cachePath,
-		entries:      make(map[string]*models.CacheEntry),
-		maxSizeBytes: maxSizeBytes,
+		cachePath:     cachePath,
+		entries:       make(map[string]*models.CacheEntry),
+		maxSizeBytes:  maxSizeBytes,
+		foreignPolicy: models.ForeignIgnore,
+		aliases:       make(map[string]string),
+		trash:         newDeleter(),
 	}
+	manager.evictDone = sync.NewCond(&manager.mu)
+
+	manager.purgeTrash()
 
 	// Scan existing cache files
 	manager.Scan()
@@ -45,8 +81,13 @@ func NewManager(cachePath string, maxSizeGB float64) *Manager {
 	return manager
 }
 
-// AddEntry adds a new cache entry
+// AddEntry adds a new cache entry for a file downloaded by the cacher
+// Eviction runs in the background; if deleted files are piling up faster
+// than they are removed from disk, AddEntry first waits for the backlog to
+// shrink
 func (m *Manager) AddEntry(id, filename string) error {
+	m.trash.waitBacklog(maxTrashBacklog)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -64,14 +105,26 @@ func (m *Manager) AddEntry(id, filename string) error {
 		Size:       info.Size(),
 		LastAccess: time.Now(),
 		Created:    info.ModTime(),
+		Origin:     models.OriginDownloaded,
+		Format:     formatOf(filename),
+	}
+
+	// A replaced file keeps the aliases, sharing and history of the video
+	if old, ok := m.entries[id]; ok {
+		entry.Aliases = old.Aliases
+		entry.Private = old.Private
+		entry.URL = old.URL
+		entry.Title = old.Title
+		entry.Hits = old.Hits
+		m.removePreview(id)
 	}
 
 	m.entries[id] = entry
 
 	// Check if we need to evict
-	m.evictIfNeeded()
+	m.scheduleEviction()
 
-	return nil
+	return m.saveIndex()
 }
 
 // GetEntry retrieves a cache entry by ID
@@ -100,15 +153,14 @@ func (m *Manager) DeleteEntry(id string) error {
 	}
 
 	// Delete file
-	filePath := filepath.Join(m.cachePath, entry.FileName)
-	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+	if err := m.discard(entry.FileName, entry.Size); err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
 
 	// Remove from map
-	delete(m.entries, id)
+	m.removeEntry(id)
 
-	return nil
+	return m.saveIndex()
 }
 
 // ListEntries returns all cache entries
@@ -130,6 +182,13 @@ func (m *Manager) ListEntries() []*models.CacheEntry {
 	return entries
 }
 
+// GetMaxSize returns the cache size limit in bytes, 0 for no limit
+func (m *Manager) GetMaxSize() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return max(m.maxSizeBytes, 0)
+}
+
 // GetSize returns the total size of all cached files
 func (m *Manager) GetSize() int64 {
 	m.mu.RLock()
@@ -149,25 +208,59 @@ func (m *Manager) Clear() error {
 	defer m.mu.Unlock()
 
 	for id := range m.entries {
-		entry := m.entries[id]
-		filePath := filepath.Join(m.cachePath, entry.FileName)
-		os.Remove(filePath) // Ignore errors
-		delete(m.entries, id)
+		m.discard(m.entries[id].FileName, m.entries[id].Size) // Ignore errors
+		m.removeEntry(id)
+	}
+
+	return m.saveIndex()
+}
+
+// Purge deletes every cache file, the index and the trash, and removes the
+// cache directory if nothing else is left in it. Files the cache does not
+// track, e.g. foreign or quarantined files, are kept. The manager must not
+// be used afterwards
+func (m *Manager) Purge() error {
+	if err := m.Clear(); err != nil {
+		return err
 	}
+	m.trash.wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
+	if err := os.Remove(filepath.Join(m.cachePath, indexName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove index: %w", err)
+	}
+	os.Remove(filepath.Join(m.cachePath, TrashDir))   // Ignore errors
+	os.Remove(filepath.Join(m.cachePath, PreviewDir)) // Ignore errors
+	os.Remove(m.cachePath)                            // Fails if other files are left
 	return nil
 }
 
-// Scan scans the cache directory and builds the entry map
+// Scan scans the cache directory and builds the entry map, reconciling it
+// with the index: indexed files keep their details and playback history,
+// index entries whose file is gone are dropped. Files missing from the
+// index, or whose size changed, are handled by the foreign file policy. A
+// cache without an index predates provenance tracking, so all its files are
+// taken as downloaded
 func (m *Manager) Scan() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	return m.scan()
+}
+
+// scan implements Scan
+// Must be called with lock held
+func (m *Manager) scan() error {
 	entries, err := os.ReadDir(m.cachePath)
 	if err != nil {
 		return fmt.Errorf("failed to read cache directory: %w", err)
 	}
 
+	index, tracked := m.loadIndex()
+	m.foreign = nil
+
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
@@ -197,19 +290,41 @@ func (m *Manager) Scan() error {
 			Size:       info.Size(),
 			LastAccess: info.ModTime(),
 			Created:    info.ModTime(),
+			Origin:     models.OriginDownloaded,
+			Format:     formatOf(filename),
+		}
+		if tracked {
+			known, ok := index[filename]
+			switch {
+			case ok && known.Size == info.Size():
+				known.apply(cacheEntry)
+			case m.foreignPolicy == models.ForeignAdopt:
+				cacheEntry.Origin = models.OriginAdopted
+			case m.foreignPolicy == models.ForeignQuarantine && m.quarantine(filename) == nil:
+				continue
+			default:
+				m.foreign = append(m.foreign, filename)
+				continue
+			}
+		}
+		if old, ok := m.entries[id]; ok && len(old.Aliases) > 0 {
+			cacheEntry.Aliases = old.Aliases
 		}
 
 		m.entries[id] = cacheEntry
 	}
+	m.rebuildAliases()
 
 	// Evict if needed
 	m.evictIfNeeded()
 
-	return nil
+	return m.saveIndex()
 }
 
-// UpdateLastAccess updates the last access time for an entry
-func (m *Manager) UpdateLastAccess(id string) error {
+// ReplaceNormalized replaces the file of entry id with its loudness
+// normalized version at srcPath, in the same directory, and marks the
+// entry as normalized
+func (m *Manager) ReplaceNormalized(id, srcPath string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -218,11 +333,111 @@ func (m *Manager) UpdateLastAccess(id string) error {
 		return ErrEntryNotFound
 	}
 
-	entry.LastAccess = time.Now()
+	filePath := filepath.Join(m.cachePath, entry.FileName)
+	if err := os.Rename(srcPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
 
-	// Also touch the file
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	entry.Size = info.Size()
+	entry.Normalized = true
+
+	m.scheduleEviction()
+	return m.saveIndex()
+}
+
+// ReplaceVariant swaps the file of entry id for the re-encoded one at
+// srcPath and records its variant. It is dropped if the entry was removed
+// or its file replaced since version. The file keeps its access time, so
+// it stays as cold as it was
+func (m *Manager) ReplaceVariant(id, version, srcPath, variant string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[id]
+	if !ok {
+		os.Remove(srcPath)
+		return ErrEntryNotFound
+	}
+	if entry.Version() != version {
+		os.Remove(srcPath)
+		return fmt.Errorf("%w: %s changed while re-encoding it", ErrInvalidEntry, id)
+	}
+
+	filePath := filepath.Join(m.cachePath, entry.FileName)
+	if err := os.Rename(srcPath, filePath); err != nil {
+		os.Remove(srcPath)
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	_ = os.Chtimes(filePath, entry.LastAccess, entry.LastAccess) // Ignore error
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	entry.Size = info.Size()
+	entry.Variant = variant
+
+	return m.saveIndex()
+}
+
+// SetPrivate marks an entry private, served only to this machine, or
+// shared with LAN clients
+func (m *Manager) SetPrivate(id string, private bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[id]
+	if !ok {
+		return ErrEntryNotFound
+	}
+	if entry.Private == private {
+		return nil
+	}
+
+	entry.Private = private
+	return m.saveIndex()
+}
+
+// SetMaxRes records the resolution limit the file of entry id was
+// downloaded with
+func (m *Manager) SetMaxRes(id string, maxRes int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[id]
+	if !ok {
+		return ErrEntryNotFound
+	}
+	if entry.MaxRes == maxRes {
+		return nil
+	}
+
+	entry.MaxRes = maxRes
+	return m.saveIndex()
+}
+
+// UpdateLastAccess records a playback of an entry: its last access time
+// and hit count
+func (m *Manager) UpdateLastAccess(id string) error {
 	now := time.Now()
+
+	m.mu.Lock()
+	entry, ok := m.entries[id]
+	if !ok {
+		m.mu.Unlock()
+		return ErrEntryNotFound
+	}
+	entry.LastAccess = now
+	entry.Hits++
 	filePath := filepath.Join(m.cachePath, entry.FileName)
+	m.scheduleIndexSave()
+	m.mu.Unlock()
+
+	// Also touch the file, outside the lock as it is disk I/O
 	_ = os.Chtimes(filePath, now, now) // Ignore error
 
 	return nil
@@ -241,16 +456,33 @@ func (m *Manager) GetFilePath(id string) (string, error) {
 	return filepath.Join(m.cachePath, entry.FileName), nil
 }
 
+// Lookup returns a copy of a cache entry, or ctx.Err() if the caller has
+// gone away before the lookup
+func (m *Manager) Lookup(ctx context.Context, id string) (*models.CacheEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.GetEntry(id)
+}
+
 // GetCachePath returns the cache directory path
 func (m *Manager) GetCachePath() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.cachePath
 }
 
-// evictIfNeeded performs LRU eviction if cache size exceeds limit
+// SetEventBus sets the bus evictions are published to
+func (m *Manager) SetEventBus(bus *events.Bus) {
+	m.bus.Store(bus)
+}
+
+// evictIfNeeded performs LRU eviction if cache size exceeds limit and
+// returns whether any entry was evicted
 // Must be called with lock held
-func (m *Manager) evictIfNeeded() {
+func (m *Manager) evictIfNeeded() bool {
 	if m.maxSizeBytes <= 0 {
-		return // No size limit
+		return false // No size limit
 	}
 
 	// Calculate current size
@@ -260,7 +492,7 @@ func (m *Manager) evictIfNeeded() {
 	}
 
 	if currentSize <= m.maxSizeBytes {
-		return // Within limit
+		return false // Within limit
 	}
 
 	// Sort entries by last access time (oldest first)
@@ -280,11 +512,16 @@ func (m *Manager) evictIfNeeded() {
 		}
 
 		// Delete file
-		filePath := filepath.Join(m.cachePath, entry.FileName)
-		os.Remove(filePath) // Ignore errors
+		m.discard(entry.FileName, entry.Size) // Ignore errors
 
 		// Remove from map
-		delete(m.entries, entry.ID)
+		m.removeEntry(entry.ID)
 		currentSize -= entry.Size
+		m.bus.Load().Publish(events.CacheEvicted, events.Eviction{
+			ID:       entry.ID,
+			FileName: entry.FileName,
+			Size:     entry.Size,
+		})
 	}
+	return true
 }
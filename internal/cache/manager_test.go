@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/pkg/models"
 )
 
 func TestNewManager(t *testing.T) {
@@ -134,6 +137,27 @@ func TestClear(t *testing.T) {
 	assert.Equal(t, 0, len(entries))
 }
 
+func TestPurge(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	manager := NewManager(cacheDir, 0)
+
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "video.mp4"), []byte("content"), 0644))
+	require.NoError(t, manager.AddEntry("video", "video.mp4"))
+	require.NoError(t, manager.DeleteEntry("video"))
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "video.mp4"), []byte("content"), 0644))
+	require.NoError(t, manager.AddEntry("video", "video.mp4"))
+
+	require.NoError(t, manager.Purge())
+	assert.NoDirExists(t, cacheDir)
+
+	// Untracked files are kept with their directory
+	manager = NewManager(cacheDir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "notes.txt"), []byte("mine"), 0644))
+	require.NoError(t, manager.Purge())
+	assert.FileExists(t, filepath.Join(cacheDir, "notes.txt"))
+	assert.NoFileExists(t, filepath.Join(cacheDir, indexName))
+}
+
 func TestLRUEviction(t *testing.T) {
 	tempDir := t.TempDir()
 	// Set max size to 2000 bytes (convert bytes to GB)
@@ -147,6 +171,7 @@ func TestLRUEviction(t *testing.T) {
 		manager.AddEntry(fmt.Sprintf("video%d", i), fmt.Sprintf("video%d.mp4", i))
 		time.Sleep(10 * time.Millisecond) // Ensure different timestamps
 	}
+	manager.WaitEviction()
 
 	// Only 2 files should remain (most recent)
 	entries := manager.ListEntries()
@@ -159,6 +184,7 @@ func TestLRUEviction(t *testing.T) {
 func TestScan(t *testing.T) {
 	tempDir := t.TempDir()
 	manager := NewManager(tempDir, 0)
+	require.NoError(t, manager.SetForeignPolicy(models.ForeignAdopt))
 
 	// Create files directly in cache directory
 	file1 := filepath.Join(tempDir, "VIDEO_ID1.mp4")
@@ -206,6 +232,105 @@ func TestUpdateLastAccess(t *testing.T) {
 	assert.True(t, entry2.LastAccess.After(entry1.LastAccess))
 }
 
+func TestReplaceNormalized(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := NewManager(tempDir, 0)
+
+	os.WriteFile(filepath.Join(tempDir, "video.mp4"), []byte("content"), 0644)
+	manager.AddEntry("video", "video.mp4")
+
+	normalized := filepath.Join(tempDir, "video.mp4.normalizing")
+	os.WriteFile(normalized, []byte("louder content"), 0644)
+	require.NoError(t, manager.ReplaceNormalized("video", normalized))
+
+	entry, err := manager.GetEntry("video")
+	require.NoError(t, err)
+	assert.True(t, entry.Normalized)
+	assert.Equal(t, int64(len("louder content")), entry.Size)
+	assert.NoFileExists(t, normalized)
+
+	// The flag survives a restart
+	entry, err = NewManager(tempDir, 0).GetEntry("video")
+	require.NoError(t, err)
+	assert.True(t, entry.Normalized)
+
+	// A new download of the video starts out unnormalized
+	manager.AddEntry("video", "video.mp4")
+	entry, _ = manager.GetEntry("video")
+	assert.False(t, entry.Normalized)
+
+	assert.ErrorIs(t, manager.ReplaceNormalized("nonexistent", normalized), ErrEntryNotFound)
+}
+
+func TestReplaceVariant(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "video.mp4"), []byte("large content"), 0644)
+	old := time.Now().Add(-30 * 24 * time.Hour).Truncate(time.Second)
+	os.Chtimes(filepath.Join(tempDir, "video.mp4"), old, old)
+	manager := NewManager(tempDir, 0)
+	entry, err := manager.GetEntry("video")
+	require.NoError(t, err)
+
+	compacted := filepath.Join(tempDir, "video.mp4.compacting")
+	os.WriteFile(compacted, []byte("small"), 0644)
+	require.NoError(t, manager.ReplaceVariant("video", entry.Version(), compacted, models.VariantAV1))
+
+	entry, err = manager.GetEntry("video")
+	require.NoError(t, err)
+	assert.Equal(t, models.VariantAV1, entry.Variant)
+	assert.Equal(t, int64(len("small")), entry.Size)
+	assert.NoFileExists(t, compacted)
+
+	// The file stays cold, and the variant survives a restart
+	entry, err = NewManager(tempDir, 0).GetEntry("video")
+	require.NoError(t, err)
+	assert.Equal(t, models.VariantAV1, entry.Variant)
+	assert.True(t, entry.LastAccess.Equal(old))
+
+	// A file replaced while re-encoding is left alone
+	os.WriteFile(compacted, []byte("tiny"), 0644)
+	err = manager.ReplaceVariant("video", "stale", compacted, models.VariantHEVC)
+	assert.ErrorIs(t, err, ErrInvalidEntry)
+	assert.NoFileExists(t, compacted)
+	entry, _ = manager.GetEntry("video")
+	assert.Equal(t, models.VariantAV1, entry.Variant)
+
+	// A new download of the video is kept as downloaded
+	manager.AddEntry("video", "video.mp4")
+	entry, _ = manager.GetEntry("video")
+	assert.Empty(t, entry.Variant)
+
+	assert.ErrorIs(t, manager.ReplaceVariant("nonexistent", "", compacted, models.VariantAV1), ErrEntryNotFound)
+}
+
+func TestSetPrivate(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := NewManager(tempDir, 0)
+
+	os.WriteFile(filepath.Join(tempDir, "video.mp4"), []byte("content"), 0644)
+	manager.AddEntry("video", "video.mp4")
+
+	require.NoError(t, manager.SetPrivate("video", true))
+	entry, err := manager.GetEntry("video")
+	require.NoError(t, err)
+	assert.True(t, entry.Private)
+
+	// The flag survives a restart and a new download of the video
+	entry, err = NewManager(tempDir, 0).GetEntry("video")
+	require.NoError(t, err)
+	assert.True(t, entry.Private)
+
+	manager.AddEntry("video", "video.mp4")
+	entry, _ = manager.GetEntry("video")
+	assert.True(t, entry.Private)
+
+	require.NoError(t, manager.SetPrivate("video", false))
+	entry, _ = NewManager(tempDir, 0).GetEntry("video")
+	assert.False(t, entry.Private)
+
+	assert.ErrorIs(t, manager.SetPrivate("nonexistent", true), ErrEntryNotFound)
+}
+
 func TestGetFilePath(t *testing.T) {
 	tempDir := t.TempDir()
 	manager := NewManager(tempDir, 0)
@@ -224,3 +349,63 @@ func TestGetFilePath(t *testing.T) {
 	_, err = manager.GetFilePath("nonexistent")
 	assert.ErrorIs(t, err, ErrEntryNotFound)
 }
+
+func TestLookup(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := NewManager(tempDir, 0)
+
+	testFile := filepath.Join(tempDir, "video.mp4")
+	os.WriteFile(testFile, []byte("content"), 0644)
+	manager.AddEntry("video", "video.mp4")
+
+	entry, err := manager.Lookup(context.Background(), "video")
+	require.NoError(t, err)
+	assert.Equal(t, "video.mp4", entry.FileName)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = manager.Lookup(ctx, "video")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// benchmarkManager returns a manager holding n entries
+func benchmarkManager(b *testing.B, n int) *Manager {
+	tempDir := b.TempDir()
+	manager := NewManager(tempDir, 0)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("VIDEO%d.mp4", i)
+		require.NoError(b, os.WriteFile(filepath.Join(tempDir, name), []byte("video"), 0644))
+		require.NoError(b, manager.AddEntry(fmt.Sprintf("VIDEO%d", i), name))
+	}
+	return manager
+}
+
+func BenchmarkLookupParallel(b *testing.B) {
+	manager := benchmarkManager(b, 100)
+	ctx := context.Background()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			manager.Lookup(ctx, fmt.Sprintf("VIDEO%d", i%100))
+			i++
+		}
+	})
+}
+
+// BenchmarkLookupAndTouchParallel mirrors a cache hit, which takes the write
+// lock to record the access
+func BenchmarkLookupAndTouchParallel(b *testing.B) {
+	manager := benchmarkManager(b, 100)
+	ctx := context.Background()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			id := fmt.Sprintf("VIDEO%d", i%100)
+			manager.Lookup(ctx, id)
+			manager.UpdateLastAccess(id)
+			i++
+		}
+	})
+}
@@ -0,0 +1,332 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"vrcvideocacher/pkg/models"
+)
+
+var (
+	ErrSameCachePath     = errors.New("new cache path is the current cache path")
+	ErrNestedCachePath   = errors.New("new cache path cannot be inside the current cache path or contain it")
+	ErrCacheFileExists   = errors.New("file already exists at new cache path")
+	ErrCacheSizeMismatch = errors.New("moved file size mismatch")
+	ErrCacheMoved        = errors.New("cache directory moved during the operation")
+)
+
+// MoveProgress reports cache relocation progress
+type MoveProgress struct {
+	File       string `json:"file"`
+	FilesDone  int    `json:"filesDone"`
+	FilesTotal int    `json:"filesTotal"`
+	BytesDone  int64  `json:"bytesDone"`
+	BytesTotal int64  `json:"bytesTotal"`
+}
+
+// stagedFile is a file made available at the new path while it is still
+// served from the old one
+type stagedFile struct {
+	src  string
+	dst  string
+	info os.FileInfo // src when it was staged
+}
+
+// MoveCache moves all files in the cache directory to newPath and switches
+// the manager to it. Files are hard linked when possible and copied
+// otherwise, without holding the lock, so lookups keep being answered from
+// the old path meanwhile; the lock is only taken to catch up with files
+// changed since and switch the path. On any failure the staged files are
+// removed and the cache stays at the old path. progress is called without
+// the lock held.
+func (m *Manager) MoveCache(newPath string, progress func(MoveProgress)) error {
+	m.mu.RLock()
+	cachePath := m.cachePath
+	m.mu.RUnlock()
+
+	oldPath, err := filepath.Abs(cachePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve cache path: %w", err)
+	}
+	newPath, err = filepath.Abs(newPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve new cache path: %w", err)
+	}
+
+	if oldPath == newPath {
+		return ErrSameCachePath
+	}
+	if isSubPath(oldPath, newPath) || isSubPath(newPath, oldPath) {
+		return ErrNestedCachePath
+	}
+
+	if err := os.MkdirAll(newPath, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	files, err := cacheFiles(oldPath)
+	if err != nil {
+		return err
+	}
+
+	// Collect the total size up front for progress reporting
+	state := MoveProgress{FilesTotal: len(files)}
+	for _, info := range files {
+		state.BytesTotal += info.Size()
+	}
+
+	staged := make(map[string]stagedFile, len(files))
+	for _, info := range files {
+		sf, err := stageFile(oldPath, newPath, info.Name())
+		if errors.Is(err, os.ErrNotExist) {
+			// Removed meanwhile
+			continue
+		}
+		if err != nil {
+			unstage(staged)
+			return err
+		}
+		staged[info.Name()] = sf
+
+		state.File = info.Name()
+		state.FilesDone++
+		state.BytesDone += info.Size()
+		if progress != nil {
+			progress(state)
+		}
+	}
+
+	m.mu.Lock()
+	if err := m.finishMove(cachePath, oldPath, newPath, staged); err != nil {
+		m.mu.Unlock()
+		unstage(staged)
+		return err
+	}
+	m.mu.Unlock()
+
+	// Everything is in place, drop the originals
+	for _, sf := range staged {
+		os.Remove(sf.src) // Ignore errors
+	}
+	return nil
+}
+
+// finishMove stages the files added or replaced in oldPath since they were
+// staged, drops those removed meanwhile and switches the manager to newPath
+// Must be called with lock held
+func (m *Manager) finishMove(cachePath, oldPath, newPath string, staged map[string]stagedFile) error {
+	// The cache may have switched to another directory meanwhile
+	if m.cachePath != cachePath {
+		return ErrCacheMoved
+	}
+
+	files, err := cacheFiles(oldPath)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool, len(files))
+	for _, info := range files {
+		current[info.Name()] = true
+		if sf, ok := staged[info.Name()]; ok {
+			if unchanged(sf.info, info) {
+				continue
+			}
+			os.Remove(sf.dst)
+			delete(staged, info.Name())
+		}
+
+		sf, err := stageFile(oldPath, newPath, info.Name())
+		if err != nil {
+			return err
+		}
+		staged[info.Name()] = sf
+	}
+
+	for name, sf := range staged {
+		if !current[name] {
+			os.Remove(sf.dst) // Ignore errors
+			delete(staged, name)
+		}
+	}
+
+	m.cachePath = newPath
+	return nil
+}
+
+// SetCachePath switches the manager to another cache directory without
+// moving any files, e.g. one already holding a cache, and rescans it. Files
+// in the old directory are left in place, so serves already reading them
+// are not interrupted
+func (m *Manager) SetCachePath(newPath string) error {
+	newPath, err := filepath.Abs(newPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve new cache path: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldPath, err := filepath.Abs(m.cachePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve cache path: %w", err)
+	}
+	if oldPath == newPath {
+		return ErrSameCachePath
+	}
+
+	if err := os.MkdirAll(newPath, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if _, err := os.ReadDir(newPath); err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	m.cachePath = newPath
+	m.entries = make(map[string]*models.CacheEntry)
+	m.aliases = make(map[string]string)
+	m.purgeTrash()
+
+	return m.scan()
+}
+
+// cacheFiles returns the regular files in dir
+func cacheFiles(dir string) ([]os.FileInfo, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	files := make([]os.FileInfo, 0, len(dirEntries))
+	for _, entry := range dirEntries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+// stageFile makes the file name in oldPath available in newPath, hard
+// linking it on the same volume and copying it otherwise, and verifies the
+// resulting size. The original stays in place
+func stageFile(oldPath, newPath, name string) (stagedFile, error) {
+	sf := stagedFile{
+		src: filepath.Join(oldPath, name),
+		dst: filepath.Join(newPath, name),
+	}
+
+	if _, err := os.Stat(sf.dst); err == nil {
+		return sf, fmt.Errorf("%w: %s", ErrCacheFileExists, sf.dst)
+	}
+
+	info, err := os.Stat(sf.src)
+	if err != nil {
+		return sf, err
+	}
+	sf.info = info
+
+	if err := os.Link(sf.src, sf.dst); err != nil {
+		if err := copyFile(sf.src, sf.dst); err != nil {
+			os.Remove(sf.dst)
+			return sf, fmt.Errorf("failed to copy %s: %w", name, err)
+		}
+	}
+
+	staged, err := os.Stat(sf.dst)
+	if err != nil || staged.Size() != info.Size() {
+		// A file replaced while it was staged is staged again by finishMove
+		if current, err := os.Stat(sf.src); err == nil && !unchanged(info, current) {
+			return sf, nil
+		}
+		os.Remove(sf.dst)
+		return sf, fmt.Errorf("%w: %s", ErrCacheSizeMismatch, name)
+	}
+
+	return sf, nil
+}
+
+// unchanged reports whether a file is still the one staged
+func unchanged(staged, current os.FileInfo) bool {
+	return os.SameFile(staged, current) && staged.Size() == current.Size() && staged.ModTime().Equal(current.ModTime())
+}
+
+// unstage removes the files staged by MoveCache, leaving the originals
+func unstage(staged map[string]stagedFile) {
+	for _, sf := range staged {
+		os.Remove(sf.dst) // Ignore errors
+	}
+}
+
+// copyFile copies src to dst preserving the modification time
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// isSubPath reports whether path is inside base
+func isSubPath(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ImportFile moves a finished download at srcPath into the cache and adds
+// it as id. Across volumes the file is copied to a temporary name first so
+// a partial file is never visible under its final name.
+func (m *Manager) ImportFile(id, srcPath string) error {
+	filename := filepath.Base(srcPath)
+
+	m.mu.RLock()
+	dst := filepath.Join(m.cachePath, filename)
+	m.mu.RUnlock()
+
+	if err := os.Rename(srcPath, dst); err != nil {
+		tmp := dst + ".importing"
+		os.Remove(tmp) // Leftover from an interrupted import
+		if err := copyFile(srcPath, tmp); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("failed to copy into cache: %w", err)
+		}
+		if err := os.Rename(tmp, dst); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("failed to move into cache: %w", err)
+		}
+		os.Remove(srcPath) // Ignore errors
+	}
+
+	return m.AddEntry(id, filename)
+}
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveCache(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := filepath.Join(t.TempDir(), "moved")
+	manager := NewManager(oldDir, 0)
+
+	require.NoError(t, os.WriteFile(filepath.Join(oldDir, "a.mp4"), make([]byte, 100), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(oldDir, "youtube_cookies.txt"), []byte("cookies"), 0600))
+	require.NoError(t, manager.AddEntry("a", "a.mp4"))
+
+	var updates []MoveProgress
+	err := manager.MoveCache(newDir, func(p MoveProgress) {
+		updates = append(updates, p)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, newDir, manager.GetCachePath())
+	assert.FileExists(t, filepath.Join(newDir, "a.mp4"))
+	assert.FileExists(t, filepath.Join(newDir, "youtube_cookies.txt"))
+	assert.FileExists(t, filepath.Join(newDir, indexName))
+	assert.NoFileExists(t, filepath.Join(oldDir, "a.mp4"))
+
+	path, err := manager.GetFilePath("a")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(newDir, "a.mp4"), path)
+
+	// Video, cookies and provenance index
+	require.Len(t, updates, 3)
+	last := updates[len(updates)-1]
+	assert.Equal(t, 3, last.FilesDone)
+	assert.Equal(t, last.BytesTotal, last.BytesDone)
+}
+
+func TestMoveCache_ConcurrentChanges(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := filepath.Join(t.TempDir(), "moved")
+	manager := NewManager(oldDir, 0)
+
+	for _, id := range []string{"a", "b"} {
+		require.NoError(t, os.WriteFile(filepath.Join(oldDir, id+".mp4"), []byte(id), 0644))
+		require.NoError(t, manager.AddEntry(id, id+".mp4"))
+	}
+
+	// The cache stays usable while files are staged
+	changed := false
+	err := manager.MoveCache(newDir, func(p MoveProgress) {
+		if changed {
+			return
+		}
+		changed = true
+
+		_, err := manager.GetEntry("a")
+		assert.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(oldDir, "c.mp4"), []byte("c"), 0644))
+		require.NoError(t, manager.AddEntry("c", "c.mp4"))
+		require.NoError(t, manager.DeleteEntry("b"))
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, newDir, manager.GetCachePath())
+	path, err := manager.GetFilePath("c")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(newDir, "c.mp4"), path)
+	assert.FileExists(t, path)
+	assert.FileExists(t, filepath.Join(newDir, "a.mp4"))
+	assert.NoFileExists(t, filepath.Join(newDir, "b.mp4"))
+	assert.NoFileExists(t, filepath.Join(oldDir, "a.mp4"))
+}
+
+func TestMoveCache_InvalidPath(t *testing.T) {
+	oldDir := t.TempDir()
+	manager := NewManager(oldDir, 0)
+
+	assert.ErrorIs(t, manager.MoveCache(oldDir, nil), ErrSameCachePath)
+	assert.ErrorIs(t, manager.MoveCache(filepath.Join(oldDir, "sub"), nil), ErrNestedCachePath)
+	assert.ErrorIs(t, manager.MoveCache(filepath.Dir(oldDir), nil), ErrNestedCachePath)
+	assert.Equal(t, oldDir, manager.GetCachePath())
+}
+
+func TestMoveCache_RollbackOnConflict(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+	manager := NewManager(oldDir, 0)
+
+	require.NoError(t, os.WriteFile(filepath.Join(oldDir, "a.mp4"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(oldDir, "b.mp4"), []byte("b"), 0644))
+	// b.mp4 already exists at the destination
+	require.NoError(t, os.WriteFile(filepath.Join(newDir, "b.mp4"), []byte("other"), 0644))
+
+	err := manager.MoveCache(newDir, nil)
+	assert.ErrorIs(t, err, ErrCacheFileExists)
+
+	// Everything is back where it was
+	assert.Equal(t, oldDir, manager.GetCachePath())
+	assert.FileExists(t, filepath.Join(oldDir, "a.mp4"))
+	assert.FileExists(t, filepath.Join(oldDir, "b.mp4"))
+	assert.NoFileExists(t, filepath.Join(newDir, "a.mp4"))
+	data, _ := os.ReadFile(filepath.Join(newDir, "b.mp4"))
+	assert.Equal(t, []byte("other"), data)
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.mp4")
+	dst := filepath.Join(dir, "dst.mp4")
+	require.NoError(t, os.WriteFile(src, []byte("video"), 0644))
+
+	require.NoError(t, copyFile(src, dst))
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("video"), data)
+
+	// Never overwrites an existing file
+	assert.Error(t, copyFile(src, dst))
+}
+
+func TestImportFile(t *testing.T) {
+	cacheDir := t.TempDir()
+	scratchDir := t.TempDir()
+	manager := NewManager(cacheDir, 0)
+
+	src := filepath.Join(scratchDir, "abc.mp4")
+	require.NoError(t, os.WriteFile(src, []byte("video"), 0644))
+
+	require.NoError(t, manager.ImportFile("abc", src))
+	assert.NoFileExists(t, src)
+
+	path, err := manager.GetFilePath("abc")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cacheDir, "abc.mp4"), path)
+	assert.FileExists(t, path)
+}
+
+func TestSetCachePath(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+	manager := NewManager(oldDir, 0)
+
+	require.NoError(t, os.WriteFile(filepath.Join(oldDir, "a.mp4"), []byte("a"), 0644))
+	require.NoError(t, manager.AddEntry("a", "a.mp4"))
+	require.NoError(t, manager.AddAlias("https://youtu.be/a", "a"))
+	require.NoError(t, os.WriteFile(filepath.Join(newDir, "b.mp4"), []byte("b"), 0644))
+
+	// A serve in progress keeps reading the old file
+	path, err := manager.GetFilePath("a")
+	require.NoError(t, err)
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	assert.ErrorIs(t, manager.SetCachePath(oldDir), ErrSameCachePath)
+	require.NoError(t, manager.SetCachePath(newDir))
+	assert.Equal(t, newDir, manager.GetCachePath())
+
+	// The new directory is scanned and the old files are left in place
+	_, err = manager.GetEntry("a")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	_, ok := manager.ResolveAlias("https://youtu.be/a")
+	assert.False(t, ok)
+	_, err = manager.GetEntry("b")
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(oldDir, "a.mp4"))
+
+	data := make([]byte, 1)
+	_, err = f.Read(data)
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+
+	// Switching back restores the old cache from its index
+	require.NoError(t, manager.SetCachePath(oldDir))
+	_, err = manager.GetEntry("a")
+	assert.NoError(t, err)
+	id, ok := manager.ResolveAlias("https://youtu.be/a")
+	assert.True(t, ok)
+	assert.Equal(t, "a", id)
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PreviewDir is where dashboard previews of cached videos are kept,
+// relative to the cache directory
+const PreviewDir = "previews"
+
+// PreviewPath returns where the preview of entry id is stored, whether or
+// not it has been generated
+func (m *Manager) PreviewPath(id string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.previewPath(id)
+}
+
+// previewPath implements PreviewPath
+// Must be called with lock held
+func (m *Manager) previewPath(id string) string {
+	return filepath.Join(m.cachePath, PreviewDir, id+".jpg")
+}
+
+// SavePreview moves the preview generated at srcPath into place for entry
+// id. It is dropped if the entry was removed or its file replaced since
+// version, as the preview would not match
+func (m *Manager) SavePreview(id, version, srcPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[id]
+	if !ok {
+		os.Remove(srcPath)
+		return ErrEntryNotFound
+	}
+	if entry.Version() != version {
+		os.Remove(srcPath)
+		return fmt.Errorf("%w: %s changed while generating its preview", ErrInvalidEntry, id)
+	}
+
+	dst := m.previewPath(id)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create preview directory: %w", err)
+	}
+	if err := os.Rename(srcPath, dst); err != nil {
+		os.Remove(srcPath)
+		return fmt.Errorf("failed to save preview: %w", err)
+	}
+	return nil
+}
+
+// removePreview deletes the preview of entry id, which no longer matches
+// its file
+// Must be called with lock held
+func (m *Manager) removePreview(id string) {
+	os.Remove(m.previewPath(id)) // Ignore errors, most entries have none
+}
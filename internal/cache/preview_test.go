@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavePreview(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := NewManager(tempDir, 0)
+
+	os.WriteFile(filepath.Join(tempDir, "video.mp4"), []byte("content"), 0644)
+	require.NoError(t, manager.AddEntry("video", "video.mp4"))
+	entry, err := manager.GetEntry("video")
+	require.NoError(t, err)
+
+	preview := manager.PreviewPath("video")
+	assert.Equal(t, filepath.Join(tempDir, PreviewDir, "video.jpg"), preview)
+
+	tmp := filepath.Join(t.TempDir(), "preview.jpg")
+	os.WriteFile(tmp, []byte("sprite"), 0644)
+	require.NoError(t, manager.SavePreview("video", entry.Version(), tmp))
+	assert.FileExists(t, preview)
+
+	// A preview of a replaced file is dropped
+	os.WriteFile(tmp, []byte("sprite"), 0644)
+	assert.ErrorIs(t, manager.SavePreview("video", "stale", tmp), ErrInvalidEntry)
+	assert.NoFileExists(t, tmp)
+
+	// and replacing the file removes the old one
+	require.NoError(t, manager.AddEntry("video", "video.mp4"))
+	assert.NoFileExists(t, preview)
+
+	// as does deleting the entry
+	os.WriteFile(tmp, []byte("sprite"), 0644)
+	entry, _ = manager.GetEntry("video")
+	require.NoError(t, manager.SavePreview("video", entry.Version(), tmp))
+	require.NoError(t, manager.DeleteEntry("video"))
+	assert.NoFileExists(t, preview)
+
+	os.WriteFile(tmp, []byte("sprite"), 0644)
+	assert.ErrorIs(t, manager.SavePreview("video", entry.Version(), tmp), ErrEntryNotFound)
+}
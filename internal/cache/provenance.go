@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"vrcvideocacher/pkg/models"
+)
+
+const (
+	// indexName is the index kept in the cache directory
+	// It records which files the cacher put there, so foreign files with a
+	// video ID as their name are not served as that video, and what is known
+	// about each video, which file names alone cannot carry over a restart
+	indexName = "cache-index.json"
+
+	// indexSaveDelay is how long recorded accesses may wait before the
+	// index is saved, so a burst of cache hits is written once
+	indexSaveDelay = 10 * time.Second
+
+	// QuarantineDir is where quarantined foreign files are moved, relative
+	// to the cache directory
+	QuarantineDir = "quarantine"
+)
+
+// indexEntry is the provenance of one cached file
+type indexEntry struct {
+	ID      string   `json:"id"`
+	Size    int64    `json:"size"`
+	Origin  string   `json:"origin"`
+	Aliases []string `json:"aliases,omitempty"`
+	// Normalized marks files whose audio loudness was normalized
+	Normalized bool `json:"normalized,omitempty"`
+	// Private marks files not shared with LAN clients
+	Private bool `json:"private,omitempty"`
+	// MaxRes is the resolution limit the file was downloaded with
+	MaxRes int `json:"maxRes,omitempty"`
+	// Variant is the codec the file was re-encoded to by compaction
+	Variant string `json:"variant,omitempty"`
+	// Format is the container of the file
+	Format string `json:"format,omitempty"`
+	// URL, Title and Resolution describe the video the file came from
+	URL        string `json:"url,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Resolution int    `json:"resolution,omitempty"`
+	// LastAccess and Hits record playback, which the file's modification
+	// time only partly keeps
+	LastAccess time.Time `json:"lastAccess,omitempty"`
+	Hits       int       `json:"hits,omitempty"`
+}
+
+// apply copies the recorded details of a file to its entry
+func (e indexEntry) apply(entry *models.CacheEntry) {
+	if e.Origin != "" {
+		entry.Origin = e.Origin
+	}
+	entry.Aliases = e.Aliases
+	entry.Normalized = e.Normalized
+	entry.Private = e.Private
+	entry.MaxRes = e.MaxRes
+	entry.Variant = e.Variant
+	entry.URL = e.URL
+	entry.Title = e.Title
+	entry.Resolution = e.Resolution
+	entry.Hits = e.Hits
+	if e.Format != "" {
+		entry.Format = e.Format
+	}
+	// The file is touched on access, but copies and restores reset it
+	if e.LastAccess.After(entry.LastAccess) {
+		entry.LastAccess = e.LastAccess
+	}
+}
+
+// VideoInfo describes the video a cached file was downloaded from
+type VideoInfo struct {
+	URL   string
+	Title string
+	// Resolution is the height of the video in pixels
+	Resolution int
+}
+
+// loadIndex reads the provenance index, keyed by file name
+// ok is false if there is no index yet, i.e. the cache predates provenance
+// tracking
+func (m *Manager) loadIndex() (index map[string]indexEntry, ok bool) {
+	data, err := os.ReadFile(filepath.Join(m.cachePath, indexName))
+	if err != nil {
+		return nil, false
+	}
+
+	if err := json.Unmarshal(data, &index); err != nil {
+		// A corrupt index only loses provenance; treat files as legacy
+		return nil, false
+	}
+	return index, true
+}
+
+// saveIndex writes the provenance and known details of the current entries
+// Must be called with lock held
+func (m *Manager) saveIndex() error {
+	m.indexDirty = false
+
+	index := make(map[string]indexEntry, len(m.entries))
+	for _, entry := range m.entries {
+		index[entry.FileName] = indexEntry{
+			ID:      entry.ID,
+			Size:    entry.Size,
+			Origin:  entry.Origin,
+			Aliases: entry.Aliases,
+
+			Normalized: entry.Normalized,
+			Private:    entry.Private,
+			MaxRes:     entry.MaxRes,
+			Variant:    entry.Variant,
+			Format:     entry.Format,
+			URL:        entry.URL,
+			Title:      entry.Title,
+			Resolution: entry.Resolution,
+			LastAccess: entry.LastAccess,
+			Hits:       entry.Hits,
+		}
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cache index: %w", err)
+	}
+
+	path := filepath.Join(m.cachePath, indexName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+	return nil
+}
+
+// scheduleIndexSave saves the index after indexSaveDelay, so recording an
+// access does not write it every time. Other changes save it at once
+// Must be called with lock held
+func (m *Manager) scheduleIndexSave() {
+	m.indexDirty = true
+	if m.indexSaving {
+		return
+	}
+	m.indexSaving = true
+	time.AfterFunc(indexSaveDelay, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.indexSaving = false
+		if m.indexDirty {
+			m.saveIndex() // Ignore errors, the next change saves it again
+		}
+	})
+}
+
+// Flush saves accesses recorded since the index was last saved, e.g. before
+// the app exits
+func (m *Manager) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.indexDirty {
+		return nil
+	}
+	return m.saveIndex()
+}
+
+// SetVideoInfo records what is known about the video of entry id, keeping
+// the current value of empty fields
+func (m *Manager) SetVideoInfo(id string, info VideoInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[id]
+	if !ok {
+		return ErrEntryNotFound
+	}
+	if info.URL != "" {
+		entry.URL = info.URL
+	}
+	if info.Title != "" {
+		entry.Title = info.Title
+	}
+	if info.Resolution > 0 {
+		entry.Resolution = info.Resolution
+	}
+	return m.saveIndex()
+}
+
+// formatOf returns the container of a cached file from its extension
+func formatOf(filename string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+}
+
+// quarantine moves a foreign file out of the served cache directory
+// Must be called with lock held
+func (m *Manager) quarantine(filename string) error {
+	dir := filepath.Join(m.cachePath, QuarantineDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	if err := os.Rename(filepath.Join(m.cachePath, filename), filepath.Join(dir, filename)); err != nil {
+		return fmt.Errorf("failed to quarantine %s: %w", filename, err)
+	}
+	return nil
+}
+
+// SetForeignPolicy sets how Scan handles video files the cacher did not put
+// in the cache (models.ForeignIgnore, ForeignAdopt or ForeignQuarantine) and
+// rescans the cache if the policy changed
+func (m *Manager) SetForeignPolicy(policy string) error {
+	if policy == "" {
+		policy = models.ForeignIgnore
+	}
+
+	m.mu.Lock()
+	changed := m.foreignPolicy != policy
+	m.foreignPolicy = policy
+	m.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	return m.Scan()
+}
+
+// ForeignFiles returns the foreign files ignored by the last Scan
+func (m *Manager) ForeignFiles() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string{}, m.foreign...)
+}
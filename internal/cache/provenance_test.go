@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/pkg/models"
+)
+
+func TestScanLegacyCache(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "OLD.mp4"), []byte("video"), 0644))
+
+	// A cache without an index predates tracking; its files are kept
+	manager := NewManager(dir, 0)
+
+	entry, err := manager.GetEntry("OLD")
+	require.NoError(t, err)
+	assert.Equal(t, models.OriginDownloaded, entry.Origin)
+	assert.FileExists(t, filepath.Join(dir, indexName))
+}
+
+func TestForeignFilePolicy(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewManager(dir, 0)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "OWN.mp4"), []byte("downloaded"), 0644))
+	require.NoError(t, manager.AddEntry("OWN", "OWN.mp4"))
+
+	// A file dropped in by hand is not served by default
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "FOREIGN.mp4"), []byte("foreign"), 0644))
+	require.NoError(t, manager.Scan())
+
+	_, err := manager.GetEntry("FOREIGN")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	assert.Equal(t, []string{"FOREIGN.mp4"}, manager.ForeignFiles())
+
+	// Downloaded files survive a restart
+	restarted := NewManager(dir, 0)
+	entry, err := restarted.GetEntry("OWN")
+	require.NoError(t, err)
+	assert.Equal(t, models.OriginDownloaded, entry.Origin)
+
+	// A downloaded file replaced by hand is foreign
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "OWN.mp4"), []byte("replaced by hand"), 0644))
+	restarted = NewManager(dir, 0)
+	_, err = restarted.GetEntry("OWN")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "OWN.mp4"), []byte("downloaded"), 0644))
+
+	// Adopted files are remembered as such
+	require.NoError(t, manager.SetForeignPolicy(models.ForeignAdopt))
+	entry, err = manager.GetEntry("FOREIGN")
+	require.NoError(t, err)
+	assert.Equal(t, models.OriginAdopted, entry.Origin)
+	assert.Empty(t, manager.ForeignFiles())
+
+	restarted = NewManager(dir, 0)
+	entry, err = restarted.GetEntry("FOREIGN")
+	require.NoError(t, err)
+	assert.Equal(t, models.OriginAdopted, entry.Origin)
+}
+
+func TestForeignFileQuarantine(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewManager(dir, 0)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "FOREIGN.webm"), []byte("foreign"), 0644))
+	require.NoError(t, manager.SetForeignPolicy(models.ForeignQuarantine))
+
+	_, err := manager.GetEntry("FOREIGN")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	assert.NoFileExists(t, filepath.Join(dir, "FOREIGN.webm"))
+	assert.FileExists(t, filepath.Join(dir, QuarantineDir, "FOREIGN.webm"))
+}
+
+func TestIndexKeepsVideoInfo(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewManager(dir, 0)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "VID.webm"), []byte("video"), 0644))
+	require.NoError(t, manager.AddEntry("VID", "VID.webm"))
+	require.NoError(t, manager.SetVideoInfo("VID", VideoInfo{
+		URL:        "https://www.youtube.com/watch?v=VID",
+		Title:      "A video",
+		Resolution: 720,
+	}))
+	require.NoError(t, manager.UpdateLastAccess("VID"))
+	require.NoError(t, manager.UpdateLastAccess("VID"))
+	played, err := manager.GetEntry("VID")
+	require.NoError(t, err)
+	require.NoError(t, manager.Flush())
+
+	// A copy of the cache resets modification times, the index keeps them
+	old := time.Now().Add(-24 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "VID.webm"), old, old))
+
+	entry, err := NewManager(dir, 0).GetEntry("VID")
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.youtube.com/watch?v=VID", entry.URL)
+	assert.Equal(t, "A video", entry.Title)
+	assert.Equal(t, 720, entry.Resolution)
+	assert.Equal(t, "webm", entry.Format)
+	assert.Equal(t, 2, entry.Hits)
+	assert.True(t, entry.LastAccess.Equal(played.LastAccess))
+
+	// Entries whose file is gone are dropped from the index
+	require.NoError(t, os.Remove(filepath.Join(dir, "VID.webm")))
+	restarted := NewManager(dir, 0)
+	_, err = restarted.GetEntry("VID")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	index, ok := restarted.loadIndex()
+	require.True(t, ok)
+	assert.Empty(t, index)
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// TrashDir is where deleted and evicted files wait for deletion,
+	// relative to the cache directory. Moving a file there is a cheap
+	// rename, so the manager lock is not held while large files are deleted
+	TrashDir = ".trash"
+
+	// maxTrashBacklog is the most bytes waiting for deletion before new
+	// entries wait for the deleter to catch up
+	maxTrashBacklog = 8 << 30
+)
+
+// trashed is a file waiting for deletion
+type trashed struct {
+	path string
+	size int64
+}
+
+// deleter deletes trashed files in the background, one at a time
+type deleter struct {
+	mu      sync.Mutex
+	idle    *sync.Cond
+	queue   []trashed
+	backlog int64
+	running bool
+}
+
+// newDeleter creates an idle deleter
+func newDeleter() *deleter {
+	d := &deleter{}
+	d.idle = sync.NewCond(&d.mu)
+	return d
+}
+
+// add queues a path of size bytes for deletion, starting the deletion
+// goroutine if needed
+func (d *deleter) add(path string, size int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.queue = append(d.queue, trashed{path: path, size: size})
+	d.backlog += size
+	if !d.running {
+		d.running = true
+		go d.run()
+	}
+}
+
+// run deletes queued paths until the queue is empty
+func (d *deleter) run() {
+	for {
+		d.mu.Lock()
+		if len(d.queue) == 0 {
+			d.running = false
+			d.idle.Broadcast()
+			d.mu.Unlock()
+			return
+		}
+		file := d.queue[0]
+		d.queue = d.queue[1:]
+		d.mu.Unlock()
+
+		os.RemoveAll(file.path) // Ignore errors, leftovers are purged on the next start
+
+		d.mu.Lock()
+		d.backlog -= file.size
+		d.idle.Broadcast()
+		d.mu.Unlock()
+	}
+}
+
+// waitBacklog blocks while more than limit bytes wait for deletion
+func (d *deleter) waitBacklog(limit int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for d.backlog > limit {
+		d.idle.Wait()
+	}
+}
+
+// wait blocks until the queue is empty
+func (d *deleter) wait() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for d.running {
+		d.idle.Wait()
+	}
+}
+
+// discard removes a cache file of size bytes: it is moved to the trash and
+// deleted in the background. Files that cannot be moved (e.g. open on Windows) are deleted
+// in place
+// Must be called with lock held
+func (m *Manager) discard(filename string, size int64) error {
+	src := filepath.Join(m.cachePath, filename)
+
+	m.trashSeq++
+	dst := filepath.Join(m.cachePath, TrashDir, fmt.Sprintf("%d-%s", m.trashSeq, filename))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err == nil {
+		if err := os.Rename(src, dst); err == nil {
+			m.trash.add(dst, size)
+			return nil
+		}
+	}
+
+	if err := os.Remove(src); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// purgeTrash queues files left in the trash by a previous run for deletion
+func (m *Manager) purgeTrash() {
+	dir := filepath.Join(m.cachePath, TrashDir)
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		var size int64
+		if info, err := entry.Info(); err == nil && !info.IsDir() {
+			size = info.Size()
+		}
+		m.trash.add(filepath.Join(dir, entry.Name()), size)
+	}
+}
+
+// WaitDeletions blocks until the files deleted from the cache so far are
+// gone from disk
+func (m *Manager) WaitDeletions() {
+	m.trash.wait()
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteEntryUsesTrash(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := NewManager(tempDir, 0)
+
+	filePath := filepath.Join(tempDir, "VIDEO.mp4")
+	require.NoError(t, os.WriteFile(filePath, []byte("old"), 0644))
+	require.NoError(t, manager.AddEntry("VIDEO", "VIDEO.mp4"))
+
+	// The file leaves the cache immediately
+	require.NoError(t, manager.DeleteEntry("VIDEO"))
+	assert.NoFileExists(t, filePath)
+
+	// A new download of the same video is not deleted with the old file
+	require.NoError(t, os.WriteFile(filePath, []byte("new"), 0644))
+	require.NoError(t, manager.AddEntry("VIDEO", "VIDEO.mp4"))
+
+	manager.WaitDeletions()
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	trashed, err := os.ReadDir(filepath.Join(tempDir, TrashDir))
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+}
+
+func TestEvictionUsesTrash(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := NewManager(tempDir, 0)
+	manager.maxSizeBytes = 10
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "OLD.mp4"), make([]byte, 8), 0644))
+	require.NoError(t, manager.AddEntry("OLD", "OLD.mp4"))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "NEW.mp4"), make([]byte, 8), 0644))
+	require.NoError(t, manager.AddEntry("NEW", "NEW.mp4"))
+	manager.WaitEviction()
+
+	_, err := manager.GetEntry("OLD")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	assert.NoFileExists(t, filepath.Join(tempDir, "OLD.mp4"))
+
+	manager.WaitDeletions()
+	trashed, err := os.ReadDir(filepath.Join(tempDir, TrashDir))
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+}
+
+func TestLeftoverTrashPurged(t *testing.T) {
+	tempDir := t.TempDir()
+	trashDir := filepath.Join(tempDir, TrashDir)
+	require.NoError(t, os.MkdirAll(trashDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(trashDir, "1-VIDEO.mp4"), []byte("video"), 0644))
+
+	manager := NewManager(tempDir, 0)
+	manager.WaitDeletions()
+
+	assert.NoFileExists(t, filepath.Join(trashDir, "1-VIDEO.mp4"))
+	assert.Empty(t, manager.ListEntries())
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"vrcvideocacher/pkg/models"
+)
+
+// validID matches IDs usable as cache file names
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// AddUpload stores a user-provided video read from r as the cache entry id,
+// replacing any existing entry. ext is ".mp4" or ".webm". The file is
+// written under a temporary name first so a partial upload is never served
+func (m *Manager) AddUpload(id, ext string, r io.Reader) (*models.CacheEntry, error) {
+	ext = strings.ToLower(ext)
+	if !validID.MatchString(id) {
+		return nil, fmt.Errorf("%w: id %q", ErrInvalidEntry, id)
+	}
+	if ext != ".mp4" && ext != ".webm" {
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidEntry, ext)
+	}
+
+	m.mu.RLock()
+	cachePath := m.cachePath
+	m.mu.RUnlock()
+
+	tmp, err := os.CreateTemp(cachePath, id+"-*.uploading")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write upload: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write upload: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The cache may have moved while the upload was written
+	if m.cachePath != cachePath {
+		return nil, ErrCacheMoved
+	}
+
+	filename := id + ext
+	if old, ok := m.entries[id]; ok && old.FileName != filename {
+		m.discard(old.FileName, old.Size) // Ignore errors
+	}
+
+	filePath := filepath.Join(m.cachePath, filename)
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return nil, fmt.Errorf("failed to move upload into cache: %w", err)
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	entry := &models.CacheEntry{
+		ID:         id,
+		FileName:   filename,
+		Size:       info.Size(),
+		LastAccess: time.Now(),
+		Created:    info.ModTime(),
+		Origin:     models.OriginUploaded,
+		Format:     formatOf(filename),
+	}
+	if old, ok := m.entries[id]; ok {
+		entry.Aliases = old.Aliases
+		entry.Private = old.Private
+		entry.URL = old.URL
+		entry.Title = old.Title
+		entry.Hits = old.Hits
+		m.removePreview(id)
+	}
+	m.entries[id] = entry
+
+	m.scheduleEviction()
+	if err := m.saveIndex(); err != nil {
+		return nil, err
+	}
+
+	entryCopy := *entry
+	return &entryCopy, nil
+}
+
+// AddLocalFile copies a local video file into the cache as the entry id
+// The original file is left in place
+func (m *Manager) AddLocalFile(id, srcPath string) (*models.CacheEntry, error) {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	return m.AddUpload(id, filepath.Ext(srcPath), f)
+}
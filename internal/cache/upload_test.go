@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/pkg/models"
+)
+
+func TestAddUpload(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewManager(dir, 0)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "VIDEO.webm"), []byte("downloaded"), 0644))
+	require.NoError(t, manager.AddEntry("VIDEO", "VIDEO.webm"))
+
+	// An upload replaces the downloaded copy, whatever its format
+	entry, err := manager.AddUpload("VIDEO", ".MP4", strings.NewReader("local copy"))
+	require.NoError(t, err)
+	assert.Equal(t, "VIDEO.mp4", entry.FileName)
+	assert.Equal(t, int64(len("local copy")), entry.Size)
+	assert.Equal(t, models.OriginUploaded, entry.Origin)
+	assert.NoFileExists(t, filepath.Join(dir, "VIDEO.webm"))
+
+	// Uploads are not foreign after a restart
+	restarted := NewManager(dir, 0)
+	entry, err = restarted.GetEntry("VIDEO")
+	require.NoError(t, err)
+	assert.Equal(t, models.OriginUploaded, entry.Origin)
+
+	_, err = manager.AddUpload("../escape", ".mp4", strings.NewReader("x"))
+	assert.ErrorIs(t, err, ErrInvalidEntry)
+	_, err = manager.AddUpload("VIDEO", ".mkv", strings.NewReader("x"))
+	assert.ErrorIs(t, err, ErrInvalidEntry)
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.uploading"))
+	assert.Empty(t, matches)
+}
+
+func TestAddLocalFile(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "event.webm")
+	require.NoError(t, os.WriteFile(src, []byte("event video"), 0644))
+
+	manager := NewManager(t.TempDir(), 0)
+	entry, err := manager.AddLocalFile("EVENT", src)
+	require.NoError(t, err)
+	assert.Equal(t, "EVENT.webm", entry.FileName)
+	assert.FileExists(t, src, "the original is kept")
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 )
 
 // CommandType represents the type of CLI command
@@ -17,6 +19,36 @@ const (
 	CommandPatch
 	CommandUnpatch
 	CommandUpdate
+	CommandCacheMode
+	CommandDoctor
+	CommandMoveCache
+	CommandSupportBundle
+	CommandLoadTest
+	CommandUninstall
+	CommandConfigInit
+	CommandAVExclusion
+	CommandTUI
+	CommandAutostart
+)
+
+// Exit codes of every command, so scripts and the GUI can branch on the
+// cause of a failure. They are a contract: never renumber them
+const (
+	ExitOK = 0
+	// ExitFailure is any failure without a more specific code, including
+	// invalid commands and flags
+	ExitFailure = 1
+	// ExitConfig means the config could not be read, written or is invalid
+	ExitConfig = 2
+	// ExitPatch means patching or unpatching a Tools directory failed
+	ExitPatch = 3
+	// ExitNetwork means a server, GitHub or another host could not be
+	// reached
+	ExitNetwork = 4
+	// ExitNotFound means a game, Tools directory or file does not exist
+	ExitNotFound = 5
+	// ExitAlreadyRunning means a server is already running on the port
+	ExitAlreadyRunning = 6
 )
 
 // Command represents a parsed CLI command
@@ -24,7 +56,34 @@ type Command struct {
 	Type      CommandType
 	Port      int
 	Path      string
+	Target    string
+	All       bool
 	CheckOnly bool
+	Rollback  bool
+	Enabled   bool
+	Offline   bool
+	SafeMode  bool
+	Force     bool
+	NoElevate bool
+
+	// Load test settings
+	Concurrency int
+	Duration    time.Duration
+	URLs        []string
+
+	// Uninstall settings
+	RemoveCache  bool
+	RemoveConfig bool
+	RemoveTools  bool
+
+	// AddExclusion registers the antivirus exclusion instead of only
+	// printing it
+	AddExclusion bool
+
+	// Autostart settings: the subcommand (enable, disable or status) and
+	// the desktop app to register instead of the server
+	Action string
+	App    string
 }
 
 // String returns a string representation of the command
@@ -35,22 +94,101 @@ func (c *Command) String() string {
 	case CommandVersion:
 		return "version"
 	case CommandServer:
+		if c.SafeMode {
+			return fmt.Sprintf("server (port: %d, safe mode)", c.Port)
+		}
 		return fmt.Sprintf("server (port: %d)", c.Port)
 	case CommandPatch:
 		if c.Path != "" {
 			return fmt.Sprintf("patch (path: %s)", c.Path)
 		}
+		if c.Target != "" {
+			return fmt.Sprintf("patch (target: %s)", c.Target)
+		}
+		if c.All {
+			return "patch (all targets)"
+		}
 		return "patch"
 	case CommandUnpatch:
 		if c.Path != "" {
 			return fmt.Sprintf("unpatch (path: %s)", c.Path)
 		}
+		if c.Target != "" {
+			return fmt.Sprintf("unpatch (target: %s)", c.Target)
+		}
 		return "unpatch"
 	case CommandUpdate:
 		if c.CheckOnly {
 			return "update (check only)"
 		}
+		if c.Rollback {
+			return "update (rollback)"
+		}
 		return "update"
+	case CommandCacheMode:
+		if c.Enabled {
+			return "cache-mode (enabled)"
+		}
+		return "cache-mode (disabled)"
+	case CommandDoctor:
+		if c.Offline {
+			return "doctor (offline)"
+		}
+		return "doctor"
+	case CommandMoveCache:
+		return fmt.Sprintf("move-cache (path: %s)", c.Path)
+	case CommandSupportBundle:
+		if c.Path != "" {
+			return fmt.Sprintf("support-bundle (output: %s)", c.Path)
+		}
+		return "support-bundle"
+	case CommandLoadTest:
+		return fmt.Sprintf("loadtest (concurrency: %d, duration: %s)", c.Concurrency, c.Duration)
+	case CommandUninstall:
+		var removed []string
+		if c.RemoveCache {
+			removed = append(removed, "cache")
+		}
+		if c.RemoveConfig {
+			removed = append(removed, "config")
+		}
+		if c.RemoveTools {
+			removed = append(removed, "tools")
+		}
+		if len(removed) > 0 {
+			return fmt.Sprintf("uninstall (remove: %s)", strings.Join(removed, ", "))
+		}
+		return "uninstall"
+	case CommandConfigInit:
+		if c.Force {
+			return "config init (force)"
+		}
+		return "config init"
+	case CommandAVExclusion:
+		var opts []string
+		if c.Path != "" {
+			opts = append(opts, "path: "+c.Path)
+		}
+		if c.Target != "" {
+			opts = append(opts, "target: "+c.Target)
+		}
+		if c.AddExclusion {
+			opts = append(opts, "add")
+		}
+		if len(opts) > 0 {
+			return fmt.Sprintf("av-exclusion (%s)", strings.Join(opts, ", "))
+		}
+		return "av-exclusion"
+	case CommandTUI:
+		if c.Port != 0 {
+			return fmt.Sprintf("tui (port: %d)", c.Port)
+		}
+		return "tui"
+	case CommandAutostart:
+		if c.App != "" {
+			return fmt.Sprintf("autostart %s (app: %s)", c.Action, c.App)
+		}
+		return "autostart " + c.Action
 	default:
 		return "unknown"
 	}
@@ -93,6 +231,26 @@ func (c *CLI) ParseCommand(args []string) (*Command, error) {
 		return c.parseUnpatchCommand(args[1:])
 	case "update":
 		return c.parseUpdateCommand(args[1:])
+	case "cache-mode":
+		return c.parseCacheModeCommand(args[1:])
+	case "doctor":
+		return c.parseDoctorCommand(args[1:])
+	case "move-cache":
+		return c.parseMoveCacheCommand(args[1:])
+	case "support-bundle":
+		return c.parseSupportBundleCommand(args[1:])
+	case "loadtest":
+		return c.parseLoadTestCommand(args[1:])
+	case "uninstall":
+		return c.parseUninstallCommand(args[1:])
+	case "config":
+		return c.parseConfigCommand(args[1:])
+	case "av-exclusion":
+		return c.parseAVExclusionCommand(args[1:])
+	case "tui":
+		return c.parseTUICommand(args[1:])
+	case "autostart":
+		return c.parseAutostartCommand(args[1:])
 	default:
 		return nil, fmt.Errorf("unknown command: %s", args[0])
 	}
@@ -102,14 +260,16 @@ func (c *CLI) ParseCommand(args []string) (*Command, error) {
 func (c *CLI) parseServerCommand(args []string) (*Command, error) {
 	fs := flag.NewFlagSet("server", flag.ContinueOnError)
 	port := fs.Int("port", 8080, "Server port")
+	safeMode := fs.Bool("safe-mode", false, "Pass every video through with cookies, extra args and blocklists off")
 
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
 
 	return &Command{
-		Type: CommandServer,
-		Port: *port,
+		Type:     CommandServer,
+		Port:     *port,
+		SafeMode: *safeMode,
 	}, nil
 }
 
@@ -117,14 +277,26 @@ func (c *CLI) parseServerCommand(args []string) (*Command, error) {
 func (c *CLI) parsePatchCommand(args []string) (*Command, error) {
 	fs := flag.NewFlagSet("patch", flag.ContinueOnError)
 	path := fs.String("path", "", "VRChat Tools directory path (auto-detect if empty)")
+	target := fs.String("target", "", "Patch target to auto-detect, e.g. vrchat-beta (default: vrchat)")
+	all := fs.Bool("all", false, "Patch every detected target")
+	noElevate := fs.Bool("no-elevate", false, "Fail instead of asking for administrator rights when access is denied")
 
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
+	if *path != "" && *target != "" {
+		return nil, fmt.Errorf("patch takes either -path or -target")
+	}
+	if *all && (*path != "" || *target != "") {
+		return nil, fmt.Errorf("patch -all cannot be combined with -path or -target")
+	}
 
 	return &Command{
-		Type: CommandPatch,
-		Path: *path,
+		Type:      CommandPatch,
+		Path:      *path,
+		Target:    *target,
+		All:       *all,
+		NoElevate: *noElevate,
 	}, nil
 }
 
@@ -132,14 +304,19 @@ func (c *CLI) parsePatchCommand(args []string) (*Command, error) {
 func (c *CLI) parseUnpatchCommand(args []string) (*Command, error) {
 	fs := flag.NewFlagSet("unpatch", flag.ContinueOnError)
 	path := fs.String("path", "", "VRChat Tools directory path (auto-detect if empty)")
+	target := fs.String("target", "", "Patch target to auto-detect, e.g. vrchat-beta (default: vrchat)")
 
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
+	if *path != "" && *target != "" {
+		return nil, fmt.Errorf("unpatch takes either -path or -target")
+	}
 
 	return &Command{
-		Type: CommandUnpatch,
-		Path: *path,
+		Type:   CommandUnpatch,
+		Path:   *path,
+		Target: *target,
 	}, nil
 }
 
@@ -147,14 +324,227 @@ func (c *CLI) parseUnpatchCommand(args []string) (*Command, error) {
 func (c *CLI) parseUpdateCommand(args []string) (*Command, error) {
 	fs := flag.NewFlagSet("update", flag.ContinueOnError)
 	checkOnly := fs.Bool("check", false, "Only check for updates without installing")
+	rollback := fs.Bool("rollback", false, "Restore the version the last update replaced")
 
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
+	if *checkOnly && *rollback {
+		return nil, fmt.Errorf("update takes either -check or -rollback")
+	}
 
 	return &Command{
 		Type:      CommandUpdate,
 		CheckOnly: *checkOnly,
+		Rollback:  *rollback,
+	}, nil
+}
+
+// parseCacheModeCommand parses the cache-mode command
+func (c *CLI) parseCacheModeCommand(args []string) (*Command, error) {
+	fs := flag.NewFlagSet("cache-mode", flag.ContinueOnError)
+	enabled := fs.Bool("enabled", true, "Enable caching (false switches to pass-through mode)")
+	port := fs.Int("port", 0, "Server port (from config if 0)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return &Command{
+		Type:    CommandCacheMode,
+		Port:    *port,
+		Enabled: *enabled,
+	}, nil
+}
+
+// parseDoctorCommand parses the doctor command
+func (c *CLI) parseDoctorCommand(args []string) (*Command, error) {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	offline := fs.Bool("offline", false, "Only use the bundled known hash list")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return &Command{
+		Type:    CommandDoctor,
+		Offline: *offline,
+	}, nil
+}
+
+// parseMoveCacheCommand parses the move-cache command
+func (c *CLI) parseMoveCacheCommand(args []string) (*Command, error) {
+	fs := flag.NewFlagSet("move-cache", flag.ContinueOnError)
+	path := fs.String("path", "", "New cache directory path")
+	port := fs.Int("port", 0, "Server port (from config if 0)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path == "" {
+		return nil, fmt.Errorf("move-cache requires -path")
+	}
+
+	return &Command{
+		Type: CommandMoveCache,
+		Path: *path,
+		Port: *port,
+	}, nil
+}
+
+// parseSupportBundleCommand parses the support-bundle command
+func (c *CLI) parseSupportBundleCommand(args []string) (*Command, error) {
+	fs := flag.NewFlagSet("support-bundle", flag.ContinueOnError)
+	output := fs.String("output", "", "Zip file to write (default: vrcvideocacher-support-<time>.zip)")
+	port := fs.Int("port", 0, "Server port (from config if 0)")
+	offline := fs.Bool("offline", false, "Only use the bundled known hash list for diagnostics")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return &Command{
+		Type:    CommandSupportBundle,
+		Path:    *output,
+		Port:    *port,
+		Offline: *offline,
+	}, nil
+}
+
+// parseLoadTestCommand parses the loadtest command
+func (c *CLI) parseLoadTestCommand(args []string) (*Command, error) {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	port := fs.Int("port", 0, "Server port (from config if 0)")
+	concurrency := fs.Int("concurrency", 8, "Number of concurrent clients")
+	duration := fs.Duration("duration", 10*time.Second, "How long to run")
+	urls := fs.String("url", "", "Comma-separated video URLs to request (default: cached videos)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *concurrency < 1 {
+		return nil, fmt.Errorf("loadtest requires -concurrency of at least 1")
+	}
+	if *duration <= 0 {
+		return nil, fmt.Errorf("loadtest requires a positive -duration")
+	}
+
+	var videoURLs []string
+	for _, u := range strings.Split(*urls, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			videoURLs = append(videoURLs, u)
+		}
+	}
+
+	return &Command{
+		Type:        CommandLoadTest,
+		Port:        *port,
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		URLs:        videoURLs,
+	}, nil
+}
+
+// parseUninstallCommand parses the uninstall command
+func (c *CLI) parseUninstallCommand(args []string) (*Command, error) {
+	fs := flag.NewFlagSet("uninstall", flag.ContinueOnError)
+	port := fs.Int("port", 0, "Server port checked for a running server (from config if 0)")
+	removeCache := fs.Bool("cache", false, "Also delete cached videos and saved cookies")
+	removeConfig := fs.Bool("config", false, "Also delete the config")
+	removeTools := fs.Bool("tools", false, "Also delete the downloaded yt-dlp and aria2c")
+	all := fs.Bool("all", false, "Delete cache, config and tools")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return &Command{
+		Type:         CommandUninstall,
+		Port:         *port,
+		RemoveCache:  *removeCache || *all,
+		RemoveConfig: *removeConfig || *all,
+		RemoveTools:  *removeTools || *all,
+	}, nil
+}
+
+// parseConfigCommand parses the config command and its subcommand
+func (c *CLI) parseConfigCommand(args []string) (*Command, error) {
+	if len(args) == 0 || args[0] != "init" {
+		return nil, fmt.Errorf("config requires a subcommand: init")
+	}
+
+	fs := flag.NewFlagSet("config init", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Replace an existing config")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+
+	return &Command{
+		Type:  CommandConfigInit,
+		Force: *force,
+	}, nil
+}
+
+// parseAVExclusionCommand parses the av-exclusion command
+func (c *CLI) parseAVExclusionCommand(args []string) (*Command, error) {
+	fs := flag.NewFlagSet("av-exclusion", flag.ContinueOnError)
+	path := fs.String("path", "", "VRChat Tools directory path (auto-detect if empty)")
+	target := fs.String("target", "", "Patch target to auto-detect, e.g. vrchat-beta (default: vrchat)")
+	add := fs.Bool("add", false, "Register the Microsoft Defender exclusion after asking for consent")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *path != "" && *target != "" {
+		return nil, fmt.Errorf("av-exclusion takes either -path or -target")
+	}
+
+	return &Command{
+		Type:         CommandAVExclusion,
+		Path:         *path,
+		Target:       *target,
+		AddExclusion: *add,
+	}, nil
+}
+
+// parseTUICommand parses the tui command
+func (c *CLI) parseTUICommand(args []string) (*Command, error) {
+	fs := flag.NewFlagSet("tui", flag.ContinueOnError)
+	port := fs.Int("port", 0, "Server port (from config if 0)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return &Command{
+		Type: CommandTUI,
+		Port: *port,
+	}, nil
+}
+
+// parseAutostartCommand parses the autostart command and its subcommand
+func (c *CLI) parseAutostartCommand(args []string) (*Command, error) {
+	if len(args) == 0 || (args[0] != "enable" && args[0] != "disable" && args[0] != "status") {
+		return nil, fmt.Errorf("autostart requires a subcommand: enable, disable or status")
+	}
+
+	fs := flag.NewFlagSet("autostart "+args[0], flag.ContinueOnError)
+	app := fs.String("gui", "", "Desktop app to start minimized instead of the server (enable only)")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	if *app != "" && args[0] != "enable" {
+		return nil, fmt.Errorf("autostart -gui only applies to enable")
+	}
+
+	return &Command{
+		Type:   CommandAutostart,
+		Action: args[0],
+		App:    *app,
 	}, nil
 }
 
@@ -166,30 +556,123 @@ Usage:
   vrcvideocacher [command] [flags]
 
 Available Commands:
-  server      Start HTTP API server
-  patch       Patch VRChat's yt-dlp.exe with stub
-  unpatch     Restore original VRChat's yt-dlp.exe
-  update      Update VRCYouTubePatcher to latest version
-  version     Print version information
-  help        Print this help message
+  server          Start HTTP API server
+  patch           Patch VRChat's yt-dlp.exe with stub
+  unpatch         Restore original VRChat's yt-dlp.exe
+  update          Update VRCYouTubePatcher to latest version
+  cache-mode      Switch a running server between caching and pass-through
+  doctor          Check patch targets for unknown yt-dlp binaries
+  move-cache      Move the cache directory to a new location
+  support-bundle  Collect logs, config and diagnostics into a zip for bug reports
+  loadtest        Measure getvideo and file serving latency of a running server
+  uninstall       Unpatch every target and optionally delete cache, config and tools
+  config init     Write a documented default config and print the paths in use
+  av-exclusion    Print or register a Microsoft Defender exclusion for a Tools directory
+  tui             Terminal dashboard of a running server, e.g. over SSH
+  autostart       Enable, disable or show starting at login (enable|disable|status)
+  version         Print version information
+  help            Print this help message
 
 Server Flags:
-  -port int   Server port (default: 8080)
+  -port int    Server port (default: 8080)
+  -safe-mode   Troubleshooting mode: pass every video through with caching,
+               cookies, additional yt-dlp args and blocklists off
 
 Patch/Unpatch Flags:
-  -path string   VRChat Tools directory path (auto-detect if empty)
+  -path string     VRChat Tools directory path (auto-detect if empty)
+  -target string   Target to auto-detect: vrchat, vrchat-beta, resonite or
+                   chilloutvr (default: vrchat)
+  -all             Patch every detected target at once (patch only)
+  -no-elevate      When the Tools directory is not writable, fail instead of
+                   asking for administrator rights (patch only)
 
 Update Flags:
-  -check   Only check for updates without installing
+  -check      Only check for updates without installing
+  -rollback   Restore the version the last update replaced
+
+Cache-mode Flags:
+  -enabled bool   Enable caching (default: true)
+  -port int       Server port (default: from config)
+
+Doctor Flags:
+  -offline   Only use the bundled known hash list
+
+Move-cache Flags:
+  -path string   New cache directory path (required)
+  -port int      Server port (default: from config)
+
+Support-bundle Flags:
+  -output string   Zip file to write (default: vrcvideocacher-support-<time>.zip)
+  -port int        Server port to collect logs and history from (default: from config)
+  -offline         Only use the bundled known hash list for diagnostics
+
+Loadtest Flags:
+  -port int            Server port (default: from config)
+  -concurrency int     Number of concurrent clients (default: 8)
+  -duration duration   How long to run (default: 10s)
+  -url string          Comma-separated video URLs to request (default: cached
+                       videos; uncached videos are queued for download)
+
+Uninstall Flags:
+  -port int   Server port checked for a running server (default: from config)
+  -cache      Also delete cached videos and saved cookies
+  -config     Also delete the config
+  -tools      Also delete the downloaded yt-dlp and aria2c
+  -all        Delete cache, config and tools
+
+Config init Flags:
+  -force   Replace an existing config (and its config.md)
+
+Av-exclusion Flags:
+  -path string     VRChat Tools directory path (auto-detect if empty)
+  -target string   Target to auto-detect (default: vrchat)
+  -add             Register the exclusion after asking for confirmation
+                   (needs an elevated prompt)
+
+Tui Flags:
+  -port int   Server port (default: from config)
+  Keys: p patch, u unpatch, t switch target, space pause/resume downloads,
+        c toggle caching, r refresh, q quit
+
+Autostart Flags:
+  -gui string   Desktop app to start minimized at login instead of
+                "vrcvideocacher server" (enable only)
+
+Exit Codes:
+  0   Success
+  1   Any other failure, including invalid commands and flags
+  2   Config error: the config cannot be read, written or is invalid
+  3   Patch failure: patching or unpatching a Tools directory failed
+  4   Network error: the server, GitHub or another host is unreachable
+  5   Not found: the game, Tools directory or file does not exist
+  6   Already running: a server is already running on the port
 
 Examples:
   vrcvideocacher server
   vrcvideocacher server -port 9000
+  vrcvideocacher server --safe-mode
   vrcvideocacher patch
   vrcvideocacher patch -path "C:\Users\...\VRChat\Tools"
+  vrcvideocacher patch -target vrchat-beta
+  vrcvideocacher patch -all
   vrcvideocacher unpatch
   vrcvideocacher update
   vrcvideocacher update -check
+  vrcvideocacher update --rollback
+  vrcvideocacher cache-mode -enabled=false
+  vrcvideocacher doctor
+  vrcvideocacher move-cache -path "D:\VRCCache"
+  vrcvideocacher support-bundle
+  vrcvideocacher loadtest -concurrency 32 -duration 30s
+  vrcvideocacher uninstall -all
+  vrcvideocacher config init
+  vrcvideocacher config init --force
+  vrcvideocacher av-exclusion -target vrchat-beta
+  vrcvideocacher av-exclusion -add
+  vrcvideocacher tui
+  vrcvideocacher autostart enable
+  vrcvideocacher autostart enable -gui "C:\Program Files\VRCVideoCacher\vrcvideocacher.exe"
+  vrcvideocacher autostart status
   vrcvideocacher version
 `
 	fmt.Fprint(w, help)
@@ -206,18 +689,18 @@ func (c *CLI) Run(args []string) int {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
 		c.PrintHelp(os.Stderr)
-		return 1
+		return ExitFailure
 	}
 
 	switch cmd.Type {
 	case CommandHelp:
 		c.PrintHelp(os.Stdout)
-		return 0
+		return ExitOK
 	case CommandVersion:
 		c.PrintVersion(os.Stdout)
-		return 0
+		return ExitOK
 	default:
 		// Other commands will be handled by the main function
-		return 0
+		return ExitOK
 	}
 }
@@ -2,8 +2,10 @@ package cli
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -82,6 +84,27 @@ func TestParseCommand_ServerWithPort(t *testing.T) {
 	assert.Equal(t, 9000, cmd.Port)
 }
 
+func TestParseCommand_ServerSafeMode(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"server", "--safe-mode"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandServer, cmd.Type)
+	assert.True(t, cmd.SafeMode)
+	assert.Contains(t, cmd.String(), "safe mode")
+}
+
+func TestParseCommand_SupportBundle(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"support-bundle", "-output", "bundle.zip", "-offline"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandSupportBundle, cmd.Type)
+	assert.Equal(t, "bundle.zip", cmd.Path)
+	assert.True(t, cmd.Offline)
+	assert.Equal(t, "support-bundle (output: bundle.zip)", cmd.String())
+}
+
 func TestParseCommand_Patch(t *testing.T) {
 	cli := NewCLI("1.0.0")
 
@@ -99,6 +122,41 @@ func TestParseCommand_PatchWithPath(t *testing.T) {
 	assert.Equal(t, "/custom/path", cmd.Path)
 }
 
+func TestParseCommand_PatchWithTarget(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"patch", "-target", "vrchat-beta"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandPatch, cmd.Type)
+	assert.Equal(t, "vrchat-beta", cmd.Target)
+	assert.Equal(t, "patch (target: vrchat-beta)", cmd.String())
+
+	cmd, err = cli.ParseCommand([]string{"unpatch", "-target", "vrchat-beta"})
+	require.NoError(t, err)
+	assert.Equal(t, "vrchat-beta", cmd.Target)
+
+	_, err = cli.ParseCommand([]string{"patch", "-path", "/custom/path", "-target", "vrchat-beta"})
+	assert.Error(t, err)
+}
+
+func TestParseCommand_PatchAll(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"patch", "-all"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandPatch, cmd.Type)
+	assert.True(t, cmd.All)
+	assert.False(t, cmd.NoElevate)
+	assert.Equal(t, "patch (all targets)", cmd.String())
+
+	cmd, err = cli.ParseCommand([]string{"patch", "-all", "-no-elevate"})
+	require.NoError(t, err)
+	assert.True(t, cmd.NoElevate)
+
+	_, err = cli.ParseCommand([]string{"patch", "-all", "-target", "vrchat-beta"})
+	assert.Error(t, err)
+}
+
 func TestParseCommand_Unpatch(t *testing.T) {
 	cli := NewCLI("1.0.0")
 
@@ -124,6 +182,152 @@ func TestParseCommand_UpdateCheck(t *testing.T) {
 	assert.True(t, cmd.CheckOnly)
 }
 
+func TestParseCommand_UpdateRollback(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"update", "--rollback"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandUpdate, cmd.Type)
+	assert.True(t, cmd.Rollback)
+	assert.False(t, cmd.CheckOnly)
+	assert.Equal(t, "update (rollback)", cmd.String())
+
+	_, err = cli.ParseCommand([]string{"update", "-check", "-rollback"})
+	assert.Error(t, err)
+}
+
+func TestParseCommand_CacheMode(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"cache-mode"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandCacheMode, cmd.Type)
+	assert.True(t, cmd.Enabled)
+	assert.Equal(t, 0, cmd.Port)
+
+	cmd, err = cli.ParseCommand([]string{"cache-mode", "-enabled=false", "-port", "9000"})
+	require.NoError(t, err)
+	assert.False(t, cmd.Enabled)
+	assert.Equal(t, 9000, cmd.Port)
+}
+
+func TestParseCommand_Doctor(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"doctor"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandDoctor, cmd.Type)
+	assert.False(t, cmd.Offline)
+
+	cmd, err = cli.ParseCommand([]string{"doctor", "-offline"})
+	require.NoError(t, err)
+	assert.True(t, cmd.Offline)
+}
+
+func TestParseCommand_Uninstall(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"uninstall"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandUninstall, cmd.Type)
+	assert.False(t, cmd.RemoveCache || cmd.RemoveConfig || cmd.RemoveTools)
+	assert.Equal(t, "uninstall", cmd.String())
+
+	cmd, err = cli.ParseCommand([]string{"uninstall", "-cache", "-tools"})
+	require.NoError(t, err)
+	assert.True(t, cmd.RemoveCache)
+	assert.False(t, cmd.RemoveConfig)
+	assert.True(t, cmd.RemoveTools)
+	assert.Equal(t, "uninstall (remove: cache, tools)", cmd.String())
+
+	cmd, err = cli.ParseCommand([]string{"uninstall", "-all"})
+	require.NoError(t, err)
+	assert.True(t, cmd.RemoveCache && cmd.RemoveConfig && cmd.RemoveTools)
+}
+
+func TestParseCommand_ConfigInit(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"config", "init"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandConfigInit, cmd.Type)
+	assert.False(t, cmd.Force)
+	assert.Equal(t, "config init", cmd.String())
+
+	cmd, err = cli.ParseCommand([]string{"config", "init", "--force"})
+	require.NoError(t, err)
+	assert.True(t, cmd.Force)
+	assert.Equal(t, "config init (force)", cmd.String())
+
+	_, err = cli.ParseCommand([]string{"config"})
+	assert.Error(t, err)
+
+	_, err = cli.ParseCommand([]string{"config", "show"})
+	assert.Error(t, err)
+}
+
+func TestParseCommand_AVExclusion(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"av-exclusion"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandAVExclusion, cmd.Type)
+	assert.False(t, cmd.AddExclusion)
+	assert.Equal(t, "av-exclusion", cmd.String())
+
+	cmd, err = cli.ParseCommand([]string{"av-exclusion", "-target", "vrchat-beta", "-add"})
+	require.NoError(t, err)
+	assert.Equal(t, "vrchat-beta", cmd.Target)
+	assert.True(t, cmd.AddExclusion)
+	assert.Equal(t, "av-exclusion (target: vrchat-beta, add)", cmd.String())
+
+	_, err = cli.ParseCommand([]string{"av-exclusion", "-path", "/tools", "-target", "vrchat"})
+	assert.Error(t, err)
+}
+
+func TestParseCommand_TUI(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"tui", "-port", "9000"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandTUI, cmd.Type)
+	assert.Equal(t, 9000, cmd.Port)
+	assert.Equal(t, "tui (port: 9000)", cmd.String())
+}
+
+func TestParseCommand_Autostart(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"autostart", "enable", "-gui", "/opt/vrcvideocacher-gui"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandAutostart, cmd.Type)
+	assert.Equal(t, "enable", cmd.Action)
+	assert.Equal(t, "/opt/vrcvideocacher-gui", cmd.App)
+	assert.Equal(t, "autostart enable (app: /opt/vrcvideocacher-gui)", cmd.String())
+
+	cmd, err = cli.ParseCommand([]string{"autostart", "status"})
+	require.NoError(t, err)
+	assert.Equal(t, "autostart status", cmd.String())
+
+	_, err = cli.ParseCommand([]string{"autostart"})
+	assert.Error(t, err)
+
+	_, err = cli.ParseCommand([]string{"autostart", "disable", "-gui", "/opt/vrcvideocacher-gui"})
+	assert.Error(t, err)
+}
+
+func TestParseCommand_MoveCache(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"move-cache", "-path", "/new/cache"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandMoveCache, cmd.Type)
+	assert.Equal(t, "/new/cache", cmd.Path)
+
+	_, err = cli.ParseCommand([]string{"move-cache"})
+	assert.Error(t, err)
+}
+
 func TestParseCommand_InvalidCommand(t *testing.T) {
 	cli := NewCLI("1.0.0")
 
@@ -144,6 +348,11 @@ func TestPrintHelp(t *testing.T) {
 	assert.Contains(t, output, "patch")
 	assert.Contains(t, output, "unpatch")
 	assert.Contains(t, output, "update")
+
+	// Every exit code is documented
+	for _, code := range []int{ExitOK, ExitFailure, ExitConfig, ExitPatch, ExitNetwork, ExitNotFound, ExitAlreadyRunning} {
+		assert.Contains(t, output, fmt.Sprintf("\n  %d   ", code))
+	}
 }
 
 func TestPrintVersion(t *testing.T) {
@@ -167,6 +376,8 @@ func TestCommand_String(t *testing.T) {
 		{CommandPatch, "patch"},
 		{CommandUnpatch, "unpatch"},
 		{CommandUpdate, "update"},
+		{CommandDoctor, "doctor"},
+		{CommandUninstall, "uninstall"},
 	}
 
 	for _, tc := range testCases {
@@ -188,6 +399,7 @@ func TestCommand_StringWithDetails(t *testing.T) {
 		{"patch with path", &Command{Type: CommandPatch, Path: "/custom/path"}, "/custom/path"},
 		{"unpatch with path", &Command{Type: CommandUnpatch, Path: "/custom/path"}, "/custom/path"},
 		{"update check only", &Command{Type: CommandUpdate, CheckOnly: true}, "check"},
+		{"cache-mode disabled", &Command{Type: CommandCacheMode, Enabled: false}, "disabled"},
 		{"unknown type", &Command{Type: CommandType(999)}, "unknown"},
 	}
 
@@ -265,3 +477,24 @@ func TestParseCommand_UpdateInvalidFlag(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, cmd)
 }
+
+func TestParseCommand_LoadTest(t *testing.T) {
+	cli := NewCLI("1.0.0")
+
+	cmd, err := cli.ParseCommand([]string{"loadtest"})
+	require.NoError(t, err)
+	assert.Equal(t, CommandLoadTest, cmd.Type)
+	assert.Equal(t, 8, cmd.Concurrency)
+	assert.Equal(t, 10*time.Second, cmd.Duration)
+	assert.Empty(t, cmd.URLs)
+
+	cmd, err = cli.ParseCommand([]string{"loadtest", "-concurrency", "32", "-duration", "1m", "-url", "https://youtu.be/A, https://youtu.be/B"})
+	require.NoError(t, err)
+	assert.Equal(t, 32, cmd.Concurrency)
+	assert.Equal(t, time.Minute, cmd.Duration)
+	assert.Equal(t, []string{"https://youtu.be/A", "https://youtu.be/B"}, cmd.URLs)
+	assert.Equal(t, "loadtest (concurrency: 32, duration: 1m0s)", cmd.String())
+
+	_, err = cli.ParseCommand([]string{"loadtest", "-concurrency", "0"})
+	assert.Error(t, err)
+}
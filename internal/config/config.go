@@ -4,10 +4,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sync"
+	"time"
 
+	"vrcvideocacher/internal/logger"
 	"vrcvideocacher/pkg/models"
 )
 
@@ -15,6 +20,25 @@ var (
 	ErrInvalidPort       = errors.New("invalid port: must be between 1 and 65535")
 	ErrInvalidResolution = errors.New("invalid resolution: must be between 144 and 4320")
 	ErrInvalidCacheSize  = errors.New("invalid cache size: must be non-negative")
+	ErrInvalidBypassURL  = errors.New("invalid bypass URL pattern")
+	ErrInvalidAria2c     = errors.New("invalid aria2c connections: must be between 1 and 16")
+	ErrInvalidFPS        = errors.New("invalid frame rate limit: must be non-negative")
+	ErrInvalidPolicy     = errors.New("invalid source policy")
+	ErrInvalidOversize   = errors.New("invalid oversize settings: limit must be non-negative and action confirm or downgrade")
+	ErrInvalidOrigin     = errors.New("invalid CORS origin")
+	ErrInvalidProxy      = errors.New("invalid trusted proxy: must be an IP address or CIDR range")
+	ErrInvalidRPCPort    = errors.New("invalid RPC port: must be between 0 and 65535 and differ from the web server port")
+	ErrInvalidBlocklist  = errors.New("invalid blocklist subscription")
+	ErrInvalidForeign    = errors.New("invalid foreign file policy: must be ignore, adopt or quarantine")
+	ErrInvalidMirror     = errors.New("invalid download mirror")
+	ErrInvalidLANURL     = errors.New("invalid LAN base URL")
+	ErrInvalidLiveLimit  = errors.New("invalid live recording limit: must be at least 1 minute")
+	ErrInvalidWebhook    = errors.New("invalid report webhook URL")
+	ErrInvalidPacing     = errors.New("invalid yt-dlp pacing: delays must be non-negative")
+	ErrInvalidUpgrade    = errors.New("invalid upgrade threshold: must be non-negative")
+	ErrInvalidCompact    = errors.New("invalid compaction age: must be non-negative")
+	ErrInvalidLogLevel   = errors.New("invalid log level: must be debug, info, warn or error")
+	ErrInvalidRemind     = errors.New("invalid update reminder: must be an RFC 3339 time")
 )
 
 // Manager handles configuration loading, saving, and updates
@@ -64,8 +88,7 @@ func (m *Manager) Get() *models.Config {
 	defer m.mu.RUnlock()
 
 	// Return a copy to prevent external modifications
-	cfg := *m.config
-	return &cfg
+	return m.config.Clone()
 }
 
 // Update applies a function to the configuration and saves it
@@ -73,16 +96,25 @@ func (m *Manager) Update(fn func(*models.Config)) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Apply updates
-	fn(m.config)
+	// Apply updates to a copy so invalid changes are discarded
+	cfg := m.config.Clone()
+	fn(cfg)
 
 	// Validate
-	if err := Validate(m.config); err != nil {
+	if err := Validate(cfg); err != nil {
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
-	// Save to disk
-	return m.save()
+	prev := m.config
+	m.config = cfg
+
+	// Save to disk, keeping the previous config if that fails
+	if err := m.save(); err != nil {
+		m.config = prev
+		return err
+	}
+
+	return nil
 }
 
 // Save writes the current configuration to disk
@@ -119,7 +151,18 @@ func (m *Manager) save() error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(m.configPath, data, 0644); err != nil {
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// Write to a temp file and rename so a crash never leaves a partial config
+	tmpPath := m.configPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, m.configPath); err != nil {
+		os.Remove(tmpPath)
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -146,54 +189,330 @@ func mergeWithDefaults(cfg *models.Config) *models.Config {
 	if cfg.CacheYouTubeMaxLength == 0 {
 		cfg.CacheYouTubeMaxLength = defaults.CacheYouTubeMaxLength
 	}
+	if cfg.OversizeAction == "" {
+		cfg.OversizeAction = defaults.OversizeAction
+	}
+	if cfg.CacheForeignFiles == "" {
+		cfg.CacheForeignFiles = defaults.CacheForeignFiles
+	}
+	if cfg.Aria2cConnections == 0 {
+		cfg.Aria2cConnections = defaults.Aria2cConnections
+	}
+	if cfg.LiveMaxMinutes == 0 {
+		cfg.LiveMaxMinutes = defaults.LiveMaxMinutes
+	}
 	if cfg.BlockedURLs == nil {
 		cfg.BlockedURLs = defaults.BlockedURLs
 	}
+	if cfg.BlocklistURLs == nil {
+		cfg.BlocklistURLs = defaults.BlocklistURLs
+	}
+	if cfg.BlocklistRefreshHours == 0 {
+		cfg.BlocklistRefreshHours = defaults.BlocklistRefreshHours
+	}
+	if cfg.YtdlCookieAccounts == nil {
+		cfg.YtdlCookieAccounts = defaults.YtdlCookieAccounts
+	}
+	if cfg.BypassURLs == nil {
+		cfg.BypassURLs = defaults.BypassURLs
+	}
+	if cfg.CORSAllowedOrigins == nil {
+		cfg.CORSAllowedOrigins = defaults.CORSAllowedOrigins
+	}
+	if cfg.TrustedProxies == nil {
+		cfg.TrustedProxies = defaults.TrustedProxies
+	}
+	if cfg.DownloadMirrors == nil {
+		cfg.DownloadMirrors = defaults.DownloadMirrors
+	}
+	if cfg.SourcePolicies == nil {
+		cfg.SourcePolicies = defaults.SourcePolicies
+	}
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = defaults.LogLevel
+	}
 
 	return cfg
 }
 
-// Validate checks if the configuration is valid
-func Validate(cfg *models.Config) error {
-	// Validate port
-	if cfg.WebServerPort < 1 || cfg.WebServerPort > 65535 {
-		return ErrInvalidPort
-	}
+// fieldCheck validates one config field, named by its JSON key
+type fieldCheck struct {
+	field string
+	check func(cfg *models.Config) error
+}
 
-	// Validate resolution
-	if cfg.CacheYouTubeMaxRes < 144 || cfg.CacheYouTubeMaxRes > 4320 {
-		return ErrInvalidResolution
-	}
+// fieldChecks are the checks Validate runs, in order
+var fieldChecks = []fieldCheck{
+	{"webServerPort", func(cfg *models.Config) error {
+		if cfg.WebServerPort < 1 || cfg.WebServerPort > 65535 {
+			return ErrInvalidPort
+		}
+		return nil
+	}},
 
-	// Validate cache size
-	if cfg.CacheMaxSizeGB < 0 {
-		return ErrInvalidCacheSize
-	}
+	// 0 disables the JSON-RPC interface
+	{"rpcPort", func(cfg *models.Config) error {
+		if cfg.RPCPort < 0 || cfg.RPCPort > 65535 || (cfg.RPCPort != 0 && cfg.RPCPort == cfg.WebServerPort) {
+			return ErrInvalidRPCPort
+		}
+		return nil
+	}},
+
+	{"cacheYouTubeMaxRes", func(cfg *models.Config) error {
+		if cfg.CacheYouTubeMaxRes < 144 || cfg.CacheYouTubeMaxRes > 4320 {
+			return ErrInvalidResolution
+		}
+		return nil
+	}},
+
+	{"cacheYouTubeMaxFps", func(cfg *models.Config) error {
+		if cfg.CacheYouTubeMaxFPS < 0 {
+			return ErrInvalidFPS
+		}
+		return nil
+	}},
+
+	{"deviceProfile", func(cfg *models.Config) error {
+		if cfg.DeviceProfile != "" {
+			if _, err := FindProfile(cfg.DeviceProfile); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+
+	{"sourcePolicies", func(cfg *models.Config) error {
+		for source, policy := range cfg.SourcePolicies {
+			if err := validatePolicy(policy); err != nil {
+				return fmt.Errorf("%w %q: %v", ErrInvalidPolicy, source, err)
+			}
+		}
+		return nil
+	}},
+
+	{"cacheMaxSizeGb", func(cfg *models.Config) error {
+		if cfg.CacheMaxSizeGB < 0 {
+			return ErrInvalidCacheSize
+		}
+		return nil
+	}},
 
+	{"cacheMaxDownloadMb", func(cfg *models.Config) error {
+		if cfg.CacheMaxDownloadMB < 0 {
+			return ErrInvalidOversize
+		}
+		return nil
+	}},
+
+	{"oversizeAction", func(cfg *models.Config) error {
+		switch cfg.OversizeAction {
+		case models.OversizeConfirm, models.OversizeDowngrade:
+			return nil
+		default:
+			return ErrInvalidOversize
+		}
+	}},
+
+	// Empty keeps the default
+	{"cacheForeignFiles", func(cfg *models.Config) error {
+		switch cfg.CacheForeignFiles {
+		case "", models.ForeignIgnore, models.ForeignAdopt, models.ForeignQuarantine:
+			return nil
+		default:
+			return ErrInvalidForeign
+		}
+	}},
+
+	// aria2c caps connections per server at 16
+	{"aria2cConnections", func(cfg *models.Config) error {
+		if cfg.Aria2cEnabled && (cfg.Aria2cConnections < 1 || cfg.Aria2cConnections > 16) {
+			return ErrInvalidAria2c
+		}
+		return nil
+	}},
+
+	// The pause between yt-dlp downloads
+	{"ytdlPacing", func(cfg *models.Config) error {
+		if cfg.YtdlPacing < 0 {
+			return ErrInvalidPacing
+		}
+		return nil
+	}},
+	{"ytdlDelay", func(cfg *models.Config) error {
+		if cfg.YtdlDelay < 0 {
+			return ErrInvalidPacing
+		}
+		return nil
+	}},
+
+	// 0 disables upgrades
+	{"upgradeAfterHits", func(cfg *models.Config) error {
+		if cfg.UpgradeAfterHits < 0 {
+			return ErrInvalidUpgrade
+		}
+		return nil
+	}},
+
+	// 0 disables compaction
+	{"compactAfterDays", func(cfg *models.Config) error {
+		if cfg.CompactAfterDays < 0 {
+			return ErrInvalidCompact
+		}
+		return nil
+	}},
+
+	{"liveMaxMinutes", func(cfg *models.Config) error {
+		if cfg.LiveRecord && cfg.LiveMaxMinutes < 1 {
+			return ErrInvalidLiveLimit
+		}
+		return nil
+	}},
+
+	// Empty uses the address LAN clients connect to
+	{"lanBaseUrl", func(cfg *models.Config) error {
+		if cfg.LANBaseURL != "" {
+			u, err := url.Parse(cfg.LANBaseURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%w: %s", ErrInvalidLANURL, cfg.LANBaseURL)
+			}
+		}
+		return nil
+	}},
+
+	// "*" or scheme://host[:port] without a path
+	{"corsAllowedOrigins", func(cfg *models.Config) error {
+		for _, origin := range cfg.CORSAllowedOrigins {
+			if origin == models.CORSAllowAll {
+				continue
+			}
+			u, err := url.Parse(origin)
+			if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+				return fmt.Errorf("%w: %s", ErrInvalidOrigin, origin)
+			}
+		}
+		return nil
+	}},
+
+	// IP addresses or CIDR ranges
+	{"trustedProxies", func(cfg *models.Config) error {
+		for _, proxy := range cfg.TrustedProxies {
+			if _, err := netip.ParsePrefix(proxy); err == nil {
+				continue
+			}
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidProxy, proxy)
+			}
+		}
+		return nil
+	}},
+
+	// http(s) URLs refreshed at least hourly
+	{"blocklistUrls", func(cfg *models.Config) error {
+		for _, entry := range cfg.BlocklistURLs {
+			u, err := url.Parse(entry)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%w: %s", ErrInvalidBlocklist, entry)
+			}
+		}
+		return nil
+	}},
+	{"blocklistRefreshHours", func(cfg *models.Config) error {
+		if len(cfg.BlocklistURLs) > 0 && cfg.BlocklistRefreshHours < 1 {
+			return fmt.Errorf("%w: refresh interval must be at least 1 hour", ErrInvalidBlocklist)
+		}
+		return nil
+	}},
+
+	// http(s) base URLs
+	{"downloadMirrors", func(cfg *models.Config) error {
+		for _, mirror := range cfg.DownloadMirrors {
+			u, err := url.Parse(mirror)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%w: %s", ErrInvalidMirror, mirror)
+			}
+		}
+		return nil
+	}},
+
+	// Empty only writes reports to disk
+	{"reportWebhookUrl", func(cfg *models.Config) error {
+		if cfg.ReportWebhookURL != "" {
+			u, err := url.Parse(cfg.ReportWebhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%w: %s", ErrInvalidWebhook, cfg.ReportWebhookURL)
+			}
+		}
+		return nil
+	}},
+
+	{"logLevel", func(cfg *models.Config) error {
+		if _, err := logger.ParseLevel(cfg.LogLevel); err != nil {
+			return ErrInvalidLogLevel
+		}
+		return nil
+	}},
+
+	// Empty reminds at every check
+	{"updateRemindAfter", func(cfg *models.Config) error {
+		if cfg.UpdateRemindAfter != "" {
+			if _, err := time.Parse(time.RFC3339, cfg.UpdateRemindAfter); err != nil {
+				return ErrInvalidRemind
+			}
+		}
+		return nil
+	}},
+
+	// Regex patterns must compile
+	{"bypassUrls", func(cfg *models.Config) error {
+		for _, entry := range cfg.BypassURLs {
+			if pattern, ok := models.BypassRegex(entry); ok {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("%w: %s", ErrInvalidBypassURL, entry)
+				}
+			}
+		}
+		return nil
+	}},
+}
+
+// Validate checks if the configuration is valid, returning the first
+// problem found
+func Validate(cfg *models.Config) error {
+	for _, c := range fieldChecks {
+		if err := c.check(cfg); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// GetDataDir returns the application data directory
-func GetDataDir() string {
-	// Try to use LocalAppData on Windows
-	if appData := os.Getenv("LOCALAPPDATA"); appData != "" {
-		dataDir := filepath.Join(appData, "VRCVideoCacher")
-		os.MkdirAll(dataDir, 0755)
-		return dataDir
+// validatePolicy checks a single source policy
+func validatePolicy(policy models.SourcePolicy) error {
+	switch policy.Format {
+	case "", "mp4", "webm":
+	default:
+		return fmt.Errorf("unknown format %q", policy.Format)
 	}
 
-	// Fallback to home directory
-	if home, err := os.UserHomeDir(); err == nil {
-		dataDir := filepath.Join(home, ".vrcvideocacher")
-		os.MkdirAll(dataDir, 0755)
-		return dataDir
+	if policy.MaxRes != 0 && (policy.MaxRes < 144 || policy.MaxRes > 4320) {
+		return ErrInvalidResolution
 	}
 
-	// Last resort: current directory
-	return "."
-}
+	switch policy.Response {
+	case "", models.ResponseURL, models.ResponseJSON:
+	case models.ResponseTemplate:
+		if policy.Template == "" {
+			return errors.New("template response without template")
+		}
+	default:
+		return fmt.Errorf("unknown response %q", policy.Response)
+	}
 
-// GetDefaultConfigPath returns the default configuration file path
-func GetDefaultConfigPath() string {
-	return filepath.Join(GetDataDir(), "config.json")
+	if policy.Template != "" {
+		if _, err := ParseResponseTemplate(policy.Template); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -23,6 +23,16 @@ func TestNewManager(t *testing.T) {
 	cfg := manager.Get()
 	assert.Equal(t, 9696, cfg.WebServerPort)
 	assert.True(t, cfg.PatchVRC)
+	assert.False(t, cfg.PatchVRCBeta)
+	assert.True(t, cfg.WeeklyReport)
+	assert.Empty(t, cfg.ReportWebhookURL)
+	assert.Equal(t, 2, cfg.YtdlPacing)
+	assert.False(t, cfg.CachePreviews)
+	assert.Zero(t, cfg.UpgradeAfterHits)
+	assert.Zero(t, cfg.CompactAfterDays)
+	assert.Empty(t, cfg.TrustedProxies)
+	assert.Empty(t, cfg.WinePrefix)
+	assert.Equal(t, "info", cfg.LogLevel)
 	assert.False(t, cfg.CacheYouTube)
 }
 
@@ -174,6 +184,208 @@ func TestValidate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "resolution",
 		},
+		{
+			name: "invalid bypass regex",
+			setup: func(cfg *models.Config) {
+				cfg.BypassURLs = []string{"example.com", "regex:("}
+			},
+			wantErr: true,
+			errMsg:  "bypass",
+		},
+		{
+			name: "invalid bypass regex with surrounding spaces",
+			setup: func(cfg *models.Config) {
+				cfg.BypassURLs = []string{" regex:( "}
+			},
+			wantErr: true,
+			errMsg:  "bypass",
+		},
+		{
+			name: "invalid aria2c connections",
+			setup: func(cfg *models.Config) {
+				cfg.Aria2cEnabled = true
+				cfg.Aria2cConnections = 32
+			},
+			wantErr: true,
+			errMsg:  "aria2c",
+		},
+		{
+			name: "invalid live recording limit",
+			setup: func(cfg *models.Config) {
+				cfg.LiveRecord = true
+				cfg.LiveMaxMinutes = -1
+			},
+			wantErr: true,
+			errMsg:  "live recording",
+		},
+		{
+			name: "negative upgrade threshold",
+			setup: func(cfg *models.Config) {
+				cfg.UpgradeAfterHits = -1
+			},
+			wantErr: true,
+			errMsg:  "upgrade",
+		},
+		{
+			name: "negative compaction age",
+			setup: func(cfg *models.Config) {
+				cfg.CompactAfterDays = -1
+			},
+			wantErr: true,
+			errMsg:  "compaction",
+		},
+		{
+			name: "negative yt-dlp pacing",
+			setup: func(cfg *models.Config) {
+				cfg.YtdlPacing = -1
+			},
+			wantErr: true,
+			errMsg:  "pacing",
+		},
+		{
+			name: "invalid oversize action",
+			setup: func(cfg *models.Config) {
+				cfg.CacheMaxDownloadMB = 2048
+				cfg.OversizeAction = "delete"
+			},
+			wantErr: true,
+			errMsg:  "oversize",
+		},
+		{
+			name: "invalid source policy",
+			setup: func(cfg *models.Config) {
+				cfg.SourcePolicies = map[string]models.SourcePolicy{
+					models.SourceResonite: {Format: "mkv"},
+				}
+			},
+			wantErr: true,
+			errMsg:  "policy",
+		},
+		{
+			name: "template response without template",
+			setup: func(cfg *models.Config) {
+				cfg.SourcePolicies = map[string]models.SourcePolicy{
+					"cvr": {Response: models.ResponseTemplate},
+				}
+			},
+			wantErr: true,
+			errMsg:  "template",
+		},
+		{
+			name: "invalid response template",
+			setup: func(cfg *models.Config) {
+				cfg.SourcePolicies = map[string]models.SourcePolicy{
+					"cvr": {Response: models.ResponseTemplate, Template: "{{.URL"},
+				}
+			},
+			wantErr: true,
+			errMsg:  "policy",
+		},
+		{
+			name: "valid response template",
+			setup: func(cfg *models.Config) {
+				cfg.SourcePolicies = map[string]models.SourcePolicy{
+					"cvr": {Response: models.ResponseTemplate, Template: `{"url": {{json .URL}}}`},
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "RPC port same as web server",
+			setup: func(cfg *models.Config) {
+				cfg.RPCPort = cfg.WebServerPort
+			},
+			wantErr: true,
+			errMsg:  "RPC",
+		},
+		{
+			name: "invalid CORS origin",
+			setup: func(cfg *models.Config) {
+				cfg.CORSAllowedOrigins = []string{"*", "http://localhost:5173/app"}
+			},
+			wantErr: true,
+			errMsg:  "CORS",
+		},
+		{
+			name: "trusted proxies",
+			setup: func(cfg *models.Config) {
+				cfg.TrustedProxies = []string{"127.0.0.1", "192.168.1.0/24", "::1"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid trusted proxy",
+			setup: func(cfg *models.Config) {
+				cfg.TrustedProxies = []string{"proxy.lan"}
+			},
+			wantErr: true,
+			errMsg:  "trusted proxy",
+		},
+		{
+			name: "invalid blocklist subscription",
+			setup: func(cfg *models.Config) {
+				cfg.BlocklistURLs = []string{"ftp://example.com/list.txt"}
+			},
+			wantErr: true,
+			errMsg:  "blocklist",
+		},
+		{
+			name: "blocklist refresh below an hour",
+			setup: func(cfg *models.Config) {
+				cfg.BlocklistURLs = []string{"https://example.com/list.txt"}
+				cfg.BlocklistRefreshHours = 0
+			},
+			wantErr: true,
+			errMsg:  "blocklist",
+		},
+		{
+			name: "invalid LAN base URL",
+			setup: func(cfg *models.Config) {
+				cfg.LANBaseURL = "192.168.1.5:9696"
+			},
+			wantErr: true,
+			errMsg:  "LAN",
+		},
+		{
+			name: "invalid download mirror",
+			setup: func(cfg *models.Config) {
+				cfg.DownloadMirrors = []string{"mirror.example.com/github"}
+			},
+			wantErr: true,
+			errMsg:  "mirror",
+		},
+		{
+			name: "invalid report webhook",
+			setup: func(cfg *models.Config) {
+				cfg.ReportWebhookURL = "discord.com/api/webhooks/1/abc"
+			},
+			wantErr: true,
+			errMsg:  "webhook",
+		},
+		{
+			name: "invalid log level",
+			setup: func(cfg *models.Config) {
+				cfg.LogLevel = "verbose"
+			},
+			wantErr: true,
+			errMsg:  "log level",
+		},
+		{
+			name: "invalid update reminder",
+			setup: func(cfg *models.Config) {
+				cfg.UpdateRemindAfter = "in 3 days"
+			},
+			wantErr: true,
+			errMsg:  "update reminder",
+		},
+		{
+			name: "invalid foreign file policy",
+			setup: func(cfg *models.Config) {
+				cfg.CacheForeignFiles = "delete"
+			},
+			wantErr: true,
+			errMsg:  "foreign",
+		},
 		{
 			name: "negative cache size",
 			setup: func(cfg *models.Config) {
@@ -203,9 +415,3 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
-
-func TestGetDataDir(t *testing.T) {
-	dir := GetDataDir()
-	assert.NotEmpty(t, dir)
-	assert.DirExists(t, dir)
-}
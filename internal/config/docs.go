@@ -0,0 +1,143 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"vrcvideocacher/pkg/models"
+)
+
+// ErrConfigExists is returned by Init when the config file already exists
+var ErrConfigExists = errors.New("config file already exists")
+
+// DocsFileName is the file written next to the config by Init, documenting
+// every field
+const DocsFileName = "config.md"
+
+// fieldDoc describes one config field, named by its JSON key
+type fieldDoc struct {
+	field string
+	doc   string
+}
+
+// fieldDocs documents the config fields in the order of models.Config
+var fieldDocs = []fieldDoc{
+	{"webServerUrl", "Base URL of cached video links handed to players on this machine"},
+	{"webServerPort", "Port of the HTTP server"},
+	{"lanMode", "Listen on all interfaces so other devices on the LAN can play cached videos"},
+	{"lanBaseUrl", "Base URL of cached video links handed to LAN clients (empty: the address the client connected to)"},
+	{"corsAllowedOrigins", "Origins allowed to call the API from a browser; `*` allows any"},
+	{"trustedProxies", "IP addresses or CIDR ranges of reverse proxies whose X-Forwarded-For and X-Real-IP headers are believed for the request log and LAN checks"},
+	{"rpcPort", "Port of the JSON-RPC control server on 127.0.0.1 (0: disabled)"},
+	{"ytdlPath", "Path of yt-dlp, relative to the data directory unless absolute"},
+	{"ytdlUseCookies", "Pass saved YouTube cookies to yt-dlp"},
+	{"ytdlCookieAccounts", "Cookie accounts in the order they are tried; unlisted accounts are tried last"},
+	{"ytdlAutoUpdate", "Keep yt-dlp up to date"},
+	{"ytdlAdditionalArgs", "Extra command line arguments for yt-dlp"},
+	{"ytdlDubLanguage", "Preferred audio language of dubbed videos (empty: original audio)"},
+	{"ytdlDelay", "Extra fixed pause between downloads in seconds"},
+	{"ytdlPacing", "Pause between yt-dlp downloads in seconds"},
+	{"aria2cEnabled", "Download with aria2c using several connections"},
+	{"aria2cConnections", "Connections per aria2c download (1-16)"},
+	{"audioNormalize", "Normalize the loudness of finished downloads with ffmpeg"},
+	{"cachePreviews", "Render preview thumbnails of cached videos in the background"},
+	{"upgradeAfterHits", "Cache hits after which a video cached below the resolution limit is downloaded again (0: never)"},
+	{"compactAfterDays", "Days without playback after which a video is re-encoded to AV1 or HEVC (0: never)"},
+	{"embedMetadata", "Embed the title, uploader and chapters in downloads"},
+	{"liveRecord", "Record live streams (costs a yt-dlp check per cache miss)"},
+	{"liveFromStart", "Record live streams from their start instead of from now"},
+	{"liveMaxMinutes", "Length limit of a live recording in minutes"},
+	{"scheduleUpcoming", "Queue upcoming premieres and streams again once they are due"},
+	{"cachePath", "Cache directory (empty: `Cache` in the data directory)"},
+	{"downloadTempPath", "Directory for downloads in progress (empty: the cache directory)"},
+	{"blockedUrls", "URLs and domains refused by getvideo"},
+	{"blockRedirect", "URL answered for blocked videos instead of 403 Forbidden"},
+	{"blocklistUrls", "Community blocklists to subscribe to"},
+	{"blocklistRefreshHours", "Hours between blocklist refreshes"},
+	{"crasherProtection", "Refuse URLs known to crash players"},
+	{"bypassUrls", "Domains, or regular expressions prefixed with `regex:`, passed through without caching"},
+	{"cacheYouTube", "Cache YouTube videos"},
+	{"cacheYouTubeMaxRes", "Resolution limit of cached YouTube videos (144-4320)"},
+	{"cacheYouTubeMaxLength", "Length limit of cached YouTube videos in minutes"},
+	{"cacheYouTubeMaxFps", "Frame rate limit of cached YouTube videos (0: no limit)"},
+	{"cacheYouTubeAvoidAv1", "Prefer other codecs over AV1, for players without an AV1 decoder"},
+	{"deviceProfile", "Device profile last applied: quest2, quest3, pcvr-1080 or pcvr-4k"},
+	{"sourcePolicies", "Format, resolution and response overrides per request source (vrchat, resonite, chilloutvr)"},
+	{"cacheMaxSizeGb", "Cache size limit in GB, least recently played videos are evicted first (0: no limit)"},
+	{"cacheForeignFiles", "Video files in the cache directory not downloaded by the cacher: ignore, adopt or quarantine"},
+	{"cacheMaxDownloadMb", "Estimated download size above which oversizeAction applies (0: no limit)"},
+	{"oversizeAction", "What to do with oversized downloads: confirm or downgrade"},
+	{"cachePyPyDance", "Cache PyPyDance videos"},
+	{"cacheVRDancing", "Cache VRDancing videos"},
+	{"patchVRC", "Patch VRChat at startup"},
+	{"patchVRCBeta", "Patch the VRChat beta at startup"},
+	{"patchResonite", "Patch Resonite at startup"},
+	{"patchChilloutVR", "Patch ChilloutVR at startup"},
+	{"resonitePath", "Resonite install directory (empty: auto-detect)"},
+	{"winePrefix", "Wine prefix or CrossOver bottle (the directory holding drive_c) to detect games in (empty: LOCALAPPDATA, or the CrossOver and Whisky bottles on macOS)"},
+	{"autoUpdate", "Check for and install VRCYouTubePatcher updates"},
+	{"skipUpdateVersion", "Do not announce this version (or older ones) as an update"},
+	{"updateRemindAfter", "Do not announce updates before this RFC 3339 time (set by \"remind me later\")"},
+	{"githubToken", "GitHub token for update checks, sent to api.github.com only"},
+	{"downloadMirrors", "Base URLs replacing https://github.com for tool and update downloads"},
+	{"weeklyReport", "Write a weekly cache report"},
+	{"reportWebhookUrl", "Discord webhook the weekly report is also posted to"},
+	{"logLevel", "Minimum level of log records: debug, info, warn or error"},
+	{"startMinimized", "Start the window minimized (also when started at login)"},
+	{"minimizeToTray", "Hide the window when minimized or closed instead of minimizing to the taskbar or quitting; start the app again to restore it"},
+}
+
+// WriteDocs writes the documentation of every config field with its
+// default value as a Markdown table
+func WriteDocs(w io.Writer) error {
+	data, err := json.Marshal(models.DefaultConfig())
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var defaults map[string]json.RawMessage
+	if err := json.Unmarshal(data, &defaults); err != nil {
+		return fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("# config.json\n\n")
+	b.WriteString("Fields missing from config.json take their default. Changes made\n")
+	b.WriteString("outside the settings UI apply at the next start.\n\n")
+	b.WriteString("| Field | Default | Description |\n")
+	b.WriteString("|-------|---------|-------------|\n")
+	for _, d := range fieldDocs {
+		fmt.Fprintf(&b, "| %s | `%s` | %s |\n", d.field, defaults[d.field], d.doc)
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// Init writes the default config to path and the field documentation to
+// DocsFileName next to it, returning the documentation's path. An existing
+// config is only replaced with force
+func Init(path string, force bool) (string, error) {
+	if _, err := os.Stat(path); err == nil && !force {
+		return "", fmt.Errorf("%w: %s", ErrConfigExists, path)
+	}
+	m := &Manager{configPath: path, config: models.DefaultConfig()}
+	if err := m.save(); err != nil {
+		return "", err
+	}
+
+	docsPath := filepath.Join(filepath.Dir(path), DocsFileName)
+	f, err := os.Create(docsPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to write config docs: %w", err)
+	}
+	defer f.Close()
+	if err := WriteDocs(f); err != nil {
+		return "", fmt.Errorf("failed to write config docs: %w", err)
+	}
+	return docsPath, nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/pkg/models"
+)
+
+func TestFieldDocs(t *testing.T) {
+	data, err := json.Marshal(models.DefaultConfig())
+	require.NoError(t, err)
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &fields))
+
+	documented := make(map[string]bool)
+	for _, d := range fieldDocs {
+		assert.Contains(t, fields, d.field, "documented field does not exist")
+		assert.False(t, documented[d.field], "%s documented twice", d.field)
+		assert.NotEmpty(t, d.doc)
+		documented[d.field] = true
+	}
+	for field := range fields {
+		assert.True(t, documented[field], "%s is not documented", field)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDocs(&buf))
+	assert.Contains(t, buf.String(), "| webServerPort | `9696` | Port of the HTTP server |")
+}
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data", "config.json")
+
+	docsPath, err := Init(path, false)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "data", DocsFileName), docsPath)
+	assert.FileExists(t, docsPath)
+
+	mgr, err := NewManager(path)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultConfig(), mgr.Get())
+
+	// An existing config is kept without force
+	require.NoError(t, mgr.Update(func(cfg *models.Config) { cfg.WebServerPort = 8000 }))
+	_, err = Init(path, false)
+	assert.ErrorIs(t, err, ErrConfigExists)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "8000")
+
+	_, err = Init(path, true)
+	require.NoError(t, err)
+	mgr, err = NewManager(path)
+	require.NoError(t, err)
+	assert.Equal(t, 9696, mgr.Get().WebServerPort)
+}
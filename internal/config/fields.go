@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"vrcvideocacher/pkg/models"
+)
+
+// FieldError is a problem with one config field, named by its JSON key, for
+// settings forms to show next to the field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidateFields runs the checks of Validate on every field and returns all
+// problems found, in the order Validate checks them
+func ValidateFields(cfg *models.Config) []FieldError {
+	problems := []FieldError{}
+	for _, c := range fieldChecks {
+		if err := c.check(cfg); err != nil {
+			problems = append(problems, FieldError{Field: c.field, Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// CheckSystem checks cfg against this machine: the server URL parses,
+// directories exist or can be created, configured files exist and ports
+// are free. current is the running config, whose ports this app already
+// holds; nil checks every port
+func CheckSystem(cfg, current *models.Config) []FieldError {
+	problems := []FieldError{}
+	add := func(field string, err error) {
+		if err != nil {
+			problems = append(problems, FieldError{Field: field, Message: err.Error()})
+		}
+	}
+
+	u, err := url.Parse(cfg.WebServerURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		add("webServerUrl", fmt.Errorf("invalid web server URL: %s", cfg.WebServerURL))
+	}
+
+	host := "127.0.0.1"
+	if cfg.LANMode {
+		host = "0.0.0.0"
+	}
+	if current == nil || cfg.WebServerPort != current.WebServerPort || cfg.LANMode != current.LANMode {
+		add("webServerPort", checkPortFree(host, cfg.WebServerPort))
+	}
+	if cfg.RPCPort > 0 && (current == nil || cfg.RPCPort != current.RPCPort) {
+		add("rpcPort", checkPortFree("127.0.0.1", cfg.RPCPort))
+	}
+
+	add("cachePath", checkDir(cfg.CachePath))
+	add("downloadTempPath", checkDir(cfg.DownloadTempPath))
+
+	// The default relative yt-dlp path is resolved at startup
+	if filepath.IsAbs(cfg.YtdlPath) {
+		add("ytdlPath", checkFile(cfg.YtdlPath))
+	}
+	if cfg.ResonitePath != "" {
+		add("resonitePath", checkFile(cfg.ResonitePath))
+	}
+	if cfg.WinePrefix != "" {
+		add("winePrefix", checkFile(filepath.Join(cfg.WinePrefix, "drive_c")))
+	}
+
+	return problems
+}
+
+// checkPortFree reports an error if port cannot be listened on at host
+func checkPortFree(host string, port int) error {
+	if port < 1 || port > 65535 {
+		return nil // Reported by Validate
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort(host, fmt.Sprint(port)))
+	if err != nil {
+		return fmt.Errorf("port %d is in use", port)
+	}
+	l.Close()
+	return nil
+}
+
+// checkDir reports an error unless dir is empty, an existing directory, or
+// can be created in an existing parent directory
+func checkDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	switch {
+	case err == nil && !info.IsDir():
+		return fmt.Errorf("%s is not a directory", dir)
+	case err == nil:
+		return nil
+	}
+	if _, err := os.Stat(filepath.Dir(dir)); err != nil {
+		return fmt.Errorf("parent directory of %s does not exist", dir)
+	}
+	return nil
+}
+
+// checkFile reports an error if path does not exist
+func checkFile(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s does not exist", path)
+	}
+	return nil
+}
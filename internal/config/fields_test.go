@@ -0,0 +1,67 @@
+package config
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/pkg/models"
+)
+
+// fieldNames returns the fields of problems
+func fieldNames(problems []FieldError) []string {
+	fields := []string{}
+	for _, p := range problems {
+		fields = append(fields, p.Field)
+	}
+	return fields
+}
+
+func TestValidateFields(t *testing.T) {
+	cfg := models.DefaultConfig()
+	assert.Empty(t, ValidateFields(cfg))
+
+	// Every problem is reported, not only the first
+	cfg.WebServerPort = 0
+	cfg.YtdlDelay = -1
+	cfg.ReportWebhookURL = "discord"
+	problems := ValidateFields(cfg)
+	assert.Equal(t, []string{"webServerPort", "ytdlDelay", "reportWebhookUrl"}, fieldNames(problems))
+	assert.Equal(t, ErrInvalidPort.Error(), problems[0].Message)
+	assert.Equal(t, Validate(cfg).Error(), problems[0].Message)
+}
+
+func TestCheckSystem(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	busy := l.Addr().(*net.TCPAddr).Port
+
+	cfg := models.DefaultConfig()
+	cfg.CachePath = filepath.Join(dir, "cache")
+	cfg.YtdlPath = file
+	current := cfg.Clone()
+	cfg.WebServerPort = busy
+	assert.Equal(t, []string{"webServerPort"}, fieldNames(CheckSystem(cfg, current)))
+
+	// The running server's own port is not in use by anyone else
+	current.WebServerPort = busy
+	assert.Empty(t, CheckSystem(cfg, current))
+
+	cfg.WebServerURL = "localhost:9696"
+	cfg.CachePath = filepath.Join(dir, "missing", "cache")
+	cfg.DownloadTempPath = file
+	cfg.YtdlPath = filepath.Join(dir, "yt-dlp.exe")
+	cfg.ResonitePath = filepath.Join(dir, "Resonite")
+	cfg.WinePrefix = dir
+	assert.Equal(t, []string{"webServerUrl", "cachePath", "downloadTempPath", "ytdlPath", "resonitePath", "winePrefix"},
+		fieldNames(CheckSystem(cfg, current)))
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"vrcvideocacher/pkg/models"
+)
+
+// Device profile names
+const (
+	ProfileQuest2   = "quest2"
+	ProfileQuest3   = "quest3"
+	ProfilePCVR1080 = "pcvr-1080"
+	ProfilePCVR4K   = "pcvr-4k"
+)
+
+var ErrUnknownProfile = errors.New("unknown device profile")
+
+// DeviceProfile bundles download limits suited to a headset or display
+type DeviceProfile struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	MaxRes      int    `json:"maxRes"`
+	MaxFPS      int    `json:"maxFps"`
+	AllowAV1    bool   `json:"allowAv1"`
+}
+
+// deviceProfiles lists the built-in profiles
+// Quest 2 has no AV1 hardware decoder, so AV1 is only allowed where it is
+// commonly decoded in hardware
+var deviceProfiles = []DeviceProfile{
+	{ProfileQuest2, "Quest 2", 1080, 60, false},
+	{ProfileQuest3, "Quest 3", 1440, 60, true},
+	{ProfilePCVR1080, "PCVR 1080p", 1080, 60, false},
+	{ProfilePCVR4K, "PCVR 4K", 2160, 60, true},
+}
+
+// Profiles returns the built-in device profiles
+func Profiles() []DeviceProfile {
+	return append([]DeviceProfile{}, deviceProfiles...)
+}
+
+// FindProfile looks up a device profile by name
+func FindProfile(name string) (DeviceProfile, error) {
+	for _, p := range deviceProfiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return DeviceProfile{}, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+}
+
+// Apply sets the profile's limits on cfg
+func (p DeviceProfile) Apply(cfg *models.Config) {
+	cfg.DeviceProfile = p.Name
+	cfg.CacheYouTubeMaxRes = p.MaxRes
+	cfg.CacheYouTubeMaxFPS = p.MaxFPS
+	cfg.CacheYouTubeAvoidAV1 = !p.AllowAV1
+}
+
+// ApplyProfile applies the named device profile and saves the config
+func (m *Manager) ApplyProfile(name string) error {
+	profile, err := FindProfile(name)
+	if err != nil {
+		return err
+	}
+
+	return m.Update(profile.Apply)
+}
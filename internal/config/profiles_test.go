@@ -0,0 +1,45 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/pkg/models"
+)
+
+func TestApplyProfile(t *testing.T) {
+	manager, err := NewManager(filepath.Join(t.TempDir(), "config.json"))
+	require.NoError(t, err)
+
+	require.NoError(t, manager.ApplyProfile(ProfileQuest2))
+	cfg := manager.Get()
+	assert.Equal(t, ProfileQuest2, cfg.DeviceProfile)
+	assert.Equal(t, 1080, cfg.CacheYouTubeMaxRes)
+	assert.Equal(t, 60, cfg.CacheYouTubeMaxFPS)
+	assert.True(t, cfg.CacheYouTubeAvoidAV1)
+
+	require.NoError(t, manager.ApplyProfile(ProfilePCVR4K))
+	cfg = manager.Get()
+	assert.Equal(t, 2160, cfg.CacheYouTubeMaxRes)
+	assert.False(t, cfg.CacheYouTubeAvoidAV1)
+
+	// Unknown profiles leave the config untouched
+	err = manager.ApplyProfile("vive")
+	assert.ErrorIs(t, err, ErrUnknownProfile)
+	assert.Equal(t, ProfilePCVR4K, manager.Get().DeviceProfile)
+}
+
+func TestProfilesAreValid(t *testing.T) {
+	for _, profile := range Profiles() {
+		cfg := models.DefaultConfig()
+		profile.Apply(cfg)
+		assert.NoError(t, Validate(cfg), profile.Name)
+	}
+
+	cfg := models.DefaultConfig()
+	cfg.DeviceProfile = "vive"
+	assert.ErrorIs(t, Validate(cfg), ErrUnknownProfile)
+}
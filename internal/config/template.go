@@ -0,0 +1,21 @@
+package config
+
+import (
+	"encoding/json"
+	"text/template"
+)
+
+// templateFuncs are the functions available to response templates
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. {{json .URL}} for a quoted string
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// ParseResponseTemplate parses a getvideo response template
+// Templates use text/template syntax with the extra function json
+func ParseResponseTemplate(text string) (*template.Template, error) {
+	return template.New("response").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
@@ -0,0 +1,269 @@
+package cookies
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const netscapeHeader = "# Netscape HTTP Cookie File"
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported cookie format")
+	ErrNoYouTubeCookies  = errors.New("no youtube.com cookies found")
+	ErrNotLoggedIn       = errors.New("LOGIN_INFO cookie missing: not logged in")
+)
+
+// Cookie is a single cookie in a cookie jar
+type Cookie struct {
+	Domain            string
+	IncludeSubdomains bool
+	Path              string
+	Secure            bool
+	HTTPOnly          bool
+	Expires           int64
+	Name              string
+	Value             string
+}
+
+// jsonCookie is a cookie as exported by common browser extensions
+type jsonCookie struct {
+	Domain         string   `json:"domain"`
+	HostOnly       bool     `json:"hostOnly"`
+	Path           string   `json:"path"`
+	Secure         bool     `json:"secure"`
+	HTTPOnly       bool     `json:"httpOnly"`
+	ExpirationDate *float64 `json:"expirationDate"`
+	Expiry         *float64 `json:"expiry"`
+	Name           string   `json:"name"`
+	Value          string   `json:"value"`
+}
+
+// Normalize parses cookies in Netscape, JSON export or header string format,
+// validates that they contain a logged-in YouTube session and returns them
+// in the Netscape format yt-dlp expects
+func Normalize(data string) (string, error) {
+	jar, err := Parse(data)
+	if err != nil {
+		return "", err
+	}
+
+	if err := Validate(jar); err != nil {
+		return "", err
+	}
+
+	return FormatNetscape(jar), nil
+}
+
+// Parse detects the format of data and parses it into cookies
+func Parse(data string) ([]Cookie, error) {
+	trimmed := strings.TrimSpace(data)
+	if trimmed == "" {
+		return nil, ErrUnsupportedFormat
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{"):
+		return parseJSON(trimmed)
+	case strings.Contains(trimmed, "\t"):
+		// Untrimmed, as the last cookie may end in the tab of an empty value
+		return parseNetscape(data)
+	default:
+		return parseHeader(trimmed)
+	}
+}
+
+// Validate checks that a cookie jar holds a logged-in YouTube session
+func Validate(jar []Cookie) error {
+	hasYouTube := false
+	for _, c := range jar {
+		if !isYouTubeDomain(c.Domain) {
+			continue
+		}
+		hasYouTube = true
+
+		if c.Name == "LOGIN_INFO" && c.Value != "" {
+			return nil
+		}
+	}
+
+	if !hasYouTube {
+		return ErrNoYouTubeCookies
+	}
+
+	return ErrNotLoggedIn
+}
+
+// FormatNetscape writes cookies in Netscape cookies.txt format
+func FormatNetscape(jar []Cookie) string {
+	var b strings.Builder
+	b.WriteString(netscapeHeader + "\n")
+
+	for _, c := range jar {
+		domain := c.Domain
+		if c.HTTPOnly {
+			domain = "#HttpOnly_" + domain
+		}
+
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain,
+			netscapeBool(c.IncludeSubdomains),
+			c.Path,
+			netscapeBool(c.Secure),
+			c.Expires,
+			c.Name,
+			c.Value,
+		)
+	}
+
+	return b.String()
+}
+
+// parseNetscape parses Netscape cookies.txt lines
+func parseNetscape(data string) ([]Cookie, error) {
+	jar := make([]Cookie, 0)
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		// Only line endings are trimmed: a cookie with an empty value ends
+		// in a tab
+		line := strings.TrimRight(scanner.Text(), "\r\n")
+
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%w: expected 7 tab-separated fields, got %d", ErrUnsupportedFormat, len(fields))
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid expiry %q", ErrUnsupportedFormat, fields[4])
+		}
+
+		jar = append(jar, Cookie{
+			Domain:            fields[0],
+			IncludeSubdomains: strings.EqualFold(fields[1], "TRUE"),
+			Path:              fields[2],
+			Secure:            strings.EqualFold(fields[3], "TRUE"),
+			HTTPOnly:          httpOnly,
+			Expires:           expires,
+			Name:              fields[5],
+			Value:             fields[6],
+		})
+	}
+
+	return jar, nil
+}
+
+// parseJSON parses a browser extension JSON export
+// Accepts a bare array or an object with a "cookies" array
+func parseJSON(data string) ([]Cookie, error) {
+	var exported []jsonCookie
+	if strings.HasPrefix(data, "{") {
+		var wrapper struct {
+			Cookies []jsonCookie `json:"cookies"`
+		}
+		if err := json.Unmarshal([]byte(data), &wrapper); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+		}
+		exported = wrapper.Cookies
+	} else if err := json.Unmarshal([]byte(data), &exported); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+
+	jar := make([]Cookie, 0, len(exported))
+	for _, c := range exported {
+		if c.Name == "" || c.Domain == "" {
+			continue
+		}
+
+		expiry := c.ExpirationDate
+		if expiry == nil {
+			expiry = c.Expiry
+		}
+
+		var expires int64
+		if expiry != nil {
+			expires = int64(math.Floor(*expiry))
+		}
+
+		path := c.Path
+		if path == "" {
+			path = "/"
+		}
+
+		domain := c.Domain
+		if !c.HostOnly && !strings.HasPrefix(domain, ".") {
+			domain = "." + domain
+		}
+
+		jar = append(jar, Cookie{
+			Domain:            domain,
+			IncludeSubdomains: !c.HostOnly,
+			Path:              path,
+			Secure:            c.Secure,
+			HTTPOnly:          c.HTTPOnly,
+			Expires:           expires,
+			Name:              c.Name,
+			Value:             c.Value,
+		})
+	}
+
+	return jar, nil
+}
+
+// parseHeader parses a "name=value; name2=value2" Cookie header string
+// Header cookies carry no domain, so they are scoped to .youtube.com
+func parseHeader(data string) ([]Cookie, error) {
+	if idx := strings.Index(data, ":"); idx >= 0 && strings.EqualFold(strings.TrimSpace(data[:idx]), "cookie") {
+		data = data[idx+1:]
+	}
+
+	jar := make([]Cookie, 0)
+	for _, part := range strings.Split(data, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("%w: invalid cookie pair %q", ErrUnsupportedFormat, part)
+		}
+
+		jar = append(jar, Cookie{
+			Domain:            ".youtube.com",
+			IncludeSubdomains: true,
+			Path:              "/",
+			Secure:            true,
+			Name:              name,
+			Value:             strings.TrimSpace(value),
+		})
+	}
+
+	return jar, nil
+}
+
+// isYouTubeDomain reports whether a cookie domain belongs to youtube.com
+func isYouTubeDomain(domain string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	return domain == "youtube.com" || strings.HasSuffix(domain, ".youtube.com")
+}
+
+// netscapeBool formats a boolean as used in cookies.txt
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
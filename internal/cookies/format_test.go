@@ -0,0 +1,142 @@
+package cookies
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantErr  error
+		contains []string
+	}{
+		{
+			name: "netscape",
+			data: `# Netscape HTTP Cookie File
+#HttpOnly_.youtube.com	TRUE	/	TRUE	1900000000	LOGIN_INFO	login
+.youtube.com	TRUE	/	TRUE	0	PREF	pref`,
+			contains: []string{
+				"#HttpOnly_.youtube.com\tTRUE\t/\tTRUE\t1900000000\tLOGIN_INFO\tlogin",
+				".youtube.com\tTRUE\t/\tTRUE\t0\tPREF\tpref",
+			},
+		},
+		{
+			name: "netscape with empty values",
+			data: "# Netscape HTTP Cookie File\r\n" +
+				".youtube.com\tTRUE\t/\tTRUE\t0\tEMPTY\t\r\n" +
+				".youtube.com\tTRUE\t/\tTRUE\t1900000000\tLOGIN_INFO\tlogin\r\n" +
+				".youtube.com\tTRUE\t/\tFALSE\t0\tLAST\t\n",
+			contains: []string{
+				".youtube.com\tTRUE\t/\tTRUE\t0\tEMPTY\t\n",
+				".youtube.com\tTRUE\t/\tTRUE\t1900000000\tLOGIN_INFO\tlogin\n",
+				".youtube.com\tTRUE\t/\tFALSE\t0\tLAST\t\n",
+			},
+		},
+		{
+			name: "json array export",
+			data: `[
+				{"domain": ".youtube.com", "name": "LOGIN_INFO", "value": "login", "path": "/", "secure": true, "httpOnly": true, "expirationDate": 1900000000.5},
+				{"domain": "www.youtube.com", "hostOnly": true, "name": "YSC", "value": "ysc", "path": "/", "secure": true}
+			]`,
+			contains: []string{
+				"#HttpOnly_.youtube.com\tTRUE\t/\tTRUE\t1900000000\tLOGIN_INFO\tlogin",
+				"www.youtube.com\tFALSE\t/\tTRUE\t0\tYSC\tysc",
+			},
+		},
+		{
+			name: "json object export",
+			data: `{"cookies": [{"domain": "youtube.com", "name": "LOGIN_INFO", "value": "login", "expiry": 1900000000}]}`,
+			contains: []string{
+				".youtube.com\tTRUE\t/\tFALSE\t1900000000\tLOGIN_INFO\tlogin",
+			},
+		},
+		{
+			name: "header string",
+			data: "Cookie: LOGIN_INFO=login; SID=abc=def",
+			contains: []string{
+				".youtube.com\tTRUE\t/\tTRUE\t0\tLOGIN_INFO\tlogin",
+				".youtube.com\tTRUE\t/\tTRUE\t0\tSID\tabc=def",
+			},
+		},
+		{
+			name:    "not logged in",
+			data:    "PREF=pref; YSC=ysc",
+			wantErr: ErrNotLoggedIn,
+		},
+		{
+			name:    "other domain only",
+			data:    ".example.com\tTRUE\t/\tTRUE\t0\tLOGIN_INFO\tlogin",
+			wantErr: ErrNoYouTubeCookies,
+		},
+		{
+			name:    "lookalike domain",
+			data:    ".notyoutube.com\tTRUE\t/\tTRUE\t0\tLOGIN_INFO\tlogin",
+			wantErr: ErrNoYouTubeCookies,
+		},
+		{
+			name:    "malformed netscape line",
+			data:    ".youtube.com\tTRUE\tLOGIN_INFO",
+			wantErr: ErrUnsupportedFormat,
+		},
+		{
+			name:    "malformed json",
+			data:    `[{"domain": `,
+			wantErr: ErrUnsupportedFormat,
+		},
+		{
+			name:    "free text",
+			data:    "not a valid cookie",
+			wantErr: ErrUnsupportedFormat,
+		},
+		{
+			name:    "empty",
+			data:    "",
+			wantErr: ErrUnsupportedFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.data)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Contains(t, got, netscapeHeader)
+			for _, line := range tt.contains {
+				assert.Contains(t, got, line)
+			}
+		})
+	}
+}
+
+func FuzzNormalize(f *testing.F) {
+	f.Add("# Netscape HTTP Cookie File\n.youtube.com\tTRUE\t/\tTRUE\t0\tLOGIN_INFO\tabc\n")
+	f.Add(`[{"domain":".youtube.com","name":"LOGIN_INFO","value":"abc","expirationDate":1e300}]`)
+	f.Add(`{"domain":".youtube.com","name":"LOGIN_INFO","value":"abc"}`)
+	f.Add("LOGIN_INFO=abc; SID=def")
+	f.Add("#HttpOnly_.youtube.com\tTRUE\t/\tTRUE\t-1\tLOGIN_INFO\ta\tb\n")
+
+	f.Fuzz(func(t *testing.T, data string) {
+		normalized, err := Normalize(data)
+		if err != nil {
+			return
+		}
+
+		// The output is what yt-dlp reads, so it must parse back to the
+		// same cookies
+		again, err := Normalize(normalized)
+		if err != nil {
+			t.Fatalf("Normalize(%q) = %q, which does not normalize: %v", data, normalized, err)
+		}
+		if again != normalized {
+			t.Errorf("Normalize(%q) = %q, normalizing again gives %q", data, normalized, again)
+		}
+	})
+}
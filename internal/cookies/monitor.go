@@ -0,0 +1,104 @@
+package cookies
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Monitor periodically checks stored cookies and notifies when an account
+// needs to be re-authenticated
+type Monitor struct {
+	mu       sync.Mutex
+	store    *Store
+	interval time.Duration
+	notify   func(AccountStatus)
+	notified map[string]time.Time
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewMonitor creates a monitor checking the store every interval
+func NewMonitor(store *Store, interval time.Duration, notify func(AccountStatus)) *Monitor {
+	return &Monitor{
+		store:    store,
+		interval: interval,
+		notify:   notify,
+		notified: make(map[string]time.Time),
+	}
+}
+
+// SetNotify replaces the notification callback
+func (m *Monitor) SetNotify(notify func(AccountStatus)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notify = notify
+}
+
+// Start begins periodic checks
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		m.Check()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.Check()
+			}
+		}
+	}()
+}
+
+// Stop ends periodic checks
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		m.wg.Wait()
+	}
+}
+
+// Check notifies once for each account needing refresh
+// An account is reported again only after its cookies have been replaced
+func (m *Monitor) Check() {
+	for _, status := range m.store.Statuses() {
+		if !status.NeedsRefresh {
+			continue
+		}
+
+		m.mu.Lock()
+		last, seen := m.notified[status.Account]
+		if seen && last.Equal(status.UpdatedAt) {
+			m.mu.Unlock()
+			continue
+		}
+		m.notified[status.Account] = status.UpdatedAt
+		notify := m.notify
+		m.mu.Unlock()
+
+		if notify != nil {
+			notify(*status)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package cookies
+
+import (
+	"os"
+	"time"
+)
+
+// refreshWindow is how long before expiry an account is reported as needing refresh
+const refreshWindow = 24 * time.Hour
+
+// authCookieNames are the cookies that keep a YouTube session logged in
+var authCookieNames = map[string]bool{
+	"LOGIN_INFO":     true,
+	"SID":            true,
+	"HSID":           true,
+	"SSID":           true,
+	"APISID":         true,
+	"SAPISID":        true,
+	"__Secure-1PSID": true,
+	"__Secure-3PSID": true,
+}
+
+// AccountStatus describes the freshness of a stored cookie set
+type AccountStatus struct {
+	Account      string    `json:"account"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	Expired      bool      `json:"expired"`
+	NeedsRefresh bool      `json:"needsRefresh"`
+}
+
+// Status returns the freshness of an account's cookies
+func (s *Store) Status(account string) (*AccountStatus, error) {
+	path, err := s.Path(account)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, ErrAccountNotFound
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if account == "" {
+		account = DefaultAccount
+	}
+
+	status := &AccountStatus{
+		Account:   account,
+		UpdatedAt: info.ModTime(),
+		ExpiresAt: ParseExpiry(string(data)),
+	}
+
+	if !status.ExpiresAt.IsZero() {
+		now := time.Now()
+		status.Expired = !now.Before(status.ExpiresAt)
+		status.NeedsRefresh = status.ExpiresAt.Sub(now) < refreshWindow
+	}
+
+	return status, nil
+}
+
+// Statuses returns the status of every stored account
+func (s *Store) Statuses() []*AccountStatus {
+	accounts := s.List()
+
+	statuses := make([]*AccountStatus, 0, len(accounts))
+	for _, account := range accounts {
+		if status, err := s.Status(account); err == nil {
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses
+}
+
+// ParseExpiry returns the earliest expiry of the auth cookies in a Netscape
+// cookies.txt file, or the zero time if none of them expire
+func ParseExpiry(cookies string) time.Time {
+	var earliest time.Time
+
+	jar, err := parseNetscape(cookies)
+	if err != nil {
+		return earliest
+	}
+
+	for _, c := range jar {
+		if !authCookieNames[c.Name] || c.Expires <= 0 {
+			continue
+		}
+
+		t := time.Unix(c.Expires, 0)
+		if earliest.IsZero() || t.Before(earliest) {
+			earliest = t
+		}
+	}
+
+	return earliest
+}
@@ -0,0 +1,82 @@
+package cookies
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// netscapeCookies builds a cookies.txt with LOGIN_INFO expiring at expires
+func netscapeCookies(expires time.Time) string {
+	return fmt.Sprintf(`# Netscape HTTP Cookie File
+.youtube.com	TRUE	/	TRUE	0	YSC	session
+#HttpOnly_.youtube.com	TRUE	/	TRUE	%d	LOGIN_INFO	login
+.youtube.com	TRUE	/	TRUE	%d	PREF	pref
+`, expires.Unix(), expires.Add(-48*time.Hour).Unix())
+}
+
+func TestParseExpiry(t *testing.T) {
+	expires := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+
+	// Non-auth cookies (PREF) are ignored
+	assert.True(t, ParseExpiry(netscapeCookies(expires)).Equal(expires))
+
+	// Session-only cookies have no expiry
+	assert.True(t, ParseExpiry(".youtube.com	TRUE	/	TRUE	0	SID	x").IsZero())
+	assert.True(t, ParseExpiry("garbage").IsZero())
+}
+
+func TestStatus(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	require.NoError(t, store.Save("fresh", netscapeCookies(time.Now().Add(72*time.Hour))))
+	require.NoError(t, store.Save("soon", netscapeCookies(time.Now().Add(time.Hour))))
+	require.NoError(t, store.Save("old", netscapeCookies(time.Now().Add(-time.Hour))))
+
+	fresh, err := store.Status("fresh")
+	require.NoError(t, err)
+	assert.False(t, fresh.NeedsRefresh)
+	assert.False(t, fresh.Expired)
+
+	soon, err := store.Status("soon")
+	require.NoError(t, err)
+	assert.True(t, soon.NeedsRefresh)
+	assert.False(t, soon.Expired)
+
+	old, err := store.Status("old")
+	require.NoError(t, err)
+	assert.True(t, old.Expired)
+
+	assert.Len(t, store.Statuses(), 3)
+
+	_, err = store.Status("missing")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+}
+
+func TestMonitorNotifiesOnce(t *testing.T) {
+	store := NewStore(t.TempDir())
+	require.NoError(t, store.Save("soon", netscapeCookies(time.Now().Add(time.Hour))))
+	require.NoError(t, store.Save("fresh", netscapeCookies(time.Now().Add(72*time.Hour))))
+
+	var notified []string
+	monitor := NewMonitor(store, time.Hour, func(status AccountStatus) {
+		notified = append(notified, status.Account)
+	})
+
+	monitor.Check()
+	monitor.Check()
+	assert.Equal(t, []string{"soon"}, notified)
+
+	// Replacing the cookies with another expiring set notifies again
+	require.NoError(t, store.Save("soon", netscapeCookies(time.Now().Add(2*time.Hour))))
+	path, err := store.Path("soon")
+	require.NoError(t, err)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	monitor.Check()
+	assert.Equal(t, []string{"soon", "soon"}, notified)
+}
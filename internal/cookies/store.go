@@ -0,0 +1,255 @@
+package cookies
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultAccount is the account used when no name is given
+	DefaultAccount = "default"
+
+	// defaultFileName is the cookies file of the default account, kept for
+	// compatibility with single-account installs
+	defaultFileName = "youtube_cookies.txt"
+
+	filePrefix = "youtube_cookies."
+	fileSuffix = ".txt"
+)
+
+var (
+	ErrInvalidAccount  = errors.New("invalid account name")
+	ErrAccountNotFound = errors.New("cookie account not found")
+)
+
+// accountNamePattern restricts account names to safe file name characters
+var accountNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Store manages named YouTube cookie sets stored as Netscape cookies.txt files
+type Store struct {
+	mu    sync.RWMutex
+	dir   string
+	order []string
+}
+
+// NewStore creates a cookie store rooted at dir
+func NewStore(dir string) *Store {
+	return &Store{
+		dir: dir,
+	}
+}
+
+// Relocate points the store at dir, moving over the cookie files of
+// accounts dir does not have yet, e.g. those earlier versions kept in the
+// cache directory. Files of accounts dir already has are removed, so no
+// cookies are left behind
+func (s *Store) Relocate(dir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if filepath.Clean(dir) == filepath.Clean(s.dir) {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cookies directory: %w", err)
+	}
+
+	for _, account := range s.list() {
+		src := filepath.Join(s.dir, fileName(account))
+		dst := filepath.Join(dir, fileName(account))
+		if _, err := os.Stat(dst); err != nil {
+			data, err := os.ReadFile(src)
+			if err != nil {
+				return fmt.Errorf("failed to read cookies file: %w", err)
+			}
+			if err := os.WriteFile(dst, data, 0600); err != nil {
+				return fmt.Errorf("failed to write cookies file: %w", err)
+			}
+		}
+		if err := os.Remove(src); err != nil {
+			return fmt.Errorf("failed to remove cookies file: %w", err)
+		}
+	}
+
+	s.dir = dir
+	return nil
+}
+
+// Save writes the cookies for an account, replacing any existing set
+func (s *Store) Save(account, cookies string) error {
+	path, err := s.path(account)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cookies directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(cookies), 0600); err != nil {
+		return fmt.Errorf("failed to write cookies file: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the cookies for an account
+func (s *Store) Delete(account string) error {
+	path, err := s.path(account)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrAccountNotFound
+		}
+		return fmt.Errorf("failed to delete cookies file: %w", err)
+	}
+
+	return nil
+}
+
+// Path returns the cookies file path for an existing account
+func (s *Store) Path(account string) (string, error) {
+	path, err := s.path(account)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrAccountNotFound
+	}
+
+	return path, nil
+}
+
+// List returns the names of all stored accounts, default first
+func (s *Store) List() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.list()
+}
+
+// SetOrder sets the preferred account fallback order
+// Accounts missing from the order are tried after the listed ones
+func (s *Store) SetOrder(order []string) error {
+	for _, account := range order {
+		if !accountNamePattern.MatchString(account) {
+			return fmt.Errorf("%w: %q", ErrInvalidAccount, account)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.order = append([]string{}, order...)
+	return nil
+}
+
+// Order returns the stored accounts in fallback order
+func (s *Store) Order() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	available := s.list()
+	seen := make(map[string]bool, len(available))
+	for _, account := range available {
+		seen[account] = false
+	}
+
+	result := make([]string, 0, len(available))
+	for _, account := range s.order {
+		if used, ok := seen[account]; ok && !used {
+			result = append(result, account)
+			seen[account] = true
+		}
+	}
+
+	for _, account := range available {
+		if !seen[account] {
+			result = append(result, account)
+		}
+	}
+
+	return result
+}
+
+// path returns the cookies file path for an account name
+func (s *Store) path(account string) (string, error) {
+	if account == "" {
+		account = DefaultAccount
+	}
+
+	if !accountNamePattern.MatchString(account) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAccount, account)
+	}
+
+	s.mu.RLock()
+	dir := s.dir
+	s.mu.RUnlock()
+
+	return filepath.Join(dir, fileName(account)), nil
+}
+
+// fileName returns the cookies file name of a valid account name
+func fileName(account string) string {
+	if account == DefaultAccount {
+		return defaultFileName
+	}
+	return filePrefix + account + fileSuffix
+}
+
+// list returns stored account names (must be called with lock held)
+func (s *Store) list() []string {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return []string{}
+	}
+
+	hasDefault := false
+	accounts := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+
+		if name == defaultFileName {
+			hasDefault = true
+			continue
+		}
+
+		if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+
+		account := strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix)
+		if accountNamePattern.MatchString(account) && account != DefaultAccount {
+			accounts = append(accounts, account)
+		}
+	}
+
+	sort.Strings(accounts)
+	if hasDefault {
+		accounts = append([]string{DefaultAccount}, accounts...)
+	}
+
+	return accounts
+}
@@ -0,0 +1,100 @@
+package cookies
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveAndPath(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	// Default account keeps the legacy file name
+	require.NoError(t, store.Save("", "cookies"))
+	path, err := store.Path(DefaultAccount)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "youtube_cookies.txt"), path)
+
+	require.NoError(t, store.Save("burner", "cookies"))
+	path, err = store.Path("burner")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "youtube_cookies.burner.txt"), path)
+
+	_, err = store.Path("missing")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+}
+
+func TestRelocate(t *testing.T) {
+	oldDir := t.TempDir()
+	store := NewStore(oldDir)
+	require.NoError(t, store.Save("", "old default"))
+	require.NoError(t, store.Save("burner", "old burner"))
+
+	// Accounts already in the new directory are kept
+	newDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(newDir, "youtube_cookies.burner.txt"), []byte("new burner"), 0600))
+
+	require.NoError(t, store.Relocate(newDir))
+	assert.Equal(t, []string{DefaultAccount, "burner"}, store.List())
+
+	data, err := os.ReadFile(filepath.Join(newDir, "youtube_cookies.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "old default", string(data))
+	data, err = os.ReadFile(filepath.Join(newDir, "youtube_cookies.burner.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "new burner", string(data))
+
+	// Nothing is left in the old directory
+	assert.NoFileExists(t, filepath.Join(oldDir, "youtube_cookies.txt"))
+	assert.NoFileExists(t, filepath.Join(oldDir, "youtube_cookies.burner.txt"))
+
+	path, err := store.Path("burner")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(newDir, "youtube_cookies.burner.txt"), path)
+}
+
+func TestInvalidAccountName(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	tests := []string{"../evil", "a b", "name.txt"}
+	for _, name := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, store.Save(name, "cookies"), ErrInvalidAccount)
+		})
+	}
+
+	assert.ErrorIs(t, store.SetOrder([]string{"ok", "../bad"}), ErrInvalidAccount)
+}
+
+func TestListAndDelete(t *testing.T) {
+	store := NewStore(t.TempDir())
+	assert.Empty(t, store.List())
+
+	require.NoError(t, store.Save("zeta", "cookies"))
+	require.NoError(t, store.Save("alpha", "cookies"))
+	require.NoError(t, store.Save(DefaultAccount, "cookies"))
+
+	assert.Equal(t, []string{"default", "alpha", "zeta"}, store.List())
+
+	require.NoError(t, store.Delete("alpha"))
+	assert.Equal(t, []string{"default", "zeta"}, store.List())
+	assert.ErrorIs(t, store.Delete("alpha"), ErrAccountNotFound)
+}
+
+func TestOrder(t *testing.T) {
+	store := NewStore(t.TempDir())
+	require.NoError(t, store.Save(DefaultAccount, "cookies"))
+	require.NoError(t, store.Save("burner", "cookies"))
+	require.NoError(t, store.Save("main", "cookies"))
+
+	// Without a preference, list order is used
+	assert.Equal(t, []string{"default", "burner", "main"}, store.Order())
+
+	// Preferred accounts first, missing ones skipped, rest appended
+	require.NoError(t, store.SetOrder([]string{"main", "gone", "main"}))
+	assert.Equal(t, []string{"main", "default", "burner"}, store.Order())
+}
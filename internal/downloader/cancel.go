@@ -0,0 +1,98 @@
+package downloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"vrcvideocacher/internal/events"
+)
+
+// ErrCancelled is the error of downloads stopped by Cancel
+var ErrCancelled = errors.New("download cancelled")
+
+// Cancel removes a download from the queue, or kills its yt-dlp process if
+// it is active. Downloads awaiting confirmation or waiting for their start
+// are discarded too. The cancelled request stays queryable like a finished
+// one
+func (d *Downloader) Cancel(videoID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The worker finishes an active download once yt-dlp exits
+	if req, ok := d.active[videoID]; ok {
+		req.cancelled = true
+		if req.cancel != nil {
+			req.cancel()
+		}
+		return nil
+	}
+
+	for i, req := range d.queue {
+		if req.VideoID == videoID {
+			d.queue = append(d.queue[:i:i], d.queue[i+1:]...)
+			d.dropCancelled(req)
+			return nil
+		}
+	}
+
+	if req, ok := d.pending[videoID]; ok {
+		delete(d.pending, videoID)
+		d.dropCancelled(req)
+		return nil
+	}
+
+	if req, ok := d.upcoming[videoID]; ok {
+		if req.timer != nil {
+			req.timer.Stop()
+		}
+		delete(d.upcoming, videoID)
+		d.dropCancelled(req)
+		return nil
+	}
+
+	return ErrNotFound
+}
+
+// isCancelled reports whether Cancel was called for req
+func (d *Downloader) isCancelled(req *DownloadRequest) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return req.cancelled
+}
+
+// finishCancelled moves an active request stopped by Cancel to the recent
+// requests, reporting whether it was cancelled
+func (d *Downloader) finishCancelled(req *DownloadRequest) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !req.cancelled {
+		return false
+	}
+	delete(d.active, req.VideoID)
+	d.dropCancelled(req)
+	return true
+}
+
+// dropCancelled marks req as cancelled and keeps it with the recent requests
+// Must be called with lock held
+func (d *Downloader) dropCancelled(req *DownloadRequest) {
+	req.Status = StatusCancelled
+	req.Error = ErrCancelled
+	req.FinishedAt = time.Now()
+	d.addRecent(req)
+	d.publish(events.DownloadCancelled, req)
+}
+
+// removePartial deletes the unfinished files yt-dlp left in dir for a video
+func removePartial(dir, videoID string) {
+	matches, _ := filepath.Glob(filepath.Join(dir, videoID+".*"))
+	for _, path := range matches {
+		if name := filepath.Base(path); strings.Contains(name, ".part") || strings.HasSuffix(name, ".ytdl") {
+			os.Remove(path) // Ignore errors
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/pkg/models"
+)
+
+// ErrCompactFailed is returned when a cached file cannot be re-encoded to a
+// smaller variant
+var ErrCompactFailed = errors.New("compaction failed")
+
+// compactInterval is how often cached entries are checked for compaction
+const compactInterval = time.Hour
+
+// compactCodecs are the ffmpeg video encoder settings of each variant
+var compactCodecs = map[string][]string{
+	models.VariantAV1:  {"-c:v", "libsvtav1", "-crf", "35", "-preset", "8"},
+	models.VariantHEVC: {"-c:v", "libx265", "-crf", "28", "-preset", "medium", "-tag:v", "hvc1"},
+}
+
+// compactWorker re-encodes cold entries in the background while
+// compactAfterDays is set
+func (d *Downloader) compactWorker() {
+	defer d.workerWg.Done()
+
+	ticker := time.NewTicker(compactInterval)
+	defer ticker.Stop()
+
+	// Entries that could not be compacted are not tried again until restart
+	failed := make(map[string]bool)
+
+	for {
+		if days := d.snapshot().CompactAfterDays; days > 0 {
+			d.compactEntries(days, failed)
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// compactEntries re-encodes the entries not played for days, coldest first, skipping those in failed and adding new failures to it.
+// It stops as soon as a download is waiting, the rest is compacted on a
+// later round
+func (d *Downloader) compactEntries(days int, failed map[string]bool) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	avoidAV1 := d.snapshot().CacheYouTubeAvoidAV1
+
+	entries := d.cache.ListEntries()
+	slices.Reverse(entries)
+
+	for _, entry := range entries {
+		if d.ctx.Err() != nil || d.GetQueueLength() > 0 || d.GetActiveDownloads() > 0 {
+			return
+		}
+		if entry.LastAccess.After(cutoff) {
+			// Coldest first, so the rest are warmer still
+			return
+		}
+		// Uploads are kept exactly as the user provided them
+		if failed[entry.ID] || entry.Variant != "" || entry.Origin == models.OriginUploaded {
+			continue
+		}
+		variant := compactVariant(entry.FileName, avoidAV1)
+		if variant == "" {
+			continue
+		}
+
+		if err := d.compactEntry(entry, variant); err != nil {
+			failed[entry.ID] = true
+			d.log().Warn("Compaction failed", "video", entry.ID, logger.Err(err))
+			continue
+		}
+		d.log().Info("Compacted", "video", entry.ID, "file", variant)
+	}
+}
+
+// compactVariant returns the codec a file is re-encoded to, AV1 unless
+// players cannot decode it, or empty if its container has no option left.
+// HEVC only fits in mp4
+func compactVariant(fileName string, avoidAV1 bool) string {
+	switch {
+	case !avoidAV1:
+		return models.VariantAV1
+	case strings.EqualFold(filepath.Ext(fileName), ".mp4"):
+		return models.VariantHEVC
+	default:
+		return ""
+	}
+}
+
+// compactEntry re-encodes the video of entry to variant, copying the audio,
+// and swaps it in only if it came out smaller
+func (d *Downloader) compactEntry(entry *models.CacheEntry, variant string) error {
+	filePath, err := d.cache.GetFilePath(entry.ID)
+	if err != nil {
+		return err
+	}
+
+	container := "mp4"
+	if strings.EqualFold(filepath.Ext(filePath), ".webm") {
+		container = "webm"
+	}
+
+	// Not a video extension, so a leftover is never picked up by a scan
+	tmpPath := filePath + ".compacting"
+	defer os.Remove(tmpPath) // No-op once replaced
+
+	args := []string{"-hide_banner", "-nostats", "-y", "-i", filePath}
+	args = append(args, compactCodecs[variant]...)
+	args = append(args, "-c:a", "copy", "-f", container)
+	if container == "mp4" {
+		args = append(args, "-movflags", "+faststart")
+	}
+	args = append(args, tmpPath)
+
+	if out, err := exec.CommandContext(d.ctx, d.ffmpegPath, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %v: %s", ErrCompactFailed, err, lastLine(string(out)))
+	}
+
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCompactFailed, err)
+	}
+	if info.Size() >= entry.Size {
+		return fmt.Errorf("%w: %s is not smaller than the original", ErrCompactFailed, variant)
+	}
+
+	return d.cache.ReplaceVariant(entry.ID, entry.Version(), tmpPath, variant)
+}
@@ -0,0 +1,97 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestCompactVariant(t *testing.T) {
+	assert.Equal(t, models.VariantAV1, compactVariant("a.mp4", false))
+	assert.Equal(t, models.VariantAV1, compactVariant("a.webm", false))
+	assert.Equal(t, models.VariantHEVC, compactVariant("a.mp4", true))
+	assert.Empty(t, compactVariant("a.webm", true))
+}
+
+func TestCompactEntries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	cacheDir := t.TempDir()
+	old := time.Now().AddDate(0, 0, -10)
+	for name, content := range map[string]string{
+		"COLD.mp4":   "a large cold video",
+		"TINY.mp4":   "tiny",
+		"WARM.mp4":   "a large warm video",
+		"UPLOAD.mp4": "a large uploaded video",
+	} {
+		path := filepath.Join(cacheDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		if name != "WARM.mp4" {
+			require.NoError(t, os.Chtimes(path, old, old))
+		}
+	}
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	_, err := cacheMgr.AddLocalFile("UPLOAD", filepath.Join(cacheDir, "UPLOAD.mp4"))
+	require.NoError(t, err)
+
+	// Fake ffmpeg: writes a small file to the last argument, logging each
+	// call
+	calls := filepath.Join(t.TempDir(), "calls")
+	script := filepath.Join(t.TempDir(), "fake-ffmpeg.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "$@" >> `+calls+`
+for last; do :; done
+printf small > "$last"
+`), 0755))
+
+	dl := NewDownloader(&models.Config{YtdlPath: "yt-dlp"}, cacheMgr, 1)
+	dl.ffmpegPath = script
+	require.NoError(t, dl.Start())
+	defer dl.Stop()
+
+	failed := make(map[string]bool)
+	dl.compactEntries(7, failed)
+
+	entry, err := cacheMgr.GetEntry("COLD")
+	require.NoError(t, err)
+	assert.Equal(t, models.VariantAV1, entry.Variant)
+	assert.Equal(t, int64(len("small")), entry.Size)
+
+	// Files that do not shrink are kept as they are
+	entry, err = cacheMgr.GetEntry("TINY")
+	require.NoError(t, err)
+	assert.Empty(t, entry.Variant)
+	assert.True(t, failed["TINY"])
+	data, err := os.ReadFile(filepath.Join(cacheDir, "TINY.mp4"))
+	require.NoError(t, err)
+	assert.Equal(t, "tiny", string(data))
+	assert.NoFileExists(t, filepath.Join(cacheDir, "TINY.mp4.compacting"))
+
+	// Recently played entries and uploads are left alone
+	for _, id := range []string{"WARM", "UPLOAD"} {
+		entry, err = cacheMgr.GetEntry(id)
+		require.NoError(t, err)
+		assert.Empty(t, entry.Variant, id)
+	}
+
+	log, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "-c:v libsvtav1")
+	assert.Contains(t, string(log), "-c:a copy")
+
+	// Compacted and failed entries are not re-encoded again
+	dl.compactEntries(7, failed)
+	again, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, log, again)
+}
@@ -1,17 +1,29 @@
 package downloader
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/cookies"
+	"vrcvideocacher/internal/events"
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/internal/platform"
+	"vrcvideocacher/internal/reqid"
 	"vrcvideocacher/pkg/models"
 )
 
@@ -19,8 +31,40 @@ var (
 	ErrDownloadFailed  = errors.New("download failed")
 	ErrAlreadyQueued   = errors.New("video already queued or downloading")
 	ErrDownloaderStopped = errors.New("downloader is stopped")
+	ErrNotFound        = errors.New("video not found")
+	ErrDraining        = errors.New("downloader is draining")
+	ErrInsufficientSpace = errors.New("insufficient disk space")
 )
 
+const (
+	// recentTTL is how long a finished request stays queryable via GetStatus
+	recentTTL = 10 * time.Minute
+
+	// maxRecent bounds the number of finished requests kept in memory
+	maxRecent = 200
+
+	// maxFailed bounds the number of failed requests kept for review
+	maxFailed = 100
+
+	// minFreeSpace is the free space required on the download and cache
+	// volumes before starting a download
+	minFreeSpace = 512 * 1024 * 1024
+)
+
+// rateLimitMarkers are yt-dlp output fragments indicating the account was rate limited
+var rateLimitMarkers = []string{
+	"HTTP Error 429",
+	"Too Many Requests",
+	"Sign in to confirm you",
+	"rate-limited",
+}
+
+// progressPattern matches yt-dlp progress lines such as "[download]  42.3% of 10MiB"
+var progressPattern = regexp.MustCompile(`\[download\]\s+(\d+(?:\.\d+)?)%`)
+
+// aria2cProgressPattern matches aria2c readouts such as "[#2089b0 4.0MiB/33MiB(12%) CN:8 DL:1.2MiB]"
+var aria2cProgressPattern = regexp.MustCompile(`\((\d+)%\) CN:`)
+
 // DownloadStatus represents the status of a download
 type DownloadStatus int
 
@@ -29,6 +73,10 @@ const (
 	StatusDownloading
 	StatusCompleted
 	StatusFailed
+	StatusAwaitingConfirmation
+	StatusUpcoming
+	StatusRestricted
+	StatusCancelled
 )
 
 func (s DownloadStatus) String() string {
@@ -41,6 +89,14 @@ func (s DownloadStatus) String() string {
 		return "completed"
 	case StatusFailed:
 		return "failed"
+	case StatusAwaitingConfirmation:
+		return "awaiting-confirmation"
+	case StatusUpcoming:
+		return "upcoming"
+	case StatusRestricted:
+		return "restricted"
+	case StatusCancelled:
+		return "cancelled"
 	default:
 		return "unknown"
 	}
@@ -48,30 +104,91 @@ func (s DownloadStatus) String() string {
 
 // DownloadRequest represents a download request
 type DownloadRequest struct {
-	VideoID    string
-	VideoURL   string
-	Format     models.DownloadFormat
-	MaxRes     int
-	MaxLength  int
-	QueuedAt   time.Time
-	StartedAt  time.Time
-	FinishedAt time.Time
-	Status     DownloadStatus
-	Error      error
+	VideoID       string
+	VideoURL      string
+	Format        models.DownloadFormat
+	MaxRes        int
+	MaxLength     int
+	QueuedAt      time.Time
+	StartedAt     time.Time
+	FinishedAt    time.Time
+	Status        DownloadStatus
+	Progress      float64
+	Account       string
+	Error         error
+	EstimatedSize int64
+	Confirmed     bool
+	RequestID     string
+	Live          bool
+	ScheduledAt   time.Time
+	// Upgrade replaces the cached file once the download has finished
+	Upgrade       bool
+	// LogFile is the name of the log of the last yt-dlp invocation, in the
+	// directory set with SetLogDir
+	LogFile       string
+
+	// config is the configuration snapshot taken when the request was queued
+	config *models.Config
+	// timer queues an upcoming request once it starts
+	timer *time.Timer
+	// cancel stops an active download, cancelled records that Cancel was
+	// called for it
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// logName identifies a request in logs: its video ID, followed by the ID of
+// the getvideo request that queued it if there is one
+func (r *DownloadRequest) logName() string {
+	if r.RequestID == "" {
+		return r.VideoID
+	}
+	return fmt.Sprintf("%s [%s]", r.VideoID, r.RequestID)
+}
+
+// logAttrs identify a request in structured logs: its video ID and, if it
+// came from a request, the request ID
+func (r *DownloadRequest) logAttrs() []any {
+	if r.RequestID == "" {
+		return []any{"video", r.VideoID}
+	}
+	return []any{"video", r.VideoID, "request", r.RequestID}
 }
 
 // Downloader manages video downloads
 type Downloader struct {
 	mu           sync.RWMutex
+	cfgMu        sync.RWMutex
 	config       *models.Config
 	cache        *cache.Manager
+	cookies      *cookies.Store
 	queue        []*DownloadRequest
 	active       map[string]*DownloadRequest
+	recent       map[string]*DownloadRequest
+	failed       map[string]*DownloadRequest
+	pending      map[string]*DownloadRequest
+	upcoming     map[string]*DownloadRequest
+	restricted   map[string]*DownloadRequest
 	ctx          context.Context
 	cancel       context.CancelFunc
 	workerWg     sync.WaitGroup
 	running      bool
+	draining     bool
+	paused       bool
+	offline      bool
+	networkCheck func() bool
 	maxWorkers   int
+	cmdPath      string
+	cmdArgs      []string
+	aria2cPath   string
+	ffmpegPath   string
+	onConfirm    func(DownloadRequest)
+	onFinish     func(DownloadRequest)
+	pacer        *pacer
+	logDir       string
+	logger       atomic.Pointer[slog.Logger]
+	bus          atomic.Pointer[events.Bus]
+	metrics      *downloadMetrics
 }
 
 // NewDownloader creates a new downloader
@@ -80,13 +197,102 @@ func NewDownloader(config *models.Config, cache *cache.Manager, maxWorkers int)
 		maxWorkers = 2
 	}
 
-	return &Downloader{
-		config:     config,
+	d := &Downloader{
+		config:     config.Clone(),
 		cache:      cache,
+		cookies:    cookies.NewStore(cache.GetCachePath()),
 		queue:      make([]*DownloadRequest, 0),
 		active:     make(map[string]*DownloadRequest),
+		recent:     make(map[string]*DownloadRequest),
+		failed:     make(map[string]*DownloadRequest),
+		pending:    make(map[string]*DownloadRequest),
+		upcoming:   make(map[string]*DownloadRequest),
+		restricted: make(map[string]*DownloadRequest),
 		maxWorkers: maxWorkers,
+		ffmpegPath: "ffmpeg",
+		pacer:      newPacer(),
+		metrics:    newDownloadMetrics(),
 	}
+	d.logger.Store(logger.For("downloader"))
+
+	if err := d.cookies.SetOrder(config.YtdlCookieAccounts); err != nil {
+		d.log().Warn("Ignoring cookie account order", logger.Err(err))
+	}
+	return d
+}
+
+// SetLogger replaces the logger downloads are reported to
+func (d *Downloader) SetLogger(l *slog.Logger) {
+	d.logger.Store(l)
+}
+
+// log returns the logger downloads are reported to
+func (d *Downloader) log() *slog.Logger {
+	return d.logger.Load()
+}
+
+// logFor returns the logger tagged with the video and request of req
+func (d *Downloader) logFor(req *DownloadRequest) *slog.Logger {
+	return d.log().With(req.logAttrs()...)
+}
+
+// SetConfig replaces the configuration used for new downloads
+// Queued and active downloads keep the snapshot taken when they were queued
+func (d *Downloader) SetConfig(config *models.Config) {
+	snapshot := config.Clone()
+
+	d.cfgMu.Lock()
+	d.config = snapshot
+	d.cfgMu.Unlock()
+
+	if err := d.cookies.SetOrder(snapshot.YtdlCookieAccounts); err != nil {
+		d.log().Warn("Ignoring cookie account order", logger.Err(err))
+	}
+}
+
+// snapshot returns the current configuration
+// The returned config is shared and must not be modified
+func (d *Downloader) snapshot() *models.Config {
+	d.cfgMu.RLock()
+	defer d.cfgMu.RUnlock()
+	return d.config
+}
+
+// requestConfig returns the configuration snapshot of a request, falling
+// back to the current configuration for requests not created by Queue
+func (d *Downloader) requestConfig(req *DownloadRequest) *models.Config {
+	if req.config != nil {
+		return req.config
+	}
+	return d.snapshot()
+}
+
+// SetCommand overrides how yt-dlp is invoked, e.g. a Python interpreter
+// with the zipapp as leading argument. An empty path uses config.YtdlPath
+func (d *Downloader) SetCommand(path string, args []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cmdPath = path
+	d.cmdArgs = append([]string{}, args...)
+}
+
+// SetAria2cPath sets the aria2c binary used as external downloader when
+// config.Aria2cEnabled is set. An empty path disables aria2c
+func (d *Downloader) SetAria2cPath(path string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.aria2cPath = path
+}
+
+// CookieStore returns the cookie store used for downloads
+func (d *Downloader) CookieStore() *cookies.Store {
+	return d.cookies
+}
+
+// SetCookieDir keeps cookies in dir instead of the cache directory, which
+// is served over HTTP, moving over those kept there so far
+func (d *Downloader) SetCookieDir(dir string) error {
+	return d.cookies.Relocate(dir)
 }
 
 // Start starts the downloader workers
@@ -106,6 +312,10 @@ func (d *Downloader) Start() error {
 		d.workerWg.Add(1)
 		go d.worker()
 	}
+	d.workerWg.Add(1)
+	go d.previewWorker()
+	d.workerWg.Add(1)
+	go d.compactWorker()
 
 	return nil
 }
@@ -120,6 +330,7 @@ func (d *Downloader) Stop() error {
 
 	d.cancel()
 	d.running = false
+	d.draining = false
 	d.mu.Unlock()
 
 	// Wait for workers to finish
@@ -130,6 +341,28 @@ func (d *Downloader) Stop() error {
 
 // Queue adds a video to the download queue
 func (d *Downloader) Queue(videoID, videoURL string, format models.DownloadFormat) error {
+	return d.QueueWithMaxRes(context.Background(), videoID, videoURL, format, 0)
+}
+
+// QueueWithMaxRes adds a video to the download queue with a resolution
+// limit. A maxRes of 0 uses config.CacheYouTubeMaxRes. Nothing is queued if
+// ctx is already done; the download itself is not bound to ctx
+func (d *Downloader) QueueWithMaxRes(ctx context.Context, videoID, videoURL string, format models.DownloadFormat, maxRes int) error {
+	return d.enqueue(ctx, videoID, videoURL, format, maxRes, false, false)
+}
+
+// enqueue implements QueueWithMaxRes, QueueLive and QueueUpgrade
+// Upgrades replace a cached video, other requests are skipped if it is cached
+func (d *Downloader) enqueue(ctx context.Context, videoID, videoURL string, format models.DownloadFormat, maxRes int, live, upgrade bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cfg := d.snapshot()
+	if maxRes <= 0 {
+		maxRes = cfg.CacheYouTubeMaxRes
+	}
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -137,6 +370,10 @@ func (d *Downloader) Queue(videoID, videoURL string, format models.DownloadForma
 		return ErrDownloaderStopped
 	}
 
+	if d.draining {
+		return ErrDraining
+	}
+
 	// Check if already in queue or downloading
 	if _, ok := d.active[videoID]; ok {
 		return ErrAlreadyQueued
@@ -148,8 +385,23 @@ func (d *Downloader) Queue(videoID, videoURL string, format models.DownloadForma
 		}
 	}
 
+	// Check if waiting for size confirmation
+	if _, ok := d.pending[videoID]; ok {
+		return ErrAlreadyQueued
+	}
+
+	// Check if waiting for a premiere or scheduled stream to start
+	if req, ok := d.upcoming[videoID]; ok {
+		return req.Error
+	}
+
+	// Check if known to be uncacheable
+	if err := d.restriction(videoID); err != nil {
+		return err
+	}
+
 	// Check if already cached
-	if _, err := d.cache.GetEntry(videoID); err == nil {
+	if _, err := d.cache.GetEntry(videoID); err == nil && !upgrade {
 		return nil // Already cached
 	}
 
@@ -158,17 +410,100 @@ func (d *Downloader) Queue(videoID, videoURL string, format models.DownloadForma
 		VideoID:   videoID,
 		VideoURL:  videoURL,
 		Format:    format,
-		MaxRes:    d.config.CacheYouTubeMaxRes,
-		MaxLength: d.config.CacheYouTubeMaxLength,
+		MaxRes:    maxRes,
+		MaxLength: cfg.CacheYouTubeMaxLength,
 		QueuedAt:  time.Now(),
 		Status:    StatusQueued,
+		RequestID: reqid.From(ctx),
+		Live:      live,
+		Upgrade:   upgrade,
+		config:    cfg,
 	}
 
 	d.queue = append(d.queue, req)
+	d.publish(events.DownloadQueued, req)
 
 	return nil
 }
 
+// Drain stops accepting new work and waits for active downloads to finish
+// Queued requests are not started. Returns the requests left unfinished: the
+// remaining queue plus any downloads still active when ctx is done, in which
+// case ctx.Err() is also returned. The downloader stays paused until Resume
+// or Stop is called
+func (d *Downloader) Drain(ctx context.Context) ([]*DownloadRequest, error) {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	var ctxErr error
+	for ctxErr == nil && d.GetActiveDownloads() > 0 {
+		select {
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	unfinished := make([]*DownloadRequest, 0, len(d.active)+len(d.queue))
+	for _, req := range d.active {
+		reqCopy := *req
+		unfinished = append(unfinished, &reqCopy)
+	}
+	for _, req := range d.queue {
+		reqCopy := *req
+		unfinished = append(unfinished, &reqCopy)
+	}
+
+	return unfinished, ctxErr
+}
+
+// Resume accepts new work again after Drain
+func (d *Downloader) Resume() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.draining = false
+}
+
+// ClearQueue removes all queued downloads that have not started and returns
+// how many were removed. Active downloads are not affected
+func (d *Downloader) ClearQueue() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := len(d.queue)
+	d.queue = make([]*DownloadRequest, 0)
+	return n
+}
+
+// SetPaused stops or resumes starting queued downloads. Unlike Drain, new
+// downloads are still queued while paused and active downloads finish
+func (d *Downloader) SetPaused(paused bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paused = paused
+}
+
+// IsPaused returns whether starting queued downloads is paused
+func (d *Downloader) IsPaused() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.paused
+}
+
+// IsDraining returns whether the downloader is draining or paused
+func (d *Downloader) IsDraining() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.draining
+}
+
 // GetStatus returns the status of a video download
 func (d *Downloader) GetStatus(videoID string) (*DownloadRequest, error) {
 	d.mu.RLock()
@@ -188,7 +523,140 @@ func (d *Downloader) GetStatus(videoID string) (*DownloadRequest, error) {
 		}
 	}
 
-	return nil, errors.New("video not found")
+	// Check downloads awaiting confirmation
+	if req, ok := d.pending[videoID]; ok {
+		reqCopy := *req
+		return &reqCopy, nil
+	}
+
+	// Check videos waiting for their start
+	if req, ok := d.upcoming[videoID]; ok {
+		reqCopy := *req
+		return &reqCopy, nil
+	}
+
+	// Check recently finished downloads
+	if req, ok := d.recent[videoID]; ok && time.Since(req.FinishedAt) < recentTTL {
+		reqCopy := *req
+		return &reqCopy, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+// ListDownloads returns copies of all active, queued, held and recently
+// finished requests. Active downloads come first, followed by the queue in
+// order, downloads awaiting confirmation, upcoming videos and then finished
+// requests, most recent first
+func (d *Downloader) ListDownloads() []*DownloadRequest {
+	pending := append(d.ListPending(), d.ListUpcoming()...)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]*DownloadRequest, 0, len(d.active)+len(d.queue)+len(pending)+len(d.recent))
+
+	active := make([]*DownloadRequest, 0, len(d.active))
+	for _, req := range d.active {
+		reqCopy := *req
+		active = append(active, &reqCopy)
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].StartedAt.Before(active[j].StartedAt)
+	})
+	result = append(result, active...)
+
+	for _, req := range d.queue {
+		reqCopy := *req
+		result = append(result, &reqCopy)
+	}
+
+	result = append(result, pending...)
+
+	recent := make([]*DownloadRequest, 0, len(d.recent))
+	for _, req := range d.recent {
+		if time.Since(req.FinishedAt) >= recentTTL {
+			continue
+		}
+		reqCopy := *req
+		recent = append(recent, &reqCopy)
+	}
+	sort.Slice(recent, func(i, j int) bool {
+		return recent[i].FinishedAt.After(recent[j].FinishedAt)
+	})
+	result = append(result, recent...)
+
+	return result
+}
+
+// ListFailed returns copies of failed requests kept for review, most recent first
+func (d *Downloader) ListFailed() []*DownloadRequest {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	failed := make([]*DownloadRequest, 0, len(d.failed))
+	for _, req := range d.failed {
+		reqCopy := *req
+		failed = append(failed, &reqCopy)
+	}
+
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].FinishedAt.After(failed[j].FinishedAt)
+	})
+
+	return failed
+}
+
+// Retry re-queues a failed download using the current configuration
+// The limits and upgrade flag it was queued with are kept
+func (d *Downloader) Retry(videoID string) error {
+	cfg := d.snapshot()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.running {
+		return ErrDownloaderStopped
+	}
+
+	if d.draining {
+		return ErrDraining
+	}
+
+	failedReq, ok := d.failed[videoID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if _, ok := d.active[videoID]; ok {
+		return ErrAlreadyQueued
+	}
+
+	for _, req := range d.queue {
+		if req.VideoID == videoID {
+			return ErrAlreadyQueued
+		}
+	}
+
+	req := &DownloadRequest{
+		VideoID:   failedReq.VideoID,
+		VideoURL:  failedReq.VideoURL,
+		Format:    failedReq.Format,
+		MaxRes:    failedReq.MaxRes,
+		MaxLength: failedReq.MaxLength,
+		QueuedAt:  time.Now(),
+		Status:    StatusQueued,
+		RequestID: failedReq.RequestID,
+		Upgrade:   failedReq.Upgrade,
+		config:    cfg,
+	}
+
+	d.queue = append(d.queue, req)
+	delete(d.failed, videoID)
+	delete(d.recent, videoID)
+	d.publish(events.DownloadQueued, req)
+
+	return nil
 }
 
 // worker processes download requests from the queue
@@ -221,7 +689,7 @@ func (d *Downloader) dequeue() *DownloadRequest {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	if len(d.queue) == 0 {
+	if d.draining || d.paused || d.offline || len(d.queue) == 0 {
 		return nil
 	}
 
@@ -236,83 +704,252 @@ func (d *Downloader) dequeue() *DownloadRequest {
 
 // processDownload processes a download request
 func (d *Downloader) processDownload(req *DownloadRequest) {
-	defer func() {
-		// Remove from active
-		d.mu.Lock()
-		delete(d.active, req.VideoID)
-		d.mu.Unlock()
-	}()
-
 	// Update status
+	d.mu.Lock()
 	req.Status = StatusDownloading
 	req.StartedAt = time.Now()
+	d.publish(events.DownloadStarted, req)
+	d.mu.Unlock()
 
 	// Execute download
 	err := d.executeDownload(req)
-	req.FinishedAt = time.Now()
 
+	// Cancelled downloads are dropped without counting as failures
+	if d.finishCancelled(req) {
+		d.logFor(req).Info("Download cancelled")
+		return
+	}
+
+	// Retry downloads cut off by a lost connection once back online
+	if err != nil && d.requeueOffline(req, err) {
+		return
+	}
+
+	// Premieres and scheduled streams wait for their start instead of failing
+	if errors.Is(err, ErrUpcoming) {
+		d.parkUpcoming(req, err)
+		return
+	}
+
+	// DRM protected and members-only videos are not retried
+	if isRestricted(err) {
+		d.markRestricted(req, err)
+		return
+	}
+
+	// Hold oversized downloads until confirmed
+	if errors.Is(err, ErrConfirmationRequired) {
+		d.mu.Lock()
+		req.FinishedAt = time.Now()
+		req.Status = StatusAwaitingConfirmation
+		req.Error = err
+		delete(d.active, req.VideoID)
+		d.hold(req)
+		notify := d.onConfirm
+		reqCopy := *req
+		d.mu.Unlock()
+
+		d.logFor(req).Info("Download needs confirmation", logger.Err(err))
+		if notify != nil {
+			notify(reqCopy)
+		}
+		return
+	}
+
+	// Move from active to recent
+	d.mu.Lock()
+	req.FinishedAt = time.Now()
 	if err != nil {
 		req.Status = StatusFailed
 		req.Error = err
-		fmt.Printf("Download failed for %s: %v\n", req.VideoID, err)
+		d.addFailed(req)
+		d.metrics.failed.Inc()
+		d.publish(events.DownloadFailed, req)
+	} else {
+		req.Status = StatusCompleted
+		req.Progress = 100
+		delete(d.failed, req.VideoID)
+		d.metrics.completed.Inc()
+		d.publish(events.DownloadCompleted, req)
+	}
+	delete(d.active, req.VideoID)
+	d.addRecent(req)
+	notify := d.onFinish
+	reqCopy := *req
+	d.mu.Unlock()
+
+	if notify != nil {
+		notify(reqCopy)
+	}
+
+	if err != nil {
+		d.logFor(req).Error("Download failed", logger.Err(err))
 		return
 	}
 
-	req.Status = StatusCompleted
-	fmt.Printf("Download completed for %s\n", req.VideoID)
+	d.logFor(req).Info("Download completed")
+}
+
+// SetFinishNotifier sets the callback invoked when a download completes or
+// fails for good
+func (d *Downloader) SetFinishNotifier(notify func(DownloadRequest)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onFinish = notify
+}
+
+// addRecent records a finished request, pruning expired and excess entries
+// Must be called with lock held
+func (d *Downloader) addRecent(req *DownloadRequest) {
+	d.recent[req.VideoID] = req
+
+	// Drop expired entries
+	for id, r := range d.recent {
+		if time.Since(r.FinishedAt) >= recentTTL {
+			delete(d.recent, id)
+		}
+	}
+
+	// Drop oldest entries until we're within the limit
+	for len(d.recent) > maxRecent {
+		var oldestID string
+		var oldest time.Time
+		for id, r := range d.recent {
+			if oldestID == "" || r.FinishedAt.Before(oldest) {
+				oldestID = id
+				oldest = r.FinishedAt
+			}
+		}
+		delete(d.recent, oldestID)
+	}
 }
 
 // executeDownload executes yt-dlp to download the video
 func (d *Downloader) executeDownload(req *DownloadRequest) error {
+	cfg := d.requestConfig(req)
+
+	// Each download has its own context so Cancel can stop it alone
+	ctx, cancel := context.WithCancel(d.ctx)
+	defer cancel()
+	d.mu.Lock()
+	req.cancel = cancel
+	cancelled := req.cancelled
+	d.mu.Unlock()
+	if cancelled {
+		return ErrCancelled
+	}
+
+	// Download into the scratch directory if configured, otherwise the cache
+	cacheDir := d.cache.GetCachePath()
+	downloadDir := cacheDir
+	if cfg.DownloadTempPath != "" {
+		downloadDir = cfg.DownloadTempPath
+	} else if req.Upgrade {
+		// The cached file keeps being served until the new one replaces it
+		downloadDir = filepath.Join(cacheDir, upgradeDir)
+	}
+	if downloadDir != cacheDir {
+		if err := os.MkdirAll(downloadDir, 0755); err != nil {
+			return fmt.Errorf("failed to create download directory: %w", err)
+		}
+	}
+
+	if err := checkFreeSpace(downloadDir, minFreeSpace); err != nil {
+		return err
+	}
+	if downloadDir != cacheDir {
+		if err := checkFreeSpace(cacheDir, minFreeSpace); err != nil {
+			return err
+		}
+	}
+
+	// Check the estimated size against the configured limit
+	if err := d.preflight(ctx, req, cfg); err != nil {
+		return err
+	}
+
 	// Determine output filename
 	ext := req.Format.String()
-	outputTemplate := filepath.Join(d.cache.GetCachePath(), fmt.Sprintf("%s.%s", req.VideoID, ext))
+	outputTemplate := filepath.Join(downloadDir, fmt.Sprintf("%s.%s", req.VideoID, ext))
 
 	// Build yt-dlp command
+	infoPath := videoInfoPath(downloadDir, req)
+	os.Remove(infoPath) // Left over from an interrupted download
 	args := []string{
 		"--no-playlist",
 		"--no-warnings",
 		"--no-check-certificate",
+		"--newline",
 		"-o", outputTemplate,
+		"--print-to-file", videoInfoTemplate, infoPath,
 	}
 
 	// Add format selection
-	// Note: Without ffmpeg, yt-dlp will download video and audio separately
-	// We detect and use the downloaded files in post-processing
-	if req.Format == models.DownloadFormatWebm {
-		// AVPro: prefer webm VP8/VP9
-		args = append(args, "-f", fmt.Sprintf("bestvideo[height<=%d][ext=webm]+bestaudio[ext=webm]/best[height<=%d][ext=webm]/best[height<=%d]", req.MaxRes, req.MaxRes, req.MaxRes))
-	} else {
-		// Non-AVPro: prefer mp4 H264
-		args = append(args, "-f", fmt.Sprintf("bestvideo[height<=%d][ext=mp4]+bestaudio[ext=m4a]/best[height<=%d][ext=mp4]/best[height<=%d]", req.MaxRes, req.MaxRes, req.MaxRes))
+	d.mu.RLock()
+	maxRes := req.MaxRes
+	d.mu.RUnlock()
+	args = append(args, "-f", formatSelector(req.Format, videoFilter(cfg, maxRes)))
+
+	args = append(args, d.aria2cArgs(cfg)...)
+	args = append(args, embedArgs(cfg)...)
+
+	// Live recordings stop at the configured limit
+	if req.Live {
+		args = append(args, liveArgs(cfg)...)
+		if cfg.LiveMaxMinutes > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.LiveMaxMinutes)*time.Minute+liveGrace)
+			defer cancel()
+		}
 	}
 
-	// Add cookies if enabled
-	if d.config.YtdlUseCookies {
-		cookiesPath := filepath.Join(d.cache.GetCachePath(), "youtube_cookies.txt")
-		if _, err := os.Stat(cookiesPath); err == nil {
-			args = append(args, "--cookies", cookiesPath)
+	// Try cookie accounts in fallback order, moving on when one is rate limited
+	accounts := []string{""}
+	if cfg.YtdlUseCookies {
+		if order := d.cookies.Order(); len(order) > 0 {
+			accounts = order
 		}
 	}
 
-	// Add additional args
-	if d.config.YtdlAdditionalArgs != "" {
-		// TODO: Parse additional args properly
-		args = append(args, d.config.YtdlAdditionalArgs)
+	// Space out downloads, slowing down further while YouTube rate limits
+	if err := d.pacer.Wait(ctx, pause(cfg)); err != nil {
+		return err
 	}
+	rateLimited := false
+	defer func() { d.reportPacing(rateLimited) }()
 
-	// Add URL
-	args = append(args, req.VideoURL)
+	for i, account := range accounts {
+		output, err := d.runYtdlp(ctx, req, d.buildArgs(cfg, args, account, req.VideoURL))
+		if err == nil {
+			d.mu.Lock()
+			req.Account = account
+			d.mu.Unlock()
+			break
+		}
 
-	// Execute yt-dlp
-	cmd := exec.CommandContext(d.ctx, d.config.YtdlPath, args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("%w: %s", ErrDownloadFailed, string(output))
+		if d.isCancelled(req) {
+			removePartial(downloadDir, req.VideoID)
+			return ErrCancelled
+		}
+
+		if isUpcoming(output) {
+			return fmt.Errorf("%w: %s", ErrUpcoming, lastLine(output))
+		}
+		if err := restrictionError(output); err != nil {
+			return err
+		}
+		rateLimited = rateLimited || isRateLimited(output)
+		if i == len(accounts)-1 || !isRateLimited(output) {
+			return fmt.Errorf("%w: %s", ErrDownloadFailed, output)
+		}
+
+		d.logFor(req).Warn("Cookie account rate limited, trying next account", "account", account)
 	}
 
-	// List files in cache directory
-	files, _ := os.ReadDir(d.cache.GetCachePath())
+	info := readVideoInfo(infoPath, req.VideoURL)
+
+	// List files in download directory
+	files, _ := os.ReadDir(downloadDir)
 
 	// Find the actual downloaded file
 	// yt-dlp may create files with different names (e.g., VIDEO_ID.f395.mp4 instead of VIDEO_ID.mp4)
@@ -343,13 +980,182 @@ func (d *Downloader) executeDownload(req *DownloadRequest) error {
 		return fmt.Errorf("failed to find downloaded file for %s", req.VideoID)
 	}
 
-	if err := d.cache.AddEntry(req.VideoID, actualFilename); err != nil {
+	if downloadDir != cacheDir {
+		// Move the finished file into the cache
+		srcPath := filepath.Join(downloadDir, actualFilename)
+		if info, err := os.Stat(srcPath); err == nil {
+			if err := checkFreeSpace(cacheDir, uint64(info.Size())); err != nil {
+				os.Remove(srcPath)
+				return err
+			}
+		}
+
+		if err := d.cache.ImportFile(req.VideoID, srcPath); err != nil {
+			return fmt.Errorf("failed to add to cache: %w", err)
+		}
+	} else if err := d.cache.AddEntry(req.VideoID, actualFilename); err != nil {
 		return fmt.Errorf("failed to add to cache: %w", err)
 	}
+	d.cache.SetMaxRes(req.VideoID, req.MaxRes) // Ignore errors, only fails if evicted meanwhile
+	d.cache.SetVideoInfo(req.VideoID, info)     // Ignore errors, as above
+
+	// Normalization is best effort, the download is usable without it
+	if cfg.AudioNormalize {
+		if err := d.normalizeEntry(req.VideoID); err != nil {
+			d.logFor(req).Warn("Audio normalization failed", logger.Err(err))
+		}
+	}
+
+	return nil
+}
+
+// checkFreeSpace fails if the volume containing dir has less than need
+// bytes available. Platforms without free space support are not checked.
+func checkFreeSpace(dir string, need uint64) error {
+	free, err := platform.FreeSpace(dir)
+	if err != nil {
+		return nil
+	}
 
+	if free < need {
+		return fmt.Errorf("%w: %s has %d MB free", ErrInsufficientSpace, dir, free/(1024*1024))
+	}
 	return nil
 }
 
+// buildArgs appends cookies, additional args and the URL to the base yt-dlp arguments
+func (d *Downloader) buildArgs(cfg *models.Config, base []string, account, videoURL string) []string {
+	args := append([]string{}, base...)
+
+	// Add cookies for the account
+	if account != "" {
+		if cookiesPath, err := d.cookies.Path(account); err == nil {
+			args = append(args, "--cookies", cookiesPath)
+		}
+	}
+
+	// Add additional args
+	if cfg.YtdlAdditionalArgs != "" {
+		// TODO: Parse additional args properly
+		args = append(args, cfg.YtdlAdditionalArgs)
+	}
+
+	// Add URL
+	return append(args, videoURL)
+}
+
+// videoFilter returns the yt-dlp format filter limiting resolution, frame
+// rate and codec per the config. Formats with unknown frame rate or codec
+// are not excluded
+func videoFilter(cfg *models.Config, maxRes int) string {
+	filter := fmt.Sprintf("[height<=%d]", maxRes)
+	if cfg.CacheYouTubeMaxFPS > 0 {
+		filter += fmt.Sprintf("[fps<=?%d]", cfg.CacheYouTubeMaxFPS)
+	}
+	if cfg.CacheYouTubeAvoidAV1 {
+		filter += "[vcodec!^=?av01]"
+	}
+	return filter
+}
+
+// formatSelector returns the yt-dlp format selection for a download
+// Note: Without ffmpeg, yt-dlp will download video and audio separately
+// We detect and use the downloaded files in post-processing
+func formatSelector(format models.DownloadFormat, filter string) string {
+	if format == models.DownloadFormatWebm {
+		// AVPro: prefer webm VP8/VP9
+		return fmt.Sprintf("bestvideo%[1]s[ext=webm]+bestaudio[ext=webm]/best%[1]s[ext=webm]/best%[1]s", filter)
+	}
+
+	// Non-AVPro: prefer mp4 H264
+	return fmt.Sprintf("bestvideo%[1]s[ext=mp4]+bestaudio[ext=m4a]/best%[1]s[ext=mp4]/best%[1]s", filter)
+}
+
+// aria2cArgs returns the yt-dlp arguments for downloading through aria2c,
+// or nil if it is disabled. aria2c is only used for plain HTTP(S) formats,
+// where parallel connections help; fragmented DASH/HLS streams already
+// download fragments in parallel and stay on the native downloader.
+func (d *Downloader) aria2cArgs(cfg *models.Config) []string {
+	d.mu.RLock()
+	path := d.aria2cPath
+	d.mu.RUnlock()
+
+	if !cfg.Aria2cEnabled || path == "" {
+		return nil
+	}
+
+	conns := cfg.Aria2cConnections
+	if conns <= 0 {
+		conns = 1
+	}
+
+	return []string{
+		"--downloader", path,
+		"--downloader", "dash,m3u8:native",
+		"--downloader-args", fmt.Sprintf("aria2c:-x %d -s %d -k 1M", conns, conns),
+	}
+}
+
+// embedArgs returns the yt-dlp arguments embedding the title, uploader and
+// chapters into the downloaded file, for players that show them, or nil if
+// disabled. Embedding needs ffmpeg; yt-dlp skips it without
+func embedArgs(cfg *models.Config) []string {
+	if !cfg.EmbedMetadata {
+		return nil
+	}
+	return []string{"--embed-metadata", "--embed-chapters"}
+}
+
+// runYtdlp executes yt-dlp, tracking progress on the request, and returns its output
+// The process is killed when ctx is done
+func (d *Downloader) runYtdlp(ctx context.Context, req *DownloadRequest, args []string) (string, error) {
+	// Progress events are sent once per whole percent
+	published := -1
+	output := &progressWriter{
+		onProgress: func(pct float64) {
+			d.mu.Lock()
+			req.Progress = pct
+			if int(pct) != published {
+				published = int(pct)
+				d.publish(events.DownloadProgress, req)
+			}
+			d.mu.Unlock()
+		},
+	}
+
+	path, args, binary := d.ytdlpCommand(args)
+	logFile := d.openDownloadLog(req, path, args)
+	err := d.runInstalled(ctx, binary, func() error {
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Stdout = output
+		cmd.Stderr = output
+		if logFile != nil {
+			cmd.Stdout = io.MultiWriter(output, logFile)
+			cmd.Stderr = cmd.Stdout
+		}
+		start := time.Now()
+		err := cmd.Run()
+		d.observeYtdlp(ytdlpDownload, start, err)
+		return err
+	})
+	if err != nil && ctx.Err() == nil {
+		err = platform.ExplainExecError(path, err)
+	}
+	closeDownloadLog(logFile, err)
+
+	return output.buf.String(), err
+}
+
+// isRateLimited reports whether yt-dlp output indicates a rate-limited account
+func isRateLimited(output string) bool {
+	for _, marker := range rateLimitMarkers {
+		if strings.Contains(output, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetQueueLength returns the number of queued downloads
 func (d *Downloader) GetQueueLength() int {
 	d.mu.RLock()
@@ -363,3 +1169,41 @@ func (d *Downloader) GetActiveDownloads() int {
 	defer d.mu.RUnlock()
 	return len(d.active)
 }
+
+// addFailed records a failed request for review, dropping the oldest beyond the limit
+// Must be called with lock held
+func (d *Downloader) addFailed(req *DownloadRequest) {
+	d.failed[req.VideoID] = req
+
+	for len(d.failed) > maxFailed {
+		var oldestID string
+		var oldest time.Time
+		for id, r := range d.failed {
+			if oldestID == "" || r.FinishedAt.Before(oldest) {
+				oldestID = id
+				oldest = r.FinishedAt
+			}
+		}
+		delete(d.failed, oldestID)
+	}
+}
+
+// progressWriter collects yt-dlp output and reports download progress as it arrives
+type progressWriter struct {
+	buf        bytes.Buffer
+	onProgress func(float64)
+}
+
+// Write implements io.Writer
+func (w *progressWriter) Write(p []byte) (int, error) {
+	for _, pattern := range []*regexp.Regexp{progressPattern, aria2cProgressPattern} {
+		if matches := pattern.FindAllSubmatch(p, -1); len(matches) > 0 {
+			last := matches[len(matches)-1]
+			if pct, err := strconv.ParseFloat(string(last[1]), 64); err == nil && w.onProgress != nil {
+				w.onProgress(pct)
+			}
+		}
+	}
+
+	return w.buf.Write(p)
+}
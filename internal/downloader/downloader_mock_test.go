@@ -1,9 +1,13 @@
 package downloader
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -256,6 +260,366 @@ func TestProcessDownloadFailure(t *testing.T) {
 	assert.False(t, req.FinishedAt.IsZero())
 }
 
+// TestGetStatusAfterCompletion tests that finished downloads remain queryable
+func TestGetStatusAfterCompletion(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	cfg := &models.Config{
+		YtdlPath:  "echo",
+		CachePath: cacheDir,
+	}
+
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	dl := NewDownloader(cfg, cacheMgr, 1)
+
+	// Create fake file
+	testFile := filepath.Join(cacheDir, "DONE.mp4")
+	err := os.WriteFile(testFile, []byte("video"), 0644)
+	require.NoError(t, err)
+
+	err = dl.Start()
+	require.NoError(t, err)
+	defer dl.Stop()
+
+	dl.processDownload(&DownloadRequest{
+		VideoID:  "DONE",
+		VideoURL: "https://youtube.com/watch?v=DONE",
+		Format:   models.DownloadFormatMP4,
+	})
+
+	status, err := dl.GetStatus("DONE")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, status.Status)
+	assert.Equal(t, 0, dl.GetActiveDownloads())
+}
+
+// TestGetStatusAfterFailure tests that failed downloads keep their error
+func TestGetStatusAfterFailure(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	cfg := &models.Config{
+		YtdlPath:  "nonexistent-command",
+		CachePath: cacheDir,
+	}
+
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	dl := NewDownloader(cfg, cacheMgr, 1)
+
+	err := dl.Start()
+	require.NoError(t, err)
+	defer dl.Stop()
+
+	dl.processDownload(&DownloadRequest{
+		VideoID:  "FAIL",
+		VideoURL: "https://youtube.com/watch?v=FAIL",
+		Format:   models.DownloadFormatMP4,
+	})
+
+	status, err := dl.GetStatus("FAIL")
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, status.Status)
+	assert.Error(t, status.Error)
+}
+
+// TestRecentExpiryAndBound tests TTL expiry and the size bound of finished requests
+func TestRecentExpiryAndBound(t *testing.T) {
+	cacheDir := t.TempDir()
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	dl := NewDownloader(&models.Config{}, cacheMgr, 1)
+
+	// Expired entries are not returned
+	dl.mu.Lock()
+	dl.recent["OLD"] = &DownloadRequest{
+		VideoID:    "OLD",
+		Status:     StatusCompleted,
+		FinishedAt: time.Now().Add(-recentTTL - time.Second),
+	}
+	dl.mu.Unlock()
+
+	_, err := dl.GetStatus("OLD")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Adding beyond the limit drops the oldest entries
+	dl.mu.Lock()
+	base := time.Now().Add(-time.Minute)
+	for i := 0; i < maxRecent+5; i++ {
+		dl.addRecent(&DownloadRequest{
+			VideoID:    fmt.Sprintf("V%d", i),
+			Status:     StatusCompleted,
+			FinishedAt: base.Add(time.Duration(i) * time.Millisecond),
+		})
+	}
+	count := len(dl.recent)
+	_, hasOldest := dl.recent["V0"]
+	dl.mu.Unlock()
+
+	assert.Equal(t, maxRecent, count)
+	assert.False(t, hasOldest)
+}
+
+// TestFailedQuarantineAndRetry tests that failed downloads are kept and can be retried
+func TestFailedQuarantineAndRetry(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	cfg := &models.Config{
+		YtdlPath:           "nonexistent-command",
+		CachePath:          cacheDir,
+		CacheYouTubeMaxRes: 720,
+	}
+
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	dl := NewDownloader(cfg, cacheMgr, 1)
+
+	// Mark running without starting workers so retried requests stay queued
+	dl.mu.Lock()
+	dl.ctx = context.Background()
+	dl.running = true
+	dl.mu.Unlock()
+
+	// Retry of an unknown video fails
+	assert.ErrorIs(t, dl.Retry("FAIL"), ErrNotFound)
+
+	// Queued with a profile's limits rather than the configured ones
+	dl.processDownload(&DownloadRequest{
+		VideoID:   "FAIL",
+		VideoURL:  "https://youtube.com/watch?v=FAIL",
+		Format:    models.DownloadFormatWebm,
+		MaxRes:    480,
+		MaxLength: 600,
+		Upgrade:   true,
+	})
+
+	failed := dl.ListFailed()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "FAIL", failed[0].VideoID)
+	assert.Error(t, failed[0].Error)
+
+	require.NoError(t, dl.Retry("FAIL"))
+	assert.Empty(t, dl.ListFailed())
+	assert.ErrorIs(t, dl.Retry("FAIL"), ErrNotFound)
+
+	status, err := dl.GetStatus("FAIL")
+	require.NoError(t, err)
+	assert.Equal(t, models.DownloadFormatWebm, status.Format)
+	assert.Equal(t, 480, status.MaxRes)
+	assert.Equal(t, 600, status.MaxLength)
+	assert.True(t, status.Upgrade)
+}
+
+// TestExecuteDownloadCookieFallback tests falling back to the next account on rate limits
+func TestExecuteDownloadCookieFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	cacheDir := t.TempDir()
+
+	// Fake yt-dlp: fails with 429 for cookies marked LIMITED, otherwise creates the file
+	script := filepath.Join(t.TempDir(), "fake-ytdlp.sh")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+prev=""
+for a; do
+  if [ "$prev" = "--cookies" ] && grep -q LIMITED "$a"; then
+    echo "ERROR: HTTP Error 429: Too Many Requests"
+    exit 1
+  fi
+  prev="$a"
+done
+touch "`+filepath.Join(cacheDir, "FALLBACK.mp4")+`"
+`), 0755)
+	require.NoError(t, err)
+
+	cfg := &models.Config{
+		YtdlPath:           script,
+		YtdlUseCookies:     true,
+		YtdlCookieAccounts: []string{"main", "burner"},
+		CachePath:          cacheDir,
+	}
+
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	dl := NewDownloader(cfg, cacheMgr, 1)
+	require.NoError(t, dl.CookieStore().Save("main", "LIMITED"))
+	require.NoError(t, dl.CookieStore().Save("burner", "OK"))
+
+	err = dl.Start()
+	require.NoError(t, err)
+	defer dl.Stop()
+
+	req := &DownloadRequest{
+		VideoID:  "FALLBACK",
+		VideoURL: "https://youtube.com/watch?v=FALLBACK",
+		Format:   models.DownloadFormatMP4,
+		MaxRes:   1080,
+	}
+
+	require.NoError(t, dl.executeDownload(req))
+	assert.Equal(t, "burner", req.Account)
+}
+
+// TestExecuteDownloadScratchDir tests downloading into a scratch directory
+func TestExecuteDownloadScratchDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	cacheDir := t.TempDir()
+	scratchDir := filepath.Join(t.TempDir(), "scratch")
+
+	// Fake yt-dlp: writes to the -o path
+	script := filepath.Join(t.TempDir(), "fake-ytdlp.sh")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+prev=""
+for a; do
+  if [ "$prev" = "-o" ]; then
+    echo video > "$a"
+  fi
+  prev="$a"
+done
+`), 0755)
+	require.NoError(t, err)
+
+	cfg := &models.Config{
+		YtdlPath:         script,
+		CachePath:        cacheDir,
+		DownloadTempPath: scratchDir,
+	}
+
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	dl := NewDownloader(cfg, cacheMgr, 1)
+
+	require.NoError(t, dl.Start())
+	defer dl.Stop()
+
+	req := &DownloadRequest{
+		VideoID:  "SCRATCH",
+		VideoURL: "https://youtube.com/watch?v=SCRATCH",
+		Format:   models.DownloadFormatMP4,
+		MaxRes:   1080,
+	}
+
+	require.NoError(t, dl.executeDownload(req))
+
+	path, err := cacheMgr.GetFilePath("SCRATCH")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cacheDir, "SCRATCH.mp4"), path)
+	assert.FileExists(t, path)
+	assert.NoFileExists(t, filepath.Join(scratchDir, "SCRATCH.mp4"))
+}
+
+// TestExecuteDownloadCommandOverride tests invoking yt-dlp through an interpreter
+func TestExecuteDownloadCommandOverride(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	cacheDir := t.TempDir()
+
+	// Not executable on its own, run through /bin/sh like a zipapp through python
+	script := filepath.Join(t.TempDir(), "yt-dlp.pyz")
+	err := os.WriteFile(script, []byte(`touch "`+filepath.Join(cacheDir, "OVERRIDE.mp4")+`"
+`), 0644)
+	require.NoError(t, err)
+
+	cfg := &models.Config{
+		YtdlPath:  filepath.Join(t.TempDir(), "missing"),
+		CachePath: cacheDir,
+	}
+
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	dl := NewDownloader(cfg, cacheMgr, 1)
+	dl.SetCommand("/bin/sh", []string{script})
+
+	require.NoError(t, dl.Start())
+	defer dl.Stop()
+
+	req := &DownloadRequest{
+		VideoID:  "OVERRIDE",
+		VideoURL: "https://youtube.com/watch?v=OVERRIDE",
+		Format:   models.DownloadFormatMP4,
+		MaxRes:   1080,
+	}
+
+	require.NoError(t, dl.executeDownload(req))
+	_, err = cacheMgr.GetEntry("OVERRIDE")
+	assert.NoError(t, err)
+}
+
+// TestIsRateLimited tests detection of rate-limit errors in yt-dlp output
+func TestIsRateLimited(t *testing.T) {
+	assert.True(t, isRateLimited("ERROR: unable to download: HTTP Error 429: Too Many Requests"))
+	assert.True(t, isRateLimited("ERROR: Sign in to confirm you're not a bot"))
+	assert.False(t, isRateLimited("ERROR: Video unavailable"))
+}
+
+// TestProgressWriter tests parsing of yt-dlp progress output
+func TestProgressWriter(t *testing.T) {
+	var got []float64
+	w := &progressWriter{
+		onProgress: func(pct float64) {
+			got = append(got, pct)
+		},
+	}
+
+	w.Write([]byte("[youtube] TEST: Downloading webpage\n"))
+	w.Write([]byte("[download]   5.0% of 10.00MiB at 1.00MiB/s ETA 00:09\n"))
+	w.Write([]byte("[download]  42.3% of 10.00MiB at 1.00MiB/s ETA 00:05\n"))
+
+	assert.Equal(t, []float64{5.0, 42.3}, got)
+	assert.Contains(t, w.buf.String(), "Downloading webpage")
+
+	// aria2c readout when used as external downloader
+	w.Write([]byte("\r[#2089b0 20.0MiB/33.2MiB(60%) CN:8 DL:4.1MiB ETA:3s]"))
+	assert.Equal(t, []float64{5.0, 42.3, 60}, got)
+}
+
+// TestAria2cArgs tests that aria2c is only used when enabled and installed
+func TestAria2cArgs(t *testing.T) {
+	cfg := &models.Config{Aria2cEnabled: true, Aria2cConnections: 4}
+	dl := NewDownloader(cfg, cache.NewManager(t.TempDir(), 0), 1)
+
+	// Not installed
+	assert.Nil(t, dl.aria2cArgs(cfg))
+
+	dl.SetAria2cPath("/usr/bin/aria2c")
+	assert.Equal(t, []string{
+		"--downloader", "/usr/bin/aria2c",
+		"--downloader", "dash,m3u8:native",
+		"--downloader-args", "aria2c:-x 4 -s 4 -k 1M",
+	}, dl.aria2cArgs(cfg))
+
+	// Disabled in config
+	cfg.Aria2cEnabled = false
+	assert.Nil(t, dl.aria2cArgs(cfg))
+}
+
+// TestEmbedArgs tests that metadata is only embedded when enabled
+func TestEmbedArgs(t *testing.T) {
+	cfg := &models.Config{}
+	assert.Nil(t, embedArgs(cfg))
+
+	cfg.EmbedMetadata = true
+	assert.Equal(t, []string{"--embed-metadata", "--embed-chapters"}, embedArgs(cfg))
+}
+
+// TestListDownloads tests listing of active, queued and finished requests
+func TestListDownloads(t *testing.T) {
+	cacheDir := t.TempDir()
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	dl := NewDownloader(&models.Config{}, cacheMgr, 1)
+
+	dl.mu.Lock()
+	dl.active["ACTIVE"] = &DownloadRequest{VideoID: "ACTIVE", Status: StatusDownloading}
+	dl.queue = append(dl.queue, &DownloadRequest{VideoID: "QUEUED", Status: StatusQueued})
+	dl.recent["DONE"] = &DownloadRequest{VideoID: "DONE", Status: StatusCompleted, FinishedAt: time.Now()}
+	dl.mu.Unlock()
+
+	list := dl.ListDownloads()
+	require.Len(t, list, 3)
+	assert.Equal(t, "ACTIVE", list[0].VideoID)
+	assert.Equal(t, "QUEUED", list[1].VideoID)
+	assert.Equal(t, "DONE", list[2].VideoID)
+}
+
 // TestFormatString tests DownloadFormat.String()
 func TestFormatString(t *testing.T) {
 	tests := []struct {
@@ -1,6 +1,7 @@
 package downloader
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -91,6 +92,61 @@ func TestQueueDownload(t *testing.T) {
 	assert.Equal(t, 1, dl.GetQueueLength())
 }
 
+func TestQueueSnapshotsConfig(t *testing.T) {
+	cfg := &models.Config{
+		CacheYouTubeMaxRes:    1080,
+		CacheYouTubeMaxLength: 120,
+		YtdlPath:              "yt-dlp",
+	}
+	cacheMgr := cache.NewManager(t.TempDir(), 0)
+
+	dl := NewDownloader(cfg, cacheMgr, 0)
+	dl.ctx, dl.cancel = context.WithCancel(context.Background())
+	defer dl.cancel()
+	dl.running = true
+
+	require.NoError(t, dl.Queue("SNAP1", "https://youtube.com/watch?v=SNAP1", models.DownloadFormatMP4))
+
+	// Changes to the caller's config and later config updates must not
+	// affect the queued request
+	cfg.CacheYouTubeMaxRes = 480
+	updated := cfg.Clone()
+	updated.CacheYouTubeMaxRes = 720
+	dl.SetConfig(updated)
+
+	req := dl.dequeue()
+	require.NotNil(t, req)
+	assert.Equal(t, 1080, dl.requestConfig(req).CacheYouTubeMaxRes)
+	assert.Equal(t, 720, dl.snapshot().CacheYouTubeMaxRes)
+}
+
+func TestQueueCancelledContext(t *testing.T) {
+	dl := NewDownloader(&models.Config{}, cache.NewManager(t.TempDir(), 0), 1)
+	require.NoError(t, dl.Start())
+	defer dl.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := dl.QueueWithMaxRes(ctx, "GONE", "https://youtube.com/watch?v=GONE", models.DownloadFormatMP4, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, dl.GetQueueLength())
+}
+
+func TestClearQueue(t *testing.T) {
+	dl := NewDownloader(&models.Config{}, cache.NewManager(t.TempDir(), 0), 0)
+	dl.ctx, dl.cancel = context.WithCancel(context.Background())
+	defer dl.cancel()
+	dl.running = true
+
+	require.NoError(t, dl.Queue("CLEAR1", "https://youtube.com/watch?v=CLEAR1", models.DownloadFormatMP4))
+	require.NoError(t, dl.Queue("CLEAR2", "https://youtube.com/watch?v=CLEAR2", models.DownloadFormatMP4))
+
+	assert.Equal(t, 2, dl.ClearQueue())
+	assert.Equal(t, 0, dl.GetQueueLength())
+	assert.Equal(t, 0, dl.ClearQueue())
+}
+
 func TestQueueDownloadWhenStopped(t *testing.T) {
 	cfg := &models.Config{
 		YtdlPath: "yt-dlp",
@@ -193,6 +249,65 @@ func TestGetStatusNotFound(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestDrain(t *testing.T) {
+	cfg := &models.Config{
+		YtdlPath: "yt-dlp",
+	}
+	cacheDir := t.TempDir()
+	cacheMgr := cache.NewManager(cacheDir, 0)
+
+	dl := NewDownloader(cfg, cacheMgr, 1)
+	err := dl.Start()
+	require.NoError(t, err)
+	defer dl.Stop()
+
+	err = dl.Queue("TEST1", "https://youtube.com/watch?v=TEST1", models.DownloadFormatMP4)
+	require.NoError(t, err)
+
+	// Drain before the worker picks up the request
+	unfinished, err := dl.Drain(context.Background())
+	require.NoError(t, err)
+	assert.True(t, dl.IsDraining())
+
+	// Queued request is reported as unfinished and never dequeued
+	require.Len(t, unfinished, 1)
+	assert.Equal(t, "TEST1", unfinished[0].VideoID)
+	assert.Nil(t, dl.dequeue())
+
+	// New work is rejected
+	err = dl.Queue("TEST2", "https://youtube.com/watch?v=TEST2", models.DownloadFormatMP4)
+	assert.ErrorIs(t, err, ErrDraining)
+
+	// Resume accepts work again
+	dl.Resume()
+	assert.False(t, dl.IsDraining())
+	err = dl.Queue("TEST2", "https://youtube.com/watch?v=TEST2", models.DownloadFormatMP4)
+	assert.NoError(t, err)
+}
+
+func TestDrainDeadline(t *testing.T) {
+	cfg := &models.Config{
+		YtdlPath: "yt-dlp",
+	}
+	cacheDir := t.TempDir()
+	cacheMgr := cache.NewManager(cacheDir, 0)
+
+	dl := NewDownloader(cfg, cacheMgr, 1)
+
+	// Simulate a download that never finishes
+	dl.mu.Lock()
+	dl.active["SLOW"] = &DownloadRequest{VideoID: "SLOW", Status: StatusDownloading}
+	dl.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	unfinished, err := dl.Drain(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, unfinished, 1)
+	assert.Equal(t, "SLOW", unfinished[0].VideoID)
+}
+
 func TestDequeue(t *testing.T) {
 	cfg := &models.Config{
 		YtdlPath: "yt-dlp",
@@ -250,6 +365,7 @@ func TestDownloadStatusString(t *testing.T) {
 		{StatusDownloading, "downloading"},
 		{StatusCompleted, "completed"},
 		{StatusFailed, "failed"},
+		{StatusAwaitingConfirmation, "awaiting-confirmation"},
 		{DownloadStatus(999), "unknown"},
 	}
 
@@ -260,6 +376,20 @@ func TestDownloadStatusString(t *testing.T) {
 	}
 }
 
+func TestVideoFilter(t *testing.T) {
+	cfg := &models.Config{}
+	assert.Equal(t, "[height<=1080]", videoFilter(cfg, 1080))
+
+	cfg.CacheYouTubeMaxFPS = 30
+	cfg.CacheYouTubeAvoidAV1 = true
+	filter := videoFilter(cfg, 720)
+	assert.Equal(t, "[height<=720][fps<=?30][vcodec!^=?av01]", filter)
+
+	assert.Equal(t,
+		"bestvideo[height<=720][fps<=?30][vcodec!^=?av01][ext=mp4]+bestaudio[ext=m4a]/best[height<=720][fps<=?30][vcodec!^=?av01][ext=mp4]/best[height<=720][fps<=?30][vcodec!^=?av01]",
+		formatSelector(models.DownloadFormatMP4, filter))
+}
+
 func TestWorkerStopsOnContextCancel(t *testing.T) {
 	cfg := &models.Config{
 		YtdlPath: "yt-dlp",
@@ -0,0 +1,149 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"vrcvideocacher/internal/logger"
+)
+
+// ErrNoDownloadLog is returned when a video has no download log
+var ErrNoDownloadLog = errors.New("no download log")
+
+// LogsDir is the directory download logs are written to in the logs
+// directory
+const LogsDir = "downloads"
+
+const (
+	// logTimeFormat is the timestamp in download log file names
+	logTimeFormat = "20060102-150405.000"
+
+	// maxDownloadLogs is the number of download logs kept, newest first
+	maxDownloadLogs = 200
+
+	// maxDownloadLogAge is how long download logs are kept
+	maxDownloadLogAge = 7 * 24 * time.Hour
+)
+
+// SetLogDir sets the directory each yt-dlp invocation's output is written
+// to, as <id>-<timestamp>.log. An empty dir disables download logs
+func (d *Downloader) SetLogDir(dir string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.logDir = dir
+}
+
+// openDownloadLog creates the log of a yt-dlp invocation for req, starting
+// with its command line, and records it on req. It returns nil if download
+// logs are disabled or the file cannot be created
+func (d *Downloader) openDownloadLog(req *DownloadRequest, path string, args []string) *os.File {
+	d.mu.RLock()
+	dir := d.logDir
+	d.mu.RUnlock()
+	if dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		d.log().Error("Failed to create download log directory", logger.Err(err))
+		return nil
+	}
+	now := time.Now()
+	name := req.VideoID + "-" + now.Format(logTimeFormat) + ".log"
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		d.logFor(req).Error("Failed to create download log", logger.Err(err))
+		return nil
+	}
+	fmt.Fprintf(f, "# %s %s\n# %s %s\n\n", now.Format(time.RFC3339), req.logName(), path, strings.Join(args, " "))
+
+	d.mu.Lock()
+	req.LogFile = name
+	d.mu.Unlock()
+
+	pruneDownloadLogs(dir, now)
+	return f
+}
+
+// closeDownloadLog ends a download log with the exit status of yt-dlp
+func closeDownloadLog(f *os.File, runErr error) {
+	if f == nil {
+		return
+	}
+	if runErr != nil {
+		fmt.Fprintf(f, "\n# exit: %v\n", runErr)
+	} else {
+		fmt.Fprintf(f, "\n# exit: ok\n")
+	}
+	f.Close()
+}
+
+// downloadLog is a download log file and the time it was started
+type downloadLog struct {
+	name    string
+	id      string
+	started time.Time
+}
+
+// listDownloadLogs returns the download logs in dir, newest first
+func listDownloadLogs(dir string) []downloadLog {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var logs []downloadLog
+	for _, f := range files {
+		base, ok := strings.CutSuffix(f.Name(), ".log")
+		if !ok || f.IsDir() || len(base) <= len(logTimeFormat)+1 {
+			continue
+		}
+		stamp := base[len(base)-len(logTimeFormat):]
+		started, err := time.ParseInLocation(logTimeFormat, stamp, time.Local)
+		if err != nil || base[len(base)-len(logTimeFormat)-1] != '-' {
+			continue
+		}
+		logs = append(logs, downloadLog{
+			name:    f.Name(),
+			id:      base[:len(base)-len(logTimeFormat)-1],
+			started: started,
+		})
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].started.After(logs[j].started)
+	})
+	return logs
+}
+
+// pruneDownloadLogs deletes the logs in dir older than maxDownloadLogAge or
+// beyond the newest maxDownloadLogs
+func pruneDownloadLogs(dir string, now time.Time) {
+	for i, log := range listDownloadLogs(dir) {
+		if i >= maxDownloadLogs || now.Sub(log.started) > maxDownloadLogAge {
+			os.Remove(filepath.Join(dir, log.name))
+		}
+	}
+}
+
+// DownloadLog returns the path of the newest download log of video id
+func (d *Downloader) DownloadLog(id string) (string, error) {
+	d.mu.RLock()
+	dir := d.logDir
+	d.mu.RUnlock()
+	if dir == "" {
+		return "", ErrNoDownloadLog
+	}
+
+	for _, log := range listDownloadLogs(dir) {
+		if log.id == id {
+			return filepath.Join(dir, log.name), nil
+		}
+	}
+	return "", ErrNoDownloadLog
+}
@@ -0,0 +1,79 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestDownloadLog(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	dl := NewDownloader(&models.Config{YtdlPath: "yt-dlp"}, cache.NewManager(t.TempDir(), 0), 1)
+	req := &DownloadRequest{VideoID: "VID-1"}
+
+	// Disabled without a directory
+	dl.SetCommand("/bin/sh", []string{"-c", "echo downloading; echo broken >&2; exit 3"})
+	_, err := dl.runYtdlp(context.Background(), req, nil)
+	require.Error(t, err)
+	assert.Empty(t, req.LogFile)
+	_, err = dl.DownloadLog("VID-1")
+	assert.ErrorIs(t, err, ErrNoDownloadLog)
+
+	dir := filepath.Join(t.TempDir(), "logs")
+	dl.SetLogDir(dir)
+	output, err := dl.runYtdlp(context.Background(), req, nil)
+	require.Error(t, err)
+	assert.Contains(t, output, "downloading")
+	require.NotEmpty(t, req.LogFile)
+
+	path, err := dl.DownloadLog("VID-1")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, req.LogFile), path)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "/bin/sh -c")
+	assert.Contains(t, string(data), "downloading\nbroken\n")
+	assert.Contains(t, string(data), "# exit: exit status 3")
+
+	// Logs of other videos sharing a prefix are not mixed up
+	_, err = dl.DownloadLog("VID")
+	assert.ErrorIs(t, err, ErrNoDownloadLog)
+}
+
+func TestPruneDownloadLogs(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	logName := func(id string, at time.Time) string {
+		return id + "-" + at.Format(logTimeFormat) + ".log"
+	}
+	for i := range maxDownloadLogs + 5 {
+		name := logName(fmt.Sprintf("ID%d", i), now.Add(-time.Duration(i)*time.Minute))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	stale := logName("STALE", now.Add(-maxDownloadLogAge-time.Hour))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, stale), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644))
+
+	pruneDownloadLogs(dir, now)
+
+	logs := listDownloadLogs(dir)
+	require.Len(t, logs, maxDownloadLogs)
+	assert.Equal(t, "ID0", logs[0].id)
+	assert.NoFileExists(t, filepath.Join(dir, stale))
+	assert.NoFileExists(t, filepath.Join(dir, logName("ID200", now.Add(-200*time.Minute))))
+	assert.FileExists(t, filepath.Join(dir, "notes.txt"))
+}
@@ -0,0 +1,23 @@
+package downloader
+
+import "vrcvideocacher/internal/events"
+
+// SetEventBus sets the bus download events are published to
+func (d *Downloader) SetEventBus(bus *events.Bus) {
+	d.bus.Store(bus)
+}
+
+// publish publishes an event of type typ about req
+// Must be called with lock held
+func (d *Downloader) publish(typ string, req *DownloadRequest) {
+	data := events.Download{
+		VideoID:   req.VideoID,
+		VideoURL:  req.VideoURL,
+		RequestID: req.RequestID,
+		Progress:  req.Progress,
+	}
+	if req.Error != nil {
+		data.Error = req.Error.Error()
+	}
+	d.bus.Load().Publish(typ, data)
+}
@@ -0,0 +1,167 @@
+package downloader
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/events"
+	"vrcvideocacher/internal/fakeytdlp"
+	"vrcvideocacher/pkg/models"
+)
+
+// newFakeDownloader starts a downloader running the fake yt-dlp
+func newFakeDownloader(t *testing.T) (*Downloader, *cache.Manager) {
+	cacheMgr := cache.NewManager(t.TempDir(), 0)
+	dl := NewDownloader(&models.Config{YtdlPath: fakeytdlp.Build(t)}, cacheMgr, 1)
+	require.NoError(t, dl.Start())
+	t.Cleanup(func() { dl.Stop() })
+	return dl, cacheMgr
+}
+
+// waitFinished waits for a request to complete or fail
+func waitFinished(t *testing.T, dl *Downloader, videoID string) *DownloadRequest {
+	var status *DownloadRequest
+	require.Eventually(t, func() bool {
+		s, err := dl.GetStatus(videoID)
+		if err != nil {
+			return false
+		}
+		status = s
+		return s.Status == StatusCompleted || s.Status == StatusFailed || s.Status == StatusRestricted
+	}, 10*time.Second, 10*time.Millisecond)
+	return status
+}
+
+func TestFakeYtdlpDownload(t *testing.T) {
+	dl, cacheMgr := newFakeDownloader(t)
+	calls := fakeytdlp.Log(t)
+	t.Setenv(fakeytdlp.EnvDelay, "50ms")
+
+	require.NoError(t, dl.Queue("abc123", "https://www.youtube.com/watch?v=abc123", models.DownloadFormatMP4))
+
+	// Progress is reported while downloading
+	require.Eventually(t, func() bool {
+		s, err := dl.GetStatus("abc123")
+		return err == nil && s.Status == StatusDownloading && s.Progress > 0
+	}, 10*time.Second, 5*time.Millisecond)
+
+	status := waitFinished(t, dl, "abc123")
+	assert.Equal(t, StatusCompleted, status.Status)
+	assert.Equal(t, float64(100), status.Progress)
+
+	entry, err := cacheMgr.GetEntry("abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123.mp4", entry.FileName)
+
+	// The index records what yt-dlp printed about the video
+	assert.Equal(t, "https://www.youtube.com/watch?v=abc123", entry.URL)
+	assert.Equal(t, "Fake video abc123", entry.Title)
+	assert.Equal(t, 360, entry.Resolution)
+	assert.NoFileExists(t, videoInfoPath(cacheMgr.GetCachePath(), status))
+
+	require.NotEmpty(t, calls())
+	assert.Contains(t, calls()[len(calls())-1], "-o ")
+}
+
+func TestFakeYtdlpEvents(t *testing.T) {
+	dl, _ := newFakeDownloader(t)
+	bus := events.NewBus()
+	dl.SetEventBus(bus)
+	ch, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	require.NoError(t, dl.Queue("abc123", "https://www.youtube.com/watch?v=abc123", models.DownloadFormatMP4))
+
+	var types []string
+	timeout := time.After(10 * time.Second)
+	for len(types) == 0 || types[len(types)-1] != events.DownloadCompleted {
+		select {
+		case event := <-ch:
+			types = append(types, event.Type)
+			assert.Equal(t, "abc123", event.Data.(events.Download).VideoID)
+		case <-timeout:
+			t.Fatalf("no completion event, got %v", types)
+		}
+	}
+	assert.Equal(t, []string{events.DownloadQueued, events.DownloadStarted}, types[:2])
+	assert.Contains(t, types, events.DownloadProgress)
+}
+
+func TestFakeYtdlpFailures(t *testing.T) {
+	dl, cacheMgr := newFakeDownloader(t)
+
+	tests := []struct {
+		id     string
+		status DownloadStatus
+		err    error
+	}{
+		{"failVideo", StatusFailed, ErrDownloadFailed},
+		{"drmVideo", StatusRestricted, ErrDRMProtected},
+		{"membersVideo", StatusRestricted, ErrMembersOnly},
+		// Last, as later downloads would wait out the slow-down
+		{"ratelimitVideo", StatusFailed, ErrDownloadFailed},
+	}
+
+	for _, tt := range tests {
+		require.NoError(t, dl.Queue(tt.id, "https://www.youtube.com/watch?v="+tt.id, models.DownloadFormatMP4), tt.id)
+		status := waitFinished(t, dl, tt.id)
+		assert.Equal(t, tt.status, status.Status, tt.id)
+		assert.True(t, errors.Is(status.Error, tt.err), tt.id)
+
+		_, err := cacheMgr.GetEntry(tt.id)
+		assert.Error(t, err, tt.id)
+	}
+
+	assert.Len(t, dl.ListFailed(), 2)
+	assert.Equal(t, minBackoff, dl.pacer.Backoff())
+}
+
+func TestFakeYtdlpNetworkLoss(t *testing.T) {
+	dl, _ := newFakeDownloader(t)
+	dl.SetNetworkCheck(func() bool { return false })
+
+	require.NoError(t, dl.Queue("networkVideo", "https://www.youtube.com/watch?v=networkVideo", models.DownloadFormatMP4))
+
+	// The download goes back to the queue until the connection returns
+	require.Eventually(t, dl.IsOffline, 10*time.Second, 10*time.Millisecond)
+	status, err := dl.GetStatus("networkVideo")
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, status.Status)
+}
+
+func TestFakeYtdlpCancel(t *testing.T) {
+	dl, cacheMgr := newFakeDownloader(t)
+	t.Setenv(fakeytdlp.EnvDelay, "2s")
+
+	require.NoError(t, dl.Queue("slowVideo", "https://www.youtube.com/watch?v=slowVideo", models.DownloadFormatMP4))
+	require.NoError(t, dl.Queue("abc123", "https://www.youtube.com/watch?v=abc123", models.DownloadFormatMP4))
+	require.Eventually(t, func() bool {
+		s, err := dl.GetStatus("slowVideo")
+		return err == nil && s.Status == StatusDownloading
+	}, 10*time.Second, 5*time.Millisecond)
+
+	// A queued download is removed before it starts
+	require.NoError(t, dl.Cancel("abc123"))
+	assert.Equal(t, 0, dl.GetQueueLength())
+	status, err := dl.GetStatus("abc123")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, status.Status)
+
+	// An active download has its yt-dlp process killed
+	require.NoError(t, dl.Cancel("slowVideo"))
+	require.Eventually(t, func() bool {
+		s, err := dl.GetStatus("slowVideo")
+		return err == nil && s.Status == StatusCancelled
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, dl.GetActiveDownloads())
+	assert.Empty(t, dl.ListFailed())
+	_, err = cacheMgr.GetEntry("slowVideo")
+	assert.Error(t, err)
+
+	assert.ErrorIs(t, dl.Cancel("missing"), ErrNotFound)
+}
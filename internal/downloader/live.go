@@ -0,0 +1,54 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"vrcvideocacher/pkg/models"
+)
+
+// liveGrace is how long a live recording may overrun its limit, e.g. while
+// yt-dlp finishes the file, before yt-dlp is killed
+const liveGrace = 5 * time.Minute
+
+// ResolveLive reports whether videoURL is an active live stream and returns
+// its direct stream URL for immediate playback, using yt-dlp metadata
+func (d *Downloader) ResolveLive(ctx context.Context, videoURL string) (string, bool, error) {
+	info, err := d.probeInfo(ctx, d.snapshot(), videoURL, "-f", "best")
+	if err != nil {
+		return "", false, err
+	}
+	if !info.IsLive {
+		return "", false, nil
+	}
+
+	streamURL := info.URL
+	if streamURL == "" {
+		streamURL = info.ManifestURL
+	}
+	if streamURL == "" {
+		return "", false, fmt.Errorf("no stream URL for live stream %s", videoURL)
+	}
+	return streamURL, true, nil
+}
+
+// QueueLive adds an active live stream to the download queue to be recorded
+// into the cache, for at most config.LiveMaxMinutes
+func (d *Downloader) QueueLive(ctx context.Context, videoID, videoURL string, format models.DownloadFormat) error {
+	return d.enqueue(ctx, videoID, videoURL, format, 0, true, false)
+}
+
+// liveArgs returns the yt-dlp arguments of a live recording: optionally
+// from the start of the stream, and limited to config.LiveMaxMinutes
+func liveArgs(cfg *models.Config) []string {
+	var args []string
+	if cfg.LiveFromStart {
+		args = append(args, "--live-from-start")
+	}
+	if cfg.LiveMaxMinutes > 0 {
+		args = append(args, "--download-sections", "*0-"+strconv.Itoa(cfg.LiveMaxMinutes*60))
+	}
+	return args
+}
@@ -0,0 +1,94 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestLiveArgs(t *testing.T) {
+	assert.Nil(t, liveArgs(&models.Config{}))
+	assert.Equal(t, []string{"--download-sections", "*0-3600"}, liveArgs(&models.Config{LiveMaxMinutes: 60}))
+	assert.Equal(t, []string{"--live-from-start", "--download-sections", "*0-7200"},
+		liveArgs(&models.Config{LiveFromStart: true, LiveMaxMinutes: 120}))
+}
+
+func TestResolveLive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	// Fake yt-dlp: LIVE is a live stream, anything else a video
+	script := filepath.Join(t.TempDir(), "fake-ytdlp.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+for last; do :; done
+case "$last" in
+*LIVE) echo '{"is_live": true, "url": "https://manifest.googlevideo.com/live.m3u8"}' ;;
+*) echo '{"is_live": false, "url": "https://rr1.googlevideo.com/video"}' ;;
+esac
+`), 0755))
+
+	dl := NewDownloader(&models.Config{YtdlPath: script}, cache.NewManager(t.TempDir(), 0), 1)
+
+	streamURL, live, err := dl.ResolveLive(context.Background(), "https://youtube.com/watch?v=LIVE")
+	require.NoError(t, err)
+	assert.True(t, live)
+	assert.Equal(t, "https://manifest.googlevideo.com/live.m3u8", streamURL)
+
+	_, live, err = dl.ResolveLive(context.Background(), "https://youtube.com/watch?v=VOD")
+	require.NoError(t, err)
+	assert.False(t, live)
+}
+
+func TestExecuteDownloadLive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	cacheDir := t.TempDir()
+	argsFile := filepath.Join(t.TempDir(), "args")
+
+	// Fake yt-dlp: records its arguments and writes to the -o path
+	script := filepath.Join(t.TempDir(), "fake-ytdlp.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "$@" > `+argsFile+`
+prev=""
+for a; do
+  if [ "$prev" = "-o" ]; then
+    echo video > "$a"
+  fi
+  prev="$a"
+done
+`), 0755))
+
+	cfg := &models.Config{YtdlPath: script, CachePath: cacheDir, LiveFromStart: true, LiveMaxMinutes: 30}
+	dl := NewDownloader(cfg, cache.NewManager(cacheDir, 0), 1)
+
+	require.NoError(t, dl.Start())
+	defer dl.Stop()
+
+	// Recordings are queued and run like downloads, flagged as live
+	require.NoError(t, dl.QueueLive(context.Background(), "LIVE", "https://youtube.com/watch?v=LIVE", models.DownloadFormatMP4))
+	require.Eventually(t, func() bool {
+		req, err := dl.GetStatus("LIVE")
+		return err == nil && req.Status == StatusCompleted
+	}, 5*time.Second, 50*time.Millisecond)
+
+	req, err := dl.GetStatus("LIVE")
+	require.NoError(t, err)
+	assert.True(t, req.Live)
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "--live-from-start --download-sections *0-1800")
+	assert.FileExists(t, filepath.Join(cacheDir, "LIVE.mp4"))
+}
@@ -0,0 +1,104 @@
+package downloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNormalizeFailed is returned when ffmpeg fails to normalize a file
+var ErrNormalizeFailed = errors.New("audio normalization failed")
+
+// loudnormTarget is the EBU R128 target: integrated loudness, true peak and
+// loudness range
+const loudnormTarget = "I=-16:TP=-1.5:LRA=11"
+
+// loudnormStats are the measurements printed by a first loudnorm pass
+type loudnormStats struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+// normalizeEntry normalizes the audio loudness of a cached video with a
+// two-pass ffmpeg loudnorm, copying the video stream. Entries already
+// normalized are left alone
+func (d *Downloader) normalizeEntry(id string) error {
+	entry, err := d.cache.GetEntry(id)
+	if err != nil {
+		return err
+	}
+	if entry.Normalized {
+		return nil
+	}
+
+	filePath, err := d.cache.GetFilePath(id)
+	if err != nil {
+		return err
+	}
+
+	// First pass: measure
+	output, err := d.runFFmpeg("-hide_banner", "-nostats", "-i", filePath,
+		"-af", "loudnorm="+loudnormTarget+":print_format=json", "-f", "null", "-")
+	if err != nil {
+		return err
+	}
+	stats, err := parseLoudnorm(output)
+	if err != nil {
+		return err
+	}
+
+	// Second pass: apply the measured correction
+	filter := fmt.Sprintf("loudnorm=%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+		loudnormTarget, stats.InputI, stats.InputTP, stats.InputLRA, stats.InputThresh, stats.TargetOffset)
+
+	container, codec := "mp4", "aac"
+	if strings.EqualFold(filepath.Ext(filePath), ".webm") {
+		container, codec = "webm", "libopus"
+	}
+
+	// Not a video extension, so a leftover is never picked up by a scan
+	tmpPath := filePath + ".normalizing"
+	defer os.Remove(tmpPath) // No-op once replaced
+
+	if _, err := d.runFFmpeg("-hide_banner", "-nostats", "-y", "-i", filePath,
+		"-af", filter, "-c:v", "copy", "-c:a", codec, "-f", container, tmpPath); err != nil {
+		return err
+	}
+
+	return d.cache.ReplaceNormalized(id, tmpPath)
+}
+
+// runFFmpeg runs ffmpeg and returns its output
+func (d *Downloader) runFFmpeg(args ...string) (string, error) {
+	out, err := exec.CommandContext(d.ctx, d.ffmpegPath, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v: %s", ErrNormalizeFailed, err, lastLine(string(out)))
+	}
+	return string(out), nil
+}
+
+// parseLoudnorm extracts the JSON measurements ffmpeg prints at the end of
+// a loudnorm pass
+func parseLoudnorm(output string) (loudnormStats, error) {
+	var stats loudnormStats
+
+	start := strings.LastIndex(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return stats, fmt.Errorf("%w: no loudnorm measurements", ErrNormalizeFailed)
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &stats); err != nil {
+		return stats, fmt.Errorf("%w: invalid loudnorm measurements: %v", ErrNormalizeFailed, err)
+	}
+	if stats.InputI == "" || stats.InputTP == "" || stats.InputLRA == "" || stats.InputThresh == "" || stats.TargetOffset == "" {
+		return stats, fmt.Errorf("%w: incomplete loudnorm measurements", ErrNormalizeFailed)
+	}
+	return stats, nil
+}
@@ -0,0 +1,109 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+// loudnormOutput is the end of a first loudnorm pass as printed by ffmpeg
+const loudnormOutput = `Stream mapping:
+  Stream #0:1 -> #0:0 (aac (native) -> pcm_s16le (native))
+[Parsed_loudnorm_0 @ 0x7f8b8c004a80]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-16.58",
+	"output_tp" : "-1.50",
+	"output_lra" : "14.78",
+	"output_thresh" : "-27.71",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.58"
+}
+`
+
+func TestParseLoudnorm(t *testing.T) {
+	stats, err := parseLoudnorm(loudnormOutput)
+	require.NoError(t, err)
+	assert.Equal(t, loudnormStats{
+		InputI:       "-27.61",
+		InputTP:      "-4.47",
+		InputLRA:     "18.06",
+		InputThresh:  "-39.20",
+		TargetOffset: "0.58",
+	}, stats)
+
+	_, err = parseLoudnorm("Output file is empty, nothing was encoded")
+	assert.ErrorIs(t, err, ErrNormalizeFailed)
+
+	_, err = parseLoudnorm(`{"input_i" : "-27.61"}`)
+	assert.ErrorIs(t, err, ErrNormalizeFailed)
+}
+
+func TestNormalizeEntry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	cacheDir := t.TempDir()
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "TEST1.webm"), []byte("quiet"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("TEST1", "TEST1.webm"))
+
+	// Fake ffmpeg: measures on the null muxer, otherwise writes the last
+	// argument, logging each call
+	calls := filepath.Join(t.TempDir(), "calls")
+	script := filepath.Join(t.TempDir(), "fake-ffmpeg.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "$@" >> `+calls+`
+for last; do :; done
+if [ "$last" = "-" ]; then
+	cat >&2 <<'JSON'
+`+loudnormOutput+`JSON
+	exit 0
+fi
+printf normalized > "$last"
+`), 0755))
+
+	dl := NewDownloader(&models.Config{YtdlPath: "yt-dlp"}, cacheMgr, 1)
+	dl.ffmpegPath = script
+	require.NoError(t, dl.Start())
+	defer dl.Stop()
+
+	require.NoError(t, dl.normalizeEntry("TEST1"))
+
+	data, err := os.ReadFile(filepath.Join(cacheDir, "TEST1.webm"))
+	require.NoError(t, err)
+	assert.Equal(t, "normalized", string(data))
+	entry, err := cacheMgr.GetEntry("TEST1")
+	require.NoError(t, err)
+	assert.True(t, entry.Normalized)
+
+	log, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "measured_I=-27.61:measured_TP=-4.47:measured_LRA=18.06:measured_thresh=-39.20:offset=0.58")
+	assert.Contains(t, string(log), "-c:a libopus -f webm")
+
+	// Normalized entries are not processed again
+	require.NoError(t, dl.normalizeEntry("TEST1"))
+	again, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, log, again)
+
+	// A failing ffmpeg leaves the file alone
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "TEST2.mp4"), []byte("quiet"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("TEST2", "TEST2.mp4"))
+	dl.ffmpegPath = filepath.Join(t.TempDir(), "missing-ffmpeg")
+	assert.ErrorIs(t, dl.normalizeEntry("TEST2"), ErrNormalizeFailed)
+	data, _ = os.ReadFile(filepath.Join(cacheDir, "TEST2.mp4"))
+	assert.Equal(t, "quiet", string(data))
+}
@@ -0,0 +1,65 @@
+package downloader
+
+import (
+	"time"
+
+	"vrcvideocacher/internal/metrics"
+)
+
+// Kinds of yt-dlp runs
+const (
+	ytdlpDownload = "download"
+	ytdlpProbe    = "probe"
+)
+
+// Metrics are the downloader's totals since it was created
+type Metrics struct {
+	Completed uint64
+	Failed    uint64
+	// Ytdlp are the durations in seconds of yt-dlp runs by kind, "download"
+	// or "probe"
+	Ytdlp map[string]metrics.HistogramSnapshot
+	// YtdlpErrors are the yt-dlp runs that failed by kind
+	YtdlpErrors map[string]uint64
+}
+
+// downloadMetrics counts finished downloads and yt-dlp runs
+type downloadMetrics struct {
+	completed   metrics.Counter
+	failed      metrics.Counter
+	ytdlp       map[string]*metrics.Histogram
+	ytdlpErrors metrics.CounterVec
+}
+
+// newDownloadMetrics creates the metrics of a downloader
+func newDownloadMetrics() *downloadMetrics {
+	return &downloadMetrics{
+		ytdlp: map[string]*metrics.Histogram{
+			ytdlpDownload: metrics.NewHistogram(metrics.DurationBuckets),
+			ytdlpProbe:    metrics.NewHistogram(metrics.DurationBuckets),
+		},
+	}
+}
+
+// Metrics returns the downloader's totals since it was created
+func (d *Downloader) Metrics() Metrics {
+	m := Metrics{
+		Completed:   d.metrics.completed.Value(),
+		Failed:      d.metrics.failed.Value(),
+		Ytdlp:       make(map[string]metrics.HistogramSnapshot, len(d.metrics.ytdlp)),
+		YtdlpErrors: make(map[string]uint64, len(d.metrics.ytdlp)),
+	}
+	for kind, h := range d.metrics.ytdlp {
+		m.Ytdlp[kind] = h.Snapshot()
+		m.YtdlpErrors[kind] = d.metrics.ytdlpErrors.With(kind).Value()
+	}
+	return m
+}
+
+// observeYtdlp records a yt-dlp run of kind that started at start
+func (d *Downloader) observeYtdlp(kind string, start time.Time, err error) {
+	d.metrics.ytdlp[kind].Observe(time.Since(start).Seconds())
+	if err != nil {
+		d.metrics.ytdlpErrors.With(kind).Inc()
+	}
+}
@@ -0,0 +1,35 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestMetrics(t *testing.T) {
+	tmpDir := t.TempDir()
+	dl := NewDownloader(&models.Config{YtdlPath: "true"}, cache.NewManager(tmpDir, 0), 1)
+
+	m := dl.Metrics()
+	assert.Zero(t, m.Completed)
+	assert.Zero(t, m.Failed)
+	require.Contains(t, m.Ytdlp, ytdlpDownload)
+	assert.Zero(t, m.Ytdlp[ytdlpProbe].Count)
+
+	_, err := dl.probeYtdlp(context.Background(), nil)
+	require.NoError(t, err)
+	dl.SetCommand("false", nil)
+	_, err = dl.probeYtdlp(context.Background(), nil)
+	require.Error(t, err)
+
+	m = dl.Metrics()
+	assert.Equal(t, uint64(2), m.Ytdlp[ytdlpProbe].Count)
+	assert.Equal(t, uint64(1), m.YtdlpErrors[ytdlpProbe])
+	assert.Zero(t, m.Ytdlp[ytdlpDownload].Count)
+	assert.Zero(t, m.YtdlpErrors[ytdlpDownload])
+}
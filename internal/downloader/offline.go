@@ -0,0 +1,83 @@
+package downloader
+
+import (
+	"strings"
+	"time"
+)
+
+// networkErrorMarkers are yt-dlp output fragments indicating the connection
+// failed rather than the video
+var networkErrorMarkers = []string{
+	"getaddrinfo failed",
+	"Temporary failure in name resolution",
+	"Name or service not known",
+	"nodename nor servname provided",
+	"Network is unreachable",
+	"No route to host",
+	"Connection refused",
+	"Connection reset by peer",
+	"timed out",
+}
+
+// SetOffline pauses starting downloads while there is no internet
+// connection. Requests queued meanwhile wait and start once back online
+func (d *Downloader) SetOffline(offline bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.offline = offline
+}
+
+// IsOffline returns whether downloads are paused for lack of connectivity
+func (d *Downloader) IsOffline() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.offline
+}
+
+// SetNetworkCheck sets the connectivity check run when a download fails
+// with a network error. It returns whether the internet is reachable
+func (d *Downloader) SetNetworkCheck(check func() bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.networkCheck = check
+}
+
+// requeueOffline puts a request that failed because the connection dropped
+// back at the front of the queue and pauses downloads, so it is retried when
+// connectivity returns. Returns false if the error is not a network error or
+// the internet is still reachable
+func (d *Downloader) requeueOffline(req *DownloadRequest, err error) bool {
+	if !isNetworkError(err.Error()) {
+		return false
+	}
+
+	d.mu.RLock()
+	check := d.networkCheck
+	d.mu.RUnlock()
+	if check == nil || check() {
+		return false
+	}
+
+	d.mu.Lock()
+	req.Status = StatusQueued
+	req.Progress = 0
+	req.StartedAt = time.Time{}
+	req.Error = err
+	delete(d.active, req.VideoID)
+	d.queue = append([]*DownloadRequest{req}, d.queue...)
+	d.offline = true
+	d.mu.Unlock()
+
+	d.logFor(req).Warn("Download interrupted by lost connection, retrying when back online")
+	return true
+}
+
+// isNetworkError reports whether yt-dlp output indicates a connection failure
+func isNetworkError(output string) bool {
+	for _, marker := range networkErrorMarkers {
+		if strings.Contains(output, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestDequeueOffline(t *testing.T) {
+	cacheDir := t.TempDir()
+	dl := NewDownloader(&models.Config{YtdlPath: "yt-dlp"}, cache.NewManager(cacheDir, 0), 1)
+
+	dl.mu.Lock()
+	dl.queue = append(dl.queue, &DownloadRequest{VideoID: "TEST1"})
+	dl.mu.Unlock()
+
+	dl.SetOffline(true)
+	assert.True(t, dl.IsOffline())
+	assert.Nil(t, dl.dequeue(), "queued downloads wait while offline")
+	assert.Equal(t, 1, dl.GetQueueLength())
+
+	dl.SetOffline(false)
+	req := dl.dequeue()
+	require.NotNil(t, req)
+	assert.Equal(t, "TEST1", req.VideoID)
+}
+
+func TestProcessDownloadRequeuesOffline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	cacheDir := t.TempDir()
+	script := filepath.Join(t.TempDir(), "fake-ytdlp.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "ERROR: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>"
+exit 1
+`), 0755))
+
+	dl := NewDownloader(&models.Config{YtdlPath: script, CachePath: cacheDir}, cache.NewManager(cacheDir, 0), 1)
+	require.NoError(t, dl.Start())
+	defer dl.Stop()
+
+	online := true
+	dl.SetNetworkCheck(func() bool { return online })
+
+	// A network error while the internet is reachable is an ordinary failure
+	req := &DownloadRequest{VideoID: "ONLINE", VideoURL: "https://youtube.com/watch?v=ONLINE", Format: models.DownloadFormatMP4}
+	dl.mu.Lock()
+	dl.active[req.VideoID] = req
+	dl.mu.Unlock()
+	dl.processDownload(req)
+	assert.Equal(t, StatusFailed, req.Status)
+
+	// Once the connection is gone the request goes back to the queue
+	online = false
+	req = &DownloadRequest{VideoID: "OFFLINE", VideoURL: "https://youtube.com/watch?v=OFFLINE", Format: models.DownloadFormatMP4}
+	dl.mu.Lock()
+	dl.active[req.VideoID] = req
+	dl.mu.Unlock()
+	dl.processDownload(req)
+
+	assert.Equal(t, StatusQueued, req.Status)
+	assert.True(t, dl.IsOffline())
+	assert.Equal(t, 0, dl.GetActiveDownloads())
+	status, err := dl.GetStatus("OFFLINE")
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, status.Status)
+	assert.Len(t, dl.ListFailed(), 1, "only the online failure is recorded")
+}
+
+func TestIsNetworkError(t *testing.T) {
+	assert.True(t, isNetworkError("ERROR: [Errno 11001] getaddrinfo failed"))
+	assert.True(t, isNetworkError("ERROR: Read timed out."))
+	assert.False(t, isNetworkError("ERROR: Video unavailable"))
+}
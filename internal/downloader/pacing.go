@@ -0,0 +1,107 @@
+package downloader
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"vrcvideocacher/pkg/models"
+)
+
+const (
+	// paceJitter is the largest random share added to a pause, so requests
+	// do not arrive at a fixed rhythm
+	paceJitter = 0.5
+
+	// minBackoff is the extra pause after the first rate limit
+	minBackoff = 30 * time.Second
+
+	// maxBackoff caps the extra pause after repeated rate limits
+	maxBackoff = 15 * time.Minute
+)
+
+// pacer spaces out consecutive yt-dlp downloads, shared by all workers
+// Each rate limited download doubles an extra pause, each successful one
+// halves it again
+type pacer struct {
+	mu      sync.Mutex
+	next    time.Time
+	backoff time.Duration
+	jitter  func() float64
+}
+
+// newPacer creates a pacer with random jitter
+func newPacer() *pacer {
+	return &pacer{jitter: rand.Float64}
+}
+
+// pause returns the pause between downloads for cfg, without jitter
+func pause(cfg *models.Config) time.Duration {
+	return time.Duration(cfg.YtdlPacing+cfg.YtdlDelay) * time.Second
+}
+
+// Wait blocks until the next download may start, reserving its slot
+// It returns early with ctx's error when ctx is done
+func (p *pacer) Wait(ctx context.Context, base time.Duration) error {
+	p.mu.Lock()
+	now := time.Now()
+	start := p.next
+	if start.Before(now) {
+		start = now
+	}
+	gap := base + p.backoff
+	p.next = start.Add(gap + time.Duration(float64(gap)*paceJitter*p.jitter()))
+	p.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Report adjusts the extra pause after a download, returning the new one
+func (p *pacer) Report(rateLimited bool) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case rateLimited:
+		p.backoff = min(max(2*p.backoff, minBackoff), maxBackoff)
+		p.next = time.Now().Add(p.backoff)
+	case p.backoff > 0:
+		p.backoff /= 2
+		if p.backoff < time.Second {
+			p.backoff = 0
+		}
+	}
+
+	return p.backoff
+}
+
+// Backoff returns the current extra pause after rate limits
+func (p *pacer) Backoff() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.backoff
+}
+
+// reportPacing records whether a download was rate limited, logging when
+// downloads slow down
+func (d *Downloader) reportPacing(rateLimited bool) {
+	before := d.pacer.Backoff()
+	after := d.pacer.Report(rateLimited)
+	if after > before {
+		d.log().Warn("yt-dlp was rate limited, pausing between downloads", "pause", after)
+	}
+}
@@ -0,0 +1,63 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/pkg/models"
+)
+
+func TestPacerWait(t *testing.T) {
+	p := &pacer{jitter: func() float64 { return 1 }}
+	ctx := context.Background()
+
+	// The first download starts right away, the next after the pause plus jitter
+	start := time.Now()
+	require.NoError(t, p.Wait(ctx, 40*time.Millisecond))
+	assert.Less(t, time.Since(start), 40*time.Millisecond)
+
+	require.NoError(t, p.Wait(ctx, 40*time.Millisecond))
+	assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+}
+
+func TestPacerWaitCancelled(t *testing.T) {
+	p := &pacer{jitter: func() float64 { return 0 }}
+	require.NoError(t, p.Wait(context.Background(), time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, p.Wait(ctx, time.Hour), context.Canceled)
+}
+
+func TestPacerReport(t *testing.T) {
+	p := newPacer()
+
+	assert.Equal(t, time.Duration(0), p.Report(false))
+
+	// Rate limits double the extra pause up to the cap
+	assert.Equal(t, minBackoff, p.Report(true))
+	assert.Equal(t, 2*minBackoff, p.Report(true))
+	for range 10 {
+		p.Report(true)
+	}
+	assert.Equal(t, maxBackoff, p.Backoff())
+
+	// Successful downloads halve it until it is gone
+	assert.Equal(t, maxBackoff/2, p.Report(false))
+	for range 20 {
+		p.Report(false)
+	}
+	assert.Equal(t, time.Duration(0), p.Backoff())
+}
+
+func TestPause(t *testing.T) {
+	cfg := models.DefaultConfig()
+	assert.Equal(t, 2*time.Second, pause(cfg))
+
+	cfg.YtdlDelay = 3
+	assert.Equal(t, 5*time.Second, pause(cfg))
+}
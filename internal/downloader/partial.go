@@ -0,0 +1,40 @@
+package downloader
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// PartialFile returns the file yt-dlp writes a queued or active download to
+// while it is in progress, and whether the download is still pending. The
+// file only grows in order when a single progressive format is downloaded
+// by yt-dlp itself; merged formats appear in the cache once complete, and
+// aria2c writes out of order, so the path is empty when aria2c is used
+func (d *Downloader) PartialFile(videoID string) (string, bool) {
+	d.mu.RLock()
+	req, ok := d.active[videoID]
+	if !ok {
+		for _, queued := range d.queue {
+			if queued.VideoID == videoID {
+				req, ok = queued, true
+				break
+			}
+		}
+	}
+	d.mu.RUnlock()
+
+	if !ok {
+		return "", false
+	}
+
+	cfg := d.requestConfig(req)
+	if len(d.aria2cArgs(cfg)) > 0 {
+		return "", true
+	}
+
+	dir := d.cache.GetCachePath()
+	if cfg.DownloadTempPath != "" {
+		dir = cfg.DownloadTempPath
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s.%s.part", req.VideoID, req.Format.String())), true
+}
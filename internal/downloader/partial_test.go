@@ -0,0 +1,41 @@
+package downloader
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestPartialFile(t *testing.T) {
+	cacheDir := t.TempDir()
+	dl := NewDownloader(&models.Config{YtdlPath: "yt-dlp"}, cache.NewManager(cacheDir, 0), 1)
+
+	_, pending := dl.PartialFile("TEST1")
+	assert.False(t, pending)
+
+	req := &DownloadRequest{VideoID: "TEST1", Format: models.DownloadFormatWebm}
+	dl.mu.Lock()
+	dl.queue = append(dl.queue, req)
+	dl.mu.Unlock()
+
+	path, pending := dl.PartialFile("TEST1")
+	assert.True(t, pending)
+	assert.Equal(t, filepath.Join(cacheDir, "TEST1.webm.part"), path)
+
+	// Downloads go to the temp path of their config snapshot
+	tempDir := t.TempDir()
+	req.config = &models.Config{YtdlPath: "yt-dlp", DownloadTempPath: tempDir}
+	path, _ = dl.PartialFile("TEST1")
+	assert.Equal(t, filepath.Join(tempDir, "TEST1.webm.part"), path)
+
+	// aria2c writes out of order, so there is nothing to stream
+	req.config = &models.Config{YtdlPath: "yt-dlp", Aria2cEnabled: true}
+	dl.SetAria2cPath("aria2c")
+	path, pending = dl.PartialFile("TEST1")
+	assert.True(t, pending)
+	assert.Empty(t, path)
+}
@@ -0,0 +1,315 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"vrcvideocacher/internal/events"
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/pkg/models"
+)
+
+var (
+	ErrConfirmationRequired = errors.New("download exceeds size limit and needs confirmation")
+	ErrSizeUnknown          = errors.New("download size unknown")
+)
+
+// maxPending bounds the number of downloads awaiting confirmation
+const maxPending = 100
+
+// downgradeLadder lists the resolutions tried, highest first, when
+// downgrading an oversized download
+var downgradeLadder = []int{2160, 1440, 1080, 720, 480, 360, 240, 144}
+
+// formatInfo holds the size fields of yt-dlp's JSON output
+type formatInfo struct {
+	Filesize       float64 `json:"filesize"`
+	FilesizeApprox float64 `json:"filesize_approx"`
+}
+
+// size returns the exact size if known, otherwise the approximation
+func (f formatInfo) size() int64 {
+	if f.Filesize > 0 {
+		return int64(f.Filesize)
+	}
+	return int64(f.FilesizeApprox)
+}
+
+// videoInfo is the subset of `yt-dlp -j` output used for size estimation
+// and metadata
+type videoInfo struct {
+	formatInfo
+	RequestedFormats []formatInfo `json:"requested_formats"`
+	Title            string       `json:"title"`
+	Duration         float64      `json:"duration"`
+	IsLive           bool         `json:"is_live"`
+	LiveStatus       string       `json:"live_status"`
+	ReleaseTimestamp int64        `json:"release_timestamp"`
+	URL              string       `json:"url"`
+	ManifestURL      string       `json:"manifest_url"`
+}
+
+// VideoMetadata describes a video for display
+type VideoMetadata struct {
+	Title    string  `json:"title"`
+	Duration float64 `json:"duration"`
+}
+
+// estimatedSize sums the selected formats, or returns the single format size
+func (v *videoInfo) estimatedSize() int64 {
+	if len(v.RequestedFormats) == 0 {
+		return v.size()
+	}
+
+	var total int64
+	for _, f := range v.RequestedFormats {
+		size := f.size()
+		if size <= 0 {
+			return 0
+		}
+		total += size
+	}
+	return total
+}
+
+// SetConfirmNotifier sets the callback invoked when a download is held for
+// confirmation because it exceeds config.CacheMaxDownloadMB
+func (d *Downloader) SetConfirmNotifier(notify func(DownloadRequest)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onConfirm = notify
+}
+
+// EstimateSize returns the expected download size in bytes of videoURL at
+// the given format and resolution, using yt-dlp metadata
+// The probe is cancelled when ctx is done or the downloader stops
+func (d *Downloader) EstimateSize(ctx context.Context, videoURL string, format models.DownloadFormat, maxRes int) (int64, error) {
+	return d.estimateSize(ctx, d.snapshot(), videoURL, format, maxRes)
+}
+
+// estimateSize implements EstimateSize with the given configuration
+func (d *Downloader) estimateSize(ctx context.Context, cfg *models.Config, videoURL string, format models.DownloadFormat, maxRes int) (int64, error) {
+	info, err := d.probeInfo(ctx, cfg, videoURL, "-f", formatSelector(format, videoFilter(cfg, maxRes)))
+	if err != nil {
+		return 0, err
+	}
+
+	size := info.estimatedSize()
+	if size <= 0 {
+		return 0, ErrSizeUnknown
+	}
+	return size, nil
+}
+
+// FetchMetadata returns the title and duration of videoURL using yt-dlp
+func (d *Downloader) FetchMetadata(ctx context.Context, videoURL string) (VideoMetadata, error) {
+	info, err := d.probeInfo(ctx, d.snapshot(), videoURL)
+	if err != nil {
+		return VideoMetadata{}, err
+	}
+	return VideoMetadata{Title: info.Title, Duration: info.Duration}, nil
+}
+
+// probeInfo runs `yt-dlp -j` for videoURL with the first cookie account
+func (d *Downloader) probeInfo(ctx context.Context, cfg *models.Config, videoURL string, extra ...string) (*videoInfo, error) {
+	args := append([]string{"-j", "--no-playlist", "--no-warnings", "--no-check-certificate"}, extra...)
+
+	account := ""
+	if cfg.YtdlUseCookies {
+		if order := d.cookies.Order(); len(order) > 0 {
+			account = order[0]
+		}
+	}
+
+	output, err := d.probeYtdlp(ctx, d.buildArgs(cfg, args, account, videoURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch video metadata: %w", err)
+	}
+
+	var info videoInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse video metadata: %w", err)
+	}
+	return &info, nil
+}
+
+// preflight checks the estimated size of a download against the configured
+// limit, downgrading the resolution or asking for confirmation as configured.
+// Downloads whose size cannot be estimated are allowed, as are live
+// recordings, which are bounded by their time limit instead
+func (d *Downloader) preflight(ctx context.Context, req *DownloadRequest, cfg *models.Config) error {
+	limitMB := cfg.CacheMaxDownloadMB
+	if limitMB <= 0 || req.Confirmed || req.Live {
+		return nil
+	}
+	limit := int64(limitMB) * 1024 * 1024
+
+	size, err := d.estimateSize(ctx, cfg, req.VideoURL, req.Format, req.MaxRes)
+	if err != nil {
+		d.logFor(req).Warn("Size estimate unavailable", logger.Err(err))
+		return nil
+	}
+
+	d.mu.Lock()
+	req.EstimatedSize = size
+	d.mu.Unlock()
+
+	if size <= limit {
+		return nil
+	}
+
+	if cfg.OversizeAction == models.OversizeDowngrade {
+		for _, res := range downgradeLadder {
+			if res >= req.MaxRes {
+				continue
+			}
+
+			size, err := d.estimateSize(ctx, cfg, req.VideoURL, req.Format, res)
+			if err != nil || size > limit {
+				continue
+			}
+
+			d.logFor(req).Info("Downgrading to stay under the size limit", "maxRes", res, "limitMB", limitMB)
+			d.mu.Lock()
+			req.MaxRes = res
+			req.EstimatedSize = size
+			d.mu.Unlock()
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: about %d MB, limit %d MB", ErrConfirmationRequired, size/(1024*1024), limitMB)
+}
+
+// probeYtdlp runs yt-dlp and returns its standard output
+// The process is killed when ctx is done or the downloader stops
+func (d *Downloader) probeYtdlp(ctx context.Context, args []string) ([]byte, error) {
+	path, args, binary := d.ytdlpCommand(args)
+	d.mu.RLock()
+	stopCtx := d.ctx
+	d.mu.RUnlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if stopCtx != nil {
+		stop := context.AfterFunc(stopCtx, cancel)
+		defer stop()
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := d.runInstalled(ctx, binary, func() error {
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		start := time.Now()
+		err := cmd.Run()
+		d.observeYtdlp(ytdlpProbe, start, err)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, lastLine(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// hold parks a request that needs confirmation, dropping the oldest beyond
+// the limit
+// Must be called with lock held
+func (d *Downloader) hold(req *DownloadRequest) {
+	d.pending[req.VideoID] = req
+
+	for len(d.pending) > maxPending {
+		var oldestID string
+		var oldest time.Time
+		for id, r := range d.pending {
+			if oldestID == "" || r.FinishedAt.Before(oldest) {
+				oldestID = id
+				oldest = r.FinishedAt
+			}
+		}
+		delete(d.pending, oldestID)
+	}
+}
+
+// ListPending returns copies of downloads awaiting confirmation, oldest first
+func (d *Downloader) ListPending() []*DownloadRequest {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	pending := make([]*DownloadRequest, 0, len(d.pending))
+	for _, req := range d.pending {
+		reqCopy := *req
+		pending = append(pending, &reqCopy)
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].FinishedAt.Before(pending[j].FinishedAt)
+	})
+
+	return pending
+}
+
+// Confirm queues a download held for confirmation at its original resolution
+func (d *Downloader) Confirm(videoID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.running {
+		return ErrDownloaderStopped
+	}
+
+	if d.draining {
+		return ErrDraining
+	}
+
+	held, ok := d.pending[videoID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	req := &DownloadRequest{
+		VideoID:       held.VideoID,
+		VideoURL:      held.VideoURL,
+		Format:        held.Format,
+		MaxRes:        held.MaxRes,
+		MaxLength:     held.MaxLength,
+		QueuedAt:      time.Now(),
+		Status:        StatusQueued,
+		EstimatedSize: held.EstimatedSize,
+		Confirmed:     true,
+		config:        held.config,
+	}
+
+	d.queue = append(d.queue, req)
+	delete(d.pending, videoID)
+	d.publish(events.DownloadQueued, req)
+
+	return nil
+}
+
+// Decline discards a download held for confirmation
+func (d *Downloader) Decline(videoID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.pending[videoID]; !ok {
+		return ErrNotFound
+	}
+
+	delete(d.pending, videoID)
+	return nil
+}
+
+// lastLine returns the last non-empty line of output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,171 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+// newSizedYtdlp writes a fake yt-dlp that reports 3000 MB at 1080p and
+// 500 MB below, and creates outputFile when downloading
+func newSizedYtdlp(t *testing.T, outputFile string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	script := filepath.Join(t.TempDir(), "yt-dlp")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+case "$*" in
+*-j*height\<=1080*) echo '{"requested_formats":[{"filesize":3000000000},{"filesize_approx":145728000}]}' ;;
+*-j*) echo '{"filesize_approx":524288000}' ;;
+*) touch "`+outputFile+`" ;;
+esac
+`), 0755)
+	require.NoError(t, err)
+	return script
+}
+
+func TestVideoInfoEstimatedSize(t *testing.T) {
+	single := videoInfo{formatInfo: formatInfo{FilesizeApprox: 100}}
+	assert.Equal(t, int64(100), single.estimatedSize())
+
+	merged := videoInfo{RequestedFormats: []formatInfo{{Filesize: 100}, {FilesizeApprox: 50}}}
+	assert.Equal(t, int64(150), merged.estimatedSize())
+
+	// An unknown part makes the whole estimate unknown
+	partial := videoInfo{RequestedFormats: []formatInfo{{Filesize: 100}, {}}}
+	assert.Equal(t, int64(0), partial.estimatedSize())
+}
+
+func TestPreflightConfirm(t *testing.T) {
+	cacheDir := t.TempDir()
+	cfg := &models.Config{
+		YtdlPath:           newSizedYtdlp(t, filepath.Join(cacheDir, "BIG.mp4")),
+		CacheMaxDownloadMB: 1000,
+		OversizeAction:     models.OversizeConfirm,
+	}
+
+	dl := NewDownloader(cfg, cache.NewManager(cacheDir, 0), 1)
+	var notified []DownloadRequest
+	dl.SetConfirmNotifier(func(req DownloadRequest) {
+		notified = append(notified, req)
+	})
+
+	// Run without workers so the confirmed request stays queued
+	dl.ctx, dl.cancel = context.WithCancel(context.Background())
+	defer dl.cancel()
+	dl.running = true
+
+	req := &DownloadRequest{
+		VideoID:  "BIG",
+		VideoURL: "https://youtube.com/watch?v=BIG",
+		Format:   models.DownloadFormatMP4,
+		MaxRes:   1080,
+	}
+	dl.mu.Lock()
+	dl.active[req.VideoID] = req
+	dl.mu.Unlock()
+
+	dl.processDownload(req)
+
+	require.Len(t, notified, 1)
+	assert.Equal(t, StatusAwaitingConfirmation, notified[0].Status)
+	assert.ErrorIs(t, notified[0].Error, ErrConfirmationRequired)
+	assert.Equal(t, int64(3145728000), notified[0].EstimatedSize)
+
+	status, err := dl.GetStatus("BIG")
+	require.NoError(t, err)
+	assert.Equal(t, StatusAwaitingConfirmation, status.Status)
+	assert.Empty(t, dl.ListFailed())
+
+	// Requesting it again does not probe a second time
+	assert.ErrorIs(t, dl.Queue("BIG", req.VideoURL, req.Format), ErrAlreadyQueued)
+
+	require.NoError(t, dl.Confirm("BIG"))
+	assert.Empty(t, dl.ListPending())
+	assert.ErrorIs(t, dl.Confirm("BIG"), ErrNotFound)
+
+	// Confirmed requests skip the size check
+	queued := dl.dequeue()
+	require.NotNil(t, queued)
+	assert.True(t, queued.Confirmed)
+	assert.Equal(t, 1080, queued.MaxRes)
+	require.NoError(t, dl.executeDownload(queued))
+}
+
+func TestPreflightDowngrade(t *testing.T) {
+	cacheDir := t.TempDir()
+	cfg := &models.Config{
+		YtdlPath:           newSizedYtdlp(t, filepath.Join(cacheDir, "BIG.mp4")),
+		CacheMaxDownloadMB: 1000,
+		OversizeAction:     models.OversizeDowngrade,
+	}
+
+	dl := NewDownloader(cfg, cache.NewManager(cacheDir, 0), 1)
+	require.NoError(t, dl.Start())
+	defer dl.Stop()
+
+	req := &DownloadRequest{
+		VideoID:  "BIG",
+		VideoURL: "https://youtube.com/watch?v=BIG",
+		Format:   models.DownloadFormatMP4,
+		MaxRes:   1080,
+	}
+
+	require.NoError(t, dl.executeDownload(req))
+	assert.Equal(t, 720, req.MaxRes)
+	assert.Equal(t, int64(524288000), req.EstimatedSize)
+}
+
+func TestDecline(t *testing.T) {
+	dl := NewDownloader(&models.Config{}, cache.NewManager(t.TempDir(), 0), 1)
+
+	dl.mu.Lock()
+	dl.hold(&DownloadRequest{VideoID: "HELD", Status: StatusAwaitingConfirmation})
+	dl.mu.Unlock()
+
+	assert.Len(t, dl.ListDownloads(), 1)
+	require.NoError(t, dl.Decline("HELD"))
+	assert.ErrorIs(t, dl.Decline("HELD"), ErrNotFound)
+	assert.Empty(t, dl.ListDownloads())
+}
+
+func TestEstimateSizeCancelled(t *testing.T) {
+	cfg := &models.Config{YtdlPath: newSizedYtdlp(t, filepath.Join(t.TempDir(), "out.mp4"))}
+	dl := NewDownloader(cfg, cache.NewManager(t.TempDir(), 0), 1)
+
+	size, err := dl.EstimateSize(context.Background(), "https://youtube.com/watch?v=SMALL", models.DownloadFormatMP4, 720)
+	require.NoError(t, err)
+	assert.Equal(t, int64(524288000), size)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = dl.EstimateSize(ctx, "https://youtube.com/watch?v=SMALL", models.DownloadFormatMP4, 720)
+	assert.Error(t, err)
+}
+
+func TestFetchMetadata(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	script := filepath.Join(t.TempDir(), "yt-dlp")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo '{"title":"Test Video","duration":212.5,"filesize":1000}'
+`), 0755))
+
+	dl := NewDownloader(&models.Config{YtdlPath: script}, cache.NewManager(t.TempDir(), 0), 1)
+
+	meta, err := dl.FetchMetadata(context.Background(), "https://youtube.com/watch?v=META")
+	require.NoError(t, err)
+	assert.Equal(t, "Test Video", meta.Title)
+	assert.Equal(t, 212.5, meta.Duration)
+}
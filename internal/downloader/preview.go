@@ -0,0 +1,148 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"vrcvideocacher/internal/logger"
+)
+
+// ErrPreviewFailed is returned when ffmpeg fails to generate a preview
+var ErrPreviewFailed = errors.New("preview generation failed")
+
+const (
+	// previewFrames is the number of frames side by side in a preview
+	previewFrames = 10
+
+	// previewWidth is the width of each preview frame in pixels
+	previewWidth = 160
+
+	// previewInterval is how often cached entries are checked for missing
+	// previews
+	previewInterval = time.Minute
+)
+
+// durationPattern matches the duration ffmpeg prints for its input
+var durationPattern = regexp.MustCompile(`Duration: (\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+
+// previewWorker generates missing previews in the background while
+// cachePreviews is enabled
+func (d *Downloader) previewWorker() {
+	defer d.workerWg.Done()
+
+	ticker := time.NewTicker(previewInterval)
+	defer ticker.Stop()
+
+	// Entries whose preview failed are not tried again until restart
+	failed := make(map[string]bool)
+
+	for {
+		if d.snapshot().CachePreviews {
+			d.generatePreviews(failed)
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// generatePreviews generates the previews cached entries are missing,
+// skipping those in failed and adding new failures to it
+func (d *Downloader) generatePreviews(failed map[string]bool) {
+	for _, entry := range d.cache.ListEntries() {
+		if d.ctx.Err() != nil {
+			return
+		}
+		if failed[entry.ID] {
+			continue
+		}
+		if _, err := os.Stat(d.cache.PreviewPath(entry.ID)); err == nil {
+			continue
+		}
+
+		if err := d.generatePreview(entry.ID); err != nil {
+			failed[entry.ID] = true
+			d.log().Warn("Preview generation failed", "video", entry.ID, logger.Err(err))
+		}
+	}
+}
+
+// generatePreview renders a JPEG sprite of frames spread over the video of
+// entry id, side by side, and stores it as the entry's preview. Each frame
+// is seeked to directly, so only a few keyframes are decoded
+func (d *Downloader) generatePreview(id string) error {
+	entry, err := d.cache.GetEntry(id)
+	if err != nil {
+		return err
+	}
+	filePath, err := d.cache.GetFilePath(id)
+	if err != nil {
+		return err
+	}
+
+	duration, err := d.videoDuration(filePath)
+	if err != nil {
+		return err
+	}
+
+	// Render next to the preview, so it is moved into place by a rename
+	dst := d.cache.PreviewPath(id)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create preview directory: %w", err)
+	}
+	tmpPath := strings.TrimSuffix(dst, ".jpg") + ".tmp.jpg"
+	defer os.Remove(tmpPath) // No-op once saved
+
+	args := []string{"-hide_banner", "-nostats", "-y"}
+	var filter strings.Builder
+	for i := range previewFrames {
+		at := duration * (float64(i) + 0.5) / previewFrames
+		args = append(args, "-ss", strconv.FormatFloat(at, 'f', 2, 64), "-i", filePath)
+		fmt.Fprintf(&filter, "[%d:v]scale=%d:-2,setsar=1[f%d];", i, previewWidth, i)
+	}
+	for i := range previewFrames {
+		fmt.Fprintf(&filter, "[f%d]", i)
+	}
+	fmt.Fprintf(&filter, "hstack=inputs=%d[out]", previewFrames)
+	args = append(args, "-filter_complex", filter.String(), "-map", "[out]", "-frames:v", "1", "-q:v", "5", tmpPath)
+
+	if out, err := exec.CommandContext(d.ctx, d.ffmpegPath, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %v: %s", ErrPreviewFailed, err, lastLine(string(out)))
+	}
+
+	return d.cache.SavePreview(id, entry.Version(), tmpPath)
+}
+
+// videoDuration returns the length of a video in seconds, as ffmpeg reports
+// it when run without an output
+func (d *Downloader) videoDuration(filePath string) (float64, error) {
+	// ffmpeg fails without an output, but prints the input details first
+	out, err := exec.CommandContext(d.ctx, d.ffmpegPath, "-hide_banner", "-i", filePath).CombinedOutput()
+
+	match := durationPattern.FindStringSubmatch(string(out))
+	if match == nil {
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v: %s", ErrPreviewFailed, err, lastLine(string(out)))
+		}
+		return 0, fmt.Errorf("%w: unknown duration", ErrPreviewFailed)
+	}
+
+	hours, _ := strconv.Atoi(match[1])
+	minutes, _ := strconv.Atoi(match[2])
+	seconds, _ := strconv.ParseFloat(match[3], 64)
+	duration := float64(hours*3600+minutes*60) + seconds
+	if duration <= 0 {
+		return 0, fmt.Errorf("%w: unknown duration", ErrPreviewFailed)
+	}
+	return duration, nil
+}
@@ -0,0 +1,77 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestGeneratePreview(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	cacheDir := t.TempDir()
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "TEST1.mp4"), []byte("video"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("TEST1", "TEST1.mp4"))
+
+	// Fake ffmpeg: prints the input details without an output, otherwise
+	// writes the last argument, logging each call
+	calls := filepath.Join(t.TempDir(), "calls")
+	script := filepath.Join(t.TempDir(), "fake-ffmpeg.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "$@" >> `+calls+`
+for last; do :; done
+case "$last" in
+*.jpg) printf sprite > "$last" ;;
+*)
+	echo "  Duration: 00:01:40.00, start: 0.000000, bitrate: 1000 kb/s" >&2
+	echo "At least one output file must be specified" >&2
+	exit 1
+	;;
+esac
+`), 0755))
+
+	dl := NewDownloader(&models.Config{YtdlPath: "yt-dlp"}, cacheMgr, 1)
+	dl.ffmpegPath = script
+	require.NoError(t, dl.Start())
+	defer dl.Stop()
+
+	failed := make(map[string]bool)
+	dl.generatePreviews(failed)
+	assert.Empty(t, failed)
+
+	data, err := os.ReadFile(cacheMgr.PreviewPath("TEST1"))
+	require.NoError(t, err)
+	assert.Equal(t, "sprite", string(data))
+
+	// Frames are seeked to across the whole video
+	log, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "-ss 5.00 -i")
+	assert.Contains(t, string(log), "-ss 95.00 -i")
+	assert.Contains(t, string(log), "hstack=inputs=10")
+
+	// Entries with a preview are skipped
+	dl.generatePreviews(failed)
+	again, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, log, again)
+
+	// Failures are remembered
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "TEST2.mp4"), []byte("video"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("TEST2", "TEST2.mp4"))
+	dl.ffmpegPath = filepath.Join(t.TempDir(), "missing-ffmpeg")
+	assert.ErrorIs(t, dl.generatePreview("TEST2"), ErrPreviewFailed)
+	dl.generatePreviews(failed)
+	assert.True(t, failed["TEST2"])
+	assert.NoFileExists(t, cacheMgr.PreviewPath("TEST2"))
+}
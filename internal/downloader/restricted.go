@@ -0,0 +1,94 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDRMProtected = errors.New("video is DRM protected")
+	ErrMembersOnly  = errors.New("video is members-only")
+)
+
+const (
+	// restrictedTTL is how long a video is known to be uncacheable before
+	// it is tried again, e.g. after joining the channel
+	restrictedTTL = 6 * time.Hour
+
+	// maxRestricted bounds the number of uncacheable videos kept
+	maxRestricted = 100
+)
+
+// restrictionMarkers map yt-dlp error messages to the restriction they
+// report
+var restrictionMarkers = []struct {
+	marker string
+	err    error
+}{
+	{"DRM protected", ErrDRMProtected},
+	{"known to use DRM", ErrDRMProtected},
+	{"members-only content", ErrMembersOnly},
+	{"available to this channel's members", ErrMembersOnly},
+}
+
+// restrictionError returns the restriction reported by yt-dlp output, or
+// nil if there is none
+func restrictionError(output string) error {
+	for _, m := range restrictionMarkers {
+		if strings.Contains(output, m.marker) {
+			return fmt.Errorf("%w: %s", m.err, lastLine(output))
+		}
+	}
+	return nil
+}
+
+// isRestricted reports whether err means the video can never be cached
+func isRestricted(err error) bool {
+	return errors.Is(err, ErrDRMProtected) || errors.Is(err, ErrMembersOnly)
+}
+
+// markRestricted finishes a request for an uncacheable video without
+// counting it as a failure, and remembers the video so requests for it are
+// not queued again for restrictedTTL
+func (d *Downloader) markRestricted(req *DownloadRequest, err error) {
+	d.mu.Lock()
+	req.FinishedAt = time.Now()
+	req.Status = StatusRestricted
+	req.Error = err
+	delete(d.active, req.VideoID)
+	d.addRecent(req)
+	d.restricted[req.VideoID] = req
+
+	for id, r := range d.restricted {
+		if time.Since(r.FinishedAt) >= restrictedTTL {
+			delete(d.restricted, id)
+		}
+	}
+	for len(d.restricted) > maxRestricted {
+		var oldestID string
+		var oldest time.Time
+		for id, r := range d.restricted {
+			if oldestID == "" || r.FinishedAt.Before(oldest) {
+				oldestID = id
+				oldest = r.FinishedAt
+			}
+		}
+		delete(d.restricted, oldestID)
+	}
+	d.mu.Unlock()
+
+	d.logFor(req).Info("Not caching", "reason", err)
+}
+
+// restriction returns the restriction of a video known to be uncacheable,
+// or nil
+// Must be called with lock held
+func (d *Downloader) restriction(videoID string) error {
+	req, ok := d.restricted[videoID]
+	if !ok || time.Since(req.FinishedAt) >= restrictedTTL {
+		return nil
+	}
+	return req.Error
+}
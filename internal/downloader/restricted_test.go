@@ -0,0 +1,79 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestRestrictionError(t *testing.T) {
+	tests := []struct {
+		output string
+		want   error
+	}{
+		{"ERROR: [youtube] abc: This video is DRM protected", ErrDRMProtected},
+		{"ERROR: The requested site is known to use DRM protection. It will not be supported.", ErrDRMProtected},
+		{"ERROR: [youtube] abc: Join this channel to get access to members-only content like this video, and other exclusive perks.", ErrMembersOnly},
+		{"ERROR: [youtube] abc: This video is available to this channel's members on level: Fan", ErrMembersOnly},
+		{"ERROR: [youtube] abc: Video unavailable", nil},
+	}
+
+	for _, tt := range tests {
+		err := restrictionError(tt.output)
+		if tt.want == nil {
+			assert.NoError(t, err, tt.output)
+			continue
+		}
+		assert.ErrorIs(t, err, tt.want, tt.output)
+		assert.True(t, isRestricted(err))
+	}
+}
+
+func TestProcessDownloadRestricted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	script := filepath.Join(t.TempDir(), "fake-ytdlp.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "ERROR: [youtube] DRM: This video is DRM protected"
+exit 1
+`), 0755))
+
+	dl := NewDownloader(&models.Config{YtdlPath: script}, cache.NewManager(t.TempDir(), 0), 1)
+	require.NoError(t, dl.Start())
+	defer dl.Stop()
+
+	req := &DownloadRequest{VideoID: "DRM", VideoURL: "https://youtube.com/watch?v=DRM", Format: models.DownloadFormatMP4}
+	dl.mu.Lock()
+	dl.active[req.VideoID] = req
+	dl.mu.Unlock()
+	dl.processDownload(req)
+
+	status, err := dl.GetStatus("DRM")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRestricted, status.Status)
+	assert.ErrorIs(t, status.Error, ErrDRMProtected)
+	assert.Empty(t, dl.ListFailed(), "restricted videos are not retried")
+
+	// Known restricted videos are not queued again
+	err = dl.QueueWithMaxRes(context.Background(), "DRM", req.VideoURL, models.DownloadFormatMP4, 0)
+	assert.ErrorIs(t, err, ErrDRMProtected)
+	assert.Equal(t, 0, dl.GetQueueLength())
+
+	// Until the restriction expires
+	dl.SetOffline(true)
+	dl.mu.Lock()
+	dl.restricted["DRM"].FinishedAt = time.Now().Add(-restrictedTTL)
+	dl.mu.Unlock()
+	assert.NoError(t, dl.QueueWithMaxRes(context.Background(), "DRM", req.VideoURL, models.DownloadFormatMP4, 0))
+}
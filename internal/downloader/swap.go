@@ -0,0 +1,66 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"vrcvideocacher/internal/filelock"
+	"vrcvideocacher/internal/logger"
+)
+
+// swapRetryDelay is how long a run waits before starting yt-dlp again when
+// it was missing, e.g. removed by an update that took no lock
+const swapRetryDelay = time.Second
+
+// ytdlpCommand returns the command running yt-dlp with args, and the yt-dlp
+// binary or zipapp it runs
+func (d *Downloader) ytdlpCommand(args []string) (path string, cmdArgs []string, binary string) {
+	path = d.snapshot().YtdlPath
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.cmdPath == "" {
+		return path, args, path
+	}
+
+	// The zipapp is the last argument of its interpreter
+	binary = d.cmdPath
+	if len(d.cmdArgs) > 0 {
+		binary = d.cmdArgs[len(d.cmdArgs)-1]
+	}
+	return d.cmdPath, append(append([]string{}, d.cmdArgs...), args...), binary
+}
+
+// runInstalled calls run once binary is not being replaced by a yt-dlp
+// update, starting it once more if it went missing during a swap
+func (d *Downloader) runInstalled(ctx context.Context, binary string, run func() error) error {
+	d.waitForSwap(ctx, binary)
+	err := run()
+	if !errors.Is(err, fs.ErrNotExist) || ctx.Err() != nil {
+		return err
+	}
+
+	d.log().Info("yt-dlp missing, retrying after update", "path", binary)
+	select {
+	case <-ctx.Done():
+		return err
+	case <-time.After(swapRetryDelay):
+	}
+	d.waitForSwap(ctx, binary)
+	return run()
+}
+
+// waitForSwap waits while binary is being replaced, see ytdl.Manager.Download.
+// Runs go ahead if the lock cannot be taken, e.g. in a read-only directory,
+// and for commands looked up in PATH, which the app does not update
+func (d *Downloader) waitForSwap(ctx context.Context, binary string) {
+	if !filepath.IsAbs(binary) {
+		return
+	}
+	if err := filelock.Wait(ctx, filelock.PathFor(binary)); err != nil && ctx.Err() == nil {
+		d.log().Debug("Not waiting for yt-dlp updates", logger.Err(err))
+	}
+}
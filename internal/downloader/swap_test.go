@@ -0,0 +1,67 @@
+package downloader
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/filelock"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestRunInstalled(t *testing.T) {
+	tmpDir := t.TempDir()
+	binary := filepath.Join(tmpDir, "yt-dlp")
+	dl := NewDownloader(&models.Config{YtdlPath: binary}, cache.NewManager(tmpDir, 0), 1)
+
+	// Runs wait while an update swaps the binary
+	lock, err := filelock.TryLock(filelock.PathFor(binary))
+	require.NoError(t, err)
+	var swapped atomic.Bool
+	time.AfterFunc(300*time.Millisecond, func() {
+		swapped.Store(true)
+		lock.Unlock()
+	})
+
+	err = dl.runInstalled(context.Background(), binary, func() error {
+		assert.True(t, swapped.Load())
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A binary missing mid-swap is started once more
+	runs := 0
+	err = dl.runInstalled(context.Background(), binary, func() error {
+		runs++
+		if runs == 1 {
+			return fs.ErrNotExist
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, runs)
+}
+
+func TestYtdlpCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	dl := NewDownloader(&models.Config{YtdlPath: "/utils/yt-dlp"}, cache.NewManager(tmpDir, 0), 1)
+
+	path, args, binary := dl.ytdlpCommand([]string{"-J"})
+	assert.Equal(t, "/utils/yt-dlp", path)
+	assert.Equal(t, []string{"-J"}, args)
+	assert.Equal(t, "/utils/yt-dlp", binary)
+
+	// The zipapp runs through Python
+	dl.SetCommand("/usr/bin/python3", []string{"/utils/yt-dlp.pyz"})
+	path, args, binary = dl.ytdlpCommand([]string{"-J"})
+	assert.Equal(t, "/usr/bin/python3", path)
+	assert.Equal(t, []string{"/utils/yt-dlp.pyz", "-J"}, args)
+	assert.Equal(t, "/utils/yt-dlp.pyz", binary)
+}
@@ -0,0 +1,179 @@
+package downloader
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"vrcvideocacher/internal/events"
+)
+
+// ErrUpcoming is returned for premieres and scheduled streams that have not
+// started yet
+var ErrUpcoming = errors.New("video has not started yet")
+
+const (
+	// upcomingMargin is how long after a video is due it is queued
+	upcomingMargin = time.Minute
+
+	// upcomingRetry is the shortest wait before a video still upcoming is
+	// tried again
+	upcomingRetry = 5 * time.Minute
+
+	// maxUpcomingLate is how long past its scheduled start a video that has
+	// not started is still waited for
+	maxUpcomingLate = 6 * time.Hour
+
+	// maxUpcoming bounds the number of upcoming videos kept
+	maxUpcoming = 100
+)
+
+// upcomingMarkers are yt-dlp error messages for videos that have not started
+var upcomingMarkers = []string{
+	"Premieres in",
+	"Premiere will begin",
+	"live event will begin",
+}
+
+// isUpcoming reports whether yt-dlp output says the video has not started
+func isUpcoming(output string) bool {
+	for _, marker := range upcomingMarkers {
+		if strings.Contains(output, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// parkUpcoming records a request for a video that has not started instead
+// of failing it. With config.ScheduleUpcoming the video is queued again once
+// it is due: a premiere after it has played, as a normal download, and a
+// scheduled stream when it starts, as a live recording
+func (d *Downloader) parkUpcoming(req *DownloadRequest, err error) {
+	cfg := d.requestConfig(req)
+
+	// The start time is only known from the metadata
+	var start time.Time
+	var duration time.Duration
+	info, probeErr := d.probeInfo(d.ctx, cfg, req.VideoURL, "--ignore-no-formats-error")
+	if probeErr == nil && info.ReleaseTimestamp > 0 {
+		start = time.Unix(info.ReleaseTimestamp, 0)
+		duration = time.Duration(info.Duration * float64(time.Second))
+	}
+
+	d.mu.Lock()
+	req.FinishedAt = time.Now()
+	req.Status = StatusUpcoming
+	req.Error = err
+	req.ScheduledAt = time.Time{}
+	delete(d.active, req.VideoID)
+	if old, ok := d.upcoming[req.VideoID]; ok && old.timer != nil {
+		old.timer.Stop()
+	}
+	d.upcoming[req.VideoID] = req
+	d.pruneUpcoming()
+
+	if cfg.ScheduleUpcoming {
+		if delay, ok := upcomingDelay(start, duration, req.FinishedAt); ok {
+			req.ScheduledAt = req.FinishedAt.Add(delay)
+			req.Live = duration == 0
+			id := req.VideoID
+			req.timer = time.AfterFunc(delay, func() { d.queueUpcoming(id) })
+		}
+	}
+	scheduled := req.ScheduledAt
+	d.mu.Unlock()
+
+	if scheduled.IsZero() {
+		d.logFor(req).Info("Not started yet", "reason", err)
+		return
+	}
+	d.logFor(req).Info("Not started yet, queueing it when due", "at", scheduled.Format(time.RFC3339))
+}
+
+// upcomingDelay returns how long to wait before queueing a video starting
+// at start, and whether to wait at all. Premieres (with a duration) are
+// queued once played; videos long overdue are given up on
+func upcomingDelay(start time.Time, duration time.Duration, now time.Time) (time.Duration, bool) {
+	if start.IsZero() || now.Sub(start) > maxUpcomingLate {
+		return 0, false
+	}
+	return max(start.Add(duration+upcomingMargin).Sub(now), upcomingRetry), true
+}
+
+// queueUpcoming queues an upcoming video once it is due
+func (d *Downloader) queueUpcoming(videoID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.running || d.draining {
+		return
+	}
+
+	waiting, ok := d.upcoming[videoID]
+	if !ok {
+		return
+	}
+	if waiting.timer != nil {
+		waiting.timer.Stop()
+	}
+	delete(d.upcoming, videoID)
+
+	req := &DownloadRequest{
+		VideoID:   waiting.VideoID,
+		VideoURL:  waiting.VideoURL,
+		Format:    waiting.Format,
+		MaxRes:    waiting.MaxRes,
+		MaxLength: waiting.MaxLength,
+		QueuedAt:  time.Now(),
+		Status:    StatusQueued,
+		RequestID: waiting.RequestID,
+		Live:      waiting.Live,
+		config:    waiting.config,
+	}
+	d.queue = append(d.queue, req)
+	d.publish(events.DownloadQueued, req)
+}
+
+// pruneUpcoming drops the oldest upcoming videos beyond the limit
+// Must be called with lock held
+func (d *Downloader) pruneUpcoming() {
+	for len(d.upcoming) > maxUpcoming {
+		var oldestID string
+		var oldest time.Time
+		for id, r := range d.upcoming {
+			if oldestID == "" || r.FinishedAt.Before(oldest) {
+				oldestID = id
+				oldest = r.FinishedAt
+			}
+		}
+		if timer := d.upcoming[oldestID].timer; timer != nil {
+			timer.Stop()
+		}
+		delete(d.upcoming, oldestID)
+	}
+}
+
+// ListUpcoming returns copies of videos waiting for their start, soonest
+// first; those not scheduled come last
+func (d *Downloader) ListUpcoming() []*DownloadRequest {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	upcoming := make([]*DownloadRequest, 0, len(d.upcoming))
+	for _, req := range d.upcoming {
+		reqCopy := *req
+		upcoming = append(upcoming, &reqCopy)
+	}
+
+	sort.Slice(upcoming, func(i, j int) bool {
+		a, b := upcoming[i].ScheduledAt, upcoming[j].ScheduledAt
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		return a.Before(b)
+	})
+
+	return upcoming
+}
@@ -0,0 +1,97 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/pkg/models"
+)
+
+func TestIsUpcoming(t *testing.T) {
+	assert.True(t, isUpcoming("ERROR: [youtube] abc: Premieres in 5 hours"))
+	assert.True(t, isUpcoming("ERROR: [youtube] abc: This live event will begin in a few moments."))
+	assert.False(t, isUpcoming("ERROR: [youtube] abc: Video unavailable"))
+}
+
+func TestUpcomingDelay(t *testing.T) {
+	now := time.Now()
+
+	// Scheduled streams are queued when they start, premieres once played
+	delay, ok := upcomingDelay(now.Add(time.Hour), 0, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour+upcomingMargin, delay)
+
+	delay, ok = upcomingDelay(now.Add(time.Hour), 10*time.Minute, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour+10*time.Minute+upcomingMargin, delay)
+
+	// Late videos are retried after a while, long overdue ones given up on
+	delay, ok = upcomingDelay(now.Add(-time.Hour), 0, now)
+	assert.True(t, ok)
+	assert.Equal(t, upcomingRetry, delay)
+
+	_, ok = upcomingDelay(now.Add(-maxUpcomingLate-time.Minute), 0, now)
+	assert.False(t, ok)
+
+	_, ok = upcomingDelay(time.Time{}, 0, now)
+	assert.False(t, ok)
+}
+
+func TestProcessDownloadUpcoming(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+
+	// Fake yt-dlp: a premiere in two hours, ten minutes long
+	release := time.Now().Add(2 * time.Hour).Unix()
+	script := filepath.Join(t.TempDir(), "fake-ytdlp.sh")
+	require.NoError(t, os.WriteFile(script, []byte(fmt.Sprintf(`#!/bin/sh
+if [ "$1" = "-j" ]; then
+	echo '{"live_status": "is_upcoming", "release_timestamp": %d, "duration": 600}'
+	exit 0
+fi
+echo "ERROR: [youtube] PREMIERE: Premieres in 2 hours"
+exit 1
+`, release)), 0755))
+
+	cacheDir := t.TempDir()
+	dl := NewDownloader(&models.Config{YtdlPath: script, ScheduleUpcoming: true}, cache.NewManager(cacheDir, 0), 1)
+	require.NoError(t, dl.Start())
+	defer dl.Stop()
+
+	req := &DownloadRequest{VideoID: "PREMIERE", VideoURL: "https://youtube.com/watch?v=PREMIERE", Format: models.DownloadFormatMP4, config: dl.snapshot()}
+	dl.mu.Lock()
+	dl.active[req.VideoID] = req
+	dl.mu.Unlock()
+	dl.processDownload(req)
+
+	status, err := dl.GetStatus("PREMIERE")
+	require.NoError(t, err)
+	assert.Equal(t, StatusUpcoming, status.Status)
+	assert.ErrorIs(t, status.Error, ErrUpcoming)
+	assert.False(t, status.Live, "premieres are downloaded once played")
+	assert.WithinDuration(t, time.Unix(release, 0).Add(10*time.Minute+upcomingMargin), status.ScheduledAt, 5*time.Second)
+	assert.Empty(t, dl.ListFailed(), "upcoming videos are not failures")
+	assert.Len(t, dl.ListUpcoming(), 1)
+
+	// Requests while waiting report the video as upcoming
+	err = dl.QueueWithMaxRes(context.Background(), "PREMIERE", req.VideoURL, models.DownloadFormatMP4, 0)
+	assert.ErrorIs(t, err, ErrUpcoming)
+
+	// Once due, the video is queued again
+	dl.SetOffline(true)
+	dl.queueUpcoming("PREMIERE")
+	status, err = dl.GetStatus("PREMIERE")
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, status.Status)
+	assert.Empty(t, dl.ListUpcoming())
+}
@@ -0,0 +1,18 @@
+package downloader
+
+import (
+	"context"
+
+	"vrcvideocacher/pkg/models"
+)
+
+// upgradeDir is where upgrades are downloaded, relative to the cache
+// directory, unless downloadTempPath is set
+const upgradeDir = ".upgrade"
+
+// QueueUpgrade queues a download of a cached video at a higher resolution
+// limit. The cached file is served until the download has finished and
+// replaces it
+func (d *Downloader) QueueUpgrade(ctx context.Context, videoID, videoURL string, format models.DownloadFormat, maxRes int) error {
+	return d.enqueue(ctx, videoID, videoURL, format, maxRes, false, true)
+}
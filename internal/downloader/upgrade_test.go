@@ -0,0 +1,44 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/pkg/models"
+)
+
+func TestQueueUpgrade(t *testing.T) {
+	dl, cacheMgr := newFakeDownloader(t)
+	ctx := context.Background()
+
+	require.NoError(t, dl.QueueWithMaxRes(ctx, "abc123", "https://www.youtube.com/watch?v=abc123", models.DownloadFormatMP4, 480))
+	require.Equal(t, StatusCompleted, waitFinished(t, dl, "abc123").Status)
+	entry, err := cacheMgr.GetEntry("abc123")
+	require.NoError(t, err)
+	assert.Equal(t, 480, entry.MaxRes)
+	require.NoError(t, cacheMgr.AddAlias("https://youtu.be/abc123", "abc123"))
+
+	// Cached videos are not queued again, unless upgraded
+	require.NoError(t, dl.QueueWithMaxRes(ctx, "abc123", "https://www.youtube.com/watch?v=abc123", models.DownloadFormatMP4, 1080))
+	assert.Zero(t, dl.GetQueueLength())
+
+	require.NoError(t, dl.QueueUpgrade(ctx, "abc123", "https://www.youtube.com/watch?v=abc123", models.DownloadFormatMP4, 1080))
+	require.Eventually(t, func() bool {
+		entry, err := cacheMgr.GetEntry("abc123")
+		return err == nil && entry.MaxRes == 1080
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// The new file replaced the old one in place, downloaded next to the cache
+	entry, err = cacheMgr.GetEntry("abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123.mp4", entry.FileName)
+	assert.Equal(t, []string{"https://youtu.be/abc123"}, entry.Aliases)
+	left, _ := os.ReadDir(filepath.Join(cacheMgr.GetCachePath(), upgradeDir))
+	assert.Empty(t, left)
+}
@@ -0,0 +1,44 @@
+package downloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"vrcvideocacher/internal/cache"
+)
+
+// videoInfoTemplate has yt-dlp print the title and height of a video once
+// its file is in place, recorded in the cache index
+const videoInfoTemplate = "after_move:%(.{title,height})j"
+
+// videoInfoPath returns the file yt-dlp prints the video info of req to
+// The leading dot keeps it from being taken for the downloaded file
+func videoInfoPath(dir string, req *DownloadRequest) string {
+	return filepath.Join(dir, "."+req.VideoID+".info")
+}
+
+// readVideoInfo reads and removes the video info printed to path. Info that
+// is missing, e.g. from a yt-dlp too old to print it, is left empty
+func readVideoInfo(path, videoURL string) cache.VideoInfo {
+	info := cache.VideoInfo{URL: videoURL}
+
+	data, err := os.ReadFile(path)
+	os.Remove(path)
+	if err != nil {
+		return info
+	}
+
+	// yt-dlp appends, so a retried download printed more than once
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var printed struct {
+		Title  string `json:"title"`
+		Height int    `json:"height"`
+	}
+	if json.Unmarshal([]byte(lines[len(lines)-1]), &printed) == nil {
+		info.Title = printed.Title
+		info.Resolution = printed.Height
+	}
+	return info
+}
@@ -0,0 +1,111 @@
+// Package events is an in-process bus the downloader, cache and patcher
+// publish to, streamed to frontends over the /ws endpoint so they do not
+// have to poll
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	DownloadQueued    = "download:queued"
+	DownloadStarted   = "download:started"
+	DownloadProgress  = "download:progress"
+	DownloadCompleted = "download:completed"
+	DownloadFailed    = "download:failed"
+	DownloadCancelled = "download:cancelled"
+	CacheEvicted      = "cache:evicted"
+	PatchStatus       = "patch:status"
+)
+
+// Patch statuses reported in PatchStatus events
+const (
+	PatchPatched  = "patched"
+	PatchFailed   = "failed"
+	PatchRestored = "restored"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// further events are dropped for it
+const subscriberBuffer = 64
+
+// Event is one published event
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data,omitempty"`
+}
+
+// Download is the data of download events
+type Download struct {
+	VideoID   string  `json:"videoId"`
+	VideoURL  string  `json:"videoUrl"`
+	RequestID string  `json:"requestId,omitempty"`
+	Progress  float64 `json:"progress"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Eviction is the data of CacheEvicted events
+type Eviction struct {
+	ID       string `json:"id"`
+	FileName string `json:"fileName"`
+	Size     int64  `json:"size"`
+}
+
+// Patch is the data of PatchStatus events
+type Patch struct {
+	Path   string `json:"path"`
+	Target string `json:"target,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Bus delivers published events to every subscriber
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[chan Event]struct{}
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{subs: make(map[chan Event]struct{})}
+}
+
+// Publish sends an event to every subscriber without blocking; subscribers
+// that fell behind miss it. Publishing to a nil bus does nothing
+func (b *Bus) Publish(typ string, data any) {
+	if b == nil {
+		return
+	}
+
+	event := Event{Type: typ, Time: time.Now(), Data: data}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the events published from now on,
+// and a function ending the subscription that closes the channel
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	bus.Publish(DownloadQueued, nil) // No subscribers yet
+
+	a, unsubscribeA := bus.Subscribe()
+	b, unsubscribeB := bus.Subscribe()
+	defer unsubscribeB()
+
+	bus.Publish(CacheEvicted, Eviction{ID: "abc", Size: 42})
+	for _, ch := range []<-chan Event{a, b} {
+		event := <-ch
+		assert.Equal(t, CacheEvicted, event.Type)
+		assert.Equal(t, Eviction{ID: "abc", Size: 42}, event.Data)
+		assert.False(t, event.Time.IsZero())
+	}
+
+	unsubscribeA()
+	unsubscribeA()
+	_, ok := <-a
+	assert.False(t, ok)
+	bus.Publish(PatchStatus, nil)
+	require.Len(t, b, 1)
+}
+
+func TestBusDropsForSlowSubscribers(t *testing.T) {
+	bus := NewBus()
+	ch, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer+10; i++ {
+		bus.Publish(DownloadProgress, i)
+	}
+	assert.Len(t, ch, subscriberBuffer)
+	assert.Equal(t, 0, (<-ch).Data)
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	assert.NotPanics(t, func() { bus.Publish(DownloadQueued, nil) })
+}
@@ -0,0 +1,117 @@
+// Package fakeytdlp builds a fake yt-dlp for tests, so the downloader, queue
+// and API can be exercised end to end without network access
+//
+// The fake picks its behavior from the video ID (the v parameter or last
+// path element of the URL) by prefix:
+//
+//   - fail: exits with "Video unavailable"
+//   - ratelimit: exits with a "Sign in to confirm you're not a bot" error
+//   - network: exits with a name resolution error
+//   - drm: exits with a DRM protection error
+//   - members: exits with a members-only error
+//   - premiere: an upcoming premiere, probes report a release time
+//   - live: probes report a live stream with an HLS manifest URL
+//   - large: probes report a 4 GiB file
+//   - slow: downloads pause 200ms per progress step
+//
+// Any other ID downloads: the fake prints yt-dlp style progress lines,
+// writes a small file to the -o path and prints the title "Fake video <id>"
+// and height 360 to the --print-to-file path. FAKE_YTDLP_DELAY (a
+// time.Duration) overrides the pause per progress step and FAKE_YTDLP_LOG
+// names a file the arguments of each invocation are appended to
+package fakeytdlp
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Env variables read by the fake
+const (
+	EnvDelay = "FAKE_YTDLP_DELAY"
+	EnvLog   = "FAKE_YTDLP_LOG"
+)
+
+var (
+	buildOnce sync.Once
+	buildDir  string
+	buildPath string
+	buildErr  error
+)
+
+// Build compiles the fake yt-dlp once per test binary and returns its path
+// The test is skipped if the Go toolchain is not available
+func Build(t testing.TB) string {
+	t.Helper()
+
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("Requires the Go toolchain")
+	}
+
+	buildOnce.Do(func() {
+		buildDir, buildErr = os.MkdirTemp("", "fake-yt-dlp-")
+		if buildErr != nil {
+			return
+		}
+
+		name := "yt-dlp"
+		if runtime.GOOS == "windows" {
+			name += ".exe"
+		}
+		buildPath = filepath.Join(buildDir, name)
+
+		cmd := exec.Command(goPath, "build", "-o", buildPath, ".")
+		cmd.Dir = sourceDir()
+		if out, err := cmd.CombinedOutput(); err != nil {
+			buildErr = &buildError{err: err, output: strings.TrimSpace(string(out))}
+		}
+	})
+
+	if buildErr != nil {
+		t.Fatalf("Failed to build fake yt-dlp: %v", buildErr)
+	}
+	return buildPath
+}
+
+// Log sets FAKE_YTDLP_LOG for the test and returns a function reading the
+// logged invocations, one argument line per call
+func Log(t testing.TB) func() []string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "yt-dlp.log")
+	t.Setenv(EnvLog, path)
+
+	return func() []string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+// sourceDir returns the directory of the fake's main package
+func sourceDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata")
+}
+
+// buildError carries the compiler output of a failed build
+type buildError struct {
+	err    error
+	output string
+}
+
+func (e *buildError) Error() string {
+	return e.err.Error() + ": " + e.output
+}
+
+func (e *buildError) Unwrap() error {
+	return e.err
+}
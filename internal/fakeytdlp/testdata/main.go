@@ -0,0 +1,181 @@
+// Command fake-yt-dlp stands in for yt-dlp in tests. It downloads nothing:
+// the video ID selects a behavior (see package fakeytdlp), "-j" prints
+// metadata and "-o" writes a small file after yt-dlp style progress output,
+// followed by the title and height to the "--print-to-file" file
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// stepDelay is the default pause between progress lines
+const stepDelay = 10 * time.Millisecond
+
+func main() {
+	args := os.Args[1:]
+	if logPath := os.Getenv("FAKE_YTDLP_LOG"); logPath != "" {
+		if f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+			fmt.Fprintln(f, strings.Join(args, " "))
+			f.Close()
+		}
+	}
+
+	if len(args) == 1 && args[0] == "--version" {
+		fmt.Println("2025.01.01-fake")
+		return
+	}
+	if len(args) == 0 {
+		fail("ERROR: You must provide at least one URL.")
+	}
+
+	id := videoID(args[len(args)-1])
+	mode := strings.ToLower(id)
+
+	switch {
+	case strings.HasPrefix(mode, "fail"):
+		fail("ERROR: [youtube] %s: Video unavailable", id)
+	case strings.HasPrefix(mode, "ratelimit"):
+		fail("ERROR: [youtube] %s: Sign in to confirm you're not a bot", id)
+	case strings.HasPrefix(mode, "network"):
+		fail("ERROR: [youtube] %s: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>", id)
+	case strings.HasPrefix(mode, "drm"):
+		fail("ERROR: [youtube] %s: This video is DRM protected", id)
+	case strings.HasPrefix(mode, "members"):
+		fail("ERROR: [youtube] %s: Join this channel to get access to members-only content like this video, and other exclusive perks.", id)
+	case strings.HasPrefix(mode, "premiere") && !(has(args, "-j") && has(args, "--ignore-no-formats-error")):
+		fail("ERROR: [youtube] %s: Premieres in 2 hours", id)
+	}
+
+	if has(args, "-j") {
+		printInfo(id, mode)
+		return
+	}
+
+	output := value(args, "-o")
+	if output == "" {
+		fail("ERROR: fake-yt-dlp only supports -j and -o")
+	}
+	download(id, mode, output)
+
+	if i := index(args, "--print-to-file"); i >= 0 && i+2 < len(args) {
+		printToFile(id, args[i+2])
+	}
+}
+
+// printInfo prints the `yt-dlp -j` metadata of a video
+func printInfo(id, mode string) {
+	info := map[string]any{
+		"id":          id,
+		"title":       "Fake video " + id,
+		"duration":    60,
+		"filesize":    1 << 20,
+		"url":         "https://rr1.googlevideo.com/videoplayback?id=" + id,
+		"is_live":     false,
+		"live_status": "not_live",
+		"webpage_url": "https://www.youtube.com/watch?v=" + id,
+	}
+	switch {
+	case strings.HasPrefix(mode, "live"):
+		info["is_live"] = true
+		info["live_status"] = "is_live"
+		info["url"] = "https://manifest.googlevideo.com/api/manifest/hls_playlist/id/" + id + "/index.m3u8"
+		delete(info, "duration")
+		delete(info, "filesize")
+	case strings.HasPrefix(mode, "premiere"):
+		info["live_status"] = "is_upcoming"
+		info["release_timestamp"] = time.Now().Add(2 * time.Hour).Unix()
+		delete(info, "url")
+		delete(info, "filesize")
+	case strings.HasPrefix(mode, "large"):
+		info["filesize"] = 4 << 30
+	}
+	json.NewEncoder(os.Stdout).Encode(info)
+}
+
+// download prints progress like yt-dlp and writes the output file
+func download(id, mode, output string) {
+	delay := stepDelay
+	if strings.HasPrefix(mode, "slow") {
+		delay = 200 * time.Millisecond
+	}
+	if v := os.Getenv("FAKE_YTDLP_DELAY"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			delay = d
+		}
+	}
+
+	fmt.Printf("[youtube] %s: Downloading webpage\n", id)
+	fmt.Printf("[info] %s: Downloading 1 format(s): 18\n", id)
+	fmt.Printf("[download] Destination: %s\n", output)
+	for pct := 0; pct <= 100; pct += 25 {
+		fmt.Printf("[download] %5.1f%% of    1.00MiB at    1.00MiB/s ETA 00:01\n", float64(pct))
+		if pct < 100 {
+			time.Sleep(delay)
+		}
+	}
+
+	if err := os.WriteFile(output, []byte("fake video "+id+"\n"), 0644); err != nil {
+		fail("ERROR: unable to write %s: %v", output, err)
+	}
+}
+
+// printToFile appends the title and height of a video to path, like
+// `--print-to-file after_move:%(.{title,height})j`
+func printToFile(id, path string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fail("ERROR: unable to open %s: %v", path, err)
+	}
+	defer f.Close()
+	json.NewEncoder(f).Encode(map[string]any{"title": "Fake video " + id, "height": 360})
+}
+
+// videoID returns the ID of a YouTube URL: the v parameter or the last path
+// element
+func videoID(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if v := u.Query().Get("v"); v != "" {
+		return v
+	}
+	return path.Base(u.Path)
+}
+
+// has reports whether flag is among args
+func has(args []string, flag string) bool {
+	return index(args, flag) >= 0
+}
+
+// index returns the position of flag in args, or -1
+func index(args []string, flag string) int {
+	for i, a := range args {
+		if a == flag {
+			return i
+		}
+	}
+	return -1
+}
+
+// value returns the argument following flag, or ""
+func value(args []string, flag string) string {
+	for i, a := range args[:len(args)-1] {
+		if a == flag {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// fail prints an error like yt-dlp and exits with status 1
+func fail(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
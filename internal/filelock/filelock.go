@@ -0,0 +1,82 @@
+// Package filelock provides locks shared between processes, so the desktop
+// app and the CLI never replace the same binary at once or run it while it
+// is being replaced
+package filelock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLocked is returned by TryLock when another process holds the lock
+var ErrLocked = errors.New("locked by another process")
+
+// pollInterval is how often Acquire retries a held lock
+const pollInterval = 100 * time.Millisecond
+
+// Lock is a held lock, released by Unlock or when the process exits
+type Lock struct {
+	f *os.File
+}
+
+// PathFor returns the lock file guarding file, e.g. a binary
+func PathFor(file string) string {
+	return file + ".lock"
+}
+
+// TryLock takes the lock at path without waiting, creating the lock file
+// if needed. It returns ErrLocked if another process holds it
+func TryLock(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock %s: %w", path, err)
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Lock{f: f}, nil
+}
+
+// Acquire takes the lock at path, waiting until it is free or ctx is done
+func Acquire(ctx context.Context, path string) (*Lock, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		lock, err := TryLock(path)
+		if !errors.Is(err, ErrLocked) {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %s", err, context.Cause(ctx))
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wait blocks until nobody holds the lock at path or ctx is done, e.g. to
+// run a binary only after it has been replaced
+func Wait(ctx context.Context, path string) error {
+	lock, err := Acquire(ctx, path)
+	if err != nil {
+		return err
+	}
+	return lock.Unlock()
+}
+
+// Unlock releases the lock. The lock file stays, removing it would let
+// two processes lock different files of the same name
+func (l *Lock) Unlock() error {
+	if err := unlockFile(l.f); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,46 @@
+package filelock
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryLock(t *testing.T) {
+	path := PathFor(filepath.Join(t.TempDir(), "yt-dlp"))
+
+	lock, err := TryLock(path)
+	require.NoError(t, err)
+
+	// Locks are held per open file, so a second open behaves like another
+	// process
+	_, err = TryLock(path)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, lock.Unlock())
+	lock, err = TryLock(path)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+}
+
+func TestAcquire(t *testing.T) {
+	path := PathFor(filepath.Join(t.TempDir(), "yt-dlp"))
+
+	lock, err := TryLock(path)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*pollInterval)
+	defer cancel()
+	_, err = Acquire(ctx, path)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// Waiting ends once the holder releases the lock
+	time.AfterFunc(2*pollInterval, func() { lock.Unlock() })
+	start := time.Now()
+	require.NoError(t, Wait(context.Background(), path))
+	assert.GreaterOrEqual(t, time.Since(start), pollInterval)
+}
@@ -0,0 +1,52 @@
+//go:build windows
+
+package filelock
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+// lockFile takes an exclusive lock on the first byte of f without blocking
+func lockFile(f *os.File) error {
+	var overlapped syscall.Overlapped
+	ret, _, callErr := procLockFileEx.Call(
+		f.Fd(),
+		lockfileExclusiveLock|lockfileFailImmediately,
+		0,
+		1,
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if ret == 0 {
+		if callErr == errorLockViolation {
+			return ErrLocked
+		}
+		return fmt.Errorf("LockFileEx failed: %w", callErr)
+	}
+	return nil
+}
+
+// unlockFile releases the lock on f
+func unlockFile(f *os.File) error {
+	var overlapped syscall.Overlapped
+	ret, _, callErr := procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if ret == 0 {
+		return fmt.Errorf("UnlockFileEx failed: %w", callErr)
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+package ghrelease
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrChecksumMismatch is returned when a download does not match its checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// githubOrigin is the origin of release asset downloads, replaced by mirrors
+const githubOrigin = "https://github.com"
+
+// checksumAssets are the checksum files looked up in releases, in order
+var checksumAssets = []string{"SHA2-256SUMS", "SHA256SUMS", "checksums.txt"}
+
+// HTTPClient is the HTTP client used for API and download requests, e.g. a
+// github.Client or a mock for testing
+type HTTPClient interface {
+	Get(url string) (*http.Response, error)
+}
+
+// ContextClient is an HTTPClient whose requests can be cancelled, e.g. a
+// github.Client. Requests of other clients, except *http.Client, are only
+// checked for cancellation before they are made
+type ContextClient interface {
+	GetContext(ctx context.Context, url string) (*http.Response, error)
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	Size               int64  `json:"size"`
+}
+
+// Release is a GitHub release
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Name    string  `json:"name"`
+	Assets  []Asset `json:"assets"`
+	Body    string  `json:"body"`
+}
+
+// Selector is an asset selection strategy
+type Selector func(Asset) bool
+
+// ByName selects the asset with the given name
+func ByName(name string) Selector {
+	return func(a Asset) bool {
+		return a.Name == name
+	}
+}
+
+// ByNames selects assets by name, in order of preference
+func ByNames(names ...string) []Selector {
+	selectors := make([]Selector, len(names))
+	for i, name := range names {
+		selectors[i] = ByName(name)
+	}
+	return selectors
+}
+
+// ByMarker selects assets whose name contains marker and ends with suffix,
+// for names carrying a version such as aria2-1.37.0-win-64bit-build1.zip
+func ByMarker(marker, suffix string) Selector {
+	return func(a Asset) bool {
+		return strings.Contains(a.Name, marker) && strings.HasSuffix(a.Name, suffix)
+	}
+}
+
+// Find returns the first asset matching the first selector that matches any
+// asset, so later selectors act as fallbacks
+func (r *Release) Find(selectors ...Selector) (Asset, bool) {
+	for _, sel := range selectors {
+		for _, asset := range r.Assets {
+			if sel(asset) {
+				return asset, true
+			}
+		}
+	}
+	return Asset{}, false
+}
+
+// LatestURL returns the latest release API URL of a repository ("owner/name")
+func LatestURL(repo string) string {
+	return fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo)
+}
+
+// Progress reports download progress; total is -1 if unknown
+type Progress func(done, total int64)
+
+// DownloadOptions control Download
+type DownloadOptions struct {
+	// Name describes the download in errors, e.g. "update"
+	Name string
+	// SHA256 is the expected hex checksum (empty skips verification)
+	SHA256 string
+	// Progress is called as data arrives
+	Progress Progress
+}
+
+// Client fetches releases and their assets
+type Client struct {
+	http HTTPClient
+
+	mu      sync.RWMutex
+	mirrors []string
+}
+
+// NewClient creates a release client using the given HTTP client
+func NewClient(client HTTPClient) *Client {
+	return &Client{http: client}
+}
+
+// SetMirrors sets the mirrors tried in order before GitHub for release asset
+// downloads. A mirror is a base URL replacing https://github.com, e.g.
+// https://mirror.example.com/github serves
+// https://github.com/yt-dlp/yt-dlp/releases/download/... as
+// https://mirror.example.com/github/yt-dlp/yt-dlp/releases/download/...
+func (c *Client) SetMirrors(mirrors []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mirrors = slices.Clone(mirrors)
+}
+
+// sources returns the URLs to try for a download: the mirrors, then the
+// original URL. Only GitHub downloads are mirrored
+func (c *Client) sources(url string) []string {
+	path, ok := strings.CutPrefix(url, githubOrigin+"/")
+	if !ok {
+		return []string{url}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	sources := make([]string, 0, len(c.mirrors)+1)
+	for _, mirror := range c.mirrors {
+		sources = append(sources, strings.TrimSuffix(mirror, "/")+"/"+path)
+	}
+	return append(sources, url)
+}
+
+// trySources calls fn with each source of url until one succeeds, so a
+// mirror that fails or serves a file not matching the checksum falls through
+// to the next. Cancellation stops at the current source
+func (c *Client) trySources(ctx context.Context, url string, fn func(src string) error) error {
+	sources := c.sources(url)
+	if len(sources) == 1 {
+		return fn(url)
+	}
+
+	var errs []error
+	for _, src := range sources {
+		err := fn(src)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", src, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// get issues a GET request tied to ctx
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	switch client := c.http.(type) {
+	case ContextClient:
+		return client.GetContext(ctx, url)
+	case *http.Client:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		return client.Do(req)
+	default:
+		return c.http.Get(url)
+	}
+}
+
+// Latest fetches the release at a latest release API URL
+func (c *Client) Latest(ctx context.Context, apiURL string) (*Release, error) {
+	resp, err := c.get(ctx, apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse release info: %w", err)
+	}
+	return &release, nil
+}
+
+// Checksum returns the SHA-256 of the named asset from the release's
+// checksum file, or "" if the release has none or it does not list the asset
+func (c *Client) Checksum(ctx context.Context, release *Release, name string) (string, error) {
+	selectors := make([]Selector, len(checksumAssets))
+	for i, sumsName := range checksumAssets {
+		selectors[i] = ByName(sumsName)
+	}
+
+	sums, ok := release.Find(selectors...)
+	if !ok {
+		return "", nil
+	}
+
+	data, err := c.Fetch(ctx, sums.BrowserDownloadURL, DownloadOptions{Name: sums.Name})
+	if err != nil {
+		return "", err
+	}
+
+	// sha256sum format: "<hex>  <name>", with "*" marking binary mode
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", nil
+}
+
+// Fetch downloads a small file into memory, verifying its checksum if given
+func (c *Client) Fetch(ctx context.Context, url string, opts DownloadOptions) ([]byte, error) {
+	var data []byte
+	err := c.trySources(ctx, url, func(src string) error {
+		var buf bytes.Buffer
+		if err := c.download(ctx, src, &buf, opts); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Download downloads a file to dst through a temporary file, so dst is only
+// replaced by a complete download with the expected checksum. The file is
+// made executable
+func (c *Client) Download(ctx context.Context, url, dst string, opts DownloadOptions) error {
+	tmpPath := dst + ".tmp"
+	err := c.trySources(ctx, url, func(src string) error {
+		out, err := os.Create(tmpPath)
+		if err != nil {
+			return fmt.Errorf("failed to create temp file: %w", err)
+		}
+		defer out.Close()
+		return c.download(ctx, src, out, opts)
+	})
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to make executable: %w", err)
+	}
+
+	// Replace the old file; Windows cannot rename over it
+	if _, err := os.Stat(dst); err == nil {
+		if err := os.Remove(dst); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to remove old file: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
+	return nil
+}
+
+// download copies a URL to w, reporting progress and verifying the checksum
+func (c *Client) download(ctx context.Context, url string, w io.Writer, opts DownloadOptions) error {
+	name := opts.Name
+	if name == "" {
+		name = "file"
+	}
+
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	hash := sha256.New()
+	var r io.Reader = io.TeeReader(resp.Body, hash)
+	if opts.Progress != nil {
+		total := resp.ContentLength
+		if total <= 0 {
+			total = -1
+		}
+		r = &progressReader{r: r, total: total, progress: opts.Progress}
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	if opts.SHA256 != "" {
+		if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(actual, opts.SHA256) {
+			return fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, name, opts.SHA256, actual)
+		}
+	}
+	return nil
+}
+
+// progressReader reports the bytes read through it
+type progressReader struct {
+	r        io.Reader
+	done     int64
+	total    int64
+	progress Progress
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.done += int64(n)
+		p.progress(p.done, p.total)
+	}
+	return n, err
+}
+
+// VerifyFile checks the SHA-256 of a file
+func VerifyFile(path, expected string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expected, actual)
+	}
+	return nil
+}
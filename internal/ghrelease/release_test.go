@@ -0,0 +1,201 @@
+package ghrelease
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient serves fixed bodies by URL
+type fakeClient map[string][]byte
+
+func (f fakeClient) Get(url string) (*http.Response, error) {
+	body, ok := f[url]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil
+	}
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		ContentLength: int64(len(body)),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestReleaseFind(t *testing.T) {
+	release := &Release{Assets: []Asset{
+		{Name: "yt-dlp"},
+		{Name: "aria2-1.37.0-win-64bit-build1.zip"},
+	}}
+
+	asset, ok := release.Find(ByName("yt-dlp.exe"), ByName("yt-dlp"))
+	require.True(t, ok)
+	assert.Equal(t, "yt-dlp", asset.Name)
+
+	asset, ok = release.Find(ByMarker("-win-64bit-", ".zip"))
+	require.True(t, ok)
+	assert.Equal(t, "aria2-1.37.0-win-64bit-build1.zip", asset.Name)
+
+	asset, ok = release.Find(ByNames("yt-dlp_arm64.exe", "yt-dlp")...)
+	require.True(t, ok)
+	assert.Equal(t, "yt-dlp", asset.Name)
+
+	_, ok = release.Find(ByName("missing"))
+	assert.False(t, ok)
+}
+
+func TestDownloadWithChecksum(t *testing.T) {
+	binary := []byte("yt-dlp binary")
+	release := Release{
+		TagName: "2024.01.01",
+		Assets: []Asset{
+			{Name: "yt-dlp.exe", BrowserDownloadURL: "https://example.com/yt-dlp.exe"},
+			{Name: "SHA2-256SUMS", BrowserDownloadURL: "https://example.com/SHA2-256SUMS"},
+		},
+	}
+	releaseJSON, _ := json.Marshal(release)
+	http := fakeClient{
+		LatestURL("yt-dlp/yt-dlp"):         releaseJSON,
+		"https://example.com/yt-dlp.exe":   binary,
+		"https://example.com/SHA2-256SUMS": []byte(sha256Hex([]byte("other")) + "  yt-dlp\n" + sha256Hex(binary) + "  yt-dlp.exe\n"),
+	}
+	client := NewClient(http)
+
+	latest, err := client.Latest(context.Background(), LatestURL("yt-dlp/yt-dlp"))
+	require.NoError(t, err)
+	assert.Equal(t, "2024.01.01", latest.TagName)
+
+	asset, _ := latest.Find(ByName("yt-dlp.exe"))
+	checksum, err := client.Checksum(context.Background(), latest, asset.Name)
+	require.NoError(t, err)
+	assert.Equal(t, sha256Hex(binary), checksum)
+
+	var reported int64
+	dst := filepath.Join(t.TempDir(), "yt-dlp.exe")
+	require.NoError(t, os.WriteFile(dst, []byte("old"), 0755))
+	require.NoError(t, client.Download(context.Background(), asset.BrowserDownloadURL, dst, DownloadOptions{
+		SHA256:   checksum,
+		Progress: func(done, total int64) { reported = done },
+	}))
+
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, binary, data)
+	assert.Equal(t, int64(len(binary)), reported)
+	require.NoError(t, VerifyFile(dst, checksum))
+
+	// A corrupted download leaves the installed file alone
+	http["https://example.com/yt-dlp.exe"] = []byte("tampered")
+	err = client.Download(context.Background(), asset.BrowserDownloadURL, dst, DownloadOptions{SHA256: checksum})
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	data, _ = os.ReadFile(dst)
+	assert.Equal(t, binary, data)
+	assert.NoFileExists(t, dst+".tmp")
+}
+
+func TestLatestErrors(t *testing.T) {
+	client := NewClient(fakeClient{"https://api.github.com/bad": []byte("not json")})
+
+	_, err := client.Latest(context.Background(), "https://api.github.com/missing")
+	assert.ErrorContains(t, err, "status 404")
+
+	_, err = client.Latest(context.Background(), "https://api.github.com/bad")
+	assert.ErrorContains(t, err, "failed to parse release info")
+}
+
+func TestChecksumWithoutSums(t *testing.T) {
+	client := NewClient(fakeClient{})
+
+	checksum, err := client.Checksum(context.Background(), &Release{Assets: []Asset{{Name: "app.exe"}}}, "app.exe")
+	require.NoError(t, err)
+	assert.Empty(t, checksum)
+}
+
+func TestDownloadMirrors(t *testing.T) {
+	binary := []byte("yt-dlp binary")
+	http := fakeClient{
+		// The first mirror is missing the file, the second serves a corrupt one
+		"https://bad.example.com/github/yt-dlp/yt-dlp/releases/download/1/yt-dlp.exe": []byte("corrupt"),
+		"https://good.example.com/yt-dlp/yt-dlp/releases/download/1/yt-dlp.exe":       binary,
+	}
+	client := NewClient(http)
+	client.SetMirrors([]string{
+		"https://missing.example.com",
+		"https://bad.example.com/github",
+		"https://good.example.com/",
+	})
+
+	dst := filepath.Join(t.TempDir(), "yt-dlp.exe")
+	err := client.Download(context.Background(), "https://github.com/yt-dlp/yt-dlp/releases/download/1/yt-dlp.exe", dst, DownloadOptions{
+		SHA256: sha256Hex(binary),
+	})
+	require.NoError(t, err)
+	data, _ := os.ReadFile(dst)
+	assert.Equal(t, binary, data)
+
+	// GitHub itself is tried last
+	http["https://github.com/aria2/aria2/releases/download/1/aria2.zip"] = []byte("zip")
+	data, err = client.Fetch(context.Background(), "https://github.com/aria2/aria2/releases/download/1/aria2.zip", DownloadOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("zip"), data)
+
+	// Every source failing reports each of them
+	_, err = client.Fetch(context.Background(), "https://github.com/none/none/releases/download/1/none", DownloadOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "https://missing.example.com/none/none/releases/download/1/none")
+	assert.Contains(t, err.Error(), "https://github.com/none/none/releases/download/1/none")
+
+	// Non-GitHub URLs are not mirrored
+	assert.Equal(t, []string{"https://example.com/file"}, client.sources("https://example.com/file"))
+}
+
+func TestDownloadCancelled(t *testing.T) {
+	// A cancelled context stops before the request is made
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client := NewClient(fakeClient{"https://example.com/file": []byte("data")})
+	dst := filepath.Join(t.TempDir(), "file")
+	err := client.Download(ctx, "https://example.com/file", dst, DownloadOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoFileExists(t, dst)
+	assert.NoFileExists(t, dst+".tmp")
+
+	// Cancelling aborts a stalled download of an *http.Client
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	var once sync.Once
+	client = NewClient(server.Client())
+	err = client.Download(ctx, server.URL, dst, DownloadOptions{
+		Progress: func(done, total int64) { once.Do(cancel) },
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoFileExists(t, dst)
+}
@@ -0,0 +1,238 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// APIHost is the host of the GitHub REST API
+	APIHost = "api.github.com"
+
+	// CacheFileName is the conditional request cache in the data directory
+	CacheFileName = "github-cache.json"
+
+	// requestTimeout bounds each request
+	requestTimeout = 30 * time.Second
+
+	// maxRateLimitWait is the longest the client waits for a rate limit to
+	// reset before giving up
+	maxRateLimitWait = time.Minute
+
+	// maxAPIResponse bounds API responses held in memory and in the cache
+	maxAPIResponse = 4 << 20
+)
+
+// ErrRateLimited is returned when the API rate limit does not reset soon
+var ErrRateLimited = errors.New("GitHub API rate limit exceeded")
+
+// Client is an HTTP client that is gentle with the GitHub API
+// API requests are made conditional on the ETag or Last-Modified of the
+// previous response, so unchanged releases cost no rate limit, carry the
+// token if one is set, and wait out short rate limits. Other requests (e.g.
+// release asset downloads) are passed through unchanged. Client implements
+// ghrelease.HTTPClient and ghrelease.ContextClient
+type Client struct {
+	http      *http.Client
+	token     string
+	cachePath string
+	apiHost   string
+	maxWait   time.Duration
+	sleep     func(time.Duration)
+	now       func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedResponse
+}
+
+// cachedResponse is the last successful response of an API URL
+type cachedResponse struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Body         []byte `json:"body"`
+}
+
+// NewClient creates a client authenticating with token (optional) and
+// keeping the conditional request cache at cachePath (empty keeps it in
+// memory only)
+func NewClient(token, cachePath string) *Client {
+	c := &Client{
+		http:      &http.Client{Timeout: requestTimeout},
+		token:     token,
+		cachePath: cachePath,
+		apiHost:   APIHost,
+		maxWait:   maxRateLimitWait,
+		sleep:     time.Sleep,
+		now:       time.Now,
+		cache:     make(map[string]cachedResponse),
+	}
+	c.loadCache()
+	return c
+}
+
+// Get issues a GET request
+func (c *Client) Get(rawURL string) (*http.Response, error) {
+	return c.GetContext(context.Background(), rawURL)
+}
+
+// GetContext issues a GET request cancelled with ctx
+func (c *Client) GetContext(ctx context.Context, rawURL string) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host != c.apiHost {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		return c.http.Do(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.getAPI(ctx, rawURL)
+		if err != nil {
+			return nil, err
+		}
+
+		wait, limited := rateLimitWait(resp, c.now())
+		if !limited {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if attempt > 0 || wait > c.maxWait {
+			return nil, fmt.Errorf("%w, retry after %s", ErrRateLimited, wait.Round(time.Second))
+		}
+		c.sleep(wait)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// getAPI makes a conditional API request, answering 304 Not Modified from
+// the cache
+func (c *Client) getAPI(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	c.mu.Lock()
+	cached, ok := c.cache[rawURL]
+	c.mu.Unlock()
+	if ok {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && ok:
+		resp.Body.Close()
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Body = io.NopCloser(bytes.NewReader(cached.Body))
+		return resp, nil
+
+	case resp.StatusCode == http.StatusOK:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxAPIResponse))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			c.store(rawURL, cachedResponse{ETag: etag, LastModified: lastModified, Body: body})
+		}
+		return resp, nil
+	}
+
+	return resp, nil
+}
+
+// rateLimitWait returns how long to wait before retrying a rate limited
+// response, from Retry-After or X-RateLimit-Reset
+func rateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		// A plain 403, e.g. a bad token
+		return 0, false
+	}
+
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return max(time.Unix(reset, 0).Sub(now), 0), true
+}
+
+// store caches a response, saving the cache if it has a path
+func (c *Client) store(rawURL string, cached cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache[rawURL] = cached
+	if c.cachePath == "" {
+		return
+	}
+
+	data, err := json.Marshal(c.cache)
+	if err != nil {
+		return
+	}
+	os.MkdirAll(filepath.Dir(c.cachePath), 0755)
+	os.WriteFile(c.cachePath, data, 0644) // A lost cache only costs a request
+}
+
+// loadCache reads the cache saved by a previous run
+func (c *Client) loadCache() {
+	if c.cachePath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(c.cachePath)
+	if err != nil {
+		return
+	}
+
+	var cache map[string]cachedResponse
+	if err := json.Unmarshal(data, &cache); err == nil && cache != nil {
+		c.cache = cache
+	}
+}
@@ -0,0 +1,125 @@
+package github
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient returns a client treating server as the GitHub API
+func newTestClient(t *testing.T, server *httptest.Server, token, cachePath string) *Client {
+	c := NewClient(token, cachePath)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	c.apiHost = u.Host
+	c.sleep = func(time.Duration) {}
+	return c
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestClientConditionalRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"tag_name":"v1.0.0"}`))
+	}))
+	defer server.Close()
+
+	cachePath := filepath.Join(t.TempDir(), CacheFileName)
+	c := newTestClient(t, server, "secret", cachePath)
+
+	resp, err := c.Get(server.URL + "/repos/o/r/releases/latest")
+	require.NoError(t, err)
+	assert.Equal(t, `{"tag_name":"v1.0.0"}`, readBody(t, resp))
+
+	// The next run revalidates and gets the cached body
+	c = newTestClient(t, server, "secret", cachePath)
+	resp, err = c.Get(server.URL + "/repos/o/r/releases/latest")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"tag_name":"v1.0.0"}`, readBody(t, resp))
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestClientRateLimit(t *testing.T) {
+	var requests atomic.Int32
+	reset := time.Now().Add(30 * time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server, "", "")
+	var waited time.Duration
+	c.sleep = func(d time.Duration) { waited = d }
+
+	// A limit resetting soon is waited out
+	resp, err := c.Get(server.URL + "/rate")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", readBody(t, resp))
+	assert.InDelta(t, 30*time.Second, waited, float64(2*time.Second))
+
+	// A later reset fails fast
+	requests.Store(0)
+	reset = time.Now().Add(time.Hour)
+	_, err = c.Get(server.URL + "/rate")
+	assert.ErrorIs(t, err, ErrRateLimited)
+}
+
+func TestClientRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server, "", "")
+	var waits []time.Duration
+	c.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	// Retried once, then reported
+	_, err := c.Get(server.URL + "/secondary")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, []time.Duration{5 * time.Second}, waits)
+}
+
+func TestClientPassesThroughOtherHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.Write([]byte("asset"))
+	}))
+	defer server.Close()
+
+	// The token is only sent to the API
+	c := NewClient("secret", "")
+	resp, err := c.Get(server.URL + "/download/yt-dlp.exe")
+	require.NoError(t, err)
+	assert.Equal(t, "asset", readBody(t, resp))
+}
@@ -0,0 +1,245 @@
+// Package loadtest hammers a running server with getvideo and file requests
+// to find lock contention and report request latencies
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoVideos is returned when there are no video URLs to request
+var ErrNoVideos = errors.New("no videos to request")
+
+// Options control a load test
+type Options struct {
+	// BaseURL is the server address, e.g. http://127.0.0.1:8080
+	BaseURL string
+	// VideoURLs are requested through getvideo in turn. Uncached videos are
+	// queued for download like any other request
+	VideoURLs []string
+	// Concurrency is the number of clients requesting at once
+	Concurrency int
+	// Duration is how long the test runs
+	Duration time.Duration
+	// Client makes the requests (a client keeping a connection per client
+	// open if nil)
+	Client *http.Client
+}
+
+// Stats are the latencies of one kind of request
+type Stats struct {
+	Requests int           `json:"requests"`
+	Errors   int           `json:"errors"`
+	P50      time.Duration `json:"p50"`
+	P99      time.Duration `json:"p99"`
+	Max      time.Duration `json:"max"`
+}
+
+// Report is the result of a load test
+type Report struct {
+	Duration time.Duration `json:"duration"`
+	GetVideo Stats         `json:"getvideo"`
+	Files    Stats         `json:"files"`
+}
+
+// samples are the latencies recorded by one client
+type samples struct {
+	getVideo, files            []time.Duration
+	getVideoErrors, fileErrors int
+}
+
+// Run requests the videos from opts.Concurrency clients until opts.Duration
+// has passed or ctx is done. Cached URLs served by the server are fetched
+// too, so file serving is measured alongside getvideo
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if len(opts.VideoURLs) == 0 {
+		return nil, ErrNoVideos
+	}
+	base, err := url.Parse(opts.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	concurrency := max(opts.Concurrency, 1)
+	client := opts.Client
+	if client == nil {
+		// The default transport keeps two idle connections per host, so
+		// most clients would reconnect for every request
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = concurrency
+		client = &http.Client{Transport: transport}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	var next atomic.Uint64
+	results := make([]samples, concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	for i := range results {
+		wg.Add(1)
+		go func(s *samples) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				videoURL := opts.VideoURLs[int(next.Add(1)-1)%len(opts.VideoURLs)]
+				s.request(ctx, client, base, videoURL)
+			}
+		}(&results[i])
+	}
+	wg.Wait()
+
+	var all samples
+	for _, s := range results {
+		all.getVideo = append(all.getVideo, s.getVideo...)
+		all.files = append(all.files, s.files...)
+		all.getVideoErrors += s.getVideoErrors
+		all.fileErrors += s.fileErrors
+	}
+
+	return &Report{
+		Duration: time.Since(start),
+		GetVideo: newStats(all.getVideo, all.getVideoErrors),
+		Files:    newStats(all.files, all.fileErrors),
+	}, nil
+}
+
+// request makes one getvideo request and fetches the file it returns if the
+// server serves it. Requests cut off by the end of the test are not counted
+func (s *samples) request(ctx context.Context, client *http.Client, base *url.URL, videoURL string) {
+	reqURL := base.JoinPath("/api/getvideo")
+	reqURL.RawQuery = url.Values{"url": {videoURL}, "avpro": {"false"}}.Encode()
+
+	body, elapsed, err := get(ctx, client, reqURL.String())
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		s.getVideoErrors++
+		return
+	}
+	s.getVideo = append(s.getVideo, elapsed)
+
+	served, err := url.Parse(strings.TrimSpace(string(body)))
+	if err != nil || served.Port() != base.Port() || served.Path == "" {
+		return // Not cached, or served elsewhere
+	}
+
+	_, elapsed, err = get(ctx, client, base.JoinPath(served.Path).String())
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		s.fileErrors++
+		return
+	}
+	s.files = append(s.files, elapsed)
+}
+
+// get fetches rawURL, returning the body and the time until it was read
+func get(ctx context.Context, client *http.Client, rawURL string) ([]byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+	return body, elapsed, nil
+}
+
+// newStats summarizes latencies
+func newStats(latencies []time.Duration, errors int) Stats {
+	stats := Stats{Requests: len(latencies) + errors, Errors: errors}
+	if len(latencies) == 0 {
+		return stats
+	}
+
+	slices.Sort(latencies)
+	stats.P50 = percentile(latencies, 0.50)
+	stats.P99 = percentile(latencies, 0.99)
+	stats.Max = latencies[len(latencies)-1]
+	return stats
+}
+
+// percentile returns the p-th percentile of sorted latencies (nearest rank)
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// Write prints the report as a table
+func (r *Report) Write(w io.Writer) {
+	seconds := r.Duration.Seconds()
+	fmt.Fprintf(w, "Duration: %s\n\n", r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(w, "%-10s %10s %8s %10s %10s %10s %10s\n", "", "requests", "errors", "req/s", "p50", "p99", "max")
+	for _, row := range []struct {
+		name  string
+		stats Stats
+	}{{"getvideo", r.GetVideo}, {"files", r.Files}} {
+		fmt.Fprintf(w, "%-10s %10d %8d %10.1f %10s %10s %10s\n",
+			row.name,
+			row.stats.Requests,
+			row.stats.Errors,
+			float64(row.stats.Requests)/seconds,
+			row.stats.P50.Round(time.Microsecond),
+			row.stats.P99.Round(time.Microsecond),
+			row.stats.Max.Round(time.Microsecond),
+		)
+	}
+}
+
+// CachedVideos returns watch URLs of up to limit videos in the server's
+// cache, for load testing cache hits
+func CachedVideos(ctx context.Context, client *http.Client, baseURL string, limit int) ([]string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	reqURL := fmt.Sprintf("%s/api/cache/list?limit=%d", strings.TrimSuffix(baseURL, "/"), limit)
+	body, _, err := get(ctx, client, reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache: %w", err)
+	}
+
+	var list struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse cache list: %w", err)
+	}
+
+	urls := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		urls = append(urls, "https://www.youtube.com/watch?v="+item.ID)
+	}
+	if len(urls) == 0 {
+		return nil, ErrNoVideos
+	}
+	return urls, nil
+}
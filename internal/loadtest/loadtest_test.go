@@ -0,0 +1,87 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeServer serves CACHED from the cache and bypasses other videos
+func newFakeServer(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	var fileRequests atomic.Int64
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/api/getvideo", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("url") == "https://www.youtube.com/watch?v=CACHED" {
+			fmt.Fprintf(w, "%s/CACHED.mp4?v=1", srv.URL)
+		}
+	})
+	mux.HandleFunc("/CACHED.mp4", func(w http.ResponseWriter, r *http.Request) {
+		fileRequests.Add(1)
+		w.Write([]byte("video"))
+	})
+	mux.HandleFunc("/api/cache/list", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"total":1,"items":[{"id":"CACHED"}]}`))
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &fileRequests
+}
+
+func TestRun(t *testing.T) {
+	srv, fileRequests := newFakeServer(t)
+
+	videos, err := CachedVideos(context.Background(), nil, srv.URL, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://www.youtube.com/watch?v=CACHED"}, videos)
+
+	report, err := Run(context.Background(), Options{
+		BaseURL:     srv.URL,
+		VideoURLs:   append(videos, "https://www.youtube.com/watch?v=MISS"),
+		Concurrency: 4,
+		Duration:    200 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	assert.Positive(t, report.GetVideo.Requests)
+	assert.Zero(t, report.GetVideo.Errors)
+	assert.Positive(t, report.Files.Requests)
+	assert.LessOrEqual(t, int64(report.Files.Requests), fileRequests.Load())
+	assert.Less(t, report.Files.Requests, report.GetVideo.Requests) // Misses serve no file
+	assert.LessOrEqual(t, report.GetVideo.P50, report.GetVideo.P99)
+	assert.LessOrEqual(t, report.GetVideo.P99, report.GetVideo.Max)
+
+	var out bytes.Buffer
+	report.Write(&out)
+	assert.Contains(t, out.String(), "getvideo")
+	assert.Contains(t, out.String(), "p99")
+}
+
+func TestRunNoVideos(t *testing.T) {
+	_, err := Run(context.Background(), Options{BaseURL: "http://127.0.0.1:1", Duration: time.Second})
+	assert.ErrorIs(t, err, ErrNoVideos)
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	stats := newStats(latencies, 2)
+	assert.Equal(t, 102, stats.Requests)
+	assert.Equal(t, 50*time.Millisecond, stats.P50)
+	assert.Equal(t, 99*time.Millisecond, stats.P99)
+	assert.Equal(t, 100*time.Millisecond, stats.Max)
+
+	assert.Equal(t, time.Millisecond, percentile([]time.Duration{time.Millisecond}, 0.99))
+	assert.Equal(t, Stats{Requests: 1, Errors: 1}, newStats(nil, 1))
+}
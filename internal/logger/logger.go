@@ -0,0 +1,160 @@
+// Package logger sets up the app-wide structured logger: leveled log/slog
+// records written to the console and to rotating files in the logs
+// directory, so failed downloads can be debugged after the fact
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidLevel is returned for log level names other than debug, info,
+// warn and error
+var ErrInvalidLevel = errors.New("invalid log level: must be debug, info, warn or error")
+
+// FileName is the current log file in the logs directory
+const FileName = "vrcvideocacher.log"
+
+const (
+	// maxFileSize is the size at which the log file is rotated
+	maxFileSize = 10 << 20
+
+	// maxFiles is the number of rotated log files kept besides the current
+	// one
+	maxFiles = 5
+)
+
+// level is the minimum level of every handler created by this package, so
+// changing it applies at once
+var level slog.LevelVar
+
+// Options control Setup
+type Options struct {
+	// Dir is where log files are written; empty logs to Console only
+	Dir string
+	// Level is the minimum level logged
+	Level slog.Level
+	// Console receives every record as text, e.g. os.Stdout
+	Console io.Writer
+}
+
+// Setup makes a logger writing to opts.Console and a rotating file in
+// opts.Dir the slog default, which the standard log package also writes
+// to. Close the returned closer on exit to flush the file
+func Setup(opts Options) (io.Closer, error) {
+	level.Set(opts.Level)
+
+	var handlers []slog.Handler
+	if opts.Console != nil {
+		handlers = append(handlers, NewTextHandler(opts.Console))
+	}
+
+	var file io.Closer = nopCloser{}
+	if opts.Dir != "" {
+		f, err := OpenRotatingFile(filepath.Join(opts.Dir, FileName), maxFileSize, maxFiles)
+		if err != nil {
+			return nil, err
+		}
+		file = f
+		handlers = append(handlers, NewTextHandler(f))
+	}
+
+	slog.SetDefault(slog.New(Tee(handlers...)))
+	return file, nil
+}
+
+// NewTextHandler returns a logfmt handler for w at the shared level
+func NewTextHandler(w io.Writer) slog.Handler {
+	return slog.NewTextHandler(w, &slog.HandlerOptions{Level: &level})
+}
+
+// SetLevel changes the minimum level of every handler of this package
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// ParseLevel parses a level name from the config
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("%w: %s", ErrInvalidLevel, name)
+	}
+}
+
+// For returns the default logger tagged with a component, e.g. "ytdl".
+// Call it when logging rather than caching the result, so loggers set up
+// later are used
+func For(component string) *slog.Logger {
+	return slog.Default().With("component", component)
+}
+
+// Err is the attribute of an error
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
+
+// Tee returns a handler passing each record to every handler enabled for it
+func Tee(handlers ...slog.Handler) slog.Handler {
+	return tee(handlers)
+}
+
+// tee is the handler returned by Tee
+type tee []slog.Handler
+
+// Enabled implements slog.Handler
+func (t tee) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle implements slog.Handler
+func (t tee) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements slog.Handler
+func (t tee) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(tee, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+// WithGroup implements slog.Handler
+func (t tee) WithGroup(name string) slog.Handler {
+	handlers := make(tee, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// nopCloser is the closer returned when no log file is written
+type nopCloser struct{}
+
+// Close implements io.Closer
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		SetLevel(slog.LevelInfo)
+	})
+
+	dir := t.TempDir()
+	var console bytes.Buffer
+	closer, err := Setup(Options{Dir: dir, Level: slog.LevelInfo, Console: &console})
+	require.NoError(t, err)
+
+	For("ytdl").Debug("hidden")
+	For("ytdl").Warn("Install failed", "version", "2026.01.01", Err(errors.New("timeout")))
+	SetLevel(slog.LevelDebug)
+	For("ytdl").Debug("shown")
+	require.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	require.NoError(t, err)
+	assert.Equal(t, console.String(), string(data))
+	assert.NotContains(t, string(data), "hidden")
+	assert.Contains(t, string(data), `level=WARN msg="Install failed" component=ytdl version=2026.01.01 error=timeout`)
+	assert.Contains(t, string(data), "msg=shown")
+}
+
+func TestParseLevel(t *testing.T) {
+	l, err := ParseLevel("debug")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, l)
+
+	l, err = ParseLevel("WARN")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, l)
+
+	_, err = ParseLevel("verbose")
+	assert.ErrorIs(t, err, ErrInvalidLevel)
+	_, err = ParseLevel("info+2")
+	assert.ErrorIs(t, err, ErrInvalidLevel)
+}
+
+func TestTee(t *testing.T) {
+	var info, errs bytes.Buffer
+	log := slog.New(Tee(
+		slog.NewTextHandler(&info, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		slog.NewTextHandler(&errs, &slog.HandlerOptions{Level: slog.LevelError}),
+	)).With("component", "api")
+
+	log.Info("Cache moved")
+	log.Error("Server error")
+
+	assert.Equal(t, 2, strings.Count(info.String(), "component=api"))
+	assert.NotContains(t, errs.String(), "Cache moved")
+	assert.Contains(t, errs.String(), `msg="Server error" component=api`)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", FileName)
+	f, err := OpenRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	// Every write overflows the 10 byte limit, so each file holds one line
+	// and the oldest is gone
+	for name, want := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(data))
+	}
+	assert.NoFileExists(t, path+".3")
+
+	// Reopening appends
+	f, err = OpenRotatingFile(path, 100, 2)
+	require.NoError(t, err)
+	f.Write([]byte("fifth\n"))
+	f.Close()
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, "fourth\nfifth\n", string(data))
+
+	_, err = f.Write([]byte("closed\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
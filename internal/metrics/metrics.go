@@ -0,0 +1,255 @@
+// Package metrics counts what the server does since it started and writes
+// it in the Prometheus text format, served at /metrics for operators
+// monitoring a headless server
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DurationBuckets are histogram buckets in seconds for yt-dlp runs, from a
+// quick metadata probe to a long download
+var DurationBuckets = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// Counter is a value that only goes up. The zero value is ready to use
+type Counter struct {
+	v atomic.Uint64
+}
+
+// Inc adds one
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Add adds n
+func (c *Counter) Add(n uint64) {
+	c.v.Add(n)
+}
+
+// Value returns the count
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+// CounterVec is a set of counters told apart by the value of one label
+type CounterVec struct {
+	mu       sync.Mutex
+	counters map[string]*Counter
+}
+
+// With returns the counter for a label value, creating it
+func (v *CounterVec) With(value string) *Counter {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.counters == nil {
+		v.counters = make(map[string]*Counter)
+	}
+	c, ok := v.counters[value]
+	if !ok {
+		c = &Counter{}
+		v.counters[value] = c
+	}
+	return c
+}
+
+// Values returns the count of each label value
+func (v *CounterVec) Values() map[string]uint64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	values := make(map[string]uint64, len(v.counters))
+	for value, c := range v.counters {
+		values[value] = c.Value()
+	}
+	return values
+}
+
+// Histogram counts observations into buckets
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// NewHistogram creates a histogram with the given upper bounds, ascending
+func NewHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+// Observe records a value
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Snapshot returns the current state of the histogram
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return HistogramSnapshot{
+		Buckets: h.buckets,
+		Counts:  append([]uint64{}, h.counts...),
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+}
+
+// HistogramSnapshot is the state of a histogram. Counts are cumulative: the
+// number of observations up to the bucket's bound
+type HistogramSnapshot struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     float64
+}
+
+// Labels are label names and values of a sample
+type Labels map[string]string
+
+// Sample is a value of a metric with its labels
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+// HistogramSample is a histogram with its labels
+type HistogramSample struct {
+	Labels    Labels
+	Histogram HistogramSnapshot
+}
+
+// Writer writes metrics in the Prometheus text format. The first write
+// error is kept and returned by Err
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+// NewWriter creates a writer to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Err returns the first write error
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Counter writes a counter with its samples
+func (w *Writer) Counter(name, help string, samples ...Sample) {
+	w.family(name, help, "counter", samples)
+}
+
+// Gauge writes a gauge with its samples
+func (w *Writer) Gauge(name, help string, samples ...Sample) {
+	w.family(name, help, "gauge", samples)
+}
+
+// Histogram writes a histogram with its samples
+func (w *Writer) Histogram(name, help string, samples ...HistogramSample) {
+	w.header(name, help, "histogram")
+	for _, s := range samples {
+		h := s.Histogram
+		for i, bound := range h.Buckets {
+			w.sample(name+"_bucket", with(s.Labels, "le", formatValue(bound)), float64(h.Counts[i]))
+		}
+		w.sample(name+"_bucket", with(s.Labels, "le", "+Inf"), float64(h.Count))
+		w.sample(name+"_sum", s.Labels, h.Sum)
+		w.sample(name+"_count", s.Labels, float64(h.Count))
+	}
+}
+
+// family writes a metric of type typ with its samples
+func (w *Writer) family(name, help, typ string, samples []Sample) {
+	w.header(name, help, typ)
+	for _, s := range samples {
+		w.sample(name, s.Labels, s.Value)
+	}
+}
+
+// header writes the HELP and TYPE lines of a metric
+func (w *Writer) header(name, help, typ string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, typ)
+}
+
+// sample writes a sample line, labels sorted by name
+func (w *Writer) sample(name string, labels Labels, value float64) {
+	if len(labels) == 0 {
+		w.printf("%s %s\n", name, formatValue(value))
+		return
+	}
+
+	names := make([]string, 0, len(labels))
+	for n := range labels {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, n := range names {
+		pairs[i] = n + `="` + escapeLabel(labels[n]) + `"`
+	}
+	w.printf("%s{%s} %s\n", name, strings.Join(pairs, ","), formatValue(value))
+}
+
+// printf writes unless a write failed before
+func (w *Writer) printf(format string, args ...any) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.w, format, args...)
+	}
+}
+
+// with returns a copy of labels with name set to value
+func with(labels Labels, name, value string) Labels {
+	l := make(Labels, len(labels)+1)
+	for n, v := range labels {
+		l[n] = v
+	}
+	l[name] = value
+	return l
+}
+
+// formatValue formats a sample value as Prometheus expects it
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// escapeHelp escapes a HELP text
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeLabel escapes a label value
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
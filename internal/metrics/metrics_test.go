@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterVec(t *testing.T) {
+	var v CounterVec
+	assert.Empty(t, v.Values())
+
+	v.With("a").Inc()
+	v.With("a").Add(2)
+	v.With("b").Inc()
+	assert.Equal(t, map[string]uint64{"a": 3, "b": 1}, v.Values())
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 5})
+	h.Observe(0.5)
+	h.Observe(3)
+	h.Observe(10)
+
+	snap := h.Snapshot()
+	assert.Equal(t, []uint64{1, 2}, snap.Counts)
+	assert.Equal(t, uint64(3), snap.Count)
+	assert.InDelta(t, 13.5, snap.Sum, 1e-9)
+}
+
+func TestWriter(t *testing.T) {
+	h := NewHistogram([]float64{0.5, 1})
+	h.Observe(0.25)
+	h.Observe(2)
+
+	var b strings.Builder
+	w := NewWriter(&b)
+	w.Gauge("app_size_bytes", "Size\nin bytes.", Sample{Value: 1 << 30})
+	w.Counter("app_requests_total", "Requests.",
+		Sample{Labels: Labels{"rule": `say "hi"`, "a": "b"}, Value: 2})
+	w.Histogram("app_duration_seconds", "Durations.", HistogramSample{Labels: Labels{"kind": "probe"}, Histogram: h.Snapshot()})
+	require.NoError(t, w.Err())
+
+	assert.Equal(t, `# HELP app_size_bytes Size\nin bytes.
+# TYPE app_size_bytes gauge
+app_size_bytes 1073741824
+# HELP app_requests_total Requests.
+# TYPE app_requests_total counter
+app_requests_total{a="b",rule="say \"hi\""} 2
+# HELP app_duration_seconds Durations.
+# TYPE app_duration_seconds histogram
+app_duration_seconds_bucket{kind="probe",le="0.5"} 1
+app_duration_seconds_bucket{kind="probe",le="1"} 1
+app_duration_seconds_bucket{kind="probe",le="+Inf"} 2
+app_duration_seconds_sum{kind="probe"} 2.25
+app_duration_seconds_count{kind="probe"} 2
+`, b.String())
+}
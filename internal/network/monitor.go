@@ -0,0 +1,146 @@
+package network
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultInterval is how often connectivity is checked while online
+	DefaultInterval = time.Minute
+
+	// offlineInterval is how often connectivity is checked while offline, so
+	// queued downloads resume soon after the connection returns
+	offlineInterval = 5 * time.Second
+
+	// probeTimeout bounds each connectivity check
+	probeTimeout = 5 * time.Second
+)
+
+// probeAddrs are dialed to detect connectivity; reaching any one is enough
+var probeAddrs = []string{"www.youtube.com:443", "github.com:443"}
+
+// Monitor tracks internet connectivity, checking periodically so a network
+// change is noticed without any request failing first
+type Monitor struct {
+	mu        sync.Mutex
+	probe     func(ctx context.Context) bool
+	interval  time.Duration
+	online    bool
+	listeners []func(online bool)
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// NewMonitor creates a monitor checking every interval while online
+// Connectivity is assumed until a check fails
+func NewMonitor(interval time.Duration) *Monitor {
+	return &Monitor{
+		probe:    dialAny,
+		interval: interval,
+		online:   true,
+	}
+}
+
+// OnChange registers a callback run when connectivity is lost or restored
+func (m *Monitor) OnChange(fn func(online bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// Online returns the result of the last check
+func (m *Monitor) Online() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.online
+}
+
+// Check probes connectivity now, notifying listeners if it changed
+func (m *Monitor) Check(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	online := m.probe(ctx)
+
+	m.mu.Lock()
+	changed := online != m.online
+	m.online = online
+	listeners := append([]func(bool){}, m.listeners...)
+	m.mu.Unlock()
+
+	if changed {
+		for _, fn := range listeners {
+			fn(online)
+		}
+	}
+	return online
+}
+
+// Start begins periodic checks, more often while offline
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		for {
+			wait := m.interval
+			if !m.Online() {
+				wait = min(wait, offlineInterval)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+				m.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Stop ends periodic checks
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		m.wg.Wait()
+	}
+}
+
+// dialAny reports whether any probe address accepts a connection
+func dialAny(ctx context.Context) bool {
+	results := make(chan bool, len(probeAddrs))
+	for _, addr := range probeAddrs {
+		go func(addr string) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err == nil {
+				conn.Close()
+			}
+			results <- err == nil
+		}(addr)
+	}
+
+	for range probeAddrs {
+		if <-results {
+			return true
+		}
+	}
+	return false
+}
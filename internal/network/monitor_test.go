@@ -0,0 +1,61 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitorCheck(t *testing.T) {
+	m := NewMonitor(DefaultInterval)
+	up := true
+	m.probe = func(context.Context) bool { return up }
+
+	var changes []bool
+	m.OnChange(func(online bool) { changes = append(changes, online) })
+
+	assert.True(t, m.Online(), "connectivity is assumed before the first check")
+	assert.True(t, m.Check(context.Background()))
+	assert.Empty(t, changes)
+
+	up = false
+	assert.False(t, m.Check(context.Background()))
+	assert.False(t, m.Check(context.Background()))
+	assert.False(t, m.Online())
+
+	up = true
+	assert.True(t, m.Check(context.Background()))
+	assert.Equal(t, []bool{false, true}, changes, "listeners only hear about changes")
+}
+
+func TestMonitorStartDetectsReconnect(t *testing.T) {
+	m := NewMonitor(10 * time.Millisecond)
+	probes := make(chan bool, 1)
+	probes <- false
+	m.probe = func(context.Context) bool {
+		select {
+		case up := <-probes:
+			return up
+		default:
+			return true
+		}
+	}
+
+	restored := make(chan struct{})
+	m.OnChange(func(online bool) {
+		if online {
+			close(restored)
+		}
+	})
+
+	m.Start()
+	defer m.Stop()
+
+	select {
+	case <-restored:
+	case <-time.After(time.Second):
+		t.Fatal("reconnect not detected")
+	}
+}
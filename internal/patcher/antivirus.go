@@ -0,0 +1,61 @@
+package patcher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"vrcvideocacher/internal/logger"
+)
+
+// ErrBlockedByAntivirus is returned when the stub could not be written or
+// was removed or altered right after writing, as antivirus software does
+// with executables it does not trust
+var ErrBlockedByAntivirus = errors.New("stub blocked by antivirus")
+
+// isAntivirusError reports whether err is Windows refusing a file for
+// containing a virus (ERROR_VIRUS_INFECTED and ERROR_VIRUS_DELETED)
+func isAntivirusError(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "virus")
+}
+
+// checkStub reports ErrBlockedByAntivirus unless the stub written to
+// ytdlpPath is still there and unchanged
+func (p *Patcher) checkStub(ytdlpPath string) error {
+	data, err := os.ReadFile(ytdlpPath)
+	if os.IsNotExist(err) || isAntivirusError(err) {
+		return fmt.Errorf("%w: %s was removed after writing", ErrBlockedByAntivirus, ytdlpPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read stub: %w", err)
+	}
+	if !bytes.Equal(data, p.stubData) {
+		return fmt.Errorf("%w: %s was altered after writing", ErrBlockedByAntivirus, ytdlpPath)
+	}
+	return nil
+}
+
+// recordBlocked restores the backed up original so the game keeps playing
+// videos and records in the manifest that antivirus blocked the stub
+func recordBlocked(toolsPath string, m *manifest) {
+	ytdlpPath := filepath.Join(toolsPath, "yt-dlp.exe")
+	if data, err := os.ReadFile(ytdlpPath + ".bkp"); err == nil {
+		// Ignore errors, the stub may be gone already
+		makeWritable(ytdlpPath)
+		os.Remove(ytdlpPath)
+		if err := os.WriteFile(ytdlpPath, data, 0644); err != nil {
+			log().Warn("Failed to restore original yt-dlp", "path", ytdlpPath, logger.Err(err))
+		}
+	}
+
+	now := time.Now()
+	m.BlockedAt = &now
+	if err := writeManifest(toolsPath, m); err != nil {
+		log().Warn("Failed to record blocked stub", "path", toolsPath, logger.Err(err))
+	}
+	log().Warn("Stub blocked by antivirus", "path", ytdlpPath)
+}
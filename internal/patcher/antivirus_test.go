@@ -0,0 +1,71 @@
+package patcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAntivirusError(t *testing.T) {
+	assert.True(t, isAntivirusError(errors.New("open yt-dlp.exe: Operation did not complete successfully because the file contains a virus or potentially unwanted software.")))
+	assert.False(t, isAntivirusError(errors.New("open yt-dlp.exe: Access is denied.")))
+	assert.False(t, isAntivirusError(nil))
+}
+
+func TestVerifyRemovedStub(t *testing.T) {
+	toolsDir := t.TempDir()
+	ytdlpPath := filepath.Join(toolsDir, "yt-dlp.exe")
+	require.NoError(t, os.WriteFile(ytdlpPath, []byte("original yt-dlp"), 0644))
+
+	p := NewPatcher([]byte("stub"))
+	require.NoError(t, p.PatchVRChat(toolsDir))
+
+	// Quarantined by antivirus after patching
+	require.NoError(t, makeWritable(ytdlpPath))
+	require.NoError(t, os.Remove(ytdlpPath))
+
+	v, err := p.Verify(toolsDir)
+	require.NoError(t, err)
+	assert.Equal(t, BinaryRemoved, v.Status)
+}
+
+func TestBlockedStub(t *testing.T) {
+	toolsDir := t.TempDir()
+	ytdlpPath := filepath.Join(toolsDir, "yt-dlp.exe")
+	original := []byte("original yt-dlp")
+	require.NoError(t, os.WriteFile(ytdlpPath, original, 0644))
+
+	p := NewPatcher([]byte("stub"))
+	require.NoError(t, p.PatchVRChat(toolsDir))
+	assert.NoError(t, p.checkStub(ytdlpPath))
+
+	require.NoError(t, makeWritable(ytdlpPath))
+	require.NoError(t, os.WriteFile(ytdlpPath, []byte("cleaned"), 0644))
+	assert.ErrorIs(t, p.checkStub(ytdlpPath), ErrBlockedByAntivirus)
+
+	require.NoError(t, os.Remove(ytdlpPath))
+	assert.ErrorIs(t, p.checkStub(ytdlpPath), ErrBlockedByAntivirus)
+
+	// The original is restored so the game keeps working
+	m, err := readManifest(toolsDir)
+	require.NoError(t, err)
+	recordBlocked(toolsDir, m)
+	data, err := os.ReadFile(ytdlpPath)
+	require.NoError(t, err)
+	assert.Equal(t, original, data)
+
+	v, err := p.Verify(toolsDir)
+	require.NoError(t, err)
+	assert.NotNil(t, v.BlockedAt)
+
+	// Patching successfully clears the record
+	require.NoError(t, p.PatchVRChat(toolsDir))
+	v, err = p.Verify(toolsDir)
+	require.NoError(t, err)
+	assert.Equal(t, BinaryPatched, v.Status)
+	assert.Nil(t, v.BlockedAt)
+}
@@ -0,0 +1,100 @@
+package patcher
+
+import (
+	"errors"
+	"sync"
+)
+
+// Patch result statuses
+const (
+	PatchPatched = "patched"
+	PatchSkipped = "skipped"
+	PatchFailed  = "error"
+)
+
+// PatchResult is the outcome of patching one target in PatchAll
+type PatchResult struct {
+	Target      string `json:"target"`
+	DisplayName string `json:"displayName"`
+	Path        string `json:"path,omitempty"`
+	Status      string `json:"status"`
+	// Error says why the target was skipped or failed
+	Error string `json:"error,omitempty"`
+
+	err error
+}
+
+// Err returns the error of a failed target, nil otherwise
+func (r PatchResult) Err() error {
+	if r.Status != PatchFailed {
+		return nil
+	}
+	return r.err
+}
+
+// PatchAll patches the named targets concurrently, all known targets if
+// names is empty. Targets that are not installed are skipped; with safe,
+// binaries that are not a known yt-dlp release, or cannot be verified, are
+// refused like SafePatchTarget does. Results are in the order of names
+func (p *Patcher) PatchAll(names []string, safe bool) []PatchResult {
+	if len(names) == 0 {
+		for _, def := range targetDefs {
+			names = append(names, def.name)
+		}
+	}
+
+	results := make([]PatchResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.patchOne(name, safe)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// patchOne patches a single target for PatchAll
+func (p *Patcher) patchOne(name string, safe bool) PatchResult {
+	result := PatchResult{Target: name, DisplayName: name}
+
+	def, err := findTarget(name)
+	if err != nil {
+		return result.fail(err)
+	}
+	result.DisplayName = def.displayName
+
+	path, err := DetectTargetPath(name)
+	if errors.Is(err, ErrTargetNotFound) {
+		result.Status = PatchSkipped
+		result.Error = "not installed"
+		return result
+	}
+	if err != nil {
+		return result.fail(err)
+	}
+	result.Path = path
+
+	if safe {
+		err = p.SafePatchTarget(name)
+	} else {
+		err = p.patch(path, name)
+	}
+	if err != nil {
+		return result.fail(err)
+	}
+
+	result.Status = PatchPatched
+	return result
+}
+
+// fail marks the result as failed with err
+func (r PatchResult) fail(err error) PatchResult {
+	r.Status = PatchFailed
+	r.Error = err.Error()
+	r.err = err
+	return r
+}
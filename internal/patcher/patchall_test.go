@@ -0,0 +1,65 @@
+package patcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchAll(t *testing.T) {
+	localLow, programFiles := setupTargetEnv(t)
+
+	vrchatDir := filepath.Join(localLow, "VRChat", "VRChat", "Tools")
+	resoniteDir := filepath.Join(programFiles, "Steam", "steamapps", "common", "Resonite", "RuntimeData")
+	for _, dir := range []string{vrchatDir, resoniteDir} {
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "yt-dlp.exe"), []byte("original"), 0644))
+	}
+
+	p := NewPatcher([]byte("stub"))
+	results := p.PatchAll(nil, false)
+	require.Len(t, results, 4)
+
+	assert.Equal(t, TargetVRChat, results[0].Target)
+	assert.Equal(t, PatchPatched, results[0].Status)
+	assert.Equal(t, vrchatDir, results[0].Path)
+	assert.NoError(t, results[0].Err())
+
+	assert.Equal(t, TargetVRChatBeta, results[1].Target)
+	assert.Equal(t, PatchSkipped, results[1].Status)
+	assert.NoError(t, results[1].Err())
+
+	assert.Equal(t, PatchPatched, results[2].Status)
+	assert.Equal(t, "Resonite", results[2].DisplayName)
+	assert.Equal(t, PatchSkipped, results[3].Status)
+
+	for _, dir := range []string{vrchatDir, resoniteDir} {
+		patched, err := p.IsPatched(dir)
+		require.NoError(t, err)
+		assert.True(t, patched)
+	}
+}
+
+func TestPatchAll_Errors(t *testing.T) {
+	localLow, _ := setupTargetEnv(t)
+
+	vrchatDir := filepath.Join(localLow, "VRChat", "VRChat", "Tools")
+	require.NoError(t, os.MkdirAll(vrchatDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(vrchatDir, "yt-dlp.exe"), []byte("unknown"), 0644))
+
+	// An unknown target does not stop the others
+	p := NewPatcher([]byte("stub"))
+	p.AddKnownHashes(map[string]string{computeHash([]byte("release")): "yt-dlp.exe"})
+	results := p.PatchAll([]string{"bogus", TargetVRChat}, true)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, PatchFailed, results[0].Status)
+	assert.ErrorIs(t, results[0].Err(), ErrUnknownTarget)
+
+	assert.Equal(t, PatchFailed, results[1].Status)
+	assert.ErrorIs(t, results[1].Err(), ErrUnknownBinary)
+	assert.NotEmpty(t, results[1].Error)
+}
@@ -5,39 +5,69 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"vrcvideocacher/internal/events"
+	"vrcvideocacher/internal/logger"
 )
 
 var (
 	ErrVRChatNotFound = errors.New("VRChat installation not found")
 	ErrFileNotFound   = errors.New("file not found")
+
+	// ErrAccessDenied is returned when the OS refuses to let this user
+	// write to a Tools directory, e.g. a game installed in Program Files
+	ErrAccessDenied = errors.New("access denied")
 )
 
 // Patcher handles VRChat/Resonite yt-dlp patching
 type Patcher struct {
 	stubData []byte
 	stubHash string
+	stubs    map[string]string
+	known    knownHashes
+	appPath  string
+
+	// runStub runs a freshly written stub to check it, nil to skip the check
+	runStub       stubRunner
+	requireServer atomic.Bool
+
+	// bus receives an event whenever a target is patched, fails to patch or
+	// is restored
+	bus atomic.Pointer[events.Bus]
 }
 
 // NewPatcher creates a new patcher
 func NewPatcher(stubData []byte) *Patcher {
-	return &Patcher{
+	p := &Patcher{
 		stubData: stubData,
 		stubHash: computeHash(stubData),
+		known:    knownHashes{hashes: make(map[string]string)},
+		runStub:  defaultStubRunner(),
 	}
+
+	// Bundled lists are validated by tests
+	if hashes, err := ParseKnownHashes(strings.NewReader(bundledHashes)); err == nil {
+		p.AddKnownHashes(hashes)
+	}
+	p.stubs, _ = ParseKnownHashes(strings.NewReader(bundledStubHashes))
+	p.appPath, _ = os.Executable()
+
+	return p
 }
 
 // DetectVRChatPath attempts to find VRChat Tools directory
 func DetectVRChatPath() (string, error) {
-	// Try common VRChat installation paths on Windows
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
+	// VRChat stores files in LocalLow
+	localLow, err := localLowPath()
+	if err != nil {
 		return "", ErrVRChatNotFound
 	}
-
-	// VRChat stores files in LocalLow
-	localLow := filepath.Join(filepath.Dir(localAppData), "LocalLow")
 	toolsPath := filepath.Join(localLow, "VRChat", "VRChat", "Tools")
 
 	// Check if directory exists
@@ -49,54 +79,137 @@ func DetectVRChatPath() (string, error) {
 }
 
 // PatchVRChat patches VRChat's yt-dlp.exe with stub
+// A stub left by an older version is replaced without touching the backup
 func (p *Patcher) PatchVRChat(toolsPath string) error {
+	return p.patch(toolsPath, "")
+}
+
+// patch patches the yt-dlp.exe in toolsPath, recording target in the
+// manifest unless it is empty. A newly written stub is run once, and the
+// original restored if it fails
+func (p *Patcher) patch(toolsPath, target string) error {
+	written, err := p.writeStub(toolsPath, target)
+	if err != nil {
+		err = accessDenied(toolsPath, err)
+		p.publish(toolsPath, target, events.PatchFailed, err)
+		return err
+	}
+	if !written {
+		return nil
+	}
+
 	ytdlpPath := filepath.Join(toolsPath, "yt-dlp.exe")
-	backupPath := filepath.Join(toolsPath, "yt-dlp.exe.bkp")
+	if err := p.verifyStub(ytdlpPath); err != nil {
+		if rbErr := p.rollback(toolsPath, err); rbErr != nil {
+			err = fmt.Errorf("%w; restoring the original failed: %w", err, rbErr)
+		}
+		p.publish(toolsPath, target, events.PatchFailed, err)
+		return err
+	}
+
+	log().Info("Patched yt-dlp", "path", ytdlpPath)
+	p.publish(toolsPath, target, events.PatchPatched, nil)
+	return nil
+}
+
+// SetEventBus sets the bus patch status changes are published to
+func (p *Patcher) SetEventBus(bus *events.Bus) {
+	p.bus.Store(bus)
+}
 
-	// Check if already patched
-	if patched, err := p.IsPatched(toolsPath); err == nil && patched {
-		return nil // Already patched
+// publish publishes a patch status change of toolsPath
+func (p *Patcher) publish(toolsPath, target, status string, err error) {
+	data := events.Patch{Path: toolsPath, Target: target, Status: status}
+	if err != nil {
+		data.Error = err.Error()
 	}
+	p.bus.Load().Publish(events.PatchStatus, data)
+}
+
+// writeStub replaces the yt-dlp.exe in toolsPath with the stub and updates
+// the manifest. It reports false if the current stub was already there
+func (p *Patcher) writeStub(toolsPath, target string) (bool, error) {
+	ytdlpPath := filepath.Join(toolsPath, "yt-dlp.exe")
+	backupPath := filepath.Join(toolsPath, "yt-dlp.exe.bkp")
 
 	// Check if yt-dlp.exe exists
-	if _, err := os.Stat(ytdlpPath); os.IsNotExist(err) {
-		return fmt.Errorf("%w: %s", ErrFileNotFound, ytdlpPath)
+	currentData, err := os.ReadFile(ytdlpPath)
+	if os.IsNotExist(err) {
+		return false, fmt.Errorf("%w: %s", ErrFileNotFound, ytdlpPath)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read original: %w", err)
 	}
 
-	// Backup original if backup doesn't exist
-	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
-		// Remove read-only attribute if present
-		if err := makeWritable(ytdlpPath); err != nil {
-			return fmt.Errorf("failed to make file writable: %w", err)
-		}
+	// A stub that restored the original itself could not delete its own
+	// running executable
+	os.Remove(ytdlpPath + ".old") // Ignore errors
 
-		// Copy to backup
-		originalData, err := os.ReadFile(ytdlpPath)
-		if err != nil {
-			return fmt.Errorf("failed to read original: %w", err)
-		}
+	// Check if already patched with the current stub
+	currentHash := computeHash(currentData)
+	if currentHash == p.stubHash {
+		return false, nil // Already patched
+	}
+
+	m, err := readManifest(toolsPath)
+	if err != nil {
+		return false, err
+	}
 
-		if err := os.WriteFile(backupPath, originalData, 0644); err != nil {
-			return fmt.Errorf("failed to create backup: %w", err)
+	// Remove read-only attribute if present
+	if err := makeWritable(ytdlpPath); err != nil {
+		return false, fmt.Errorf("failed to make file writable: %w", err)
+	}
+
+	// Backup original if backup doesn't exist, never backing up a stub
+	if !p.isStubHash(m, currentHash) {
+		if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+			if err := os.WriteFile(backupPath, currentData, 0644); err != nil {
+				return false, fmt.Errorf("failed to create backup: %w", err)
+			}
+			m.OriginalHash = currentHash
 		}
 	}
 
 	// Remove old file
 	if err := os.Remove(ytdlpPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove original: %w", err)
+		return false, fmt.Errorf("failed to remove original: %w", err)
 	}
 
 	// Write stub
 	if err := os.WriteFile(ytdlpPath, p.stubData, 0644); err != nil {
-		return fmt.Errorf("failed to write stub: %w", err)
+		if isAntivirusError(err) {
+			recordBlocked(toolsPath, m)
+			return false, fmt.Errorf("%w: failed to write stub: %w", ErrBlockedByAntivirus, err)
+		}
+		return false, fmt.Errorf("failed to write stub: %w", err)
+	}
+
+	// Antivirus software may quarantine the stub as soon as it is closed
+	if err := p.checkStub(ytdlpPath); err != nil {
+		if errors.Is(err, ErrBlockedByAntivirus) {
+			recordBlocked(toolsPath, m)
+		}
+		return false, err
 	}
 
 	// Make read-only
 	if err := makeReadOnly(ytdlpPath); err != nil {
-		return fmt.Errorf("failed to make read-only: %w", err)
+		return false, fmt.Errorf("failed to make read-only: %w", err)
 	}
 
-	return nil
+	// Record the stub so later versions still recognize it
+	if target != "" {
+		m.Target = target
+	}
+	m.App = p.appPath
+	m.BlockedAt = nil
+	m.addStubHash(p.stubHash)
+	if err := writeManifest(toolsPath, m); err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
 // UnpatchVRChat restores original yt-dlp.exe
@@ -141,10 +254,17 @@ func (p *Patcher) UnpatchVRChat(toolsPath string) error {
 		return fmt.Errorf("failed to remove backup: %w", err)
 	}
 
+	// Remove manifest
+	if err := os.Remove(filepath.Join(toolsPath, manifestName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove manifest: %w", err)
+	}
+
+	log().Info("Restored original yt-dlp", "path", ytdlpPath)
+	p.publish(toolsPath, "", events.PatchRestored, nil)
 	return nil
 }
 
-// IsPatched checks if yt-dlp.exe is patched with stub
+// IsPatched checks if yt-dlp.exe is patched with the current or an older stub
 func (p *Patcher) IsPatched(toolsPath string) (bool, error) {
 	ytdlpPath := filepath.Join(toolsPath, "yt-dlp.exe")
 
@@ -154,9 +274,22 @@ func (p *Patcher) IsPatched(toolsPath string) (bool, error) {
 		return false, err
 	}
 
+	m, err := readManifest(toolsPath)
+	if err != nil {
+		return false, err
+	}
+
 	// Compare hash
-	fileHash := computeHash(data)
-	return fileHash == p.stubHash, nil
+	return p.isStubHash(m, computeHash(data)), nil
+}
+
+// accessDenied marks err with ErrAccessDenied if the OS refused access to
+// toolsPath
+func accessDenied(toolsPath string, err error) error {
+	if err == nil || errors.Is(err, ErrAccessDenied) || !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	return fmt.Errorf("%w to %s: %w", ErrAccessDenied, toolsPath, err)
 }
 
 // computeHash computes SHA256 hash of data
@@ -174,3 +307,8 @@ func makeReadOnly(path string) error {
 func makeWritable(path string) error {
 	return os.Chmod(path, 0644)
 }
+
+// log returns the logger of this package
+func log() *slog.Logger {
+	return logger.For("patcher")
+}
@@ -1,12 +1,16 @@
 package patcher
 
 import (
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/events"
 )
 
 func TestNewPatcher(t *testing.T) {
@@ -435,3 +439,34 @@ func TestPatchVRChat_MultiplePatches(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, patched)
 }
+
+func TestPatchEvents(t *testing.T) {
+	toolsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(toolsDir, "yt-dlp.exe"), []byte("original yt-dlp"), 0644))
+
+	p := NewPatcher([]byte("stub"))
+	bus := events.NewBus()
+	p.SetEventBus(bus)
+	ch, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	require.NoError(t, p.PatchVRChat(toolsDir))
+	require.NoError(t, p.PatchVRChat(toolsDir)) // Already patched, no change
+	require.NoError(t, p.UnpatchVRChat(toolsDir))
+
+	require.Len(t, ch, 2)
+	assert.Equal(t, events.Patch{Path: toolsDir, Status: events.PatchPatched}, (<-ch).Data)
+	assert.Equal(t, events.Patch{Path: toolsDir, Status: events.PatchRestored}, (<-ch).Data)
+}
+
+func TestAccessDenied(t *testing.T) {
+	denied := &os.PathError{Op: "open", Path: "/games/Tools/yt-dlp.exe", Err: fs.ErrPermission}
+
+	err := accessDenied("/games/Tools", fmt.Errorf("failed to write stub: %w", denied))
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.ErrorContains(t, err, "/games/Tools")
+
+	assert.NotErrorIs(t, accessDenied("/games/Tools", ErrFileNotFound), ErrAccessDenied)
+	assert.NoError(t, accessDenied("/games/Tools", nil))
+}
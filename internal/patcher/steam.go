@@ -0,0 +1,95 @@
+package patcher
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// libraryPathPattern matches the "path" entries of libraryfolders.vdf
+var libraryPathPattern = regexp.MustCompile(`(?m)^\s*"path"\s+"((?:[^"\\]|\\.)*)"`)
+
+// vdfUnescaper undoes the escaping of VDF strings
+var vdfUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`)
+
+// steamLibraries returns the Steam library directories: the default Steam
+// install first, then every library listed in its libraryfolders.vdf
+func steamLibraries() []string {
+	programFiles, ok := programFilesX86Path()
+	if !ok {
+		return nil
+	}
+
+	steamDir := filepath.Join(programFiles, "Steam")
+	libraries := []string{steamDir}
+
+	data, err := os.ReadFile(filepath.Join(steamDir, "steamapps", "libraryfolders.vdf"))
+	if err != nil {
+		return libraries
+	}
+
+	// Under Wine the listed paths are Windows paths inside the prefix
+	var prefix string
+	if winePrefixes() != nil {
+		prefix = filepath.Dir(filepath.Dir(programFiles))
+	}
+
+	for _, library := range parseLibraryFolders(data) {
+		if prefix != "" {
+			library = winePath(prefix, library)
+		}
+		if library != "" && !containsDir(libraries, library) {
+			libraries = append(libraries, library)
+		}
+	}
+
+	return libraries
+}
+
+// parseLibraryFolders returns the library paths of a libraryfolders.vdf
+func parseLibraryFolders(data []byte) []string {
+	var paths []string
+	for _, match := range libraryPathPattern.FindAllSubmatch(data, -1) {
+		paths = append(paths, vdfUnescaper.Replace(string(match[1])))
+	}
+	return paths
+}
+
+// findSteamApp returns the directory below steamapps/common of the first
+// Steam library that has it
+func findSteamApp(dir ...string) (string, bool) {
+	for _, library := range steamLibraries() {
+		path := filepath.Join(append([]string{library, "steamapps", "common"}, dir...)...)
+		if dirExists(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// winePath maps a Windows path such as D:\SteamLibrary to the directory of
+// prefix it refers to, or returns "" if it has no drive letter
+func winePath(prefix, path string) string {
+	if len(path) < 2 || path[1] != ':' {
+		return ""
+	}
+
+	drive := strings.ToLower(path[:1])
+	rest := filepath.FromSlash(strings.ReplaceAll(path[2:], `\`, "/"))
+	if drive == "c" {
+		return filepath.Join(prefix, "drive_c", rest)
+	}
+	return filepath.Join(prefix, "dosdevices", drive+":", rest)
+}
+
+// containsDir reports whether dirs holds dir, comparing the directories
+// themselves where they exist
+func containsDir(dirs []string, dir string) bool {
+	for _, d := range dirs {
+		if filepath.Clean(d) == filepath.Clean(dir) || sameDir(d, dir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package patcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLibraryFolders lists libraries in the libraryfolders.vdf of the
+// default Steam install
+func writeLibraryFolders(t *testing.T, programFiles string, libraries ...string) {
+	var b strings.Builder
+	b.WriteString("\"libraryfolders\"\n{\n")
+	for i, library := range libraries {
+		escaped := strings.ReplaceAll(library, `\`, `\\`)
+		fmt.Fprintf(&b, "\t\"%d\"\n\t{\n\t\t\"path\"\t\t\"%s\"\n\t}\n", i, escaped)
+	}
+	b.WriteString("}\n")
+
+	steamapps := filepath.Join(programFiles, "Steam", "steamapps")
+	require.NoError(t, os.MkdirAll(steamapps, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(steamapps, "libraryfolders.vdf"), []byte(b.String()), 0644))
+}
+
+func TestParseLibraryFolders(t *testing.T) {
+	data := []byte(`"libraryfolders"
+{
+	"0"
+	{
+		"path"		"C:\\Program Files (x86)\\Steam"
+		"apps"
+		{
+			"438100"		"12345"
+		}
+	}
+	"1"
+	{
+		"path"		"D:\\SteamLibrary"
+	}
+}`)
+
+	assert.Equal(t, []string{`C:\Program Files (x86)\Steam`, `D:\SteamLibrary`}, parseLibraryFolders(data))
+	assert.Empty(t, parseLibraryFolders([]byte("garbage")))
+}
+
+func TestDetectResonitePath_SteamLibrary(t *testing.T) {
+	_, programFiles := setupTargetEnv(t)
+
+	library := filepath.Join(t.TempDir(), "SteamLibrary")
+	writeLibraryFolders(t, programFiles, filepath.Join(programFiles, "Steam"), library)
+
+	_, err := DetectResonitePath()
+	assert.ErrorIs(t, err, ErrResoniteNotFound)
+
+	runtimeDir := filepath.Join(library, "steamapps", "common", "Resonite", "RuntimeData")
+	require.NoError(t, os.MkdirAll(runtimeDir, 0755))
+
+	path, err := DetectResonitePath()
+	require.NoError(t, err)
+	assert.Equal(t, runtimeDir, path)
+}
+
+func TestWinePath(t *testing.T) {
+	prefix := filepath.Join("home", "user", ".wine")
+
+	assert.Equal(t, filepath.Join(prefix, "drive_c", "Program Files (x86)", "Steam"), winePath(prefix, `C:\Program Files (x86)\Steam`))
+	assert.Equal(t, filepath.Join(prefix, "dosdevices", "d:", "SteamLibrary"), winePath(prefix, `D:\SteamLibrary`))
+	assert.Empty(t, winePath(prefix, "/mnt/games"))
+}
@@ -0,0 +1,111 @@
+package patcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"vrcvideocacher/internal/logger"
+)
+
+// StubCheckFlag makes the stub only report whether it runs and reaches the
+// server. It must match the flag handled in cmd/ytdlp-stub
+const StubCheckFlag = "--vrcvideocacher-check"
+
+// stubBanner starts the output of a stub check
+const stubBanner = "vrcvideocacher-stub"
+
+// stubCheckTimeout bounds a stub check, a stub held by an antivirus scan
+// may never exit
+const stubCheckTimeout = 15 * time.Second
+
+// stubExitNotRunning is the exit code of a stub that cannot reach the server
+const stubExitNotRunning = 3
+
+var (
+	ErrStubCheckFailed   = errors.New("stub check failed")
+	ErrServerUnreachable = errors.New("stub cannot reach the server")
+)
+
+// stubRunner runs the stub at path with StubCheckFlag and returns its exit
+// code and output. err is set if the stub could not run at all
+type stubRunner func(path string) (code int, out []byte, err error)
+
+// defaultStubRunner returns the runner of this OS. Elsewhere than Windows the
+// stub only runs under the game's Wine, so it is not checked
+func defaultStubRunner() stubRunner {
+	if runtime.GOOS != "windows" {
+		return nil
+	}
+	return runStub
+}
+
+// runStub runs the stub at path with StubCheckFlag
+func runStub(path string) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), stubCheckTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, StubCheckFlag).CombinedOutput()
+	if ctx.Err() != nil {
+		return 0, out, fmt.Errorf("no exit within %s", stubCheckTimeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), out, nil
+	}
+	return 0, out, err
+}
+
+// SetRequireServer makes patching also check that the stub reaches the
+// server, for callers that run it. Off by default so patching works while
+// the server is stopped
+func (p *Patcher) SetRequireServer(require bool) {
+	p.requireServer.Store(require)
+}
+
+// verifyStub runs the stub written to ytdlpPath the way the game would. It
+// fails if the stub does not run, e.g. blocked by antivirus or SmartScreen,
+// and with SetRequireServer if it cannot reach the server
+func (p *Patcher) verifyStub(ytdlpPath string) error {
+	if p.runStub == nil {
+		return nil
+	}
+
+	code, out, err := p.runStub(ytdlpPath)
+	output := strings.TrimSpace(string(out))
+	switch {
+	case isAntivirusError(err):
+		return fmt.Errorf("%w: %w: %w", ErrStubCheckFailed, ErrBlockedByAntivirus, err)
+	case err != nil:
+		return fmt.Errorf("%w: stub did not run, it may be blocked by antivirus or SmartScreen: %w", ErrStubCheckFailed, err)
+	case !bytes.HasPrefix(out, []byte(stubBanner)):
+		return fmt.Errorf("%w: unexpected output %q", ErrStubCheckFailed, output)
+	case code == stubExitNotRunning && !p.requireServer.Load():
+		return nil
+	case code == stubExitNotRunning:
+		return fmt.Errorf("%w: %w: %s", ErrStubCheckFailed, ErrServerUnreachable, output)
+	case code != 0:
+		return fmt.Errorf("%w: exit code %d: %s", ErrStubCheckFailed, code, output)
+	}
+	return nil
+}
+
+// rollback puts the original yt-dlp.exe in toolsPath back after the stub
+// failed its check for cause
+func (p *Patcher) rollback(toolsPath string, cause error) error {
+	log().Warn("Stub check failed, restoring original yt-dlp", "path", toolsPath, logger.Err(cause))
+	if errors.Is(cause, ErrBlockedByAntivirus) {
+		m, err := readManifest(toolsPath)
+		if err != nil {
+			return err
+		}
+		recordBlocked(toolsPath, m)
+		return nil
+	}
+	return p.UnpatchVRChat(toolsPath)
+}
@@ -0,0 +1,66 @@
+package patcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStub returns a stubRunner answering with code and out, or err
+func fakeStub(code int, out string, err error) stubRunner {
+	return func(path string) (int, []byte, error) {
+		return code, []byte(out), err
+	}
+}
+
+func TestVerifyStub(t *testing.T) {
+	p := NewPatcher([]byte("stub"))
+
+	p.runStub = fakeStub(0, "vrcvideocacher-stub 1.0.0\n", nil)
+	assert.NoError(t, p.verifyStub("yt-dlp.exe"))
+
+	p.runStub = fakeStub(0, "", errors.New("exec: This program is blocked by group policy"))
+	assert.ErrorIs(t, p.verifyStub("yt-dlp.exe"), ErrStubCheckFailed)
+
+	p.runStub = fakeStub(0, "", errors.New("Operation did not complete successfully because the file contains a virus"))
+	assert.ErrorIs(t, p.verifyStub("yt-dlp.exe"), ErrBlockedByAntivirus)
+
+	p.runStub = fakeStub(0, "yt-dlp 2024.01.01\n", nil)
+	assert.ErrorIs(t, p.verifyStub("yt-dlp.exe"), ErrStubCheckFailed)
+
+	// An unreachable server only fails the check when it is required
+	p.runStub = fakeStub(stubExitNotRunning, "vrcvideocacher-stub 1.0.0\nERROR: not running\n", nil)
+	assert.NoError(t, p.verifyStub("yt-dlp.exe"))
+	p.SetRequireServer(true)
+	assert.ErrorIs(t, p.verifyStub("yt-dlp.exe"), ErrServerUnreachable)
+}
+
+func TestPatchRollsBackFailedStub(t *testing.T) {
+	toolsDir := t.TempDir()
+	ytdlpPath := filepath.Join(toolsDir, "yt-dlp.exe")
+	original := []byte("original yt-dlp")
+	require.NoError(t, os.WriteFile(ytdlpPath, original, 0644))
+
+	p := NewPatcher([]byte("stub"))
+	p.runStub = fakeStub(0, "", errors.New("exec: killed"))
+
+	err := p.PatchVRChat(toolsDir)
+	assert.ErrorIs(t, err, ErrStubCheckFailed)
+
+	data, err := os.ReadFile(ytdlpPath)
+	require.NoError(t, err)
+	assert.Equal(t, original, data)
+	assert.NoFileExists(t, ytdlpPath+".bkp")
+	assert.NoFileExists(t, ManifestPath(toolsDir))
+
+	// A stub that runs stays
+	p.runStub = fakeStub(0, "vrcvideocacher-stub 1.0.0\n", nil)
+	require.NoError(t, p.PatchVRChat(toolsDir))
+	patched, err := p.IsPatched(toolsDir)
+	require.NoError(t, err)
+	assert.True(t, patched)
+}
@@ -0,0 +1,102 @@
+package patcher
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// manifestName is the patch manifest written next to yt-dlp.exe
+const manifestName = "yt-dlp.exe.patch.json"
+
+// bundledStubHashes lists the hashes of stubs shipped by previous releases
+//
+//go:embed stub_hashes.txt
+var bundledStubHashes string
+
+// manifest records what was written to a target
+type manifest struct {
+	StubHashes   []string  `json:"stubHashes"`
+	OriginalHash string    `json:"originalHash,omitempty"`
+	PatchedAt    time.Time `json:"patchedAt"`
+
+	// Target is the name of the patched target, empty for a directory
+	// patched by path
+	Target string `json:"target,omitempty"`
+
+	// App is the program that installed the stub. The stub restores the
+	// backup by itself once the app is gone and the server stays unreachable
+	App string `json:"app,omitempty"`
+
+	// BlockedAt is when antivirus software last blocked or removed the
+	// stub, cleared by the next successful patch
+	BlockedAt *time.Time `json:"blockedAt,omitempty"`
+}
+
+// ManifestPath returns the path of the patch manifest in toolsPath
+func ManifestPath(toolsPath string) string {
+	return filepath.Join(toolsPath, manifestName)
+}
+
+// readManifest loads the manifest in toolsPath, returning an empty one if missing
+func readManifest(toolsPath string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(toolsPath, manifestName))
+	if os.IsNotExist(err) {
+		return &manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		// A corrupt manifest only loses history
+		return &manifest{}, nil
+	}
+	return &m, nil
+}
+
+// writeManifest saves the manifest to toolsPath
+func writeManifest(toolsPath string, m *manifest) error {
+	m.PatchedAt = time.Now()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(toolsPath, manifestName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// addStubHash records hash if not already present
+func (m *manifest) addStubHash(hash string) {
+	for _, h := range m.StubHashes {
+		if h == hash {
+			return
+		}
+	}
+	m.StubHashes = append(m.StubHashes, hash)
+}
+
+// isStubHash reports whether hash belongs to the current stub, a bundled
+// historical stub or a stub recorded in the manifest
+func (p *Patcher) isStubHash(m *manifest, hash string) bool {
+	if hash == p.stubHash {
+		return true
+	}
+	if _, ok := p.stubs[hash]; ok {
+		return true
+	}
+	for _, h := range m.StubHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package patcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundledStubHashes(t *testing.T) {
+	_, err := ParseKnownHashes(strings.NewReader(bundledStubHashes))
+	assert.NoError(t, err)
+}
+
+func TestPatchVRChat_WritesManifest(t *testing.T) {
+	toolsDir := t.TempDir()
+	original := []byte("original yt-dlp")
+	require.NoError(t, os.WriteFile(filepath.Join(toolsDir, "yt-dlp.exe"), original, 0644))
+
+	p := NewPatcher([]byte("stub v1"))
+	require.NoError(t, p.PatchVRChat(toolsDir))
+
+	m, err := readManifest(toolsDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{computeHash([]byte("stub v1"))}, m.StubHashes)
+	assert.Equal(t, computeHash(original), m.OriginalHash)
+	assert.NotEmpty(t, m.App)
+
+	require.NoError(t, p.UnpatchVRChat(toolsDir))
+	assert.NoFileExists(t, filepath.Join(toolsDir, manifestName))
+}
+
+func TestPatchVRChat_RemovesSelfRestoredStub(t *testing.T) {
+	toolsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(toolsDir, "yt-dlp.exe"), []byte("original"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(toolsDir, "yt-dlp.exe.old"), []byte("stub"), 0444))
+
+	require.NoError(t, NewPatcher([]byte("stub")).PatchVRChat(toolsDir))
+	assert.NoFileExists(t, filepath.Join(toolsDir, "yt-dlp.exe.old"))
+}
+
+func TestPatchVRChat_UpgradeFromOldStub(t *testing.T) {
+	toolsDir := t.TempDir()
+	ytdlpPath := filepath.Join(toolsDir, "yt-dlp.exe")
+	backupPath := filepath.Join(toolsDir, "yt-dlp.exe.bkp")
+	original := []byte("original yt-dlp")
+	require.NoError(t, os.WriteFile(ytdlpPath, original, 0644))
+
+	require.NoError(t, NewPatcher([]byte("stub v1")).PatchVRChat(toolsDir))
+
+	// A newer release still recognizes the old stub
+	p := NewPatcher([]byte("stub v2"))
+	patched, err := p.IsPatched(toolsDir)
+	require.NoError(t, err)
+	assert.True(t, patched)
+
+	// Re-patching replaces the old stub and keeps the original backup
+	require.NoError(t, p.PatchVRChat(toolsDir))
+	data, _ := os.ReadFile(ytdlpPath)
+	assert.Equal(t, []byte("stub v2"), data)
+	backup, _ := os.ReadFile(backupPath)
+	assert.Equal(t, original, backup)
+
+	m, err := readManifest(toolsDir)
+	require.NoError(t, err)
+	assert.Len(t, m.StubHashes, 2)
+}
+
+func TestPatchVRChat_OldStubWithoutBackup(t *testing.T) {
+	toolsDir := t.TempDir()
+	ytdlpPath := filepath.Join(toolsDir, "yt-dlp.exe")
+	require.NoError(t, os.WriteFile(ytdlpPath, []byte("stub v1"), 0444))
+
+	p := NewPatcher([]byte("stub v2"))
+	p.stubs[computeHash([]byte("stub v1"))] = "old"
+
+	require.NoError(t, p.PatchVRChat(toolsDir))
+
+	// The old stub must not become the "original" backup
+	assert.NoFileExists(t, filepath.Join(toolsDir, "yt-dlp.exe.bkp"))
+	data, _ := os.ReadFile(ytdlpPath)
+	assert.Equal(t, []byte("stub v2"), data)
+}
@@ -0,0 +1,266 @@
+package patcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Patch target names
+const (
+	TargetVRChat     = "vrchat"
+	TargetVRChatBeta = "vrchat-beta"
+	TargetResonite   = "resonite"
+	TargetChilloutVR = "chilloutvr"
+)
+
+var (
+	ErrUnknownTarget      = errors.New("unknown patch target")
+	ErrTargetNotFound     = errors.New("patch target not found")
+	ErrResoniteNotFound   = errors.New("Resonite installation not found")
+	ErrVRChatBetaNotFound = errors.New("VRChat beta installation not found")
+	ErrChilloutVRNotFound = errors.New("ChilloutVR installation not found")
+)
+
+// Target describes a platform whose yt-dlp.exe can be patched
+type Target struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Path        string `json:"path"`
+	Detected    bool   `json:"detected"`
+	Patched     bool   `json:"patched"`
+	Binary      string `json:"binary,omitempty"`
+	// PatchedAt is when the target was last patched, from its manifest
+	PatchedAt *time.Time `json:"patchedAt,omitempty"`
+}
+
+var (
+	resoniteMu   sync.RWMutex
+	resonitePath string
+)
+
+// SetResonitePath sets the Resonite install directory, overriding the
+// search of the Steam libraries. An empty path restores the detection
+func SetResonitePath(path string) {
+	resoniteMu.Lock()
+	defer resoniteMu.Unlock()
+	resonitePath = path
+}
+
+// targetDef maps a target name to its tools directory detection
+type targetDef struct {
+	name        string
+	displayName string
+	detect      func() (string, error)
+}
+
+var targetDefs = []targetDef{
+	{TargetVRChat, "VRChat", DetectVRChatPath},
+	{TargetVRChatBeta, "VRChat (Beta)", DetectVRChatBetaPath},
+	{TargetResonite, "Resonite", DetectResonitePath},
+	{TargetChilloutVR, "ChilloutVR", DetectChilloutVRPath},
+}
+
+// vrchatBetaDirs are the LocalLow directories the VRChat open beta keeps
+// its data in, depending on how the beta branch was installed
+var vrchatBetaDirs = [][]string{
+	{"VRChat", "VRChat Beta"},
+	{"VRChat", "VRChat-Beta"},
+	{"VRChat Beta", "VRChat"},
+}
+
+// DetectVRChatBetaPath attempts to find the VRChat beta Tools directory
+// A beta sharing the Tools directory of the stable branch is not reported,
+// patching the stable branch covers it
+func DetectVRChatBetaPath() (string, error) {
+	localLow, err := localLowPath()
+	if err != nil {
+		return "", ErrVRChatBetaNotFound
+	}
+
+	stablePath, _ := DetectVRChatPath()
+	for _, dir := range vrchatBetaDirs {
+		toolsPath := filepath.Join(append([]string{localLow}, append(dir, "Tools")...)...)
+		if dirExists(toolsPath) && !sameDir(toolsPath, stablePath) {
+			return toolsPath, nil
+		}
+	}
+
+	return "", ErrVRChatBetaNotFound
+}
+
+// DetectResonitePath attempts to find the Resonite RuntimeData directory,
+// which holds its yt-dlp.exe, in the configured install directory or else
+// in the Steam libraries
+func DetectResonitePath() (string, error) {
+	resoniteMu.RLock()
+	installPath := resonitePath
+	resoniteMu.RUnlock()
+
+	if installPath != "" {
+		// Accept the RuntimeData directory itself as well
+		for _, dir := range []string{filepath.Join(installPath, "RuntimeData"), installPath} {
+			if filepath.Base(dir) == "RuntimeData" && dirExists(dir) {
+				return dir, nil
+			}
+		}
+		return "", fmt.Errorf("%w: no RuntimeData directory in %s", ErrResoniteNotFound, installPath)
+	}
+
+	runtimePath, ok := findSteamApp("Resonite", "RuntimeData")
+	if !ok {
+		return "", ErrResoniteNotFound
+	}
+
+	return runtimePath, nil
+}
+
+// DetectChilloutVRPath attempts to find the ChilloutVR StreamingAssets
+// directory, which holds its yt-dlp.exe, in the Steam libraries
+func DetectChilloutVRPath() (string, error) {
+	assetsPath, ok := findSteamApp("ChilloutVR", "ChilloutVR_Data", "StreamingAssets")
+	if !ok {
+		return "", ErrChilloutVRNotFound
+	}
+
+	return assetsPath, nil
+}
+
+// DetectTargetPath returns the directory containing yt-dlp.exe for a target
+func DetectTargetPath(name string) (string, error) {
+	def, err := findTarget(name)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := def.detect()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTargetNotFound, err)
+	}
+
+	return path, nil
+}
+
+// ListTargets returns all known targets with their detection and patch state
+func (p *Patcher) ListTargets() []Target {
+	targets := make([]Target, 0, len(targetDefs))
+	for _, def := range targetDefs {
+		target := Target{
+			Name:        def.name,
+			DisplayName: def.displayName,
+		}
+
+		if path, err := def.detect(); err == nil {
+			target.Path = path
+			target.Detected = true
+			if v, err := p.Verify(path); err == nil {
+				target.Binary = v.Status
+				target.Patched = v.Status == BinaryPatched
+			}
+			if m, err := readManifest(path); err == nil && target.Patched && !m.PatchedAt.IsZero() {
+				target.PatchedAt = &m.PatchedAt
+			}
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// PatchTarget patches the yt-dlp.exe of the named target, recording the
+// target in the patch manifest
+func (p *Patcher) PatchTarget(name string) error {
+	path, err := DetectTargetPath(name)
+	if err != nil {
+		return err
+	}
+
+	return p.patch(path, name)
+}
+
+// UnpatchTarget restores the original yt-dlp.exe of the named target
+func (p *Patcher) UnpatchTarget(name string) error {
+	path, err := DetectTargetPath(name)
+	if err != nil {
+		return err
+	}
+
+	return p.UnpatchVRChat(path)
+}
+
+// TargetDisplayName returns the display name of a target, e.g. VRChat
+// (Beta), or the name itself for unknown targets
+func TargetDisplayName(name string) string {
+	if def, err := findTarget(name); err == nil {
+		return def.displayName
+	}
+	return name
+}
+
+// PatchResonite patches Resonite's yt-dlp.exe
+func (p *Patcher) PatchResonite() error {
+	return p.PatchTarget(TargetResonite)
+}
+
+// UnpatchResonite restores Resonite's original yt-dlp.exe
+func (p *Patcher) UnpatchResonite() error {
+	return p.UnpatchTarget(TargetResonite)
+}
+
+// findTarget looks up a target definition by name
+func findTarget(name string) (targetDef, error) {
+	for _, def := range targetDefs {
+		if def.name == name {
+			return def, nil
+		}
+	}
+	return targetDef{}, fmt.Errorf("%w: %s", ErrUnknownTarget, name)
+}
+
+// localLowPath returns the AppData\LocalLow directory, of the Wine prefix
+// if one is set or found
+func localLowPath() (string, error) {
+	if prefixes := winePrefixes(); prefixes != nil {
+		if dir, ok := findInPrefixes(prefixes, "users/*/AppData/LocalLow", "VRChat"); ok {
+			return dir, nil
+		}
+		return "", ErrVRChatNotFound
+	}
+
+	localAppData := os.Getenv("LOCALAPPDATA")
+	if localAppData == "" {
+		return "", ErrVRChatNotFound
+	}
+	return filepath.Join(filepath.Dir(localAppData), "LocalLow"), nil
+}
+
+// programFilesX86Path returns the Program Files (x86) directory, of the
+// Wine prefix if one is set or found
+func programFilesX86Path() (string, bool) {
+	if prefixes := winePrefixes(); prefixes != nil {
+		return findInPrefixes(prefixes, "Program Files (x86)", "Steam")
+	}
+
+	programFiles := os.Getenv("ProgramFiles(x86)")
+	return programFiles, programFiles != ""
+}
+
+// sameDir reports whether a and b are the same directory
+func sameDir(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// dirExists reports whether path is an existing directory
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,188 @@
+package patcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTargetEnv points target detection at a temp directory
+func setupTargetEnv(t *testing.T) (localLow, programFiles string) {
+	tempDir := t.TempDir()
+	localAppData := filepath.Join(tempDir, "AppData", "Local")
+	programFiles = filepath.Join(tempDir, "Program Files (x86)")
+
+	t.Setenv("LOCALAPPDATA", localAppData)
+	t.Setenv("ProgramFiles(x86)", programFiles)
+
+	return filepath.Join(tempDir, "AppData", "LocalLow"), programFiles
+}
+
+func TestListTargets(t *testing.T) {
+	localLow, programFiles := setupTargetEnv(t)
+
+	vrchatDir := filepath.Join(localLow, "VRChat", "VRChat", "Tools")
+	resoniteDir := filepath.Join(programFiles, "Steam", "steamapps", "common", "Resonite", "RuntimeData")
+	require.NoError(t, os.MkdirAll(vrchatDir, 0755))
+	require.NoError(t, os.MkdirAll(resoniteDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(vrchatDir, "yt-dlp.exe"), []byte("stub"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(resoniteDir, "yt-dlp.exe"), []byte("original"), 0644))
+
+	p := NewPatcher([]byte("stub"))
+	targets := p.ListTargets()
+	require.Len(t, targets, 4)
+
+	assert.Equal(t, TargetVRChat, targets[0].Name)
+	assert.True(t, targets[0].Detected)
+	assert.True(t, targets[0].Patched)
+	assert.Equal(t, vrchatDir, targets[0].Path)
+
+	assert.Equal(t, TargetVRChatBeta, targets[1].Name)
+	assert.False(t, targets[1].Detected)
+	assert.Empty(t, targets[1].Path)
+
+	assert.Equal(t, TargetResonite, targets[2].Name)
+	assert.True(t, targets[2].Detected)
+	assert.False(t, targets[2].Patched)
+	assert.Equal(t, BinaryUnknown, targets[2].Binary)
+
+	assert.Equal(t, TargetChilloutVR, targets[3].Name)
+	assert.False(t, targets[3].Detected)
+}
+
+func TestDetectChilloutVRPath(t *testing.T) {
+	_, programFiles := setupTargetEnv(t)
+
+	_, err := DetectChilloutVRPath()
+	assert.ErrorIs(t, err, ErrChilloutVRNotFound)
+
+	assetsDir := filepath.Join(programFiles, "Steam", "steamapps", "common", "ChilloutVR", "ChilloutVR_Data", "StreamingAssets")
+	require.NoError(t, os.MkdirAll(assetsDir, 0755))
+
+	path, err := DetectTargetPath(TargetChilloutVR)
+	require.NoError(t, err)
+	assert.Equal(t, assetsDir, path)
+}
+
+func TestPatchTarget(t *testing.T) {
+	_, programFiles := setupTargetEnv(t)
+
+	resoniteDir := filepath.Join(programFiles, "Steam", "steamapps", "common", "Resonite", "RuntimeData")
+	require.NoError(t, os.MkdirAll(resoniteDir, 0755))
+	ytdlpPath := filepath.Join(resoniteDir, "yt-dlp.exe")
+	require.NoError(t, os.WriteFile(ytdlpPath, []byte("original"), 0644))
+
+	p := NewPatcher([]byte("stub"))
+
+	require.NoError(t, p.PatchTarget(TargetResonite))
+	data, _ := os.ReadFile(ytdlpPath)
+	assert.Equal(t, []byte("stub"), data)
+
+	require.NoError(t, p.UnpatchTarget(TargetResonite))
+	data, _ = os.ReadFile(ytdlpPath)
+	assert.Equal(t, []byte("original"), data)
+}
+
+func TestSetResonitePath(t *testing.T) {
+	setupTargetEnv(t)
+	installDir := filepath.Join(t.TempDir(), "Resonite")
+	SetResonitePath(installDir)
+	t.Cleanup(func() { SetResonitePath("") })
+
+	_, err := DetectResonitePath()
+	assert.ErrorIs(t, err, ErrResoniteNotFound)
+
+	runtimeDir := filepath.Join(installDir, "RuntimeData")
+	require.NoError(t, os.MkdirAll(runtimeDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(runtimeDir, "yt-dlp.exe"), []byte("original"), 0644))
+
+	path, err := DetectTargetPath(TargetResonite)
+	require.NoError(t, err)
+	assert.Equal(t, runtimeDir, path)
+
+	// The RuntimeData directory itself is accepted too
+	SetResonitePath(runtimeDir)
+	path, err = DetectResonitePath()
+	require.NoError(t, err)
+	assert.Equal(t, runtimeDir, path)
+
+	p := NewPatcher([]byte("stub"))
+	require.NoError(t, p.PatchResonite())
+	data, _ := os.ReadFile(filepath.Join(runtimeDir, "yt-dlp.exe"))
+	assert.Equal(t, []byte("stub"), data)
+
+	require.NoError(t, p.UnpatchResonite())
+	data, _ = os.ReadFile(filepath.Join(runtimeDir, "yt-dlp.exe"))
+	assert.Equal(t, []byte("original"), data)
+}
+
+func TestPatchTarget_Errors(t *testing.T) {
+	setupTargetEnv(t)
+	p := NewPatcher([]byte("stub"))
+
+	err := p.PatchTarget("quest")
+	assert.ErrorIs(t, err, ErrUnknownTarget)
+
+	err = p.PatchTarget(TargetVRChatBeta)
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+
+	err = p.UnpatchTarget(TargetResonite)
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+}
+
+func TestDetectVRChatBetaPath(t *testing.T) {
+	localLow, _ := setupTargetEnv(t)
+
+	_, err := DetectVRChatBetaPath()
+	assert.ErrorIs(t, err, ErrVRChatBetaNotFound)
+
+	betaDir := filepath.Join(localLow, "VRChat Beta", "VRChat", "Tools")
+	require.NoError(t, os.MkdirAll(betaDir, 0755))
+	path, err := DetectVRChatBetaPath()
+	require.NoError(t, err)
+	assert.Equal(t, betaDir, path)
+
+	// A beta sharing the stable Tools directory is not a separate target
+	require.NoError(t, os.RemoveAll(filepath.Join(localLow, "VRChat Beta")))
+	stableDir := filepath.Join(localLow, "VRChat", "VRChat", "Tools")
+	require.NoError(t, os.MkdirAll(stableDir, 0755))
+	require.NoError(t, os.Symlink(filepath.Join(localLow, "VRChat", "VRChat"), filepath.Join(localLow, "VRChat", "VRChat Beta")))
+	_, err = DetectVRChatBetaPath()
+	assert.ErrorIs(t, err, ErrVRChatBetaNotFound)
+}
+
+func TestPatchTarget_StableAndBeta(t *testing.T) {
+	localLow, _ := setupTargetEnv(t)
+
+	stableDir := filepath.Join(localLow, "VRChat", "VRChat", "Tools")
+	betaDir := filepath.Join(localLow, "VRChat", "VRChat Beta", "Tools")
+	for _, dir := range []string{stableDir, betaDir} {
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "yt-dlp.exe"), []byte("original"), 0644))
+	}
+
+	// Patching the beta leaves the stable branch alone
+	p := NewPatcher([]byte("stub"))
+	require.NoError(t, p.PatchTarget(TargetVRChatBeta))
+
+	targets := p.ListTargets()
+	assert.True(t, targets[0].Detected)
+	assert.False(t, targets[0].Patched)
+	assert.Nil(t, targets[0].PatchedAt)
+	assert.True(t, targets[1].Detected)
+	assert.True(t, targets[1].Patched)
+	assert.NotNil(t, targets[1].PatchedAt)
+
+	m, err := readManifest(betaDir)
+	require.NoError(t, err)
+	assert.Equal(t, TargetVRChatBeta, m.Target)
+	assert.NoFileExists(t, ManifestPath(stableDir))
+
+	require.NoError(t, p.PatchTarget(TargetVRChat))
+	m, err = readManifest(stableDir)
+	require.NoError(t, err)
+	assert.Equal(t, TargetVRChat, m.Target)
+}
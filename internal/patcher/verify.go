@@ -0,0 +1,272 @@
+package patcher
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"vrcvideocacher/internal/logger"
+)
+
+// KnownHashesURL lists the hashes of the official yt-dlp release binaries
+// that VRChat ships
+const KnownHashesURL = "https://github.com/yt-dlp/yt-dlp/releases/latest/download/SHA2-256SUMS"
+
+const fetchTimeout = 10 * time.Second
+
+// HashHistoryFileName keeps the yt-dlp release hashes fetched so far in the
+// data directory, so releases older than the latest and offline starts are
+// still recognized
+const HashHistoryFileName = "known-hashes.txt"
+
+// Binary states reported by Verify
+const (
+	BinaryMissing = "missing"
+	BinaryPatched = "patched"
+	BinaryKnown   = "known"
+	BinaryUnknown = "unknown"
+
+	// BinaryRemoved is a missing yt-dlp.exe where the stub was written,
+	// usually quarantined by antivirus software
+	BinaryRemoved = "removed"
+)
+
+var ErrUnknownBinary = errors.New("unknown yt-dlp binary")
+
+// ErrUnverifiedBinary is returned by SafePatchTarget when no known hashes
+// are loaded to check the binary against, e.g. offline on the first start.
+// Patching it anyway is left to the user to confirm
+var ErrUnverifiedBinary = errors.New("yt-dlp binary not verified: no known hashes loaded")
+
+//go:embed known_hashes.txt
+var bundledHashes string
+
+// HTTPClient interface for mocking
+type HTTPClient interface {
+	Get(url string) (*http.Response, error)
+}
+
+// Verification is the result of checking a target's yt-dlp.exe
+type Verification struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Hash   string `json:"hash,omitempty"`
+	Label  string `json:"label,omitempty"`
+
+	// BlockedAt is when antivirus software last blocked the stub
+	BlockedAt *time.Time `json:"blockedAt,omitempty"`
+}
+
+// knownHashes is a thread-safe set of trusted SHA256 hashes
+type knownHashes struct {
+	mu     sync.RWMutex
+	hashes map[string]string
+}
+
+// ParseKnownHashes parses a sha256sum style list ("<hash>  <label>")
+// Blank lines and lines starting with # are ignored
+func ParseKnownHashes(r io.Reader) (map[string]string, error) {
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		hash := strings.ToLower(fields[0])
+		if len(hash) != 64 {
+			return nil, fmt.Errorf("invalid hash line: %q", line)
+		}
+
+		label := ""
+		if len(fields) > 1 {
+			label = strings.TrimPrefix(strings.Join(fields[1:], " "), "*")
+		}
+		hashes[hash] = label
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// FetchKnownHashes downloads a hash list from url
+func FetchKnownHashes(client HTTPClient, url string) (map[string]string, error) {
+	if client == nil {
+		client = &http.Client{Timeout: fetchTimeout}
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch known hashes: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("known hashes returned status %d", resp.StatusCode)
+	}
+
+	return ParseKnownHashes(resp.Body)
+}
+
+// readHashHistory reads the hash history, empty if missing or malformed
+func readHashHistory(historyFile string) map[string]string {
+	f, err := os.Open(historyFile)
+	if err != nil {
+		return make(map[string]string)
+	}
+	defer f.Close()
+
+	history, err := ParseKnownHashes(f)
+	if err != nil {
+		log().Warn("Ignoring malformed yt-dlp hash history", "path", historyFile, logger.Err(err))
+		return make(map[string]string)
+	}
+	return history
+}
+
+// LoadHashHistory trusts the hashes kept in the history file. It does not
+// touch the network, so it can run before the patcher takes requests
+func (p *Patcher) LoadHashHistory(historyFile string) {
+	p.AddKnownHashes(readHashHistory(historyFile))
+}
+
+// LoadKnownHashes trusts the hashes kept in the history file and, unless
+// offline, those of the latest yt-dlp release, which are added to the
+// history. The history is trusted even if the fetch fails, which is
+// returned
+func (p *Patcher) LoadKnownHashes(client HTTPClient, historyFile string, offline bool) error {
+	history := readHashHistory(historyFile)
+	p.AddKnownHashes(history)
+
+	if offline {
+		return nil
+	}
+	latest, err := FetchKnownHashes(client, KnownHashesURL)
+	if err != nil {
+		return err
+	}
+	p.AddKnownHashes(latest)
+
+	for hash, label := range latest {
+		history[hash] = label
+	}
+	return writeKnownHashes(historyFile, history)
+}
+
+// writeKnownHashes writes hashes as a sha256sum style list to file
+func writeKnownHashes(file string, hashes map[string]string) error {
+	var b strings.Builder
+	b.WriteString("# yt-dlp release hashes seen so far\n")
+	for _, hash := range slices.Sorted(maps.Keys(hashes)) {
+		fmt.Fprintf(&b, "%s  %s\n", hash, hashes[hash])
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(file), err)
+	}
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write yt-dlp hash history: %w", err)
+	}
+	return nil
+}
+
+// AddKnownHashes marks additional yt-dlp.exe hashes as trusted
+func (p *Patcher) AddKnownHashes(hashes map[string]string) {
+	p.known.mu.Lock()
+	defer p.known.mu.Unlock()
+
+	for hash, label := range hashes {
+		p.known.hashes[strings.ToLower(hash)] = label
+	}
+}
+
+// Verify checks whether the yt-dlp.exe in toolsPath is the stub, a known
+// VRChat-shipped binary or something else
+func (p *Patcher) Verify(toolsPath string) (*Verification, error) {
+	ytdlpPath := filepath.Join(toolsPath, "yt-dlp.exe")
+	result := &Verification{Path: ytdlpPath}
+
+	m, err := readManifest(toolsPath)
+	if err != nil {
+		return nil, err
+	}
+	result.BlockedAt = m.BlockedAt
+
+	data, err := os.ReadFile(ytdlpPath)
+	if os.IsNotExist(err) || isAntivirusError(err) {
+		result.Status = BinaryMissing
+		if len(m.StubHashes) > 0 || m.BlockedAt != nil {
+			result.Status = BinaryRemoved
+		}
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read yt-dlp.exe: %w", err)
+	}
+
+	result.Hash = computeHash(data)
+	if p.isStubHash(m, result.Hash) {
+		result.Status = BinaryPatched
+		return result, nil
+	}
+
+	p.known.mu.RLock()
+	label, ok := p.known.hashes[result.Hash]
+	p.known.mu.RUnlock()
+
+	if ok {
+		result.Status = BinaryKnown
+		result.Label = label
+	} else {
+		result.Status = BinaryUnknown
+	}
+
+	return result, nil
+}
+
+// VerifyTarget runs Verify against the named target
+func (p *Patcher) VerifyTarget(name string) (*Verification, error) {
+	path, err := DetectTargetPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Verify(path)
+}
+
+// SafePatchTarget patches the named target only if its yt-dlp.exe is a
+// known binary or already patched. Without any known hashes there is
+// nothing to check against and ErrUnverifiedBinary is returned; PatchTarget
+// patches it once the user confirms
+func (p *Patcher) SafePatchTarget(name string) error {
+	v, err := p.VerifyTarget(name)
+	if err != nil {
+		return err
+	}
+
+	if v.Status == BinaryUnknown {
+		p.known.mu.RLock()
+		loaded := len(p.known.hashes) > 0
+		p.known.mu.RUnlock()
+
+		if loaded {
+			return fmt.Errorf("%w: %s (sha256 %s)", ErrUnknownBinary, v.Path, v.Hash)
+		}
+		return fmt.Errorf("%w: %s (sha256 %s)", ErrUnverifiedBinary, v.Path, v.Hash)
+	}
+
+	return p.PatchTarget(name)
+}
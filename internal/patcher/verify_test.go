@@ -0,0 +1,157 @@
+package patcher
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockHTTPClient is a mock HTTP client for testing
+type mockHTTPClient struct {
+	status int
+	body   string
+}
+
+func (m *mockHTTPClient) Get(url string) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: m.status,
+		Body:       io.NopCloser(strings.NewReader(m.body)),
+	}, nil
+}
+
+func TestBundledKnownHashes(t *testing.T) {
+	_, err := ParseKnownHashes(strings.NewReader(bundledHashes))
+	assert.NoError(t, err)
+}
+
+func TestParseKnownHashes(t *testing.T) {
+	hash := computeHash([]byte("original yt-dlp"))
+	input := "# comment\n\n" + strings.ToUpper(hash) + " *yt-dlp.exe\n"
+
+	hashes, err := ParseKnownHashes(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{hash: "yt-dlp.exe"}, hashes)
+
+	_, err = ParseKnownHashes(strings.NewReader("abc yt-dlp.exe"))
+	assert.Error(t, err)
+}
+
+func TestFetchKnownHashes(t *testing.T) {
+	hash := computeHash([]byte("original yt-dlp"))
+
+	hashes, err := FetchKnownHashes(&mockHTTPClient{status: http.StatusOK, body: hash + "  yt-dlp.exe\n"}, KnownHashesURL)
+	require.NoError(t, err)
+	assert.Contains(t, hashes, hash)
+
+	_, err = FetchKnownHashes(&mockHTTPClient{status: http.StatusNotFound}, KnownHashesURL)
+	assert.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	toolsDir := t.TempDir()
+	ytdlpPath := filepath.Join(toolsDir, "yt-dlp.exe")
+	original := []byte("original yt-dlp")
+
+	p := NewPatcher([]byte("stub"))
+
+	v, err := p.Verify(toolsDir)
+	require.NoError(t, err)
+	assert.Equal(t, BinaryMissing, v.Status)
+
+	require.NoError(t, os.WriteFile(ytdlpPath, original, 0644))
+	v, err = p.Verify(toolsDir)
+	require.NoError(t, err)
+	assert.Equal(t, BinaryUnknown, v.Status)
+	assert.Equal(t, computeHash(original), v.Hash)
+
+	p.AddKnownHashes(map[string]string{computeHash(original): "yt-dlp.exe"})
+	v, err = p.Verify(toolsDir)
+	require.NoError(t, err)
+	assert.Equal(t, BinaryKnown, v.Status)
+	assert.Equal(t, "yt-dlp.exe", v.Label)
+
+	require.NoError(t, p.PatchVRChat(toolsDir))
+	v, err = p.Verify(toolsDir)
+	require.NoError(t, err)
+	assert.Equal(t, BinaryPatched, v.Status)
+}
+
+func TestSafePatchTarget(t *testing.T) {
+	localLow, _ := setupTargetEnv(t)
+
+	toolsDir := filepath.Join(localLow, "VRChat", "VRChat", "Tools")
+	require.NoError(t, os.MkdirAll(toolsDir, 0755))
+	ytdlpPath := filepath.Join(toolsDir, "yt-dlp.exe")
+	require.NoError(t, os.WriteFile(ytdlpPath, []byte("modified yt-dlp"), 0644))
+
+	p := NewPatcher([]byte("stub"))
+	p.AddKnownHashes(map[string]string{computeHash([]byte("release yt-dlp")): "yt-dlp.exe"})
+
+	// Unknown binary is left alone
+	err := p.SafePatchTarget(TargetVRChat)
+	assert.ErrorIs(t, err, ErrUnknownBinary)
+	data, _ := os.ReadFile(ytdlpPath)
+	assert.Equal(t, []byte("modified yt-dlp"), data)
+
+	// Known binary is patched
+	p.AddKnownHashes(map[string]string{computeHash([]byte("modified yt-dlp")): "custom"})
+	require.NoError(t, p.SafePatchTarget(TargetVRChat))
+	data, _ = os.ReadFile(ytdlpPath)
+	assert.Equal(t, []byte("stub"), data)
+}
+
+func TestSafePatchTargetWithoutKnownHashes(t *testing.T) {
+	localLow, _ := setupTargetEnv(t)
+
+	toolsDir := filepath.Join(localLow, "VRChat", "VRChat", "Tools")
+	require.NoError(t, os.MkdirAll(toolsDir, 0755))
+	ytdlpPath := filepath.Join(toolsDir, "yt-dlp.exe")
+	require.NoError(t, os.WriteFile(ytdlpPath, []byte("vrchat yt-dlp"), 0644))
+
+	// Nothing to check against: left alone until the user confirms
+	p := NewPatcher([]byte("stub"))
+	err := p.SafePatchTarget(TargetVRChat)
+	assert.ErrorIs(t, err, ErrUnverifiedBinary)
+	assert.NotErrorIs(t, err, ErrUnknownBinary)
+	data, _ := os.ReadFile(ytdlpPath)
+	assert.Equal(t, []byte("vrchat yt-dlp"), data)
+
+	// The history loaded before any request makes it a known binary
+	history := filepath.Join(t.TempDir(), HashHistoryFileName)
+	require.NoError(t, writeKnownHashes(history, map[string]string{computeHash([]byte("vrchat yt-dlp")): "yt-dlp.exe"}))
+	p.LoadHashHistory(history)
+	require.NoError(t, p.SafePatchTarget(TargetVRChat))
+	data, _ = os.ReadFile(ytdlpPath)
+	assert.Equal(t, []byte("stub"), data)
+	data, _ = os.ReadFile(ytdlpPath + ".bkp")
+	assert.Equal(t, []byte("vrchat yt-dlp"), data)
+}
+
+func TestLoadKnownHashes(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "data", HashHistoryFileName)
+	older := computeHash([]byte("older yt-dlp"))
+	latest := computeHash([]byte("latest yt-dlp"))
+
+	// The latest release is added to the history
+	p := NewPatcher([]byte("stub"))
+	require.NoError(t, p.LoadKnownHashes(&mockHTTPClient{status: http.StatusOK, body: older + "  yt-dlp.exe\n"}, history, false))
+	p = NewPatcher([]byte("stub"))
+	require.NoError(t, p.LoadKnownHashes(&mockHTTPClient{status: http.StatusOK, body: latest + "  yt-dlp.exe\n"}, history, false))
+	assert.Contains(t, p.known.hashes, older)
+	assert.Contains(t, p.known.hashes, latest)
+
+	// Offline or failing, the history is still trusted
+	p = NewPatcher([]byte("stub"))
+	require.NoError(t, p.LoadKnownHashes(nil, history, true))
+	assert.Contains(t, p.known.hashes, older)
+
+	p = NewPatcher([]byte("stub"))
+	assert.Error(t, p.LoadKnownHashes(&mockHTTPClient{status: http.StatusNotFound}, history, false))
+	assert.Contains(t, p.known.hashes, latest)
+}
@@ -0,0 +1,80 @@
+package patcher
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// macBottleDirs are where Wine wrappers on macOS keep their bottles, relative
+// to the home directory: CrossOver and Whisky
+var macBottleDirs = []string{
+	"Library/Application Support/CrossOver/Bottles",
+	"Library/Containers/com.isaacmarovitz.Whisky/Bottles",
+}
+
+var (
+	wineMu     sync.RWMutex
+	winePrefix string
+)
+
+// SetWinePrefix sets the Wine prefix or CrossOver bottle (the directory
+// holding drive_c) that games are detected in, overriding LOCALAPPDATA and
+// the search of CrossOver and Whisky bottles on macOS. An empty prefix
+// restores the default detection
+func SetWinePrefix(prefix string) {
+	wineMu.Lock()
+	defer wineMu.Unlock()
+	winePrefix = prefix
+}
+
+// winePrefixes returns the Wine prefixes games are detected in instead of
+// the Windows environment, or nil to use the environment
+func winePrefixes() []string {
+	home, _ := os.UserHomeDir()
+	return prefixesFor(runtime.GOOS, home, os.Getenv("LOCALAPPDATA"))
+}
+
+// prefixesFor returns the configured Wine prefix, or on macOS without
+// LOCALAPPDATA every CrossOver and Whisky bottle in home
+func prefixesFor(goos, home, localAppData string) []string {
+	wineMu.RLock()
+	prefix := winePrefix
+	wineMu.RUnlock()
+	if prefix != "" {
+		return []string{prefix}
+	}
+	if goos != "darwin" || localAppData != "" || home == "" {
+		return nil
+	}
+
+	var prefixes []string
+	for _, dir := range macBottleDirs {
+		bottles, _ := filepath.Glob(filepath.Join(home, filepath.FromSlash(dir), "*"))
+		prefixes = append(prefixes, bottles...)
+	}
+	return prefixes
+}
+
+// findInPrefixes returns the first directory matching pattern under drive_c
+// of prefixes, preferring one that holds a prefer subdirectory, e.g. the
+// LocalLow of the Windows user that ran VRChat
+func findInPrefixes(prefixes []string, pattern, prefer string) (string, bool) {
+	var first string
+	for _, prefix := range prefixes {
+		matches, _ := filepath.Glob(filepath.Join(prefix, "drive_c", filepath.FromSlash(pattern)))
+		for _, dir := range matches {
+			if !dirExists(dir) {
+				continue
+			}
+			if dirExists(filepath.Join(dir, prefer)) {
+				return dir, true
+			}
+			if first == "" {
+				first = dir
+			}
+		}
+	}
+	return first, first != ""
+}
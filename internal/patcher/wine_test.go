@@ -0,0 +1,59 @@
+package patcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetWinePrefix(t *testing.T) {
+	setupTargetEnv(t)
+	prefix := t.TempDir()
+	SetWinePrefix(prefix)
+	t.Cleanup(func() { SetWinePrefix("") })
+
+	// Another Windows user without VRChat data comes first alphabetically
+	driveC := filepath.Join(prefix, "drive_c")
+	require.NoError(t, os.MkdirAll(filepath.Join(driveC, "users", "Public", "AppData", "LocalLow"), 0755))
+	vrchatDir := filepath.Join(driveC, "users", "crossover", "AppData", "LocalLow", "VRChat", "VRChat", "Tools")
+	resoniteDir := filepath.Join(driveC, "Program Files (x86)", "Steam", "steamapps", "common", "Resonite", "RuntimeData")
+	require.NoError(t, os.MkdirAll(vrchatDir, 0755))
+	require.NoError(t, os.MkdirAll(resoniteDir, 0755))
+
+	path, err := DetectVRChatPath()
+	require.NoError(t, err)
+	assert.Equal(t, vrchatDir, path)
+
+	path, err = DetectResonitePath()
+	require.NoError(t, err)
+	assert.Equal(t, resoniteDir, path)
+
+	_, err = DetectChilloutVRPath()
+	assert.ErrorIs(t, err, ErrChilloutVRNotFound)
+
+	// The prefix overrides LOCALAPPDATA
+	SetWinePrefix(t.TempDir())
+	_, err = DetectVRChatPath()
+	assert.ErrorIs(t, err, ErrVRChatNotFound)
+}
+
+func TestPrefixesFor(t *testing.T) {
+	home := t.TempDir()
+	crossOver := filepath.Join(home, "Library", "Application Support", "CrossOver", "Bottles", "Steam")
+	whisky := filepath.Join(home, "Library", "Containers", "com.isaacmarovitz.Whisky", "Bottles", "VRChat")
+	require.NoError(t, os.MkdirAll(crossOver, 0755))
+	require.NoError(t, os.MkdirAll(whisky, 0755))
+
+	assert.Equal(t, []string{crossOver, whisky}, prefixesFor("darwin", home, ""))
+
+	// Bottles are only searched on macOS without a Windows environment
+	assert.Nil(t, prefixesFor("linux", home, ""))
+	assert.Nil(t, prefixesFor("darwin", home, `C:\Users\user\AppData\Local`))
+
+	SetWinePrefix("/prefix")
+	t.Cleanup(func() { SetWinePrefix("") })
+	assert.Equal(t, []string{"/prefix"}, prefixesFor("linux", home, ""))
+}
@@ -0,0 +1,159 @@
+// Package paths resolves where the cacher keeps its files: the config, the
+// default cache, the downloaded tools, logs and other app data. Resolving
+// never touches the disk beyond looking for a legacy data directory; the
+// code writing each file creates its directory
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Environment variables overriding the resolved paths
+const (
+	// EnvDataDir keeps every file in one directory, e.g. for a portable
+	// install on a USB drive
+	EnvDataDir = "VRCVIDEOCACHER_DATA_DIR"
+	// EnvConfig is the config file to use
+	EnvConfig = "VRCVIDEOCACHER_CONFIG"
+	// EnvCacheDir is the cache directory used when cachePath is empty
+	EnvCacheDir = "VRCVIDEOCACHER_CACHE_DIR"
+)
+
+const (
+	// appName names the data directories on Windows and macOS
+	appName = "VRCVideoCacher"
+
+	// xdgName names the data directories on Linux
+	xdgName = "vrcvideocacher"
+
+	// legacyDirName is the data directory in the home directory used before
+	// the per-OS locations, kept for existing installs
+	legacyDirName = ".vrcvideocacher"
+
+	// ConfigFileName is the config file
+	ConfigFileName = "config.json"
+)
+
+// Paths are the locations of the cacher's files
+type Paths struct {
+	// Data holds app state: usage stats, reports and the GitHub API cache
+	Data string
+	// Config is the config file
+	Config string
+	// Cache is the cache directory used when cachePath is empty
+	Cache string
+	// Utils holds the downloaded yt-dlp and aria2c
+	Utils string
+	// Logs holds log files
+	Logs string
+}
+
+// Flat returns the paths of a single directory holding every file, the
+// layout used on Windows
+func Flat(dir string) Paths {
+	return Paths{
+		Data:   dir,
+		Config: filepath.Join(dir, ConfigFileName),
+		Cache:  filepath.Join(dir, "Cache"),
+		Utils:  filepath.Join(dir, "Utils"),
+		Logs:   filepath.Join(dir, "logs"),
+	}
+}
+
+// Resolve returns the paths for this OS and user with the environment
+// overrides applied:
+//
+//	Windows  %LOCALAPPDATA%\VRCVideoCacher
+//	macOS    ~/Library/Application Support, Caches and Logs
+//	Linux    $XDG_DATA_HOME, $XDG_CONFIG_HOME, $XDG_CACHE_HOME and
+//	         $XDG_STATE_HOME, or ~/.vrcvideocacher if it exists
+func Resolve() Paths {
+	home, _ := os.UserHomeDir()
+	return resolve(system{
+		goos:   runtime.GOOS,
+		getenv: os.Getenv,
+		home:   home,
+		exists: func(path string) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		},
+	})
+}
+
+// system is what resolve needs to know about the machine
+type system struct {
+	goos   string
+	getenv func(string) string
+	home   string
+	exists func(string) bool
+}
+
+// resolve returns the paths for sys
+func resolve(sys system) Paths {
+	var p Paths
+	if dir := sys.getenv(EnvDataDir); dir != "" {
+		p = Flat(dir)
+	} else {
+		p = osPaths(sys)
+	}
+
+	if path := sys.getenv(EnvConfig); path != "" {
+		p.Config = path
+	}
+	if dir := sys.getenv(EnvCacheDir); dir != "" {
+		p.Cache = dir
+	}
+	return p
+}
+
+// osPaths returns the default paths of the OS
+func osPaths(sys system) Paths {
+	if sys.goos == "windows" {
+		if dir := sys.getenv("LOCALAPPDATA"); dir != "" {
+			return Flat(filepath.Join(dir, appName))
+		}
+	}
+
+	// Without a home directory there is nowhere better than here
+	if sys.home == "" {
+		return Flat(".")
+	}
+	if legacy := filepath.Join(sys.home, legacyDirName); sys.exists(legacy) {
+		return Flat(legacy)
+	}
+
+	switch sys.goos {
+	case "windows":
+		return Flat(filepath.Join(sys.home, "AppData", "Local", appName))
+	case "darwin":
+		library := filepath.Join(sys.home, "Library")
+		data := filepath.Join(library, "Application Support", appName)
+		return Paths{
+			Data:   data,
+			Config: filepath.Join(data, ConfigFileName),
+			Cache:  filepath.Join(library, "Caches", appName),
+			Utils:  filepath.Join(data, "Utils"),
+			Logs:   filepath.Join(library, "Logs", appName),
+		}
+	default:
+		data := filepath.Join(xdgDir(sys, "XDG_DATA_HOME", ".local/share"), xdgName)
+		return Paths{
+			Data:   data,
+			Config: filepath.Join(xdgDir(sys, "XDG_CONFIG_HOME", ".config"), xdgName, ConfigFileName),
+			Cache:  filepath.Join(xdgDir(sys, "XDG_CACHE_HOME", ".cache"), xdgName),
+			Utils:  filepath.Join(data, "Utils"),
+			Logs:   filepath.Join(xdgDir(sys, "XDG_STATE_HOME", ".local/state"), xdgName),
+		}
+	}
+}
+
+// xdgDir returns the XDG base directory in env, or fallback in the home
+// directory. Relative paths in env are ignored, as the spec requires
+func xdgDir(sys system, env, fallback string) string {
+	if dir := sys.getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(sys.home, filepath.FromSlash(fallback))
+}
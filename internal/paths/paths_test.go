@@ -0,0 +1,91 @@
+package paths
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testSystem returns a machine with home /home/user, the given environment
+// and the given existing paths
+func testSystem(goos string, env map[string]string, existing ...string) system {
+	return system{
+		goos:   goos,
+		getenv: func(key string) string { return env[key] },
+		home:   filepath.FromSlash("/home/user"),
+		exists: func(path string) bool {
+			for _, e := range existing {
+				if filepath.FromSlash(e) == path {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+func TestResolve_Windows(t *testing.T) {
+	p := resolve(testSystem("windows", map[string]string{"LOCALAPPDATA": "/appdata"}))
+	assert.Equal(t, Flat(filepath.FromSlash("/appdata/VRCVideoCacher")), p)
+	assert.Equal(t, filepath.FromSlash("/appdata/VRCVideoCacher/config.json"), p.Config)
+	assert.Equal(t, filepath.FromSlash("/appdata/VRCVideoCacher/Utils"), p.Utils)
+
+	p = resolve(testSystem("windows", nil))
+	assert.Equal(t, filepath.FromSlash("/home/user/AppData/Local/VRCVideoCacher"), p.Data)
+}
+
+func TestResolve_Darwin(t *testing.T) {
+	p := resolve(testSystem("darwin", nil))
+	assert.Equal(t, Paths{
+		Data:   filepath.FromSlash("/home/user/Library/Application Support/VRCVideoCacher"),
+		Config: filepath.FromSlash("/home/user/Library/Application Support/VRCVideoCacher/config.json"),
+		Cache:  filepath.FromSlash("/home/user/Library/Caches/VRCVideoCacher"),
+		Utils:  filepath.FromSlash("/home/user/Library/Application Support/VRCVideoCacher/Utils"),
+		Logs:   filepath.FromSlash("/home/user/Library/Logs/VRCVideoCacher"),
+	}, p)
+}
+
+func TestResolve_Linux(t *testing.T) {
+	p := resolve(testSystem("linux", nil))
+	assert.Equal(t, Paths{
+		Data:   filepath.FromSlash("/home/user/.local/share/vrcvideocacher"),
+		Config: filepath.FromSlash("/home/user/.config/vrcvideocacher/config.json"),
+		Cache:  filepath.FromSlash("/home/user/.cache/vrcvideocacher"),
+		Utils:  filepath.FromSlash("/home/user/.local/share/vrcvideocacher/Utils"),
+		Logs:   filepath.FromSlash("/home/user/.local/state/vrcvideocacher"),
+	}, p)
+
+	p = resolve(testSystem("linux", map[string]string{
+		"XDG_CONFIG_HOME": "/xdg/config",
+		"XDG_CACHE_HOME":  "relative/cache", // Ignored
+	}))
+	assert.Equal(t, filepath.FromSlash("/xdg/config/vrcvideocacher/config.json"), p.Config)
+	assert.Equal(t, filepath.FromSlash("/home/user/.cache/vrcvideocacher"), p.Cache)
+}
+
+func TestResolve_Legacy(t *testing.T) {
+	p := resolve(testSystem("linux", nil, "/home/user/.vrcvideocacher"))
+	assert.Equal(t, Flat(filepath.FromSlash("/home/user/.vrcvideocacher")), p)
+
+}
+
+func TestResolve_NoHome(t *testing.T) {
+	sys := testSystem("linux", nil)
+	sys.home = ""
+	assert.Equal(t, Flat("."), resolve(sys))
+}
+
+func TestResolve_Overrides(t *testing.T) {
+	p := resolve(testSystem("linux", map[string]string{
+		EnvDataDir:  "/portable",
+		EnvCacheDir: "/videos",
+	}))
+	assert.Equal(t, filepath.FromSlash("/portable"), p.Data)
+	assert.Equal(t, filepath.FromSlash("/portable/config.json"), p.Config)
+	assert.Equal(t, "/videos", p.Cache)
+
+	p = resolve(testSystem("darwin", map[string]string{EnvConfig: "/etc/vrcvideocacher.json"}))
+	assert.Equal(t, "/etc/vrcvideocacher.json", p.Config)
+	assert.Equal(t, filepath.FromSlash("/home/user/Library/Caches/VRCVideoCacher"), p.Cache)
+}
@@ -0,0 +1,149 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// autostartName is the name the app is registered under to start at login
+const autostartName = "VRCVideoCacher"
+
+// launchAgentLabel is the label of the macOS LaunchAgent
+const launchAgentLabel = "de.k4na.vrcvideocacher"
+
+// Autostart is the registration starting the app at login
+type Autostart struct {
+	Enabled bool `json:"enabled"`
+	// Command is the registered command line
+	Command string `json:"command,omitempty"`
+	// Location is the registry value or file holding the registration
+	Location string `json:"location"`
+}
+
+var (
+	desktopExecPattern = regexp.MustCompile(`(?m)^Exec=(.*)$`)
+	plistStringPattern = regexp.MustCompile(`<string>(.*?)</string>`)
+)
+
+// autostartFile returns the file registering the app at login on goos: a
+// LaunchAgent on macOS, an XDG autostart entry elsewhere
+func autostartFile(goos, home, configHome string) string {
+	if goos == "darwin" {
+		return filepath.Join(home, "Library", "LaunchAgents", launchAgentLabel+".plist")
+	}
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "autostart", "vrcvideocacher.desktop")
+}
+
+// autostartEntry returns the contents of the autostart file on goos running
+// path with args
+func autostartEntry(goos, path string, args []string) string {
+	argv := append([]string{path}, args...)
+
+	if goos == "darwin" {
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>` + launchAgentLabel + `</string>
+	<key>ProgramArguments</key>
+	<array>
+`)
+		for _, arg := range argv {
+			b.WriteString("\t\t<string>" + html.EscapeString(arg) + "</string>\n")
+		}
+		b.WriteString(`	</array>
+	<key>RunAtLoad</key>
+	<true/>
+</dict>
+</plist>
+`)
+		return b.String()
+	}
+
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		quoted[i] = quoteDesktopArg(arg)
+	}
+	return fmt.Sprintf(`[Desktop Entry]
+Type=Application
+Name=%s
+Exec=%s
+Terminal=false
+X-GNOME-Autostart-enabled=true
+`, autostartName, strings.Join(quoted, " "))
+}
+
+// autostartCommand returns the command line of an autostart file on goos
+func autostartCommand(goos string, data []byte) string {
+	if goos == "darwin" {
+		var argv []string
+		for i, match := range plistStringPattern.FindAllSubmatch(data, -1) {
+			// The label is the first string
+			if i > 0 {
+				argv = append(argv, html.UnescapeString(string(match[1])))
+			}
+		}
+		return strings.Join(argv, " ")
+	}
+
+	if match := desktopExecPattern.FindSubmatch(data); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// quoteDesktopArg quotes an argument of a desktop entry's Exec key
+func quoteDesktopArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		return arg
+	}
+	escaped := strings.NewReplacer(`"`, `\"`, "`", "\\`", `$`, `\$`, `\`, `\\`).Replace(arg)
+	// Backslashes are unescaped once more as part of the key's string value
+	return `"` + strings.ReplaceAll(escaped, `\`, `\\`) + `"`
+}
+
+// enableAutostartFile writes the autostart file on goos
+func enableAutostartFile(goos, file, path string, args []string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(file), err)
+	}
+	if err := os.WriteFile(file, []byte(autostartEntry(goos, path, args)), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
+
+// disableAutostartFile removes the autostart file, if any
+func disableAutostartFile(file string) error {
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", file, err)
+	}
+	return nil
+}
+
+// getAutostartFile reads the registration from the autostart file on goos
+func getAutostartFile(goos, file string) (Autostart, error) {
+	state := Autostart{Location: file}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	state.Enabled = true
+	state.Command = autostartCommand(goos, data)
+	return state, nil
+}
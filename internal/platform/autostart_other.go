@@ -0,0 +1,31 @@
+//go:build !windows
+
+package platform
+
+import (
+	"os"
+	"runtime"
+)
+
+// EnableAutostart registers path with args to start at login, with a
+// LaunchAgent on macOS or an XDG autostart entry elsewhere. There is no
+// console window to minimize, so minimized is ignored
+func EnableAutostart(path string, args []string, minimized bool) error {
+	return enableAutostartFile(runtime.GOOS, userAutostartFile(), path, args)
+}
+
+// DisableAutostart removes the registration, if any
+func DisableAutostart() error {
+	return disableAutostartFile(userAutostartFile())
+}
+
+// GetAutostart returns the registration starting the app at login
+func GetAutostart() (Autostart, error) {
+	return getAutostartFile(runtime.GOOS, userAutostartFile())
+}
+
+// userAutostartFile returns the autostart file of the current user
+func userAutostartFile() string {
+	home, _ := os.UserHomeDir()
+	return autostartFile(runtime.GOOS, home, os.Getenv("XDG_CONFIG_HOME"))
+}
@@ -0,0 +1,52 @@
+package platform
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutostartFile(t *testing.T) {
+	home := filepath.Join("home", "me")
+	assert.Equal(t, filepath.Join(home, ".config", "autostart", "vrcvideocacher.desktop"), autostartFile("linux", home, ""))
+	assert.Equal(t, filepath.Join("xdg", "autostart", "vrcvideocacher.desktop"), autostartFile("linux", home, "xdg"))
+	assert.Equal(t, filepath.Join(home, "Library", "LaunchAgents", "de.k4na.vrcvideocacher.plist"), autostartFile("darwin", home, "xdg"))
+}
+
+func TestAutostartEntry(t *testing.T) {
+	entry := autostartEntry("linux", "/opt/VRC Cacher/vrcvideocacher", []string{"server"})
+	assert.Contains(t, entry, `Exec="/opt/VRC Cacher/vrcvideocacher" server`+"\n")
+	assert.Equal(t, `"/opt/VRC Cacher/vrcvideocacher" server`, autostartCommand("linux", []byte(entry)))
+
+	assert.Equal(t, `"a \\"b\\" \\$c"`, quoteDesktopArg(`a "b" $c`))
+	assert.Equal(t, `""`, quoteDesktopArg(""))
+
+	entry = autostartEntry("darwin", "/Applications/VRC & Cacher/vrcvideocacher", []string{"--minimized"})
+	assert.Contains(t, entry, "<string>/Applications/VRC &amp; Cacher/vrcvideocacher</string>")
+	assert.Equal(t, "/Applications/VRC & Cacher/vrcvideocacher --minimized", autostartCommand("darwin", []byte(entry)))
+}
+
+func TestAutostartFileLifecycle(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "autostart", "vrcvideocacher.desktop")
+
+	state, err := getAutostartFile("linux", file)
+	require.NoError(t, err)
+	assert.False(t, state.Enabled)
+	assert.Equal(t, file, state.Location)
+
+	require.NoError(t, enableAutostartFile("linux", file, "/usr/bin/vrcvideocacher", []string{"server"}))
+	state, err = getAutostartFile("linux", file)
+	require.NoError(t, err)
+	assert.True(t, state.Enabled)
+	assert.Equal(t, "/usr/bin/vrcvideocacher server", state.Command)
+
+	require.NoError(t, disableAutostartFile(file))
+	state, err = getAutostartFile("linux", file)
+	require.NoError(t, err)
+	assert.False(t, state.Enabled)
+
+	// Disabling twice is harmless
+	assert.NoError(t, disableAutostartFile(file))
+}
@@ -0,0 +1,71 @@
+//go:build windows
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// runKey is the registry key of the programs Windows starts at login
+const runKey = `HKCU\Software\Microsoft\Windows\CurrentVersion\Run`
+
+// EnableAutostart registers path with args to start at login in the Run
+// key. With minimized a console program starts in a minimized window
+func EnableAutostart(path string, args []string, minimized bool) error {
+	argv := append([]string{path}, args...)
+	if minimized {
+		argv = append([]string{"cmd.exe", "/c", "start", "", "/min"}, argv...)
+	}
+
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		quoted[i] = syscall.EscapeArg(arg)
+	}
+
+	out, err := exec.Command("reg.exe", "add", runKey, "/v", autostartName, "/t", "REG_SZ", "/d", strings.Join(quoted, " "), "/f").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to register autostart: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// DisableAutostart removes the registration, if any
+func DisableAutostart() error {
+	state, err := GetAutostart()
+	if err != nil || !state.Enabled {
+		return err
+	}
+
+	out, err := exec.Command("reg.exe", "delete", runKey, "/v", autostartName, "/f").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to remove autostart: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// GetAutostart returns the registration starting the app at login
+func GetAutostart() (Autostart, error) {
+	state := Autostart{Location: runKey + `\` + autostartName}
+
+	out, err := exec.Command("reg.exe", "query", runKey, "/v", autostartName).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// reg.exe fails when the value does not exist
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read autostart: %w", err)
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if _, command, ok := strings.Cut(line, "REG_SZ"); ok {
+			state.Enabled = true
+			state.Command = strings.TrimSpace(command)
+		}
+	}
+	return state, nil
+}
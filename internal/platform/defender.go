@@ -0,0 +1,9 @@
+package platform
+
+import "strings"
+
+// DefenderExclusionCommand returns the PowerShell command excluding path from
+// Microsoft Defender scans. It has to run in an elevated PowerShell
+func DefenderExclusionCommand(path string) string {
+	return "Add-MpPreference -ExclusionPath '" + strings.ReplaceAll(path, "'", "''") + "'"
+}
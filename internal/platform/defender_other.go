@@ -0,0 +1,9 @@
+//go:build !windows
+
+package platform
+
+// AddDefenderExclusion returns ErrUnsupported, Microsoft Defender only runs
+// on Windows
+func AddDefenderExclusion(path string) error {
+	return ErrUnsupported
+}
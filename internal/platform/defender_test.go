@@ -0,0 +1,16 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefenderExclusionCommand(t *testing.T) {
+	assert.Equal(t,
+		`Add-MpPreference -ExclusionPath 'C:\Users\me\AppData\LocalLow\VRChat\VRChat\Tools'`,
+		DefenderExclusionCommand(`C:\Users\me\AppData\LocalLow\VRChat\VRChat\Tools`))
+	assert.Equal(t,
+		`Add-MpPreference -ExclusionPath 'C:\Users\O''Brien\Tools'`,
+		DefenderExclusionCommand(`C:\Users\O'Brien\Tools`))
+}
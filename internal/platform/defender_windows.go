@@ -0,0 +1,20 @@
+//go:build windows
+
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// AddDefenderExclusion excludes path from Microsoft Defender scans. Windows
+// refuses unless the process runs as administrator
+func AddDefenderExclusion(path string) error {
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", DefenderExclusionCommand(path))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to add Defender exclusion for %s: %v: %s", path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
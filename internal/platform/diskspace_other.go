@@ -0,0 +1,8 @@
+//go:build !windows && !linux && !darwin && !freebsd
+
+package platform
+
+// FreeSpace is not available on this platform
+func FreeSpace(path string) (uint64, error) {
+	return 0, ErrUnsupported
+}
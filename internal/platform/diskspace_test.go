@@ -0,0 +1,20 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeSpace(t *testing.T) {
+	free, err := FreeSpace(t.TempDir())
+	if err == ErrUnsupported {
+		t.Skip("FreeSpace not supported on this platform")
+	}
+	require.NoError(t, err)
+	assert.Greater(t, free, uint64(0))
+
+	_, err = FreeSpace("/does/not/exist")
+	assert.Error(t, err)
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd
+
+package platform
+
+import "syscall"
+
+// FreeSpace returns the bytes available to the current user on the volume
+// containing path
+func FreeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
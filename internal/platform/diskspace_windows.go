@@ -0,0 +1,33 @@
+//go:build windows
+
+package platform
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// FreeSpace returns the bytes available to the current user on the volume
+// containing path
+func FreeSpace(path string) (uint64, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var available uint64
+	ret, _, callErr := procGetDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&available)),
+		0,
+		0,
+	)
+	if ret == 0 {
+		return 0, fmt.Errorf("GetDiskFreeSpaceEx failed: %w", callErr)
+	}
+
+	return available, nil
+}
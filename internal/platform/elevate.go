@@ -0,0 +1,6 @@
+package platform
+
+import "errors"
+
+// ErrElevationCancelled is returned when the user declined the UAC prompt
+var ErrElevationCancelled = errors.New("elevation cancelled")
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package platform
+
+// RunElevated returns ErrUnsupported, elsewhere the user reruns the command
+// with sudo
+func RunElevated(path string, args []string) (int, error) {
+	return 0, ErrUnsupported
+}
@@ -0,0 +1,86 @@
+//go:build windows
+
+package platform
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	seeMaskNoCloseProcess = 0x40
+	swShowNormal          = 1
+	errorCancelled        = syscall.Errno(1223)
+)
+
+var procShellExecuteEx = syscall.NewLazyDLL("shell32.dll").NewProc("ShellExecuteExW")
+
+// shellExecuteInfo is SHELLEXECUTEINFOW
+type shellExecuteInfo struct {
+	cbSize       uint32
+	fMask        uint32
+	hwnd         uintptr
+	lpVerb       *uint16
+	lpFile       *uint16
+	lpParameters *uint16
+	lpDirectory  *uint16
+	nShow        int32
+	hInstApp     uintptr
+	lpIDList     uintptr
+	lpClass      *uint16
+	hkeyClass    uintptr
+	dwHotKey     uint32
+	hIcon        uintptr
+	hProcess     syscall.Handle
+}
+
+// RunElevated runs path with args as administrator after a UAC prompt and
+// returns its exit code once it exits
+func RunElevated(path string, args []string) (int, error) {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = syscall.EscapeArg(arg)
+	}
+
+	verb, err := syscall.UTF16PtrFromString("runas")
+	if err != nil {
+		return 0, err
+	}
+	file, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	params, err := syscall.UTF16PtrFromString(strings.Join(quoted, " "))
+	if err != nil {
+		return 0, err
+	}
+
+	info := shellExecuteInfo{
+		fMask:        seeMaskNoCloseProcess,
+		lpVerb:       verb,
+		lpFile:       file,
+		lpParameters: params,
+		nShow:        swShowNormal,
+	}
+	info.cbSize = uint32(unsafe.Sizeof(info))
+
+	ret, _, callErr := procShellExecuteEx.Call(uintptr(unsafe.Pointer(&info)))
+	if ret == 0 {
+		if callErr == errorCancelled {
+			return 0, ErrElevationCancelled
+		}
+		return 0, fmt.Errorf("ShellExecuteEx failed: %w", callErr)
+	}
+	defer syscall.CloseHandle(info.hProcess)
+
+	if _, err := syscall.WaitForSingleObject(info.hProcess, syscall.INFINITE); err != nil {
+		return 0, fmt.Errorf("failed to wait for elevated process: %w", err)
+	}
+	var code uint32
+	if err := syscall.GetExitCodeProcess(info.hProcess, &code); err != nil {
+		return 0, fmt.Errorf("failed to get exit code of elevated process: %w", err)
+	}
+	return int(code), nil
+}
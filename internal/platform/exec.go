@@ -0,0 +1,36 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// ErrBlockedByOS is returned for binaries macOS refused to run
+var ErrBlockedByOS = errors.New("blocked by macOS")
+
+// ExplainExecError adds what to do to the error of running the binary at
+// path when macOS blocked it. Other errors are returned unchanged
+func ExplainExecError(path string, err error) error {
+	return explainExecError(runtime.GOOS, path, err)
+}
+
+// explainExecError is ExplainExecError on goos
+func explainExecError(goos, path string, err error) error {
+	if goos != "darwin" || err == nil {
+		return err
+	}
+
+	msg := err.Error()
+	switch {
+	// Code signing enforcement kills a rejected binary before it runs
+	case strings.Contains(msg, "signal: killed"):
+		return fmt.Errorf("%w: %s was killed at launch, its code signature was probably rejected; allow it in System Settings > Privacy & Security or run `codesign --force --sign - %s`: %w", ErrBlockedByOS, path, path, err)
+	case strings.Contains(msg, "bad CPU type"):
+		return fmt.Errorf("%w: %s is an Intel binary, install Rosetta with `softwareupdate --install-rosetta`: %w", ErrBlockedByOS, path, err)
+	case strings.Contains(msg, "operation not permitted"):
+		return fmt.Errorf("%w: %s is quarantined, run `xattr -d com.apple.quarantine %s`: %w", ErrBlockedByOS, path, path, err)
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+package platform
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainExecError(t *testing.T) {
+	killed := errors.New("signal: killed")
+	err := explainExecError("darwin", "/Utils/yt-dlp_macos", killed)
+	assert.ErrorIs(t, err, ErrBlockedByOS)
+	assert.ErrorContains(t, err, "code signature")
+	assert.ErrorContains(t, err, "/Utils/yt-dlp_macos")
+	assert.ErrorIs(t, err, killed)
+
+	err = explainExecError("darwin", "/Utils/yt-dlp_macos", errors.New("fork/exec /Utils/yt-dlp_macos: bad CPU type in executable"))
+	assert.ErrorIs(t, err, ErrBlockedByOS)
+	assert.ErrorContains(t, err, "Rosetta")
+
+	err = explainExecError("darwin", "/Utils/yt-dlp_macos", errors.New("fork/exec /Utils/yt-dlp_macos: operation not permitted"))
+	assert.ErrorContains(t, err, "xattr -d com.apple.quarantine")
+
+	// Other errors and platforms are left alone
+	other := errors.New("exit status 1")
+	assert.Equal(t, other, explainExecError("darwin", "/Utils/yt-dlp_macos", other))
+	assert.Equal(t, killed, explainExecError("linux", "/Utils/yt-dlp_linux", killed))
+	assert.NoError(t, explainExecError("darwin", "/Utils/yt-dlp_macos", nil))
+}
+
+func TestRemoveQuarantine(t *testing.T) {
+	// A file without the attribute is not an error
+	assert.NoError(t, RemoveQuarantine(t.TempDir()))
+}
@@ -0,0 +1,6 @@
+// Package platform provides platform-specific operations
+package platform
+
+import "errors"
+
+var ErrUnsupported = errors.New("not supported on this platform")
@@ -0,0 +1,23 @@
+//go:build darwin
+
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// quarantineAttr is the extended attribute that makes Gatekeeper check a
+// downloaded file before its first run
+const quarantineAttr = "com.apple.quarantine"
+
+// RemoveQuarantine clears the quarantine attribute of a downloaded binary
+// so macOS runs it without a Gatekeeper prompt
+func RemoveQuarantine(path string) error {
+	out, err := exec.Command("xattr", "-d", quarantineAttr, path).CombinedOutput()
+	if err != nil && !strings.Contains(string(out), "No such xattr") {
+		return fmt.Errorf("failed to remove quarantine from %s: %v: %s", path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !darwin
+
+package platform
+
+// RemoveQuarantine does nothing, only macOS quarantines downloads
+func RemoveQuarantine(path string) error {
+	return nil
+}
@@ -0,0 +1,39 @@
+package reqid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+// Header carries the request ID from the stub to the server and back
+const Header = "X-Request-ID"
+
+// validID matches request IDs accepted from clients
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type contextKey struct{}
+
+// New returns a random request ID
+func New() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether a client-provided request ID is safe to log and echo
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// With returns a context carrying the request ID
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// From returns the request ID of a context, or "" if it has none
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
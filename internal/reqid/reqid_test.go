@@ -0,0 +1,21 @@
+package reqid
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	id := New()
+	assert.Len(t, id, 12)
+	assert.True(t, Valid(id))
+	assert.NotEqual(t, id, New())
+
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("bad id\n"))
+
+	assert.Empty(t, From(context.Background()))
+	assert.Equal(t, id, From(With(context.Background(), id)))
+}
@@ -0,0 +1,144 @@
+// Package startup times the steps of application startup, so slow steps
+// show up in the log and the GUI can show startup progress
+package startup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Budget is how long startup should take before the GUI is usable; slower
+// startups are logged with their slowest steps
+const Budget = 5 * time.Second
+
+// Step states
+const (
+	StateRunning = "running"
+	StateDone    = "done"
+	StateFailed  = "failed"
+	StateSkipped = "skipped"
+)
+
+// Step is a startup step
+type Step struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// Deferred steps run in the background after the GUI is usable
+	Deferred   bool      `json:"deferred,omitempty"`
+	Started    time.Time `json:"started"`
+	DurationMs int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Duration returns how long a finished step took
+func (s Step) Duration() time.Duration {
+	return time.Duration(s.DurationMs) * time.Millisecond
+}
+
+// Tracker records startup steps. It is safe for concurrent use, so
+// independent steps can run in parallel
+type Tracker struct {
+	mu      sync.Mutex
+	started time.Time
+	steps   []Step
+	notify  func(Step)
+	now     func() time.Time
+}
+
+// NewTracker creates a tracker calling notify (optional) whenever a step
+// starts or ends
+func NewTracker(notify func(Step)) *Tracker {
+	return &Tracker{started: time.Now(), notify: notify, now: time.Now}
+}
+
+// Run runs fn as the step name, recording its timing and error
+func (t *Tracker) Run(name string, fn func() error) error {
+	return t.run(name, false, fn)
+}
+
+// RunDeferred runs fn as the background step name
+func (t *Tracker) RunDeferred(name string, fn func() error) error {
+	return t.run(name, true, fn)
+}
+
+// run records a step around fn
+func (t *Tracker) run(name string, deferred bool, fn func() error) error {
+	i := t.add(Step{Name: name, State: StateRunning, Deferred: deferred, Started: t.now()})
+
+	err := fn()
+
+	t.update(i, func(s *Step) {
+		s.DurationMs = t.now().Sub(s.Started).Milliseconds()
+		s.State = StateDone
+		if err != nil {
+			s.State = StateFailed
+			s.Error = err.Error()
+		}
+	})
+	return err
+}
+
+// Skip records a step that did not run, with the reason
+func (t *Tracker) Skip(name, reason string) {
+	t.add(Step{Name: name, State: StateSkipped, Started: t.now(), Error: reason})
+}
+
+// add appends a step and returns its index
+func (t *Tracker) add(step Step) int {
+	t.mu.Lock()
+	t.steps = append(t.steps, step)
+	i := len(t.steps) - 1
+	t.mu.Unlock()
+
+	if t.notify != nil {
+		t.notify(step)
+	}
+	return i
+}
+
+// update changes step i
+func (t *Tracker) update(i int, fn func(*Step)) {
+	t.mu.Lock()
+	fn(&t.steps[i])
+	step := t.steps[i]
+	t.mu.Unlock()
+
+	if t.notify != nil {
+		t.notify(step)
+	}
+}
+
+// Steps returns the steps in the order they started
+func (t *Tracker) Steps() []Step {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Step{}, t.steps...)
+}
+
+// Elapsed returns the time since startup began
+func (t *Tracker) Elapsed() time.Duration {
+	return t.now().Sub(t.started)
+}
+
+// Summary describes the finished steps, slowest first, e.g.
+// "cache 1.2s, yt-dlp 800ms, config 3ms"
+func (t *Tracker) Summary() string {
+	steps := t.Steps()
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].DurationMs > steps[j].DurationMs
+	})
+
+	parts := make([]string, 0, len(steps))
+	for _, s := range steps {
+		switch s.State {
+		case StateDone:
+			parts = append(parts, fmt.Sprintf("%s %s", s.Name, s.Duration()))
+		case StateFailed:
+			parts = append(parts, fmt.Sprintf("%s %s (failed)", s.Name, s.Duration()))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
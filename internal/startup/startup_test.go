@@ -0,0 +1,79 @@
+package startup
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	var mu sync.Mutex
+	var events []Step
+	tracker := NewTracker(func(step Step) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, step)
+	})
+
+	// A fake clock advancing a second per reading
+	clock := time.Unix(0, 0)
+	tracker.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	require.NoError(t, tracker.Run("config", func() error { return nil }))
+	errFailed := errors.New("no network")
+	assert.ErrorIs(t, tracker.RunDeferred("update", func() error { return errFailed }), errFailed)
+	tracker.Skip("yt-dlp", "offline")
+
+	steps := tracker.Steps()
+	require.Len(t, steps, 3)
+
+	assert.Equal(t, "config", steps[0].Name)
+	assert.Equal(t, StateDone, steps[0].State)
+	assert.Equal(t, time.Second, steps[0].Duration())
+	assert.False(t, steps[0].Deferred)
+
+	assert.Equal(t, StateFailed, steps[1].State)
+	assert.True(t, steps[1].Deferred)
+	assert.Equal(t, "no network", steps[1].Error)
+
+	assert.Equal(t, StateSkipped, steps[2].State)
+	assert.Equal(t, "offline", steps[2].Error)
+
+	// Steps are reported when they start and when they end
+	require.Len(t, events, 5)
+	assert.Equal(t, StateRunning, events[0].State)
+	assert.Equal(t, StateDone, events[1].State)
+
+	assert.Equal(t, "config 1s, update 1s (failed)", tracker.Summary())
+}
+
+func TestTrackerParallel(t *testing.T) {
+	tracker := NewTracker(nil)
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"cache", "yt-dlp", "patch"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracker.Run(name, func() error {
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	steps := tracker.Steps()
+	require.Len(t, steps, 3)
+	for _, s := range steps {
+		assert.Equal(t, StateDone, s.State, s.Name)
+		assert.GreaterOrEqual(t, s.Duration(), 10*time.Millisecond, s.Name)
+	}
+}
@@ -0,0 +1,155 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReportsDir is the directory reports are written to in the data directory
+const ReportsDir = "reports"
+
+// topVideos is the number of most played videos listed in a report
+const topVideos = 10
+
+// discordMaxContent is the length limit of a Discord message
+const discordMaxContent = 2000
+
+// ErrWebhook is returned when a webhook rejects a report
+var ErrWebhook = errors.New("webhook rejected report")
+
+// Report summarizes the cache usage of a period
+type Report struct {
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	VideosCached    int       `json:"videosCached"`
+	BytesDownloaded int64     `json:"bytesDownloaded"`
+	Hits            int       `json:"hits"`
+	BytesSaved      int64     `json:"bytesSaved"`
+	TopVideos       []Video   `json:"topVideos"`
+	Failed          int       `json:"failed"`
+	Failures        []Failure `json:"failures"`
+}
+
+// newReport summarizes p, which ended at end
+func newReport(p period, end time.Time) *Report {
+	r := &Report{
+		From:            p.Start,
+		To:              end,
+		VideosCached:    p.Cached,
+		BytesDownloaded: p.BytesDownloaded,
+		Failed:          p.Failed,
+		Failures:        append([]Failure{}, p.Failures...),
+		TopVideos:       []Video{},
+	}
+
+	for _, v := range p.Videos {
+		r.Hits += v.Hits
+		r.BytesSaved += v.BytesSaved
+		r.TopVideos = append(r.TopVideos, *v)
+	}
+	sort.Slice(r.TopVideos, func(i, j int) bool {
+		if r.TopVideos[i].Hits != r.TopVideos[j].Hits {
+			return r.TopVideos[i].Hits > r.TopVideos[j].Hits
+		}
+		return r.TopVideos[i].ID < r.TopVideos[j].ID
+	})
+	if len(r.TopVideos) > topVideos {
+		r.TopVideos = r.TopVideos[:topVideos]
+	}
+
+	return r
+}
+
+// Markdown renders the report as Markdown, which Discord displays as well
+func (r *Report) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "**VRCVideoCacher report %s - %s**\n\n", r.From.Format("2006-01-02"), r.To.Format("2006-01-02"))
+	fmt.Fprintf(&b, "- Videos cached: %d (%s downloaded)\n", r.VideosCached, formatBytes(r.BytesDownloaded))
+	fmt.Fprintf(&b, "- Played from cache: %d times (%s saved)\n", r.Hits, formatBytes(r.BytesSaved))
+	fmt.Fprintf(&b, "- Failed downloads: %d\n", r.Failed)
+
+	if len(r.TopVideos) > 0 {
+		b.WriteString("\n**Top videos**\n")
+		for i, v := range r.TopVideos {
+			fmt.Fprintf(&b, "%d. https://youtu.be/%s - %d plays\n", i+1, v.ID, v.Hits)
+		}
+	}
+
+	if len(r.Failures) > 0 {
+		b.WriteString("\n**Recent failures**\n")
+		for _, f := range r.Failures {
+			fmt.Fprintf(&b, "- %s: %s\n", f.VideoID, f.Error)
+		}
+	}
+
+	return b.String()
+}
+
+// Write saves the report as Markdown in dir, named after its end date, and
+// returns the file path
+func (r *Report) Write(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("report-%s.md", r.To.Format("2006-01-02")))
+	if err := os.WriteFile(path, []byte(r.Markdown()), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// PostDiscord sends the report to a Discord webhook, cut to the message
+// length limit
+func (r *Report) PostDiscord(ctx context.Context, client *http.Client, webhookURL string) error {
+	content := r.Markdown()
+	if len(content) > discordMaxContent {
+		content = content[:strings.LastIndex(content[:discordMaxContent-4], "\n")+1] + "..."
+	}
+
+	body, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: %s", ErrWebhook, resp.Status)
+	}
+	return nil
+}
+
+// formatBytes formats a size in binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReport() *Report {
+	return &Report{
+		From:            time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:              time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC),
+		VideosCached:    3,
+		BytesDownloaded: 300 << 20,
+		Hits:            12,
+		BytesSaved:      3 << 30,
+		TopVideos:       []Video{{ID: "VIDEO_ID", Hits: 10}},
+		Failed:          1,
+		Failures:        []Failure{{VideoID: "BROKEN", Error: "video unavailable"}},
+	}
+}
+
+func TestReport_Markdown(t *testing.T) {
+	md := testReport().Markdown()
+
+	assert.Contains(t, md, "2026-01-01 - 2026-01-08")
+	assert.Contains(t, md, "Videos cached: 3 (300.0 MiB downloaded)")
+	assert.Contains(t, md, "Played from cache: 12 times (3.0 GiB saved)")
+	assert.Contains(t, md, "1. https://youtu.be/VIDEO_ID - 10 plays")
+	assert.Contains(t, md, "- BROKEN: video unavailable")
+}
+
+func TestReport_Write(t *testing.T) {
+	dir := t.TempDir()
+
+	path, err := testReport().Write(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "report-2026-01-08.md", strings.TrimPrefix(path, dir+string(os.PathSeparator)))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Videos cached: 3")
+}
+
+func TestReport_PostDiscord(t *testing.T) {
+	var content string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		content = body["content"]
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	report := testReport()
+	for i := 0; i < 200; i++ {
+		report.Failures = append(report.Failures, Failure{VideoID: "BROKEN", Error: "video unavailable"})
+	}
+
+	require.NoError(t, report.PostDiscord(context.Background(), server.Client(), server.URL))
+	assert.LessOrEqual(t, len(content), discordMaxContent)
+	assert.True(t, strings.HasSuffix(content, "\n..."))
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer rejecting.Close()
+	assert.ErrorIs(t, report.PostDiscord(context.Background(), rejecting.Client(), rejecting.URL), ErrWebhook)
+}
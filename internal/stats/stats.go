@@ -0,0 +1,172 @@
+// Package stats collects cache usage over a reporting period, persisted so
+// a weekly summary survives restarts
+package stats
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileName is the stats file in the data directory
+const FileName = "stats.json"
+
+// maxFailures is the number of failures kept with their error per period
+const maxFailures = 20
+
+// Video is the usage of a single cached video
+type Video struct {
+	ID   string `json:"id"`
+	Hits int    `json:"hits"`
+	// BytesSaved is the size of the file times the times it was served
+	// from the cache instead of downloaded by the player
+	BytesSaved int64 `json:"bytesSaved"`
+}
+
+// Failure is a download that failed
+type Failure struct {
+	VideoID string    `json:"videoId"`
+	Error   string    `json:"error"`
+	Time    time.Time `json:"time"`
+}
+
+// period is the usage since the last report
+type period struct {
+	Start           time.Time         `json:"start"`
+	Videos          map[string]*Video `json:"videos"`
+	Cached          int               `json:"cached"`
+	BytesDownloaded int64             `json:"bytesDownloaded"`
+	Failed          int               `json:"failed"`
+	Failures        []Failure         `json:"failures"`
+}
+
+// newPeriod starts an empty period at start
+func newPeriod(start time.Time) period {
+	return period{Start: start, Videos: make(map[string]*Video)}
+}
+
+// Collector records cache usage for reports
+type Collector struct {
+	mu     sync.Mutex
+	path   string
+	period period
+	dirty  bool
+}
+
+// NewCollector loads the stats saved at path, starting a new period at now
+// if there are none
+func NewCollector(path string, now time.Time) *Collector {
+	c := &Collector{path: path, period: newPeriod(now)}
+
+	if data, err := os.ReadFile(path); err == nil {
+		var p period
+		if err := json.Unmarshal(data, &p); err == nil && !p.Start.IsZero() {
+			if p.Videos == nil {
+				p.Videos = make(map[string]*Video)
+			}
+			c.period = p
+		}
+	}
+
+	return c
+}
+
+// Hit records a video served from the cache
+func (c *Collector) Hit(videoID string, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.period.Videos[videoID]
+	if !ok {
+		v = &Video{ID: videoID}
+		c.period.Videos[videoID] = v
+	}
+	v.Hits++
+	v.BytesSaved += size
+	c.dirty = true
+}
+
+// Hits returns how often a video was served from the cache this period
+func (c *Collector) Hits(videoID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if v, ok := c.period.Videos[videoID]; ok {
+		return v.Hits
+	}
+	return 0
+}
+
+// Cached records a finished download
+func (c *Collector) Cached(videoID string, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.period.Cached++
+	c.period.BytesDownloaded += size
+	c.dirty = true
+}
+
+// Failed records a failed download
+func (c *Collector) Failed(videoID string, err error, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.period.Failed++
+	c.period.Failures = append(c.period.Failures, Failure{VideoID: videoID, Error: err.Error(), Time: now})
+	if len(c.period.Failures) > maxFailures {
+		c.period.Failures = c.period.Failures[len(c.period.Failures)-maxFailures:]
+	}
+	c.dirty = true
+}
+
+// PeriodStart returns when the current period started
+func (c *Collector) PeriodStart() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.period.Start
+}
+
+// Rotate ends the current period at now, returning its report, and starts
+// a new one
+func (c *Collector) Rotate(now time.Time) (*Report, error) {
+	c.mu.Lock()
+	report := newReport(c.period, now)
+	c.period = newPeriod(now)
+	c.dirty = true
+	c.mu.Unlock()
+
+	return report, c.Save()
+}
+
+// Save writes the stats to disk if they changed
+func (c *Collector) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c.period, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,77 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c := NewCollector(path, start)
+	assert.Equal(t, start, c.PeriodStart())
+
+	c.Cached("A", 100)
+	c.Cached("B", 50)
+	c.Hit("A", 100)
+	c.Hit("A", 100)
+	c.Hit("B", 50)
+	c.Failed("C", errors.New("HTTP Error 429"), start.Add(time.Hour))
+	require.NoError(t, c.Save())
+	assert.Equal(t, 2, c.Hits("A"))
+	assert.Zero(t, c.Hits("C"))
+
+	// Stats survive a restart
+	c = NewCollector(path, start.Add(24*time.Hour))
+	assert.Equal(t, start, c.PeriodStart())
+
+	end := start.Add(7 * 24 * time.Hour)
+	report, err := c.Rotate(end)
+	require.NoError(t, err)
+	assert.Equal(t, start, report.From)
+	assert.Equal(t, end, report.To)
+	assert.Equal(t, 2, report.VideosCached)
+	assert.Equal(t, int64(150), report.BytesDownloaded)
+	assert.Equal(t, 3, report.Hits)
+	assert.Equal(t, int64(250), report.BytesSaved)
+	require.Len(t, report.TopVideos, 2)
+	assert.Equal(t, "A", report.TopVideos[0].ID)
+	assert.Equal(t, 2, report.TopVideos[0].Hits)
+	assert.Equal(t, 1, report.Failed)
+	require.Len(t, report.Failures, 1)
+	assert.Equal(t, "HTTP Error 429", report.Failures[0].Error)
+
+	// A new period starts empty
+	c = NewCollector(path, time.Now())
+	assert.Equal(t, end, c.PeriodStart())
+	report, err = c.Rotate(end.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, report.Hits)
+	assert.Empty(t, report.TopVideos)
+}
+
+func TestCollector_Bounds(t *testing.T) {
+	c := NewCollector(filepath.Join(t.TempDir(), FileName), time.Now())
+
+	for i := 0; i < maxFailures+5; i++ {
+		c.Failed(fmt.Sprintf("F%d", i), errors.New("failed"), time.Now())
+	}
+	for i := 0; i < topVideos+5; i++ {
+		c.Hit(fmt.Sprintf("V%02d", i), 1)
+	}
+
+	report, err := c.Rotate(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, maxFailures+5, report.Failed)
+	assert.Len(t, report.Failures, maxFailures)
+	assert.Equal(t, "F5", report.Failures[0].VideoID)
+	assert.Len(t, report.TopVideos, topVideos)
+	assert.Equal(t, topVideos+5, report.Hits)
+}
@@ -0,0 +1,140 @@
+package support
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"vrcvideocacher/pkg/models"
+)
+
+// redacted replaces values that must not leave the user's machine
+const redacted = "[redacted]"
+
+// Bundle collects troubleshooting files into a zip for bug reports
+// Every text added is stripped of the user's home directory
+type Bundle struct {
+	home  string
+	files []bundleFile
+}
+
+// bundleFile is a file in the bundle
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// NewBundle creates an empty bundle redacting the home directory home
+func NewBundle(home string) *Bundle {
+	return &Bundle{home: home}
+}
+
+// Add adds a file to the bundle
+func (b *Bundle) Add(name string, data []byte) {
+	b.files = append(b.files, bundleFile{name: name, data: []byte(b.Redact(string(data)))})
+}
+
+// AddJSON adds v as an indented JSON file
+func (b *Bundle) AddJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	b.Add(name, data)
+	return nil
+}
+
+// Names returns the names of the files in the bundle
+func (b *Bundle) Names() []string {
+	names := make([]string, len(b.files))
+	for i, f := range b.files {
+		names[i] = f.name
+	}
+	return names
+}
+
+// Redact replaces the home directory in s with ~
+// JSON-escaped Windows paths are replaced too
+func (b *Bundle) Redact(s string) string {
+	if b.home == "" {
+		return s
+	}
+
+	s = strings.ReplaceAll(s, b.home, "~")
+	if escaped := strings.ReplaceAll(b.home, `\`, `\\`); escaped != b.home {
+		s = strings.ReplaceAll(s, escaped, "~")
+	}
+	return s
+}
+
+// WriteZip writes the bundle as a zip archive
+func (b *Bundle) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	for _, f := range b.files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", f.name, err)
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish zip: %w", err)
+	}
+	return nil
+}
+
+// RedactConfig returns a copy of cfg safe to share: additional yt-dlp
+// arguments (which may hold proxies or credentials), the GitHub token, the
+// report webhook (whose path is its token) and cookie account names are
+// hidden, and credentials and queries are stripped from subscription and
+// mirror URLs
+func RedactConfig(cfg *models.Config) *models.Config {
+	clone := cfg.Clone()
+
+	if clone.YtdlAdditionalArgs != "" {
+		clone.YtdlAdditionalArgs = redacted
+	}
+	if clone.GitHubToken != "" {
+		clone.GitHubToken = redacted
+	}
+	if clone.ReportWebhookURL != "" {
+		clone.ReportWebhookURL = redacted
+	}
+	for i := range clone.YtdlCookieAccounts {
+		clone.YtdlCookieAccounts[i] = redacted
+	}
+	for i, entry := range clone.BlocklistURLs {
+		clone.BlocklistURLs[i] = redactURL(entry)
+	}
+	for i, mirror := range clone.DownloadMirrors {
+		clone.DownloadMirrors[i] = redactURL(mirror)
+	}
+
+	return clone
+}
+
+// redactURL removes credentials and the query from a URL
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+
+	u.User = nil
+	if u.RawQuery != "" {
+		u.RawQuery = redacted
+	}
+	return u.String()
+}
@@ -0,0 +1,190 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// requestTimeout bounds one API request
+	requestTimeout = 5 * time.Second
+
+	// reconnectDelay is how long the event stream waits before reconnecting
+	reconnectDelay = 2 * time.Second
+
+	// listLimit is how many downloads and requests are fetched
+	listLimit = 10
+)
+
+// Status is the part of GET /api/status the dashboard shows
+type Status struct {
+	Version         string `json:"version"`
+	Caching         bool   `json:"caching"`
+	SafeMode        bool   `json:"safeMode"`
+	Offline         bool   `json:"offline"`
+	CacheSize       int64  `json:"cacheSize"`
+	CacheMaxSize    int64  `json:"cacheMaxSize"`
+	CacheCount      int    `json:"cacheCount"`
+	DownloadsActive int    `json:"downloadsActive"`
+	DownloadsQueued int    `json:"downloadsQueued"`
+	DownloadsPaused bool   `json:"downloadsPaused"`
+}
+
+// Download is one item of GET /api/downloads
+type Download struct {
+	VideoID  string  `json:"videoId"`
+	Status   string  `json:"status"`
+	Progress float64 `json:"progress"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Request is one getvideo response of GET /api/now-playing
+type Request struct {
+	VideoID   string    `json:"videoId"`
+	URL       string    `json:"url"`
+	Source    string    `json:"source"`
+	Result    string    `json:"result"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Snapshot is the server state shown by the dashboard
+type Snapshot struct {
+	Status    Status
+	Downloads []Download
+	Requests  []Request
+}
+
+// Event is a message of the /ws event stream
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Client talks to a running server
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client for the server at baseURL, e.g.
+// http://127.0.0.1:9696
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Snapshot fetches the status, the newest downloads and the recent getvideo
+// requests
+func (c *Client) Snapshot(ctx context.Context) (Snapshot, error) {
+	var snap Snapshot
+	if err := c.get(ctx, "/api/status", &snap.Status); err != nil {
+		return snap, err
+	}
+
+	var downloads struct {
+		Items []Download `json:"items"`
+	}
+	if err := c.get(ctx, fmt.Sprintf("/api/downloads?limit=%d", listLimit), &downloads); err != nil {
+		return snap, err
+	}
+	snap.Downloads = downloads.Items
+
+	var nowPlaying struct {
+		History []Request `json:"history"`
+	}
+	if err := c.get(ctx, fmt.Sprintf("/api/now-playing?limit=%d", listLimit), &nowPlaying); err != nil {
+		return snap, err
+	}
+	snap.Requests = nowPlaying.History
+
+	return snap, nil
+}
+
+// Action runs one of the /api/actions endpoints and returns its message
+func (c *Client) Action(ctx context.Context, action string, query url.Values) (string, error) {
+	target := c.baseURL + "/api/actions/" + action
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("%s: %s", action, strings.TrimSpace(string(msg)))
+	}
+
+	var result struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode %s result: %w", action, err)
+	}
+	return result.Message, nil
+}
+
+// WatchEvents sends the events of the server's /ws stream to fn until ctx is
+// done, reconnecting when the connection drops
+func (c *Client) WatchEvents(ctx context.Context, fn func(Event)) {
+	wsURL := "ws" + strings.TrimPrefix(c.baseURL, "http") + "/ws"
+	for ctx.Err() == nil {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+		if err == nil {
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			for {
+				var event Event
+				if err := conn.ReadJSON(&event); err != nil {
+					break
+				}
+				fn(event)
+			}
+			stop()
+			conn.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// get decodes the JSON response of a GET request to path into v
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: server returned status %d", path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
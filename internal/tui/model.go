@@ -0,0 +1,302 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"vrcvideocacher/internal/events"
+	"vrcvideocacher/internal/patcher"
+)
+
+// targets are the patch targets the target key cycles through
+var targets = []string{patcher.TargetVRChat, patcher.TargetVRChatBeta, patcher.TargetResonite, patcher.TargetChilloutVR}
+
+// gaugeWidth is the number of cells of the cache usage gauge
+const gaugeWidth = 30
+
+// keyCtrlC is the byte Ctrl+C sends in raw mode
+const keyCtrlC = 3
+
+// Msg is something that happened: a key press, fetched state, a server
+// event or the result of an action
+type Msg any
+
+type (
+	// keyMsg is a key pressed by the user
+	keyMsg rune
+	// tickMsg asks for a periodic refresh
+	tickMsg struct{}
+	// resizeMsg reports the terminal width
+	resizeMsg int
+	// eventMsg is an event of the server's event stream
+	eventMsg Event
+	// snapshotMsg is the result of fetching the server state
+	snapshotMsg struct {
+		snap Snapshot
+		err  error
+	}
+	// actionMsg is the result of an action
+	actionMsg struct {
+		message string
+		err     error
+	}
+)
+
+// Cmd is work started by Update, run outside the update loop; its message
+// is fed back to Update
+type Cmd func(context.Context) Msg
+
+// Model is the dashboard state. Update applies messages to it and View
+// renders it
+type Model struct {
+	client  *Client
+	baseURL string
+	target  int
+	width   int
+
+	snap     Snapshot
+	fetched  bool
+	fetching bool
+	stale    bool
+	err      error
+	message  string
+	quitting bool
+}
+
+// NewModel creates the dashboard of the server client talks to
+func NewModel(client *Client, baseURL string) *Model {
+	return &Model{client: client, baseURL: baseURL, width: 80}
+}
+
+// Init returns the command fetching the initial state
+func (m *Model) Init() Cmd {
+	return m.fetch()
+}
+
+// Quitting reports whether the user asked to quit
+func (m *Model) Quitting() bool {
+	return m.quitting
+}
+
+// Update applies msg to the model and returns the command to run next, if
+// any
+func (m *Model) Update(msg Msg) Cmd {
+	switch msg := msg.(type) {
+	case keyMsg:
+		return m.handleKey(msg)
+	case tickMsg:
+		return m.fetch()
+	case resizeMsg:
+		if msg > 0 {
+			m.width = int(msg)
+		}
+	case eventMsg:
+		return m.handleEvent(Event(msg))
+	case snapshotMsg:
+		m.fetching = false
+		m.err = msg.err
+		if msg.err == nil {
+			m.snap = msg.snap
+			m.fetched = true
+		}
+		if m.stale {
+			return m.fetch()
+		}
+	case actionMsg:
+		if msg.err != nil {
+			m.message = "Error: " + msg.err.Error()
+		} else {
+			m.message = msg.message
+		}
+		return m.fetch()
+	}
+	return nil
+}
+
+// handleKey runs the action bound to key
+func (m *Model) handleKey(key keyMsg) Cmd {
+	target := targets[m.target]
+	switch key {
+	case 'q', keyCtrlC:
+		m.quitting = true
+	case 'r':
+		return m.fetch()
+	case 't':
+		m.target = (m.target + 1) % len(targets)
+		m.message = "Target: " + targets[m.target]
+	case 'p':
+		m.message = "Patching " + target + "..."
+		return m.action("patch", url.Values{"target": {target}})
+	case 'u':
+		m.message = "Restoring " + target + "..."
+		return m.action("unpatch", url.Values{"target": {target}})
+	case ' ':
+		return m.action("pause-downloads", url.Values{"paused": {fmt.Sprint(!m.snap.Status.DownloadsPaused)}})
+	case 'c':
+		return m.action("toggle-cache", url.Values{"enabled": {fmt.Sprint(!m.snap.Status.Caching)}})
+	}
+	return nil
+}
+
+// handleEvent applies download progress in place and fetches the state
+// again for other events
+func (m *Model) handleEvent(event Event) Cmd {
+	if event.Type != events.DownloadProgress {
+		return m.fetch()
+	}
+
+	var data events.Download
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return nil
+	}
+	for i := range m.snap.Downloads {
+		if m.snap.Downloads[i].VideoID == data.VideoID {
+			m.snap.Downloads[i].Status = "downloading"
+			m.snap.Downloads[i].Progress = data.Progress
+		}
+	}
+	return nil
+}
+
+// fetch returns the command fetching the server state, or marks the state
+// stale if a fetch is already running
+func (m *Model) fetch() Cmd {
+	if m.fetching {
+		m.stale = true
+		return nil
+	}
+	m.fetching = true
+	m.stale = false
+
+	client := m.client
+	return func(ctx context.Context) Msg {
+		snap, err := client.Snapshot(ctx)
+		return snapshotMsg{snap: snap, err: err}
+	}
+}
+
+// action returns the command running an action endpoint
+func (m *Model) action(name string, query url.Values) Cmd {
+	client := m.client
+	return func(ctx context.Context) Msg {
+		message, err := client.Action(ctx, name, query)
+		return actionMsg{message: message, err: err}
+	}
+}
+
+// View renders the dashboard as lines fitting the terminal width
+func (m *Model) View() []string {
+	lines := []string{fmt.Sprintf("VRCYouTubePatcher %s  %s", m.snap.Status.Version, m.baseURL)}
+
+	if !m.fetched {
+		if m.err != nil {
+			lines = append(lines, "", "Server unreachable: "+m.err.Error(), "Is `vrcvideocacher server` running?")
+		} else {
+			lines = append(lines, "", "Connecting...")
+		}
+		return m.fit(append(lines, "", "[q] quit"))
+	}
+
+	status := m.snap.Status
+	state := []string{onOff("Caching", status.Caching)}
+	if status.DownloadsPaused {
+		state = append(state, "Downloads paused")
+	} else {
+		state = append(state, "Downloads running")
+	}
+	if status.Offline {
+		state = append(state, "Offline")
+	}
+	if status.SafeMode {
+		state = append(state, "Safe mode")
+	}
+	if m.err != nil {
+		state = append(state, "Server unreachable")
+	}
+	lines = append(lines, strings.Join(state, " · "))
+	lines = append(lines, "Cache "+gauge(status.CacheSize, status.CacheMaxSize)+fmt.Sprintf(" · %d videos", status.CacheCount))
+
+	lines = append(lines, "", fmt.Sprintf("Downloads (%d active, %d queued)", status.DownloadsActive, status.DownloadsQueued))
+	if len(m.snap.Downloads) == 0 {
+		lines = append(lines, "  none")
+	}
+	for _, d := range m.snap.Downloads {
+		line := fmt.Sprintf("  %-14s %-12s", d.VideoID, d.Status)
+		switch {
+		case d.Status == "downloading":
+			line += fmt.Sprintf(" %3.0f%%", d.Progress)
+		case d.Error != "":
+			line += " " + firstLine(d.Error)
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, "", "Recent requests")
+	if len(m.snap.Requests) == 0 {
+		lines = append(lines, "  none")
+	}
+	for _, r := range m.snap.Requests {
+		id := r.VideoID
+		if id == "" {
+			id = r.URL
+		}
+		lines = append(lines, fmt.Sprintf("  %s  %-10s %-12s %s", r.Timestamp.Local().Format("15:04:05"), r.Source, r.Result, id))
+	}
+
+	lines = append(lines, "",
+		fmt.Sprintf("[p] patch %s  [u] unpatch  [t] target  [space] pause  [c] caching  [r] refresh  [q] quit", targets[m.target]),
+		m.message)
+	return m.fit(lines)
+}
+
+// fit cuts lines to the terminal width
+func (m *Model) fit(lines []string) []string {
+	for i, line := range lines {
+		if r := []rune(line); len(r) > m.width {
+			lines[i] = string(r[:m.width])
+		}
+	}
+	return lines
+}
+
+// onOff describes a setting as on or off
+func onOff(name string, on bool) string {
+	if on {
+		return name + " on"
+	}
+	return name + " off"
+}
+
+// gauge draws the cache usage against its limit, or the size alone if
+// there is no limit
+func gauge(size, limit int64) string {
+	if limit <= 0 {
+		return fmt.Sprintf("%s (no limit)", formatBytes(size))
+	}
+	filled := min(int(size*gaugeWidth/limit), gaugeWidth)
+	return fmt.Sprintf("[%s%s] %s / %s", strings.Repeat("█", filled), strings.Repeat("░", gaugeWidth-filled),
+		formatBytes(size), formatBytes(limit))
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// formatBytes formats a size in binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
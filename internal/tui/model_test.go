@@ -0,0 +1,138 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/events"
+)
+
+// fakeServer answers the endpoints the dashboard uses and records actions
+func fakeServer(t *testing.T) (*httptest.Server, *[]string) {
+	var actions []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version":"0.1.0","caching":true,"cacheSize":536870912,"cacheMaxSize":1073741824,"cacheCount":3,"downloadsActive":1}`))
+	})
+	mux.HandleFunc("GET /api/downloads", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"total":2,"items":[{"videoId":"abc123","status":"downloading","progress":40},{"videoId":"def456","status":"failed","error":"download failed: HTTP Error 403\nmore"}]}`))
+	})
+	mux.HandleFunc("GET /api/now-playing", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"total":1,"history":[{"videoId":"abc123","source":"vrchat","result":"queued","timestamp":"2026-02-05T12:00:00Z"}]}`))
+	})
+	mux.HandleFunc("POST /api/actions/{action}", func(w http.ResponseWriter, r *http.Request) {
+		actions = append(actions, r.PathValue("action")+"?"+r.URL.RawQuery)
+		w.Write([]byte(`{"action":"` + r.PathValue("action") + `","changed":true,"message":"done"}`))
+	})
+	mux.HandleFunc("GET /ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteJSON(events.Event{Type: events.DownloadProgress, Data: events.Download{VideoID: "abc123", Progress: 75}})
+		conn.ReadMessage() // Until the client leaves
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &actions
+}
+
+// runCmd runs cmd and applies its message to m, returning the next command
+func runCmd(t *testing.T, m *Model, cmd Cmd) Cmd {
+	require.NotNil(t, cmd)
+	return m.Update(cmd(context.Background()))
+}
+
+func TestModelView(t *testing.T) {
+	server, _ := fakeServer(t)
+	m := NewModel(NewClient(server.URL), server.URL)
+
+	assert.Contains(t, strings.Join(m.View(), "\n"), "Connecting...")
+
+	assert.Nil(t, runCmd(t, m, m.Init()))
+	view := strings.Join(m.View(), "\n")
+	assert.Contains(t, view, "Caching on · Downloads running")
+	assert.Contains(t, view, "512.0 MiB / 1.0 GiB · 3 videos")
+	assert.Contains(t, view, "abc123         downloading   40%")
+	assert.Contains(t, view, "download failed: HTTP Error 403")
+	assert.NotContains(t, view, "more")
+	assert.Contains(t, view, "vrchat     queued")
+
+	// Lines are cut to the terminal width
+	m.Update(resizeMsg(20))
+	for _, line := range m.View() {
+		assert.LessOrEqual(t, len([]rune(line)), 20)
+	}
+}
+
+func TestModelUnreachable(t *testing.T) {
+	m := NewModel(NewClient("http://127.0.0.1:1"), "http://127.0.0.1:1")
+	runCmd(t, m, m.Init())
+	assert.Contains(t, strings.Join(m.View(), "\n"), "Server unreachable")
+}
+
+func TestModelKeys(t *testing.T) {
+	server, actions := fakeServer(t)
+	m := NewModel(NewClient(server.URL), server.URL)
+	runCmd(t, m, m.Init())
+
+	// Actions run on the selected target and refresh the state
+	m.Update(keyMsg('t'))
+	next := runCmd(t, m, m.Update(keyMsg('p')))
+	assert.Equal(t, "done", m.message)
+	runCmd(t, m, next)
+	runCmd(t, m, runCmd(t, m, m.Update(keyMsg('u'))))
+	runCmd(t, m, runCmd(t, m, m.Update(keyMsg(' '))))
+	runCmd(t, m, runCmd(t, m, m.Update(keyMsg('c'))))
+	assert.Equal(t, []string{
+		"patch?target=vrchat-beta",
+		"unpatch?target=vrchat-beta",
+		"pause-downloads?paused=true",
+		"toggle-cache?enabled=false",
+	}, *actions)
+
+	// Unbound keys do nothing
+	assert.Nil(t, m.Update(keyMsg('x')))
+	assert.False(t, m.Quitting())
+	m.Update(keyMsg('q'))
+	assert.True(t, m.Quitting())
+}
+
+func TestModelEvents(t *testing.T) {
+	server, _ := fakeServer(t)
+	m := NewModel(NewClient(server.URL), server.URL)
+	runCmd(t, m, m.Init())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan Event, 1)
+	go m.client.WatchEvents(ctx, func(e Event) { received <- e })
+
+	// Progress is applied in place
+	var event Event
+	select {
+	case event = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+	assert.Nil(t, m.Update(eventMsg(event)))
+	assert.Equal(t, float64(75), m.snap.Downloads[0].Progress)
+
+	// Other events fetch the state again, once at a time
+	data, _ := json.Marshal(events.Download{VideoID: "def456"})
+	cmd := m.Update(eventMsg{Type: events.DownloadQueued, Data: data})
+	require.NotNil(t, cmd)
+	assert.Nil(t, m.Update(eventMsg{Type: events.DownloadQueued, Data: data}))
+	assert.NotNil(t, runCmd(t, m, cmd))
+}
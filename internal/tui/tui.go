@@ -0,0 +1,136 @@
+// Package tui is a terminal dashboard for a running server, for users who
+// run the cacher headless and reach it over SSH. It shows the download
+// queue, cache usage and recent requests, kept live by the /ws event
+// stream, and binds keys to the action endpoints. The model follows the Elm
+// architecture: Update applies messages and returns commands, View renders
+package tui
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// ErrNotTerminal is returned by Run when input or output is not a terminal
+var ErrNotTerminal = errors.New("not a terminal")
+
+// refreshInterval is how often the state is fetched without server events
+const refreshInterval = 2 * time.Second
+
+// Escape sequences switching to the alternate screen and back, and moving
+// the cursor home and clearing to the end of the line or screen
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	cursorHome  = "\x1b[H"
+	clearLine   = "\x1b[K"
+	clearBelow  = "\x1b[J"
+)
+
+// Run shows the dashboard of the server at baseURL on the terminal of in
+// and out until the user quits or ctx is done
+func Run(ctx context.Context, baseURL string, in, out *os.File) error {
+	inFd, outFd := int(in.Fd()), int(out.Fd())
+	if !term.IsTerminal(inFd) || !term.IsTerminal(outFd) {
+		return ErrNotTerminal
+	}
+
+	state, err := term.MakeRaw(inFd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(inFd, state)
+	io.WriteString(out, enterScreen)
+	defer io.WriteString(out, leaveScreen)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	msgs := make(chan Msg, 64)
+	send := func(msg Msg) {
+		select {
+		case msgs <- msg:
+		case <-ctx.Done():
+		}
+	}
+	run := func(cmd Cmd) {
+		if cmd != nil {
+			go func() { send(cmd(ctx)) }()
+		}
+	}
+
+	client := NewClient(baseURL)
+	go readKeys(in, send)
+	go client.WatchEvents(ctx, func(event Event) { send(eventMsg(event)) })
+	go tick(ctx, outFd, send)
+
+	m := NewModel(client, baseURL)
+	if width, _, err := term.GetSize(outFd); err == nil {
+		m.Update(resizeMsg(width))
+	}
+	run(m.Init())
+
+	for {
+		render(out, m.View())
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-msgs:
+			run(m.Update(msg))
+			if m.Quitting() {
+				return nil
+			}
+		}
+	}
+}
+
+// readKeys sends the keys read from in until reading fails. In raw mode
+// every byte is a key; escape sequences arrive as bytes without a binding
+func readKeys(in io.Reader, send func(Msg)) {
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		for _, b := range buf[:n] {
+			send(keyMsg(b))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// tick asks for a refresh and reports the terminal width periodically
+func tick(ctx context.Context, outFd int, send func(Msg)) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if width, _, err := term.GetSize(outFd); err == nil {
+				send(resizeMsg(width))
+			}
+			send(tickMsg{})
+		}
+	}
+}
+
+// render draws lines over the previous frame
+func render(w io.Writer, lines []string) {
+	var b strings.Builder
+	b.WriteString(cursorHome)
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteString(clearLine)
+		b.WriteString("\r\n")
+	}
+	b.WriteString(clearBelow)
+	io.WriteString(w, b.String())
+}
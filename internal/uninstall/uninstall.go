@@ -0,0 +1,185 @@
+// Package uninstall undoes what the cacher changed on the machine: every
+// patched yt-dlp.exe is restored, so VRChat is not left pointing at a stub
+// whose server is gone, and the cache, config and downloaded tools are
+// optionally deleted
+package uninstall
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"vrcvideocacher/internal/api"
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/config"
+	"vrcvideocacher/internal/cookies"
+	"vrcvideocacher/internal/github"
+	"vrcvideocacher/internal/patcher"
+	"vrcvideocacher/internal/paths"
+	"vrcvideocacher/internal/stats"
+)
+
+// ErrStillPatched is returned for a target whose stub could not be replaced
+// because there is no backup of the original yt-dlp.exe
+var ErrStillPatched = errors.New("stub still installed and no backup to restore")
+
+// Options control Run
+type Options struct {
+	// Paths locate the config, the downloaded tools, logs and app data
+	Paths paths.Paths
+	// CachePath is the cache directory
+	CachePath string
+
+	// RemoveCache deletes the cached videos and the saved cookies
+	RemoveCache bool
+	// RemoveConfig deletes the config, the GitHub API cache, the usage
+	// stats, the reports and the logs
+	RemoveConfig bool
+	// RemoveTools deletes the downloaded yt-dlp and aria2c
+	RemoveTools bool
+}
+
+// Report lists what Run changed
+type Report struct {
+	// Unpatched are the targets whose original yt-dlp.exe was restored
+	Unpatched []patcher.Target
+	// Removed are the deleted files and directories
+	Removed []string
+	// Kept are directories left in place because they hold other files
+	Kept []string
+	// Errors are the steps that failed; the others still ran
+	Errors []error
+}
+
+// Run unpatches every detected target, then removes what opts selects
+func Run(p *patcher.Patcher, opts Options) *Report {
+	report := &Report{}
+
+	for _, target := range p.ListTargets() {
+		if !target.Detected || (!target.Patched && !hasBackup(target.Path)) {
+			continue
+		}
+
+		if err := p.UnpatchTarget(target.Name); err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("failed to unpatch %s: %w", target.DisplayName, err))
+			continue
+		}
+		if patched, err := p.IsPatched(target.Path); err == nil && patched {
+			report.Errors = append(report.Errors, fmt.Errorf("%s: %w", target.DisplayName, ErrStillPatched))
+			continue
+		}
+		report.Unpatched = append(report.Unpatched, target)
+	}
+
+	dirs := opts.Paths
+	if opts.RemoveCache && opts.CachePath != "" {
+		report.removeCache(opts.CachePath)
+	}
+	if opts.RemoveCache && dirs.Data != "" {
+		report.removeCookies(dirs.Data)
+	}
+	if opts.RemoveTools && dirs.Utils != "" {
+		report.removeAll(dirs.Utils)
+	}
+	if opts.RemoveConfig {
+		if dirs.Config != "" {
+			report.remove(dirs.Config)
+			report.remove(filepath.Join(filepath.Dir(dirs.Config), config.DocsFileName))
+		}
+		if dirs.Data != "" {
+			report.remove(filepath.Join(dirs.Data, github.CacheFileName))
+			report.remove(filepath.Join(dirs.Data, stats.FileName))
+			report.remove(filepath.Join(dirs.Data, api.RPCTokenFileName))
+			report.remove(filepath.Join(dirs.Data, patcher.HashHistoryFileName))
+			report.removeAll(filepath.Join(dirs.Data, stats.ReportsDir))
+		}
+		if dirs.Logs != "" {
+			report.removeAll(dirs.Logs)
+		}
+	}
+
+	// The data directories themselves go once everything in them is gone
+	if opts.RemoveCache && opts.RemoveConfig && opts.RemoveTools {
+		seen := slices.Clone(report.Removed)
+		for _, dir := range []string{dirs.Logs, filepath.Dir(dirs.Config), dirs.Data} {
+			if dir == "" || dir == "." || slices.Contains(seen, dir) {
+				continue
+			}
+			seen = append(seen, dir)
+			report.removeEmpty(dir)
+		}
+	}
+
+	return report
+}
+
+// removeEmpty deletes dir if it exists and is empty
+func (r *Report) removeEmpty(dir string) {
+	if err := os.Remove(dir); err == nil {
+		r.Removed = append(r.Removed, dir)
+	} else if !os.IsNotExist(err) {
+		r.Kept = append(r.Kept, dir)
+	}
+}
+
+// removeCache deletes the cached videos in dir and any cookies earlier
+// versions kept there
+func (r *Report) removeCache(dir string) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return
+	}
+
+	r.removeCookies(dir)
+
+	if err := cache.NewManager(dir, 0).Purge(); err != nil {
+		r.Errors = append(r.Errors, fmt.Errorf("failed to remove cache: %w", err))
+		return
+	}
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		r.Removed = append(r.Removed, dir)
+	} else {
+		r.Kept = append(r.Kept, dir)
+	}
+}
+
+// removeCookies deletes the cookies of every account stored in dir
+func (r *Report) removeCookies(dir string) {
+	store := cookies.NewStore(dir)
+	for _, account := range store.List() {
+		if err := store.Delete(account); err != nil {
+			r.Errors = append(r.Errors, err)
+		}
+	}
+}
+
+// removeAll deletes a directory and its contents if it exists
+func (r *Report) removeAll(path string) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return
+	}
+	if err := os.RemoveAll(path); err != nil {
+		r.Errors = append(r.Errors, fmt.Errorf("failed to remove %s: %w", path, err))
+		return
+	}
+	r.Removed = append(r.Removed, path)
+}
+
+// remove deletes a file if it exists
+func (r *Report) remove(path string) {
+	if err := os.Remove(path); err != nil {
+		if !os.IsNotExist(err) {
+			r.Errors = append(r.Errors, fmt.Errorf("failed to remove %s: %w", path, err))
+		}
+		return
+	}
+	r.Removed = append(r.Removed, path)
+}
+
+// hasBackup reports whether dir holds a backup of the original yt-dlp.exe
+func hasBackup(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "yt-dlp.exe.bkp"))
+	return err == nil
+}
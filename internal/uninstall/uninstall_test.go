@@ -0,0 +1,148 @@
+package uninstall
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/cache"
+	"vrcvideocacher/internal/cookies"
+	"vrcvideocacher/internal/patcher"
+	"vrcvideocacher/internal/paths"
+)
+
+// setupTools creates the VRChat and VRChat beta Tools directories with an
+// original yt-dlp.exe under a temporary LocalLow
+func setupTools(t *testing.T) (stable, beta string) {
+	tempDir := t.TempDir()
+	t.Setenv("LOCALAPPDATA", filepath.Join(tempDir, "AppData", "Local"))
+	t.Setenv("ProgramFiles(x86)", "")
+
+	localLow := filepath.Join(tempDir, "AppData", "LocalLow")
+	stable = filepath.Join(localLow, "VRChat", "VRChat", "Tools")
+	beta = filepath.Join(localLow, "VRChat", "VRChat Beta", "Tools")
+	for _, dir := range []string{stable, beta} {
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "yt-dlp.exe"), []byte("original"), 0644))
+	}
+	return stable, beta
+}
+
+func TestRun_Unpatch(t *testing.T) {
+	stable, beta := setupTools(t)
+	p := patcher.NewPatcher([]byte("stub"))
+	require.NoError(t, p.PatchTarget(patcher.TargetVRChat))
+
+	report := Run(p, Options{})
+	require.Empty(t, report.Errors)
+	require.Len(t, report.Unpatched, 1)
+	assert.Equal(t, patcher.TargetVRChat, report.Unpatched[0].Name)
+
+	for _, dir := range []string{stable, beta} {
+		data, err := os.ReadFile(filepath.Join(dir, "yt-dlp.exe"))
+		require.NoError(t, err)
+		assert.Equal(t, "original", string(data))
+	}
+	assert.NoFileExists(t, filepath.Join(stable, "yt-dlp.exe.bkp"))
+	assert.Empty(t, report.Removed)
+}
+
+func TestRun_StubWithoutBackup(t *testing.T) {
+	_, beta := setupTools(t)
+	require.NoError(t, os.WriteFile(filepath.Join(beta, "yt-dlp.exe"), []byte("stub"), 0644))
+
+	report := Run(patcher.NewPatcher([]byte("stub")), Options{})
+	require.Len(t, report.Errors, 1)
+	assert.ErrorIs(t, report.Errors[0], ErrStillPatched)
+	assert.Empty(t, report.Unpatched)
+}
+
+func TestRun_RemoveAll(t *testing.T) {
+	setupTools(t)
+	dataDir := t.TempDir()
+	cacheDir := filepath.Join(dataDir, "cache")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "config.json"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "github-cache.json"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "stats.json"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "rpc-token"), []byte("token"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "known-hashes.txt"), []byte("# hashes"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDir, "reports"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "reports", "report-2026-01-01.md"), []byte("report"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDir, "Utils"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "Utils", "yt-dlp.exe"), []byte("yt-dlp"), 0755))
+
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "abc.mp4"), []byte("video"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("abc", "abc.mp4"))
+	require.NoError(t, cookies.NewStore(cacheDir).Save("", "cookies"))
+	require.NoError(t, cookies.NewStore(dataDir).Save("burner", "cookies"))
+
+	report := Run(patcher.NewPatcher([]byte("stub")), Options{
+		Paths:        paths.Flat(dataDir),
+		CachePath:    cacheDir,
+		RemoveCache:  true,
+		RemoveConfig: true,
+		RemoveTools:  true,
+	})
+	require.Empty(t, report.Errors)
+	assert.Empty(t, report.Kept)
+	assert.Contains(t, report.Removed, cacheDir)
+	assert.Contains(t, report.Removed, filepath.Join(dataDir, "config.json"))
+	assert.Contains(t, report.Removed, filepath.Join(dataDir, "Utils"))
+	assert.Contains(t, report.Removed, filepath.Join(dataDir, "reports"))
+	assert.Contains(t, report.Removed, dataDir)
+	assert.NoDirExists(t, dataDir)
+}
+
+func TestRun_RemoveAllSplitLayout(t *testing.T) {
+	setupTools(t)
+	root := t.TempDir()
+	dirs := paths.Paths{
+		Data:   filepath.Join(root, "share"),
+		Config: filepath.Join(root, "config", "config.json"),
+		Cache:  filepath.Join(root, "cache"),
+		Utils:  filepath.Join(root, "share", "Utils"),
+		Logs:   filepath.Join(root, "state"),
+	}
+
+	require.NoError(t, os.MkdirAll(dirs.Utils, 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dirs.Logs, "downloads"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Dir(dirs.Config), 0755))
+	require.NoError(t, os.WriteFile(dirs.Config, []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "config", "config.md"), []byte("# config.json"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "config", "notes.txt"), []byte("mine"), 0644))
+
+	report := Run(patcher.NewPatcher([]byte("stub")), Options{
+		Paths:        dirs,
+		RemoveCache:  true,
+		RemoveConfig: true,
+		RemoveTools:  true,
+	})
+	require.Empty(t, report.Errors)
+	assert.Contains(t, report.Removed, dirs.Logs)
+	assert.Contains(t, report.Removed, dirs.Data)
+	assert.Equal(t, []string{filepath.Join(root, "config")}, report.Kept)
+	assert.NoFileExists(t, dirs.Config)
+	assert.NoFileExists(t, filepath.Join(root, "config", "config.md"))
+	assert.FileExists(t, filepath.Join(root, "config", "notes.txt"))
+}
+
+func TestRun_KeepsForeignFiles(t *testing.T) {
+	setupTools(t)
+	cacheDir := t.TempDir()
+
+	cacheMgr := cache.NewManager(cacheDir, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "abc.mp4"), []byte("video"), 0644))
+	require.NoError(t, cacheMgr.AddEntry("abc", "abc.mp4"))
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "notes.txt"), []byte("mine"), 0644))
+
+	report := Run(patcher.NewPatcher([]byte("stub")), Options{CachePath: cacheDir, RemoveCache: true})
+	require.Empty(t, report.Errors)
+	assert.Equal(t, []string{cacheDir}, report.Kept)
+	assert.NoFileExists(t, filepath.Join(cacheDir, "abc.mp4"))
+	assert.FileExists(t, filepath.Join(cacheDir, "notes.txt"))
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+
+	"vrcvideocacher/internal/ghrelease"
 )
 
 // MockHTTPClient is a mock HTTP client for testing
@@ -22,14 +24,10 @@ func (m *MockHTTPClient) Get(url string) (*http.Response, error) {
 
 // NewMockReleaseResponse creates a mock GitHub release response
 func NewMockReleaseResponse(tagName string, assetName string) *http.Response {
-	release := GitHubRelease{
+	release := ghrelease.Release{
 		TagName: tagName,
 		Name:    tagName,
-		Assets: []struct {
-			Name               string `json:"name"`
-			BrowserDownloadURL string `json:"browser_download_url"`
-			Size               int64  `json:"size"`
-		}{
+		Assets: []ghrelease.Asset{
 			{Name: assetName, BrowserDownloadURL: "http://example.com/" + assetName, Size: 1024},
 		},
 		Body: "Release notes",
@@ -0,0 +1,149 @@
+package updater
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/pkg/models"
+)
+
+// DefaultInterval is how often a Notifier checks for updates
+const DefaultInterval = 24 * time.Hour
+
+// Preferences decide which available updates are announced
+type Preferences struct {
+	// Enabled turns the periodic checks on
+	Enabled bool
+	// SkipVersion is not announced, nor any older version
+	SkipVersion string
+	// RemindAfter holds announcements back until then
+	RemindAfter time.Time
+}
+
+// PreferencesFrom returns the update preferences of cfg: autoUpdate,
+// skipUpdateVersion and updateRemindAfter
+func PreferencesFrom(cfg *models.Config) Preferences {
+	// Validated by the config manager
+	remindAfter, _ := time.Parse(time.RFC3339, cfg.UpdateRemindAfter)
+	return Preferences{
+		Enabled:     cfg.AutoUpdate,
+		SkipVersion: cfg.SkipUpdateVersion,
+		RemindAfter: remindAfter,
+	}
+}
+
+// Announces reports whether an update to version is announced at now
+func (p Preferences) Announces(version string, now time.Time) bool {
+	if p.SkipVersion != "" && !compareVersions(p.SkipVersion, version) {
+		return false
+	}
+	return !now.Before(p.RemindAfter)
+}
+
+// Update is an available update
+type Update struct {
+	Current string `json:"current"`
+	Latest  string `json:"latest"`
+	// Announced is false when the preferences hold the update back
+	Announced bool `json:"announced"`
+}
+
+// Notifier periodically checks for updates and announces the ones the
+// preferences do not hold back
+type Notifier struct {
+	mu       sync.Mutex
+	updater  *Updater
+	interval time.Duration
+	prefs    func() Preferences
+	notify   func(Update)
+	now      func() time.Time
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewNotifier creates a notifier checking every interval with the current
+// preferences returned by prefs
+func NewNotifier(u *Updater, interval time.Duration, prefs func() Preferences, notify func(Update)) *Notifier {
+	return &Notifier{
+		updater:  u,
+		interval: interval,
+		prefs:    prefs,
+		notify:   notify,
+		now:      time.Now,
+	}
+}
+
+// Start begins periodic checks, skipped while the preferences disable them
+func (n *Notifier) Start() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n.cancel = cancel
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+
+		ticker := time.NewTicker(n.interval)
+		defer ticker.Stop()
+
+		for {
+			if n.prefs().Enabled {
+				if _, err := n.Check(); err != nil {
+					log().Warn("Failed to check for updates", logger.Err(err))
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends periodic checks
+func (n *Notifier) Stop() {
+	n.mu.Lock()
+	cancel := n.cancel
+	n.cancel = nil
+	n.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		n.wg.Wait()
+	}
+}
+
+// Check checks for an update and announces it unless the preferences hold
+// it back. It returns nil when up to date
+func (n *Notifier) Check() (*Update, error) {
+	latest, hasUpdate, err := n.updater.CheckForUpdate()
+	if err != nil || !hasUpdate {
+		return nil, err
+	}
+
+	update := &Update{
+		Current:   n.updater.GetCurrentVersion(),
+		Latest:    latest,
+		Announced: n.prefs().Announces(latest, n.now()),
+	}
+	if !update.Announced {
+		log().Debug("Update held back", "version", latest)
+		return update, nil
+	}
+
+	log().Info("Update available", "current", update.Current, "latest", latest)
+	if n.notify != nil {
+		n.notify(*update)
+	}
+	return update, nil
+}
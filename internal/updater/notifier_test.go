@@ -0,0 +1,98 @@
+package updater
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/pkg/models"
+)
+
+func TestPreferencesAnnounces(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.True(t, Preferences{}.Announces("v1.2.0", now))
+
+	skip := Preferences{SkipVersion: "v1.2.0"}
+	assert.False(t, skip.Announces("v1.2.0", now))
+	assert.False(t, skip.Announces("v1.1.0", now))
+	assert.True(t, skip.Announces("v1.2.1", now), "a newer release is announced again")
+
+	snoozed := Preferences{RemindAfter: now.Add(72 * time.Hour)}
+	assert.False(t, snoozed.Announces("v1.2.0", now))
+	assert.True(t, snoozed.Announces("v1.2.0", now.Add(72*time.Hour)))
+}
+
+func TestPreferencesFrom(t *testing.T) {
+	cfg := models.DefaultConfig()
+	cfg.SkipUpdateVersion = "v1.2.0"
+	cfg.UpdateRemindAfter = "2026-03-04T12:00:00Z"
+
+	prefs := PreferencesFrom(cfg)
+	assert.True(t, prefs.Enabled)
+	assert.Equal(t, "v1.2.0", prefs.SkipVersion)
+	assert.Equal(t, time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC), prefs.RemindAfter.UTC())
+
+	assert.True(t, PreferencesFrom(models.DefaultConfig()).RemindAfter.IsZero())
+}
+
+func TestNotifierCheck(t *testing.T) {
+	latest := "v1.1.0"
+	mockClient := &MockHTTPClient{
+		GetFunc: func(url string) (*http.Response, error) {
+			return NewMockReleaseResponse(latest, detectAssetName()), nil
+		},
+	}
+
+	prefs := Preferences{Enabled: true, SkipVersion: "v1.1.0"}
+	var notified []Update
+	n := NewNotifier(NewUpdaterWithClient("myuser/myrepo", "v1.0.0", mockClient), time.Hour,
+		func() Preferences { return prefs },
+		func(u Update) { notified = append(notified, u) })
+
+	// Skipped
+	update, err := n.Check()
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.False(t, update.Announced)
+	assert.Empty(t, notified)
+
+	// A newer release is announced
+	latest = "v1.2.0"
+	update, err = n.Check()
+	require.NoError(t, err)
+	assert.True(t, update.Announced)
+	assert.Equal(t, []Update{{Current: "v1.0.0", Latest: "v1.2.0", Announced: true}}, notified)
+
+	// Up to date
+	latest = "v1.0.0"
+	update, err = n.Check()
+	require.NoError(t, err)
+	assert.Nil(t, update)
+	assert.Len(t, notified, 1)
+}
+
+func TestNotifierStart(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		GetFunc: func(url string) (*http.Response, error) {
+			return NewMockReleaseResponse("v1.1.0", detectAssetName()), nil
+		},
+	}
+
+	notified := make(chan Update, 1)
+	n := NewNotifier(NewUpdaterWithClient("myuser/myrepo", "v1.0.0", mockClient), time.Hour,
+		func() Preferences { return Preferences{Enabled: true} },
+		func(u Update) { notified <- u })
+	n.Start()
+	defer n.Stop()
+
+	select {
+	case u := <-notified:
+		assert.Equal(t, "v1.1.0", u.Latest)
+	case <-time.After(time.Second):
+		t.Fatal("update not announced at start")
+	}
+}
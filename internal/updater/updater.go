@@ -1,45 +1,54 @@
 package updater
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"time"
+
+	"vrcvideocacher/internal/filelock"
+	"vrcvideocacher/internal/ghrelease"
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/internal/platform"
 )
 
 const (
 	checkTimeout = 30 * time.Second
+
+	// lockName is the lock file next to the executable held during an
+	// update, shared by the desktop app and the CLI installed beside it
+	lockName = "vrcvideocacher-update.lock"
+
+	// oldSuffix is appended to the executable to keep the version an update
+	// replaced, restored by Rollback
+	oldSuffix = ".old"
+)
+
+var (
+	// ErrUpdateInProgress is returned by Download and Rollback while another
+	// process is updating
+	ErrUpdateInProgress = errors.New("another update is in progress")
+	// ErrNoRollback is returned by Rollback when no update kept a previous
+	// version
+	ErrNoRollback = errors.New("no previous version to roll back to")
 )
 
 // HTTPClient interface for mocking
-type HTTPClient interface {
-	Get(url string) (*http.Response, error)
-}
+type HTTPClient = ghrelease.HTTPClient
 
 // Updater handles application updates
 type Updater struct {
 	repo           string
 	currentVersion string
-	httpClient     HTTPClient
-}
-
-// GitHubRelease represents a GitHub release
-type GitHubRelease struct {
-	TagName string `json:"tag_name"`
-	Name    string `json:"name"`
-	Assets  []struct {
-		Name               string `json:"name"`
-		BrowserDownloadURL string `json:"browser_download_url"`
-		Size               int64  `json:"size"`
-	} `json:"assets"`
-	Body string `json:"body"`
+	releases       *ghrelease.Client
 }
 
 // NewUpdater creates a new updater
@@ -47,16 +56,17 @@ func NewUpdater(repo, currentVersion string) *Updater {
 	return &Updater{
 		repo:           repo,
 		currentVersion: currentVersion,
-		httpClient:     &http.Client{Timeout: checkTimeout},
+		releases:       ghrelease.NewClient(&http.Client{Timeout: checkTimeout}),
 	}
 }
 
-// NewUpdaterWithClient creates an updater with custom HTTP client
+// NewUpdaterWithClient creates an updater with custom HTTP client, e.g. a
+// github.Client
 func NewUpdaterWithClient(repo, currentVersion string, client HTTPClient) *Updater {
 	return &Updater{
 		repo:           repo,
 		currentVersion: currentVersion,
-		httpClient:     client,
+		releases:       ghrelease.NewClient(client),
 	}
 }
 
@@ -65,24 +75,18 @@ func (u *Updater) GetCurrentVersion() string {
 	return u.currentVersion
 }
 
+// SetMirrors sets the download mirrors tried before GitHub, see
+// ghrelease.Client.SetMirrors
+func (u *Updater) SetMirrors(mirrors []string) {
+	u.releases.SetMirrors(mirrors)
+}
+
 // CheckForUpdate checks if a new version is available
 func (u *Updater) CheckForUpdate() (string, bool, error) {
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", u.repo)
-
-	resp, err := u.httpClient.Get(apiURL)
+	release, err := u.releases.Latest(context.Background(), ghrelease.LatestURL(u.repo))
 	if err != nil {
 		return "", false, fmt.Errorf("failed to check for updates: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", false, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
-	}
-
-	var release GitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", false, fmt.Errorf("failed to parse release info: %w", err)
-	}
 
 	// Compare versions
 	hasUpdate := compareVersions(u.currentVersion, release.TagName)
@@ -90,34 +94,34 @@ func (u *Updater) CheckForUpdate() (string, bool, error) {
 	return release.TagName, hasUpdate, nil
 }
 
-// Download downloads and applies the update
+// Download downloads and applies the update, verifying it against the
+// release checksums if published. The replaced version is kept for Rollback
 func (u *Updater) Download(exePath string) error {
-	// Get latest release info
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", u.repo)
-
-	resp, err := u.httpClient.Get(apiURL)
+	lock, err := lockUpdate(exePath)
 	if err != nil {
-		return fmt.Errorf("failed to fetch release info: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
+	defer lock.Unlock()
 
-	var release GitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return fmt.Errorf("failed to parse release info: %w", err)
+	// Get latest release info
+	release, err := u.releases.Latest(context.Background(), ghrelease.LatestURL(u.repo))
+	if err != nil {
+		return fmt.Errorf("failed to fetch release info: %w", err)
 	}
 
 	// Find the correct asset for this platform
-	assetName := detectAssetName()
-	var downloadURL string
-	for _, asset := range release.Assets {
-		if asset.Name == assetName {
-			downloadURL = asset.BrowserDownloadURL
-			break
-		}
+	names := assetNames(runtime.GOOS, runtime.GOARCH)
+	asset, ok := release.Find(ghrelease.ByNames(names...)...)
+	if !ok {
+		return fmt.Errorf("no asset found for platform: %s", names[0])
+	}
+	if asset.Name != names[0] {
+		log().Info("No native build, using an emulated one", "asset", asset.Name)
 	}
 
-	if downloadURL == "" {
-		return fmt.Errorf("no asset found for platform: %s", assetName)
+	checksum, err := u.releases.Checksum(context.Background(), release, asset.Name)
+	if err != nil {
+		return fmt.Errorf("failed to fetch checksums: %w", err)
 	}
 
 	// Backup current executable
@@ -126,62 +130,85 @@ func (u *Updater) Download(exePath string) error {
 		return fmt.Errorf("failed to backup executable: %w", err)
 	}
 
-	// Download new version
-	fmt.Printf("Downloading update %s...\n", release.TagName)
-	resp, err = u.httpClient.Get(downloadURL)
-	if err != nil {
+	// Download new version, replacing the executable
+	log().Info("Downloading update", "version", release.TagName)
+	if err := u.releases.Download(context.Background(), asset.BrowserDownloadURL, exePath, ghrelease.DownloadOptions{
+		Name:   "update",
+		SHA256: checksum,
+	}); err != nil {
 		u.restoreBackup(exePath, backupPath)
-		return fmt.Errorf("failed to download update: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		u.restoreBackup(exePath, backupPath)
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+	if err := platform.RemoveQuarantine(exePath); err != nil {
+		log().Warn("Failed to remove quarantine", logger.Err(err))
 	}
 
-	// Write to temporary file
-	tmpPath := exePath + ".new"
-	out, err := os.Create(tmpPath)
-	if err != nil {
-		u.restoreBackup(exePath, backupPath)
-		return fmt.Errorf("failed to create temp file: %w", err)
+	// Keep the backup for a rollback; Windows cannot rename over a file
+	oldPath := exePath + oldSuffix
+	os.Remove(oldPath)
+	if err := os.Rename(backupPath, oldPath); err != nil {
+		log().Warn("Failed to keep previous version", logger.Err(err))
+		os.Remove(backupPath)
 	}
 
-	_, err = io.Copy(out, resp.Body)
-	out.Close()
+	log().Info("Update completed", "version", release.TagName)
+	return nil
+}
+
+// Rollback restores the version the last update replaced. The replaced
+// version is kept in turn, so a second rollback undoes the first. The
+// executable is renamed rather than overwritten, which Windows allows while
+// it runs
+func Rollback(exePath string) error {
+	lock, err := lockUpdate(exePath)
 	if err != nil {
-		os.Remove(tmpPath)
-		u.restoreBackup(exePath, backupPath)
-		return fmt.Errorf("failed to write update: %w", err)
+		return err
 	}
+	defer lock.Unlock()
 
-	// Make executable
-	if err := os.Chmod(tmpPath, 0755); err != nil {
-		os.Remove(tmpPath)
-		u.restoreBackup(exePath, backupPath)
-		return fmt.Errorf("failed to make executable: %w", err)
+	oldPath := exePath + oldSuffix
+	if _, err := os.Stat(oldPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNoRollback
+		}
+		return fmt.Errorf("failed to find previous version: %w", err)
 	}
 
-	// Replace old executable
-	if err := os.Remove(exePath); err != nil {
-		os.Remove(tmpPath)
-		u.restoreBackup(exePath, backupPath)
-		return fmt.Errorf("failed to remove old executable: %w", err)
+	current := exePath + ".bak"
+	os.Remove(current)
+	if err := os.Rename(exePath, current); err != nil {
+		return fmt.Errorf("failed to move current version: %w", err)
 	}
-
-	if err := os.Rename(tmpPath, exePath); err != nil {
-		u.restoreBackup(exePath, backupPath)
-		return fmt.Errorf("failed to rename new executable: %w", err)
+	if err := os.Rename(oldPath, exePath); err != nil {
+		os.Rename(current, exePath)
+		return fmt.Errorf("failed to restore previous version: %w", err)
+	}
+	if err := os.Rename(current, oldPath); err != nil {
+		log().Warn("Failed to keep rolled back version", logger.Err(err))
 	}
 
-	// Remove backup on success
-	os.Remove(backupPath)
-
-	fmt.Printf("Update to %s completed successfully\n", release.TagName)
+	log().Info("Rolled back to previous version", "path", exePath)
 	return nil
 }
 
+// lockUpdate takes the update lock next to the executable
+func lockUpdate(exePath string) (*filelock.Lock, error) {
+	lock, err := filelock.TryLock(filepath.Join(filepath.Dir(exePath), lockName))
+	if errors.Is(err, filelock.ErrLocked) {
+		return nil, ErrUpdateInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock update: %w", err)
+	}
+	return lock, nil
+}
+
+// log returns the logger of this package
+func log() *slog.Logger {
+	return logger.For("updater")
+}
+
 // backupExecutable creates a backup of the current executable
 func (u *Updater) backupExecutable(exePath string) (string, error) {
 	backupPath := exePath + ".bak"
@@ -213,21 +240,9 @@ func (u *Updater) restoreBackup(exePath, backupPath string) error {
 	return nil
 }
 
-// VerifyChecksum verifies the checksum of a file
+// VerifyChecksum verifies the SHA-256 checksum of a file
 func (u *Updater) VerifyChecksum(filePath, expectedChecksum string) error {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	hash := sha256.Sum256(data)
-	actualChecksum := hex.EncodeToString(hash[:])
-
-	if actualChecksum != expectedChecksum {
-		return fmt.Errorf("checksum mismatch: expected %s, got %s", expectedChecksum, actualChecksum)
-	}
-
-	return nil
+	return ghrelease.VerifyFile(filePath, expectedChecksum)
 }
 
 // compareVersions returns true if latest > current
@@ -266,20 +281,29 @@ func parseVersion(version string) [3]int {
 
 // detectAssetName returns the appropriate asset name for the current platform
 func detectAssetName() string {
-	switch runtime.GOOS {
+	return assetNames(runtime.GOOS, runtime.GOARCH)[0]
+}
+
+// assetNames returns the release assets that run on goos and goarch, in
+// order of preference. Windows and macOS on arm64 fall back to the amd64
+// build, which runs emulated
+func assetNames(goos, goarch string) []string {
+	switch goos {
 	case "windows":
-		return "VRCVideoCacher-windows-amd64.exe"
-	case "linux":
-		if runtime.GOARCH == "arm64" {
-			return "VRCVideoCacher-linux-arm64"
+		names := []string{"VRCVideoCacher-windows-" + goarch + ".exe"}
+		if goarch == "arm64" {
+			names = append(names, "VRCVideoCacher-windows-amd64.exe")
 		}
-		return "VRCVideoCacher-linux-amd64"
+		return names
+	case "linux":
+		return []string{"VRCVideoCacher-linux-" + goarch}
 	case "darwin":
-		if runtime.GOARCH == "arm64" {
-			return "VRCVideoCacher-darwin-arm64"
+		names := []string{"VRCVideoCacher-darwin-" + goarch}
+		if goarch == "arm64" {
+			names = append(names, "VRCVideoCacher-darwin-amd64")
 		}
-		return "VRCVideoCacher-darwin-amd64"
+		return names
 	default:
-		return "VRCVideoCacher"
+		return []string{"VRCVideoCacher"}
 	}
 }
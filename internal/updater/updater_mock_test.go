@@ -124,6 +124,12 @@ func TestDownload_Success(t *testing.T) {
 	data, err := os.ReadFile(exePath)
 	require.NoError(t, err)
 	assert.Equal(t, "new version", string(data))
+
+	// The previous version is kept for a rollback
+	data, err = os.ReadFile(exePath + oldSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, "old version", string(data))
+	assert.NoFileExists(t, exePath+".bak")
 }
 
 // TestDownload_NoMatchingAsset tests error when no matching asset found
@@ -206,6 +212,8 @@ func TestDetectAssetName(t *testing.T) {
 	// Verify it returns a valid asset name
 	validAssets := []string{
 		"VRCVideoCacher-windows-amd64.exe",
+		"VRCVideoCacher-windows-arm64.exe",
+		"VRCVideoCacher-windows-386.exe",
 		"VRCVideoCacher-linux-amd64",
 		"VRCVideoCacher-linux-arm64",
 		"VRCVideoCacher-darwin-amd64",
@@ -218,13 +226,9 @@ func TestDetectAssetName(t *testing.T) {
 	// Verify current platform matches expected
 	switch runtime.GOOS {
 	case "windows":
-		assert.Equal(t, "VRCVideoCacher-windows-amd64.exe", asset)
+		assert.Equal(t, "VRCVideoCacher-windows-"+runtime.GOARCH+".exe", asset)
 	case "linux":
-		if runtime.GOARCH == "arm64" {
-			assert.Equal(t, "VRCVideoCacher-linux-arm64", asset)
-		} else {
-			assert.Equal(t, "VRCVideoCacher-linux-amd64", asset)
-		}
+		assert.Equal(t, "VRCVideoCacher-linux-"+runtime.GOARCH, asset)
 	case "darwin":
 		if runtime.GOARCH == "arm64" {
 			assert.Equal(t, "VRCVideoCacher-darwin-arm64", asset)
@@ -236,6 +240,30 @@ func TestDetectAssetName(t *testing.T) {
 	}
 }
 
+// TestAssetNames tests asset selection for each OS and architecture
+func TestAssetNames(t *testing.T) {
+	tests := []struct {
+		goos, goarch string
+		want         []string
+	}{
+		{"windows", "amd64", []string{"VRCVideoCacher-windows-amd64.exe"}},
+		{"windows", "arm64", []string{"VRCVideoCacher-windows-arm64.exe", "VRCVideoCacher-windows-amd64.exe"}},
+		{"windows", "386", []string{"VRCVideoCacher-windows-386.exe"}},
+		{"linux", "amd64", []string{"VRCVideoCacher-linux-amd64"}},
+		{"linux", "arm64", []string{"VRCVideoCacher-linux-arm64"}},
+		{"linux", "386", []string{"VRCVideoCacher-linux-386"}},
+		{"darwin", "amd64", []string{"VRCVideoCacher-darwin-amd64"}},
+		{"darwin", "arm64", []string{"VRCVideoCacher-darwin-arm64", "VRCVideoCacher-darwin-amd64"}},
+		{"freebsd", "amd64", []string{"VRCVideoCacher"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos+"/"+tt.goarch, func(t *testing.T) {
+			assert.Equal(t, tt.want, assetNames(tt.goos, tt.goarch))
+		})
+	}
+}
+
 // TestVerifyChecksum_Success tests successful checksum verification
 func TestVerifyChecksum_Success(t *testing.T) {
 	tmpDir := t.TempDir()
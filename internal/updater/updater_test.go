@@ -2,10 +2,13 @@ package updater
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/filelock"
 )
 
 func TestNewUpdater(t *testing.T) {
@@ -158,3 +161,52 @@ func TestRestoreBackup(t *testing.T) {
 	// Backup should be removed
 	assert.NoFileExists(t, backupPath)
 }
+
+func TestDownloadLocked(t *testing.T) {
+	tmpDir := t.TempDir()
+	exePath := filepath.Join(tmpDir, "test.exe")
+	require.NoError(t, os.WriteFile(exePath, []byte("original"), 0755))
+
+	// Another process is updating
+	lock, err := filelock.TryLock(filepath.Join(tmpDir, lockName))
+	require.NoError(t, err)
+	defer lock.Unlock()
+
+	updater := NewUpdater("test/repo", "1.0.0")
+	assert.ErrorIs(t, updater.Download(exePath), ErrUpdateInProgress)
+
+	data, err := os.ReadFile(exePath)
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(data))
+}
+
+func TestRollback(t *testing.T) {
+	tmpDir := t.TempDir()
+	exePath := filepath.Join(tmpDir, "test.exe")
+	require.NoError(t, os.WriteFile(exePath, []byte("new"), 0755))
+
+	assert.ErrorIs(t, Rollback(exePath), ErrNoRollback)
+
+	require.NoError(t, os.WriteFile(exePath+oldSuffix, []byte("old"), 0755))
+	require.NoError(t, Rollback(exePath))
+
+	data, err := os.ReadFile(exePath)
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+	data, err = os.ReadFile(exePath + oldSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	assert.NoFileExists(t, exePath+".bak")
+
+	// Rolling back again undoes the rollback
+	require.NoError(t, Rollback(exePath))
+	data, err = os.ReadFile(exePath)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	// Not while another process is updating
+	lock, err := filelock.TryLock(filepath.Join(tmpDir, lockName))
+	require.NoError(t, err)
+	defer lock.Unlock()
+	assert.ErrorIs(t, Rollback(exePath), ErrUpdateInProgress)
+}
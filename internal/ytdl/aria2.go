@@ -0,0 +1,132 @@
+package ytdl
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"vrcvideocacher/internal/ghrelease"
+)
+
+const (
+	aria2cAPI = "https://api.github.com/repos/aria2/aria2/releases/latest"
+
+	// aria2cFileName is the managed aria2c binary in the utils directory
+	// Only Windows builds are published upstream
+	aria2cFileName = "aria2c.exe"
+
+	// aria2cAssetMarker identifies the 64-bit Windows release archive
+	aria2cAssetMarker = "-win-64bit-"
+)
+
+var ErrAria2cNotFound = errors.New("aria2c not found")
+
+// Aria2cPath returns the managed aria2c binary if installed, otherwise
+// aria2c from PATH
+func (m *Manager) Aria2cPath() (string, error) {
+	managed := filepath.Join(m.utilsDir, aria2cFileName)
+	if _, err := os.Stat(managed); err == nil {
+		return managed, nil
+	}
+
+	if path, err := exec.LookPath("aria2c"); err == nil {
+		return path, nil
+	}
+
+	return "", ErrAria2cNotFound
+}
+
+// EnsureAria2c returns the path to aria2c, downloading the managed binary
+// on Windows if it is not installed. Other platforms must provide aria2c
+// through the system package manager.
+func (m *Manager) EnsureAria2c(ctx context.Context) (string, error) {
+	if path, err := m.Aria2cPath(); err == nil {
+		return path, nil
+	}
+
+	if runtime.GOOS != "windows" {
+		return "", fmt.Errorf("%w: install aria2 with your package manager", ErrAria2cNotFound)
+	}
+
+	log().Info("aria2c not found, downloading")
+	return m.downloadAria2c(ctx)
+}
+
+// downloadAria2c installs aria2c.exe from the latest aria2 release archive
+func (m *Manager) downloadAria2c(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, installTimeout)
+	defer cancel()
+
+	release, err := m.releases.Latest(ctx, aria2cAPI)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch aria2 release info: %w", err)
+	}
+
+	asset, ok := release.Find(ghrelease.ByMarker(aria2cAssetMarker, ".zip"))
+	if !ok {
+		return "", fmt.Errorf("no Windows asset found in aria2 release %s", release.TagName)
+	}
+
+	log().Info("Downloading aria2", "version", release.TagName)
+	data, err := m.releases.Fetch(ctx, asset.BrowserDownloadURL, m.downloadOptions("aria2", ""))
+	if err != nil {
+		return "", err
+	}
+
+	aria2cPath := filepath.Join(m.utilsDir, aria2cFileName)
+	if err := extractZipFile(data, aria2cFileName, aria2cPath); err != nil {
+		return "", err
+	}
+
+	log().Info("aria2 installed", "version", release.TagName)
+	return aria2cPath, nil
+}
+
+// extractZipFile writes the archive entry with the given base name to dst
+func extractZipFile(data []byte, name, dst string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || path.Base(f.Name) != name {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		defer rc.Close()
+
+		tmpPath := dst + ".tmp"
+		out, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+
+		_, err = io.Copy(out, rc)
+		out.Close()
+		if err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+
+		if err := os.Rename(tmpPath, dst); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to rename file: %w", err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%s not found in archive", name)
+}
@@ -0,0 +1,92 @@
+package ytdl
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAria2Archive builds a zip laid out like the aria2 Windows release
+func newAria2Archive(t *testing.T, binary []byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.Create("aria2-1.37.0-win-64bit-build1/README.html")
+	require.NoError(t, err)
+	w.Write([]byte("readme"))
+
+	w, err = zw.Create("aria2-1.37.0-win-64bit-build1/aria2c.exe")
+	require.NoError(t, err)
+	w.Write(binary)
+
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestDownloadAria2c(t *testing.T) {
+	archive := newAria2Archive(t, []byte("fake aria2c"))
+
+	client := &MockHTTPClient{
+		GetFunc: func(url string) (*http.Response, error) {
+			if url == aria2cAPI {
+				release := map[string]any{
+					"tag_name": "release-1.37.0",
+					"assets": []map[string]string{
+						{"name": "aria2-1.37.0-aarch64-linux-android-build1.zip", "browser_download_url": "http://example.com/android.zip"},
+						{"name": "aria2-1.37.0-win-64bit-build1.zip", "browser_download_url": "http://example.com/win64.zip"},
+					},
+				}
+				body, _ := json.Marshal(release)
+				return NewMockBinaryResponse(body), nil
+			}
+			assert.Equal(t, "http://example.com/win64.zip", url)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(archive))}, nil
+		},
+	}
+
+	utilsDir := t.TempDir()
+	mgr := NewManagerWithClient(utilsDir, client)
+
+	path, err := mgr.downloadAria2c(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(utilsDir, aria2cFileName), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fake aria2c", string(data))
+
+	// The managed binary takes precedence over PATH
+	found, err := mgr.Aria2cPath()
+	require.NoError(t, err)
+	assert.Equal(t, path, found)
+}
+
+func TestDownloadAria2c_NoAsset(t *testing.T) {
+	client := &MockHTTPClient{
+		GetFunc: func(url string) (*http.Response, error) {
+			return NewMockReleaseResponse("release-1.37.0", "aria2-1.37.0.tar.gz"), nil
+		},
+	}
+
+	mgr := NewManagerWithClient(t.TempDir(), client)
+	_, err := mgr.downloadAria2c(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no Windows asset")
+}
+
+func TestAria2cPath_NotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	mgr := NewManager(t.TempDir())
+	_, err := mgr.Aria2cPath()
+	assert.ErrorIs(t, err, ErrAria2cNotFound)
+}
@@ -0,0 +1,116 @@
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"vrcvideocacher/internal/platform"
+)
+
+const (
+	// selfTestURL is a long-lived, known-safe video used for the extraction probe
+	selfTestURL = "https://www.youtube.com/watch?v=jNQXAC9IVRw"
+
+	selfTestTimeout = 60 * time.Second
+)
+
+var ErrUnhealthy = errors.New("yt-dlp failed self-test")
+
+// commandRunner runs a command and returns its combined output
+type commandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// execCommand is the default commandRunner
+func execCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+// Health is the result of the last yt-dlp self-test
+type Health struct {
+	Healthy   bool      `json:"healthy"`
+	Version   string    `json:"version,omitempty"`
+	Channel   string    `json:"channel"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Health returns the result of the last self-test
+// CheckedAt is zero if no self-test has run yet
+func (m *Manager) Health() Health {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.health
+}
+
+// SelfTest runs `yt-dlp --version` and a simulated extraction of a
+// known-safe video, recording the result as the current health
+func (m *Manager) SelfTest(ctx context.Context) Health {
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	health := Health{
+		Channel:   m.Channel(),
+		CheckedAt: time.Now(),
+	}
+
+	cmd := m.Command()
+	out, err := m.runCommand(ctx, cmd.Path, append(cmd.Args, "--version")...)
+	if err != nil && ctx.Err() == nil {
+		err = platform.ExplainExecError(cmd.Path, err)
+	}
+	if err != nil {
+		health.Error = fmt.Sprintf("--version failed: %v", err)
+	} else {
+		health.Version = strings.TrimSpace(string(out))
+
+		out, err = m.runCommand(ctx, cmd.Path, append(cmd.Args, "--simulate", "--no-playlist", "--no-warnings", selfTestURL)...)
+		if err != nil {
+			health.Error = fmt.Sprintf("simulated extraction failed: %v: %s", err, lastLine(string(out)))
+		} else {
+			health.Healthy = true
+		}
+	}
+
+	m.mu.Lock()
+	m.health = health
+	m.mu.Unlock()
+
+	return health
+}
+
+// EnsureHealthy self-tests yt-dlp, reinstalling it if the test fails and
+// falling back to the stable channel if the nightly build stays broken
+func (m *Manager) EnsureHealthy(ctx context.Context) (Health, error) {
+	health := m.SelfTest(ctx)
+	if health.Healthy {
+		return health, nil
+	}
+
+	log().Warn("yt-dlp self-test failed, reinstalling", "error", health.Error)
+	if err := m.Download(ctx); err == nil {
+		if health = m.SelfTest(ctx); health.Healthy {
+			return health, nil
+		}
+	}
+
+	if m.Channel() == ChannelNightly {
+		log().Warn("yt-dlp nightly still failing, falling back to stable channel")
+		m.SetChannel(ChannelStable)
+		if err := m.Download(ctx); err == nil {
+			if health = m.SelfTest(ctx); health.Healthy {
+				return health, nil
+			}
+		}
+	}
+
+	return health, fmt.Errorf("%w: %s", ErrUnhealthy, health.Error)
+}
+
+// lastLine returns the last non-empty line of output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,121 @@
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReleaseClient returns a mock client serving the release API and binary
+// downloads, recording which APIs were queried
+func newReleaseClient(apis *[]string) *MockHTTPClient {
+	return &MockHTTPClient{
+		GetFunc: func(url string) (*http.Response, error) {
+			if strings.HasPrefix(url, "https://api.github.com/") {
+				*apis = append(*apis, url)
+				return NewMockReleaseResponse("2024.01.01", detectPlatform()), nil
+			}
+			return NewMockBinaryResponse([]byte("fake yt-dlp binary")), nil
+		},
+	}
+}
+
+func TestSelfTest_Healthy(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	mgr.runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		assert.Equal(t, mgr.GetYtdlpPath(), name)
+		if args[0] == "--version" {
+			return []byte("2024.01.01\n"), nil
+		}
+		assert.Contains(t, args, "--simulate")
+		return nil, nil
+	}
+
+	assert.True(t, mgr.Health().CheckedAt.IsZero())
+
+	health := mgr.SelfTest(context.Background())
+	assert.True(t, health.Healthy)
+	assert.Equal(t, "2024.01.01", health.Version)
+	assert.Equal(t, ChannelNightly, health.Channel)
+	assert.Equal(t, health, mgr.Health())
+}
+
+func TestSelfTest_Unhealthy(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	mgr.runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if args[0] == "--version" {
+			return []byte("2024.01.01"), nil
+		}
+		return []byte("[youtube] jNQXAC9IVRw\nERROR: unable to extract"), errors.New("exit status 1")
+	}
+
+	health := mgr.SelfTest(context.Background())
+	assert.False(t, health.Healthy)
+	assert.Contains(t, health.Error, "ERROR: unable to extract")
+}
+
+func TestEnsureHealthy_Reinstall(t *testing.T) {
+	var apis []string
+	mgr := NewManagerWithClient(t.TempDir(), newReleaseClient(&apis))
+
+	// Healthy once a fresh binary is installed
+	mgr.runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if !mgr.IsInstalled() {
+			return nil, os.ErrNotExist
+		}
+		return []byte("2024.01.01"), nil
+	}
+
+	health, err := mgr.EnsureHealthy(context.Background())
+	require.NoError(t, err)
+	assert.True(t, health.Healthy)
+	assert.Equal(t, []string{ytdlpNightlyAPI}, apis)
+	assert.Equal(t, ChannelNightly, mgr.Channel())
+}
+
+func TestEnsureHealthy_ChannelFallback(t *testing.T) {
+	var apis []string
+	mgr := NewManagerWithClient(t.TempDir(), newReleaseClient(&apis))
+
+	// Only the stable build works
+	mgr.runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if mgr.Channel() != ChannelStable {
+			return nil, errors.New("exit status 1")
+		}
+		return []byte("2024.01.01"), nil
+	}
+
+	health, err := mgr.EnsureHealthy(context.Background())
+	require.NoError(t, err)
+	assert.True(t, health.Healthy)
+	assert.Equal(t, ChannelStable, health.Channel)
+	assert.Equal(t, []string{ytdlpNightlyAPI, ytdlpStableAPI}, apis)
+}
+
+func TestEnsureHealthy_StillBroken(t *testing.T) {
+	var apis []string
+	mgr := NewManagerWithClient(t.TempDir(), newReleaseClient(&apis))
+	mgr.runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, errors.New("exit status 1")
+	}
+
+	health, err := mgr.EnsureHealthy(context.Background())
+	assert.ErrorIs(t, err, ErrUnhealthy)
+	assert.False(t, health.Healthy)
+}
+
+func TestSetChannel(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+
+	require.NoError(t, mgr.SetChannel(ChannelStable))
+	assert.Equal(t, ChannelStable, mgr.Channel())
+
+	assert.ErrorIs(t, mgr.SetChannel("beta"), ErrUnknownChannel)
+	assert.Equal(t, ChannelStable, mgr.Channel())
+}
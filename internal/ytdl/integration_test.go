@@ -3,6 +3,7 @@
 package ytdl
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -15,7 +16,7 @@ func TestDownloadYtdlp(t *testing.T) {
 	mgr := NewManager(utilsDir)
 
 	// Download yt-dlp
-	err := mgr.Download()
+	err := mgr.Download(context.Background())
 	require.NoError(t, err)
 
 	// Verify installation
@@ -37,7 +38,7 @@ func TestCheckForUpdate(t *testing.T) {
 	mgr := NewManager(utilsDir)
 
 	// Check for updates (not installed)
-	version, hasUpdate, err := mgr.CheckForUpdate()
+	version, hasUpdate, err := mgr.CheckForUpdate(context.Background())
 	require.NoError(t, err)
 	assert.True(t, hasUpdate, "Should have update when not installed")
 	assert.NotEmpty(t, version)
@@ -49,11 +50,11 @@ func TestEnsureInstalled(t *testing.T) {
 	mgr := NewManager(utilsDir)
 
 	// First call should download
-	err := mgr.EnsureInstalled()
+	err := mgr.EnsureInstalled(context.Background())
 	require.NoError(t, err)
 	assert.True(t, mgr.IsInstalled())
 
 	// Second call should be no-op
-	err = mgr.EnsureInstalled()
+	err = mgr.EnsureInstalled(context.Background())
 	require.NoError(t, err)
 }
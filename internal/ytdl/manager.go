@@ -1,40 +1,78 @@
 package ytdl
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"time"
+
+	"vrcvideocacher/internal/filelock"
+	"vrcvideocacher/internal/ghrelease"
+	"vrcvideocacher/internal/logger"
+	"vrcvideocacher/internal/platform"
 )
 
 const (
 	ytdlpNightlyAPI = "https://api.github.com/repos/yt-dlp/yt-dlp-nightly-builds/releases/latest"
+	ytdlpStableAPI  = "https://api.github.com/repos/yt-dlp/yt-dlp/releases/latest"
+
+	// installTimeout bounds an install or update, so a stalled connection
+	// cannot hang startup
+	installTimeout = 10 * time.Minute
+
+	// updateLockName is the lock file in the utils directory held while
+	// yt-dlp is downloaded and installed
+	updateLockName = "yt-dlp-update.lock"
+
+	// stagedSuffix marks a downloaded binary not yet installed
+	stagedSuffix = ".new"
 )
 
-// HTTPClient interface for mocking
-type HTTPClient interface {
-	Get(url string) (*http.Response, error)
+// Release channels
+const (
+	ChannelNightly = "nightly"
+	ChannelStable  = "stable"
+)
+
+var ErrUnknownChannel = errors.New("unknown release channel")
+
+// channelAPIs maps release channels to their latest release API
+var channelAPIs = map[string]string{
+	ChannelNightly: ytdlpNightlyAPI,
+	ChannelStable:  ytdlpStableAPI,
 }
 
+// HTTPClient interface for mocking
+type HTTPClient = ghrelease.HTTPClient
+
+// Progress reports the progress of a download of name ("yt-dlp" or
+// "aria2"); total is -1 if unknown
+type Progress func(name string, done, total int64)
+
 // Manager handles yt-dlp installation and updates
 type Manager struct {
+	mu             sync.RWMutex
 	utilsDir       string
+	channel        string
 	currentVersion string
 	lastCheckTime  time.Time
-	httpClient     HTTPClient
-}
-
-// GitHubRelease represents a GitHub release
-type GitHubRelease struct {
-	TagName string `json:"tag_name"`
-	Assets  []struct {
-		Name               string `json:"name"`
-		BrowserDownloadURL string `json:"browser_download_url"`
-	} `json:"assets"`
+	releases       *ghrelease.Client
+	health         Health
+	python         *Command
+	runCommand     commandRunner
+	progress       Progress
+
+	// inUse reports whether yt-dlp is running; pending is the version of an
+	// update staged until it is not, checked every idlePoll
+	inUse    func() bool
+	pending  string
+	idlePoll time.Duration
 }
 
 // NewManager creates a new yt-dlp manager
@@ -44,17 +82,24 @@ func NewManager(utilsDir string) *Manager {
 
 	return &Manager{
 		utilsDir:   utilsDir,
-		httpClient: &http.Client{Timeout: 30 * time.Second},
+		channel:    ChannelNightly,
+		releases:   ghrelease.NewClient(&http.Client{Timeout: 30 * time.Second}),
+		runCommand: execCommand,
+		idlePoll:   defaultIdlePoll,
 	}
 }
 
-// NewManagerWithClient creates a manager with custom HTTP client (for testing)
+// NewManagerWithClient creates a manager with custom HTTP client, e.g. a
+// github.Client or a mock for testing
 func NewManagerWithClient(utilsDir string, client HTTPClient) *Manager {
 	os.MkdirAll(utilsDir, 0755)
 
 	return &Manager{
 		utilsDir:   utilsDir,
-		httpClient: client,
+		channel:    ChannelNightly,
+		releases:   ghrelease.NewClient(client),
+		runCommand: execCommand,
+		idlePoll:   defaultIdlePoll,
 	}
 }
 
@@ -64,34 +109,82 @@ func (m *Manager) GetYtdlpPath() string {
 	return filepath.Join(m.utilsDir, filename)
 }
 
-// IsInstalled checks if yt-dlp is installed
+// IsInstalled checks if yt-dlp is installed, natively or as a zipapp
 func (m *Manager) IsInstalled() bool {
-	_, err := os.Stat(m.GetYtdlpPath())
+	if _, err := os.Stat(m.GetYtdlpPath()); err == nil {
+		return true
+	}
+	_, err := os.Stat(m.zipappPath())
 	return err == nil
 }
 
+// Channel returns the release channel used for installs and updates
+func (m *Manager) Channel() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.channel
+}
+
+// SetChannel sets the release channel used for installs and updates
+func (m *Manager) SetChannel(channel string) error {
+	if _, ok := channelAPIs[channel]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownChannel, channel)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channel = channel
+	return nil
+}
+
+// SetMirrors sets the download mirrors tried before GitHub, see
+// ghrelease.Client.SetMirrors
+func (m *Manager) SetMirrors(mirrors []string) {
+	m.releases.SetMirrors(mirrors)
+}
+
+// SetProgress sets the callback reporting install and update downloads
+func (m *Manager) SetProgress(progress Progress) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.progress = progress
+}
+
+// downloadOptions returns the options for downloading name, reporting
+// progress to the callback if set
+func (m *Manager) downloadOptions(name, checksum string) ghrelease.DownloadOptions {
+	m.mu.RLock()
+	progress := m.progress
+	m.mu.RUnlock()
+
+	opts := ghrelease.DownloadOptions{Name: name, SHA256: checksum}
+	if progress != nil {
+		opts.Progress = func(done, total int64) {
+			progress(name, done, total)
+		}
+	}
+	return opts
+}
+
+// releaseAPI returns the latest release API of the current channel
+func (m *Manager) releaseAPI() string {
+	return channelAPIs[m.Channel()]
+}
+
 // GetCurrentVersion returns the currently installed version
 func (m *Manager) GetCurrentVersion() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.currentVersion
 }
 
 // CheckForUpdate checks if a newer version is available
-func (m *Manager) CheckForUpdate() (string, bool, error) {
+func (m *Manager) CheckForUpdate(ctx context.Context) (string, bool, error) {
 	// Get latest release from GitHub
-	resp, err := m.httpClient.Get(ytdlpNightlyAPI)
+	release, err := m.releases.Latest(ctx, m.releaseAPI())
 	if err != nil {
 		return "", false, fmt.Errorf("failed to check for updates: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", false, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
-	}
-
-	var release GitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", false, fmt.Errorf("failed to parse release info: %w", err)
-	}
 
 	m.lastCheckTime = time.Now()
 
@@ -100,137 +193,189 @@ func (m *Manager) CheckForUpdate() (string, bool, error) {
 		return release.TagName, true, nil
 	}
 
-	// Compare versions
-	if m.currentVersion == "" || m.currentVersion != release.TagName {
+	// Compare versions; a staged update is not downloaded again
+	current := m.GetCurrentVersion()
+	if pending := m.PendingVersion(); pending != "" {
+		current = pending
+	}
+	if current == "" || current != release.TagName {
 		return release.TagName, true, nil
 	}
 
 	return release.TagName, false, nil
 }
 
-// Download downloads and installs yt-dlp
-func (m *Manager) Download() error {
+// Download downloads and installs yt-dlp, verifying it against the
+// release's SHA2-256SUMS. It gives up after installTimeout or when ctx is
+// cancelled, leaving any installed yt-dlp in place. While yt-dlp is in use
+// the download is staged and installed in the background once it is idle,
+// until ctx is cancelled
+func (m *Manager) Download(ctx context.Context) error {
+	lifetime := ctx
+	ctx, cancel := context.WithTimeout(ctx, installTimeout)
+	defer cancel()
+
 	// Get latest release info
-	resp, err := m.httpClient.Get(ytdlpNightlyAPI)
+	release, err := m.releases.Latest(ctx, m.releaseAPI())
 	if err != nil {
 		return fmt.Errorf("failed to fetch release info: %w", err)
 	}
-	defer resp.Body.Close()
 
-	var release GitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return fmt.Errorf("failed to parse release info: %w", err)
+	// Find the correct asset for this platform, falling back to the Python
+	// zipapp when there is no native build
+	names := assetNames(runtime.GOOS, runtime.GOARCH)
+	assetName := names[0]
+	ytdlpPath := m.GetYtdlpPath()
+	asset, ok := release.Find(ghrelease.ByNames(names...)...)
+	if ok && asset.Name != assetName {
+		log().Info("No native yt-dlp build, using an emulated one", "asset", asset.Name)
 	}
-
-	// Find the correct asset for this platform
-	platform := detectPlatform()
-	var downloadURL string
-	for _, asset := range release.Assets {
-		if asset.Name == platform {
-			downloadURL = asset.BrowserDownloadURL
-			break
+	if !ok && assetName != zipappAsset {
+		if asset, ok = release.Find(ghrelease.ByName(zipappAsset)); ok {
+			if _, err := m.findPython(); err != nil {
+				return fmt.Errorf("no asset found for platform: %s: %w", assetName, err)
+			}
+			ytdlpPath = m.zipappPath()
 		}
 	}
 
-	if downloadURL == "" {
-		return fmt.Errorf("no asset found for platform: %s", platform)
+	if !ok {
+		return fmt.Errorf("no asset found for platform: %s", assetName)
 	}
 
-	// Download the file
-	fmt.Printf("Downloading yt-dlp %s...\n", release.TagName)
-	resp, err = m.httpClient.Get(downloadURL)
+	checksum, err := m.releases.Checksum(ctx, release, asset.Name)
 	if err != nil {
-		return fmt.Errorf("failed to download yt-dlp: %w", err)
+		return fmt.Errorf("failed to fetch checksums: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+	if m.PendingVersion() == release.TagName {
+		log().Info("yt-dlp update already waiting for downloads to finish", "version", release.TagName)
+		return nil
 	}
 
-	// Write to file
-	ytdlpPath := m.GetYtdlpPath()
-	tmpPath := ytdlpPath + ".tmp"
-
-	out, err := os.Create(tmpPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+	// Only one process updates yt-dlp at a time, e.g. the desktop app and
+	// a CLI server sharing the utils directory
+	updateLock, err := filelock.TryLock(filepath.Join(m.utilsDir, updateLockName))
+	if errors.Is(err, filelock.ErrLocked) {
+		log().Info("Another process is updating yt-dlp, waiting")
+		updateLock, err = filelock.Acquire(ctx, filepath.Join(m.utilsDir, updateLockName))
 	}
-
-	_, err = io.Copy(out, resp.Body)
-	out.Close()
 	if err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to write file: %w", err)
+		return fmt.Errorf("failed to lock yt-dlp update: %w", err)
 	}
+	staged := false
+	defer func() {
+		if !staged {
+			updateLock.Unlock()
+		}
+	}()
 
-	// Make executable
-	if err := os.Chmod(tmpPath, 0755); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to make executable: %w", err)
+	// Download next to the binary, which keeps working meanwhile
+	log().Info("Downloading yt-dlp", "version", release.TagName)
+	stagedPath := ytdlpPath + stagedSuffix
+	if err := m.releases.Download(ctx, asset.BrowserDownloadURL, stagedPath, m.downloadOptions("yt-dlp", checksum)); err != nil {
+		return err
 	}
-
-	// Replace old file
-	if m.IsInstalled() {
-		if err := os.Remove(ytdlpPath); err != nil {
-			os.Remove(tmpPath)
-			return fmt.Errorf("failed to remove old file: %w", err)
-		}
+	if err := platform.RemoveQuarantine(stagedPath); err != nil {
+		log().Warn("Failed to remove quarantine", logger.Err(err))
 	}
 
-	if err := os.Rename(tmpPath, ytdlpPath); err != nil {
-		return fmt.Errorf("failed to rename file: %w", err)
+	// Windows cannot replace a running binary, so wait for downloads to
+	// finish; the update lock is held until then
+	if m.isInUse() {
+		log().Info("yt-dlp is in use, installing the update once downloads finish", "version", release.TagName)
+		staged = true
+		m.setPending(release.TagName)
+		go func() {
+			defer updateLock.Unlock()
+			m.installWhenIdle(lifetime, stagedPath, ytdlpPath, release.TagName)
+		}()
+		return nil
 	}
 
-	// Update version
-	m.currentVersion = release.TagName
-	fmt.Printf("yt-dlp %s installed successfully\n", release.TagName)
+	if err := install(ctx, stagedPath, ytdlpPath); err != nil {
+		os.Remove(stagedPath)
+		return err
+	}
+	m.installed(release.TagName)
 
 	return nil
 }
 
 // EnsureInstalled ensures yt-dlp is installed, downloading if necessary
-func (m *Manager) EnsureInstalled() error {
+func (m *Manager) EnsureInstalled(ctx context.Context) error {
 	if m.IsInstalled() {
 		return nil
 	}
 
-	fmt.Println("yt-dlp not found, downloading...")
-	return m.Download()
+	log().Info("yt-dlp not found, downloading")
+	return m.Download(ctx)
 }
 
 // AutoUpdate checks for and applies updates if available
-func (m *Manager) AutoUpdate() error {
-	latestVersion, hasUpdate, err := m.CheckForUpdate()
+func (m *Manager) AutoUpdate(ctx context.Context) error {
+	latestVersion, hasUpdate, err := m.CheckForUpdate(ctx)
 	if err != nil {
 		return err
 	}
 
 	if !hasUpdate {
-		fmt.Println("yt-dlp is up to date")
+		log().Info("yt-dlp is up to date")
 		return nil
 	}
 
-	fmt.Printf("Updating yt-dlp to %s...\n", latestVersion)
-	return m.Download()
+	log().Info("Updating yt-dlp", "version", latestVersion)
+	return m.Download(ctx)
+}
+
+// log returns the logger of this package
+func log() *slog.Logger {
+	return logger.For("ytdl")
 }
 
 // detectPlatform returns the appropriate yt-dlp binary name for the current platform
 func detectPlatform() string {
-	switch runtime.GOOS {
-	case "windows":
+	return binaryName(runtime.GOOS, runtime.GOARCH)
+}
+
+// binaryName returns the file name yt-dlp is installed under on goos and
+// goarch. On Windows it is yt-dlp.exe whichever build is installed
+func binaryName(goos, goarch string) string {
+	if goos == "windows" {
 		return "yt-dlp.exe"
+	}
+	return assetNames(goos, goarch)[0]
+}
+
+// assetNames returns the yt-dlp release assets that run on goos and goarch,
+// in order of preference. Windows on arm64 falls back to the x64 build and
+// macOS on arm64 to the universal build, which run emulated. Platforms
+// without a native build get the zipapp
+func assetNames(goos, goarch string) []string {
+	switch goos {
+	case "windows":
+		switch goarch {
+		case "arm64":
+			return []string{"yt-dlp_arm64.exe", "yt-dlp.exe"}
+		case "386":
+			return []string{"yt-dlp_x86.exe"}
+		default:
+			return []string{"yt-dlp.exe"}
+		}
 	case "linux":
-		if runtime.GOARCH == "arm64" {
-			return "yt-dlp_linux_aarch64"
+		switch goarch {
+		case "amd64":
+			return []string{"yt-dlp_linux"}
+		case "arm64":
+			return []string{"yt-dlp_linux_aarch64"}
+		case "arm":
+			return []string{"yt-dlp_linux_armv7l"}
 		}
-		return "yt-dlp_linux"
 	case "darwin":
-		if runtime.GOARCH == "arm64" {
-			return "yt-dlp_macos_arm64"
+		if goarch == "arm64" {
+			return []string{"yt-dlp_macos_arm64", "yt-dlp_macos"}
 		}
-		return "yt-dlp_macos"
-	default:
-		return "yt-dlp"
+		return []string{"yt-dlp_macos"}
 	}
+	return []string{zipappAsset}
 }
@@ -1,6 +1,7 @@
 package ytdl
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -23,7 +24,7 @@ func TestCheckForUpdate_NotInstalled_HasUpdate(t *testing.T) {
 
 	mgr := NewManagerWithClient(utilsDir, mockClient)
 
-	version, hasUpdate, err := mgr.CheckForUpdate()
+	version, hasUpdate, err := mgr.CheckForUpdate(context.Background())
 	require.NoError(t, err)
 	assert.True(t, hasUpdate)
 	assert.Equal(t, "2024.01.01", version)
@@ -46,7 +47,7 @@ func TestCheckForUpdate_AlreadyUpToDate(t *testing.T) {
 	err := os.WriteFile(mgr.GetYtdlpPath(), []byte("test"), 0755)
 	require.NoError(t, err)
 
-	version, hasUpdate, err := mgr.CheckForUpdate()
+	version, hasUpdate, err := mgr.CheckForUpdate(context.Background())
 	require.NoError(t, err)
 	assert.False(t, hasUpdate)
 	assert.Equal(t, "2024.01.01", version)
@@ -64,7 +65,7 @@ func TestCheckForUpdate_HTTPError(t *testing.T) {
 
 	mgr := NewManagerWithClient(utilsDir, mockClient)
 
-	_, _, err := mgr.CheckForUpdate()
+	_, _, err := mgr.CheckForUpdate(context.Background())
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to check for updates")
 }
@@ -88,7 +89,7 @@ func TestDownload_Success(t *testing.T) {
 
 	mgr := NewManagerWithClient(utilsDir, mockClient)
 
-	err := mgr.Download()
+	err := mgr.Download(context.Background())
 	require.NoError(t, err)
 
 	// Verify file was created
@@ -111,11 +112,61 @@ func TestDownload_NoMatchingAsset(t *testing.T) {
 
 	mgr := NewManagerWithClient(utilsDir, mockClient)
 
-	err := mgr.Download()
+	err := mgr.Download(context.Background())
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no asset found for platform")
 }
 
+// TestDownload_Progress tests that downloads are reported to the progress callback
+func TestDownload_Progress(t *testing.T) {
+	utilsDir := t.TempDir()
+	binary := []byte("fake yt-dlp binary")
+
+	mockClient := &MockHTTPClient{
+		GetFunc: func(url string) (*http.Response, error) {
+			if url == "http://example.com/"+detectPlatform() {
+				return NewMockBinaryResponse(binary), nil
+			}
+			return NewMockReleaseResponse("2024.01.01", detectPlatform()), nil
+		},
+	}
+
+	mgr := NewManagerWithClient(utilsDir, mockClient)
+
+	var names []string
+	var done, total int64
+	mgr.SetProgress(func(name string, d, tot int64) {
+		names = append(names, name)
+		done, total = d, tot
+	})
+
+	require.NoError(t, mgr.Download(context.Background()))
+	require.NotEmpty(t, names)
+	assert.Equal(t, "yt-dlp", names[0])
+	assert.Equal(t, int64(len(binary)), done)
+	assert.Equal(t, int64(-1), total)
+}
+
+// TestDownload_Cancelled tests that a cancelled download keeps the installed yt-dlp
+func TestDownload_Cancelled(t *testing.T) {
+	utilsDir := t.TempDir()
+	mgr := NewManagerWithClient(utilsDir, &MockHTTPClient{
+		GetFunc: func(url string) (*http.Response, error) {
+			t.Fatalf("unexpected request to %s", url)
+			return nil, nil
+		},
+	})
+	require.NoError(t, os.WriteFile(mgr.GetYtdlpPath(), []byte("installed"), 0755))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := mgr.Download(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	data, _ := os.ReadFile(mgr.GetYtdlpPath())
+	assert.Equal(t, "installed", string(data))
+}
+
 // TestAutoUpdate_HasUpdate tests AutoUpdate when update is available
 func TestAutoUpdate_HasUpdate(t *testing.T) {
 	utilsDir := t.TempDir()
@@ -140,7 +191,7 @@ func TestAutoUpdate_HasUpdate(t *testing.T) {
 	err := os.WriteFile(mgr.GetYtdlpPath(), []byte("old"), 0755)
 	require.NoError(t, err)
 
-	err = mgr.AutoUpdate()
+	err = mgr.AutoUpdate(context.Background())
 	require.NoError(t, err)
 
 	// Should have new version
@@ -164,7 +215,7 @@ func TestAutoUpdate_NoUpdate(t *testing.T) {
 	err := os.WriteFile(mgr.GetYtdlpPath(), []byte("current"), 0755)
 	require.NoError(t, err)
 
-	err = mgr.AutoUpdate()
+	err = mgr.AutoUpdate(context.Background())
 	require.NoError(t, err)
 
 	// Should still have same version
@@ -181,6 +232,7 @@ func TestDetectPlatform_AllPlatforms(t *testing.T) {
 		"yt-dlp.exe",           // Windows
 		"yt-dlp_linux",         // Linux x86_64
 		"yt-dlp_linux_aarch64", // Linux ARM64
+		"yt-dlp_linux_armv7l",  // Linux ARMv7
 		"yt-dlp_macos",         // macOS x86_64
 		"yt-dlp_macos_arm64",   // macOS ARM64
 		"yt-dlp",               // Fallback
@@ -193,10 +245,16 @@ func TestDetectPlatform_AllPlatforms(t *testing.T) {
 	case "windows":
 		assert.Equal(t, "yt-dlp.exe", platform)
 	case "linux":
-		if runtime.GOARCH == "arm64" {
-			assert.Equal(t, "yt-dlp_linux_aarch64", platform)
-		} else {
+		switch runtime.GOARCH {
+		case "amd64":
 			assert.Equal(t, "yt-dlp_linux", platform)
+		case "arm64":
+			assert.Equal(t, "yt-dlp_linux_aarch64", platform)
+		case "arm":
+			assert.Equal(t, "yt-dlp_linux_armv7l", platform)
+		default:
+			// No native build, the zipapp is used
+			assert.Equal(t, "yt-dlp", platform)
 		}
 	case "darwin":
 		if runtime.GOARCH == "arm64" {
@@ -237,7 +295,7 @@ func TestDownload_ReplaceExisting(t *testing.T) {
 	require.NoError(t, err)
 
 	// Download new version
-	err = mgr.Download()
+	err = mgr.Download(context.Background())
 	require.NoError(t, err)
 
 	// Verify new version
@@ -261,7 +319,7 @@ func TestCheckForUpdate_InvalidJSON(t *testing.T) {
 
 	mgr := NewManagerWithClient(utilsDir, mockClient)
 
-	_, _, err := mgr.CheckForUpdate()
+	_, _, err := mgr.CheckForUpdate(context.Background())
 	assert.Error(t, err)
 }
 
@@ -280,7 +338,7 @@ func TestCheckForUpdate_Non200Status(t *testing.T) {
 
 	mgr := NewManagerWithClient(utilsDir, mockClient)
 
-	_, _, err := mgr.CheckForUpdate()
+	_, _, err := mgr.CheckForUpdate(context.Background())
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "status 404")
 }
@@ -297,7 +355,7 @@ func TestDownload_HTTPError(t *testing.T) {
 
 	mgr := NewManagerWithClient(utilsDir, mockClient)
 
-	err := mgr.Download()
+	err := mgr.Download(context.Background())
 	assert.Error(t, err)
 }
 
@@ -323,7 +381,7 @@ func TestDownload_DownloadFailed(t *testing.T) {
 
 	mgr := NewManagerWithClient(utilsDir, mockClient)
 
-	err := mgr.Download()
+	err := mgr.Download(context.Background())
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "status 404")
 }
@@ -340,7 +398,7 @@ func TestAutoUpdate_CheckError(t *testing.T) {
 
 	mgr := NewManagerWithClient(utilsDir, mockClient)
 
-	err := mgr.AutoUpdate()
+	err := mgr.AutoUpdate(context.Background())
 	assert.Error(t, err)
 }
 
@@ -365,7 +423,7 @@ func TestEnsureInstalled_NotInstalled(t *testing.T) {
 	assert.False(t, mgr.IsInstalled())
 
 	// Ensure installed - should download
-	err := mgr.EnsureInstalled()
+	err := mgr.EnsureInstalled(context.Background())
 	require.NoError(t, err)
 
 	// Should now be installed
@@ -469,3 +527,30 @@ func TestGetCurrentVersion_AfterSet(t *testing.T) {
 	version := mgr.GetCurrentVersion()
 	assert.Equal(t, testVersion, version)
 }
+
+// TestAssetNames tests asset selection for each OS and architecture
+func TestAssetNames(t *testing.T) {
+	tests := []struct {
+		goos, goarch string
+		assets       []string
+		binary       string
+	}{
+		{"windows", "amd64", []string{"yt-dlp.exe"}, "yt-dlp.exe"},
+		{"windows", "arm64", []string{"yt-dlp_arm64.exe", "yt-dlp.exe"}, "yt-dlp.exe"},
+		{"windows", "386", []string{"yt-dlp_x86.exe"}, "yt-dlp.exe"},
+		{"linux", "amd64", []string{"yt-dlp_linux"}, "yt-dlp_linux"},
+		{"linux", "arm64", []string{"yt-dlp_linux_aarch64"}, "yt-dlp_linux_aarch64"},
+		{"linux", "arm", []string{"yt-dlp_linux_armv7l"}, "yt-dlp_linux_armv7l"},
+		{"linux", "386", []string{"yt-dlp"}, "yt-dlp"},
+		{"darwin", "amd64", []string{"yt-dlp_macos"}, "yt-dlp_macos"},
+		{"darwin", "arm64", []string{"yt-dlp_macos_arm64", "yt-dlp_macos"}, "yt-dlp_macos_arm64"},
+		{"freebsd", "amd64", []string{"yt-dlp"}, "yt-dlp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos+"/"+tt.goarch, func(t *testing.T) {
+			assert.Equal(t, tt.assets, assetNames(tt.goos, tt.goarch))
+			assert.Equal(t, tt.binary, binaryName(tt.goos, tt.goarch))
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package ytdl
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -100,7 +101,7 @@ func TestEnsureInstalled_AlreadyInstalled(t *testing.T) {
 	require.NoError(t, err)
 
 	// Should not download
-	err = mgr.EnsureInstalled()
+	err = mgr.EnsureInstalled(context.Background())
 	require.NoError(t, err)
 }
 
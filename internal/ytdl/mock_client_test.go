@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+
+	"vrcvideocacher/internal/ghrelease"
 )
 
 // MockHTTPClient is a mock HTTP client for testing
@@ -21,12 +23,9 @@ func (m *MockHTTPClient) Get(url string) (*http.Response, error) {
 
 // NewMockReleaseResponse creates a mock GitHub release response
 func NewMockReleaseResponse(tagName string, assetName string) *http.Response {
-	release := GitHubRelease{
+	release := ghrelease.Release{
 		TagName: tagName,
-		Assets: []struct {
-			Name               string `json:"name"`
-			BrowserDownloadURL string `json:"browser_download_url"`
-		}{
+		Assets: []ghrelease.Asset{
 			{Name: assetName, BrowserDownloadURL: "http://example.com/" + assetName},
 		},
 	}
@@ -0,0 +1,112 @@
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+const (
+	// zipappAsset is the platform-independent yt-dlp release that runs on Python
+	zipappAsset = "yt-dlp"
+
+	// zipappFileName is where the zipapp is stored in the utils directory
+	zipappFileName = "yt-dlp.pyz"
+
+	// minPythonMinor is the oldest Python 3 minor version yt-dlp supports
+	minPythonMinor = 9
+
+	pythonProbeTimeout = 10 * time.Second
+)
+
+var ErrPythonNotFound = errors.New("no supported Python interpreter found")
+
+// pythonVersionPattern matches `python --version` output such as "Python 3.12.1"
+var pythonVersionPattern = regexp.MustCompile(`Python 3\.(\d+)`)
+
+// Command describes how to invoke yt-dlp
+type Command struct {
+	Path string   `json:"path"`
+	Args []string `json:"args,omitempty"`
+}
+
+// Command returns how to invoke the installed yt-dlp: the native binary if
+// present, otherwise the zipapp through a Python interpreter
+func (m *Manager) Command() Command {
+	binary := m.GetYtdlpPath()
+	if _, err := os.Stat(binary); err == nil {
+		return Command{Path: binary}
+	}
+
+	zipapp := m.zipappPath()
+	if _, err := os.Stat(zipapp); err == nil {
+		if python, err := m.findPython(); err == nil {
+			args := append(append([]string{}, python.Args...), zipapp)
+			return Command{Path: python.Path, Args: args}
+		}
+	}
+
+	return Command{Path: binary}
+}
+
+// zipappPath returns the path of the zipapp fallback
+func (m *Manager) zipappPath() string {
+	return filepath.Join(m.utilsDir, zipappFileName)
+}
+
+// findPython returns the first supported Python interpreter, caching the result
+func (m *Manager) findPython() (*Command, error) {
+	m.mu.RLock()
+	python := m.python
+	m.mu.RUnlock()
+	if python != nil {
+		return python, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pythonProbeTimeout)
+	defer cancel()
+
+	for _, candidate := range pythonCandidates(runtime.GOOS) {
+		args := append(append([]string{}, candidate.Args...), "--version")
+		out, err := m.runCommand(ctx, candidate.Path, args...)
+		if err != nil {
+			continue
+		}
+
+		match := pythonVersionPattern.FindSubmatch(out)
+		if match == nil {
+			continue
+		}
+		if minor, _ := strconv.Atoi(string(match[1])); minor < minPythonMinor {
+			continue
+		}
+
+		found := candidate
+		m.mu.Lock()
+		m.python = &found
+		m.mu.Unlock()
+		return &found, nil
+	}
+
+	return nil, ErrPythonNotFound
+}
+
+// pythonCandidates returns interpreters to try in order of preference
+func pythonCandidates(goos string) []Command {
+	if goos == "windows" {
+		return []Command{
+			{Path: "py", Args: []string{"-3"}},
+			{Path: "python3"},
+			{Path: "python"},
+		}
+	}
+	return []Command{
+		{Path: "python3"},
+		{Path: "python"},
+	}
+}
@@ -0,0 +1,94 @@
+package ytdl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vrcvideocacher/internal/ghrelease"
+)
+
+// newZipappOnlyClient serves a release that only has the zipapp asset
+func newZipappOnlyClient() *MockHTTPClient {
+	return &MockHTTPClient{
+		GetFunc: func(url string) (*http.Response, error) {
+			if strings.HasPrefix(url, "https://api.github.com/") {
+				release := ghrelease.Release{TagName: "2024.01.01"}
+				release.Assets = append(release.Assets, ghrelease.Asset{Name: zipappAsset, BrowserDownloadURL: "http://example.com/yt-dlp"})
+				body, _ := json.Marshal(release)
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body))}, nil
+			}
+			return NewMockBinaryResponse([]byte("zipapp")), nil
+		},
+	}
+}
+
+// fakePython answers --version for the named interpreter only
+func fakePython(name, version string) commandRunner {
+	return func(ctx context.Context, cmd string, args ...string) ([]byte, error) {
+		if cmd != name {
+			return nil, errors.New("executable file not found")
+		}
+		return []byte(version), nil
+	}
+}
+
+func TestDownload_ZipappFallback(t *testing.T) {
+	if detectPlatform() == zipappAsset {
+		t.Skip("Platform already uses the zipapp")
+	}
+
+	utilsDir := t.TempDir()
+	mgr := NewManagerWithClient(utilsDir, newZipappOnlyClient())
+	mgr.runCommand = fakePython("python", "Python 3.11.4")
+
+	require.NoError(t, mgr.Download(context.Background()))
+	assert.FileExists(t, filepath.Join(utilsDir, zipappFileName))
+	assert.True(t, mgr.IsInstalled())
+
+	cmd := mgr.Command()
+	assert.Equal(t, "python", cmd.Path)
+	assert.Equal(t, []string{filepath.Join(utilsDir, zipappFileName)}, cmd.Args)
+}
+
+func TestDownload_ZipappNoPython(t *testing.T) {
+	if detectPlatform() == zipappAsset {
+		t.Skip("Platform already uses the zipapp")
+	}
+
+	mgr := NewManagerWithClient(t.TempDir(), newZipappOnlyClient())
+	mgr.runCommand = fakePython("python3", "Python 3.6.9") // Too old
+
+	err := mgr.Download(context.Background())
+	assert.ErrorIs(t, err, ErrPythonNotFound)
+	assert.False(t, mgr.IsInstalled())
+}
+
+func TestCommand_PrefersNativeBinary(t *testing.T) {
+	utilsDir := t.TempDir()
+	mgr := NewManager(utilsDir)
+	mgr.runCommand = fakePython("python3", "Python 3.12.0")
+
+	require.NoError(t, os.WriteFile(filepath.Join(utilsDir, zipappFileName), []byte("zipapp"), 0644))
+	require.NoError(t, os.WriteFile(mgr.GetYtdlpPath(), []byte("binary"), 0755))
+
+	assert.Equal(t, Command{Path: mgr.GetYtdlpPath()}, mgr.Command())
+}
+
+func TestPythonCandidates(t *testing.T) {
+	windows := pythonCandidates("windows")
+	assert.Equal(t, Command{Path: "py", Args: []string{"-3"}}, windows[0])
+
+	linux := pythonCandidates("linux")
+	assert.Equal(t, "python3", linux[0].Path)
+}
@@ -0,0 +1,106 @@
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"vrcvideocacher/internal/filelock"
+	"vrcvideocacher/internal/logger"
+)
+
+// defaultIdlePoll is how often a staged update checks whether yt-dlp is
+// still in use
+const defaultIdlePoll = 5 * time.Second
+
+// SetInUse sets the check whether yt-dlp is running, e.g. by active
+// downloads. While it reports true, updates are staged beside the binary
+// and installed once it reports false
+func (m *Manager) SetInUse(inUse func() bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inUse = inUse
+}
+
+// PendingVersion returns the version of an update waiting for yt-dlp to be
+// idle, or "" if there is none
+func (m *Manager) PendingVersion() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pending
+}
+
+// isInUse reports whether yt-dlp is running
+func (m *Manager) isInUse() bool {
+	m.mu.RLock()
+	inUse := m.inUse
+	m.mu.RUnlock()
+	return inUse != nil && inUse()
+}
+
+// setPending records the version of a staged update
+func (m *Manager) setPending(version string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = version
+}
+
+// installed records version as installed
+func (m *Manager) installed(version string) {
+	m.mu.Lock()
+	m.currentVersion = version
+	m.pending = ""
+	m.mu.Unlock()
+	log().Info("yt-dlp installed", "version", version)
+}
+
+// installWhenIdle installs the staged update once yt-dlp is not in use,
+// retrying while the old binary cannot be replaced. When ctx is done first
+// the staged file is removed and the old binary kept
+func (m *Manager) installWhenIdle(ctx context.Context, staged, dst, version string) {
+	ticker := time.NewTicker(m.idlePoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			os.Remove(staged)
+			m.setPending("")
+			return
+		case <-ticker.C:
+		}
+
+		if m.isInUse() {
+			continue
+		}
+		if err := install(ctx, staged, dst); err != nil {
+			log().Warn("Failed to install yt-dlp update, retrying", "version", version, logger.Err(err))
+			continue
+		}
+		m.installed(version)
+		return
+	}
+}
+
+// install moves a downloaded binary over dst while holding the lock of dst,
+// so downloads do not start it halfway through the swap
+func install(ctx context.Context, staged, dst string) error {
+	lock, err := filelock.Acquire(ctx, filelock.PathFor(dst))
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %w", filepath.Base(dst), err)
+	}
+	defer lock.Unlock()
+
+	// Windows cannot rename over the old file
+	if err := os.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove old file: %w", err)
+	}
+	if err := os.Rename(staged, dst); err != nil {
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package ytdl
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// releaseClient serves a release of version with the binary data
+func releaseClient(version, data string) *MockHTTPClient {
+	return &MockHTTPClient{
+		GetFunc: func(url string) (*http.Response, error) {
+			if url == ytdlpNightlyAPI {
+				return NewMockReleaseResponse(version, detectPlatform()), nil
+			}
+			return NewMockBinaryResponse([]byte(data)), nil
+		},
+	}
+}
+
+func TestDownload_WaitsUntilIdle(t *testing.T) {
+	mgr := NewManagerWithClient(t.TempDir(), releaseClient("2024.02.01", "new version"))
+	mgr.idlePoll = 10 * time.Millisecond
+	mgr.currentVersion = "2024.01.01"
+	require.NoError(t, os.WriteFile(mgr.GetYtdlpPath(), []byte("old version"), 0755))
+
+	var busy atomic.Bool
+	busy.Store(true)
+	mgr.SetInUse(busy.Load)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, mgr.Download(ctx))
+
+	// The running binary is kept while in use
+	time.Sleep(50 * time.Millisecond)
+	data, err := os.ReadFile(mgr.GetYtdlpPath())
+	require.NoError(t, err)
+	assert.Equal(t, "old version", string(data))
+	assert.Equal(t, "2024.01.01", mgr.GetCurrentVersion())
+	assert.Equal(t, "2024.02.01", mgr.PendingVersion())
+
+	_, hasUpdate, err := mgr.CheckForUpdate(ctx)
+	require.NoError(t, err)
+	assert.False(t, hasUpdate, "a staged update is not downloaded again")
+
+	busy.Store(false)
+	assert.Eventually(t, func() bool { return mgr.GetCurrentVersion() == "2024.02.01" }, time.Second, 10*time.Millisecond)
+	assert.Empty(t, mgr.PendingVersion())
+	data, err = os.ReadFile(mgr.GetYtdlpPath())
+	require.NoError(t, err)
+	assert.Equal(t, "new version", string(data))
+	assert.NoFileExists(t, mgr.GetYtdlpPath()+stagedSuffix)
+}
+
+func TestDownload_StagedUpdateCancelled(t *testing.T) {
+	mgr := NewManagerWithClient(t.TempDir(), releaseClient("2024.02.01", "new version"))
+	mgr.idlePoll = 10 * time.Millisecond
+	require.NoError(t, os.WriteFile(mgr.GetYtdlpPath(), []byte("old version"), 0755))
+	mgr.SetInUse(func() bool { return true })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, mgr.Download(ctx))
+	cancel()
+
+	assert.Eventually(t, func() bool { return mgr.PendingVersion() == "" }, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(mgr.GetYtdlpPath() + stagedSuffix)
+		return os.IsNotExist(err)
+	}, time.Second, 10*time.Millisecond)
+	data, err := os.ReadFile(mgr.GetYtdlpPath())
+	require.NoError(t, err)
+	assert.Equal(t, "old version", string(data))
+}
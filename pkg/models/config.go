@@ -1,30 +1,147 @@
 package models
 
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DefaultCORSOrigins are the frontend dev servers allowed to call the API:
+// Vite and the Wails dev server
+var DefaultCORSOrigins = []string{
+	"http://localhost:5173",
+	"http://127.0.0.1:5173",
+	"http://localhost:34115",
+	"http://127.0.0.1:34115",
+}
+
+// CORSAllowAll in CORSAllowedOrigins allows any origin
+const CORSAllowAll = "*"
+
+// BypassRegexPrefix marks a BypassURLs entry as a regular expression matched
+// against the full URL instead of a domain
+const BypassRegexPrefix = "regex:"
+
+// BypassRegex returns the regular expression of a BypassURLs entry, ignoring
+// surrounding whitespace, and whether the entry is one
+func BypassRegex(entry string) (string, bool) {
+	return strings.CutPrefix(strings.TrimSpace(entry), BypassRegexPrefix)
+}
+
+// Actions taken when a download's estimated size exceeds CacheMaxDownloadMB
+const (
+	OversizeConfirm   = "confirm"
+	OversizeDowngrade = "downgrade"
+)
+
+// Handling of video files in the cache directory that the cacher did not
+// download (e.g. VIDEO_ID.mp4 copied in by hand)
+const (
+	ForeignIgnore     = "ignore"
+	ForeignAdopt      = "adopt"
+	ForeignQuarantine = "quarantine"
+)
+
+// Video request sources passed by the yt-dlp stub
+const (
+	SourceVRChat     = "vrchat"
+	SourceResonite   = "resonite"
+	SourceChilloutVR = "chilloutvr"
+)
+
+// Response types for getvideo
+const (
+	ResponseURL      = "url"
+	ResponseJSON     = "json"
+	ResponseTemplate = "template"
+)
+
+// SourcePolicy overrides download and response settings for a request source
+// Empty fields keep the default behavior
+type SourcePolicy struct {
+	// Format forces "mp4" or "webm" instead of following the avpro flag
+	Format string `json:"format,omitempty"`
+	// MaxRes overrides cacheYouTubeMaxRes
+	MaxRes int `json:"maxRes,omitempty"`
+	// Response is "url" for a plain URL, "json" for a yt-dlp style info
+	// object or "template" for Template
+	Response string `json:"response,omitempty"`
+	// Template is a text/template rendering the response, with the fields of
+	// the yt-dlp style info object and the source
+	Template string `json:"template,omitempty"`
+	// ContentType is the Content-Type of template responses (default
+	// text/plain)
+	ContentType string `json:"contentType,omitempty"`
+	// Seek appends a #t= media fragment with the start time of timestamped
+	// links to cached URLs, for players that seek to it
+	Seek bool `json:"seek,omitempty"`
+}
+
 // Config represents the application configuration
 type Config struct {
-	WebServerURL          string   `json:"webServerUrl"`
-	WebServerPort         int      `json:"webServerPort"`
-	YtdlPath              string   `json:"ytdlPath"`
-	YtdlUseCookies        bool     `json:"ytdlUseCookies"`
-	YtdlAutoUpdate        bool     `json:"ytdlAutoUpdate"`
-	YtdlAdditionalArgs    string   `json:"ytdlAdditionalArgs"`
-	YtdlDubLanguage       string   `json:"ytdlDubLanguage"`
-	YtdlDelay             int      `json:"ytdlDelay"`
-	CachePath             string   `json:"cachePath"`
-	BlockedURLs           []string `json:"blockedUrls"`
-	BlockRedirect         string   `json:"blockRedirect"`
-	CacheYouTube          bool     `json:"cacheYouTube"`
-	CacheYouTubeMaxRes    int      `json:"cacheYouTubeMaxRes"`
-	CacheYouTubeMaxLength int      `json:"cacheYouTubeMaxLength"`
-	CacheMaxSizeGB        float64  `json:"cacheMaxSizeGb"`
-	CachePyPyDance        bool     `json:"cachePyPyDance"`
-	CacheVRDancing        bool     `json:"cacheVRDancing"`
-	PatchVRC              bool     `json:"patchVRC"`
-	PatchResonite         bool     `json:"patchResonite"`
-	ResonitePath          string   `json:"resonitePath"`
-	AutoUpdate            bool     `json:"autoUpdate"`
-	StartMinimized        bool     `json:"startMinimized"`
-	MinimizeToTray        bool     `json:"minimizeToTray"`
+	WebServerURL          string                  `json:"webServerUrl"`
+	WebServerPort         int                     `json:"webServerPort"`
+	LANMode               bool                    `json:"lanMode"`
+	LANBaseURL            string                  `json:"lanBaseUrl"`
+	CORSAllowedOrigins    []string                `json:"corsAllowedOrigins"`
+	TrustedProxies        []string                `json:"trustedProxies"`
+	RPCPort               int                     `json:"rpcPort"`
+	YtdlPath              string                  `json:"ytdlPath"`
+	YtdlUseCookies        bool                    `json:"ytdlUseCookies"`
+	YtdlCookieAccounts    []string                `json:"ytdlCookieAccounts"`
+	YtdlAutoUpdate        bool                    `json:"ytdlAutoUpdate"`
+	YtdlAdditionalArgs    string                  `json:"ytdlAdditionalArgs"`
+	YtdlDubLanguage       string                  `json:"ytdlDubLanguage"`
+	YtdlDelay             int                     `json:"ytdlDelay"`
+	YtdlPacing            int                     `json:"ytdlPacing"`
+	Aria2cEnabled         bool                    `json:"aria2cEnabled"`
+	Aria2cConnections     int                     `json:"aria2cConnections"`
+	AudioNormalize        bool                    `json:"audioNormalize"`
+	CachePreviews         bool                    `json:"cachePreviews"`
+	UpgradeAfterHits      int                     `json:"upgradeAfterHits"`
+	CompactAfterDays      int                     `json:"compactAfterDays"`
+	EmbedMetadata         bool                    `json:"embedMetadata"`
+	LiveRecord            bool                    `json:"liveRecord"`
+	LiveFromStart         bool                    `json:"liveFromStart"`
+	LiveMaxMinutes        int                     `json:"liveMaxMinutes"`
+	ScheduleUpcoming      bool                    `json:"scheduleUpcoming"`
+	CachePath             string                  `json:"cachePath"`
+	DownloadTempPath      string                  `json:"downloadTempPath"`
+	BlockedURLs           []string                `json:"blockedUrls"`
+	BlockRedirect         string                  `json:"blockRedirect"`
+	BlocklistURLs         []string                `json:"blocklistUrls"`
+	BlocklistRefreshHours int                     `json:"blocklistRefreshHours"`
+	CrasherProtection     bool                    `json:"crasherProtection"`
+	BypassURLs            []string                `json:"bypassUrls"`
+	CacheYouTube          bool                    `json:"cacheYouTube"`
+	CacheYouTubeMaxRes    int                     `json:"cacheYouTubeMaxRes"`
+	CacheYouTubeMaxLength int                     `json:"cacheYouTubeMaxLength"`
+	CacheYouTubeMaxFPS    int                     `json:"cacheYouTubeMaxFps"`
+	CacheYouTubeAvoidAV1  bool                    `json:"cacheYouTubeAvoidAv1"`
+	DeviceProfile         string                  `json:"deviceProfile"`
+	SourcePolicies        map[string]SourcePolicy `json:"sourcePolicies"`
+	CacheMaxSizeGB        float64                 `json:"cacheMaxSizeGb"`
+	CacheForeignFiles     string                  `json:"cacheForeignFiles"`
+	CacheMaxDownloadMB    int                     `json:"cacheMaxDownloadMb"`
+	OversizeAction        string                  `json:"oversizeAction"`
+	CachePyPyDance        bool                    `json:"cachePyPyDance"`
+	CacheVRDancing        bool                    `json:"cacheVRDancing"`
+	PatchVRC              bool                    `json:"patchVRC"`
+	PatchVRCBeta          bool                    `json:"patchVRCBeta"`
+	PatchResonite         bool                    `json:"patchResonite"`
+	PatchChilloutVR       bool                    `json:"patchChilloutVR"`
+	ResonitePath          string                  `json:"resonitePath"`
+	WinePrefix            string                  `json:"winePrefix"`
+	AutoUpdate            bool                    `json:"autoUpdate"`
+	SkipUpdateVersion     string                  `json:"skipUpdateVersion"`
+	UpdateRemindAfter     string                  `json:"updateRemindAfter"`
+	GitHubToken           string                  `json:"githubToken"`
+	DownloadMirrors       []string                `json:"downloadMirrors"`
+	WeeklyReport          bool                    `json:"weeklyReport"`
+	ReportWebhookURL      string                  `json:"reportWebhookUrl"`
+	LogLevel              string                  `json:"logLevel"`
+	StartMinimized        bool                    `json:"startMinimized"`
+	MinimizeToTray        bool                    `json:"minimizeToTray"`
 }
 
 // DefaultConfig returns a configuration with default values
@@ -32,26 +149,87 @@ func DefaultConfig() *Config {
 	return &Config{
 		WebServerURL:          "http://localhost:9696",
 		WebServerPort:         9696,
+		LANMode:               false,
+		LANBaseURL:            "",
+		CORSAllowedOrigins:    slices.Clone(DefaultCORSOrigins),
+		TrustedProxies:        []string{},
+		RPCPort:               0,
 		YtdlPath:              "Utils/yt-dlp.exe",
 		YtdlUseCookies:        true,
+		YtdlCookieAccounts:    []string{},
 		YtdlAutoUpdate:        true,
 		YtdlAdditionalArgs:    "",
 		YtdlDubLanguage:       "",
 		YtdlDelay:             0,
+		YtdlPacing:            2,
+		Aria2cEnabled:         false,
+		Aria2cConnections:     8,
+		AudioNormalize:        false,
+		CachePreviews:         false,
+		UpgradeAfterHits:      0,
+		CompactAfterDays:      0,
+		EmbedMetadata:         false,
+		LiveRecord:            false,
+		LiveFromStart:         false,
+		LiveMaxMinutes:        180,
+		ScheduleUpcoming:      false,
 		CachePath:             "",
+		DownloadTempPath:      "",
 		BlockedURLs:           []string{},
 		BlockRedirect:         "",
+		BlocklistURLs:         []string{},
+		BlocklistRefreshHours: 24,
+		CrasherProtection:     false,
+		BypassURLs:            []string{},
 		CacheYouTube:          false,
 		CacheYouTubeMaxRes:    1080,
 		CacheYouTubeMaxLength: 120,
-		CacheMaxSizeGB:        0,
-		CachePyPyDance:        false,
-		CacheVRDancing:        false,
-		PatchVRC:              true,
-		PatchResonite:         false,
-		ResonitePath:          "",
-		AutoUpdate:            true,
-		StartMinimized:        false,
-		MinimizeToTray:        true,
+		CacheYouTubeMaxFPS:    0,
+		CacheYouTubeAvoidAV1:  false,
+		DeviceProfile:         "",
+		SourcePolicies: map[string]SourcePolicy{
+			// Resonite runs yt-dlp with -J and plays mp4
+			SourceResonite: {Format: "mp4", Response: ResponseJSON},
+			// ChilloutVR runs yt-dlp like VRChat but expects a plain mp4 URL
+			// whatever format filter it passes
+			SourceChilloutVR: {Format: "mp4", Response: ResponseURL},
+		},
+		CacheMaxSizeGB:     0,
+		CacheForeignFiles:  ForeignIgnore,
+		CacheMaxDownloadMB: 0,
+		OversizeAction:     OversizeConfirm,
+		CachePyPyDance:     false,
+		CacheVRDancing:     false,
+		PatchVRC:           true,
+		PatchVRCBeta:       false,
+		PatchResonite:      false,
+		PatchChilloutVR:    false,
+		ResonitePath:       "",
+		WinePrefix:         "",
+		AutoUpdate:         true,
+		SkipUpdateVersion:  "",
+		UpdateRemindAfter:  "",
+		GitHubToken:        "",
+		DownloadMirrors:    []string{},
+		WeeklyReport:       true,
+		ReportWebhookURL:   "",
+		LogLevel:           "info",
+		StartMinimized:     false,
+		MinimizeToTray:     true,
 	}
 }
+
+// Clone returns a deep copy of the configuration, so the copy can be read
+// while the original is being replaced
+func (c *Config) Clone() *Config {
+	clone := *c
+	clone.CORSAllowedOrigins = slices.Clone(c.CORSAllowedOrigins)
+	clone.TrustedProxies = slices.Clone(c.TrustedProxies)
+	clone.YtdlCookieAccounts = slices.Clone(c.YtdlCookieAccounts)
+	clone.BlockedURLs = slices.Clone(c.BlockedURLs)
+	clone.BlocklistURLs = slices.Clone(c.BlocklistURLs)
+	clone.BypassURLs = slices.Clone(c.BypassURLs)
+	clone.DownloadMirrors = slices.Clone(c.DownloadMirrors)
+	clone.SourcePolicies = maps.Clone(c.SourcePolicies)
+	return &clone
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // VideoInfo represents video metadata
 type VideoInfo struct {
@@ -28,6 +31,18 @@ const (
 	DownloadFormatWebm
 )
 
+// ParseDownloadFormat converts "mp4" or "webm" into a DownloadFormat
+func ParseDownloadFormat(s string) (DownloadFormat, bool) {
+	switch s {
+	case "mp4":
+		return DownloadFormatMP4, true
+	case "webm":
+		return DownloadFormatWebm, true
+	default:
+		return DownloadFormatMP4, false
+	}
+}
+
 func (f DownloadFormat) String() string {
 	switch f {
 	case DownloadFormatMP4:
@@ -46,4 +61,46 @@ type CacheEntry struct {
 	Size        int64     `json:"size"`
 	LastAccess  time.Time `json:"lastAccess"`
 	Created     time.Time `json:"created"`
+	Origin      string    `json:"origin"`
+	Aliases     []string  `json:"aliases,omitempty"`
+	Normalized  bool      `json:"normalized,omitempty"`
+	// MaxRes is the resolution limit the file was downloaded with, 0 if
+	// unknown (e.g. uploads)
+	MaxRes      int       `json:"maxRes,omitempty"`
+	// Private entries are only served to this machine, not to LAN clients
+	Private     bool      `json:"private,omitempty"`
+	// Variant is the codec the file was re-encoded to by compaction, empty
+	// if it is kept as downloaded
+	Variant     string    `json:"variant,omitempty"`
+	// Format is the container of the file: mp4 or webm
+	Format      string    `json:"format"`
+	// URL, Title and Resolution (the height in pixels) describe the video
+	// the file was downloaded from, empty if unknown (e.g. uploads)
+	URL         string    `json:"url,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Resolution  int       `json:"resolution,omitempty"`
+	// Hits counts how often the file was served, across restarts
+	Hits        int       `json:"hits"`
+}
+
+// Cache entry origins
+const (
+	// OriginDownloaded marks files downloaded by the cacher
+	OriginDownloaded = "downloaded"
+	// OriginAdopted marks foreign files accepted by the ForeignAdopt policy
+	OriginAdopted = "adopted"
+	// OriginUploaded marks local files added through the API or GUI
+	OriginUploaded = "uploaded"
+)
+
+// Cache entry variants, the codecs compaction re-encodes to
+const (
+	VariantAV1  = "av1"
+	VariantHEVC = "hevc"
+)
+
+// Version identifies the cached file's content, like an nginx ETag built
+// from its modification time and size. It changes when the file is replaced
+func (e *CacheEntry) Version() string {
+	return fmt.Sprintf("%x-%x", e.Created.Unix(), e.Size)
 }
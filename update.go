@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"vrcvideocacher/internal/github"
+	"vrcvideocacher/internal/updater"
+	"vrcvideocacher/pkg/models"
+)
+
+const (
+	appVersion = "0.1.0"
+	appRepo    = "kqnade/VRCYouTubePatcher"
+)
+
+// newUpdateNotifier creates the notifier announcing app updates with the
+// "app:update-available" event, unless skipUpdateVersion or
+// updateRemindAfter hold them back
+func (a *App) newUpdateNotifier(client *github.Client) *updater.Notifier {
+	u := updater.NewUpdaterWithClient(appRepo, appVersion, client)
+	return updater.NewNotifier(u, updater.DefaultInterval, func() updater.Preferences {
+		return updater.PreferencesFrom(a.configManager.Get())
+	}, func(update updater.Update) {
+		runtime.EventsEmit(a.ctx, "app:update-available", update)
+	})
+}
+
+// CheckAppUpdate checks for an app update now, announcing it like the
+// periodic check. It returns nil when up to date
+func (a *App) CheckAppUpdate() (*updater.Update, error) {
+	return a.updates.Check()
+}
+
+// SkipUpdateVersion stops announcing version and older versions, for the
+// "skip this version" button of the update prompt
+func (a *App) SkipUpdateVersion(version string) error {
+	return a.configManager.Update(func(c *models.Config) {
+		c.SkipUpdateVersion = version
+	})
+}
+
+// SnoozeUpdate stops announcing updates for days, for the "remind me
+// later" button of the update prompt
+func (a *App) SnoozeUpdate(days int) error {
+	if days < 1 {
+		return fmt.Errorf("snooze must be at least 1 day, got %d", days)
+	}
+
+	remindAfter := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	return a.configManager.Update(func(c *models.Config) {
+		c.UpdateRemindAfter = remindAfter.UTC().Format(time.RFC3339)
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"os"
+	"slices"
+
+	"github.com/wailsapp/wails/v2/pkg/options"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"vrcvideocacher/pkg/models"
+)
+
+// minimizedArg starts the app minimized, passed when it starts at login
+const minimizedArg = "--minimized"
+
+// singleInstanceID identifies the app to a second instance started while it
+// runs
+const singleInstanceID = "de.k4na.vrcvideocacher"
+
+// initWindow shows the window, which starts hidden, unless the app starts
+// minimized: then it stays hidden with minimizeToTray, or is minimized to
+// the taskbar. Without a config the window is shown
+func (a *App) initWindow(cfg *models.Config) {
+	if cfg == nil || !(cfg.StartMinimized || slices.Contains(os.Args[1:], minimizedArg)) {
+		runtime.WindowShow(a.ctx)
+		return
+	}
+
+	if !cfg.MinimizeToTray {
+		runtime.WindowShow(a.ctx)
+		runtime.WindowMinimise(a.ctx)
+	}
+	log().Info("Started minimized", "tray", cfg.MinimizeToTray)
+}
+
+// beforeClose hides the window instead of quitting with minimizeToTray;
+// the server keeps running until Quit is called
+func (a *App) beforeClose(ctx context.Context) (prevent bool) {
+	if a.quitting.Load() || a.configManager == nil || !a.configManager.Get().MinimizeToTray {
+		return false
+	}
+
+	runtime.WindowHide(ctx)
+	return true
+}
+
+// secondInstance restores the window when the app is started again while
+// it runs, unless it was started at login
+func (a *App) secondInstance(data options.SecondInstanceData) {
+	if slices.Contains(data.Args, minimizedArg) {
+		return
+	}
+	a.ShowWindow()
+}
+
+// ShowWindow restores the window from the tray or the taskbar
+func (a *App) ShowWindow() {
+	runtime.WindowShow(a.ctx)
+	runtime.WindowUnminimise(a.ctx)
+}
+
+// MinimizeWindow hides the window to the tray with minimizeToTray, or else
+// minimizes it to the taskbar
+func (a *App) MinimizeWindow() {
+	if a.configManager != nil && a.configManager.Get().MinimizeToTray {
+		runtime.WindowHide(a.ctx)
+		return
+	}
+	runtime.WindowMinimise(a.ctx)
+}
+
+// Quit stops the server and exits, also with minimizeToTray
+func (a *App) Quit() {
+	a.quitting.Store(true)
+	runtime.Quit(a.ctx)
+}